@@ -33,6 +33,9 @@ var objStoreInsertCmd = &cobra.Command{
 	Short: "Create a new knowledge object of a given type",
 	Long: `This command allows the creation of a new knowledge object of a given type in the Knowledge Store.
 
+The object is validated against the JSON schema and the allowed layers of its type before it is created, using the
+type definition cached by "knowledge describe-type", unless --no-validate is specified.
+
 Example:
   fsoc knowledge create --type<fully-qualified-typename> --object-file=<fully-qualified-path> --layer-type=<valid-layer-type> [--layer-id=<valid-layer-id>]
 `,
@@ -60,6 +63,8 @@ func getCreateObjectCmd() *cobra.Command {
 	objStoreInsertCmd.Flags().
 		String("layer-id", "", "The layer-id that the created knowledge object will be added to. Optional for TENANT and SOLUTION layers ")
 
+	addNoValidateFlag(objStoreInsertCmd)
+
 	return objStoreInsertCmd
 
 }
@@ -99,6 +104,8 @@ func insertObject(cmd *cobra.Command, args []string) {
 		"layer-id":   layerID,
 	}
 
+	checkObject(cmd, objType, layerType, objectStruct)
+
 	var res any
 	// objJsonStr, err := json.Marshal(objectStruct)
 	err = api.JSONPost(getObjStoreObjectUrl()+"/"+objType, objectStruct, &res, &api.Options{Headers: headers})
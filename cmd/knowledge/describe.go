@@ -0,0 +1,267 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package knowledge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+// typeCacheTTL is how long the type definitions fetched from the knowledge store are cached
+const typeCacheTTL = 24 * time.Hour
+
+// typeDefinition is the definition of a knowledge type, used to describe and validate its objects
+type typeDefinition struct {
+	Name                  string         `json:"name" yaml:"name"`
+	Solution              string         `json:"solution" yaml:"solution"`
+	JsonSchema            map[string]any `json:"jsonSchema" yaml:"jsonSchema"`
+	IdentifyingProperties []string       `json:"identifyingProperties" yaml:"identifyingProperties"`
+	AllowedLayers         []string       `json:"allowedLayers" yaml:"allowedLayers"`
+	SecureProperties      []string       `json:"secureProperties,omitempty" yaml:"secureProperties,omitempty"`
+}
+
+// typeCacheFile is the content of the file caching a type definition
+type typeCacheFile struct {
+	Fetched time.Time      `json:"fetched"`
+	Type    typeDefinition `json:"type"`
+}
+
+// typeProperty is a property of the objects of a type, from its JSON schema, named by its path in
+// the object data, e.g., name, settings.color or rules[].name for the properties of array items
+type typeProperty struct {
+	Name        string
+	Type        string
+	Description string
+	Required    bool
+	Identifying bool
+	Secure      bool
+}
+
+func newDescribeTypeCmd() *cobra.Command {
+	describeTypeCmd := &cobra.Command{
+		Use:   "describe-type <fully-qualified-type-name>",
+		Short: "Describe a knowledge type: its properties, layers, identifying and secure properties",
+		Long: `Describe a knowledge type, showing the layers where its objects can be created, the properties that identify
+an object and the secure properties, whose values are encrypted, along with the properties of its objects, as defined
+by the type's JSON schema. Use --output json or yaml to get the complete type definition, including the schema.
+
+The type definition is cached for a day in the fsoc directory of the user's cache directory, where other commands,
+e.g., the completion of data fields in "knowledge get", use it as well; use --refresh to fetch it again, e.g., after
+upgrading the solution that defines the type.`,
+		Example: `  fsoc knowledge describe-type extensibility:solution
+  fsoc knowledge describe-type mysolution:config --refresh
+  fsoc knowledge describe-type mysolution:config -o json`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: typeCompletionFunc,
+		Run:               describeType,
+		TraverseChildren:  true,
+	}
+	describeTypeCmd.Flags().Bool("refresh", false, "Fetch the type definition from the knowledge store instead of the cache")
+
+	return describeTypeCmd
+}
+
+func describeType(cmd *cobra.Command, args []string) {
+	fqtn := args[0]
+	refresh, _ := cmd.Flags().GetBool("refresh")
+	def, err := loadTypeDefinition(fqtn, refresh)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	output.PrintCmdOutputCustom(cmd, def, &output.Table{
+		Headers: []string{"Type", "Allowed Layers", "Identifying Properties", "Secure Properties"},
+		Lines: [][]string{{
+			def.Solution + ":" + def.Name,
+			strings.Join(def.AllowedLayers, ", "),
+			strings.Join(def.IdentifyingProperties, ", "),
+			strings.Join(def.SecureProperties, ", "),
+		}},
+		Detail: true,
+	})
+
+	// machine-readable formats include the schema in the document above
+	if format, _ := cmd.Flags().GetString("output"); format == "" || format == "auto" || format == "table" || format == "detail" {
+		properties := def.properties()
+		lines := [][]string{}
+		for _, p := range properties {
+			lines = append(lines, []string{p.Name, p.Type, yesIf(p.Required), yesIf(p.Identifying), yesIf(p.Secure), p.Description})
+		}
+		output.PrintCmdStatus(cmd, "\nProperties:\n")
+		output.PrintCmdOutputCustom(cmd, properties, &output.Table{
+			Headers: []string{"Property", "Type", "Required", "Identifying", "Secure", "Description"},
+			Lines:   lines,
+		})
+	}
+}
+
+// loadTypeDefinition returns the definition of a type, from the cache unless it is stale or refresh is true
+func loadTypeDefinition(fqtn string, refresh bool) (*typeDefinition, error) {
+	cfg := config.GetCurrentContext()
+	if cfg == nil {
+		return nil, fmt.Errorf("no current profile")
+	}
+	path, err := typeCachePath(cfg.Tenant, fqtn)
+	if err != nil {
+		log.Warnf("The type definition will not be cached: %v", err)
+	}
+	if path != "" && !refresh {
+		if cached, err := readTypeCache(path); err != nil {
+			log.Warnf("Failed to read the type definition cache, ignoring it: %v", err)
+		} else if cached != nil && time.Since(cached.Fetched) < typeCacheTTL {
+			log.WithFields(log.Fields{"type": fqtn, "path": path}).Info("using cached type definition")
+			return &cached.Type, nil
+		}
+	}
+
+	var def typeDefinition
+	err = api.JSONGet(getTypeUrl(fqtn), &def, &api.Options{ExpectedErrors: []int{http.StatusNotFound}})
+	var statusErr *api.HttpStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("type %q not found", fqtn)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the definition of type %v: %w", fqtn, err)
+	}
+	if path != "" {
+		if err := writeTypeCache(path, typeCacheFile{Fetched: time.Now(), Type: def}); err != nil {
+			log.Warnf("Failed to cache the type definition: %v", err)
+		}
+	}
+	return &def, nil
+}
+
+// typeCachePath returns the path of the file caching the definition of a type for a tenant
+func typeCachePath(tenant string, fqtn string) (string, error) {
+	namespace, name, found := strings.Cut(fqtn, ":")
+	if !found || namespace == "" || name == "" {
+		return "", fmt.Errorf("invalid type name %q; expected <solution>:<type>", fqtn)
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "fsoc", "knowledge_types", url.PathEscape(tenant), url.PathEscape(namespace), url.PathEscape(name)+".json"), nil
+}
+
+// readTypeCache reads a type definition cache file; it returns nil if the file does not exist
+func readTypeCache(path string) (*typeCacheFile, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cached typeCacheFile
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %w", path, err)
+	}
+	return &cached, nil
+}
+
+func writeTypeCache(path string, cached typeCacheFile) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// properties returns the properties of the type's objects, including nested ones, sorted by name
+func (def *typeDefinition) properties() []typeProperty {
+	identifying := map[string]bool{}
+	for _, pointer := range def.IdentifyingProperties {
+		identifying[strings.ReplaceAll(strings.TrimPrefix(pointer, "/"), "/", ".")] = true
+	}
+	secure := map[string]bool{}
+	for _, path := range def.SecureProperties {
+		secure[strings.TrimPrefix(path, "$.")] = true
+	}
+
+	properties := []typeProperty{}
+	var walk func(prefix string, schema map[string]any)
+	walk = func(prefix string, schema map[string]any) {
+		fields, _ := schema["properties"].(map[string]any)
+		required, _ := schema["required"].([]any)
+		for name, value := range fields {
+			fieldSchema, _ := value.(map[string]any)
+			p := typeProperty{
+				Name:     prefix + name,
+				Type:     schemaTypeName(fieldSchema),
+				Required: slices.Contains(required, any(name)),
+			}
+			p.Description, _ = fieldSchema["description"].(string)
+			p.Identifying = identifying[p.Name]
+			p.Secure = secure[p.Name]
+			properties = append(properties, p)
+			walk(p.Name+".", fieldSchema)
+			if items, ok := fieldSchema["items"].(map[string]any); ok {
+				walk(p.Name+"[].", items)
+			}
+		}
+	}
+	walk("", def.JsonSchema)
+	sort.Slice(properties, func(i, j int) bool { return properties[i].Name < properties[j].Name })
+	return properties
+}
+
+// schemaTypeName returns the type of a JSON schema, e.g., string, string[] for arrays of strings or
+// string|null for a list of types; it is empty if the schema has no type
+func schemaTypeName(schema map[string]any) string {
+	switch t := schema["type"].(type) {
+	case string:
+		if items, ok := schema["items"].(map[string]any); ok && t == "array" {
+			if itemType := schemaTypeName(items); itemType != "" {
+				return itemType + "[]"
+			}
+		}
+		return t
+	case []any:
+		names := []string{}
+		for _, name := range t {
+			names = append(names, fmt.Sprint(name))
+		}
+		return strings.Join(names, "|")
+	}
+	return ""
+}
+
+// yesIf returns "yes" for true and an empty string for false, for table columns
+func yesIf(b bool) string {
+	if b {
+		return "yes"
+	}
+	return ""
+}
@@ -0,0 +1,259 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package knowledge
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/cmdkit/jsondoc"
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+// diffSide - where the objects compared come from: a layer, seen with a profile (empty for the current one)
+type diffSide struct {
+	profile   string
+	layerType string
+	layerID   string
+	label     string
+}
+
+// objectDifference is a difference between the objects of two layers or tenants
+type objectDifference struct {
+	Change string `json:"change"` // added (only in the second), removed (only in the first) or modified
+	Type   string `json:"type"`
+	Object string `json:"object"`
+	Field  string `json:"field,omitempty"`
+	From   any    `json:"from,omitempty"`
+	To     any    `json:"to,omitempty"`
+}
+
+func newDiffCmd() *cobra.Command {
+	ltFlag := unknown
+	toLtFlag := unknown
+
+	diffCmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Compare knowledge objects between two layers or two tenants",
+		Long: `Compare a knowledge object, or all the objects of a type, between two layers (e.g., SOLUTION, TENANT and LOCALUSER)
+or between two tenants, showing the objects and the fields that differ, e.g., to find out why a tenant or a user behaves
+differently from another.
+
+The objects are compared as they are seen at each layer, including the objects and values inherited from other layers,
+i.e., as they apply there. The first side is given with --layer-type and --layer-id, in the current profile; the second
+side with --to-layer-type and --to-layer-id, in the profile given with --to-profile, each defaulting to the first side.
+Layer IDs default to the ones of each profile, e.g., its tenant. Only the data of the objects is compared.
+
+Changes are from the first side to the second one: "added" objects and fields exist only in the second one and
+"removed" ones only in the first one.`,
+		Example: `  # Compare the theme of the current user with the tenant's
+  fsoc knowledge diff --type preferences:theme --object-id dark --layer-type TENANT --to-layer-type LOCALUSER
+
+  # Compare all the objects of a type between two tenants
+  fsoc knowledge diff --type mysolution:config --layer-type TENANT --to-profile staging
+
+  # Compare the objects of a solution with their values in the tenant
+  fsoc knowledge diff --type mysolution:config --layer-type SOLUTION --layer-id mysolution --to-layer-type TENANT`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			diffObjects(cmd, ltFlag, toLtFlag)
+		},
+		TraverseChildren: true,
+	}
+
+	diffCmd.Flags().
+		String("type", "", "Fully qualified type name of the objects to compare, e.g., extensibility:solution")
+	_ = diffCmd.MarkFlagRequired("type")
+	_ = diffCmd.RegisterFlagCompletionFunc("type", typeCompletionFunc)
+
+	diffCmd.Flags().String("object-id", "", "Object ID of the object to compare (default: all the objects of the type)")
+	_ = diffCmd.RegisterFlagCompletionFunc("object-id", objectCompletionFunc)
+
+	diffCmd.Flags().
+		Var(&ltFlag, "layer-type", fmt.Sprintf("Layer type of the first side.  Valid values: %q, %q, %q, %q, %q", solution, account, globalUser, tenant, localUser))
+	_ = diffCmd.MarkFlagRequired("layer-type")
+	_ = diffCmd.RegisterFlagCompletionFunc("layer-type", layerTypeCompletionFunc)
+	diffCmd.Flags().String("layer-id", "", "Layer ID of the first side. Optional for all layers but SOLUTION")
+
+	diffCmd.Flags().
+		Var(&toLtFlag, "to-layer-type", "Layer type of the second side (default: --layer-type)")
+	_ = diffCmd.RegisterFlagCompletionFunc("to-layer-type", layerTypeCompletionFunc)
+	diffCmd.Flags().String("to-layer-id", "", "Layer ID of the second side (default: --layer-id for the same layer type and profile)")
+	diffCmd.Flags().String("to-profile", "", "Profile of the tenant of the second side (default: the current profile)")
+
+	return diffCmd
+}
+
+func diffObjects(cmd *cobra.Command, ltFlag layerType, toLtFlag layerType) {
+	fqtn, _ := cmd.Flags().GetString("type")
+	objID, _ := cmd.Flags().GetString("object-id")
+	from := diffSide{layerType: ltFlag.String()}
+	from.layerID, _ = cmd.Flags().GetString("layer-id")
+	to := diffSide{layerType: toLtFlag.String()}
+	to.layerID, _ = cmd.Flags().GetString("to-layer-id")
+	to.profile, _ = cmd.Flags().GetString("to-profile")
+	if to.layerType == "" {
+		to.layerType = from.layerType
+	}
+	if to.layerID == "" && to.layerType == from.layerType && to.profile == "" {
+		to.layerID = from.layerID
+	}
+	if !cmd.Flags().Changed("to-layer-type") && !cmd.Flags().Changed("to-layer-id") && !cmd.Flags().Changed("to-profile") {
+		log.Fatalf("Nothing to compare with: specify the second side with --to-layer-type, --to-layer-id or --to-profile")
+	}
+	for _, side := range []*diffSide{&from, &to} {
+		if err := side.resolve(fqtn); err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+	if from.label == to.label {
+		from.label, to.label = "From", "To"
+	}
+
+	var fromObjects, toObjects map[string]any
+	var err error
+	if fromObjects, err = from.fetchObjects(fqtn, objID); err != nil {
+		log.Fatalf("Failed to fetch the objects of %v: %v", from.label, err)
+	}
+	if toObjects, err = to.fetchObjects(fqtn, objID); err != nil {
+		log.Fatalf("Failed to fetch the objects of %v: %v", to.label, err)
+	}
+	if objID != "" && len(fromObjects) == 0 && len(toObjects) == 0 {
+		log.Fatalf("Object %q of type %v not found in %v nor in %v", objID, fqtn, from.label, to.label)
+	}
+
+	differences := diffObjectSets(fqtn, fromObjects, toObjects)
+	if len(differences) == 0 {
+		output.PrintCmdStatus(cmd, fmt.Sprintf("No differences between %v and %v.\n", from.label, to.label))
+		return
+	}
+	lines := [][]string{}
+	for _, d := range differences {
+		lines = append(lines, []string{d.Change, d.Object, d.Field, formatDiffValue(d.From), formatDiffValue(d.To)})
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []objectDifference `json:"items"`
+		Total int                `json:"total"`
+	}{differences, len(differences)}, &output.Table{
+		Headers: []string{"Change", "Object", "Field", from.label, to.label},
+		Lines:   lines,
+	})
+}
+
+// resolve sets the default layer ID of a side and its label
+func (s *diffSide) resolve(fqtn string) error {
+	cfg := config.GetCurrentContext()
+	if s.profile != "" {
+		var err error
+		if cfg, err = config.GetContext(s.profile); err != nil {
+			return fmt.Errorf("failed to use profile: %w", err)
+		}
+	}
+	if s.layerID == "" {
+		if s.layerType == string(solution) {
+			return fmt.Errorf("comparing objects of the SOLUTION layer requires a layer ID")
+		}
+		s.layerID = getLayerIDForContext(cfg, s.layerType, fqtn)
+	}
+	s.label = s.layerType
+	if s.layerType == string(solution) {
+		s.label = fmt.Sprintf("%v %v", s.layerType, s.layerID)
+	}
+	if s.profile != "" {
+		s.label = fmt.Sprintf("%v %v", s.profile, s.label)
+	}
+	return nil
+}
+
+// fetchObjects returns the data of an object, or of all the objects of a type if objID is empty, by ID,
+// as seen at the side's layer; an object that doesn't exist is not returned
+func (s *diffSide) fetchObjects(fqtn string, objID string) (map[string]any, error) {
+	options := &api.Options{
+		Headers: map[string]string{"layer-type": s.layerType, "layer-id": s.layerID},
+		Profile: s.profile,
+		Quiet:   true,
+	}
+	objects := map[string]any{}
+	if objID != "" {
+		options.ExpectedErrors = []int{http.StatusNotFound}
+		var object KSObject
+		err := api.JSONGet(getObjectUrl(fqtn, objID), &object, options)
+		var statusErr *api.HttpStatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		objects[objID] = object.Data
+		return objects, nil
+	}
+
+	var res api.CollectionResult[KSObject]
+	if err := api.JSONGetCollection(getObjectListUrl(fqtn), &res, options); err != nil {
+		return nil, err
+	}
+	for _, object := range res.Items {
+		objects[object.ID] = object.Data
+	}
+	return objects, nil
+}
+
+// diffObjectSets compares the objects of two sides by ID, returning the objects that exist on one side
+// only and the fields that differ, sorted by object ID
+func diffObjectSets(fqtn string, fromObjects map[string]any, toObjects map[string]any) []objectDifference {
+	ids := []string{}
+	for id := range fromObjects {
+		ids = append(ids, id)
+	}
+	for id := range toObjects {
+		if _, found := fromObjects[id]; !found {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	differences := []objectDifference{}
+	for _, id := range ids {
+		fromData, inFrom := fromObjects[id]
+		toData, inTo := toObjects[id]
+		switch {
+		case !inFrom:
+			differences = append(differences, objectDifference{Change: jsondoc.ChangeAdded, Type: fqtn, Object: id})
+		case !inTo:
+			differences = append(differences, objectDifference{Change: jsondoc.ChangeRemoved, Type: fqtn, Object: id})
+		default:
+			for _, c := range jsondoc.Diff(toData, fromData) {
+				differences = append(differences, objectDifference{Change: c.Change, Type: fqtn, Object: id, Field: c.Path, From: c.Old, To: c.New})
+			}
+		}
+	}
+	return differences
+}
+
+// formatDiffValue formats a value for the table, leaving absent values empty
+func formatDiffValue(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
@@ -58,6 +58,8 @@ func editObjectCmd() *cobra.Command {
 	editCmd.Flags().
 		String("layer-id", "", "The layer-id of the knowledge object to update. Optional for TENANT and SOLUTION layers ")
 
+	addNoValidateFlag(editCmd)
+
 	return editCmd
 
 }
@@ -111,6 +113,7 @@ func editObject(cmd *cobra.Command, args []string) {
 	if err != nil {
 		log.Fatalf("Edited data is not valid json: %v", err)
 	}
+	checkObject(cmd, fqtn, layerType, editedData)
 
 	// Send update to server, with etag
 	headersPut := map[string]string{
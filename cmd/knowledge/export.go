@@ -0,0 +1,306 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package knowledge
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+// exportManifestName is the name of the manifest of an export directory, without extension
+const exportManifestName = "manifest"
+
+// exportManifest - the knowledge objects exported into a directory, to import them back
+type exportManifest struct {
+	Tenant    string           `json:"tenant" yaml:"tenant"`
+	LayerType string           `json:"layerType" yaml:"layerType"`
+	LayerID   string           `json:"layerId" yaml:"layerId"`
+	Types     []string         `json:"types" yaml:"types"`
+	Objects   []exportedObject `json:"objects" yaml:"objects"`
+}
+
+// exportedObject - a knowledge object exported into a file, relative to the export directory
+type exportedObject struct {
+	Type string `json:"type" yaml:"type"`
+	ID   string `json:"id" yaml:"id"`
+	File string `json:"file" yaml:"file"`
+}
+
+// layerObject - a knowledge object fetched from a layer, possibly inherited from another layer
+type layerObject struct {
+	ID        string         `json:"id"`
+	LayerType string         `json:"layerType"`
+	Data      map[string]any `json:"data"`
+}
+
+// exportFileNameRegexp matches the characters not used in exported object file names
+var exportFileNameRegexp = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+func newExportCmd() *cobra.Command {
+	ltFlag := unknown
+
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export knowledge objects into a directory, one file per object",
+		Long: `Export the knowledge objects of one or more types at a layer into a directory, one JSON (or YAML) file per
+object, e.g., to back up the configuration of a tenant in git or to copy it to another tenant with "knowledge import".
+
+The objects of each type are saved in a directory named after the type, e.g., out/dashui/template for dashui:template
+objects, with the object data only. A manifest (manifest.json or manifest.yaml) lists the tenant, the layer and the
+file of each object, by type and ID. Only the objects that exist at the layer are exported, unless --include-inherited
+is specified; objects inherited from other layers, e.g., provided by solutions, are visible at the layer as well.
+
+Exporting again into the same directory replaces the files of the previous export, so that the files of objects deleted
+since then are removed. The types are fetched in parallel.`,
+		Example: `  fsoc knowledge export --type dashui:template --layer-type TENANT --dir ./out
+  fsoc knowledge export --type mysolution:config --type mysolution:rule --layer-type TENANT --dir ./backup --yaml
+  fsoc knowledge export --type preferences:theme --layer-type LOCALUSER --dir ./out --filter 'data.name eq "dark"'`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			exportObjects(cmd, ltFlag)
+		},
+		TraverseChildren: true,
+	}
+
+	exportCmd.Flags().
+		StringSlice("type", nil, "Fully qualified type name of the objects to export, e.g., dashui:template; can be repeated")
+	_ = exportCmd.MarkFlagRequired("type")
+	_ = exportCmd.RegisterFlagCompletionFunc("type", typeCompletionFunc)
+
+	exportCmd.Flags().
+		Var(&ltFlag, "layer-type", fmt.Sprintf("Layer type of the objects to export.  Valid values: %q, %q, %q, %q, %q", solution, account, globalUser, tenant, localUser))
+	_ = exportCmd.MarkFlagRequired("layer-type")
+	_ = exportCmd.RegisterFlagCompletionFunc("layer-type", layerTypeCompletionFunc)
+
+	exportCmd.Flags().String("layer-id", "", "Layer ID of the objects to export. Optional for all layers but SOLUTION")
+	exportCmd.Flags().String("dir", "", "Directory to export the objects into; created if it doesn't exist")
+	_ = exportCmd.MarkFlagRequired("dir")
+	exportCmd.Flags().String("filter", "", "Export only the objects matching the filter, in SCIM filter format")
+	exportCmd.Flags().Bool("include-inherited", false, "Export the objects inherited from other layers as well")
+	exportCmd.Flags().Bool("yaml", false, "Use YAML format instead of JSON for the objects and the manifest")
+	exportCmd.Flags().Int("parallel", 4, "Number of types to fetch in parallel")
+
+	return exportCmd
+}
+
+func exportObjects(cmd *cobra.Command, ltFlag layerType) {
+	typeNames, _ := cmd.Flags().GetStringSlice("type")
+	dir, _ := cmd.Flags().GetString("dir")
+	filter, _ := cmd.Flags().GetString("filter")
+	includeInherited, _ := cmd.Flags().GetBool("include-inherited")
+	useYaml, _ := cmd.Flags().GetBool("yaml")
+	parallel, _ := cmd.Flags().GetInt("parallel")
+	if parallel < 1 {
+		log.Fatalf("Invalid --parallel %d, must be at least 1", parallel)
+	}
+	for _, typeName := range typeNames {
+		if namespace, name, found := strings.Cut(typeName, ":"); !found || namespace == "" || name == "" {
+			log.Fatalf("Invalid type name %q; expected <solution>:<type>, e.g., dashui:template", typeName)
+		}
+	}
+	lType := ltFlag.String()
+	layerID, _ := cmd.Flags().GetString("layer-id")
+	if layerID == "" {
+		if lType == string(solution) {
+			log.Fatalf("Exporting objects from the SOLUTION layer requires the --layer-id flag")
+		}
+		layerID = getCorrectLayerID(lType, typeNames[0])
+	}
+
+	// fetch the objects of all types before writing anything
+	objectsByType := make([][]layerObject, len(typeNames))
+	errs := make([]error, len(typeNames))
+	forEachParallel(len(typeNames), parallel, func(i int) {
+		objectsByType[i], errs[i] = fetchLayerObjects(typeNames[i], lType, layerID, filter, includeInherited)
+	})
+	for i, err := range errs {
+		if err != nil {
+			log.Fatalf("Failed to fetch the objects of type %v: %v", typeNames[i], err)
+		}
+	}
+
+	// replace the previous export, if any
+	format := "json"
+	if useYaml {
+		format = "yaml"
+	}
+	if previous, previousFile, err := readExportManifest(dir); err == nil {
+		for _, object := range previous.Objects {
+			if filepath.IsLocal(filepath.FromSlash(object.File)) {
+				_ = os.Remove(filepath.Join(dir, filepath.FromSlash(object.File)))
+			}
+		}
+		_ = os.Remove(previousFile)
+	}
+
+	manifest := exportManifest{
+		Tenant:    config.GetCurrentContext().Tenant,
+		LayerType: lType,
+		LayerID:   layerID,
+		Types:     typeNames,
+		Objects:   []exportedObject{},
+	}
+	for i, typeName := range typeNames {
+		namespace, name, _ := strings.Cut(typeName, ":")
+		typeDir := path.Join(namespace, name)
+		if err := os.MkdirAll(filepath.Join(dir, typeDir), os.ModePerm); err != nil {
+			log.Fatalf("Failed to create the directory for type %v: %v", typeName, err)
+		}
+		usedNames := map[string]bool{}
+		for _, object := range objectsByType[i] {
+			file := path.Join(typeDir, getExportFileName(object.ID, usedNames)+"."+format)
+			if err := writeObjectFile(filepath.Join(dir, filepath.FromSlash(file)), object.Data, useYaml); err != nil {
+				log.Fatalf("Failed to save object %q: %v", object.ID, err)
+			}
+			manifest.Objects = append(manifest.Objects, exportedObject{Type: typeName, ID: object.ID, File: file})
+		}
+	}
+	if err := writeObjectFile(filepath.Join(dir, exportManifestName+"."+format), manifest, useYaml); err != nil {
+		log.Fatalf("Failed to write the manifest: %v", err)
+	}
+
+	lines := [][]string{}
+	for _, object := range manifest.Objects {
+		lines = append(lines, []string{object.Type, object.ID, object.File})
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []exportedObject `json:"items"`
+		Total int              `json:"total"`
+	}{manifest.Objects, len(manifest.Objects)}, &output.Table{Headers: []string{"Type", "ID", "File"}, Lines: lines})
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Exported %d objects into %v.\n", len(manifest.Objects), dir))
+}
+
+// fetchLayerObjects returns the objects of a type at a layer, optionally filtered, sorted by ID;
+// objects inherited from other layers are included only if requested
+func fetchLayerObjects(typeName string, lType string, layerID string, filter string, includeInherited bool) ([]layerObject, error) {
+	query := ""
+	if filter != "" {
+		query = "?filter=" + url.QueryEscape(filter)
+	}
+	headers := map[string]string{
+		"layer-type": lType,
+		"layer-id":   layerID,
+	}
+	var res api.CollectionResult[layerObject]
+	if err := api.JSONGetCollection(getObjectListUrl(typeName)+query, &res, &api.Options{Headers: headers, Quiet: true}); err != nil {
+		return nil, err
+	}
+
+	objects := []layerObject{}
+	for _, object := range res.Items {
+		if includeInherited || object.LayerType == lType {
+			objects = append(objects, object)
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].ID < objects[j].ID })
+	return objects, nil
+}
+
+// readExportManifest reads the manifest of an export directory, returning it with its file name
+func readExportManifest(dir string) (*exportManifest, string, error) {
+	for _, ext := range []string{"json", "yaml", "yml"} {
+		fileName := filepath.Join(dir, exportManifestName+"."+ext)
+		content, err := os.ReadFile(fileName)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fileName, err
+		}
+		var manifest exportManifest
+		if ext == "json" {
+			err = json.Unmarshal(content, &manifest)
+		} else {
+			err = yaml.Unmarshal(content, &manifest)
+		}
+		if err != nil {
+			return nil, fileName, fmt.Errorf("failed to parse the manifest %q: %w", fileName, err)
+		}
+		return &manifest, fileName, nil
+	}
+	return nil, "", fmt.Errorf("no manifest.json or manifest.yaml found in %q", dir)
+}
+
+// getExportFileName returns a file name (without extension) for an exported object, based on its
+// ID, that is not in usedNames; the name is added to usedNames
+func getExportFileName(objectId string, usedNames map[string]bool) string {
+	base := strings.Trim(exportFileNameRegexp.ReplaceAllString(objectId, "-"), "-.")
+	if base == "" {
+		base = "object"
+	}
+	name := base
+	for i := 2; usedNames[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%v-%d", base, i)
+	}
+	usedNames[strings.ToLower(name)] = true
+	return name
+}
+
+// writeObjectFile writes a value into a JSON or YAML file
+func writeObjectFile(fileName string, value any, useYaml bool) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if useYaml {
+		return output.WriteYaml(value, f)
+	}
+	enc := json.NewEncoder(f)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", output.JsonIndent)
+	return enc.Encode(value)
+}
+
+// forEachParallel calls f for each index from 0 to n-1, with up to parallel calls at once. The first
+// call is made alone, so that the others share its login, if any.
+func forEachParallel(n int, parallel int, f func(i int)) {
+	if n == 0 {
+		return
+	}
+	f(0)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				f(i)
+			}
+		}()
+	}
+	for i := 1; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
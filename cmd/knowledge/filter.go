@@ -0,0 +1,133 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package knowledge
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/cmd/uql"
+)
+
+// whereOperators maps the operators of --where conditions to SCIM filter operators, longest first
+// so that, e.g., ">=" is not taken for ">"
+var whereOperators = []struct{ op, scim string }{
+	{"!=", "ne"},
+	{">=", "ge"},
+	{"<=", "le"},
+	{"=", "eq"},
+	{">", "gt"},
+	{"<", "lt"},
+}
+
+// timeFilterFlags maps the object time flags to the object attribute and SCIM filter operator they use
+var timeFilterFlags = []struct{ flag, attribute, scim string }{
+	{"created-since", "createdAt", "ge"},
+	{"created-until", "createdAt", "lt"},
+	{"updated-since", "updatedAt", "ge"},
+	{"updated-until", "updatedAt", "lt"},
+}
+
+// addFilterFlags adds the flags that select objects on the server side, in addition to --filter
+func addFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArray("where", nil, `Condition on a data field, e.g., "size>=4" or "name=dark"; can be repeated. Operators: = != > >= < <=`)
+	_ = cmd.RegisterFlagCompletionFunc("where", whereCompletionFunc)
+	cmd.Flags().String("created-since", "", "Select objects created at or after a time: a duration before now (e.g., 15m, 2h, 3d) or a time (e.g., 2024-01-13T13:57:20Z)")
+	cmd.Flags().String("created-until", "", "Select objects created before a time, given like --created-since")
+	cmd.Flags().String("updated-since", "", "Select objects last updated at or after a time, given like --created-since")
+	cmd.Flags().String("updated-until", "", "Select objects last updated before a time, given like --created-since")
+}
+
+// getObjectFilter returns the SCIM filter selecting the objects specified by --filter, --where and the
+// object time flags, all of which must match, or an empty string if none is specified
+func getObjectFilter(cmd *cobra.Command) (string, error) {
+	conditions := []string{}
+	if filter, _ := cmd.Flags().GetString("filter"); filter != "" {
+		conditions = append(conditions, filter)
+	}
+	where, _ := cmd.Flags().GetStringArray("where")
+	for _, condition := range where {
+		scim, err := parseWhereCondition(condition)
+		if err != nil {
+			return "", err
+		}
+		conditions = append(conditions, scim)
+	}
+	for _, f := range timeFilterFlags {
+		value, _ := cmd.Flags().GetString(f.flag)
+		if value == "" {
+			continue
+		}
+		t, err := uql.ResolveTime(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid --%v: %w", f.flag, err)
+		}
+		conditions = append(conditions, fmt.Sprintf("%v %v %q", f.attribute, f.scim, t.Format(time.RFC3339)))
+	}
+
+	if len(conditions) > 1 {
+		for i, condition := range conditions {
+			conditions[i] = "(" + condition + ")"
+		}
+	}
+	return strings.Join(conditions, " and "), nil
+}
+
+// parseWhereCondition converts a --where condition, e.g., size>=4, to a SCIM filter condition on the
+// object's data, e.g., data.size ge 4. Values that are not JSON numbers, booleans, null or quoted
+// strings are taken as strings.
+func parseWhereCondition(condition string) (string, error) {
+	for _, o := range whereOperators {
+		field, value, found := strings.Cut(condition, o.op)
+		if !found {
+			continue
+		}
+		field = strings.TrimSpace(field)
+		if field == "" || strings.ContainsAny(field, "!<>= ") {
+			break
+		}
+		if !strings.HasPrefix(field, "data.") {
+			field = "data." + field
+		}
+		value = strings.TrimSpace(value)
+		var v any
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			return fmt.Sprintf("%v %v %q", field, o.scim, value), nil
+		}
+		switch v.(type) {
+		case map[string]any, []any:
+			return "", fmt.Errorf("invalid --where %q: only numbers, booleans, null and strings can be compared", condition)
+		}
+		return fmt.Sprintf("%v %v %v", field, o.scim, value), nil
+	}
+	return "", fmt.Errorf("invalid --where %q: expected <field><operator><value>, e.g., size>=4, with one of the operators = != > >= < <=", condition)
+}
+
+// filterFlagsChanged returns whether any flag selecting objects is specified
+func filterFlagsChanged(cmd *cobra.Command) bool {
+	if cmd.Flags().Changed("filter") || cmd.Flags().Changed("where") {
+		return true
+	}
+	for _, f := range timeFilterFlags {
+		if cmd.Flags().Changed(f.flag) {
+			return true
+		}
+	}
+	return false
+}
@@ -45,6 +45,10 @@ func newGetObjectCmd() *cobra.Command {
   # Get list of objects filtering by a data field
   fsoc knowledge get --type=extensibility:solution --layer-type=TENANT --filter="data.isSystem eq true"
   fsoc knowledge get --type=preferences:theme --layer-type=TENANT --filter="data.backgroundColor eq \"green\""
+  fsoc knowledge get --type=preferences:theme --layer-type=TENANT --where backgroundColor=green --where "size>=4"
+
+  # Get list of objects updated in the last day, fetching only some of their fields
+  fsoc knowledge get --type=preferences:theme --layer-type=TENANT --updated-since=1d --select=id,updatedAt,data.name
   `,
 		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -69,7 +73,12 @@ func newGetObjectCmd() *cobra.Command {
 	_ = getCmd.RegisterFlagCompletionFunc("layer-type", layerTypeCompletionFunc)
 
 	getCmd.PersistentFlags().String("filter", "", "Filter condition in SCIM filter format for getting knowledge objects")
-	getCmd.PersistentFlags().String("fields", "", "Specific fields to fetch when getting knowledge objects.  By default, all fields are returned unless otherwise specified.  Please specify fields as a csv string.")
+	addFilterFlags(getCmd)
+	getCmd.PersistentFlags().String("select", "", "Specific fields to fetch when getting knowledge objects, as a comma-separated list (e.g., id,data.name).  By default, all fields are returned")
+	_ = getCmd.RegisterFlagCompletionFunc("select", selectCompletionFunc)
+	// --fields was renamed to --select; it is kept for compatibility, shadowing the global jq --fields flag as before
+	getCmd.PersistentFlags().String("fields", "", "Specific fields to fetch when getting knowledge objects (same as --select)")
+	_ = getCmd.PersistentFlags().MarkDeprecated("fields", "please use --select instead")
 	_ = getCmd.MarkPersistentFlagRequired("type")
 	_ = getCmd.MarkPersistentFlagRequired("layer-type")
 
@@ -130,43 +139,37 @@ func getObject(cmd *cobra.Command, args []string, ltFlag layerType) error {
 	}
 
 	// execute command and print output
-	var objStoreUrl string
-	var isCollection bool = true
-	var filterSpecified bool = false
-	if objID != "" {
-		objStoreUrl = getObjectUrl(fqtn, objID)
-		if cmd.Flags().Changed("fields") {
-			fieldsToFetch, err := cmd.Flags().GetString("fields")
-			if err != nil {
-				return fmt.Errorf("error trying to get %q flag value: %w", "fields", err)
-			}
-			query := fmt.Sprintf("fields=%s", url.QueryEscape(fieldsToFetch))
-			objStoreUrl = fmt.Sprintf("%s?%s", objStoreUrl, query)
+	query := url.Values{}
+	fields, _ := cmd.Flags().GetString("select")
+	if cmd.Flags().Changed("fields") {
+		if cmd.Flags().Changed("select") {
+			return fmt.Errorf("--fields is a deprecated alias of --select; they cannot be used together")
 		}
-		isCollection = false
-	} else {
-		if cmd.Flags().Changed("filter") {
-			filterSpecified = true
-			filterCriteria, err := cmd.Flags().GetString("filter")
-			if err != nil {
-				return fmt.Errorf("error trying to get %q flag value: %w", "filter", err)
-			}
-			filterQuery := fmt.Sprintf("filter=%s", url.QueryEscape(filterCriteria))
-			fqtn = fqtn + "?" + filterQuery
+		fields, _ = cmd.Flags().GetString("fields")
+		_ = cmd.Flags().Set("fields", "") // not a jq expression for the output
+	}
+	if fields != "" {
+		query.Set("fields", fields)
+	}
+	var objStoreUrl string
+	isCollection := objID == ""
+	if isCollection {
+		filter, err := getObjectFilter(cmd)
+		if err != nil {
+			return err
 		}
-		if cmd.Flags().Changed("fields") {
-			fieldsToFetch, err := cmd.Flags().GetString("fields")
-			if err != nil {
-				return fmt.Errorf("error trying to get %q flag value: %w", "fields", err)
-			}
-			fieldsQuery := fmt.Sprintf("fields=%s", url.QueryEscape(fieldsToFetch))
-			if filterSpecified {
-				fqtn = fqtn + "&" + fieldsQuery
-			} else {
-				fqtn = fqtn + "?" + fieldsQuery
-			}
+		if filter != "" {
+			query.Set("filter", filter)
 		}
 		objStoreUrl = getObjectListUrl(fqtn)
+	} else {
+		if filterFlagsChanged(cmd) {
+			log.Warnf("Filters are ignored when getting a single object with --object-id")
+		}
+		objStoreUrl = getObjectUrl(fqtn, objID)
+	}
+	if len(query) > 0 {
+		objStoreUrl += "?" + query.Encode()
 	}
 
 	cmdkit.FetchAndPrint(cmd, objStoreUrl, &cmdkit.FetchAndPrintOptions{Headers: headers, IsCollection: isCollection})
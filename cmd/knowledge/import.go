@@ -0,0 +1,222 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package knowledge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+// importedObject - the result of importing an exported knowledge object
+type importedObject struct {
+	exportedObject `yaml:",inline"`
+	Result         string `json:"result" yaml:"result"`
+	Error          string `json:"error,omitempty" yaml:"error,omitempty"`
+
+	data map[string]any
+}
+
+func newImportCmd() *cobra.Command {
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import knowledge objects exported into a directory",
+		Long: `Import the knowledge objects exported into a directory with "knowledge export", e.g., to restore a backup or
+to copy the configuration of a tenant to another one.
+
+The objects listed in the manifest of the directory are created at the layer they were exported from, or at the
+layer specified with --layer-type (and --layer-id); the layer ID defaults to the one of the current profile, e.g.,
+its tenant, except for the SOLUTION layer. Objects that already exist are updated with the data of their file.
+Use --type to import only the objects of some types, and --dry-run to check the files and see what would be
+imported. The objects are validated against the JSON schema of their type before importing any, unless --no-validate
+is specified. The objects are imported in parallel.`,
+		Example: `  fsoc knowledge import --dir ./out
+  fsoc knowledge import --dir ./backup --type mysolution:config --dry-run
+  fsoc knowledge import --dir ./out --layer-type LOCALUSER`,
+		Args:             cobra.NoArgs,
+		Run:              importObjects,
+		TraverseChildren: true,
+	}
+
+	importCmd.Flags().String("dir", "", "Directory the objects were exported into")
+	_ = importCmd.MarkFlagRequired("dir")
+	importCmd.Flags().StringSlice("type", nil, "Import only the objects of this type; can be repeated")
+	_ = importCmd.RegisterFlagCompletionFunc("type", typeCompletionFunc)
+	importCmd.Flags().String("layer-type", "", "Layer type to import the objects into (default: the layer they were exported from)")
+	_ = importCmd.RegisterFlagCompletionFunc("layer-type", layerTypeCompletionFunc)
+	importCmd.Flags().String("layer-id", "", "Layer ID to import the objects into. Optional for all layers but SOLUTION")
+	importCmd.Flags().Bool("dry-run", false, "Check the files and display the objects to import, without importing them")
+	importCmd.Flags().Int("parallel", 4, "Number of objects to import in parallel")
+	addNoValidateFlag(importCmd)
+
+	return importCmd
+}
+
+func importObjects(cmd *cobra.Command, args []string) {
+	dir, _ := cmd.Flags().GetString("dir")
+	typeNames, _ := cmd.Flags().GetStringSlice("type")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	noValidate, _ := cmd.Flags().GetBool("no-validate")
+	parallel, _ := cmd.Flags().GetInt("parallel")
+	if parallel < 1 {
+		log.Fatalf("Invalid --parallel %d, must be at least 1", parallel)
+	}
+	manifest, manifestFile, err := readExportManifest(dir)
+	if err != nil {
+		log.Fatalf("Failed to read the export manifest: %v", err)
+	}
+
+	lType, _ := cmd.Flags().GetString("layer-type")
+	layerID, _ := cmd.Flags().GetString("layer-id")
+	if lType == "" {
+		lType = manifest.LayerType
+	}
+	var lt layerType
+	if err := lt.Set(lType); err != nil {
+		log.Fatalf("Invalid layer type %q: %v", lType, err)
+	}
+	if layerID == "" && lType == string(solution) {
+		if manifest.LayerType != lType {
+			log.Fatalf("Importing objects into the SOLUTION layer requires the --layer-id flag")
+		}
+		layerID = manifest.LayerID
+	}
+
+	// read all the objects before importing any
+	objects := []*importedObject{}
+	for _, object := range manifest.Objects {
+		if len(typeNames) > 0 && !slices.Contains(typeNames, object.Type) {
+			continue
+		}
+		data, err := readObjectFile(dir, object.File)
+		if err != nil {
+			log.Fatalf("Failed to read object %q of type %v listed in %v: %v", object.ID, object.Type, manifestFile, err)
+		}
+		objects = append(objects, &importedObject{exportedObject: object, Result: "to import", data: data})
+	}
+	if len(objects) == 0 {
+		log.Fatalf("No objects to import")
+	}
+
+	// validate all the objects before importing any
+	invalid := 0
+	if !noValidate {
+		unvalidated := map[string]bool{}
+		for _, object := range objects {
+			if unvalidated[object.Type] {
+				continue
+			}
+			violations, err := validateObject(object.Type, lType, object.data)
+			if err != nil {
+				log.Warnf("The objects of type %v will not be validated before importing them: %v", object.Type, err)
+				unvalidated[object.Type] = true
+				continue
+			}
+			if len(violations) > 0 {
+				object.Result, object.Error = "invalid", strings.Join(violations, "; ")
+				invalid++
+			}
+		}
+	}
+
+	if !dryRun && invalid == 0 {
+		output.PrintCmdStatus(cmd, fmt.Sprintf("Importing %d objects into the %v layer\n", len(objects), lType))
+		forEachParallel(len(objects), parallel, func(i int) {
+			object := objects[i]
+			id := layerID
+			if id == "" {
+				id = getCorrectLayerID(lType, object.Type)
+			}
+			var err error
+			if object.Result, err = importObject(object.Type, object.ID, object.data, lType, id); err != nil {
+				object.Result, object.Error = "failed", err.Error()
+			}
+		})
+	}
+
+	lines := [][]string{}
+	failed := 0
+	for _, object := range objects {
+		lines = append(lines, []string{object.Type, object.ID, object.File, object.Result, object.Error})
+		if object.Error != "" {
+			failed++
+		}
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []*importedObject `json:"items"`
+		Total int               `json:"total"`
+	}{objects, len(objects)}, &output.Table{Headers: []string{"Type", "ID", "File", "Result", "Error"}, Lines: lines})
+	if invalid > 0 {
+		log.Fatalf("%d of %d objects are not valid, nothing was imported; fix them or use --no-validate to import them anyway", invalid, len(objects))
+	}
+	if failed > 0 {
+		log.Fatalf("Failed to import %d of %d objects", failed, len(objects))
+	}
+	if !dryRun {
+		output.PrintCmdStatus(cmd, fmt.Sprintf("Imported %d objects.\n", len(objects)))
+	}
+}
+
+// importObject creates an object at a layer or, if it already exists, updates it, returning which
+func importObject(typeName string, objectID string, data map[string]any, lType string, layerID string) (string, error) {
+	headers := map[string]string{
+		"layer-type": lType,
+		"layer-id":   layerID,
+	}
+	var res any
+	err := api.JSONPost(getObjectListUrl(typeName), data, &res, &api.Options{Headers: headers, Quiet: true, ExpectedErrors: []int{http.StatusConflict}})
+	var statusErr *api.HttpStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusConflict {
+		return "created", err
+	}
+	if err := api.JSONPut(getObjectUrl(typeName, objectID), data, &res, &api.Options{Headers: headers, Quiet: true}); err != nil {
+		return "", err
+	}
+	return "updated", nil
+}
+
+// readObjectFile reads the data of an exported object, from a file relative to the export directory
+func readObjectFile(dir string, file string) (map[string]any, error) {
+	if !filepath.IsLocal(filepath.FromSlash(file)) {
+		return nil, fmt.Errorf("the file %q is not in the export directory", file)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+	if err != nil {
+		return nil, err
+	}
+	var data map[string]any
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &data)
+	default:
+		err = json.Unmarshal(content, &data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %w", file, err)
+	}
+	return data, nil
+}
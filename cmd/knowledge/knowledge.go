@@ -52,24 +52,40 @@ To see the exact API call that fsoc makes, use the --curl flag with the desired
 		Example: `  # Get knowledge object type
   fsoc knowledge get-type --type=<fully-qualified-type-name>
 
+  # Describe knowledge object type, with its properties
+  fsoc knowledge describe-type <fully-qualified-type-name>
+
   # Get object
   fsoc knowledge get --type=<fully-qualified-type-name> --object-id <objectId> --layer-type=SOLUTION|ACCOUNT|GLOBALUSER|TENANT|LOCALUSER [--layer-id=<layerId>]
 
   # Create object
   fsoc knowledge create --type=<fully-qualified-typename> --object-file=<fully-qualified-path> --layer-type=SOLUTION|ACCOUNT|GLOBALUSER|TENANT|LOCALUSER [--layer-id=<layer-id>]
 
+  # Patch object with a JSON patch or a JSON merge patch
+  fsoc knowledge patch --type=<fully-qualified-typename> --object-id=<object-id> --patch=<patch> [--dry-run]
+
   # Delete object
-  fsoc knowledge delete --type=<fully-qualified-typename> --object-id=<object-id> --layer-type=SOLUTION|ACCOUNT|GLOBALUSER|TENANT|LOCALUSER [--layer-id=<layer-id>]`,
+  fsoc knowledge delete --type=<fully-qualified-typename> --object-id=<object-id> --layer-type=SOLUTION|ACCOUNT|GLOBALUSER|TENANT|LOCALUSER [--layer-id=<layer-id>]
+
+  # Export objects into a directory and import them back
+  fsoc knowledge export --type=<fully-qualified-typename> --layer-type=TENANT --dir=<directory>
+  fsoc knowledge import --dir=<directory>`,
 		TraverseChildren: true,
 	}
 
 	knowledgeStoreCmd.AddCommand(newGetObjectCmd())
 	knowledgeStoreCmd.AddCommand(newGetTypeCmd())
+	knowledgeStoreCmd.AddCommand(newDescribeTypeCmd())
 	knowledgeStoreCmd.AddCommand(getCreateObjectCmd())
 	knowledgeStoreCmd.AddCommand(getUpdateObjectCmd())
 	knowledgeStoreCmd.AddCommand(getDeleteObjectCmd())
 	knowledgeStoreCmd.AddCommand(getCreatePatchObjectCmd())
 	knowledgeStoreCmd.AddCommand(editObjectCmd())
+	knowledgeStoreCmd.AddCommand(newExportCmd())
+	knowledgeStoreCmd.AddCommand(newImportCmd())
+	knowledgeStoreCmd.AddCommand(newDiffCmd())
+	knowledgeStoreCmd.AddCommand(newPatchCmd())
+	knowledgeStoreCmd.AddCommand(newWatchCmd())
 
 	return knowledgeStoreCmd
 }
@@ -94,6 +110,38 @@ var objectCompletionFunc = func(cmd *cobra.Command, args []string, toComplete st
 	return getObjectsForType(typeName, layerType, layerID, toComplete), cobra.ShellCompDirectiveNoFileComp
 }
 
+// whereCompletionFunc completes the data fields of --where conditions, from the type's cached definition
+var whereCompletionFunc = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return getTypeProperties(cmd, ""), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+// selectCompletionFunc completes the last of the comma-separated --select fields, including the data
+// fields from the type's cached definition
+var selectCompletionFunc = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	selected := toComplete[:strings.LastIndex(toComplete, ",")+1]
+	fields := []string{}
+	for _, field := range append([]string{"id", "layerType", "layerId", "createdAt", "updatedAt", "data"}, getTypeProperties(cmd, "data.")...) {
+		fields = append(fields, selected+field)
+	}
+	return fields, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+// getTypeProperties returns the names of the properties of the type given with --type, with a prefix
+func getTypeProperties(cmd *cobra.Command, prefix string) (properties []string) {
+	typeName, _ := cmd.Flags().GetString("type")
+	if typeName == "" {
+		return properties
+	}
+	def, err := loadTypeDefinition(typeName, false)
+	if err != nil {
+		return properties
+	}
+	for _, p := range def.properties() {
+		properties = append(properties, prefix+p.Name)
+	}
+	return properties
+}
+
 var layerTypeCompletionFunc = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	return []string{string(solution), string(account), string(globalUser), string(tenant), string(localUser)},
 		cobra.ShellCompDirectiveNoFileComp
@@ -21,7 +21,11 @@ import (
 )
 
 func getCorrectLayerID(layerType string, fqtn string) string {
-	cfg := config.GetCurrentContext()
+	return getLayerIDForContext(config.GetCurrentContext(), layerType, fqtn)
+}
+
+// getLayerIDForContext returns the default layer ID of a layer type for a config context, e.g., its tenant
+func getLayerIDForContext(cfg *config.Context, layerType string, fqtn string) string {
 	var layerID string
 
 	if layerType == "TENANT" {
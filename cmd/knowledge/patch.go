@@ -0,0 +1,205 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package knowledge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/cmdkit/jsondoc"
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+func newPatchCmd() *cobra.Command {
+	ltFlag := tenant
+
+	patchCmd := &cobra.Command{
+		Use:   "patch",
+		Short: "Modify a knowledge object with a JSON patch or a JSON merge patch",
+		Long: `Modify a knowledge object by applying a JSON patch (RFC 6902), i.e., an array of operations, or a JSON merge
+patch (RFC 7386), i.e., an object with the fields to change, null removing a field, to its data.
+
+The patch is given inline with --patch or in a file with --patch-file ("-" for stdin). Its kind is detected from its
+content, unless --json-patch or --json-merge-patch is specified. The object is fetched, patched and updated only if it
+wasn't modified in the meantime. Use --dry-run to display the resulting object and its changes without updating it.
+
+This command updates the object at its own layer; to override an object inherited from another layer, e.g., provided
+by a solution, use "knowledge create-patch" instead.`,
+		Example: `  fsoc knowledge patch --type mysolution:config --object-id base --patch '[{"op":"replace","path":"/size","value":4}]'
+  fsoc knowledge patch --type mysolution:config --object-id base --patch '{"size":4,"obsolete":null}' --dry-run
+  fsoc knowledge patch --type preferences:theme --object-id dark --layer-type LOCALUSER --patch-file theme.patch.json`,
+		Args:             cobra.NoArgs,
+		Run:              patchObject,
+		TraverseChildren: true,
+	}
+
+	patchCmd.Flags().
+		String("type", "", "Fully qualified type name of the object to patch, e.g., extensibility:solution")
+	_ = patchCmd.MarkFlagRequired("type")
+	_ = patchCmd.RegisterFlagCompletionFunc("type", typeCompletionFunc)
+
+	patchCmd.Flags().String("object-id", "", "Object ID of the object to patch")
+	_ = patchCmd.MarkFlagRequired("object-id")
+	_ = patchCmd.RegisterFlagCompletionFunc("object-id", objectCompletionFunc)
+
+	patchCmd.Flags().
+		Var(&ltFlag, "layer-type", fmt.Sprintf("Layer type at which the object exists.  Valid values: %q, %q, %q, %q, %q", solution, account, globalUser, tenant, localUser))
+	_ = patchCmd.RegisterFlagCompletionFunc("layer-type", layerTypeCompletionFunc)
+	patchCmd.Flags().String("layer-id", "", "Layer ID at which the object exists. Optional for all layers but SOLUTION")
+
+	patchCmd.Flags().String("patch", "", "Patch to apply, in JSON")
+	patchCmd.Flags().String("patch-file", "", "File containing the patch to apply, in JSON; \"-\" for stdin")
+	patchCmd.MarkFlagsMutuallyExclusive("patch", "patch-file")
+	patchCmd.Flags().Bool("json-patch", false, "Apply the patch as a JSON patch (RFC 6902)")
+	patchCmd.Flags().Bool("json-merge-patch", false, "Apply the patch as a JSON merge patch (RFC 7386)")
+	patchCmd.MarkFlagsMutuallyExclusive("json-patch", "json-merge-patch")
+	patchCmd.Flags().Bool("dry-run", false, "Display the resulting object and its changes, without updating it")
+	addNoValidateFlag(patchCmd)
+
+	return patchCmd
+}
+
+func patchObject(cmd *cobra.Command, args []string) {
+	patch, err := readPatch(cmd)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	useJsonPatch, _ := cmd.Flags().GetBool("json-patch")
+	useJsonMergePatch, _ := cmd.Flags().GetBool("json-merge-patch")
+	if !useJsonPatch && !useJsonMergePatch {
+		useJsonPatch = bytes.HasPrefix(patch, []byte("["))
+	}
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	fqtn, objID, layerID, lType, err := parseObjectInfo(cmd)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	headers := map[string]string{
+		"layer-type": lType,
+		"layer-id":   layerID,
+	}
+	httpOptions := &api.Options{Headers: headers}
+	url := getObjectUrl(fqtn, objID)
+	var object KSObject
+	if err := api.JSONGet(url, &object, httpOptions); err != nil {
+		log.Fatalf("Failed to fetch object: %v", err)
+	}
+
+	// apply the patch to a copy, to compare with the original data
+	var data any
+	raw, err := json.Marshal(object.Data)
+	if err == nil {
+		err = json.Unmarshal(raw, &data)
+	}
+	if err != nil {
+		log.Fatalf("Failed to copy the object data: %v", err)
+	}
+	if useJsonPatch {
+		data, err = jsondoc.ApplyPatch(data, patch)
+	} else {
+		var mergePatch any
+		if err = json.Unmarshal(patch, &mergePatch); err != nil {
+			err = fmt.Errorf("invalid JSON merge patch: %w", err)
+		}
+		data = applyMergePatch(data, mergePatch)
+	}
+	if err != nil {
+		log.Fatalf("Failed to apply the patch: %v", err)
+	}
+	patched, ok := data.(map[string]any)
+	if !ok {
+		log.Fatalf("The patched object data is not a JSON object")
+	}
+
+	changes := jsondoc.Diff(patched, object.Data)
+	if len(changes) == 0 {
+		output.PrintCmdStatus(cmd, fmt.Sprintf("The patch doesn't change object %q; nothing to update.\n", objID))
+		return
+	}
+	checkObject(cmd, fqtn, lType, patched)
+	var sb strings.Builder
+	for _, c := range changes {
+		sb.WriteString(fmt.Sprintf("  %v %v\n", c.Change, c.Path))
+	}
+	if dryRun {
+		output.PrintCmdStatus(cmd, fmt.Sprintf("Patching object %q would make %d changes:\n%v", objID, len(changes), sb.String()))
+		output.PrintCmdOutput(cmd, patched)
+		return
+	}
+
+	// update the object only if it wasn't modified since it was fetched
+	etagHeader := httpOptions.ResponseHeaders["Etag"]
+	if len(etagHeader) != 1 || etagHeader[0] == "" {
+		log.Fatalf("etag not found in response headers")
+	}
+	headers["If-Match"] = etagHeader[0]
+	var res any
+	if err := api.JSONPut(url, patched, &res, &api.Options{Headers: headers}); err != nil {
+		log.Fatalf("Knowledge object update failed: %v", err)
+	}
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Patched object %q with %d changes:\n%v", objID, len(changes), sb.String()))
+}
+
+// readPatch returns the patch given inline or in a file
+func readPatch(cmd *cobra.Command) ([]byte, error) {
+	patch, _ := cmd.Flags().GetString("patch")
+	patchFile, _ := cmd.Flags().GetString("patch-file")
+	var content []byte
+	var err error
+	switch {
+	case patchFile == "-":
+		content, err = io.ReadAll(os.Stdin)
+	case patchFile != "":
+		content, err = os.ReadFile(patchFile)
+	case patch != "":
+		content = []byte(patch)
+	default:
+		return nil, fmt.Errorf("a patch is required: specify it with --patch or --patch-file")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the patch file %q: %w", patchFile, err)
+	}
+	return bytes.TrimSpace(content), nil
+}
+
+// applyMergePatch applies a JSON merge patch (RFC 7386) to a value: objects are merged recursively,
+// with null removing a field, and any other patch value replaces the target value
+func applyMergePatch(target any, patch any) any {
+	patchFields, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetFields, ok := target.(map[string]any)
+	if !ok {
+		targetFields = map[string]any{}
+	}
+	for name, value := range patchFields {
+		if value == nil {
+			delete(targetFields, name)
+		} else {
+			targetFields[name] = applyMergePatch(targetFields[name], value)
+		}
+	}
+	return targetFields
+}
@@ -44,7 +44,8 @@ var objStoreUpdateCmd = &cobra.Command{
 	--object-id - Flag to indicate the ID of the knowledge object that you want to update
 	--object-file - Flag to indicate the fully qualified path (from your root directory) to the file containing the definition of the knowledge object that you want to update. Please note that update internally calls HTTP PUT so you will need to specify all fields in the knowledge object (even if you are updating just one field)
 	--layer-type - Flag to indicate the layer at which the knowledge object you would like to update exists
-	--layer-id - OPTIONAL Flag to specify a custom layer ID for the knowledge object that you would like to update.  This is calculated automatically for all layers currently supported but can be overridden with this flag`,
+	--layer-id - OPTIONAL Flag to specify a custom layer ID for the knowledge object that you would like to update.  This is calculated automatically for all layers currently supported but can be overridden with this flag
+	--no-validate - OPTIONAL Flag to send the object without validating it against the JSON schema of its type first`,
 
 	Args:             cobra.ExactArgs(0),
 	Run:              updateObject,
@@ -74,6 +75,8 @@ func getUpdateObjectCmd() *cobra.Command {
 	objStoreUpdateCmd.Flags().
 		String("layer-id", "", "The layer-id of the knowledge object to update. Optional for TENANT and SOLUTION layers ")
 
+	addNoValidateFlag(objStoreUpdateCmd)
+
 	return objStoreUpdateCmd
 
 }
@@ -113,6 +116,8 @@ func updateObject(cmd *cobra.Command, args []string) {
 		"layer-id":   layerID,
 	}
 
+	checkObject(cmd, objType, layerType, objectStruct)
+
 	var res any
 	objId, _ := cmd.Flags().GetString("object-id")
 	urlStrf := getObjStoreObjectUrl() + "/%s/%s"
@@ -0,0 +1,83 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package knowledge
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/cisco-open/fsoc/cmdkit/jsondoc"
+)
+
+// addNoValidateFlag adds the flag that skips the validation of objects before sending them
+func addNoValidateFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("no-validate", false, "Send the object without validating it against its type's JSON schema first")
+}
+
+// validateObject validates the data of an object to create or update at a layer against the JSON schema
+// and allowed layers of its type, from the cached type definition. It returns the violations found, each
+// with the JSON pointer of the offending value, e.g., "/size: Must be greater than or equal to 1".
+func validateObject(fqtn string, lType string, data any) ([]string, error) {
+	def, err := loadTypeDefinition(fqtn, false)
+	if err != nil {
+		return nil, err
+	}
+
+	violations := []string{}
+	if len(def.AllowedLayers) > 0 && !slices.Contains(def.AllowedLayers, lType) {
+		violations = append(violations, fmt.Sprintf("objects of type %v cannot be stored at the %v layer; allowed layers: %v", fqtn, lType, strings.Join(def.AllowedLayers, ", ")))
+	}
+	if def.JsonSchema == nil {
+		return violations, nil
+	}
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(def.JsonSchema))
+	if err != nil {
+		return nil, fmt.Errorf("the JSON schema of type %v is invalid: %w", fqtn, err)
+	}
+	result, err := schema.Validate(gojsonschema.NewGoLoader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate the object: %w", err)
+	}
+	for _, resultErr := range result.Errors() {
+		violations = append(violations, jsondoc.FormatSchemaPointerError(resultErr))
+	}
+	return violations, nil
+}
+
+// checkObject validates an object before creating or updating it, unless --no-validate is specified,
+// exiting with the violations found. If the type definition cannot be loaded, the object is not
+// validated and is left for the knowledge store to check.
+func checkObject(cmd *cobra.Command, fqtn string, lType string, data any) {
+	if noValidate, _ := cmd.Flags().GetBool("no-validate"); noValidate {
+		return
+	}
+	violations, err := validateObject(fqtn, lType, data)
+	if err != nil {
+		log.Warnf("The object will not be validated before sending it: %v", err)
+		return
+	}
+	if len(violations) == 0 {
+		return
+	}
+	for _, violation := range violations {
+		log.Errorf("  %v", violation)
+	}
+	log.Fatalf("The object is not valid for type %v; fix it or use --no-validate to send it anyway", fqtn)
+}
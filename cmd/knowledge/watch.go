@@ -0,0 +1,153 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package knowledge
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/output"
+)
+
+// watchEvent is a change of a watched knowledge object, as found when polling
+type watchEvent struct {
+	Time time.Time `json:"time"`
+	objectDifference
+}
+
+func newWatchCmd() *cobra.Command {
+	ltFlag := tenant
+
+	watchCmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Watch knowledge objects for changes",
+		Long: `Watch a knowledge object, or all the objects of a type, at a layer and display their changes as they occur, until
+interrupted, e.g., to find out what a controller or a UI edit modifies behind your back.
+
+The objects are polled every --interval and compared with their previous version: objects that were created
+("added") or deleted ("removed") and the fields that were added, removed or modified are displayed, with their old
+and new values. The objects are seen as they apply at the layer, including the objects and values inherited from
+other layers. Only the data of the objects is compared.
+
+With --output json, each change is displayed as a JSON object on its own line.`,
+		Example: `  fsoc knowledge watch --type mysolution:config
+  fsoc knowledge watch --type preferences:theme --object-id dark --layer-type LOCALUSER --interval 2s
+  fsoc knowledge watch --type mysolution:config --output json > changes.jsonl`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			watchObjects(cmd, ltFlag)
+		},
+		TraverseChildren: true,
+	}
+
+	watchCmd.Flags().
+		String("type", "", "Fully qualified type name of the objects to watch, e.g., extensibility:solution")
+	_ = watchCmd.MarkFlagRequired("type")
+	_ = watchCmd.RegisterFlagCompletionFunc("type", typeCompletionFunc)
+
+	watchCmd.Flags().String("object-id", "", "Object ID of the object to watch (default: all the objects of the type)")
+	_ = watchCmd.RegisterFlagCompletionFunc("object-id", objectCompletionFunc)
+
+	watchCmd.Flags().
+		Var(&ltFlag, "layer-type", fmt.Sprintf("Layer type of the objects to watch.  Valid values: %q, %q, %q, %q, %q", solution, account, globalUser, tenant, localUser))
+	_ = watchCmd.RegisterFlagCompletionFunc("layer-type", layerTypeCompletionFunc)
+	watchCmd.Flags().String("layer-id", "", "Layer ID of the objects to watch. Optional for all layers but SOLUTION")
+	watchCmd.Flags().Duration("interval", 5*time.Second, "Interval between polls of the objects")
+
+	return watchCmd
+}
+
+func watchObjects(cmd *cobra.Command, ltFlag layerType) {
+	fqtn, _ := cmd.Flags().GetString("type")
+	objID, _ := cmd.Flags().GetString("object-id")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	if interval <= 0 {
+		log.Fatalf("Invalid --interval %v, must be positive", interval)
+	}
+	side := diffSide{layerType: ltFlag.String()}
+	side.layerID, _ = cmd.Flags().GetString("layer-id")
+	if err := side.resolve(fqtn); err != nil {
+		log.Fatal(err.Error())
+	}
+	format, _ := cmd.Flags().GetString("output")
+
+	objects, err := side.fetchObjects(fqtn, objID)
+	if err != nil {
+		log.Fatalf("Failed to fetch the objects: %v", err)
+	}
+	what := fmt.Sprintf("%d objects of type %v", len(objects), fqtn)
+	if objID != "" {
+		what = fmt.Sprintf("object %q of type %v", objID, fqtn)
+	}
+	log.Infof("Watching %v at the %v layer every %v; press Ctrl-C to stop", what, side.label, interval)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-interrupt:
+			return
+		case <-ticker.C:
+		}
+
+		current, err := side.fetchObjects(fqtn, objID)
+		if err != nil {
+			log.Errorf("Failed to fetch the objects, will retry in %v: %v", interval, err)
+			continue
+		}
+		now := time.Now()
+		for _, d := range diffObjectSets(fqtn, objects, current) {
+			printWatchEvent(cmd, format, watchEvent{Time: now, objectDifference: d})
+		}
+		objects = current
+	}
+}
+
+// printWatchEvent displays a change as a JSON line or, for the other output formats, as a line of text
+func printWatchEvent(cmd *cobra.Command, format string, event watchEvent) {
+	if format == "json" {
+		data, err := json.Marshal(event)
+		if err != nil {
+			log.Warnf("Failed to format change of object %q: %v", event.Object, err)
+			return
+		}
+		output.PrintCmdStatus(cmd, string(data)+"\n")
+		return
+	}
+	line := fmt.Sprintf("%v %-8v %v", event.Time.Format(time.TimeOnly), event.Change, event.Object)
+	if event.Field != "" {
+		line += fmt.Sprintf(" %v: %v -> %v", event.Field, formatWatchValue(event.From), formatWatchValue(event.To))
+	}
+	output.PrintCmdStatus(cmd, line+"\n")
+}
+
+// formatWatchValue formats a value for a line of text, showing absent values
+func formatWatchValue(v any) string {
+	if v == nil {
+		return "(none)"
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,372 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package melt
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/maps"
+	"gopkg.in/yaml.v2"
+
+	"github.com/cisco-open/fsoc/platform/melt"
+)
+
+// csvMapping maps the columns of a CSV or TSV file to MELT data: each row has the attributes of an
+// entity (rows with the same attributes are the same entity), with data points of its metrics, an
+// event or a log at the row's timestamp.
+//
+// Attribute values are the names of columns, optionally followed by the attribute type, e.g.,
+// "restarts:long" (string, long, double or boolean; string by default), or constant values
+// prefixed with "=", e.g., "=production".
+type csvMapping struct {
+	Entity    csvEntityMapping   `yaml:"entity"`
+	Timestamp csvTimestamp       `yaml:"timestamp,omitempty"`
+	Interval  time.Duration      `yaml:"interval,omitempty"` // of the data points, ending at the timestamp
+	Metrics   []csvMetricMapping `yaml:"metrics,omitempty"`
+	Events    []csvEventMapping  `yaml:"events,omitempty"`
+	Logs      []csvLogMapping    `yaml:"logs,omitempty"`
+}
+
+type csvEntityMapping struct {
+	Type       string            `yaml:"type"`
+	Attributes map[string]string `yaml:"attributes"`
+}
+
+type csvTimestamp struct {
+	Column string `yaml:"column"`
+	Format string `yaml:"format,omitempty"` // rfc3339 (default), unix, unix_ms, unix_ns or a Go time layout
+}
+
+type csvMetricMapping struct {
+	Type        string            `yaml:"type"`
+	Column      string            `yaml:"column"`
+	Unit        string            `yaml:"unit,omitempty"`
+	ContentType string            `yaml:"contentType,omitempty"` // gauge (default) or sum
+	ValueType   string            `yaml:"valueType,omitempty"`   // double (default) or long
+	Attributes  map[string]string `yaml:"attributes,omitempty"`
+}
+
+type csvEventMapping struct {
+	Type       string            `yaml:"type"`
+	Attributes map[string]string `yaml:"attributes"`
+}
+
+type csvLogMapping struct {
+	Body       string            `yaml:"body"`
+	Severity   string            `yaml:"severity,omitempty"`
+	Attributes map[string]string `yaml:"attributes,omitempty"`
+}
+
+// csvAttributeTypes are the types of attribute values
+var csvAttributeTypes = []string{"string", "long", "double", "boolean"}
+
+// csvRow is a row of a CSV file, with the columns by name
+type csvRow struct {
+	number  int
+	columns map[string]int
+	values  []string
+}
+
+func loadCsvMapping(fileName string) (*csvMapping, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var mapping csvMapping
+	if err := yaml.UnmarshalStrict(content, &mapping); err != nil {
+		return nil, fmt.Errorf("failed to parse mapping file %q: %w", fileName, err)
+	}
+	if mapping.Entity.Type == "" {
+		return nil, fmt.Errorf("the mapping file %q has no entity type", fileName)
+	}
+	if mapping.Interval == 0 {
+		mapping.Interval = time.Minute
+	}
+	return &mapping, nil
+}
+
+// columns returns the columns referenced by the mapping
+func (m *csvMapping) columns() []string {
+	columns := []string{}
+	addAttributes := func(attributes map[string]string) {
+		for _, value := range attributes {
+			if column, _ := splitAttributeType(value); !strings.HasPrefix(value, "=") {
+				columns = append(columns, column)
+			}
+		}
+	}
+	add := func(value string) {
+		if value != "" && !strings.HasPrefix(value, "=") {
+			columns = append(columns, value)
+		}
+	}
+	addAttributes(m.Entity.Attributes)
+	add(m.Timestamp.Column)
+	for _, metric := range m.Metrics {
+		add(metric.Column)
+		addAttributes(metric.Attributes)
+	}
+	for _, event := range m.Events {
+		addAttributes(event.Attributes)
+	}
+	for _, l := range m.Logs {
+		add(l.Body)
+		add(l.Severity)
+		addAttributes(l.Attributes)
+	}
+	slices.Sort(columns)
+	return slices.Compact(columns)
+}
+
+// parseCsvData converts the rows of a CSV or TSV file into MELT data, as specified by the mapping
+func parseCsvData(dataBytes []byte, delimiter rune, mapping *csvMapping) (*melt.FsocData, error) {
+	reader := csv.NewReader(bytes.NewReader(dataBytes))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = delimiter == '\t'
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	var missing []string
+	for _, column := range mapping.columns() {
+		if _, found := columns[column]; !found {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("columns %v of the mapping are not in the file's header %v", missing, header)
+	}
+
+	data := &melt.FsocData{Melt: []*melt.Entity{}}
+	entities := map[string]*melt.Entity{}
+	metrics := map[*melt.Entity]map[string]*melt.Metric{}
+	var errs []error
+	for number := 2; ; number++ {
+		values, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := &csvRow{number: number, columns: columns, values: values}
+
+		// the entity, identified by its attributes
+		attributes, err := row.attributes(mapping.Entity.Attributes)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		key := attributesKey(mapping.Entity.Type, attributes)
+		entity, found := entities[key]
+		if !found {
+			entity = melt.NewEntity(mapping.Entity.Type)
+			entity.Attributes = attributes
+			entities[key] = entity
+			metrics[entity] = map[string]*melt.Metric{}
+			data.Melt = append(data.Melt, entity)
+		}
+
+		var timestamp time.Time
+		if mapping.Timestamp.Column != "" {
+			if timestamp, err = parseCsvTimestamp(row.get(mapping.Timestamp.Column), mapping.Timestamp.Format); err != nil {
+				errs = append(errs, fmt.Errorf("row %d: %w", number, err))
+				continue
+			}
+		}
+		if err := row.addMetrics(entity, metrics[entity], mapping, timestamp); err != nil {
+			errs = append(errs, err)
+		}
+		if err := row.addEvents(entity, mapping, timestamp); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return data, errors.Join(errs...)
+}
+
+func (r *csvRow) get(column string) string {
+	if i := r.columns[column]; i < len(r.values) {
+		return strings.TrimSpace(r.values[i])
+	}
+	return ""
+}
+
+// attributes returns the values of mapped attributes, omitting those with empty cells
+func (r *csvRow) attributes(mapping map[string]string) (map[string]any, error) {
+	attributes := map[string]any{}
+	for name, spec := range mapping {
+		if value, isConstant := strings.CutPrefix(spec, "="); isConstant {
+			attributes[name] = value
+			continue
+		}
+		column, attrType := splitAttributeType(spec)
+		value := r.get(column)
+		if value == "" {
+			continue
+		}
+		var err error
+		switch attrType {
+		case "long":
+			attributes[name], err = strconv.ParseInt(value, 10, 64)
+		case "double":
+			attributes[name], err = strconv.ParseFloat(value, 64)
+		case "boolean":
+			attributes[name], err = strconv.ParseBool(value)
+		default:
+			attributes[name] = value
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: invalid %v value %q for attribute %q", r.number, attrType, value, name)
+		}
+	}
+	return attributes, nil
+}
+
+func (r *csvRow) addMetrics(entity *melt.Entity, metrics map[string]*melt.Metric, mapping *csvMapping, timestamp time.Time) error {
+	for _, metricMapping := range mapping.Metrics {
+		cell := r.get(metricMapping.Column)
+		if cell == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(cell, 64)
+		if err != nil {
+			return fmt.Errorf("row %d: invalid value %q for metric %q", r.number, cell, metricMapping.Type)
+		}
+		attributes, err := r.attributes(metricMapping.Attributes)
+		if err != nil {
+			return err
+		}
+
+		// data points of the same metric with the same attributes belong to the same metric
+		key := attributesKey(metricMapping.Type, attributes)
+		metric, found := metrics[key]
+		if !found {
+			contentType, valueType := metricMapping.ContentType, metricMapping.ValueType
+			if contentType == "" {
+				contentType = "gauge"
+			}
+			if valueType == "" {
+				valueType = "double"
+			}
+			metric = melt.NewMetric(metricMapping.Type, metricMapping.Unit, contentType, valueType)
+			metric.Attributes = attributes
+			metrics[key] = metric
+			entity.AddMetric(metric)
+		}
+		if timestamp.IsZero() {
+			metric.AddDataPoint(0, 0, value) // timestamps are assigned when sending
+		} else {
+			metric.AddDataPoint(timestamp.Add(-mapping.Interval).UnixNano(), timestamp.UnixNano(), value)
+		}
+	}
+	return nil
+}
+
+func (r *csvRow) addEvents(entity *melt.Entity, mapping *csvMapping, timestamp time.Time) error {
+	for _, eventMapping := range mapping.Events {
+		attributes, err := r.attributes(eventMapping.Attributes)
+		if err != nil {
+			return err
+		}
+		if len(attributes) == 0 {
+			continue
+		}
+		event := melt.NewEvent(eventMapping.Type)
+		event.Attributes = attributes
+		if !timestamp.IsZero() {
+			event.Timestamp = timestamp.UnixNano()
+		}
+		entity.AddLog(event)
+	}
+	for _, logMapping := range mapping.Logs {
+		body := r.get(logMapping.Body)
+		if body == "" {
+			continue
+		}
+		attributes, err := r.attributes(logMapping.Attributes)
+		if err != nil {
+			return err
+		}
+		l := melt.NewLog()
+		l.Body = body
+		l.Attributes = attributes
+		if severity, isConstant := strings.CutPrefix(logMapping.Severity, "="); isConstant {
+			l.Severity = severity
+		} else if logMapping.Severity != "" {
+			l.Severity = r.get(logMapping.Severity)
+		}
+		if !timestamp.IsZero() {
+			l.Timestamp = timestamp.UnixNano()
+		}
+		entity.AddLog(l)
+	}
+	return nil
+}
+
+// splitAttributeType splits an attribute's column from its type, if specified
+func splitAttributeType(spec string) (string, string) {
+	if i := strings.LastIndex(spec, ":"); i >= 0 && slices.Contains(csvAttributeTypes, spec[i+1:]) {
+		return spec[:i], spec[i+1:]
+	}
+	return spec, "string"
+}
+
+// attributesKey identifies a type of entity or metric with the given attribute values
+func attributesKey(typeName string, attributes map[string]any) string {
+	names := maps.Keys(attributes)
+	slices.Sort(names)
+	var sb strings.Builder
+	sb.WriteString(typeName)
+	for _, name := range names {
+		fmt.Fprintf(&sb, "\x00%s=%v", name, attributes[name])
+	}
+	return sb.String()
+}
+
+func parseCsvTimestamp(value string, format string) (time.Time, error) {
+	switch strings.ToLower(format) {
+	case "", "rfc3339":
+		return time.Parse(time.RFC3339Nano, value)
+	case "unix", "unix_ms", "unix_ns":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %v timestamp %q", format, value)
+		}
+		switch strings.ToLower(format) {
+		case "unix":
+			return time.Unix(n, 0), nil
+		case "unix_ms":
+			return time.UnixMilli(n), nil
+		default:
+			return time.Unix(0, n), nil
+		}
+	default:
+		return time.Parse(format, value)
+	}
+}
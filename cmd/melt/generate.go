@@ -0,0 +1,598 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package melt
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/maps"
+	"gopkg.in/yaml.v2"
+
+	sol "github.com/cisco-open/fsoc/cmd/solution"
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/melt"
+)
+
+var meltGenerateCmd = &cobra.Command{
+	Use:   "generate {--from-solution <dir> | --scenario <name>}",
+	Short: "Generate realistic telemetry for a solution's entity model or a scenario",
+	Long: `This command fabricates metrics, logs and events for the FMM entities, metrics and events defined in a
+solution, over a period of time ending now, and writes them into a fsoc telemetry data file, to be sent
+with "fsoc melt send", e.g., to populate a demo tenant or to put load on the solution.
+
+The number of entities of each entity type sets the cardinality of the data. Metric values stay within a
+realistic range for their unit, drift randomly and follow a seasonal cycle (e.g., daily) of the given
+amplitude; monotonic sums only increase. Logs and events are spread randomly over the period; the error
+rate is the fraction of logs and events that report errors, and the level of metrics about errors or
+failures.
+
+The model is read as it is in the solution's files; solutions with pseudo-isolation are not supported.
+
+Instead of a solution, the data can follow a scenario (--scenario), e.g., a Kubernetes cluster with a
+number of pods or a service with an error spike, that defines its own entity types, metrics and events,
+the number of entities of each type and incidents: periods of time during which the error rate or the
+metric values of entities change. Scenarios come with fsoc (see --list-scenarios), from a directory of
+custom scenarios (--scenario-dir) or from a file. Scenarios have parameters, set with --param, and can
+have defaults for the other flags, e.g., --duration.
+
+A scenario file is a Go template of a YAML document, with parameters set by {{param "name" default}}:
+
+    description: Service with an error spike
+    defaults:
+      duration: 2h                        # any flag of this command
+    entities:
+      - type: apm:service
+        count: {{param "services" 3}}     # defaults to --entities
+        attributes:
+          name: string                    # or long, double, boolean; qualified as apm.service.name
+        metrics: [apm:errors]
+    metrics:
+      apm:errors:
+        unit: "{errors}"
+        contentType: sum                  # or gauge (default), distribution
+        type: long                        # or double (default)
+    events: {}
+    incidents:
+      - start: {{param "spike-at" "-20m"}}  # after the start, or before the end if negative
+        duration: 10m
+        entity: apm:service               # all entities if not set
+        errorRate: 0.5
+        metrics:
+          apm:errors: 10                  # factor of the metric's values`,
+	Example: `  fsoc melt generate --from-solution ./mysolution --entities 50 --duration 1h
+  fsoc melt generate --from-solution . --duration 24h --interval 5m --seasonality 24h --amplitude 0.5
+  fsoc melt generate --from-solution . --error-rate 0.2 --output-file - | fsoc melt send --profile myagent
+  fsoc melt generate --list-scenarios
+  fsoc melt generate --scenario k8s-cluster --param pods=50,nodes=5
+  fsoc melt generate --scenario myscenario --scenario-dir ./scenarios`,
+	Args:        cobra.ExactArgs(0),
+	Run:         meltGenerate,
+	Annotations: map[string]string{config.AnnotationForConfigBypass: ""},
+}
+
+func init() {
+	meltGenerateCmd.Flags().String("from-solution", "", "Path to the root directory of the solution whose model to generate data for")
+	meltGenerateCmd.Flags().Int("entities", 10, "Number of entities of each entity type")
+	meltGenerateCmd.Flags().Duration("duration", time.Hour, "Period of time to generate data for, ending now")
+	meltGenerateCmd.Flags().Duration("interval", time.Minute, "Interval between the data points of each metric")
+	meltGenerateCmd.Flags().Duration("seasonality", 24*time.Hour, "Period of the seasonal cycle of metric values (0 for none)")
+	meltGenerateCmd.Flags().Float64("amplitude", 0.3, "Amplitude of the seasonal cycle, relative to the metric values")
+	meltGenerateCmd.Flags().Float64("error-rate", 0.05, "Fraction of logs and events reporting errors, from 0 to 1")
+	meltGenerateCmd.Flags().Float64("logs-per-hour", 30, "Number of logs per entity per hour")
+	meltGenerateCmd.Flags().Float64("events-per-hour", 6, "Number of events of each event type per entity per hour")
+	meltGenerateCmd.Flags().Int64("seed", 0, "Seed for the random values, to generate the same data every time (defaults to a random seed)")
+	meltGenerateCmd.Flags().String("output-file", "", `File to write the data into, "-" for stdout (defaults to <solution>-generated.yaml)`)
+	meltGenerateCmd.Flags().String("scenario", "", "Name or file of the scenario to generate data for, instead of a solution's model")
+	meltGenerateCmd.Flags().String("scenario-dir", "", "Directory with custom scenarios, one <name>.yaml file per scenario")
+	meltGenerateCmd.Flags().StringToString("param", nil, "Values of the scenario's parameters, e.g., pods=50")
+	meltGenerateCmd.Flags().Bool("list-scenarios", false, "List the built-in scenarios and those in the scenario directory")
+	meltGenerateCmd.MarkFlagsMutuallyExclusive("from-solution", "scenario")
+
+	meltCmd.AddCommand(meltGenerateCmd)
+}
+
+// generateOptions are the settings of the generated telemetry
+type generateOptions struct {
+	entities      int           // entities of each entity type
+	interval      time.Duration // between data points
+	seasonality   time.Duration // period of the seasonal cycle, 0 for none
+	amplitude     float64       // of the seasonal cycle, relative to the values
+	errorRate     float64
+	logsPerHour   float64        // per entity
+	eventsPerHour float64        // per entity and event type
+	counts        map[string]int // entities of the entity types with their own count
+	incidents     []incident
+}
+
+// incident is a period of time during which the telemetry of entities deviates, e.g., an error spike
+type incident struct {
+	from, to   time.Time
+	entityType string             // "" for all entities
+	errorRate  *float64           // nil to keep the error rate
+	metrics    map[string]float64 // factors of the metric values, by metric type
+}
+
+// telemetryGenerator fabricates telemetry for the entities of a FMM model; the entities and the
+// state of their metrics are kept across calls to generate, so that consecutive periods of time
+// continue each other
+type telemetryGenerator struct {
+	model    *sol.FmmModel
+	options  generateOptions
+	rand     *rand.Rand
+	entities []*generatedEntity
+}
+
+// generatedEntity is an entity with its attributes and the current state of its metrics
+type generatedEntity struct {
+	fmmEntity  *sol.FmmEntity
+	typeName   string
+	attributes map[string]any
+	metrics    map[string]*metricState // by metric type
+}
+
+// metricState is the current value of a metric of an entity
+type metricState struct {
+	low, high  float64 // realistic range of values for the unit
+	level      float64 // baseline value, drifting randomly
+	cumulative float64 // for monotonic sums
+	bounded    bool    // whether values can't exceed the range even during incidents, e.g., percentages
+}
+
+// errorKeywords identify metrics and attributes about errors or failures
+var errorKeywords = []string{"error", "fail", "fault", "exception"}
+
+func meltGenerate(cmd *cobra.Command, args []string) {
+	if list, _ := cmd.Flags().GetBool("list-scenarios"); list {
+		scenarioDir, _ := cmd.Flags().GetString("scenario-dir")
+		listMeltScenarios(cmd, scenarioDir)
+		return
+	}
+
+	// load the model, from the solution or the scenario, whose defaults apply to the flags below
+	name, model, scenario := loadGenerateModel(cmd)
+
+	duration, _ := cmd.Flags().GetDuration("duration")
+	options := getGenerateOptions(cmd)
+	if duration < options.interval {
+		log.Fatalf("Invalid options: the duration must be at least the interval between data points")
+	}
+	seed, _ := cmd.Flags().GetInt64("seed")
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	end := time.Now().Truncate(options.interval)
+	if scenario != nil {
+		options.counts = scenario.entityCounts()
+		var err error
+		if options.incidents, err = scenario.incidents(end.Add(-duration), end); err != nil {
+			log.Fatalf("Invalid scenario %q: %v", name, err)
+		}
+	}
+	generator := newTelemetryGenerator(model, options, rand.New(rand.NewSource(seed)))
+	data := generator.generate(end.Add(-duration), end)
+
+	// write the data
+	outputFile, _ := cmd.Flags().GetString("output-file")
+	if outputFile == "" {
+		outputFile = fmt.Sprintf("%s-generated.yaml", name)
+	}
+	var w io.Writer = cmd.OutOrStdout()
+	if outputFile != "-" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			log.Fatalf("Failed to create file %q: %v", outputFile, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	content, err := yaml.Marshal(data)
+	if err != nil {
+		log.Fatalf("(bug) Failed to marshal the generated data: %v", err)
+	}
+	if _, err := w.Write(content); err != nil {
+		log.Fatalf("Failed to write the generated data: %v", err)
+	}
+	if outputFile != "-" {
+		dataPoints, logs, events := countTelemetry(data)
+		output.PrintCmdStatus(cmd, fmt.Sprintf("Generated %d entities with %d data points, %d logs and %d events over %v into %v\nUse \"fsoc melt send %v\" to send the data\n",
+			len(data.Melt), dataPoints, logs, events, duration, outputFile, outputFile))
+	}
+}
+
+// loadGenerateModel returns the name and the FMM model of the solution (--from-solution) or the
+// scenario (--scenario) to generate data for, with the scenario, if any, whose defaults are applied
+// to the flags of the command, except for the ignored ones
+func loadGenerateModel(cmd *cobra.Command, ignoredDefaults ...string) (string, *sol.FmmModel, *meltScenario) {
+	solutionDirectory, _ := cmd.Flags().GetString("from-solution")
+	scenarioSource, _ := cmd.Flags().GetString("scenario")
+	scenarioDir, _ := cmd.Flags().GetString("scenario-dir")
+	switch {
+	case solutionDirectory != "":
+		manifest, model := loadSolutionModel(solutionDirectory)
+		return manifest.Name, model, nil
+	case scenarioSource != "":
+		params, _ := cmd.Flags().GetStringToString("param")
+		name, scenario, err := loadScenario(scenarioSource, scenarioDir, params)
+		if err != nil {
+			log.Fatalf("Failed to load the scenario: %v", err)
+		}
+		for _, flag := range ignoredDefaults {
+			delete(scenario.Defaults, flag)
+		}
+		if err := scenario.applyDefaults(cmd); err != nil {
+			log.Fatalf("Failed to apply the defaults of scenario %q: %v", name, err)
+		}
+		model, err := scenario.fmmModel()
+		if err != nil {
+			log.Fatalf("Invalid scenario %q: %v", name, err)
+		}
+		return name, model, scenario
+	default:
+		log.Fatalf("Either --from-solution or --scenario is required")
+		return "", nil, nil
+	}
+}
+
+// getGenerateOptions returns the settings of the generated telemetry from the command line
+func getGenerateOptions(cmd *cobra.Command) generateOptions {
+	options := generateOptions{}
+	options.entities, _ = cmd.Flags().GetInt("entities")
+	options.interval, _ = cmd.Flags().GetDuration("interval")
+	options.seasonality, _ = cmd.Flags().GetDuration("seasonality")
+	options.amplitude, _ = cmd.Flags().GetFloat64("amplitude")
+	options.errorRate, _ = cmd.Flags().GetFloat64("error-rate")
+	options.logsPerHour, _ = cmd.Flags().GetFloat64("logs-per-hour")
+	options.eventsPerHour, _ = cmd.Flags().GetFloat64("events-per-hour")
+	if err := options.validate(); err != nil {
+		log.Fatalf("Invalid options: %v", err)
+	}
+	return options
+}
+
+func (o generateOptions) validate() error {
+	switch {
+	case o.entities < 1:
+		return fmt.Errorf("the number of entities must be at least 1")
+	case o.interval <= 0:
+		return fmt.Errorf("the interval between data points must be positive")
+	case o.seasonality < 0 || o.amplitude < 0 || o.amplitude > 1:
+		return fmt.Errorf("the seasonality cannot be negative and its amplitude must be between 0 and 1")
+	case o.errorRate < 0 || o.errorRate > 1:
+		return fmt.Errorf("the error rate must be between 0 and 1")
+	case o.logsPerHour < 0 || o.eventsPerHour < 0:
+		return fmt.Errorf("the numbers of logs and events per hour cannot be negative")
+	}
+	return nil
+}
+
+// loadSolutionModel reads the manifest and the FMM model of the solution in a directory
+func loadSolutionModel(solutionDirectory string) (*sol.Manifest, *sol.FmmModel) {
+	solutionDirectory, err := filepath.Abs(solutionDirectory)
+	if err != nil {
+		log.Fatalf("Error getting solution directory: %v", err)
+	}
+	manifest, err := sol.GetManifest(solutionDirectory)
+	if err != nil {
+		log.Fatalf("Failed to get manifest: %v", err)
+	}
+	if manifest.HasPseudoIsolation() {
+		log.Fatalf("Pseudo-isolated solutions are not supported")
+	}
+	model, err := sol.LoadFmmModel(solutionDirectory)
+	if err != nil {
+		log.Fatalf("Failed to read the solution's model definitions:\n%v", err)
+	}
+	if len(model.Entities) == 0 {
+		log.Fatalf("The solution does not define any FMM entities")
+	}
+	return manifest, model
+}
+
+func newTelemetryGenerator(model *sol.FmmModel, options generateOptions, rnd *rand.Rand) *telemetryGenerator {
+	g := &telemetryGenerator{model: model, options: options, rand: rnd}
+	for _, fmmEntity := range model.Entities {
+		if fmmEntity.FmmTypeDef == nil || fmmEntity.Namespace == nil {
+			continue
+		}
+		n := options.entities
+		if count, found := options.counts[fmmEntity.GetTypeName()]; found {
+			n = count
+		}
+		for i := 1; i <= n; i++ {
+			g.entities = append(g.entities, g.newEntity(fmmEntity, i))
+		}
+	}
+	return g
+}
+
+// newEntity creates an entity with realistic attribute values and a baseline for each of its metrics
+func (g *telemetryGenerator) newEntity(fmmEntity *sol.FmmEntity, index int) *generatedEntity {
+	entity := &generatedEntity{
+		fmmEntity:  fmmEntity,
+		typeName:   fmmEntity.GetTypeName(),
+		attributes: map[string]any{},
+		metrics:    map[string]*metricState{},
+	}
+	if fmmEntity.AttributeDefinitions != nil && fmmEntity.AttributeDefinitions.FmmAttributeDefinitionsTypeDef != nil {
+		for _, name := range sortedKeys(fmmEntity.AttributeDefinitions.Attributes) {
+			def := fmmEntity.AttributeDefinitions.Attributes[name]
+			attrName := name
+			if !strings.Contains(name, fmmEntity.Namespace.Name) {
+				attrName = fmt.Sprintf("%s.%s.%s", fmmEntity.Namespace.Name, fmmEntity.Name, name)
+			}
+			entity.attributes[attrName] = sol.SampleAttributeValue(g.rand, name, def, fmmEntity.Name, index)
+		}
+	}
+	for _, metricType := range fmmEntity.MetricTypes {
+		fmmMetric, found := g.model.Metrics[metricType]
+		if !found {
+			continue
+		}
+		low, high := sol.SampleMetricValueRange(fmmMetric.Unit)
+		level := low + g.rand.Float64()*(high-low)
+		if hasErrorKeyword(fmmMetric.Name) {
+			level = low + (high-low)*g.options.errorRate
+		}
+		bounded := slices.Contains([]string{"%", "percent", "1", "ratio"}, strings.ToLower(strings.Trim(fmmMetric.Unit, "{}")))
+		entity.metrics[metricType] = &metricState{low: low, high: high, level: level, bounded: bounded}
+	}
+	return entity
+}
+
+// generate fabricates the telemetry of the entities from start to end
+func (g *telemetryGenerator) generate(start time.Time, end time.Time) *melt.FsocData {
+	data := &melt.FsocData{Melt: []*melt.Entity{}}
+	for _, e := range g.entities {
+		entity := e.newEntity()
+		for _, metricType := range e.fmmEntity.MetricTypes {
+			if state, found := e.metrics[metricType]; found {
+				metric := g.newMetric(metricType, g.model.Metrics[metricType])
+				for t := start; t.Before(end); t = t.Add(g.options.interval) {
+					g.addDataPoint(e, metric, g.model.Metrics[metricType], state, t, t.Add(g.options.interval))
+				}
+				entity.AddMetric(metric)
+			}
+		}
+		for _, timestamp := range g.timestamps(g.options.logsPerHour, start, end) {
+			entity.AddLog(g.newLog(e, timestamp))
+		}
+		for _, eventType := range e.fmmEntity.EventTypes {
+			if fmmEvent, found := g.model.Events[eventType]; found {
+				for i, timestamp := range g.timestamps(g.options.eventsPerHour, start, end) {
+					entity.AddLog(g.newEvent(e, eventType, fmmEvent, i+1, timestamp))
+				}
+			}
+		}
+		data.Melt = append(data.Melt, entity)
+	}
+	return data
+}
+
+// newEntity creates a MELT entity with the attributes of the generated entity
+func (e *generatedEntity) newEntity() *melt.Entity {
+	entity := melt.NewEntity(e.typeName)
+	for name, value := range e.attributes {
+		entity.SetAttribute(name, value)
+	}
+	return entity
+}
+
+// newMetric creates a metric of a type, without data points
+func (g *telemetryGenerator) newMetric(metricType string, fmmMetric *sol.FmmMetric) *melt.Metric {
+	metric := melt.NewMetric(metricType, fmmMetric.Unit, string(fmmMetric.ContentType), string(fmmMetric.Type))
+	metric.IsMonotonic = fmmMetric.IsMonotonic
+	switch strings.ToLower(fmmMetric.AggregationTemporality) {
+	case "delta":
+		metric.AggregationTemporality = melt.AggregationTemporalityDelta
+	case "cumulative":
+		metric.AggregationTemporality = melt.AggregationTemporalityCumulative
+	}
+	if fmmMetric.AttributeDefinitions != nil {
+		for _, name := range sortedKeys(fmmMetric.AttributeDefinitions.Attributes) {
+			metric.SetAttribute(name, sol.SampleAttributeValue(g.rand, name, fmmMetric.AttributeDefinitions.Attributes[name], fmmMetric.Name, 1))
+		}
+	}
+	return metric
+}
+
+// addDataPoint adds a data point from start to end to a metric of an entity, with the next value of
+// its state, changed by the incidents at the end time
+func (g *telemetryGenerator) addDataPoint(e *generatedEntity, metric *melt.Metric, fmmMetric *sol.FmmMetric, state *metricState, start time.Time, end time.Time) {
+	span := state.high - state.low
+	season := g.season(end)
+	factor := g.metricFactor(e, metric.TypeName, fmmMetric, end)
+	var value float64
+	if fmmMetric.IsMonotonic && fmmMetric.ContentType == sol.ContentType_Sum {
+		// monotonic sums only increase, faster at the peak of the season or during incidents
+		state.cumulative += (state.low + g.rand.Float64()*span/10) * season * factor
+		value = state.cumulative
+	} else {
+		// other values drift around their level, following the season
+		state.level = math.Max(state.low, math.Min(state.high, state.level+(g.rand.Float64()-0.5)*span/50))
+		value = math.Max(state.low, math.Min(state.high, state.level*season)) * factor
+		if state.bounded {
+			value = math.Min(state.high, value)
+		}
+	}
+	if fmmMetric.Type == sol.Type_Long {
+		value = math.Round(value)
+	} else {
+		value = math.Round(value*100) / 100
+	}
+
+	if fmmMetric.ContentType == sol.ContentType_Distribution {
+		count := int64(math.Max(1, math.Round(float64(1+g.rand.Intn(100))*season)))
+		quantiles := []*melt.QuantileValue{{Quantile: 0.0, Value: math.Round(value*50) / 100}, {Quantile: 1.0, Value: math.Round(value*150) / 100}}
+		metric.AddDistributionDataPoint(start.UnixNano(), end.UnixNano(), value*float64(count), count, quantiles)
+	} else {
+		metric.AddDataPoint(start.UnixNano(), end.UnixNano(), value)
+	}
+}
+
+// newLog creates a log of an entity, an error at the error rate
+func (g *telemetryGenerator) newLog(e *generatedEntity, timestamp time.Time) *melt.Log {
+	l := melt.NewLog()
+	l.Timestamp = timestamp.UnixNano()
+	if g.rand.Float64() < g.errorRate(e, timestamp) {
+		l.Severity = "ERROR"
+		l.Body = fmt.Sprintf("Request to %s failed: connection reset by peer", e.typeName)
+	} else {
+		l.Severity = "INFO"
+		l.Body = fmt.Sprintf("Request to %s completed in %dms", e.typeName, 5+g.rand.Intn(500))
+	}
+	l.SetAttribute("level", strings.ToLower(l.Severity))
+	return l
+}
+
+// newEvent creates an event of a type for an entity, reporting an error at the error rate
+func (g *telemetryGenerator) newEvent(e *generatedEntity, eventType string, fmmEvent *sol.FmmEvent, index int, timestamp time.Time) *melt.Log {
+	event := melt.NewEvent(eventType)
+	event.Timestamp = timestamp.UnixNano()
+	isError := g.rand.Float64() < g.errorRate(e, timestamp)
+	if fmmEvent.AttributeDefinitions != nil {
+		for _, name := range sortedKeys(fmmEvent.AttributeDefinitions.Attributes) {
+			event.SetAttribute(name, g.eventAttributeValue(name, fmmEvent.AttributeDefinitions.Attributes[name], fmmEvent.Name, index, isError))
+		}
+	}
+	return event
+}
+
+// eventAttributeValue returns a realistic value for an attribute of an event, reporting an error
+// in the attributes about the event's outcome if isError
+func (g *telemetryGenerator) eventAttributeValue(name string, def *sol.FmmAttributeTypeDef, owner string, index int, isError bool) any {
+	value := sol.SampleAttributeValue(g.rand, name, def, owner, index)
+	if _, isString := value.(string); !isString {
+		return value
+	}
+	key := strings.ToLower(name[strings.LastIndex(name, ".")+1:])
+	switch {
+	case strings.Contains(key, "severity") || strings.Contains(key, "level"):
+		if isError {
+			return "ERROR"
+		}
+		return "INFO"
+	case strings.Contains(key, "status") || strings.Contains(key, "state") || strings.Contains(key, "outcome"):
+		if isError {
+			return "failed"
+		}
+		return "succeeded"
+	case hasErrorKeyword(key) && !isError:
+		return ""
+	}
+	return value
+}
+
+// errorRate returns the error rate of an entity at a time, set by the latest incident then, if any
+func (g *telemetryGenerator) errorRate(e *generatedEntity, t time.Time) float64 {
+	rate := g.options.errorRate
+	for _, inc := range g.options.incidents {
+		if inc.errorRate != nil && inc.affects(e, t) {
+			rate = *inc.errorRate
+		}
+	}
+	return rate
+}
+
+// metricFactor returns the factor of the values of a metric of an entity at a time: the product of
+// the factors of the incidents then and, for metrics about errors, the change of the error rate
+func (g *telemetryGenerator) metricFactor(e *generatedEntity, metricType string, fmmMetric *sol.FmmMetric, t time.Time) float64 {
+	factor := 1.0
+	for _, inc := range g.options.incidents {
+		if f, found := inc.metrics[metricType]; found && inc.affects(e, t) {
+			factor *= f
+		}
+	}
+	if g.options.errorRate > 0 && hasErrorKeyword(fmmMetric.Name) {
+		factor *= g.errorRate(e, t) / g.options.errorRate
+	}
+	return factor
+}
+
+// affects tells whether the incident affects an entity at a time
+func (inc *incident) affects(e *generatedEntity, t time.Time) bool {
+	return (inc.entityType == "" || inc.entityType == e.typeName) && !t.Before(inc.from) && t.Before(inc.to)
+}
+
+// timestamps returns random times from start to end, in order, for a number of occurrences per hour
+func (g *telemetryGenerator) timestamps(perHour float64, start time.Time, end time.Time) []time.Time {
+	expected := perHour * end.Sub(start).Hours()
+	n := int(expected)
+	if g.rand.Float64() < expected-float64(n) {
+		n++
+	}
+	times := make([]time.Time, n)
+	for i := range times {
+		times[i] = start.Add(time.Duration(g.rand.Int63n(int64(end.Sub(start)))))
+	}
+	slices.SortFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+	return times
+}
+
+// season returns the factor of the seasonal cycle at a time, 1 without seasonality; the cycle is
+// lowest at the start of each period, e.g., at midnight UTC for a daily cycle
+func (g *telemetryGenerator) season(t time.Time) float64 {
+	if g.options.seasonality <= 0 {
+		return 1
+	}
+	phase := float64(t.UnixNano()%int64(g.options.seasonality)) / float64(g.options.seasonality)
+	return 1 - g.options.amplitude*math.Cos(2*math.Pi*phase)
+}
+
+// sortedKeys returns the keys of a map, e.g., attribute definitions, in a stable order, so that the
+// same seed generates the same data
+func sortedKeys[V any](m map[string]V) []string {
+	keys := maps.Keys(m)
+	slices.Sort(keys)
+	return keys
+}
+
+func hasErrorKeyword(name string) bool {
+	name = strings.ToLower(name)
+	for _, keyword := range errorKeywords {
+		if strings.Contains(name, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// countTelemetry returns the numbers of data points, logs and events of the data
+func countTelemetry(data *melt.FsocData) (dataPoints int, logs int, events int) {
+	for _, entity := range data.Melt {
+		for _, metric := range entity.Metrics {
+			dataPoints += len(metric.DataPoints)
+		}
+		for _, l := range entity.Logs {
+			if l.IsEvent {
+				events++
+			} else {
+				logs++
+			}
+		}
+	}
+	return dataPoints, logs, events
+}
@@ -0,0 +1,317 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package melt
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/melt"
+)
+
+var meltLoadCmd = &cobra.Command{
+	Use:     "load --from-solution <dir>",
+	Aliases: []string{"soak"},
+	Short:   "Send generated telemetry continuously at a target rate",
+	Long: `This command sends telemetry for the FMM entity model of a solution continuously, at target rates of
+metric data points, logs and events, for a given duration, e.g., to validate an ingestion pipeline or the
+ingestion quotas of a tenant under sustained load. The telemetry is generated as with "fsoc melt generate".
+
+The data are sent in batches, one every --batch-interval, each with the data points, logs and events due
+since the previous batch. During the --ramp-up period, the rates increase linearly from zero to their
+targets; --jitter varies the size of each batch randomly around the target, e.g., 0.1 for plus or minus 10%.
+
+When the duration is over, or when interrupted with Ctrl-C, the command reports the target and achieved
+rates, with the numbers of items sent and of failed requests, for each kind of data.`,
+	Example: `  fsoc melt load --from-solution ./mysolution --datapoints-per-min 6000 --duration 30m --profile myagent
+  fsoc melt soak --from-solution . --logs-per-sec 200 --events-per-sec 20 --ramp-up 5m --jitter 0.2 --duration 2h
+  fsoc melt load --from-solution . --datapoints-per-min 600 --duration 1m --dry-run`,
+	Args: cobra.ExactArgs(0),
+	Run:  meltLoad,
+}
+
+func init() {
+	meltLoadCmd.Flags().String("from-solution", "", "Path to the root directory of the solution whose model to generate data for")
+	meltLoadCmd.Flags().Int("entities", 10, "Number of entities of each entity type")
+	meltLoadCmd.Flags().Float64("datapoints-per-min", 600, "Target rate of metric data points, per minute")
+	meltLoadCmd.Flags().Float64("logs-per-sec", 1, "Target rate of logs, per second")
+	meltLoadCmd.Flags().Float64("events-per-sec", 1, "Target rate of events, per second")
+	meltLoadCmd.Flags().Duration("duration", 10*time.Minute, "How long to send data")
+	meltLoadCmd.Flags().Duration("ramp-up", time.Minute, "Period over which the rates increase to their targets")
+	meltLoadCmd.Flags().Float64("jitter", 0.1, "Random variation of the size of each batch, relative to the target, from 0 to 1")
+	meltLoadCmd.Flags().Duration("batch-interval", 5*time.Second, "Interval between batches of data")
+	meltLoadCmd.Flags().Float64("error-rate", 0.05, "Fraction of logs and events reporting errors, from 0 to 1")
+	meltLoadCmd.Flags().Int64("seed", 0, "Seed for the random values (defaults to a random seed)")
+	meltLoadCmd.Flags().Bool("dry-run", false, "Generate the data but don't send it to the ingestion API")
+	_ = meltLoadCmd.MarkFlagRequired("from-solution")
+
+	meltCmd.AddCommand(meltLoadCmd)
+}
+
+// loadKind is a kind of data sent under load, with its target rate and statistics
+type loadKind struct {
+	Kind           string  `json:"kind"`
+	Unit           string  `json:"unit"` // of the rates, per second or per minute
+	TargetRate     float64 `json:"targetRate"`
+	AchievedRate   float64 `json:"achievedRate"`
+	Sent           int     `json:"sent"`
+	Requests       int     `json:"requests"`
+	FailedRequests int     `json:"failedRequests"`
+	ErrorRate      float64 `json:"errorRate"`
+
+	perSecond float64
+	due       float64 // items due, including the fraction not sent in the previous batches
+}
+
+// loadBatcher distributes the items of each batch over the entities, so that all metrics of all
+// entities get data points
+type loadBatcher struct {
+	*telemetryGenerator
+	series     []loadSeries // metrics of entities
+	eventTypes []loadSeries // event types of entities
+	next       [3]int       // next series, entity and event type to get an item
+}
+
+type loadSeries struct {
+	entity   int
+	typeName string
+}
+
+func meltLoad(cmd *cobra.Command, args []string) {
+	solutionDirectory, _ := cmd.Flags().GetString("from-solution")
+	duration, _ := cmd.Flags().GetDuration("duration")
+	rampUp, _ := cmd.Flags().GetDuration("ramp-up")
+	jitter, _ := cmd.Flags().GetFloat64("jitter")
+	batchInterval, _ := cmd.Flags().GetDuration("batch-interval")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	options := generateOptions{interval: batchInterval}
+	options.entities, _ = cmd.Flags().GetInt("entities")
+	options.errorRate, _ = cmd.Flags().GetFloat64("error-rate")
+	if err := options.validate(); err != nil {
+		log.Fatalf("Invalid options: %v", err)
+	}
+	if duration <= 0 || rampUp < 0 || jitter < 0 || jitter > 1 {
+		log.Fatalf("Invalid options: the duration must be positive, the ramp-up cannot be negative and the jitter must be between 0 and 1")
+	}
+	kinds := []*loadKind{
+		{Kind: "data points", Unit: "min"},
+		{Kind: "logs", Unit: "s"},
+		{Kind: "events", Unit: "s"},
+	}
+	kinds[0].TargetRate, _ = cmd.Flags().GetFloat64("datapoints-per-min")
+	kinds[1].TargetRate, _ = cmd.Flags().GetFloat64("logs-per-sec")
+	kinds[2].TargetRate, _ = cmd.Flags().GetFloat64("events-per-sec")
+	kinds[0].perSecond, kinds[1].perSecond, kinds[2].perSecond = kinds[0].TargetRate/60, kinds[1].TargetRate, kinds[2].TargetRate
+	for _, kind := range kinds {
+		if kind.TargetRate < 0 {
+			log.Fatalf("Invalid options: the target rates cannot be negative")
+		}
+	}
+	seed, _ := cmd.Flags().GetInt64("seed")
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	_, model := loadSolutionModel(solutionDirectory)
+	batcher := newLoadBatcher(newTelemetryGenerator(model, options, rand.New(rand.NewSource(seed))))
+	if len(batcher.series) == 0 && kinds[0].TargetRate > 0 {
+		log.Warn("The entities of the solution have no metrics; no data points will be sent")
+		kinds[0].TargetRate, kinds[0].perSecond = 0, 0
+	}
+	if len(batcher.eventTypes) == 0 && kinds[2].TargetRate > 0 {
+		log.Warn("The entities of the solution have no event types; no events will be sent")
+		kinds[2].TargetRate, kinds[2].perSecond = 0, 0
+	}
+	exp := &melt.Exporter{DryRun: dryRun}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Sending data for %v, until done or interrupted with Ctrl-C\n", duration))
+	start := time.Now()
+	last := start
+	ticker := time.NewTicker(batchInterval)
+	defer ticker.Stop()
+	for done := false; !done; {
+		var now time.Time
+		select {
+		case <-ctx.Done():
+			now, done = time.Now(), true
+		case now = <-ticker.C:
+		}
+		if now.Sub(start) >= duration {
+			now, done = start.Add(duration), true
+		}
+
+		// the items due in the batch, at the ramped-up rates with jitter
+		factor := 1.0
+		if elapsed := now.Sub(start); elapsed < rampUp {
+			factor = float64(elapsed) / float64(rampUp)
+		}
+		factor *= 1 + jitter*(2*batcher.rand.Float64()-1)
+		counts := make([]int, len(kinds))
+		for i, kind := range kinds {
+			kind.due += kind.perSecond * now.Sub(last).Seconds() * factor
+			counts[i] = int(kind.due)
+			kind.due -= float64(counts[i])
+		}
+
+		data := batcher.batch(last, now, counts[0], counts[1], counts[2])
+		if counts[0] > 0 {
+			recordRequest(exp.ExportMetrics(data.Melt), kinds[0], counts[0])
+		}
+		if counts[1] > 0 || counts[2] > 0 {
+			err := exp.ExportLogs(data.Melt)
+			recordRequest(err, kinds[1], counts[1])
+			recordRequest(err, kinds[2], counts[2])
+		}
+		log.WithFields(log.Fields{"elapsed": now.Sub(start).Round(time.Second), "datapoints": counts[0], "logs": counts[1], "events": counts[2]}).Info("Sent batch")
+		last = now
+	}
+
+	elapsed := last.Sub(start)
+	lines := make([][]string, len(kinds))
+	for i, kind := range kinds {
+		if elapsed > 0 {
+			kind.AchievedRate = float64(kind.Sent) / elapsed.Seconds()
+			if kind.Unit == "min" {
+				kind.AchievedRate *= 60
+			}
+		}
+		if kind.Requests > 0 {
+			kind.ErrorRate = float64(kind.FailedRequests) / float64(kind.Requests)
+		}
+		lines[i] = []string{
+			kind.Kind,
+			formatRate(kind.TargetRate, kind.Unit),
+			formatRate(kind.AchievedRate, kind.Unit),
+			strconv.Itoa(kind.Sent),
+			strconv.Itoa(kind.Requests),
+			strconv.Itoa(kind.FailedRequests),
+			fmt.Sprintf("%.1f%%", kind.ErrorRate*100),
+		}
+	}
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Sent data for %v\n", elapsed.Round(time.Second)))
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []*loadKind `json:"items"`
+		Total int         `json:"total"`
+	}{kinds, len(kinds)}, &output.Table{
+		Headers: []string{"Kind", "Target", "Achieved", "Sent", "Requests", "Failed", "Error Rate"},
+		Lines:   lines,
+	})
+}
+
+// recordRequest records the outcome of a request sending n items of a kind
+func recordRequest(err error, kind *loadKind, n int) {
+	if n == 0 {
+		return
+	}
+	kind.Requests++
+	if err != nil {
+		kind.FailedRequests++
+		log.Warnf("Failed to send %d %s: %v", n, kind.Kind, err)
+		return
+	}
+	kind.Sent += n
+}
+
+func formatRate(rate float64, unit string) string {
+	return strconv.FormatFloat(math.Round(rate*10)/10, 'f', -1, 64) + "/" + unit
+}
+
+func newLoadBatcher(g *telemetryGenerator) *loadBatcher {
+	b := &loadBatcher{telemetryGenerator: g}
+	for i, e := range g.entities {
+		for _, metricType := range e.fmmEntity.MetricTypes {
+			if _, found := e.metrics[metricType]; found {
+				b.series = append(b.series, loadSeries{entity: i, typeName: metricType})
+			}
+		}
+		for _, eventType := range e.fmmEntity.EventTypes {
+			if _, found := g.model.Events[eventType]; found {
+				b.eventTypes = append(b.eventTypes, loadSeries{entity: i, typeName: eventType})
+			}
+		}
+	}
+	return b
+}
+
+// batch generates the given numbers of data points, logs and events from start to end, taking
+// turns among the metrics, entities and event types of the entities
+func (b *loadBatcher) batch(start time.Time, end time.Time, dataPoints int, logs int, events int) *melt.FsocData {
+	entities := map[int]*melt.Entity{}
+	entity := func(i int) *melt.Entity {
+		if entities[i] == nil {
+			entities[i] = b.entities[i].newEntity()
+		}
+		return entities[i]
+	}
+	window := end.Sub(start)
+
+	// the data points of a metric are spread evenly over the window
+	if len(b.series) > 0 {
+		for i := 0; i < min(dataPoints, len(b.series)); i++ {
+			s := b.series[(b.next[0]+i)%len(b.series)]
+			n := dataPoints / len(b.series)
+			if i < dataPoints%len(b.series) {
+				n++
+			}
+			fmmMetric := b.model.Metrics[s.typeName]
+			metric := b.newMetric(s.typeName, fmmMetric)
+			for j := 0; j < n; j++ {
+				pointStart := start.Add(window * time.Duration(j) / time.Duration(n))
+				pointEnd := start.Add(window * time.Duration(j+1) / time.Duration(n))
+				b.addDataPoint(b.entities[s.entity], metric, fmmMetric, b.entities[s.entity].metrics[s.typeName], pointStart, pointEnd)
+			}
+			entity(s.entity).AddMetric(metric)
+		}
+		b.next[0] = (b.next[0] + dataPoints) % len(b.series)
+	}
+	for i := 0; i < logs && len(b.entities) > 0; i++ {
+		e := b.next[1] % len(b.entities)
+		entity(e).AddLog(b.newLog(b.entities[e], b.randomTime(start, end)))
+		b.next[1]++
+	}
+	for i := 0; i < events && len(b.eventTypes) > 0; i++ {
+		s := b.eventTypes[b.next[2]%len(b.eventTypes)]
+		entity(s.entity).AddLog(b.newEvent(b.entities[s.entity], s.typeName, b.model.Events[s.typeName], b.next[2]+1, b.randomTime(start, end)))
+		b.next[2]++
+	}
+
+	data := &melt.FsocData{Melt: []*melt.Entity{}}
+	for i := range b.entities {
+		if entities[i] != nil {
+			data.Melt = append(data.Melt, entities[i])
+		}
+	}
+	return data
+}
+
+func (b *loadBatcher) randomTime(start time.Time, end time.Time) time.Time {
+	if !end.After(start) {
+		return start
+	}
+	return start.Add(time.Duration(b.rand.Int63n(int64(end.Sub(start)))))
+}
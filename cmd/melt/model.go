@@ -13,6 +13,7 @@ import (
 	"gopkg.in/yaml.v2"
 
 	sol "github.com/cisco-open/fsoc/cmd/solution"
+	"github.com/cisco-open/fsoc/cmdkit/jsondoc"
 	"github.com/cisco-open/fsoc/output"
 	"github.com/cisco-open/fsoc/platform/melt"
 )
@@ -83,13 +84,15 @@ func meltModel(cmd *cobra.Command, args []string) {
 
 func getFsocDataModel(cmd *cobra.Command, manifest *sol.Manifest, isolationNamespace string) *melt.FsocData {
 	fsocData := &melt.FsocData{}
-	fmmEntities := manifest.GetFmmEntities()
+	// read all definitions before failing, to report all errors at once
+	fmmEntities, entitiesErr := manifest.GetFmmEntities()
+	fmmMetrics, metricsErr := manifest.GetFmmMetrics()
+	fmmEvents, eventsErr := manifest.GetFmmEvents()
+	if err := jsondoc.JoinParseErrors(entitiesErr, metricsErr, eventsErr); err != nil {
+		log.Fatalf("Failed to read the solution's model definitions:\n%v", err)
+	}
 	output.PrintCmdStatus(cmd, fmt.Sprintf("Adding %v entities to the fsoc data model\n", len(fmmEntities)))
-
-	fmmMetrics := manifest.GetFmmMetrics()
 	output.PrintCmdStatus(cmd, fmt.Sprintf("Adding %v metrics to the fsoc data model\n", len(fmmMetrics)))
-
-	fmmEvents := manifest.GetFmmEvents()
 	output.PrintCmdStatus(cmd, fmt.Sprintf("Adding %v events to the fsoc data model\n", len(fmmEvents)))
 
 	if isolationNamespace != "" {
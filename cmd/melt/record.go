@@ -0,0 +1,182 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package melt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/melt"
+)
+
+var meltRecordCmd = &cobra.Command{
+	Use:   "record",
+	Short: "Record telemetry sent by OpenTelemetry SDKs or collectors into fsoc telemetry data files",
+	Long: `
+This command runs a small OTLP/HTTP receiver that accepts telemetry from any OpenTelemetry SDK or collector,
+e.g., an application instrumented locally or a collector with an otlphttp exporter pointing at it, and writes
+the data into fsoc telemetry data files, to edit it and to send or replay it later with "fsoc melt send".
+
+The receiver accepts metrics, logs and traces on the standard OTLP/HTTP paths (/v1/metrics, /v1/logs and
+/v1/traces), in protobuf or JSON, optionally gzip-compressed, with bodies of up to 32 MiB. The data received
+during each --flush-interval is written into a new file in the --out directory, named after the time it is
+written (with a sequence number if needed, so that existing files are never overwritten); the rest is written
+when the command is stopped with Ctrl-C. With a --flush-interval of 0, all the data is written into a single
+file at the end.
+
+Each distinct resource becomes an entity. Entities with well-known resource attributes are typed accordingly
+(e.g., apm:service for service.name, k8s:pod for k8s.pod.name and infra:host for host.name); the type of the
+others is left empty, to be filled in. Histograms are recorded as distributions with their count and sum,
+and spans are nested under their parent spans.
+
+With --scrub, the data is scrubbed before it is written, so that customer identifiers never reach the disk;
+see "fsoc melt send" for the format of the scrub file.`,
+	Example: `  fsoc melt record
+  fsoc melt record --listen localhost:4318 --out capture/ --flush-interval 5m
+  fsoc melt record --scrub scrub.yaml
+  fsoc melt send capture/melt-20240102-150405.yaml --shift-to-now`,
+	Args: cobra.NoArgs,
+	Run:  meltRecord,
+}
+
+const defaultRecordFlushInterval = time.Minute
+
+func init() {
+	meltRecordCmd.Flags().String("listen", ":4318", "Address to receive OTLP/HTTP data on, as [host]:port")
+	meltRecordCmd.Flags().String("out", "capture", "Directory to write the recorded data files into")
+	meltRecordCmd.Flags().Duration("flush-interval", defaultRecordFlushInterval, "How often to write the data received into a new file; 0 to write a single file at the end")
+	meltRecordCmd.Flags().String("scrub", "", "Remove and hash attributes of the data before writing it, as configured in this file (yaml)")
+
+	meltCmd.AddCommand(meltRecordCmd)
+}
+
+func meltRecord(cmd *cobra.Command, args []string) {
+	listen, _ := cmd.Flags().GetString("listen")
+	outDir, _ := cmd.Flags().GetString("out")
+	flushInterval, _ := cmd.Flags().GetDuration("flush-interval")
+	if flushInterval != 0 && flushInterval < time.Second {
+		log.Fatalf("Invalid --flush-interval %v, must be 0 or at least 1s", flushInterval)
+	}
+	scrubber := getScrubber(cmd)
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		log.Fatalf("Failed to create the output directory %q: %v", outDir, err)
+	}
+
+	receiver := &melt.Receiver{
+		OnReceive: func(kind string, items int) {
+			log.WithFields(log.Fields{"kind": kind, "items": items}).Info("Received OTLP data")
+		},
+	}
+	server := &http.Server{
+		Addr:         listen,
+		Handler:      receiver,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatalf("Failed to receive OTLP data on %v: %v", listen, err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var tick <-chan time.Time
+	if flushInterval > 0 {
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Receiving OTLP/HTTP data on %v (/v1/metrics, /v1/logs and /v1/traces) into %v, until stopped with Ctrl-C\n", listen, outDir))
+
+	files := 0
+	for {
+		select {
+		case <-tick:
+			if writeRecording(cmd, receiver.Take(), outDir, scrubber) {
+				files++
+			}
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				log.Warnf("Failed to stop receiving OTLP data: %v", err)
+			}
+			if writeRecording(cmd, receiver.Take(), outDir, scrubber) {
+				files++
+			}
+			output.PrintCmdStatus(cmd, fmt.Sprintf("Recorded %d data files into %v\n", files, outDir))
+			return
+		}
+	}
+}
+
+// writeRecording converts recorded OTLP data to fsoc telemetry data and writes it into a new file
+// in the output directory, scrubbing it if requested, returning whether there was any data to write
+func writeRecording(cmd *cobra.Command, otlpData *melt.OtlpData, outDir string, scrubber *melt.Scrubber) bool {
+	data := otlpData.ToFsocData()
+	if len(data.Melt) == 0 {
+		return false
+	}
+	if scrubber != nil {
+		scrubber.ScrubFsocData(data)
+	}
+	content, err := yaml.Marshal(data)
+	if err != nil {
+		log.Fatalf("(bug) Failed to marshal the recorded data: %v", err)
+	}
+	file, err := createRecordingFile(outDir, time.Now())
+	if err != nil {
+		log.Fatalf("Failed to create a file for the recorded data: %v", err)
+	}
+	fileName := file.Name()
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		log.Fatalf("Failed to write the recorded data into %q: %v", fileName, err)
+	}
+	dataPoints, logs, spans := data.ItemCounts()
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Wrote %d entities with %d data points, %d logs and %d spans into %v\n", len(data.Melt), dataPoints, logs, spans, fileName))
+	return true
+}
+
+// createRecordingFile creates a new file for recorded data in the output directory, named after the
+// time, e.g., melt-20060102-150405.yaml; a sequence number is added to the name if a file with
+// that name exists already, e.g., from an earlier recording in the same second
+func createRecordingFile(outDir string, now time.Time) (*os.File, error) {
+	baseName := "melt-" + now.Format("20060102-150405")
+	fileName := filepath.Join(outDir, baseName+".yaml")
+	for seq := 2; ; seq++ {
+		file, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if !errors.Is(err, os.ErrExist) {
+			return file, err
+		}
+		fileName = filepath.Join(outDir, fmt.Sprintf("%s-%d.yaml", baseName, seq))
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package melt
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/melt"
+)
+
+// replayBatchInterval is the time window of the data sent at once when replaying at a given speed
+const replayBatchInterval = 5 * time.Second
+
+// replayOptions - how to replay recorded data: shifted so that it ends now or, at a given speed,
+// paced from now as if it was happening again
+type replayOptions struct {
+	speed float64 // 0 if not paced
+}
+
+// getReplayOptions returns the replay options from the command line, nil if the data is to be sent
+// as it is
+func getReplayOptions(cmd *cobra.Command) (*replayOptions, error) {
+	shiftToNow, _ := cmd.Flags().GetBool("shift-to-now")
+	speedFlag, _ := cmd.Flags().GetString("speed")
+	if !shiftToNow && speedFlag == "" {
+		return nil, nil
+	}
+	options := &replayOptions{}
+	if speedFlag != "" {
+		speed, err := strconv.ParseFloat(strings.TrimSuffix(strings.ToLower(speedFlag), "x"), 64)
+		if err != nil || speed <= 0 {
+			return nil, fmt.Errorf("invalid --speed %q, must be a positive factor, e.g., 10x or 0.5x", speedFlag)
+		}
+		options.speed = speed
+	}
+	return options, nil
+}
+
+// timeMapping returns the function that maps the timestamps of recorded data, from first to last,
+// to replay timestamps: shifted so that the last one is now or, when paced, scaled by the speed
+// from now
+func (o *replayOptions) timeMapping(first, last int64, now time.Time) func(int64) int64 {
+	if o.speed == 0 {
+		offset := now.UnixNano() - last
+		return func(t int64) int64 { return t + offset }
+	}
+	return func(t int64) int64 { return now.UnixNano() + int64(float64(t-first)/o.speed) }
+}
+
+// rewriteTimestamps rewrites the timestamps of data, given its time range and timestamp mapper, as
+// specified by the replay options, returning the rewritten time range and whether the data is to
+// be replayed paced (see replayMelt and replayOtlp) rather than sent at once
+func (o *replayOptions) rewriteTimestamps(cmd *cobra.Command, timeRange func() (int64, int64), mapTimestamps func(func(int64) int64)) (int64, int64, bool) {
+	first, last := timeRange()
+	if first == 0 {
+		log.Warn("The data has no timestamps to rewrite, sending it as it is")
+		return 0, 0, false
+	}
+	now := time.Now()
+	mapTimestamps(o.timeMapping(first, last, now))
+	if o.speed == 0 {
+		output.PrintCmdStatus(cmd, fmt.Sprintf("Shifted the timestamps by %v to end now\n", now.Sub(time.Unix(0, last)).Round(time.Second)))
+	}
+	return first, last, o.speed != 0
+}
+
+// replayMelt sends fsoc telemetry data whose timestamps were rewritten for a paced replay, in
+// batches each sent at the end of its time window
+func replayMelt(cmd *cobra.Command, fsoData *melt.FsocData, options *replayOptions, first, last int64) {
+	start, _ := fsoData.TimeRange()
+	slices := fsoData.SplitByTime(start, replayBatchInterval.Nanoseconds())
+	due := make([]time.Time, len(slices))
+	for i, slice := range slices {
+		due[i] = time.Unix(0, slice.End)
+	}
+	exp, _ := newExporter(cmd)
+	defer exp.Close()
+	paceReplay(cmd, options, first, last, due, func(i int) error {
+		if err := exp.ExportMetrics(slices[i].Data.Melt); err != nil {
+			return fmt.Errorf("failed to export metrics: %w", err)
+		}
+		if err := exp.ExportLogs(slices[i].Data.Melt); err != nil {
+			return fmt.Errorf("failed to export logs: %w", err)
+		}
+		if err := exp.ExportSpans(slices[i].Data.Melt); err != nil {
+			return fmt.Errorf("failed to export spans: %w", err)
+		}
+		return nil
+	})
+}
+
+// replayOtlp sends OTLP requests whose timestamps were rewritten for a paced replay, each when its
+// latest timestamp has passed
+func replayOtlp(cmd *cobra.Command, otlpData *melt.OtlpData, options *replayOptions, first, last int64) {
+	parts := otlpData.SplitRequests()
+	sort.SliceStable(parts, func(i, j int) bool {
+		_, lastI := parts[i].TimeRange()
+		_, lastJ := parts[j].TimeRange()
+		return lastI < lastJ
+	})
+	due := make([]time.Time, len(parts))
+	for i, part := range parts {
+		_, partLast := part.TimeRange()
+		due[i] = time.Unix(0, partLast)
+	}
+	exp, _ := newExporter(cmd)
+	defer exp.Close()
+	paceReplay(cmd, options, first, last, due, func(i int) error {
+		return exp.ExportOtlp(parts[i], func(string) {})
+	})
+}
+
+// paceReplay sends the batches of replayed data, each when it's due, until done or interrupted
+func paceReplay(cmd *cobra.Command, options *replayOptions, first, last int64, due []time.Time, send func(i int) error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	n := len(due)
+	recorded := time.Duration(last - first)
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Replaying %v of data at %vx speed in %d batches over %v, until done or interrupted with Ctrl-C\n",
+		recorded.Round(time.Second), options.speed, n, time.Duration(float64(recorded)/options.speed).Round(time.Second)))
+	for i := 0; i < n; i++ {
+		select {
+		case <-ctx.Done():
+			output.PrintCmdStatus(cmd, fmt.Sprintf("Replay interrupted after %d of %d batches\n", i, n))
+			return
+		case <-time.After(time.Until(due[i])):
+		}
+		if err := send(i); err != nil {
+			log.Fatalf("Error replaying batch %d of %d: %v", i+1, n, err)
+		}
+		output.PrintCmdStatus(cmd, fmt.Sprintf("  Sent batch %d of %d, up to %v\n", i+1, n, due[i].Format(time.TimeOnly)))
+	}
+	output.PrintCmdStatus(cmd, "\nMELT data replayed (see log for traceresponse ID)\n")
+}
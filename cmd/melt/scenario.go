@@ -0,0 +1,373 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package melt
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	sol "github.com/cisco-open/fsoc/cmd/solution"
+	"github.com/cisco-open/fsoc/output"
+)
+
+// The scenarios built into fsoc, one <name>.yaml file per scenario
+//
+//go:embed scenarios
+var embeddedScenarios embed.FS
+
+// meltScenario describes a scenario of generated telemetry: the entity types with their metrics and
+// events, how many entities of each type, and incidents during which their telemetry deviates, e.g.,
+// an error spike. Scenario files are Go templates whose parameters are set with {{param "name" default}}.
+type meltScenario struct {
+	Description string                    `yaml:"description"`
+	Defaults    map[string]string         `yaml:"defaults,omitempty"` // values of the generate flags not set on the command line, by flag name
+	Entities    []scenarioEntity          `yaml:"entities"`
+	Metrics     map[string]scenarioMetric `yaml:"metrics,omitempty"` // by type name
+	Events      map[string]scenarioEvent  `yaml:"events,omitempty"`  // by type name
+	Incidents   []scenarioIncident        `yaml:"incidents,omitempty"`
+}
+
+// scenarioEntity - an entity type of a scenario; attribute names without the namespace are
+// qualified with the namespace and the entity name, e.g., name becomes k8s.pod.name
+type scenarioEntity struct {
+	Type       string            `yaml:"type"`
+	Count      int               `yaml:"count,omitempty"`      // defaults to --entities
+	Attributes map[string]string `yaml:"attributes,omitempty"` // attribute types (string, long, double or boolean), by name
+	Metrics    []string          `yaml:"metrics,omitempty"`
+	Events     []string          `yaml:"events,omitempty"`
+}
+
+// scenarioMetric - a metric type of a scenario
+type scenarioMetric struct {
+	Unit                   string            `yaml:"unit"`
+	ContentType            string            `yaml:"contentType,omitempty"` // gauge (default), sum or distribution
+	Type                   string            `yaml:"type,omitempty"`        // double (default) or long
+	IsMonotonic            bool              `yaml:"isMonotonic,omitempty"`
+	AggregationTemporality string            `yaml:"aggregationTemporality,omitempty"`
+	Attributes             map[string]string `yaml:"attributes,omitempty"`
+}
+
+// scenarioEvent - an event type of a scenario
+type scenarioEvent struct {
+	Attributes map[string]string `yaml:"attributes,omitempty"`
+}
+
+// scenarioIncident - a period of time during which the telemetry of entities deviates
+type scenarioIncident struct {
+	Start     time.Duration      `yaml:"start"` // after the start of the generated period, or before its end if negative
+	Duration  time.Duration      `yaml:"duration"`
+	Entity    string             `yaml:"entity,omitempty"`    // type of the affected entities, all if not set
+	ErrorRate *float64           `yaml:"errorRate,omitempty"` // error rate during the incident
+	Metrics   map[string]float64 `yaml:"metrics,omitempty"`   // factors of the metric values, by metric type
+}
+
+// scenarioParameter - a parameter of a scenario, with its default value
+type scenarioParameter struct {
+	Name    string `json:"name" yaml:"name"`
+	Default string `json:"default" yaml:"default"`
+}
+
+// scenarioInfo - a scenario available to melt generate, as listed
+type scenarioInfo struct {
+	Name        string              `json:"name" yaml:"name"`
+	Source      string              `json:"source" yaml:"source"` // built-in or the scenario directory
+	Description string              `json:"description" yaml:"description"`
+	Parameters  []scenarioParameter `json:"parameters" yaml:"parameters"`
+}
+
+const builtInScenarioSource = "built-in"
+
+// loadScenario reads a scenario given by file path, name in the scenario directory (if any) or
+// built-in name, and renders it with the parameter values, returning its name and the scenario
+func loadScenario(source string, dir string, values map[string]string) (string, *meltScenario, error) {
+	name, content, err := readScenario(source, dir)
+	if err != nil {
+		return "", nil, err
+	}
+	scenario, params, err := renderScenario(name, content, values)
+	if err != nil {
+		return "", nil, err
+	}
+	for param := range values {
+		if !slices.ContainsFunc(params, func(p scenarioParameter) bool { return p.Name == param }) {
+			names := []string{}
+			for _, p := range params {
+				names = append(names, p.Name)
+			}
+			return "", nil, fmt.Errorf("unknown parameter %q for scenario %q (expected one of %q)", param, name, names)
+		}
+	}
+	return name, scenario, nil
+}
+
+// readScenario returns the name and the content of a scenario given by file path, name in the
+// scenario directory (if any) or built-in name
+func readScenario(source string, dir string) (string, []byte, error) {
+	if info, err := os.Stat(source); err == nil && !info.IsDir() {
+		content, err := os.ReadFile(source)
+		return strings.TrimSuffix(filepath.Base(source), filepath.Ext(source)), content, err
+	}
+	if dir != "" {
+		content, err := os.ReadFile(filepath.Join(dir, source+".yaml"))
+		if err == nil || !errors.Is(err, fs.ErrNotExist) {
+			return source, content, err
+		}
+	}
+	content, err := embeddedScenarios.ReadFile(path.Join("scenarios", source+".yaml"))
+	if err != nil {
+		scenarios, _ := listScenarios(dir)
+		names := []string{}
+		for _, s := range scenarios {
+			names = append(names, s.Name)
+		}
+		return "", nil, fmt.Errorf("unknown scenario %q (expected one of %q or a file)", source, names)
+	}
+	return source, content, nil
+}
+
+// renderScenario renders a scenario's template with parameter values and parses it, returning the
+// scenario and the parameters used in the template
+func renderScenario(name string, content []byte, values map[string]string) (*meltScenario, []scenarioParameter, error) {
+	params := []scenarioParameter{}
+	funcs := template.FuncMap{
+		"param": func(param string, defaultValue any) string {
+			if !slices.ContainsFunc(params, func(p scenarioParameter) bool { return p.Name == param }) {
+				params = append(params, scenarioParameter{Name: param, Default: fmt.Sprint(defaultValue)})
+			}
+			if value, found := values[param]; found {
+				return value
+			}
+			return fmt.Sprint(defaultValue)
+		},
+	}
+	t, err := template.New(name).Funcs(funcs).Parse(string(content))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse scenario %q: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, nil); err != nil {
+		return nil, nil, fmt.Errorf("failed to render scenario %q: %w", name, err)
+	}
+	var scenario meltScenario
+	if err := yaml.UnmarshalStrict(buf.Bytes(), &scenario); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse scenario %q: %w", name, err)
+	}
+	return &scenario, params, nil
+}
+
+// listScenarios returns the built-in scenarios and the scenarios in a directory (if any), which take
+// precedence over the built-in ones with the same name
+func listScenarios(dir string) ([]scenarioInfo, error) {
+	scenarios := []scenarioInfo{}
+	add := func(fsys fs.FS, source string) error {
+		files, err := fs.Glob(fsys, "*.yaml")
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			name := strings.TrimSuffix(file, ".yaml")
+			content, err := fs.ReadFile(fsys, file)
+			if err != nil {
+				return err
+			}
+			scenario, params, err := renderScenario(name, content, nil)
+			if err != nil {
+				return err
+			}
+			info := scenarioInfo{Name: name, Source: source, Description: scenario.Description, Parameters: params}
+			if i := slices.IndexFunc(scenarios, func(s scenarioInfo) bool { return s.Name == name }); i >= 0 {
+				scenarios[i] = info
+			} else {
+				scenarios = append(scenarios, info)
+			}
+		}
+		return nil
+	}
+	builtIn, _ := fs.Sub(embeddedScenarios, "scenarios")
+	if err := add(builtIn, builtInScenarioSource); err != nil {
+		return nil, fmt.Errorf("(bug) %w", err)
+	}
+	if dir != "" {
+		if err := add(os.DirFS(dir), dir); err != nil {
+			return nil, err
+		}
+	}
+	return scenarios, nil
+}
+
+func listMeltScenarios(cmd *cobra.Command, dir string) {
+	scenarios, err := listScenarios(dir)
+	if err != nil {
+		log.Fatalf("Failed to list the scenarios: %v", err)
+	}
+	lines := [][]string{}
+	for _, s := range scenarios {
+		params := []string{}
+		for _, p := range s.Parameters {
+			params = append(params, p.Name+"="+p.Default)
+		}
+		lines = append(lines, []string{s.Name, s.Source, s.Description, strings.Join(params, ", ")})
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []scenarioInfo `json:"items"`
+		Total int            `json:"total"`
+	}{scenarios, len(scenarios)}, &output.Table{Headers: []string{"Name", "Source", "Description", "Parameters"}, Lines: lines})
+}
+
+// applyDefaults sets the flags of the command that the scenario has defaults for, unless they are
+// set on the command line
+func (s *meltScenario) applyDefaults(cmd *cobra.Command) error {
+	for _, name := range sortedKeys(s.Defaults) {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil {
+			return fmt.Errorf("the scenario has a default for the unknown flag --%v", name)
+		}
+		if flag.Changed {
+			continue
+		}
+		if err := flag.Value.Set(s.Defaults[name]); err != nil {
+			return fmt.Errorf("invalid default for --%v: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// fmmModel returns the FMM model of the scenario's entities, metrics and events
+func (s *meltScenario) fmmModel() (*sol.FmmModel, error) {
+	model := &sol.FmmModel{Metrics: map[string]*sol.FmmMetric{}, Events: map[string]*sol.FmmEvent{}}
+	for _, typeName := range sortedKeys(s.Metrics) {
+		m := s.Metrics[typeName]
+		typeDef, err := scenarioTypeDef(typeName, "metric")
+		if err != nil {
+			return nil, err
+		}
+		metric := &sol.FmmMetric{
+			FmmTypeDef:             typeDef,
+			ContentType:            sol.FmmMetricContentType(m.ContentType),
+			Type:                   sol.FmmMetricType(m.Type),
+			Unit:                   m.Unit,
+			IsMonotonic:            m.IsMonotonic,
+			AggregationTemporality: m.AggregationTemporality,
+			AttributeDefinitions:   scenarioAttributeDefinitions(m.Attributes),
+		}
+		if metric.ContentType == "" {
+			metric.ContentType = sol.ContentType_Gauge
+		}
+		if metric.Type == "" {
+			metric.Type = sol.Type_Double
+		}
+		if !slices.Contains([]sol.FmmMetricContentType{sol.ContentType_Gauge, sol.ContentType_Sum, sol.ContentType_Distribution}, metric.ContentType) {
+			return nil, fmt.Errorf("metric %q has an invalid content type %q, must be one of gauge, sum or distribution", typeName, m.ContentType)
+		}
+		if metric.Type != sol.Type_Long && metric.Type != sol.Type_Double {
+			return nil, fmt.Errorf("metric %q has an invalid type %q, must be long or double", typeName, m.Type)
+		}
+		model.Metrics[typeName] = metric
+	}
+	for _, typeName := range sortedKeys(s.Events) {
+		typeDef, err := scenarioTypeDef(typeName, "event")
+		if err != nil {
+			return nil, err
+		}
+		model.Events[typeName] = &sol.FmmEvent{FmmTypeDef: typeDef, AttributeDefinitions: scenarioAttributeDefinitions(s.Events[typeName].Attributes)}
+	}
+	for _, e := range s.Entities {
+		typeDef, err := scenarioTypeDef(e.Type, "entity")
+		if err != nil {
+			return nil, err
+		}
+		for _, metricType := range e.Metrics {
+			if model.Metrics[metricType] == nil {
+				return nil, fmt.Errorf("entity %q refers to the undefined metric %q", e.Type, metricType)
+			}
+		}
+		for _, eventType := range e.Events {
+			if model.Events[eventType] == nil {
+				return nil, fmt.Errorf("entity %q refers to the undefined event %q", e.Type, eventType)
+			}
+		}
+		model.Entities = append(model.Entities, &sol.FmmEntity{
+			FmmTypeDef:           typeDef,
+			AttributeDefinitions: &sol.FmmRequiredAttributeDefinitionsTypeDef{FmmAttributeDefinitionsTypeDef: scenarioAttributeDefinitions(e.Attributes)},
+			MetricTypes:          e.Metrics,
+			EventTypes:           e.Events,
+		})
+	}
+	if len(model.Entities) == 0 {
+		return nil, fmt.Errorf("the scenario does not define any entities")
+	}
+	return model, nil
+}
+
+// entityCounts returns the numbers of entities of the entity types with a count
+func (s *meltScenario) entityCounts() map[string]int {
+	counts := map[string]int{}
+	for _, e := range s.Entities {
+		if e.Count > 0 {
+			counts[e.Type] = e.Count
+		}
+	}
+	return counts
+}
+
+// incidents returns the scenario's incidents in a generated period from start to end
+func (s *meltScenario) incidents(start time.Time, end time.Time) ([]incident, error) {
+	incidents := []incident{}
+	for i, si := range s.Incidents {
+		if si.Duration <= 0 {
+			return nil, fmt.Errorf("incident %d must have a positive duration", i+1)
+		}
+		if si.ErrorRate != nil && (*si.ErrorRate < 0 || *si.ErrorRate > 1) {
+			return nil, fmt.Errorf("the error rate of incident %d must be between 0 and 1", i+1)
+		}
+		from := start.Add(si.Start)
+		if si.Start < 0 {
+			from = end.Add(si.Start)
+		}
+		incidents = append(incidents, incident{from: from, to: from.Add(si.Duration), entityType: si.Entity, errorRate: si.ErrorRate, metrics: si.Metrics})
+	}
+	return incidents, nil
+}
+
+// scenarioTypeDef returns the FMM type definition of a type name, e.g., k8s:pod
+func scenarioTypeDef(typeName string, kind string) (*sol.FmmTypeDef, error) {
+	namespace, name, found := strings.Cut(typeName, ":")
+	if !found || namespace == "" || name == "" {
+		return nil, fmt.Errorf("invalid %v type %q, must be <namespace>:<name>", kind, typeName)
+	}
+	return &sol.FmmTypeDef{Namespace: &sol.FmmNamespaceAssignTypeDef{Name: namespace, Version: 1}, Kind: kind, Name: name}, nil
+}
+
+func scenarioAttributeDefinitions(attributes map[string]string) *sol.FmmAttributeDefinitionsTypeDef {
+	defs := &sol.FmmAttributeDefinitionsTypeDef{Attributes: map[string]*sol.FmmAttributeTypeDef{}}
+	for name, attrType := range attributes {
+		defs.Attributes[name] = &sol.FmmAttributeTypeDef{Type: attrType}
+	}
+	return defs
+}
@@ -0,0 +1,50 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package melt
+
+import (
+	"os"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/cisco-open/fsoc/platform/melt"
+)
+
+// getScrubber returns the scrubber configured by the scrub file on the command line (--scrub);
+// nil if no scrubbing is requested
+func getScrubber(cmd *cobra.Command) *melt.Scrubber {
+	scrubFileName, _ := cmd.Flags().GetString("scrub")
+	if scrubFileName == "" {
+		return nil
+	}
+	content, err := os.ReadFile(scrubFileName)
+	if err != nil {
+		log.Fatalf("Failed to read the scrub file %q: %v", scrubFileName, err)
+	}
+	var config melt.ScrubConfig
+	if err := yaml.UnmarshalStrict(content, &config); err != nil {
+		log.Fatalf("Failed to parse the scrub file %q: %v", scrubFileName, err)
+	}
+	scrubber, err := melt.NewScrubber(config)
+	if err != nil {
+		log.Fatalf("Invalid scrub file %q: %v", scrubFileName, err)
+	}
+	if config.Salt == "" {
+		log.Warn("The scrub file has no salt, so hashed values can be guessed by hashing likely values")
+	}
+	return scrubber
+}
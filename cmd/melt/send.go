@@ -1,13 +1,18 @@
 package melt
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/apex/log"
 	"github.com/spf13/cobra"
@@ -31,6 +36,104 @@ fsoc melt send <fsocdatamodel>.yaml --profile <agent-principal-profile>
 
 Or use input from STDIN:
 cat <fsocdatamodel>.yaml | fsoc melt send --profile <agent-principal-profile>
+
+Besides metrics and events, entities in the fsoc data can have logs, with a body and a severity (e.g., INFO
+or ERROR), and spans, with their kind, status and child spans nested under them. Child spans belong to the
+trace of their parent and, unless set, take its start and end times; trace and span IDs can be hex strings
+or any names, e.g., "checkout", which are hashed into IDs, and missing IDs are generated. For example:
+
+    logs:
+      - body: payment declined
+        severity: WARN
+    spans:
+      - name: checkout
+        kind: server
+        status:
+          code: error
+        children:
+          - name: charge
+            kind: client
+
+The data file can also be OTLP telemetry, as written by the file exporter of the OpenTelemetry collector,
+to replay real captured telemetry: OTLP JSON, with one export request per line, or OTLP protobuf, with
+export requests each prefixed with their size (or a single export request). The format is detected from
+the file's content, unless specified with --input-format. OTLP data are sent as they are.
+
+CSV and TSV files, e.g., exports of historical data, are converted to MELT data with a mapping file
+(--mapping) that maps their columns to the attributes of entities, metrics, events and logs and to the
+timestamp of each row; rows with the same entity attributes belong to the same entity. For example:
+
+    entity:
+      type: k8s:deployment
+      attributes:
+        k8s.deployment.name: name
+        k8s.cluster.name: =production     # a constant value
+        k8s.deployment.replicas: replicas:long
+    timestamp:
+      column: time
+      format: rfc3339                     # or unix, unix_ms, unix_ns or a Go time layout
+    interval: 1m                          # of the data points, ending at the timestamp
+    metrics:
+      - type: k8s:cpu_usage
+        column: cpu
+        unit: "{cores}"
+    events:
+      - type: k8s:deployment_event
+        attributes:
+          reason: reason
+    logs:
+      - body: message
+        severity: level
+
+The format is detected from the file's extension (.csv or .tsv, otherwise CSV when a mapping is given),
+unless specified with --input-format.
+
+Data that doesn't match the FMM model, e.g., of an unknown entity type or with a metric in the wrong unit,
+is dropped silently during ingestion. To check fsoc and CSV data before sending it, validate it against the
+model of a solution in a local directory (--from-solution), the model of all solutions the tenant is
+subscribed to (--validate), or both, with the local definitions taking precedence. Unknown entity, metric
+and event types and attributes, attribute values of the wrong type, missing required attributes and metrics
+whose unit or content type differ from their definitions are reported and nothing is sent.
+
+Recorded data, e.g., historical captures, can be replayed into the current ingestion window: --shift-to-now
+shifts all timestamps so that the latest one is now, keeping their spacing, and --speed replays the data as
+if it was happening from now, faster or slower than real time (e.g., 10x replays an hour of data in 6
+minutes), sending each part of the data as its (rewritten) time comes.
+
+By default, the data is sent to the platform's ingestion API. With --protocol grpc, it is sent with OTLP/gRPC
+to the platform's collector endpoint, as agents do, with the profile's access token: by default on port 443 of
+the profile's host, or at --grpc-endpoint (e.g., a collector in front of the platform).
+
+Large data, e.g., backfills, is sent faster in batches of up to --batch-size data points, logs or spans per
+request, compressed with --gzip, with several requests prepared and sent in parallel (--parallel), up to
+--max-in-flight requests at once. The progress is displayed while sending, with the number of items accepted
+and rejected so far. Requests that fail with a transient error, e.g., rate limiting, a gateway error or a
+dropped connection, are retried up to --retries times with an increasing delay; requests that still fail are
+reported without stopping the others, and their data is written into a file of OTLP JSON (--rejected-file),
+to inspect it and to send it again with "fsoc melt send" once the problem is fixed. The command reports the
+items accepted and rejected, the retries, the throughput and the latency of the requests of each kind of data
+at the end, including the items that the platform rejected from requests it accepted partially.
+
+Data captured in a customer's environment, e.g., with "fsoc melt record", can be reused in shared tenants
+without leaking customer identifiers by scrubbing it first with --scrub: a file lists the attributes to
+remove (deny), the ones to replace by hashes of their values (hash) and, optionally, the only ones to keep as
+they are (allow), hashing all others. Attributes are matched by name or by pattern, e.g., "k8s.*", in all of
+the data: entities, relationships, metrics, logs and spans. Values are hashed consistently, so entities
+keep their relationships and their telemetry; use a secret salt, so that hashes can't be reversed by
+hashing guessed values. For example:
+
+    deny:
+      - user.email
+      - http.request.header.*
+    hash:
+      - k8s.cluster.name
+      - service.instance.id
+    allow: []                  # e.g., [service.name, "k8s.*"] to hash everything else
+    salt: s0me-s3cret
+    logBodies: hash            # keep (default), hash or drop
+
+With --verify, the command then checks that the entities, metrics and events sent show up in the platform,
+querying UQL for them for up to 5 minutes, and reports what arrived (see "fsoc melt verify").
 `,
 	Aliases:          []string{"push"}, // "push" is kept for backward compatibility, not deprecated but not canonical
 	TraverseChildren: true,
@@ -47,12 +150,40 @@ const (
 	OutputFormatHex   = melt.DumpFormatHex
 )
 
+const (
+	InputFormatAuto      = "auto"
+	InputFormatFsoc      = "fsoc"
+	InputFormatOtlpJson  = "otlp-json"
+	InputFormatOtlpProto = "otlp-proto"
+	InputFormatCsv       = "csv"
+	InputFormatTsv       = "tsv"
+)
+
 const nRandomDatapoints = 5
 
+// otlpJsonKeyRegexp detects the top-level keys of OTLP JSON export requests
+var otlpJsonKeyRegexp = regexp.MustCompile(`^\{\s*"resource(Metrics|Logs|Spans)"`)
+
 func init() {
 	meltSendCmd.Flags().Bool("dry-run", false, "Process data but don't send it to the ingestion API")
 	meltSendCmd.Flags().Bool("dump", false, "Display MELT data protobuf payloads")
 	meltSendCmd.Flags().StringP("output", "o", "auto", "output format for dump (auto, human, json, yaml, text, hex)")
+	meltSendCmd.Flags().String("input-format", InputFormatAuto, "format of the data file (auto, fsoc, otlp-json, otlp-proto, csv, tsv)")
+	meltSendCmd.Flags().String("mapping", "", "Mapping of the columns of a CSV or TSV data file to MELT data (yaml file)")
+	meltSendCmd.Flags().String("from-solution", "", "Validate the data against the FMM model of the solution in this directory before sending it")
+	meltSendCmd.Flags().Bool("validate", false, "Validate the data against the FMM model of the tenant before sending it")
+	meltSendCmd.Flags().Bool("shift-to-now", false, "Shift the timestamps of the data so that the latest one is now")
+	meltSendCmd.Flags().String("speed", "", "Replay the data from now at a speed relative to real time, e.g., 10x or 0.5x")
+	meltSendCmd.Flags().Bool("verify", false, "After sending, check that the data shows up in the platform (see melt verify)")
+	meltSendCmd.Flags().String("protocol", melt.ProtocolHTTP, "How to send the data: http (ingestion API) or grpc (OTLP/gRPC collector endpoint)")
+	meltSendCmd.Flags().Int("batch-size", 0, "Maximum number of data points, logs or spans per request (0 for all of a kind in one request)")
+	meltSendCmd.Flags().Bool("gzip", false, "Compress the requests with gzip")
+	meltSendCmd.Flags().Int("parallel", 1, "Number of requests to prepare and send in parallel")
+	meltSendCmd.Flags().Int("max-in-flight", 0, "Maximum number of requests being sent at once (0 for up to --parallel)")
+	meltSendCmd.Flags().Int("retries", 3, "Number of times to retry a request if it fails with a transient error, e.g., rate limiting or a dropped connection")
+	meltSendCmd.Flags().String("rejected-file", "", "File to write the data of the requests that failed into, as OTLP JSON (default: melt-rejected-<time>.json)")
+	meltSendCmd.Flags().String("scrub", "", "Remove and hash attributes of the data before sending it, as configured in this file (yaml)")
+	meltSendCmd.Flags().String("grpc-endpoint", "", "Address of the OTLP/gRPC collector endpoint, as host:port or URL (default: the profile's host on port 443)")
 
 	meltCmd.AddCommand(meltSendCmd)
 }
@@ -63,6 +194,32 @@ func meltSendWithUsageCheck(cmd *cobra.Command, args []string) error {
 	if !dump && cmd.Flags().Changed("output") {
 		return errors.New("--output format is allowed only when --dump is specified as well")
 	}
+	inputFormat, _ := cmd.Flags().GetString("input-format")
+	switch inputFormat {
+	case InputFormatAuto, InputFormatFsoc, InputFormatOtlpJson, InputFormatOtlpProto:
+		if inputFormat != InputFormatAuto && cmd.Flags().Changed("mapping") {
+			return fmt.Errorf("--mapping is allowed only with CSV and TSV data files")
+		}
+	case InputFormatCsv, InputFormatTsv:
+		if !cmd.Flags().Changed("mapping") {
+			return fmt.Errorf("--mapping is required for %v data files", inputFormat)
+		}
+	default:
+		return fmt.Errorf("invalid input format %q, must be one of (auto, fsoc, otlp-json, otlp-proto, csv, tsv)", inputFormat)
+	}
+	if _, err := getReplayOptions(cmd); err != nil {
+		return err
+	}
+	if _, err := getSendOptions(cmd); err != nil {
+		return err
+	}
+	protocol, _ := cmd.Flags().GetString("protocol")
+	if protocol != melt.ProtocolHTTP && protocol != melt.ProtocolGrpc {
+		return fmt.Errorf("invalid protocol %q, must be one of (http, grpc)", protocol)
+	}
+	if protocol != melt.ProtocolGrpc && cmd.Flags().Changed("grpc-endpoint") {
+		return errors.New("--grpc-endpoint is allowed only with --protocol grpc")
+	}
 
 	// process command
 	meltSend(cmd, args)
@@ -81,7 +238,32 @@ func meltSend(cmd *cobra.Command, args []string) {
 }
 
 func sendDataFromFile(cmd *cobra.Command, dataFileName string) {
-	fsoData, err := loadDataFile(dataFileName)
+	dataBytes := readDataFile(dataFileName)
+	inputFormat, _ := cmd.Flags().GetString("input-format")
+	if inputFormat == InputFormatAuto {
+		inputFormat = detectInputFormat(dataFileName, dataBytes)
+		if inputFormat != InputFormatTsv && cmd.Flags().Changed("mapping") {
+			inputFormat = InputFormatCsv // e.g., from STDIN
+		}
+	}
+	if inputFormat == InputFormatOtlpJson || inputFormat == InputFormatOtlpProto {
+		if validate, _ := cmd.Flags().GetBool("validate"); validate || cmd.Flags().Changed("from-solution") {
+			log.Fatalf("Validating %v data against the FMM model is not supported", inputFormat)
+		}
+		if verify, _ := cmd.Flags().GetBool("verify"); verify {
+			log.Fatalf("Verifying that %v data shows up is not supported", inputFormat)
+		}
+		sendOtlpData(cmd, dataFileName, dataBytes, inputFormat)
+		return
+	}
+
+	var fsoData *melt.FsocData
+	var err error
+	if inputFormat == InputFormatCsv || inputFormat == InputFormatTsv {
+		fsoData, err = parseCsvDataFile(cmd, dataBytes, inputFormat)
+	} else {
+		fsoData, err = parseDataFile(dataBytes)
+	}
 	if err != nil {
 		log.Fatalf("Can't open data file %q: %v", dataFileName, err)
 	}
@@ -89,6 +271,12 @@ func sendDataFromFile(cmd *cobra.Command, dataFileName string) {
 		log.Fatalf("Failed to load data from file %q: empty file", dataFileName)
 		panic("unreachable") // unreachable, keep glanci-lint happy for using fsoData below
 	}
+	if scrubber := getScrubber(cmd); scrubber != nil {
+		scrubber.ScrubFsocData(fsoData)
+	}
+	if model := getValidationModel(cmd); model != nil {
+		validateData(cmd, fsoData, model)
+	}
 
 	for _, entity := range fsoData.Melt {
 		if _, ok := entity.Attributes["telemetry.sdk.name"]; ok {
@@ -187,9 +375,33 @@ func sendDataFromFile(cmd *cobra.Command, dataFileName string) {
 				l.Timestamp = time.Now().UnixNano()
 			}
 		}
+		for _, s := range entity.Spans {
+			// root spans without times end now; their children default to the same times
+			if s.StartTime == 0 && s.EndTime == 0 {
+				s.EndTime = time.Now().UnixNano()
+				s.StartTime = s.EndTime - time.Second.Nanoseconds()
+			}
+		}
+	}
+
+	paced := false
+	if replay, _ := getReplayOptions(cmd); replay != nil {
+		var first, last int64
+		if first, last, paced = replay.rewriteTimestamps(cmd, fsoData.TimeRange, fsoData.MapTimestamps); paced {
+			replayMelt(cmd, fsoData, replay, first, last)
+		}
+	}
+	if !paced {
+		exportMeltStraight(cmd, fsoData)
 	}
 
-	exportMeltStraight(cmd, fsoData)
+	if verify, _ := cmd.Flags().GetBool("verify"); verify {
+		if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+			log.Warn("Skipping the verification of a dry run")
+			return
+		}
+		verifyData(cmd, fsoData, time.Time{}, defaultVerifyTimeout, defaultVerifyInterval)
+	}
 }
 
 func exportMeltStraight(cmd *cobra.Command, fsoData *melt.FsocData) {
@@ -197,26 +409,13 @@ func exportMeltStraight(cmd *cobra.Command, fsoData *melt.FsocData) {
 }
 
 func exportMelt(cmd *cobra.Command, fsoData melt.FsocData) {
-	// construct the exporter with options from the command line
-	exp := &melt.Exporter{}
-	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
-		exp.DryRun = true
-	}
-	dump, _ := cmd.Flags().GetBool("dump")
-	if dump {
-		// prepare a dump function with closure
-		exp.DumpFunc = func(s string) {
-			output.PrintCmdStatus(cmd, s)
-		}
-	}
-	format, _ := cmd.Flags().GetString("output")
-	if format == "" || format == OutputFormatAuto {
-		format = OutputFormatHuman
-	}
-	if dump {
-		exp.DumpFormat = format // set format only if dump is enabled
-	} else {
-		format = "" // clear format specifier if not dumping, ignoring format specifier
+	exp, format := newExporter(cmd)
+	defer exp.Close()
+	dump := exp.DumpFunc != nil
+	if !dump && !exp.DryRun {
+		options, _ := getSendOptions(cmd)
+		sendConcurrently(cmd, exp, meltRequests(exp, &fsoData, options.batchSize), options)
+		return
 	}
 
 	// --- Export data in sections (metrics, logs, spans)
@@ -248,8 +447,38 @@ func exportMelt(cmd *cobra.Command, fsoData melt.FsocData) {
 	}
 }
 
-func loadDataFile(fileName string) (*melt.FsocData, error) {
-	var fsoData *melt.FsocData
+// newExporter constructs the exporter with options from the command line, returning it with the
+// dump format (empty if not dumping)
+func newExporter(cmd *cobra.Command) (*melt.Exporter, string) {
+	exp := &melt.Exporter{}
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		exp.DryRun = true
+	}
+	exp.Protocol, _ = cmd.Flags().GetString("protocol")
+	exp.GrpcEndpoint, _ = cmd.Flags().GetString("grpc-endpoint")
+	exp.Compress, _ = cmd.Flags().GetBool("gzip")
+	exp.MaxInFlight, _ = cmd.Flags().GetInt("max-in-flight")
+	exp.Retries, _ = cmd.Flags().GetInt("retries")
+	dump, _ := cmd.Flags().GetBool("dump")
+	if dump {
+		// prepare a dump function with closure
+		exp.DumpFunc = func(s string) {
+			output.PrintCmdStatus(cmd, s)
+		}
+	}
+	format, _ := cmd.Flags().GetString("output")
+	if format == "" || format == OutputFormatAuto {
+		format = OutputFormatHuman
+	}
+	if dump {
+		exp.DumpFormat = format // set format only if dump is enabled
+	} else {
+		format = "" // clear format specifier if not dumping, ignoring format specifier
+	}
+	return exp, format
+}
+
+func readDataFile(fileName string) []byte {
 	var dataFile *os.File
 
 	if fileName == "" {
@@ -267,8 +496,12 @@ func loadDataFile(fileName string) (*melt.FsocData, error) {
 	if err != nil {
 		log.Fatalf("Can't read the file %q: %v", fileName, err)
 	}
+	return dataBytes
+}
 
-	err = yaml.Unmarshal(dataBytes, &fsoData)
+func parseDataFile(dataBytes []byte) (*melt.FsocData, error) {
+	var fsoData *melt.FsocData
+	err := yaml.Unmarshal(dataBytes, &fsoData)
 	if err != nil {
 		log.Fatalf("Failed to parse fsoc telemetry model file: %v", err)
 	}
@@ -276,6 +509,102 @@ func loadDataFile(fileName string) (*melt.FsocData, error) {
 	return fsoData, nil
 }
 
+// parseCsvDataFile converts a CSV or TSV data file to MELT data, with the mapping file from the
+// command line
+func parseCsvDataFile(cmd *cobra.Command, dataBytes []byte, inputFormat string) (*melt.FsocData, error) {
+	mappingFileName, _ := cmd.Flags().GetString("mapping")
+	if mappingFileName == "" {
+		return nil, fmt.Errorf("--mapping is required for %v data files", inputFormat)
+	}
+	mapping, err := loadCsvMapping(mappingFileName)
+	if err != nil {
+		return nil, err
+	}
+	delimiter := ','
+	if inputFormat == InputFormatTsv {
+		delimiter = '\t'
+	}
+	fsoData, err := parseCsvData(dataBytes, delimiter, mapping)
+	if err != nil {
+		return nil, err
+	}
+	log.WithFields(log.Fields{
+		"format":   inputFormat,
+		"entities": len(fsoData.Melt),
+		"mapping":  mappingFileName,
+	}).Info("Converted rows to MELT data")
+	return fsoData, nil
+}
+
+// detectInputFormat determines the format of a data file from its name and content: CSV and TSV
+// files have such extensions, OTLP JSON starts with an export request object, OTLP protobuf is
+// binary and anything else is the fsoc telemetry model
+func detectInputFormat(dataFileName string, dataBytes []byte) string {
+	trimmed := bytes.TrimSpace(dataBytes)
+	switch {
+	case strings.EqualFold(filepath.Ext(dataFileName), ".tsv"):
+		return InputFormatTsv
+	case strings.EqualFold(filepath.Ext(dataFileName), ".csv"):
+		return InputFormatCsv
+	case bytes.HasPrefix(trimmed, []byte("{")) && otlpJsonKeyRegexp.Match(trimmed):
+		return InputFormatOtlpJson
+	case !utf8.Valid(dataBytes) || bytes.IndexByte(dataBytes, 0) >= 0:
+		return InputFormatOtlpProto
+	default:
+		return InputFormatFsoc
+	}
+}
+
+func sendOtlpData(cmd *cobra.Command, dataFileName string, dataBytes []byte, inputFormat string) {
+	var otlpData *melt.OtlpData
+	var err error
+	if inputFormat == InputFormatOtlpJson {
+		otlpData, err = melt.ParseOtlpJson(dataBytes)
+	} else {
+		otlpData, err = melt.ParseOtlpProto(dataBytes)
+	}
+	if err != nil {
+		log.Fatalf("Failed to parse OTLP data file %q: %v", dataFileName, err)
+	}
+	if scrubber := getScrubber(cmd); scrubber != nil {
+		scrubber.ScrubOtlpData(otlpData)
+	}
+	log.WithFields(log.Fields{
+		"format":   inputFormat,
+		"metrics":  len(otlpData.Metrics),
+		"logs":     len(otlpData.Logs),
+		"spans":    len(otlpData.Spans),
+		"filename": dataFileName,
+	}).Info("Read OTLP export requests")
+
+	if replay, _ := getReplayOptions(cmd); replay != nil {
+		if first, last, paced := replay.rewriteTimestamps(cmd, otlpData.TimeRange, otlpData.MapTimestamps); paced {
+			replayOtlp(cmd, otlpData, replay, first, last)
+			return
+		}
+	}
+
+	exp, format := newExporter(cmd)
+	defer exp.Close()
+	if exp.DumpFunc == nil && !exp.DryRun {
+		options, _ := getSendOptions(cmd)
+		sendConcurrently(cmd, exp, otlpRequests(exp, otlpData), options)
+		return
+	}
+	if exp.DumpFunc == nil {
+		output.PrintCmdStatus(cmd, formatStatusMsg("Sending OTLP telemetry", format))
+	}
+	err = exp.ExportOtlp(otlpData, func(name string) {
+		output.PrintCmdStatus(cmd, formatSection(name, format))
+	})
+	if err != nil {
+		log.Fatalf("Error exporting OTLP data: %v", err)
+	}
+	if exp.DumpFunc == nil {
+		output.PrintCmdStatus(cmd, "\nMELT data sent (see log for traceresponse ID)\n")
+	}
+}
+
 func formatSection(section string, format string) string {
 	switch format {
 	case OutputFormatHuman, OutputFormatYaml, OutputFormatHex:
@@ -0,0 +1,334 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package melt
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/melt"
+)
+
+// sendOptions - how to send the data: in batches of up to batchSize items (0 for all the items of a
+// kind in one request), with parallel senders, writing the data of the requests that failed into
+// rejectedFile (a new file if empty)
+type sendOptions struct {
+	batchSize    int
+	parallel     int
+	rejectedFile string
+}
+
+// sendRequest - a request sending one kind of data of a batch
+type sendRequest struct {
+	kind  string // as in the exporter: metrics, logs or trace
+	items int
+	send  func() error
+}
+
+// sendStats - the statistics of the requests sending a kind of data
+type sendStats struct {
+	Kind       string  `json:"kind"`
+	Accepted   int     `json:"accepted"`
+	Rejected   int     `json:"rejected"`
+	Requests   int     `json:"requests"`
+	Failed     int     `json:"failedRequests"`
+	Retries    int     `json:"retries"`
+	Bytes      int64   `json:"bytes"`
+	Throughput float64 `json:"itemsPerSecond"`
+	LatencyP50 float64 `json:"latencyP50Ms"`
+	LatencyP95 float64 `json:"latencyP95Ms"`
+	LatencyMax float64 `json:"latencyMaxMs"`
+
+	latencies []time.Duration
+}
+
+// sendKinds are the kinds of data, as named by the exporter, with their names in the report
+var sendKinds = []struct{ kind, name string }{{"metrics", "data points"}, {"logs", "logs"}, {"trace", "spans"}}
+
+// getSendOptions returns the send options from the command line
+func getSendOptions(cmd *cobra.Command) (sendOptions, error) {
+	options := sendOptions{}
+	options.batchSize, _ = cmd.Flags().GetInt("batch-size")
+	options.parallel, _ = cmd.Flags().GetInt("parallel")
+	options.rejectedFile, _ = cmd.Flags().GetString("rejected-file")
+	maxInFlight, _ := cmd.Flags().GetInt("max-in-flight")
+	retries, _ := cmd.Flags().GetInt("retries")
+	if options.batchSize < 0 || options.parallel < 1 || maxInFlight < 0 || retries < 0 {
+		return options, fmt.Errorf("--batch-size, --max-in-flight and --retries cannot be negative and --parallel must be at least 1")
+	}
+	return options, nil
+}
+
+// meltRequests returns the requests sending MELT data in batches: for each batch, a request per
+// kind of data it has
+func meltRequests(exp *melt.Exporter, data *melt.FsocData, batchSize int) []*sendRequest {
+	requests := []*sendRequest{}
+	for _, batch := range data.SplitBySize(batchSize) {
+		batch := batch
+		dataPoints, logs, spans := batch.ItemCounts()
+		if dataPoints > 0 {
+			requests = append(requests, &sendRequest{kind: "metrics", items: dataPoints, send: func() error { return exp.ExportMetrics(batch.Melt) }})
+		}
+		if logs > 0 {
+			requests = append(requests, &sendRequest{kind: "logs", items: logs, send: func() error { return exp.ExportLogs(batch.Melt) }})
+		}
+		if spans > 0 {
+			requests = append(requests, &sendRequest{kind: "trace", items: spans, send: func() error { return exp.ExportSpans(batch.Melt) }})
+		}
+	}
+	return requests
+}
+
+// otlpRequests returns the requests sending OTLP data, one per OTLP export request
+func otlpRequests(exp *melt.Exporter, data *melt.OtlpData) []*sendRequest {
+	requests := []*sendRequest{}
+	for _, part := range data.SplitRequests() {
+		part := part
+		dataPoints, logs, spans := part.ItemCounts()
+		r := &sendRequest{kind: "metrics", items: dataPoints, send: func() error { return exp.ExportOtlp(part, func(string) {}) }}
+		switch {
+		case len(part.Logs) > 0:
+			r.kind, r.items = "logs", logs
+		case len(part.Spans) > 0:
+			r.kind, r.items = "trace", spans
+		}
+		requests = append(requests, r)
+	}
+	return requests
+}
+
+// sendConcurrently sends the requests with parallel senders, displaying the progress, then reports
+// the items accepted and rejected, the throughput and the latency of the requests of each kind of
+// data; it fails if any request failed, after sending the others and writing their data into the
+// rejected file. The first request is sent alone, so that the senders share its login, if any.
+func sendConcurrently(cmd *cobra.Command, exp *melt.Exporter, requests []*sendRequest, options sendOptions) {
+	stats := map[string]*sendStats{}
+	for _, k := range sendKinds {
+		stats[k.kind] = &sendStats{Kind: k.name}
+	}
+	var failedPayloads []proto.Message
+	var mutex sync.Mutex
+	exp.OnSent = func(kind string, size int, latency time.Duration) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		stats[kind].Bytes += int64(size)
+		stats[kind].latencies = append(stats[kind].latencies, latency)
+	}
+	exp.OnRetry = func(kind string, attempt int, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		stats[kind].Retries++
+	}
+	exp.OnRejected = func(kind string, rejected int64, message string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		stats[kind].Rejected += int(rejected)
+		stats[kind].Accepted -= int(rejected) // counted as accepted with the request
+	}
+	exp.OnFailed = func(kind string, payload proto.Message, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		failedPayloads = append(failedPayloads, payload)
+	}
+	progress := newSendProgress(len(requests))
+	exp.Quiet = options.parallel > 1 || progress != nil
+	send := func(r *sendRequest) {
+		err := r.send()
+		mutex.Lock()
+		defer mutex.Unlock()
+		s := stats[r.kind]
+		s.Requests++
+		if err != nil {
+			s.Failed++
+			s.Rejected += r.items
+			log.Warnf("Failed to send %d %s: %v", r.items, s.Kind, err)
+		} else {
+			s.Accepted += r.items
+		}
+		progress.update(stats)
+	}
+
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Sending MELT telemetry in %d requests with %d parallel senders\n", len(requests), options.parallel))
+	start := time.Now()
+	if len(requests) > 0 {
+		send(requests[0])
+	}
+	queue := make(chan *sendRequest)
+	var wg sync.WaitGroup
+	for i := 0; i < options.parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range queue {
+				send(r)
+			}
+		}()
+	}
+	for _, r := range requests[min(1, len(requests)):] {
+		queue <- r
+	}
+	close(queue)
+	wg.Wait()
+	elapsed := time.Since(start)
+	progress.finish()
+
+	rejectedFile := ""
+	if len(failedPayloads) > 0 {
+		rejectedFile = writeRejectedFile(cmd, failedPayloads, options.rejectedFile)
+	}
+	printSendReport(cmd, stats, elapsed, rejectedFile)
+}
+
+// writeRejectedFile writes the payloads of the requests that failed into a file of OTLP JSON, one
+// export request per line, as "fsoc melt send" reads them, returning the name of the file
+func writeRejectedFile(cmd *cobra.Command, payloads []proto.Message, fileName string) string {
+	if fileName == "" {
+		fileName = fmt.Sprintf("melt-rejected-%s.json", time.Now().Format("20060102-150405"))
+	}
+	var sb strings.Builder
+	for _, payload := range payloads {
+		line, err := protojson.Marshal(payload)
+		if err != nil {
+			log.Fatalf("(bug) Failed to marshal the data of a failed request: %v", err)
+		}
+		sb.Write(line)
+		sb.WriteString("\n")
+	}
+	if err := os.WriteFile(fileName, []byte(sb.String()), 0o644); err != nil {
+		log.Errorf("Failed to write the data of the requests that failed into %q: %v", fileName, err)
+		return ""
+	}
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Wrote the data of the %d requests that failed into %v\n", len(payloads), fileName))
+	return fileName
+}
+
+func printSendReport(cmd *cobra.Command, stats map[string]*sendStats, elapsed time.Duration, rejectedFile string) {
+	items := []*sendStats{}
+	lines := [][]string{}
+	failed, total := 0, 0
+	var bytes int64
+	for _, k := range sendKinds {
+		s := stats[k.kind]
+		if s.Requests == 0 {
+			continue
+		}
+		s.Throughput = float64(s.Accepted) / elapsed.Seconds()
+		s.LatencyP50, s.LatencyP95, s.LatencyMax = latencyPercentile(s.latencies, 0.5), latencyPercentile(s.latencies, 0.95), latencyPercentile(s.latencies, 1)
+		failed += s.Failed
+		total += s.Requests
+		bytes += s.Bytes
+		items = append(items, s)
+		lines = append(lines, []string{
+			s.Kind,
+			strconv.Itoa(s.Accepted),
+			strconv.Itoa(s.Rejected),
+			strconv.Itoa(s.Requests),
+			strconv.Itoa(s.Failed),
+			strconv.Itoa(s.Retries),
+			formatBytes(s.Bytes),
+			formatRate(s.Throughput, "s"),
+			fmt.Sprintf("%.0fms", s.LatencyP50),
+			fmt.Sprintf("%.0fms", s.LatencyP95),
+			fmt.Sprintf("%.0fms", s.LatencyMax),
+		})
+	}
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Sent %v in %v (%v/s; see log for traceresponse IDs)\n", formatBytes(bytes), elapsed.Round(time.Millisecond), formatBytes(int64(float64(bytes)/elapsed.Seconds()))))
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []*sendStats `json:"items"`
+		Total int          `json:"total"`
+	}{items, len(items)}, &output.Table{
+		Headers: []string{"Kind", "Accepted", "Rejected", "Requests", "Failed", "Retries", "Bytes", "Throughput", "Latency P50", "P95", "Max"},
+		Lines:   lines,
+	})
+	if failed > 0 && rejectedFile != "" {
+		log.Fatalf("%d of %d requests failed; the data they had was not sent and is in %v", failed, total, rejectedFile)
+	}
+	if failed > 0 {
+		log.Fatalf("%d of %d requests failed; the data they had was not sent", failed, total)
+	}
+}
+
+// sendProgress displays the progress of sending requests on a line of the terminal; nil if stderr
+// is not a terminal, e.g., in scripts, where the log records the requests sent
+type sendProgress struct {
+	total int
+	done  int
+}
+
+const sendProgressWidth = 30
+
+func newSendProgress(total int) *sendProgress {
+	if total == 0 || !term.IsTerminal(int(os.Stderr.Fd())) {
+		return nil
+	}
+	return &sendProgress{total: total}
+}
+
+// update displays the progress after a request, with the items accepted and rejected so far
+func (p *sendProgress) update(stats map[string]*sendStats) {
+	if p == nil {
+		return
+	}
+	p.done++
+	accepted, rejected := 0, 0
+	for _, s := range stats {
+		accepted += s.Accepted
+		rejected += s.Rejected
+	}
+	filled := sendProgressWidth * p.done / p.total
+	fmt.Fprintf(os.Stderr, "\r[%s%s] %d/%d requests, %d items accepted, %d rejected ",
+		strings.Repeat("=", filled), strings.Repeat(" ", sendProgressWidth-filled), p.done, p.total, accepted, rejected)
+}
+
+// finish ends the line of the progress
+func (p *sendProgress) finish() {
+	if p != nil {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+// latencyPercentile returns a percentile, from 0 to 1, of latencies, in milliseconds
+func latencyPercentile(latencies []time.Duration, percentile float64) float64 {
+	if len(latencies) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(latencies)
+	slices.Sort(sorted)
+	i := int(float64(len(sorted)-1) * percentile)
+	return float64(sorted[i]) / float64(time.Millisecond)
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%dB", n)
+	}
+}
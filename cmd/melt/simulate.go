@@ -0,0 +1,563 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package melt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/melt"
+)
+
+var meltSimulateCmd = &cobra.Command{
+	Use:   "simulate {--from-solution <dir> | --scenario <name>} [--daemon]",
+	Short: "Send generated telemetry continuously, e.g., to keep a demo tenant alive",
+	Long: `This command sends telemetry for the FMM entity model of a solution, or for a scenario, continuously and
+indefinitely, e.g., to keep a demo tenant alive. The telemetry is generated as with "fsoc melt generate" and
+sent every --interval, with the data points, logs and events of the interval that just ended; incidents of
+scenarios are not simulated.
+
+With --daemon (or "fsoc melt simulate start"), the simulation runs in the background, logging into a file,
+until stopped with "fsoc melt simulate stop"; "fsoc melt simulate status" shows the simulations with what
+they sent. Otherwise, it runs until interrupted with Ctrl-C.
+
+Each simulation has a name, by default the name of the solution or scenario, and keeps its state in the
+fsoc directory of the user's configuration directory: its settings, its entities and the current values of
+their metrics, and what it sent. When a simulation is started again, e.g., after a reboot, it continues with
+the same entities and metric values and, if it stopped less than an hour ago, sends the data it missed
+since. "fsoc melt simulate start --name <name>" restarts a simulation with its previous settings.`,
+	Example: `  fsoc melt simulate --from-solution ./mysolution --profile myagent
+  fsoc melt simulate --scenario k8s-cluster --param pods=20 --daemon --profile myagent
+  fsoc melt simulate status
+  fsoc melt simulate stop k8s-cluster
+  fsoc melt simulate start --name k8s-cluster`,
+	Args: cobra.NoArgs,
+	Run:  meltSimulate,
+}
+
+var meltSimulateStartCmd = &cobra.Command{
+	Use:   "start {--from-solution <dir> | --scenario <name> | --name <simulation>}",
+	Short: "Start a simulation in the background",
+	Long: `This command starts a simulation in the background, as "fsoc melt simulate --daemon" does. Without
+--from-solution or --scenario, it restarts the simulation with the given --name with its previous settings.`,
+	Example: `  fsoc melt simulate start --scenario service-error-spike --profile myagent
+  fsoc melt simulate start --name service-error-spike`,
+	Args: cobra.NoArgs,
+	Run:  meltSimulateStart,
+}
+
+var meltSimulateStopCmd = &cobra.Command{
+	Use:         "stop [NAME]",
+	Short:       "Stop a simulation running in the background",
+	Long:        `This command stops a simulation, by default the only one running. Its state is kept to start it again later.`,
+	Example:     `  fsoc melt simulate stop k8s-cluster`,
+	Args:        cobra.MaximumNArgs(1),
+	Run:         meltSimulateStop,
+	Annotations: map[string]string{config.AnnotationForConfigBypass: ""},
+}
+
+var meltSimulateStatusCmd = &cobra.Command{
+	Use:         "status",
+	Short:       "Show the simulations, whether they are running and what they sent",
+	Example:     `  fsoc melt simulate status -o json`,
+	Args:        cobra.NoArgs,
+	Run:         meltSimulateStatus,
+	Annotations: map[string]string{config.AnnotationForConfigBypass: ""},
+}
+
+const (
+	// simulationMaxCatchUp is the longest time a simulation catches up with when started again
+	simulationMaxCatchUp = time.Hour
+
+	// simulationStartCheck is how long a simulation started in the background is watched for errors
+	simulationStartCheck = 2 * time.Second
+
+	// simulationStopTimeout is how long to wait for a simulation to stop
+	simulationStopTimeout = 10 * time.Second
+)
+
+// simulateIgnoredDefaults are the flags of melt generate that simulations don't have, whose
+// defaults in scenarios are ignored
+var simulateIgnoredDefaults = []string{"duration", "output-file"}
+
+// simulationNamePattern is the pattern of simulation names, which are file names;
+// simulationNameInvalid matches the characters not allowed in them
+var (
+	simulationNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	simulationNameInvalid = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// simulationState is the state of a simulation, kept across runs
+type simulationState struct {
+	Name           string            `json:"name" yaml:"name"`
+	Status         string            `json:"status" yaml:"-"` // running or stopped
+	PID            int               `json:"pid,omitempty" yaml:"pid,omitempty"`
+	Started        time.Time         `json:"started" yaml:"started"`
+	LastSent       time.Time         `json:"lastSent" yaml:"lastSent"` // end of the data sent last
+	DataPoints     int               `json:"dataPoints" yaml:"dataPoints"`
+	Logs           int               `json:"logs" yaml:"logs"`
+	Events         int               `json:"events" yaml:"events"`
+	Requests       int               `json:"requests" yaml:"requests"`
+	FailedRequests int               `json:"failedRequests" yaml:"failedRequests"`
+	LastError      string            `json:"lastError,omitempty" yaml:"lastError,omitempty"`
+	Args           []string          `json:"-" yaml:"args"` // of fsoc, to run the simulation again
+	Dir            string            `json:"-" yaml:"dir"`  // working directory of the simulation
+	Entities       []simulatedEntity `json:"-" yaml:"entities"`
+}
+
+// simulatedEntity is a generated entity of a simulation, with the current values of its metrics
+type simulatedEntity struct {
+	Type       string                     `yaml:"type"`
+	Attributes map[string]any             `yaml:"attributes"`
+	Metrics    map[string]simulatedMetric `yaml:"metrics"`
+}
+
+type simulatedMetric struct {
+	Level      float64 `yaml:"level"`
+	Cumulative float64 `yaml:"cumulative,omitempty"`
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{meltSimulateCmd, meltSimulateStartCmd} {
+		cmd.Flags().String("from-solution", "", "Path to the root directory of the solution whose model to generate data for")
+		cmd.Flags().String("scenario", "", "Name or file of the scenario to generate data for, instead of a solution's model")
+		cmd.Flags().String("scenario-dir", "", "Directory with custom scenarios, one <name>.yaml file per scenario")
+		cmd.Flags().StringToString("param", nil, "Values of the scenario's parameters, e.g., pods=50")
+		cmd.Flags().Int("entities", 10, "Number of entities of each entity type")
+		cmd.Flags().Duration("interval", time.Minute, "Interval between the data points of each metric, and between the batches of data sent")
+		cmd.Flags().Duration("seasonality", 24*time.Hour, "Period of the seasonal cycle of metric values (0 for none)")
+		cmd.Flags().Float64("amplitude", 0.3, "Amplitude of the seasonal cycle, relative to the metric values")
+		cmd.Flags().Float64("error-rate", 0.05, "Fraction of logs and events reporting errors, from 0 to 1")
+		cmd.Flags().Float64("logs-per-hour", 30, "Number of logs per entity per hour")
+		cmd.Flags().Float64("events-per-hour", 6, "Number of events of each event type per entity per hour")
+		cmd.Flags().Int64("seed", 0, "Seed for the random values of new simulations (defaults to a random seed)")
+		cmd.Flags().String("name", "", "Name of the simulation (defaults to the name of the solution or scenario)")
+		cmd.Flags().Bool("dry-run", false, "Generate the data but don't send it to the ingestion API")
+		cmd.MarkFlagsMutuallyExclusive("from-solution", "scenario")
+	}
+	meltSimulateCmd.Flags().Bool("daemon", false, "Run the simulation in the background")
+
+	meltSimulateCmd.AddCommand(meltSimulateStartCmd, meltSimulateStopCmd, meltSimulateStatusCmd)
+	meltCmd.AddCommand(meltSimulateCmd)
+}
+
+func meltSimulate(cmd *cobra.Command, args []string) {
+	if daemon, _ := cmd.Flags().GetBool("daemon"); daemon {
+		meltSimulateStart(cmd, args)
+		return
+	}
+
+	modelName, model, scenario := loadGenerateModel(cmd, simulateIgnoredDefaults...)
+	name := simulationName(cmd, modelName)
+	options := getGenerateOptions(cmd)
+	if scenario != nil {
+		options.counts = scenario.entityCounts()
+	}
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	seed, _ := cmd.Flags().GetInt64("seed")
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	state, err := loadSimulationState(name)
+	if err != nil {
+		log.Fatalf("Failed to read the state of simulation %q: %v", name, err)
+	}
+	if state.running() && state.PID != os.Getpid() {
+		log.Fatalf("Simulation %q is already running (pid %d)", name, state.PID)
+	}
+	generator := newTelemetryGenerator(model, options, rand.New(rand.NewSource(seed)))
+	now := time.Now()
+	start := now.Truncate(options.interval)
+	if state == nil {
+		state = &simulationState{Name: name}
+	} else {
+		if state.restore(generator) {
+			output.PrintCmdStatus(cmd, fmt.Sprintf("Continuing simulation %q with its entities\n", name))
+		}
+		if since := now.Sub(state.LastSent); since < simulationMaxCatchUp && state.LastSent.Before(start) {
+			start = state.LastSent
+			output.PrintCmdStatus(cmd, fmt.Sprintf("Catching up with the last %v\n", since.Round(time.Second)))
+		}
+	}
+	state.Args = simulationArgs(cmd, name)
+	state.Dir, _ = os.Getwd()
+	state.PID, state.Started, state.LastError = os.Getpid(), now, ""
+	if err := state.save(); err != nil {
+		log.Fatalf("Failed to save the state of simulation %q: %v", name, err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	exp := &melt.Exporter{DryRun: dryRun}
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Simulating %d entities, sending data every %v until stopped\n", len(generator.entities), options.interval))
+	for {
+		if end := time.Now().Truncate(options.interval); end.After(start) {
+			state.send(cmd, exp, generator.generate(start, end))
+			state.LastSent, start = end, end
+			state.capture(generator)
+			if err := state.save(); err != nil {
+				log.Warnf("Failed to save the state of simulation %q: %v", name, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			state.PID = 0
+			if err := state.save(); err != nil {
+				log.Warnf("Failed to save the state of simulation %q: %v", name, err)
+			}
+			output.PrintCmdStatus(cmd, fmt.Sprintf("Simulation %q stopped\n", name))
+			return
+		case <-time.After(time.Until(start.Add(options.interval))):
+		}
+	}
+}
+
+func meltSimulateStart(cmd *cobra.Command, args []string) {
+	// the simulation's arguments: from the command line, or its previous ones to restart it
+	var name, dir string
+	var fsocArgs []string
+	if cmd.Flags().Changed("from-solution") || cmd.Flags().Changed("scenario") {
+		modelName, _, _ := loadGenerateModel(cmd, simulateIgnoredDefaults...)
+		getGenerateOptions(cmd) // to fail early on invalid options
+		name = simulationName(cmd, modelName)
+		fsocArgs = simulationArgs(cmd, name)
+		dir, _ = os.Getwd()
+	} else {
+		name, _ = cmd.Flags().GetString("name")
+		if name == "" {
+			log.Fatalf("Either --from-solution, --scenario or the --name of a simulation to restart is required")
+		}
+		state, err := loadSimulationState(name)
+		if err != nil || state == nil {
+			log.Fatalf("Failed to find simulation %q to restart: %v", name, err)
+		}
+		fsocArgs, dir = state.Args, state.Dir
+	}
+	state, err := loadSimulationState(name)
+	if err != nil {
+		log.Fatalf("Failed to read the state of simulation %q: %v", name, err)
+	}
+	if state.running() {
+		log.Fatalf("Simulation %q is already running (pid %d)", name, state.PID)
+	}
+
+	simulationDir, err := simulationDirectory(name)
+	if err != nil {
+		log.Fatalf("Failed to locate the simulation's directory: %v", err)
+	}
+	if err := os.MkdirAll(simulationDir, 0o755); err != nil {
+		log.Fatalf("Failed to create the simulation's directory: %v", err)
+	}
+	logFileName := filepath.Join(simulationDir, "simulate.log")
+	logFile, err := os.OpenFile(logFileName, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		log.Fatalf("Failed to open the simulation's log file: %v", err)
+	}
+	defer logFile.Close()
+	executable, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Failed to locate the fsoc executable: %v", err)
+	}
+
+	daemon := exec.Command(executable, fsocArgs...)
+	daemon.Dir = dir
+	daemon.Stdout, daemon.Stderr = logFile, logFile
+	daemon.SysProcAttr = detachedProcess()
+	log.WithField("command", strings.Join(daemon.Args, " ")).Info("Starting simulation")
+	if err := daemon.Start(); err != nil {
+		log.Fatalf("Failed to start simulation %q: %v", name, err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- daemon.Wait() }()
+	select {
+	case err := <-exited:
+		log.Fatalf("Simulation %q stopped right after starting (%v); see %v", name, err, logFileName)
+	case <-time.After(simulationStartCheck):
+	}
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Started simulation %q in the background (pid %d), logging into %v\n"+
+		"Use \"fsoc melt simulate status\" to check it and \"fsoc melt simulate stop %v\" to stop it\n",
+		name, daemon.Process.Pid, logFileName, name))
+}
+
+func meltSimulateStop(cmd *cobra.Command, args []string) {
+	var state *simulationState
+	if len(args) > 0 {
+		var err error
+		if state, err = loadSimulationState(args[0]); err != nil || state == nil {
+			log.Fatalf("Failed to find simulation %q: %v", args[0], err)
+		}
+	} else {
+		states, err := listSimulationStates()
+		if err != nil {
+			log.Fatalf("Failed to list the simulations: %v", err)
+		}
+		running := []string{}
+		for _, s := range states {
+			if s.running() {
+				state = s
+				running = append(running, s.Name)
+			}
+		}
+		switch len(running) {
+		case 0:
+			log.Fatalf("No simulation is running")
+		case 1:
+		default:
+			log.Fatalf("Several simulations are running (%v); specify the name of the simulation to stop", strings.Join(running, ", "))
+		}
+	}
+	if !state.running() {
+		output.PrintCmdStatus(cmd, fmt.Sprintf("Simulation %q is not running\n", state.Name))
+		return
+	}
+
+	if err := stopProcess(state.PID); err != nil {
+		log.Fatalf("Failed to stop simulation %q (pid %d): %v", state.Name, state.PID, err)
+	}
+	for deadline := time.Now().Add(simulationStopTimeout); processRunning(state.PID); time.Sleep(100 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			log.Fatalf("Simulation %q (pid %d) did not stop within %v", state.Name, state.PID, simulationStopTimeout)
+		}
+	}
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Stopped simulation %q\n", state.Name))
+}
+
+func meltSimulateStatus(cmd *cobra.Command, args []string) {
+	states, err := listSimulationStates()
+	if err != nil {
+		log.Fatalf("Failed to list the simulations: %v", err)
+	}
+	lines := make([][]string, len(states))
+	for i, s := range states {
+		pid := ""
+		s.Status = "stopped"
+		if s.running() {
+			s.Status, pid = "running", strconv.Itoa(s.PID)
+		} else {
+			s.PID = 0
+		}
+		lastSent := ""
+		if !s.LastSent.IsZero() {
+			lastSent = s.LastSent.Local().Format(time.DateTime)
+		}
+		lines[i] = []string{
+			s.Name,
+			s.Status,
+			pid,
+			s.Started.Local().Format(time.DateTime),
+			lastSent,
+			strconv.Itoa(s.DataPoints),
+			strconv.Itoa(s.Logs),
+			strconv.Itoa(s.Events),
+			fmt.Sprintf("%d/%d", s.FailedRequests, s.Requests),
+			s.LastError,
+		}
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []*simulationState `json:"items"`
+		Total int                `json:"total"`
+	}{states, len(states)}, &output.Table{
+		Headers: []string{"Name", "Status", "PID", "Started", "Last Sent", "Data Points", "Logs", "Events", "Failed", "Last Error"},
+		Lines:   lines,
+	})
+}
+
+// simulationName returns the name of the simulation, from --name or else the name of its solution
+// or scenario, with the characters not allowed in file names replaced
+func simulationName(cmd *cobra.Command, modelName string) string {
+	name, _ := cmd.Flags().GetString("name")
+	if name == "" {
+		name = strings.Trim(simulationNameInvalid.ReplaceAllString(modelName, "-"), "-.")
+	}
+	if !simulationNamePattern.MatchString(name) {
+		log.Fatalf("Invalid simulation name %q: only letters, digits, '.', '_' and '-' are allowed", name)
+	}
+	return name
+}
+
+// simulationArgs returns the arguments of fsoc to run a simulation as specified by the command
+// line, for the simulation to run in the background or to be restarted later
+func simulationArgs(cmd *cobra.Command, name string) []string {
+	args := []string{"melt", "simulate"}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		switch f.Name {
+		case "daemon", "name", "output", "fields", "no-version-check":
+		case "param":
+			params, _ := cmd.Flags().GetStringToString(f.Name)
+			for _, param := range sortedKeys(params) {
+				args = append(args, "--param", param+"="+params[param])
+			}
+		default:
+			args = append(args, "--"+f.Name+"="+f.Value.String())
+		}
+	})
+	return append(args, "--name", name, "--no-version-check")
+}
+
+// simulationDirectory returns the directory with the state of a simulation
+func simulationDirectory(name string) (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine the configuration directory: %w", err)
+	}
+	return filepath.Join(configDir, "fsoc", "simulations", name), nil
+}
+
+// loadSimulationState returns the state of a simulation, nil if there is none
+func loadSimulationState(name string) (*simulationState, error) {
+	dir, err := simulationDirectory(name)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "state.yaml"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var state simulationState
+	if err := yaml.Unmarshal(content, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse the state of simulation %q: %w", name, err)
+	}
+	return &state, nil
+}
+
+// listSimulationStates returns the states of all simulations, by name
+func listSimulationStates() ([]*simulationState, error) {
+	dir, err := simulationDirectory("")
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	states := []*simulationState{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		state, err := loadSimulationState(entry.Name())
+		if err != nil {
+			log.Warnf("Skipping simulation %q: %v", entry.Name(), err)
+			continue
+		}
+		if state != nil {
+			states = append(states, state)
+		}
+	}
+	return states, nil
+}
+
+func (s *simulationState) save() error {
+	dir, err := simulationDirectory(s.Name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	content, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	// write a new file and rename it, so that the state is never partially written
+	fileName := filepath.Join(dir, "state.yaml")
+	if err := os.WriteFile(fileName+".tmp", content, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(fileName+".tmp", fileName)
+}
+
+// running tells whether the simulation is running
+func (s *simulationState) running() bool {
+	return s != nil && s.PID != 0 && processRunning(s.PID)
+}
+
+// send sends the data generated for an interval, recording what was sent
+func (s *simulationState) send(cmd *cobra.Command, exp *melt.Exporter, data *melt.FsocData) {
+	dataPoints, logs, events := countTelemetry(data)
+	failed := false
+	for _, export := range []func([]*melt.Entity) error{exp.ExportMetrics, exp.ExportLogs} {
+		s.Requests++
+		if err := export(data.Melt); err != nil {
+			s.FailedRequests++
+			s.LastError = err.Error()
+			log.Warnf("Failed to send the simulated data: %v", err)
+			failed = true
+		}
+	}
+	if failed {
+		return
+	}
+	s.DataPoints += dataPoints
+	s.Logs += logs
+	s.Events += events
+	output.PrintCmdStatus(cmd, fmt.Sprintf("%v: sent %d data points, %d logs and %d events\n", time.Now().Format(time.TimeOnly), dataPoints, logs, events))
+}
+
+// capture records the entities of a generator with the current values of their metrics
+func (s *simulationState) capture(g *telemetryGenerator) {
+	s.Entities = make([]simulatedEntity, len(g.entities))
+	for i, e := range g.entities {
+		metrics := map[string]simulatedMetric{}
+		for metricType, state := range e.metrics {
+			metrics[metricType] = simulatedMetric{Level: state.level, Cumulative: state.cumulative}
+		}
+		s.Entities[i] = simulatedEntity{Type: e.typeName, Attributes: e.attributes, Metrics: metrics}
+	}
+}
+
+// restore sets the entities of a generator, with the values of their metrics, to the recorded
+// ones, if the entities are of the same types, e.g., unless the model or the number of entities
+// changed; it returns whether they were restored
+func (s *simulationState) restore(g *telemetryGenerator) bool {
+	if len(s.Entities) != len(g.entities) {
+		return false
+	}
+	for i, e := range g.entities {
+		if s.Entities[i].Type != e.typeName {
+			return false
+		}
+	}
+	for i, e := range g.entities {
+		e.attributes = s.Entities[i].Attributes
+		for metricType, m := range s.Entities[i].Metrics {
+			if state, found := e.metrics[metricType]; found {
+				state.level, state.cumulative = m.Level, m.Cumulative
+			}
+		}
+	}
+	return true
+}
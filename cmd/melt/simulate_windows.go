@@ -0,0 +1,50 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build windows
+
+package melt
+
+import (
+	"os"
+	"syscall"
+)
+
+// detachedProcessFlag is the DETACHED_PROCESS process creation flag, for processes without a console
+const detachedProcessFlag = 0x00000008
+
+// detachedProcess returns the attributes of a process that keeps running after fsoc exits, without
+// a console
+func detachedProcess() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | detachedProcessFlag, HideWindow: true}
+}
+
+// processRunning tells whether a process is running
+func processRunning(pid int) bool {
+	p, err := os.FindProcess(pid) // fails if there is no such process
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+	return true
+}
+
+// stopProcess stops a process; Windows has no signal to ask it to stop
+func stopProcess(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Kill()
+}
@@ -0,0 +1,210 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package melt
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	sol "github.com/cisco-open/fsoc/cmd/solution"
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/melt"
+)
+
+// unvalidatedAttributePrefixes are the prefixes of resource attributes that are not entity
+// attributes, e.g., the ones that fsoc sets
+var unvalidatedAttributePrefixes = []string{"telemetry.sdk."}
+
+// getValidationModel returns the FMM model to validate MELT data against, as selected on the
+// command line: the model of a local solution (--from-solution), the model of the tenant (--validate)
+// or both, with the local definitions taking precedence; nil if no validation is requested
+func getValidationModel(cmd *cobra.Command) *sol.FmmModel {
+	solutionDir, _ := cmd.Flags().GetString("from-solution")
+	fetch, _ := cmd.Flags().GetBool("validate")
+	if solutionDir == "" && !fetch {
+		return nil
+	}
+
+	var model *sol.FmmModel
+	if solutionDir != "" {
+		_, model = loadSolutionModel(solutionDir)
+	}
+	if fetch {
+		tenantModel, err := sol.FetchFmmModel()
+		if err != nil {
+			log.Fatalf("Failed to fetch the FMM model of the tenant: %v", err)
+		}
+		if model == nil {
+			model = tenantModel
+		} else {
+			model.Merge(tenantModel)
+		}
+	}
+	return model
+}
+
+// validateData checks MELT data against an FMM model before sending it, failing with the list of
+// problems found, as data that doesn't match the model is dropped silently during ingestion
+func validateData(cmd *cobra.Command, data *melt.FsocData, model *sol.FmmModel) {
+	problems := validateFsocData(data, model)
+	if len(problems) == 0 {
+		log.WithFields(log.Fields{"entities": len(data.Melt)}).Info("MELT data matches the FMM model")
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString("The MELT data does not match the FMM model:\n")
+	for _, problem := range problems {
+		fmt.Fprintf(&sb, "  - %v\n", problem)
+	}
+	output.PrintCmdStatus(cmd, sb.String())
+	log.Fatalf("%d error(s) found while validating the MELT data; the data was not sent", len(problems))
+}
+
+// validateFsocData returns the problems of MELT data with respect to an FMM model: unknown entity,
+// metric and event types and attributes, attribute values of the wrong type, missing required
+// attributes and metrics whose unit or content type differ from their definitions
+func validateFsocData(data *melt.FsocData, model *sol.FmmModel) []string {
+	problems := []string{}
+	for i, entity := range data.Melt {
+		where := fmt.Sprintf("entity #%d (%v)", i+1, entity.TypeName)
+		fmmEntity := model.Entity(entity.TypeName)
+		if fmmEntity == nil {
+			problems = append(problems, fmt.Sprintf("%v: unknown entity type %q", where, entity.TypeName))
+			continue
+		}
+		problems = append(problems, validateEntityAttributes(where, entity, fmmEntity)...)
+
+		for _, metric := range entity.Metrics {
+			metricWhere := fmt.Sprintf("%v, metric %v", where, metric.TypeName)
+			fmmMetric, found := model.Metrics[metric.TypeName]
+			switch {
+			case !found:
+				problems = append(problems, fmt.Sprintf("%v: unknown metric type %q", metricWhere, metric.TypeName))
+				continue
+			case !slices.Contains(fmmEntity.MetricTypes, metric.TypeName):
+				problems = append(problems, fmt.Sprintf("%v: not a metric type of entity type %v (%v)", metricWhere, entity.TypeName, strings.Join(fmmEntity.MetricTypes, ", ")))
+			}
+			if metric.Unit != fmmMetric.Unit {
+				problems = append(problems, fmt.Sprintf("%v: unit %q differs from the metric type's unit %q", metricWhere, metric.Unit, fmmMetric.Unit))
+			}
+			if metric.ContentType != "" && metric.ContentType != string(fmmMetric.ContentType) {
+				problems = append(problems, fmt.Sprintf("%v: content type %q differs from the metric type's content type %q", metricWhere, metric.ContentType, fmmMetric.ContentType))
+			}
+			if metric.Type != "" && fmmMetric.Type != "" && metric.Type != string(fmmMetric.Type) {
+				problems = append(problems, fmt.Sprintf("%v: value type %q differs from the metric type's value type %q", metricWhere, metric.Type, fmmMetric.Type))
+			}
+			var defs map[string]*sol.FmmAttributeTypeDef
+			if fmmMetric.AttributeDefinitions != nil {
+				defs = fmmMetric.AttributeDefinitions.Attributes
+			}
+			problems = append(problems, validateAttributes(metricWhere, metric.Attributes, defs, nil)...)
+		}
+
+		for _, l := range entity.Logs {
+			if !l.IsEvent {
+				continue
+			}
+			eventWhere := fmt.Sprintf("%v, event %v", where, l.TypeName)
+			fmmEvent, found := model.Events[l.TypeName]
+			switch {
+			case !found:
+				problems = append(problems, fmt.Sprintf("%v: unknown event type %q", eventWhere, l.TypeName))
+				continue
+			case !slices.Contains(fmmEntity.EventTypes, l.TypeName):
+				problems = append(problems, fmt.Sprintf("%v: not an event type of entity type %v", eventWhere, entity.TypeName))
+			}
+			var defs map[string]*sol.FmmAttributeTypeDef
+			if fmmEvent.AttributeDefinitions != nil {
+				defs = fmmEvent.AttributeDefinitions.Attributes
+			}
+			problems = append(problems, validateAttributes(eventWhere, l.Attributes, defs, nil)...)
+		}
+	}
+	return problems
+}
+
+// validateEntityAttributes checks the attributes of an entity, which are named either as they are
+// defined or qualified with the entity's namespace and name (e.g., k8s.deployment.name for name)
+func validateEntityAttributes(where string, entity *melt.Entity, fmmEntity *sol.FmmEntity) []string {
+	if fmmEntity.AttributeDefinitions == nil || fmmEntity.AttributeDefinitions.FmmAttributeDefinitionsTypeDef == nil {
+		return validateAttributes(where, entity.Attributes, nil, nil)
+	}
+	defs := map[string]*sol.FmmAttributeTypeDef{}
+	qualifiedNames := map[string]string{}
+	for name, def := range fmmEntity.AttributeDefinitions.Attributes {
+		defs[name] = def
+		if !strings.Contains(name, fmmEntity.Namespace.Name) {
+			qualifiedName := fmt.Sprintf("%s.%s.%s", fmmEntity.Namespace.Name, fmmEntity.Name, name)
+			defs[qualifiedName] = def
+			qualifiedNames[name] = qualifiedName
+		}
+	}
+
+	var required []string
+	for _, name := range fmmEntity.AttributeDefinitions.Required {
+		_, found := entity.Attributes[name]
+		if !found && qualifiedNames[name] != "" {
+			_, found = entity.Attributes[qualifiedNames[name]]
+		}
+		if !found {
+			required = append(required, name)
+		}
+	}
+	return validateAttributes(where, entity.Attributes, defs, required)
+}
+
+// validateAttributes checks that attributes are defined, with values of their types, and that
+// required attributes are present
+func validateAttributes(where string, attributes map[string]any, defs map[string]*sol.FmmAttributeTypeDef, missing []string) []string {
+	problems := []string{}
+	for _, name := range missing {
+		problems = append(problems, fmt.Sprintf("%v: missing required attribute %q", where, name))
+	}
+	for _, name := range sortedKeys(attributes) {
+		if slices.ContainsFunc(unvalidatedAttributePrefixes, func(prefix string) bool { return strings.HasPrefix(name, prefix) }) {
+			continue
+		}
+		def, found := defs[name]
+		if !found {
+			problems = append(problems, fmt.Sprintf("%v: unknown attribute %q", where, name))
+			continue
+		}
+		if def != nil && !isAttributeOfType(attributes[name], def.Type) {
+			problems = append(problems, fmt.Sprintf("%v: attribute %q has value %v (%T), expected a %v", where, name, attributes[name], attributes[name], def.Type))
+		}
+	}
+	return problems
+}
+
+// isAttributeOfType tells whether an attribute value, as read from yaml, matches an FMM attribute
+// type; values of other types are not checked
+func isAttributeOfType(value any, attrType string) bool {
+	switch value.(type) {
+	case string:
+		return attrType != "long" && attrType != "double" && attrType != "boolean"
+	case int, int64, uint64:
+		return attrType == "long" || attrType == "double"
+	case float64:
+		return attrType == "double"
+	case bool:
+		return attrType == "boolean"
+	default:
+		return true
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package melt
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/cmd/uql"
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/melt"
+)
+
+var meltVerifyCmd = &cobra.Command{
+	Use:   "verify [DATAFILE]",
+	Short: "Check that sent MELT data shows up in the platform",
+	Long: `
+This command checks that the entities, metrics and events of a fsoc telemetry data file, as sent with
+"fsoc melt send", show up in the platform: it queries UQL for each entity, by its type and attributes,
+with the metrics and events sent for it, repeating the queries until everything shows up or the timeout
+expires, and reports what arrived. It fails if anything is still missing at the end.
+
+Ingestion takes a while, typically a minute or two, and metric data points may be aggregated, so the
+number of data points found may differ from the number sent. Use "fsoc melt send --verify" to check the
+data right after sending it.`,
+	Example: `  fsoc melt verify mydata.yaml
+  fsoc melt verify mydata.yaml --timeout 10m --since 2h`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  meltVerify,
+}
+
+// verifyCheck - an entity, or a metric or event type of an entity, whose arrival is verified
+type verifyCheck struct {
+	Kind    string `json:"kind" yaml:"kind"` // entity, metric or event
+	Type    string `json:"type" yaml:"type"`
+	Entity  string `json:"entity" yaml:"entity"` // the entity's type and name
+	Sent    int    `json:"sent" yaml:"sent"`
+	Found   int    `json:"found" yaml:"found"`
+	Arrived bool   `json:"arrived" yaml:"arrived"`
+}
+
+// verifyQuery - the UQL query for an entity with its metrics and events, each fetched in a column
+// after the entity's id
+type verifyQuery struct {
+	query  string
+	entity *verifyCheck
+	fields []*verifyCheck // the metrics and events, in the order of the query's fields
+}
+
+const (
+	defaultVerifyTimeout  = 5 * time.Minute
+	defaultVerifyInterval = 15 * time.Second
+)
+
+func init() {
+	meltVerifyCmd.Flags().Duration("timeout", defaultVerifyTimeout, "How long to wait for the data to show up")
+	meltVerifyCmd.Flags().Duration("interval", defaultVerifyInterval, "Time between queries while the data hasn't shown up")
+	meltVerifyCmd.Flags().Duration("since", 0, "Start of the time range to look for the data, as a duration before now (default: from the data's earliest timestamp, or 1h)")
+
+	meltCmd.AddCommand(meltVerifyCmd)
+}
+
+func meltVerify(cmd *cobra.Command, args []string) {
+	var dataFileName string
+	if len(args) > 0 {
+		dataFileName = args[0]
+	} else {
+		output.PrintCmdStatus(cmd, "Reading MELT data from STDIN\n")
+	}
+	fsoData, err := parseDataFile(readDataFile(dataFileName))
+	if err != nil || fsoData == nil {
+		log.Fatalf("Failed to load data from file %q: %v", dataFileName, err)
+	}
+
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	since, _ := cmd.Flags().GetDuration("since")
+	var sinceTime time.Time
+	if since > 0 {
+		sinceTime = time.Now().Add(-since)
+	}
+	verifyData(cmd, fsoData, sinceTime, timeout, interval)
+}
+
+// verifyData queries UQL for the entities, metrics and events of MELT data until all of them show
+// up or the timeout expires, then reports what arrived, failing if anything is missing. The time
+// range to look for the data starts at since or, if zero, a minute before the data's earliest
+// timestamp (an hour ago if it has none).
+func verifyData(cmd *cobra.Command, data *melt.FsocData, since time.Time, timeout time.Duration, interval time.Duration) {
+	if since.IsZero() {
+		if first, _ := data.TimeRange(); first != 0 {
+			since = time.Unix(0, first).Add(-time.Minute)
+		} else {
+			since = time.Now().Add(-time.Hour)
+		}
+	}
+	queries := make([]*verifyQuery, 0, len(data.Melt))
+	for _, entity := range data.Melt {
+		queries = append(queries, newVerifyQuery(entity, since))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	deadline := time.Now().Add(timeout)
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Verifying that %d entities show up, for up to %v\n", len(queries), timeout))
+retries:
+	for attempt := 1; ; attempt++ {
+		pending := 0
+		for _, q := range queries {
+			if q.arrived() {
+				continue
+			}
+			if err := q.run(); err != nil {
+				log.Fatalf("Failed to query the data of %v: %v", q.entity.Entity, err)
+			}
+			if !q.arrived() {
+				pending++
+			}
+		}
+		log.WithFields(log.Fields{"attempt": attempt, "pending": pending}).Info("Verified MELT data")
+		if pending == 0 || !time.Now().Before(deadline) {
+			break
+		}
+		wait := min(interval, time.Until(deadline))
+		output.PrintCmdStatus(cmd, fmt.Sprintf("  %d of %d entities still incomplete, retrying in %v\n", pending, len(queries), wait.Round(time.Second)))
+		select {
+		case <-ctx.Done():
+			break retries // report what arrived so far
+		case <-time.After(wait):
+		}
+	}
+	printVerifyResults(cmd, queries)
+}
+
+// newVerifyQuery builds the query for an entity, identified by its type and string attributes, with
+// the metric and event types sent for it
+func newVerifyQuery(entity *melt.Entity, since time.Time) *verifyQuery {
+	var filters []string
+	label := "" // the entity's name attribute, or its first string attribute
+	for _, name := range sortedKeys(entity.Attributes) {
+		value, isString := entity.Attributes[name].(string)
+		if !isString || strings.HasPrefix(name, "telemetry.sdk.") {
+			continue
+		}
+		filters = append(filters, fmt.Sprintf(`[attributes("%s") = "%s"]`, name, strings.ReplaceAll(value, `"`, `\"`)))
+		if label == "" || strings.HasSuffix(name, ".name") {
+			label = value
+		}
+	}
+	entityName := entity.TypeName
+	if label != "" {
+		entityName += " " + label
+	}
+	q := &verifyQuery{entity: &verifyCheck{Kind: "entity", Type: entity.TypeName, Entity: entityName, Sent: 1}}
+
+	// a field per metric or event type, with the number of data points or events sent
+	fields := []string{"id"}
+	byType := map[string]*verifyCheck{}
+	addField := func(kind, typeName, field string, sent int) {
+		if check, found := byType[kind+" "+typeName]; found {
+			check.Sent += sent
+			return
+		}
+		check := &verifyCheck{Kind: kind, Type: typeName, Entity: entityName, Sent: sent}
+		byType[kind+" "+typeName] = check
+		q.fields = append(q.fields, check)
+		fields = append(fields, field)
+	}
+	for _, m := range entity.Metrics {
+		addField("metric", m.TypeName, fmt.Sprintf("metrics(%s) {timestamp, value}", m.TypeName), len(m.DataPoints))
+	}
+	for _, l := range entity.Logs {
+		if l.IsEvent {
+			addField("event", l.TypeName, fmt.Sprintf("events(%s) {timestamp}", l.TypeName), 1)
+		}
+	}
+	q.query = fmt.Sprintf("SINCE %v FETCH %v FROM entities(%v)%v", since.UTC().Format(time.RFC3339), strings.Join(fields, ", "), entity.TypeName, strings.Join(filters, ""))
+	return q
+}
+
+// run executes the query and records what was found
+func (q *verifyQuery) run() error {
+	log.WithField("query", q.query).Info("Querying the sent data")
+	resp, err := uql.Client.ExecuteQuery(&uql.Query{Str: q.query})
+	if err != nil {
+		return err
+	}
+	if resp.HasErrors() {
+		return uql.Errors(resp.Errors())
+	}
+	var rows [][]any
+	if resp.Main() != nil {
+		rows = resp.Main().Values()
+	}
+	q.entity.Found = len(rows)
+	q.entity.Arrived = len(rows) > 0
+	for i, check := range q.fields {
+		check.Found = 0
+		for _, row := range rows {
+			if i+1 < len(row) {
+				check.Found += countLeafRows(row[i+1])
+			}
+		}
+		check.Arrived = check.Found > 0
+	}
+	return nil
+}
+
+// arrived tells whether the entity and all its metrics and events arrived
+func (q *verifyQuery) arrived() bool {
+	if !q.entity.Arrived {
+		return false
+	}
+	for _, check := range q.fields {
+		if !check.Arrived {
+			return false
+		}
+	}
+	return true
+}
+
+// countLeafRows counts the rows of a value's nested data, down to rows without nested data; values
+// that are not data sets count as one row
+func countLeafRows(value any) int {
+	complexValue, ok := value.(uql.Complex)
+	if !ok || complexValue == nil {
+		if value == nil {
+			return 0
+		}
+		return 1
+	}
+	if dataSet, isDataSet := value.(*uql.DataSet); isDataSet && dataSet == nil {
+		return 0
+	}
+	n := 0
+	for _, row := range complexValue.Values() {
+		nested := 0
+		hasNested := false
+		for _, cell := range row {
+			if _, isDataSet := cell.(*uql.DataSet); isDataSet {
+				hasNested = true
+				nested += countLeafRows(cell)
+			}
+		}
+		if hasNested {
+			n += nested
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+func printVerifyResults(cmd *cobra.Command, queries []*verifyQuery) {
+	checks := []*verifyCheck{}
+	for _, q := range queries {
+		checks = append(checks, q.entity)
+		checks = append(checks, q.fields...)
+	}
+	lines := make([][]string, 0, len(checks))
+	missing := 0
+	for _, check := range checks {
+		status := "arrived"
+		if !check.Arrived {
+			status = "missing"
+			missing++
+		}
+		lines = append(lines, []string{check.Kind, check.Type, check.Entity, fmt.Sprint(check.Sent), fmt.Sprint(check.Found), status})
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []*verifyCheck `json:"items"`
+		Total int            `json:"total"`
+	}{checks, len(checks)}, &output.Table{Headers: []string{"Kind", "Type", "Entity", "Sent", "Found", "Status"}, Lines: lines})
+	if missing > 0 {
+		log.Fatalf("%d of %d entities, metrics and events did not show up", missing, len(checks))
+	}
+	output.PrintCmdStatus(cmd, fmt.Sprintf("All %d entities, metrics and events showed up\n", len(checks)))
+}
@@ -1,7 +1,6 @@
 package optimize
 
 import (
-	"fmt"
 	"strings"
 
 	"github.com/apex/log"
@@ -10,6 +9,7 @@ import (
 	"github.com/cisco-open/fsoc/cmd/uql"
 	"github.com/cisco-open/fsoc/config"
 	"github.com/cisco-open/fsoc/platform/api"
+	"github.com/cisco-open/fsoc/platform/uqlquery"
 )
 
 func registerReportCompletion(command *cobra.Command, flag profilerReportFlag) {
@@ -65,8 +65,8 @@ func completeFlagFromKS(flag optimizerFlag, cmd *cobra.Command, args []string, t
 
 func completeFlagFromMelt(flag profilerReportFlag, cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 
-	filterSegments := []string{
-		fmt.Sprintf(`attributes(%s) ~ "%s*"`, flag.K8sAttribute(), toComplete),
+	conditions := []uqlquery.Condition{
+		uqlquery.Attribute(flag.K8sAttribute()).Matches(toComplete + "*"),
 	}
 	flags := cmd.Flags()
 	if flags != nil {
@@ -74,27 +74,19 @@ func completeFlagFromMelt(flag profilerReportFlag, cmd *cobra.Command, args []st
 		for _, f := range profileFlags {
 			val, _ := flags.GetString(f.String())
 			if val != "" {
-				filterSegments = append(
-					filterSegments,
-					fmt.Sprintf(`attributes(%s) = "%s"`, f.K8sAttribute(), val),
-				)
+				conditions = append(conditions, uqlquery.Attribute(f.K8sAttribute()).Eq(val))
 			}
 		}
 	}
 
-	filter := fmt.Sprintf("[%s]", strings.Join(filterSegments, " && "))
-
-	fetchField := flag.ReportAttribute()
-	query := fmt.Sprintf(`
-				SINCE
-					-3d
-				FETCH
-					id,
-					events(k8sprofiler:report){attributes(%s)}
-				FROM
-					entities(k8s:deployment)%s
-				LIMITS
-					events.count(1)`, fetchField, filter)
+	query, err := uqlquery.Fetch(uqlquery.Name("id"), uqlquery.Events("k8sprofiler:report", uqlquery.Attributes(flag.ReportAttribute()))).
+		From(uqlquery.Entities("k8s:deployment").Where(conditions...)).
+		Since("-3d").
+		Limit("events.count", 1).
+		Build()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
 
 	resp, err := uql.ClientV1.ExecuteQuery(&uql.Query{Str: query})
 	if err != nil || resp.HasErrors() {
@@ -36,6 +36,7 @@ import (
 	"github.com/cisco-open/fsoc/config"
 	"github.com/cisco-open/fsoc/output"
 	"github.com/cisco-open/fsoc/platform/api"
+	"github.com/cisco-open/fsoc/platform/uqlquery"
 )
 
 type configureFlags struct {
@@ -120,12 +121,6 @@ and push the configuration to the knowledge store. You may optionally override t
 }
 
 func configureOptimizer(flags *configureFlags) func(cmd *cobra.Command, args []string) error {
-	var workloadTemplate = template.Must(template.New("").Parse(`
-SINCE -1w
-FETCH id
-FROM entities(k8s:deployment)[attributes("k8s.cluster.name") = "{{.Cluster}}" && attributes("k8s.namespace.name") = "{{.Namespace}}" && attributes("k8s.workload.name") = "{{.WorkloadName}}"]
-`))
-
 	return func(cmd *cobra.Command, args []string) error {
 		var profilerReport map[string]any
 		var optimizerConfig OptimizerConfiguration
@@ -160,12 +155,17 @@ FROM entities(k8s:deployment)[attributes("k8s.cluster.name") = "{{.Cluster}}" &&
 			optimizerConfig, optimizerConfigError = getOptimizerConfig("", workloadId, flags.solutionName)
 
 		} else if flags.Cluster != "" { //note MarkFlagsRequiredTogether is checking namespace and workloadName
-			var query string
-			var buff bytes.Buffer
-			if err := workloadTemplate.Execute(&buff, flags); err != nil {
-				return fmt.Errorf("workloadTemplate.Execute: %w", err)
+			query, err := uqlquery.Fetch(uqlquery.Name("id")).
+				From(uqlquery.Entities("k8s:deployment").Where(
+					uqlquery.Attribute("k8s.cluster.name").Eq(flags.Cluster),
+					uqlquery.Attribute("k8s.namespace.name").Eq(flags.Namespace),
+					uqlquery.Attribute("k8s.workload.name").Eq(flags.WorkloadName),
+				)).
+				Since("-1w").
+				Build()
+			if err != nil {
+				return fmt.Errorf("uqlquery.Build: %w", err)
 			}
-			query = buff.String()
 
 			resp, err := uql.ClientV1.ExecuteQuery(&uql.Query{Str: query})
 			if err != nil {
@@ -0,0 +1,90 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxCachedArchives is the number of pushed archives kept in the cache for each solution and tag
+const maxCachedArchives = 10
+
+// getArchiveCacheDir returns the directory where the archives pushed for a solution with a tag are
+// cached, in the user's cache directory (e.g., ~/.cache/fsoc/solutions/<solution>/<tag> on Linux)
+func getArchiveCacheDir(name string, tag string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "fsoc", "solutions", name, tag), nil
+}
+
+// cacheSolutionArchive keeps a copy of a pushed solution archive, so that the version can be
+// pushed again by "fsoc solution rollback"; only the most recently pushed archives are kept
+func cacheSolutionArchive(archivePath string, name string, tag string, version string) error {
+	dir, err := getArchiveCacheDir(name, tag)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	if err := copyLocalFile(archivePath, filepath.Join(dir, version+".zip")); err != nil {
+		return err
+	}
+
+	// remove the oldest archives
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	type cachedArchive struct {
+		path    string
+		modTime int64
+	}
+	archives := []cachedArchive{}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || !strings.HasSuffix(entry.Name(), ".zip") {
+			continue
+		}
+		archives = append(archives, cachedArchive{filepath.Join(dir, entry.Name()), info.ModTime().UnixNano()})
+	}
+	sort.Slice(archives, func(i, j int) bool { return archives[i].modTime > archives[j].modTime })
+	for i := maxCachedArchives; i < len(archives); i++ {
+		if err := os.Remove(archives[i].path); err != nil {
+			return fmt.Errorf("failed to remove cached archive: %w", err)
+		}
+	}
+	return nil
+}
+
+// getCachedSolutionArchive returns the path of the cached archive of a solution version pushed
+// with a tag, or "" if the archive is not in the cache
+func getCachedSolutionArchive(name string, tag string, version string) string {
+	dir, err := getArchiveCacheDir(name, tag)
+	if err != nil {
+		return ""
+	}
+	archivePath := filepath.Join(dir, version+".zip")
+	if info, err := os.Stat(archivePath); err != nil || !info.Mode().IsRegular() {
+		return ""
+	}
+	return archivePath
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apex/log"
+)
+
+// default package budget limits, which are only warned about unless the limits are set in the
+// .fsoclint file
+const (
+	defaultMaxArchiveSize = 10 * 1024 * 1024 // bytes
+	defaultMaxObjectSize  = 1024 * 1024      // bytes
+	defaultMaxObjects     = 10000
+	defaultMaxImageSize   = 1024 * 1024 // bytes
+)
+
+// budgetWarningRatio is the fraction of a limit above which a warning is displayed
+const budgetWarningRatio = 0.8
+
+// PackageBudget limits the size of a solution package; it is set in the packageBudget block of
+// the .fsoclint file. The limits are checked when packaging the solution, before it is uploaded;
+// the limits that are not set (0) are checked against their default, with a warning only.
+type PackageBudget struct {
+	MaxArchiveSize int64 `yaml:"maxArchiveSize"` // size of the zip file, in bytes
+	MaxObjectSize  int64 `yaml:"maxObjectSize"`  // size of a single object, JSON-encoded, in bytes
+	MaxObjects     int   `yaml:"maxObjects"`     // number of objects in the solution
+	MaxImageSize   int64 `yaml:"maxImageSize"`   // size of a documentation image, in bytes
+}
+
+// loadPackageBudget returns the package budget from the .fsoclint file in the solution
+// directory, with no limits set if there is no such file
+func loadPackageBudget(solutionPath string) (*PackageBudget, error) {
+	configPath := filepath.Join(solutionPath, LintConfigFileName)
+	if _, err := os.Stat(configPath); err != nil {
+		configPath = ""
+	}
+	lintConfig, err := loadLintConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	return &lintConfig.PackageBudget, nil
+}
+
+// checkObjectsBudget checks the number and size of the objects of a staged solution, logging
+// warnings for the limits that are almost reached. It returns the limits that are exceeded.
+func (budget *PackageBudget) checkObjectsBudget(stagedPath string) ([]string, error) {
+	manifest, err := getSolutionManifest(stagedPath)
+	if err != nil {
+		return nil, err
+	}
+	objectFiles, _ := loadManifestObjects(stagedPath, manifest) // files that fail to parse are reported by validation
+
+	problems := []string{}
+	nObjects := 0
+	for _, file := range objectFiles {
+		for _, obj := range file.objects {
+			nObjects++
+			data, err := json.Marshal(obj)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %w", file.path, err)
+			}
+			objMap, _ := obj.(map[string]any)
+			description := fmt.Sprintf("%v object %q in %v", file.objType, getLocalObjectName(objMap), file.path)
+			if problem := checkBudgetLimit(description+" is", int64(len(data)), budget.MaxObjectSize, defaultMaxObjectSize, "bytes"); problem != "" {
+				problems = append(problems, problem)
+			}
+		}
+	}
+	if problem := checkBudgetLimit("the solution has", int64(nObjects), int64(budget.MaxObjects), defaultMaxObjects, "objects"); problem != "" {
+		problems = append(problems, problem)
+	}
+	return problems, nil
+}
+
+// checkArchiveBudget checks the size of the solution archive, logging a warning if the limit is
+// almost reached. It returns the exceeded limit, if any.
+func (budget *PackageBudget) checkArchiveBudget(archivePath string) ([]string, error) {
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	if problem := checkBudgetLimit("the solution archive is", info.Size(), budget.MaxArchiveSize, defaultMaxArchiveSize, "bytes"); problem != "" {
+		return []string{problem}, nil
+	}
+	return nil, nil
+}
+
+// checkBudgetLimit returns a description of the problem if the value exceeds the limit and
+// logs a warning if the value is close to the limit. If the limit is not set, the value is
+// checked against the default limit, logging a warning if it is exceeded.
+func checkBudgetLimit(subject string, value int64, limit int64, defaultLimit int64, unit string) string {
+	if limit <= 0 {
+		if value > defaultLimit {
+			log.Warnf("Package budget: %v %d %v, over the default limit of %d %v (set packageBudget in %v to enforce a limit)", subject, value, unit, defaultLimit, unit, LintConfigFileName)
+		}
+		return ""
+	}
+	if value > limit {
+		return fmt.Sprintf("%v %d %v, over the limit of %d %v", subject, value, unit, limit, unit)
+	}
+	if float64(value) > budgetWarningRatio*float64(limit) {
+		log.Warnf("Package budget: %v %d %v, close to the limit of %d %v", subject, value, unit, limit, unit)
+	}
+	return ""
+}
+
+// budgetError returns the error for the exceeded package budget limits
+func budgetError(problems []string) error {
+	return fmt.Errorf("the solution exceeds its package budget (see packageBudget in %v):\n  %v", LintConfigFileName, strings.Join(problems, "\n  "))
+}
@@ -0,0 +1,247 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/output"
+)
+
+var solutionBumpDepsCmd = &cobra.Command{
+	Use:   "bump-deps",
+	Args:  cobra.ExactArgs(0),
+	Short: "Update the locked versions of the solution's dependencies",
+	Long: `This command checks each dependency of the solution for a newer version available in the current tenant and
+updates the solution.lock file with the newer versions (see "fsoc solution lock"); the lockfile is created if it
+doesn't exist. Like "go get -u", updates to a new major version are not applied unless --major is specified.
+
+A compatibility report is displayed for each dependency: the kind of update (patch, minor or major) and, if the
+dependency's type schemas are vendored (see "fsoc solution vendor"), the breaking changes between the vendored
+schemas and the types in the tenant, e.g., a removed property or a new required property. Re-run
+"fsoc solution vendor" after updating to refresh the vendored schemas.
+
+Use --dry-run to display the report without updating the lockfile.`,
+	Example: `  fsoc solution bump-deps
+  fsoc solution bump-deps --dry-run
+  fsoc solution bump-deps -d mysolution --major`,
+	Run: solutionBumpDeps,
+}
+
+// DependencyUpdate is the outcome of checking a dependency for a newer version
+type DependencyUpdate struct {
+	Name     string       `json:"name" yaml:"name"`
+	Locked   string       `json:"locked" yaml:"locked"`
+	Latest   string       `json:"latest" yaml:"latest"`
+	Update   string       `json:"update" yaml:"update"` // none, patch, minor, major or new
+	Breaking []TypeChange `json:"breaking,omitempty" yaml:"breaking,omitempty"`
+	Status   string       `json:"status" yaml:"status"`
+}
+
+func getSolutionBumpDepsCmd() *cobra.Command {
+	solutionBumpDepsCmd.Flags().
+		StringP("directory", "d", "", "Path to the solution root directory (defaults to current dir)")
+
+	solutionBumpDepsCmd.Flags().
+		Bool("major", false, "Update dependencies to new major versions")
+
+	solutionBumpDepsCmd.Flags().
+		Bool("dry-run", false, "Display the available updates without updating the lockfile")
+
+	return solutionBumpDepsCmd
+}
+
+func solutionBumpDeps(cmd *cobra.Command, args []string) {
+	solutionRootDirectory, _ := cmd.Flags().GetString("directory")
+	if solutionRootDirectory == "" {
+		solutionRootDirectory = "."
+	}
+	solutionRootDirectory = absolutizePath(solutionRootDirectory)
+	allowMajor, _ := cmd.Flags().GetBool("major")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	manifest, err := getSolutionManifest(solutionRootDirectory)
+	if err != nil {
+		log.Fatalf("Failed to read the solution manifest: %v", err)
+	}
+	lock, err := readSolutionLock(solutionRootDirectory)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Fatalf("Failed to read %v: %v", SolutionLockFileName, err)
+		}
+		lock = &SolutionLock{}
+	}
+	locked := map[string]string{}
+	for _, dep := range lock.Dependencies {
+		locked[dep.Name] = dep.Version
+	}
+
+	// check each dependency
+	updates := []DependencyUpdate{}
+	newLock := SolutionLock{Dependencies: []LockedDependency{}}
+	missing := []string{}
+	nUpdated := 0
+	for _, name := range getDependencyNames(manifest) {
+		latest, found, err := resolveDependencyVersion(name)
+		if err != nil {
+			log.Fatalf("Failed to resolve dependency %q: %v", name, err)
+		}
+		if !found {
+			missing = append(missing, name)
+			continue
+		}
+		lockedVersion, isLocked := locked[name]
+		update := DependencyUpdate{Name: name, Locked: lockedVersion, Latest: latest}
+		update.Update, err = getDependencyUpdateKind(lockedVersion, isLocked, latest)
+		if err != nil {
+			log.Fatalf("Failed to compare the versions of dependency %q: %v", name, err)
+		}
+		update.Breaking, err = getVendoredTypeBreakingChanges(solutionRootDirectory, name)
+		if err != nil {
+			log.Fatalf("Failed to check the types of dependency %q: %v", name, err)
+		}
+
+		version := latest
+		switch {
+		case update.Update == "none":
+			update.Status = "up to date"
+		case update.Update == "major" && !allowMajor:
+			update.Status = "not updated; use --major to update"
+			version = lockedVersion
+		case dryRun:
+			update.Status = "update available"
+		default:
+			update.Status = "updated"
+			nUpdated++
+		}
+		if len(update.Breaking) > 0 {
+			update.Status += fmt.Sprintf("; %d breaking type change(s)", len(update.Breaking))
+		}
+		updates = append(updates, update)
+		newLock.Dependencies = append(newLock.Dependencies, LockedDependency{Name: name, Version: version})
+	}
+	if len(missing) > 0 {
+		log.Fatalf("Dependencies not available in the tenant: %v", missing)
+	}
+
+	// display report
+	lines := [][]string{}
+	for _, update := range updates {
+		lines = append(lines, []string{update.Name, update.Locked, update.Latest, update.Update, update.Status})
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []DependencyUpdate `json:"items"`
+		Total int                `json:"total"`
+	}{updates, len(updates)}, &output.Table{Headers: []string{"Name", "Locked", "Latest", "Update", "Status"}, Lines: lines})
+	for _, update := range updates {
+		for _, change := range update.Breaking {
+			log.Warnf("%v: %v", strings.TrimSuffix(change.Type+"/"+change.Property, "/"), change.Change)
+		}
+	}
+
+	if dryRun {
+		return
+	}
+	if nUpdated == 0 && len(lock.Dependencies) == len(newLock.Dependencies) {
+		output.PrintCmdStatus(cmd, "All dependencies are up to date\n")
+		return
+	}
+	if err := writeSolutionLock(solutionRootDirectory, &newLock); err != nil {
+		log.Fatalf("Failed to write %v: %v", SolutionLockFileName, err)
+	}
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Updated %d dependencies in %v\n", nUpdated, filepath.Join(solutionRootDirectory, SolutionLockFileName)))
+}
+
+// getDependencyUpdateKind classifies the update from the locked version to the latest one:
+// none, patch, minor, major or new (not locked yet)
+func getDependencyUpdateKind(lockedVersion string, isLocked bool, latest string) (string, error) {
+	if !isLocked {
+		return "new", nil
+	}
+	if lockedVersion == latest || latest == "" {
+		return "none", nil // e.g., system solutions, which have no version
+	}
+	if lockedVersion == "" {
+		return "new", nil
+	}
+	lockedSemver, err := semver.NewVersion(lockedVersion)
+	if err != nil {
+		return "", fmt.Errorf("invalid locked version %q: %w", lockedVersion, err)
+	}
+	latestSemver, err := semver.NewVersion(latest)
+	if err != nil {
+		return "", fmt.Errorf("invalid version %q: %w", latest, err)
+	}
+	switch {
+	case !latestSemver.GreaterThan(lockedSemver):
+		return "none", nil // the locked version is checked by fsoc solution lock --verify
+	case latestSemver.Major() != lockedSemver.Major():
+		return "major", nil
+	case latestSemver.Minor() != lockedSemver.Minor():
+		return "minor", nil
+	default:
+		return "patch", nil
+	}
+}
+
+// getVendoredTypeBreakingChanges compares the dependency's vendored type schemas, if any, with the
+// dependency's types in the tenant and returns the breaking changes
+func getVendoredTypeBreakingChanges(solutionRoot string, name string) ([]TypeChange, error) {
+	files, err := filepath.Glob(filepath.Join(solutionRoot, VendorDirName, name, "*.schema.json"))
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+	vendoredTypes := map[string]*KnowledgeDef{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var schema map[string]any
+		if err := json.Unmarshal(data, &schema); err != nil {
+			return nil, fmt.Errorf("failed to parse vendored schema %q: %w", file, err)
+		}
+		typeName := strings.TrimSuffix(filepath.Base(file), ".schema.json")
+		vendoredTypes[typeName] = &KnowledgeDef{Name: typeName, JsonSchema: schema}
+	}
+
+	types, err := getSolutionTypes(name)
+	if err != nil {
+		return nil, err
+	}
+	tenantTypes := map[string]*KnowledgeDef{}
+	for _, typeDef := range types {
+		schema, _ := typeDef.JsonSchema.(map[string]any)
+		tenantTypes[typeDef.Name] = &KnowledgeDef{Name: typeDef.Name, JsonSchema: schema}
+	}
+
+	breaking := []TypeChange{}
+	for _, change := range compareKnowledgeTypes(vendoredTypes, tenantTypes) {
+		if change.Breaking {
+			change.Type = name + ":" + change.Type
+			breaking = append(breaking, change)
+		}
+	}
+	return breaking, nil
+}
@@ -15,7 +15,13 @@
 package solution
 
 import (
+	"bytes"
 	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/apex/log"
@@ -25,26 +31,57 @@ import (
 	"github.com/cisco-open/fsoc/output"
 )
 
+// ChangelogFileName is the name of the changelog file updated by "solution bump --changelog"
+const ChangelogFileName = "CHANGELOG.md"
+
 var solutionBumpCmd = &cobra.Command{
-	Use:   "bump",
-	Short: "Increment the patch version of the solution",
-	Long: `Increment the patch version of the solution in the manifest to prepare
-it for validation or push.`,
-	Example:          `  fsoc solution bump`,
-	Args:             cobra.ExactArgs(0),
+	Use:   "bump [major|minor|patch]",
+	Short: "Increment the version of the solution",
+	Long: `Increment the version of the solution in the manifest to prepare it for validation or push.
+By default, the patch version is incremented; specify major or minor to increment these instead (the
+lower parts of the version are reset to 0).
+
+With --changelog, a section for the new version is added at the top of the CHANGELOG.md file in the solution
+directory, listing the git commits that changed the solution directory since the solution was last pushed.
+Each successful push from a git repository records the pushed commit as the refs/fsoc/pushed/<solution-name>
+git reference; if the solution was never pushed from the repository, the most recent git tag reachable from
+HEAD is used instead. Use --since to list the commits since another git reference.
+
+Note that "fsoc solution push" refuses to push a solution whose version is not newer than the version deployed
+with the same tag; use bump (or push --bump) to increment it.`,
+	Example: `  fsoc solution bump
+  fsoc solution bump minor
+  fsoc solution bump major -d mysolution --changelog
+  fsoc solution bump --changelog --since mysolution-1.2.0`,
+	Args:             cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+	ValidArgs:        []string{"major", "minor", "patch"},
 	Run:              bumpSolutionVersion,
 	Annotations:      map[string]string{config.AnnotationForConfigBypass: ""},
 	TraverseChildren: true,
 }
 
 func getSolutionBumpCmd() *cobra.Command {
-	//TODO: consider adding a --minor flag to bump the minor version instead of
-	//the patch.
+	solutionBumpCmd.Flags().
+		StringP("directory", "d", "", "Path to the solution root directory (defaults to current dir)")
+
+	solutionBumpCmd.Flags().
+		Bool("changelog", false, "Add the git commits since the last push to "+ChangelogFileName)
+
+	solutionBumpCmd.Flags().
+		String("since", "", "Git reference to list the changelog commits from (defaults to the last pushed commit)")
+
 	return solutionBumpCmd
 }
 
 func bumpSolutionVersion(cmd *cobra.Command, args []string) {
-	manifestDir := "."
+	manifestDir, _ := cmd.Flags().GetString("directory")
+	if manifestDir == "" {
+		manifestDir = "."
+	}
+	part := "patch"
+	if len(args) > 0 {
+		part = args[0]
+	}
 
 	manifest, err := getSolutionManifest(manifestDir)
 	if err != nil {
@@ -52,7 +89,7 @@ func bumpSolutionVersion(cmd *cobra.Command, args []string) {
 	}
 	oldVer := manifest.SolutionVersion
 
-	if err = bumpManifestPatchVersion(manifest); err != nil {
+	if err = bumpManifestVersion(manifest, part); err != nil {
 		log.Fatalf(err.Error())
 	}
 	newVer := manifest.SolutionVersion
@@ -62,9 +99,23 @@ func bumpSolutionVersion(cmd *cobra.Command, args []string) {
 	}
 
 	output.PrintCmdStatus(cmd, fmt.Sprintf("Successfully bumped solution version from %v to %v\n", oldVer, newVer))
+
+	if changelog, _ := cmd.Flags().GetBool("changelog"); changelog {
+		since, _ := cmd.Flags().GetString("since")
+		nCommits, err := updateChangelog(manifestDir, manifest.GetSolutionName(), newVer, since)
+		if err != nil {
+			log.Fatalf("Failed to update %v: %v", ChangelogFileName, err)
+		}
+		output.PrintCmdStatus(cmd, fmt.Sprintf("Added %d change(s) to %v\n", nCommits, filepath.Join(manifestDir, ChangelogFileName)))
+	}
 }
 
 func bumpManifestPatchVersion(m *Manifest) error {
+	return bumpManifestVersion(m, "patch")
+}
+
+// bumpManifestVersion increments the major, minor or patch part of the solution version
+func bumpManifestVersion(m *Manifest, part string) error {
 	ver, err := semver.StrictNewVersion(m.SolutionVersion)
 	if err != nil {
 		return fmt.Errorf("failed to semver parse solution version %q: %w", m.SolutionVersion, err)
@@ -76,9 +127,111 @@ func bumpManifestPatchVersion(m *Manifest) error {
 		return fmt.Errorf("cannot bump current version %q because it has prelease and/or metadata info; please set the desired new version manually in the manifest", m.SolutionVersion)
 	}
 
-	// bump patch version and update into the manifest
-	newVer := ver.IncPatch()
+	// bump version and update into the manifest
+	var newVer semver.Version
+	switch part {
+	case "major":
+		newVer = ver.IncMajor()
+	case "minor":
+		newVer = ver.IncMinor()
+	case "patch":
+		newVer = ver.IncPatch()
+	default:
+		return fmt.Errorf("unknown version part %q; must be major, minor or patch", part)
+	}
 	m.SolutionVersion = newVer.String()
 
 	return nil
 }
+
+// updateChangelog adds a section for the version at the top of the solution's changelog,
+// listing the subjects of the git commits that changed the solution directory since
+// the given git reference or, if empty, since the last pushed commit of the solution (or
+// the most recent tag, if it was never pushed). Returns the number of commits.
+func updateChangelog(solutionDir string, solutionName string, version string, since string) (int, error) {
+	if since == "" {
+		if _, err := runGit(solutionDir, "rev-parse", "--verify", "--quiet", pushedCommitRef(solutionName)); err == nil {
+			since = pushedCommitRef(solutionName)
+		}
+	}
+	if since == "" {
+		tag, err := runGit(solutionDir, "describe", "--tags", "--abbrev=0")
+		if err != nil {
+			log.Warnf("The solution was not pushed from this repository and no git tag was found, listing all commits: %v", err)
+		}
+		since = tag
+	}
+	revisions := "HEAD"
+	if since != "" {
+		revisions = since + "..HEAD"
+	}
+	commits, err := runGit(solutionDir, "log", "--no-merges", "--pretty=format:%s (%h)", revisions, "--", ".")
+	if err != nil {
+		return 0, err
+	}
+
+	var section bytes.Buffer
+	section.WriteString(fmt.Sprintf("## %v - %v\n\n", version, time.Now().Format(time.DateOnly)))
+	nCommits := 0
+	for _, commit := range strings.Split(commits, "\n") {
+		if commit != "" {
+			section.WriteString("- " + commit + "\n")
+			nCommits++
+		}
+	}
+	if nCommits == 0 {
+		section.WriteString("- No changes\n")
+	}
+	section.WriteString("\n")
+
+	// insert after the title, if any, or at the top
+	path := filepath.Join(solutionDir, ChangelogFileName)
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	if len(content) == 0 {
+		content = []byte("# Changelog\n\n")
+	}
+	var newContent bytes.Buffer
+	if bytes.HasPrefix(content, []byte("# ")) {
+		if i := bytes.Index(content, []byte("\n")); i >= 0 {
+			newContent.Write(content[:i+1])
+			newContent.WriteString("\n")
+			content = bytes.TrimLeft(content[i+1:], "\n")
+		}
+	}
+	newContent.Write(section.Bytes())
+	newContent.Write(content)
+	return nCommits, os.WriteFile(path, newContent.Bytes(), 0o644)
+}
+
+// pushedCommitRef returns the git reference that records the last pushed commit of a solution
+func pushedCommitRef(solutionName string) string {
+	return "refs/fsoc/pushed/" + solutionName
+}
+
+// recordPushedCommit records the git commit a solution was pushed from, if its directory is
+// in a git repository, so that the changelog of its next version lists the changes since
+func recordPushedCommit(solutionDir string, solutionName string) {
+	if _, err := runGit(solutionDir, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		log.WithField("directory", solutionDir).Info("Solution is not in a git repository, not recording the pushed commit")
+		return
+	}
+	if _, err := runGit(solutionDir, "update-ref", pushedCommitRef(solutionName), "HEAD"); err != nil {
+		log.Warnf("Failed to record the pushed commit: %v", err)
+	}
+}
+
+// runGit runs a git command in the given directory and returns its trimmed output
+func runGit(dir string, args ...string) (string, error) {
+	gitCmd := exec.Command("git", args...)
+	gitCmd.Dir = dir
+	var stderr bytes.Buffer
+	gitCmd.Stderr = &stderr
+	out, err := gitCmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %v failed: %v: %v", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
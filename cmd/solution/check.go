@@ -127,35 +127,18 @@ func checkComponentDef(cmd *cobra.Command, compDef ComponentDef, cfg *config.Con
 	}
 	schemaLoader := gojsonschema.NewStringLoader(w.String())
 
-	compDefFile := openFile(compDef.ObjectsFile)
-	defer compDefFile.Close()
-
-	compDefBytes, _ := io.ReadAll(compDefFile)
-
-	x := bytes.TrimLeft(compDefBytes, " \t\r\n")
+	doc, err := readObjectsFile(compDef.ObjectsFile)
+	if err != nil {
+		log.Fatalf("Failed to parse component file %q: %v", compDef.ObjectsFile, err)
+	}
 
-	isArray := len(x) > 0 && x[0] == '['
-	// isObject := len(x) > 0 && x[0] == '{'
-	if isArray {
-		var jsonArray []map[string]interface{}
-		err = json.Unmarshal(compDefBytes, &jsonArray)
-		if err != nil {
-			log.Errorf("Failed to parse component: %v", err)
-		}
-		for _, object := range jsonArray {
-			w := new(bytes.Buffer)
-			err = output.WriteJson(object, w)
-			if err != nil {
-				log.Errorf("Couldn't marshal object to json: %v", err)
-			}
-			documentLoader := gojsonschema.NewStringLoader(w.String())
-			validate(cmd, schemaLoader, documentLoader, compDef)
+	if objects, isArray := doc.([]any); isArray {
+		for _, object := range objects {
+			validate(cmd, schemaLoader, gojsonschema.NewGoLoader(object), compDef)
 		}
 	} else {
-		documentLoader := gojsonschema.NewStringLoader(string(compDefBytes))
-		validate(cmd, schemaLoader, documentLoader, compDef)
+		validate(cmd, schemaLoader, gojsonschema.NewGoLoader(doc), compDef)
 	}
-
 }
 
 func validate(cmd *cobra.Command, schemaLoader, documentLoader gojsonschema.JSONLoader, compDef ComponentDef) {
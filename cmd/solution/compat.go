@@ -0,0 +1,440 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/cmdkit/jsondoc"
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+)
+
+var solutionCompatCmd = &cobra.Command{
+	Use:   "compat [<old-solution> <new-solution>]",
+	Args:  cobra.RangeArgs(0, 2),
+	Short: "Check a new solution version for breaking changes to its knowledge types",
+	Long: `This command compares the knowledge types of two versions of a solution and classifies each change
+as safe or breaking for the objects that already exist in tenants. Without arguments, the local solution is
+compared with the version deployed with the given tag (like "fsoc solution diff"); otherwise, the two arguments
+are the old and the new version, each either a solution archive (.zip) or a solution directory.
+
+Breaking changes are:
+  - removing a type, a property or an allowed layer
+  - changing the identifying properties of a type or adding secure properties
+  - adding a required property or making an existing property required
+  - tightening a property's schema: changing its type, removing enum values, adding or changing a pattern,
+    format or const, increasing minimums or decreasing maximums, or disallowing additional properties
+Adding types, optional properties and allowed layers, and loosening schemas are safe changes.
+
+The command fails if there are breaking changes and the new version does not have a higher major version
+than the old one, making it suitable for CI pipelines.`,
+	Example: `  fsoc solution compat
+  fsoc solution compat --tag stable -d mysolution
+  fsoc solution compat mysolution-1.2.0.zip mysolution-1.3.0.zip
+  fsoc solution compat build/old mysolution`,
+	Run: solutionCompat,
+	Annotations: map[string]string{
+		config.AnnotationForConfigBypass: "", // needed only when comparing with the deployed version, checked in solutionCompat
+	},
+}
+
+// TypeChange is a change to a knowledge type between two versions of a solution
+type TypeChange struct {
+	Type     string `json:"type" yaml:"type"`
+	Property string `json:"property,omitempty" yaml:"property,omitempty"` // path of the property in the type's schema, if any
+	Change   string `json:"change" yaml:"change"`
+	Breaking bool   `json:"breaking" yaml:"breaking"`
+}
+
+// schema keywords that set lower and upper limits on values
+var (
+	schemaLowerLimitKeywords = []string{"minimum", "exclusiveMinimum", "minLength", "minItems", "minProperties"}
+	schemaUpperLimitKeywords = []string{"maximum", "exclusiveMaximum", "maxLength", "maxItems", "maxProperties"}
+)
+
+func getSolutionCompatCmd() *cobra.Command {
+	addTagFlags(solutionCompatCmd) // tag, stable
+
+	solutionCompatCmd.Flags().
+		StringP("directory", "d", "", "Path to the solution root directory, when comparing with the deployed version (defaults to current dir)")
+
+	solutionCompatCmd.Flags().
+		Bool("no-isolate", false, "Disable fsoc-supported solution pseudo-isolation")
+
+	addVariableFlags(solutionCompatCmd)
+
+	return solutionCompatCmd
+}
+
+func solutionCompat(cmd *cobra.Command, args []string) {
+	var oldDirectory, newDirectory string
+	switch len(args) {
+	case 0:
+		// the command bypasses the config check to allow offline comparison, so check here
+		if config.GetCurrentContext() == nil {
+			log.Fatal(`fsoc is not configured, please use "fsoc config create" to configure an initial context`)
+		}
+		var cleanup func()
+		newDirectory, oldDirectory, _, _, cleanup = prepareLocalAndDeployed(cmd)
+		defer cleanup()
+	case 2:
+		var cleanup func()
+		oldDirectory, cleanup = prepareCompatSolution(cmd, args[0])
+		defer cleanup()
+		newDirectory, cleanup = prepareCompatSolution(cmd, args[1])
+		defer cleanup()
+	default:
+		_ = cmd.Help()
+		log.Fatal("Specify both the old and the new solution, or neither to compare with the deployed version")
+	}
+
+	oldManifest, err := getSolutionManifest(oldDirectory)
+	if err != nil {
+		log.Fatalf("Failed to read the old solution's manifest: %v", err)
+	}
+	newManifest, err := getSolutionManifest(newDirectory)
+	if err != nil {
+		log.Fatalf("Failed to read the new solution's manifest: %v", err)
+	}
+	oldTypes, err := loadKnowledgeTypes(oldDirectory, oldManifest)
+	if err != nil {
+		log.Fatalf("Failed to read the old solution's types: %v", err)
+	}
+	newTypes, err := loadKnowledgeTypes(newDirectory, newManifest)
+	if err != nil {
+		log.Fatalf("Failed to read the new solution's types: %v", err)
+	}
+	changes := compareKnowledgeTypes(oldTypes, newTypes)
+
+	nBreaking := 0
+	lines := [][]string{}
+	for _, change := range changes {
+		impact := "safe"
+		if change.Breaking {
+			impact = "breaking"
+			nBreaking++
+		}
+		lines = append(lines, []string{change.Type, change.Property, change.Change, impact})
+	}
+	if len(changes) == 0 {
+		output.PrintCmdStatus(cmd, fmt.Sprintf("No changes to the knowledge types between versions %v and %v.\n", oldManifest.SolutionVersion, newManifest.SolutionVersion))
+		return
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []TypeChange `json:"items"`
+		Total int          `json:"total"`
+	}{changes, len(changes)}, &output.Table{Headers: []string{"Type", "Property", "Change", "Impact"}, Lines: lines})
+	if nBreaking == 0 {
+		output.PrintCmdStatus(cmd, fmt.Sprintf("All %d changes are safe.\n", len(changes)))
+		return
+	}
+
+	majorBump, err := isMajorVersionBump(oldManifest.SolutionVersion, newManifest.SolutionVersion)
+	if err != nil {
+		log.Fatalf("Failed to compare the solution versions: %v", err)
+	}
+	if !majorBump {
+		log.Fatalf("Found %d breaking changes, but version %v is not a major version bump from %v", nBreaking, newManifest.SolutionVersion, oldManifest.SolutionVersion)
+	}
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Found %d breaking changes, allowed by the major version bump from %v to %v.\n", nBreaking, oldManifest.SolutionVersion, newManifest.SolutionVersion))
+}
+
+// prepareCompatSolution returns the solution directory for a solution archive or directory. Archives
+// are inspected and extracted, directories are rendered the way they would be packaged. The returned
+// function removes the temporary directories and should be called when done.
+func prepareCompatSolution(cmd *cobra.Command, source string) (string, func()) {
+	info, err := os.Stat(source)
+	if err != nil {
+		log.Fatalf("Failed to access %q: %v", source, err)
+	}
+	if !info.IsDir() {
+		inspection, dir, err := inspectArchive(source)
+		if err != nil {
+			log.Fatalf("Failed to inspect archive %q: %v", source, err)
+		}
+		if dir == "" {
+			log.Fatalf("The solution archive %q is not safe to extract; run fsoc solution inspect for details", source)
+		}
+		return filepath.Join(dir, inspection.root), func() { os.RemoveAll(dir) }
+	}
+
+	if !isSolutionPackageRoot(source) {
+		log.Fatalf("No solution manifest found in %q", source)
+	}
+	stagedDirectory, err := stageSolution(absolutizePath(source), "", getStageOptions(cmd))
+	if err != nil {
+		log.Fatalf("Failed to prepare solution %q: %v", source, err)
+	}
+	return stagedDirectory, func() { os.RemoveAll(filepath.Dir(stagedDirectory)) }
+}
+
+// loadKnowledgeTypes reads the knowledge types defined in a solution, by name
+func loadKnowledgeTypes(root string, manifest *Manifest) (map[string]*KnowledgeDef, error) {
+	types := map[string]*KnowledgeDef{}
+	for _, typeFile := range manifest.Types {
+		doc, err := readObjectsFile(filepath.Join(root, typeFile))
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", typeFile, err)
+		}
+		docs, isArray := doc.([]any)
+		if !isArray {
+			docs = []any{doc}
+		}
+		for _, doc := range docs {
+			typeDef := &KnowledgeDef{}
+			if err := remarshal(doc, typeDef); err != nil {
+				return nil, fmt.Errorf("%v: %w", typeFile, err)
+			}
+			types[typeDef.Name] = typeDef
+		}
+	}
+	return types, nil
+}
+
+// compareKnowledgeTypes classifies the changes between the old and the new types, sorted by type
+func compareKnowledgeTypes(oldTypes map[string]*KnowledgeDef, newTypes map[string]*KnowledgeDef) []TypeChange {
+	names := []string{}
+	for name := range oldTypes {
+		names = append(names, name)
+	}
+	for name := range newTypes {
+		if _, found := oldTypes[name]; !found {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := []TypeChange{}
+	for _, name := range names {
+		oldType, inOld := oldTypes[name]
+		newType, inNew := newTypes[name]
+		add := func(property string, breaking bool, format string, args ...any) {
+			changes = append(changes, TypeChange{Type: name, Property: property, Change: fmt.Sprintf(format, args...), Breaking: breaking})
+		}
+		switch {
+		case !inOld:
+			add("", false, "type added")
+			continue
+		case !inNew:
+			add("", true, "type removed")
+			continue
+		}
+
+		if !slices.Equal(oldType.IdentifyingProperties, newType.IdentifyingProperties) {
+			add("", true, "identifying properties changed from %v to %v", oldType.IdentifyingProperties, newType.IdentifyingProperties)
+		}
+		for _, layer := range oldType.AllowedLayers {
+			if !slices.Contains(newType.AllowedLayers, layer) {
+				add("", true, "allowed layer %v removed", layer)
+			}
+		}
+		for _, layer := range newType.AllowedLayers {
+			if !slices.Contains(oldType.AllowedLayers, layer) {
+				add("", false, "allowed layer %v added", layer)
+			}
+		}
+		for _, property := range newType.SecureProperties {
+			if !slices.Contains(oldType.SecureProperties, property) {
+				add(property, true, "secure property added")
+			}
+		}
+		for _, property := range oldType.SecureProperties {
+			if !slices.Contains(newType.SecureProperties, property) {
+				add(property, false, "secure property removed")
+			}
+		}
+		compareSchemas("", oldType.JsonSchema, newType.JsonSchema, add)
+	}
+	return changes
+}
+
+// compareSchemas classifies the changes between two JSON schemas of a property (or of the whole
+// object, if path is empty), recursing into the object's properties and the array's items
+func compareSchemas(path string, oldSchema map[string]any, newSchema map[string]any, add func(string, bool, string, ...any)) {
+	// type
+	oldTypes, newTypes := getSchemaTypes(oldSchema), getSchemaTypes(newSchema)
+	if len(newTypes) > 0 {
+		for _, t := range oldTypes {
+			if !slices.Contains(newTypes, t) && !(t == "integer" && slices.Contains(newTypes, "number")) {
+				add(path, true, "type changed from %v to %v", strings.Join(oldTypes, ","), strings.Join(newTypes, ","))
+				break
+			}
+		}
+		if len(oldTypes) == 0 {
+			add(path, true, "type %v added", strings.Join(newTypes, ","))
+		}
+	}
+
+	// enumerated values
+	oldEnum, oldHasEnum := oldSchema["enum"].([]any)
+	newEnum, newHasEnum := newSchema["enum"].([]any)
+	switch {
+	case newHasEnum && !oldHasEnum:
+		add(path, true, "enum added")
+	case oldHasEnum && !newHasEnum:
+		add(path, false, "enum removed")
+	case oldHasEnum && newHasEnum:
+		for _, value := range oldEnum {
+			if !slices.ContainsFunc(newEnum, func(v any) bool { return reflect.DeepEqual(v, value) }) {
+				add(path, true, "enum value %v removed", value)
+			}
+		}
+		for _, value := range newEnum {
+			if !slices.ContainsFunc(oldEnum, func(v any) bool { return reflect.DeepEqual(v, value) }) {
+				add(path, false, "enum value %v added", value)
+			}
+		}
+	}
+
+	// constraints on values
+	for _, keyword := range []string{"pattern", "format", "const"} {
+		oldValue, inOld := oldSchema[keyword]
+		newValue, inNew := newSchema[keyword]
+		switch {
+		case inNew && !inOld:
+			add(path, true, "%v %v added", keyword, jsondoc.CompactValue(newValue))
+		case inOld && !inNew:
+			add(path, false, "%v removed", keyword)
+		case inOld && inNew && !reflect.DeepEqual(oldValue, newValue):
+			add(path, true, "%v changed from %v to %v", keyword, jsondoc.CompactValue(oldValue), jsondoc.CompactValue(newValue))
+		}
+	}
+	for _, keyword := range schemaLowerLimitKeywords {
+		compareSchemaLimit(path, keyword, oldSchema, newSchema, func(oldLimit, newLimit float64) bool { return newLimit > oldLimit }, add)
+	}
+	for _, keyword := range schemaUpperLimitKeywords {
+		compareSchemaLimit(path, keyword, oldSchema, newSchema, func(oldLimit, newLimit float64) bool { return newLimit < oldLimit }, add)
+	}
+	if newSchema["additionalProperties"] == false && oldSchema["additionalProperties"] != false {
+		add(path, true, "additional properties disallowed")
+	}
+	if oldSchema["additionalProperties"] == false && newSchema["additionalProperties"] != false {
+		add(path, false, "additional properties allowed")
+	}
+
+	// properties
+	oldProperties, _ := oldSchema["properties"].(map[string]any)
+	newProperties, _ := newSchema["properties"].(map[string]any)
+	oldRequired, newRequired := getSchemaRequired(oldSchema), getSchemaRequired(newSchema)
+	names := []string{}
+	for name := range oldProperties {
+		names = append(names, name)
+	}
+	for name := range newProperties {
+		if _, found := oldProperties[name]; !found {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		propertyPath := name
+		if path != "" {
+			propertyPath = path + "." + name
+		}
+		oldProperty, inOld := oldProperties[name].(map[string]any)
+		newProperty, inNew := newProperties[name].(map[string]any)
+		switch {
+		case !inNew:
+			add(propertyPath, true, "property removed")
+		case !inOld && slices.Contains(newRequired, name):
+			add(propertyPath, true, "required property added")
+		case !inOld:
+			add(propertyPath, false, "optional property added")
+		default:
+			if slices.Contains(newRequired, name) && !slices.Contains(oldRequired, name) {
+				add(propertyPath, true, "property became required")
+			}
+			if slices.Contains(oldRequired, name) && !slices.Contains(newRequired, name) {
+				add(propertyPath, false, "property no longer required")
+			}
+			compareSchemas(propertyPath, oldProperty, newProperty, add)
+		}
+	}
+
+	// array items
+	oldItems, oldHasItems := oldSchema["items"].(map[string]any)
+	newItems, newHasItems := newSchema["items"].(map[string]any)
+	if oldHasItems && newHasItems {
+		compareSchemas(path+"[]", oldItems, newItems, add)
+	}
+}
+
+// compareSchemaLimit classifies the change of a limit keyword (e.g., minimum); tightened returns
+// true if the new limit is stricter than the old one
+func compareSchemaLimit(path string, keyword string, oldSchema map[string]any, newSchema map[string]any, tightened func(float64, float64) bool, add func(string, bool, string, ...any)) {
+	oldLimit, inOld := oldSchema[keyword].(float64)
+	newLimit, inNew := newSchema[keyword].(float64)
+	switch {
+	case inNew && !inOld:
+		add(path, true, "%v %v added", keyword, newLimit)
+	case inOld && !inNew:
+		add(path, false, "%v removed", keyword)
+	case inOld && inNew && oldLimit != newLimit:
+		add(path, tightened(oldLimit, newLimit), "%v changed from %v to %v", keyword, oldLimit, newLimit)
+	}
+}
+
+// getSchemaTypes returns the types allowed by a schema, which may be a single type or a list
+func getSchemaTypes(schema map[string]any) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []any:
+		types := []string{}
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// getSchemaRequired returns the names of the required properties of an object schema
+func getSchemaRequired(schema map[string]any) []string {
+	required := []string{}
+	list, _ := schema["required"].([]any)
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			required = append(required, s)
+		}
+	}
+	return required
+}
+
+// isMajorVersionBump returns true if the new version has a higher major version than the old one
+func isMajorVersionBump(oldVersion string, newVersion string) (bool, error) {
+	oldSemver, err := semver.NewVersion(oldVersion)
+	if err != nil {
+		return false, fmt.Errorf("invalid version %q: %w", oldVersion, err)
+	}
+	newSemver, err := semver.NewVersion(newVersion)
+	if err != nil {
+		return false, fmt.Errorf("invalid version %q: %w", newVersion, err)
+	}
+	return newSemver.Major() > oldSemver.Major(), nil
+}
@@ -0,0 +1,194 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSchema parses a JSON schema, so that numbers are float64 as when read from a type file
+func testSchema(t *testing.T, schema string) map[string]any {
+	parsed := map[string]any{}
+	require.NoError(t, json.Unmarshal([]byte(schema), &parsed))
+	return parsed
+}
+
+func TestCompareSchemas(t *testing.T) {
+	tests := []struct {
+		name      string
+		oldSchema string
+		newSchema string
+		changes   []TypeChange
+	}{
+		{
+			name:      "unchanged",
+			oldSchema: `{"type": "object", "properties": {"name": {"type": "string"}}, "required": ["name"]}`,
+			newSchema: `{"type": "object", "properties": {"name": {"type": "string"}}, "required": ["name"]}`,
+			changes:   []TypeChange{},
+		},
+		{
+			name:      "required property added",
+			oldSchema: `{"type": "object", "properties": {"name": {"type": "string"}}}`,
+			newSchema: `{"type": "object", "properties": {"name": {"type": "string"}, "owner": {"type": "string"}}, "required": ["owner"]}`,
+			changes:   []TypeChange{{Type: "t", Property: "owner", Change: "required property added", Breaking: true}},
+		},
+		{
+			name:      "optional property added",
+			oldSchema: `{"type": "object", "properties": {"name": {"type": "string"}}}`,
+			newSchema: `{"type": "object", "properties": {"name": {"type": "string"}, "owner": {"type": "string"}}}`,
+			changes:   []TypeChange{{Type: "t", Property: "owner", Change: "optional property added", Breaking: false}},
+		},
+		{
+			name:      "property became required",
+			oldSchema: `{"type": "object", "properties": {"name": {"type": "string"}}}`,
+			newSchema: `{"type": "object", "properties": {"name": {"type": "string"}}, "required": ["name"]}`,
+			changes:   []TypeChange{{Type: "t", Property: "name", Change: "property became required", Breaking: true}},
+		},
+		{
+			name:      "property removed",
+			oldSchema: `{"type": "object", "properties": {"name": {"type": "string"}, "owner": {"type": "string"}}}`,
+			newSchema: `{"type": "object", "properties": {"name": {"type": "string"}}}`,
+			changes:   []TypeChange{{Type: "t", Property: "owner", Change: "property removed", Breaking: true}},
+		},
+		{
+			name:      "nested property removed",
+			oldSchema: `{"type": "object", "properties": {"spec": {"type": "object", "properties": {"size": {"type": "integer"}}}}}`,
+			newSchema: `{"type": "object", "properties": {"spec": {"type": "object", "properties": {}}}}`,
+			changes:   []TypeChange{{Type: "t", Property: "spec.size", Change: "property removed", Breaking: true}},
+		},
+		{
+			name:      "type changed",
+			oldSchema: `{"type": "object", "properties": {"size": {"type": "string"}}}`,
+			newSchema: `{"type": "object", "properties": {"size": {"type": "integer"}}}`,
+			changes:   []TypeChange{{Type: "t", Property: "size", Change: "type changed from string to integer", Breaking: true}},
+		},
+		{
+			name:      "type widened from integer to number",
+			oldSchema: `{"type": "object", "properties": {"size": {"type": "integer"}}}`,
+			newSchema: `{"type": "object", "properties": {"size": {"type": "number"}}}`,
+			changes:   []TypeChange{},
+		},
+		{
+			name:      "type of array items changed",
+			oldSchema: `{"type": "object", "properties": {"tags": {"type": "array", "items": {"type": "string"}}}}`,
+			newSchema: `{"type": "object", "properties": {"tags": {"type": "array", "items": {"type": "object"}}}}`,
+			changes:   []TypeChange{{Type: "t", Property: "tags[]", Change: "type changed from string to object", Breaking: true}},
+		},
+		{
+			name:      "enum narrowed",
+			oldSchema: `{"type": "object", "properties": {"level": {"type": "string", "enum": ["low", "high"]}}}`,
+			newSchema: `{"type": "object", "properties": {"level": {"type": "string", "enum": ["high"]}}}`,
+			changes:   []TypeChange{{Type: "t", Property: "level", Change: "enum value low removed", Breaking: true}},
+		},
+		{
+			name:      "enum widened",
+			oldSchema: `{"type": "object", "properties": {"level": {"type": "string", "enum": ["high"]}}}`,
+			newSchema: `{"type": "object", "properties": {"level": {"type": "string", "enum": ["low", "high"]}}}`,
+			changes:   []TypeChange{{Type: "t", Property: "level", Change: "enum value low added", Breaking: false}},
+		},
+		{
+			name:      "enum added",
+			oldSchema: `{"type": "object", "properties": {"level": {"type": "string"}}}`,
+			newSchema: `{"type": "object", "properties": {"level": {"type": "string", "enum": ["high"]}}}`,
+			changes:   []TypeChange{{Type: "t", Property: "level", Change: "enum added", Breaking: true}},
+		},
+		{
+			name:      "maximum decreased",
+			oldSchema: `{"type": "object", "properties": {"size": {"type": "integer", "maximum": 10}}}`,
+			newSchema: `{"type": "object", "properties": {"size": {"type": "integer", "maximum": 5}}}`,
+			changes:   []TypeChange{{Type: "t", Property: "size", Change: "maximum changed from 10 to 5", Breaking: true}},
+		},
+		{
+			name:      "minimum decreased",
+			oldSchema: `{"type": "object", "properties": {"size": {"type": "integer", "minimum": 5}}}`,
+			newSchema: `{"type": "object", "properties": {"size": {"type": "integer", "minimum": 1}}}`,
+			changes:   []TypeChange{{Type: "t", Property: "size", Change: "minimum changed from 5 to 1", Breaking: false}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := compareKnowledgeTypes(
+				map[string]*KnowledgeDef{"t": {Name: "t", JsonSchema: testSchema(t, tt.oldSchema)}},
+				map[string]*KnowledgeDef{"t": {Name: "t", JsonSchema: testSchema(t, tt.newSchema)}},
+			)
+			assert.Equal(t, tt.changes, changes)
+		})
+	}
+}
+
+func TestCompareKnowledgeTypes(t *testing.T) {
+	schema := `{"type": "object", "properties": {"name": {"type": "string"}}}`
+	tests := []struct {
+		name     string
+		oldTypes map[string]*KnowledgeDef
+		newTypes map[string]*KnowledgeDef
+		changes  []TypeChange
+	}{
+		{
+			name:     "type added",
+			oldTypes: map[string]*KnowledgeDef{},
+			newTypes: map[string]*KnowledgeDef{"t": {Name: "t", JsonSchema: testSchema(t, schema)}},
+			changes:  []TypeChange{{Type: "t", Change: "type added", Breaking: false}},
+		},
+		{
+			name:     "type removed",
+			oldTypes: map[string]*KnowledgeDef{"t": {Name: "t", JsonSchema: testSchema(t, schema)}},
+			newTypes: map[string]*KnowledgeDef{},
+			changes:  []TypeChange{{Type: "t", Change: "type removed", Breaking: true}},
+		},
+		{
+			name:     "identifying properties changed",
+			oldTypes: map[string]*KnowledgeDef{"t": {Name: "t", JsonSchema: testSchema(t, schema), IdentifyingProperties: []string{"/name"}}},
+			newTypes: map[string]*KnowledgeDef{"t": {Name: "t", JsonSchema: testSchema(t, schema), IdentifyingProperties: []string{"/id"}}},
+			changes:  []TypeChange{{Type: "t", Change: "identifying properties changed from [/name] to [/id]", Breaking: true}},
+		},
+		{
+			name:     "allowed layer removed",
+			oldTypes: map[string]*KnowledgeDef{"t": {Name: "t", JsonSchema: testSchema(t, schema), AllowedLayers: []string{"TENANT", "LOCAL_USER"}}},
+			newTypes: map[string]*KnowledgeDef{"t": {Name: "t", JsonSchema: testSchema(t, schema), AllowedLayers: []string{"TENANT"}}},
+			changes:  []TypeChange{{Type: "t", Change: "allowed layer LOCAL_USER removed", Breaking: true}},
+		},
+		{
+			name:     "secure property added",
+			oldTypes: map[string]*KnowledgeDef{"t": {Name: "t", JsonSchema: testSchema(t, schema)}},
+			newTypes: map[string]*KnowledgeDef{"t": {Name: "t", JsonSchema: testSchema(t, schema), SecureProperties: []string{"$.name"}}},
+			changes:  []TypeChange{{Type: "t", Property: "$.name", Change: "secure property added", Breaking: true}},
+		},
+		{
+			name: "sorted by type",
+			oldTypes: map[string]*KnowledgeDef{
+				"b": {Name: "b", JsonSchema: testSchema(t, schema)},
+			},
+			newTypes: map[string]*KnowledgeDef{
+				"c": {Name: "c", JsonSchema: testSchema(t, schema)},
+				"a": {Name: "a", JsonSchema: testSchema(t, schema)},
+			},
+			changes: []TypeChange{
+				{Type: "a", Change: "type added", Breaking: false},
+				{Type: "b", Change: "type removed", Breaking: true},
+				{Type: "c", Change: "type added", Breaking: false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.changes, compareKnowledgeTypes(tt.oldTypes, tt.newTypes))
+		})
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/cisco-open/fsoc/cmdkit/jsondoc"
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+)
+
+var solutionConvertCmd = &cobra.Command{
+	Use:   "convert --to json|yaml",
+	Args:  cobra.NoArgs,
+	Short: "Convert the solution's manifest and object files between JSON and YAML",
+	Long: `This command converts the solution manifest to the specified format and, with --objects, also all object and
+type definition files referenced by the manifest. The converted files replace the original ones, with the file
+extension changed accordingly (e.g., objects/model/host.json becomes objects/model/host.yaml), and the references
+in the manifest are updated to match, including the include patterns of objectsDir (e.g., "**/*.json" becomes
+"**/*.yaml"). Files listed in included manifest files are not converted.
+
+The manifest is written as by "solution fix --manifest-format". The object and type files keep the order of their
+fields; comments are lost when converting to JSON, which does not support comments.
+All files are parsed before any is written, so a file that fails to parse leaves the solution unchanged.
+
+Pseudo-isolated solutions are supported only in JSON and cannot be converted to YAML.`,
+	Example: `  fsoc solution convert --to yaml
+  fsoc solution convert --to yaml --objects
+  fsoc solution convert --to json --objects -d mysolution`,
+	Run:         convertSolution,
+	Annotations: map[string]string{config.AnnotationForConfigBypass: ""},
+}
+
+// fileConversion is a file converted (or updated) by the convert command
+type fileConversion struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	data []byte
+}
+
+func getSolutionConvertCmd() *cobra.Command {
+	solutionConvertCmd.Flags().
+		String("to", "", "Target format, json or yaml")
+	_ = solutionConvertCmd.MarkFlagRequired("to")
+
+	solutionConvertCmd.Flags().
+		Bool("objects", false, "Convert also the object and type definition files referenced by the manifest")
+
+	solutionConvertCmd.Flags().
+		StringP("directory", "d", "", "Path to the solution root directory (defaults to current dir)")
+
+	return solutionConvertCmd
+}
+
+func convertSolution(cmd *cobra.Command, args []string) {
+	solutionRootDirectory, _ := cmd.Flags().GetString("directory")
+	if solutionRootDirectory == "" {
+		solutionRootDirectory = "."
+	}
+	solutionRootDirectory = absolutizePath(solutionRootDirectory)
+	manifest, err := getSolutionManifest(solutionRootDirectory)
+	if err != nil {
+		log.Fatalf("Failed to read the solution manifest: %v", err)
+	}
+
+	// the manifest format is changed as by solution fix --manifest-format
+	oldManifestFormat := manifest.ManifestFormat
+	to, _ := cmd.Flags().GetString("to")
+	if err := changeManifestFormat(cmd, manifest, to); err != nil && !errors.Is(err, ErrNoEffect) {
+		log.Fatalf("Failed to convert the solution, no files were changed: %v", err)
+	}
+	format := manifest.ManifestFormat
+
+	// convert the object and type files first, to know the paths to update in the manifest.
+	// Files listed in included manifest files are left as they are, since the included
+	// files are not rewritten.
+	own := manifest.withoutIncluded()
+	conversions := []fileConversion{}
+	renames := map[string]string{} // old path -> new path, as referenced in the manifest
+	if convertObjects, _ := cmd.Flags().GetBool("objects"); convertObjects {
+		if manifest.included != nil && len(manifest.included.files) > 0 {
+			log.Warnf("The files listed in the included manifest files %q are not converted", manifest.included.files)
+		}
+		objectFiles, errs := loadManifestObjects(solutionRootDirectory, own)
+		if len(errs) > 0 {
+			log.Fatalf("Failed to convert the solution, no files were changed: %v", errors.Join(errs...))
+		}
+		paths := slices.Clone(own.Types)
+		for _, file := range objectFiles {
+			paths = append(paths, file.path)
+		}
+		for _, path := range paths {
+			newPath := convertedFileName(path, format)
+			if _, done := renames[path]; done || newPath == path {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(solutionRootDirectory, newPath)); err == nil {
+				log.Fatalf("Failed to convert %q, no files were changed: file %q already exists", path, newPath)
+			}
+			data, err := convertFile(filepath.Join(solutionRootDirectory, path), format)
+			if err != nil {
+				log.Fatalf("Failed to convert %q, no files were changed: %v", path, err)
+			}
+			renames[path] = newPath
+			conversions = append(conversions, fileConversion{From: path, To: newPath, data: data})
+		}
+		renameManifestPaths(own, renames)
+	}
+
+	// save the manifest with the paths of converted files updated in objectsFile, types and
+	// the include patterns of objectsDir
+	manifestName := "manifest." + oldManifestFormat.String()
+	newManifestName := "manifest." + format.String()
+	if manifestName != newManifestName || len(renames) > 0 {
+		var buf bytes.Buffer
+		if err := writeSolutionManifest(own, &buf); err != nil {
+			log.Fatalf("Failed to convert %q, no files were changed: %v", manifestName, err)
+		}
+		conversions = append(conversions, fileConversion{From: manifestName, To: newManifestName, data: buf.Bytes()})
+	}
+	if len(conversions) == 0 {
+		output.PrintCmdStatus(cmd, fmt.Sprintf("The solution is already in %v format; no files were changed.\n", format))
+		return
+	}
+
+	// write the converted files and remove the originals
+	lines := [][]string{}
+	for _, conversion := range conversions {
+		if err := os.WriteFile(filepath.Join(solutionRootDirectory, conversion.To), conversion.data, 0o644); err != nil {
+			log.Fatalf("Failed to write %q: %v", conversion.To, err)
+		}
+		if conversion.From != conversion.To {
+			if err := os.Remove(filepath.Join(solutionRootDirectory, conversion.From)); err != nil {
+				log.Warnf("Failed to remove %q, please delete it manually: %v", conversion.From, err)
+			}
+		}
+		lines = append(lines, []string{conversion.From, conversion.To})
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []fileConversion `json:"items"`
+		Total int              `json:"total"`
+	}{conversions, len(conversions)}, &output.Table{Headers: []string{"From", "To"}, Lines: lines})
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Converted %d file(s) to %v.\n", len(conversions), format))
+}
+
+// convertedFileName returns the file path with the extension for the format, or the
+// same path if the file is already in that format
+func convertedFileName(path string, format FileFormat) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch {
+	case format == FileFormatJSON && ext == ".json":
+		return path
+	case format == FileFormatYAML && (ext == ".yaml" || ext == ".yml"):
+		return path
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path)) + "." + format.String()
+}
+
+// convertFile reads a JSON or YAML file and returns its content in the format, keeping the
+// order of fields
+func convertFile(path string, format FileFormat) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parseObjectsData(data, path); err != nil {
+		return nil, err
+	}
+	var doc yaml.Node // JSON is parsed as YAML, to get the field order and comments
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, jsondoc.NewYamlParseError(err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("the file is empty")
+	}
+	root := doc.Content[0]
+
+	var buf bytes.Buffer
+	switch format {
+	case FileFormatYAML:
+		clearJsonNodeStyles(&doc)
+		err = writeComponent(&doc, &buf, FileFormatYAML)
+	case FileFormatJSON:
+		var value any
+		if err = root.Decode(&value); err == nil {
+			err = writeComponent(toOrderedValue(value, root), &buf, FileFormatJSON)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renameManifestPaths replaces the converted file paths in the manifest's objectsFile fields
+// and types list. The include patterns of objectsDir that end with a converted file's old
+// extension are changed to the new one, so that they keep selecting the converted files; a
+// warning is logged if a converted file would still not be selected.
+func renameManifestPaths(manifest *Manifest, renames map[string]string) {
+	for i, typeFile := range manifest.Types {
+		if newPath, found := renames[typeFile]; found {
+			manifest.Types[i] = newPath
+		}
+	}
+	for i := range manifest.Objects {
+		compDef := &manifest.Objects[i]
+		if newPath, found := renames[compDef.ObjectsFile]; found {
+			compDef.ObjectsFile = newPath
+		}
+		if compDef.ObjectsDir == "" {
+			continue
+		}
+		oldDef := *compDef
+		compDef.Include = slices.Clone(compDef.Include)
+		for _, oldPath := range sortedKeys(renames) {
+			dirRelPath, inDir := objectsDirRelPath(oldDef, oldPath)
+			if !inDir || !isSelectedObjectsFile(oldDef, dirRelPath) {
+				continue
+			}
+			oldExt, newExt := filepath.Ext(oldPath), filepath.Ext(renames[oldPath])
+			for j, pattern := range compDef.Include {
+				if strings.HasSuffix(pattern, oldExt) && matchGlob(pattern, dirRelPath) {
+					compDef.Include[j] = strings.TrimSuffix(pattern, oldExt) + newExt
+				}
+			}
+		}
+		for _, oldPath := range sortedKeys(renames) {
+			dirRelPath, inDir := objectsDirRelPath(oldDef, oldPath)
+			if !inDir || !isSelectedObjectsFile(oldDef, dirRelPath) {
+				continue
+			}
+			newDirRelPath, _ := objectsDirRelPath(*compDef, renames[oldPath])
+			if !isSelectedObjectsFile(*compDef, newDirRelPath) {
+				log.Warnf("The converted file %q is not selected by the include and exclude patterns of objectsDir %q; please update them", renames[oldPath], compDef.ObjectsDir)
+			}
+		}
+	}
+}
+
+// objectsDirRelPath returns the slash-separated path of a file relative to the objectsDir of
+// the component definition, and whether the file is in objectsDir
+func objectsDirRelPath(compDef ComponentDef, path string) (string, bool) {
+	relPath, err := filepath.Rel(filepath.Clean(compDef.ObjectsDir), filepath.Clean(path))
+	if err != nil || !filepath.IsLocal(relPath) {
+		return "", false
+	}
+	return filepath.ToSlash(relPath), true
+}
+
+// clearJsonNodeStyles switches the flow collections and quoted strings parsed from JSON to the
+// default YAML block style; strings that would be read as other types remain quoted
+func clearJsonNodeStyles(node *yaml.Node) {
+	node.Style &^= yaml.FlowStyle | yaml.DoubleQuotedStyle | yaml.SingleQuotedStyle
+	for _, child := range node.Content {
+		clearJsonNodeStyles(child)
+	}
+}
@@ -54,6 +54,9 @@ var solutionDeleteCmd = &cobra.Command{
 This is for the purpose of deleting a solution that you no longer want to use.  
 This will clean up all of objects/types defined by the solution as well as all of the solution metadata.  
 Please note you must terminate all active subscriptions to the solution before issuing this command.
+Before deleting, the command lists the solutions that depend on the solution, the tenant objects of the solution's
+types and the FMM types whose data will be orphaned; it fails if any subscribed solution depends on the solution,
+unless --force is specified. The deletion must be confirmed by typing the solution name, unless --yes is specified.
 Please also note this is an asynchronous operation and thus it may take some time for the status to reflect properly.
 If you issue this command while an active deletion is in progress, it will simply wait for that deletion to finish.`,
 	Example:          `  fsoc solution delete mysolution --tag custom --wait 45 --yes`,
@@ -77,13 +80,15 @@ func getSolutionDeleteCommand() *cobra.Command {
 	solutionDeleteCmd.Flags().
 		BoolP("yes", "y", false, "Skip warning message and bypass confirmation step")
 
+	solutionDeleteCmd.Flags().
+		Bool("force", false, "Delete the solution even if subscribed solutions depend on it")
+
 	solutionDeleteCmd.MarkFlagsMutuallyExclusive("wait", "no-wait")
 
 	return solutionDeleteCmd
 }
 
 func deleteSolution(cmd *cobra.Command, args []string) {
-	var confirmationAnswer string
 	var solutionName string
 	var solutionTag string
 	var existingSolutionDeletionObjectId string
@@ -100,13 +105,11 @@ func deleteSolution(cmd *cobra.Command, args []string) {
 		"tag": solutionTag,
 	}
 
-	if !skipConfirmationMessage {
-		fmt.Printf("WARNING! This command will remove all objects and types that are associated with this solution and will purge all data related to those objects and types.  It will also remove all solution metadata (including, but not limited to, subscriptions and other related objects).\nProceed with caution!  \nPlease type the name of the solution you want to delete and hit enter confirm that you want to delete the solution with name: %s and tag: %s \n", solutionName, solutionTag)
-		fmt.Scanln(&confirmationAnswer)
+	force, _ := cmd.Flags().GetBool("force")
+	checkUninstall(cmd, solutionName, solutionTag, force)
 
-		if confirmationAnswer != solutionName {
-			log.Fatal("Solution delete not confirmed, exiting command")
-		}
+	if !skipConfirmationMessage {
+		confirmUninstall(solutionName, fmt.Sprintf("WARNING! This command will remove all objects and types that are associated with this solution and will purge all data related to those objects and types.  It will also remove all solution metadata (including, but not limited to, subscriptions and other related objects).\nProceed with caution!  \nYou are deleting the solution with name: %s and tag: %s", solutionName, solutionTag))
 	}
 
 	existingDeletionObj := getSolutionDeletionObject(solutionTag, solutionName)
@@ -0,0 +1,134 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+// DependencyAvailability is the result of checking a dependency against the target tenant
+type DependencyAvailability struct {
+	Name       string `json:"name" yaml:"name"`
+	Available  bool   `json:"available" yaml:"available"`
+	Subscribed bool   `json:"subscribed" yaml:"subscribed"`
+	IsSystem   bool   `json:"isSystem" yaml:"isSystem"`
+	Version    string `json:"version,omitempty" yaml:"version,omitempty"` // installed version
+	Locked     string `json:"locked,omitempty" yaml:"locked,omitempty"`   // version in the lockfile
+	Status     string `json:"status" yaml:"status"`
+	Fix        string `json:"fix,omitempty" yaml:"fix,omitempty"` // command that fixes the problem, if any
+	ok         bool
+}
+
+// checkDependencyAvailability verifies that each of the manifest's dependencies is available in the
+// tenant and, unless it is a system solution, subscribed to. If the solution has a lockfile, the
+// installed versions must also be compatible with the locked ones.
+func checkDependencyAvailability(solutionRoot string, manifest *Manifest) ([]DependencyAvailability, error) {
+	lockChecks, err := checkSolutionLock(solutionRoot, manifest)
+	if err != nil {
+		return nil, err
+	}
+	lockByName := map[string]DependencyCheck{}
+	for _, check := range lockChecks {
+		lockByName[check.Name] = check
+	}
+
+	headers := getHeaders()
+	checks := []DependencyAvailability{}
+	for _, name := range getDependencyNames(manifest) {
+		check := DependencyAvailability{Name: name}
+		var solData struct {
+			Data SolutionDef `json:"data"`
+		}
+		err := api.JSONGet(getSolutionObjectUrl(name), &solData, &api.Options{Headers: headers, ExpectedErrors: []int{404}})
+		var httpErr *api.HttpStatusError
+		switch {
+		case err == nil:
+			check.Available = true
+			check.Subscribed = solData.Data.IsSubscribed
+			check.IsSystem = solData.Data.IsSystem
+		case errors.As(err, &httpErr) && httpErr.StatusCode == 404:
+			check.Status = "not available in the tenant; it must be pushed to the tenant first"
+			checks = append(checks, check)
+			continue
+		default:
+			return nil, fmt.Errorf("failed to check dependency %q: %w", name, err)
+		}
+
+		lockCheck, isLocked := lockByName[name]
+		if isLocked {
+			check.Locked = lockCheck.Locked
+			check.Version = lockCheck.Resolved
+		} else if check.Version, _, err = resolveDependencyVersion(name); err != nil {
+			return nil, fmt.Errorf("failed to resolve dependency %q: %w", name, err)
+		}
+
+		switch {
+		case !check.IsSystem && !check.Subscribed:
+			check.Status = "not subscribed"
+			check.Fix = fmt.Sprintf("fsoc solution subscribe %s", name)
+		case isLocked && !lockCheck.ok:
+			check.Status = lockCheck.Status
+			if lockCheck.Locked == "" && lockCheck.Resolved == "" {
+				check.Fix = "fsoc solution lock"
+			}
+		default:
+			check.Status, check.ok = "ok", true
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// verifyDependencyAvailability checks the dependencies against the tenant and, if any
+// fails the check, displays a report with the commands that fix the problems
+func verifyDependencyAvailability(cmd *cobra.Command, solutionRoot string, manifest *Manifest) error {
+	checks, err := checkDependencyAvailability(solutionRoot, manifest)
+	if err != nil {
+		return err
+	}
+	nFailed := 0
+	fixes := []string{}
+	lines := [][]string{}
+	for _, check := range checks {
+		if !check.ok {
+			nFailed++
+			if check.Fix != "" && !slices.Contains(fixes, check.Fix) {
+				fixes = append(fixes, check.Fix)
+			}
+		}
+		lines = append(lines, []string{check.Name, check.Version, check.Locked, check.Status})
+	}
+	if nFailed == 0 {
+		log.WithField("dependencies", len(checks)).Info("Verified dependencies against the tenant")
+		return nil
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []DependencyAvailability `json:"items"`
+		Total int                      `json:"total"`
+	}{checks, len(checks)}, &output.Table{Headers: []string{"Name", "Installed", "Locked", "Status"}, Lines: lines})
+	if len(fixes) > 0 {
+		output.PrintCmdStatus(cmd, fmt.Sprintf("To fix, run:\n  %v\n", strings.Join(fixes, "\n  ")))
+	}
+	return fmt.Errorf("%d of %d dependencies are not ready in the tenant", nFailed, len(checks))
+}
@@ -0,0 +1,415 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+var solutionDepsCmd = &cobra.Command{
+	Use:   "deps",
+	Args:  cobra.ExactArgs(0),
+	Short: "Show the dependency graph of the solution",
+	Long: `This command shows the dependencies of the solution in the current (or specified) directory, as
+declared in its manifest, together with the versions locked in the solution.lock file, if any.
+
+With --tenant, the solutions available in the current tenant and their dependencies are added to the graph,
+and the installed version of each dependency is checked against the locked one (see "fsoc solution lock");
+dependencies that are missing from the tenant or whose installed version is incompatible with the locked
+version are reported as conflicts. Without --tenant, the command works offline.
+
+With --graph, the dependency graph is displayed in DOT format (for Graphviz) or, with --graph=mermaid, as a
+Mermaid flowchart. Conflicts and dependency cycles are highlighted in red.`,
+	Example: `  fsoc solution deps
+  fsoc solution deps --tenant
+  fsoc solution deps --graph | dot -Tsvg > deps.svg
+  fsoc solution deps --graph=mermaid --tenant -d mysolution`,
+	Annotations: map[string]string{
+		config.AnnotationForConfigBypass: "", // needed only with --tenant, checked in solutionDeps
+	},
+	Run: solutionDeps,
+}
+
+// supported dependency graph formats
+const (
+	graphFormatDot     = "dot"
+	graphFormatMermaid = "mermaid"
+)
+
+// DependencyGraph is the dependency graph of a local solution and, optionally,
+// of the solutions available in the tenant
+type DependencyGraph struct {
+	Nodes  []DependencyNode `json:"nodes" yaml:"nodes"`
+	Edges  []DependencyEdge `json:"edges" yaml:"edges"`
+	Cycles [][]string       `json:"cycles" yaml:"cycles"` // solutions that depend on each other
+}
+
+// DependencyNode is a solution in the dependency graph
+type DependencyNode struct {
+	Name    string `json:"name" yaml:"name"`
+	Version string `json:"version,omitempty" yaml:"version,omitempty"` // installed version, if known
+	Local   bool   `json:"local,omitempty" yaml:"local,omitempty"`     // the solution in the local directory
+	Missing bool   `json:"missing,omitempty" yaml:"missing,omitempty"` // not available in the tenant
+}
+
+// DependencyEdge is a dependency of one solution on another
+type DependencyEdge struct {
+	From     string `json:"from" yaml:"from"`
+	To       string `json:"to" yaml:"to"`
+	Locked   string `json:"locked,omitempty" yaml:"locked,omitempty"`     // version in the lockfile, for the local solution's dependencies
+	Conflict string `json:"conflict,omitempty" yaml:"conflict,omitempty"` // why the dependency cannot be satisfied, if it can't
+	InCycle  bool   `json:"inCycle,omitempty" yaml:"inCycle,omitempty"`
+}
+
+func getSolutionDepsCmd() *cobra.Command {
+	solutionDepsCmd.Flags().
+		StringP("directory", "d", "", "Path to the solution root directory (defaults to current dir)")
+
+	solutionDepsCmd.Flags().
+		String("graph", "", "Display the dependency graph in the given format: dot or mermaid")
+	solutionDepsCmd.Flag("graph").NoOptDefVal = graphFormatDot
+
+	solutionDepsCmd.Flags().
+		Bool("tenant", false, "Include the solutions available in the tenant and check the installed versions")
+
+	return solutionDepsCmd
+}
+
+func solutionDeps(cmd *cobra.Command, args []string) {
+	solutionRootDirectory, _ := cmd.Flags().GetString("directory")
+	if solutionRootDirectory == "" {
+		solutionRootDirectory = "."
+	}
+	solutionRootDirectory = absolutizePath(solutionRootDirectory)
+	graphFormat, _ := cmd.Flags().GetString("graph")
+	if graphFormat != "" && graphFormat != graphFormatDot && graphFormat != graphFormatMermaid {
+		log.Fatalf("Unsupported graph format %q, must be %v or %v", graphFormat, graphFormatDot, graphFormatMermaid)
+	}
+	useTenant, _ := cmd.Flags().GetBool("tenant")
+
+	// the command bypasses the config check to work offline, so check here
+	if useTenant && config.GetCurrentContext() == nil {
+		log.Fatal(`fsoc is not configured, please use "fsoc config create" to configure an initial context`)
+	}
+
+	manifest, err := getSolutionManifest(solutionRootDirectory)
+	if err != nil {
+		log.Fatalf("Failed to read the solution manifest: %v", err)
+	}
+	graph, err := buildDependencyGraph(solutionRootDirectory, manifest, useTenant)
+	if err != nil {
+		log.Fatalf("Failed to build the dependency graph: %v", err)
+	}
+
+	switch graphFormat {
+	case graphFormatDot:
+		output.PrintCmdStatus(cmd, graph.toDot())
+	case graphFormatMermaid:
+		output.PrintCmdStatus(cmd, graph.toMermaid())
+	default:
+		lines := [][]string{}
+		for _, edge := range graph.Edges {
+			status := "ok"
+			switch {
+			case edge.Conflict != "":
+				status = edge.Conflict
+			case edge.InCycle:
+				status = "dependency cycle"
+			case !useTenant:
+				status = ""
+			}
+			lines = append(lines, []string{edge.From, edge.To, edge.Locked, graph.getNode(edge.To).Version, status})
+		}
+		output.PrintCmdOutputCustom(cmd, graph, &output.Table{Headers: []string{"Solution", "Dependency", "Locked", "Installed", "Status"}, Lines: lines})
+	}
+
+	for _, cycle := range graph.Cycles {
+		log.Warnf("Dependency cycle between solutions: %v", strings.Join(cycle, ", "))
+	}
+}
+
+// buildDependencyGraph builds the dependency graph of the local solution, including the
+// solutions available in the tenant and their installed versions if useTenant is true
+func buildDependencyGraph(solutionRoot string, manifest *Manifest, useTenant bool) (*DependencyGraph, error) {
+	dependencies := map[string][]string{manifest.Name: getDependencyNames(manifest)}
+	locked := map[string]string{}
+	lock, err := readSolutionLock(solutionRoot)
+	switch {
+	case err == nil:
+		for _, dep := range lock.Dependencies {
+			locked[dep.Name] = dep.Version
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("failed to read %v: %w", SolutionLockFileName, err)
+	}
+
+	graph := &DependencyGraph{Nodes: []DependencyNode{}, Edges: []DependencyEdge{}, Cycles: [][]string{}}
+	versions := map[string]string{}
+	missing := map[string]bool{}
+	if useTenant {
+		var solutions api.CollectionResult[struct {
+			ID   string      `json:"id"`
+			Data SolutionDef `json:"data"`
+		}]
+		if err := api.JSONGetCollection(getSolutionObjectUrl(""), &solutions, &api.Options{Headers: getHeaders()}); err != nil {
+			return nil, fmt.Errorf("failed to list solutions: %w", err)
+		}
+		for _, solution := range solutions.Items {
+			if solution.ID == manifest.Name {
+				continue // the local manifest supersedes the version in the tenant
+			}
+			deps := append([]string{}, solution.Data.Dependencies...)
+			sort.Strings(deps)
+			dependencies[solution.ID] = deps
+		}
+
+		// resolve the installed versions of the solutions the local solution depends on
+		for _, name := range getReachableSolutions(dependencies, manifest.Name) {
+			if name == manifest.Name {
+				continue
+			}
+			version, found, err := resolveDependencyVersion(name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve dependency %q: %w", name, err)
+			}
+			versions[name] = version
+			missing[name] = !found
+		}
+	}
+
+	// collect nodes and edges
+	names := map[string]bool{}
+	for name, deps := range dependencies {
+		names[name] = true
+		for _, dep := range deps {
+			names[dep] = true
+		}
+	}
+	for name := range names {
+		graph.Nodes = append(graph.Nodes, DependencyNode{Name: name, Version: versions[name], Local: name == manifest.Name, Missing: missing[name]})
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		if graph.Nodes[i].Local != graph.Nodes[j].Local {
+			return graph.Nodes[i].Local
+		}
+		return graph.Nodes[i].Name < graph.Nodes[j].Name
+	})
+	for _, node := range graph.Nodes {
+		for _, dep := range dependencies[node.Name] {
+			edge := DependencyEdge{From: node.Name, To: dep}
+			if node.Local {
+				edge.Locked = locked[dep]
+			}
+			switch {
+			case missing[dep]:
+				edge.Conflict = "missing in tenant"
+			case node.Local && useTenant && edge.Locked != "" && versions[dep] != "" && versions[dep] != edge.Locked:
+				if status, ok := getVersionCompatibility(versions[dep], edge.Locked); !ok {
+					edge.Conflict = status
+				}
+			}
+			graph.Edges = append(graph.Edges, edge)
+		}
+	}
+
+	graph.findCycles(dependencies)
+	return graph, nil
+}
+
+// getReachableSolutions returns the solutions that the given solution depends on, directly or
+// indirectly, including the solution itself
+func getReachableSolutions(dependencies map[string][]string, name string) []string {
+	visited := map[string]bool{}
+	reachable := []string{}
+	var visit func(string)
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		reachable = append(reachable, name)
+		for _, dep := range dependencies[name] {
+			visit(dep)
+		}
+	}
+	visit(name)
+	return reachable
+}
+
+// findCycles marks the edges that are part of a dependency cycle and lists the groups of
+// solutions that depend on each other. A dependency is part of a cycle if the dependency
+// also depends, directly or indirectly, on the dependent solution.
+func (graph *DependencyGraph) findCycles(dependencies map[string][]string) {
+	reachable := map[string]map[string]bool{}
+	for _, node := range graph.Nodes {
+		reachable[node.Name] = map[string]bool{}
+		for _, name := range getReachableSolutions(dependencies, node.Name) {
+			reachable[node.Name][name] = true
+		}
+	}
+	for i, edge := range graph.Edges {
+		graph.Edges[i].InCycle = reachable[edge.To][edge.From]
+	}
+
+	// solutions that can reach each other form a cycle
+	inCycle := map[string]bool{}
+	for _, node := range graph.Nodes {
+		if inCycle[node.Name] {
+			continue
+		}
+		cycle := []string{node.Name}
+		for _, other := range graph.Nodes {
+			if other.Name != node.Name && reachable[node.Name][other.Name] && reachable[other.Name][node.Name] {
+				cycle = append(cycle, other.Name)
+			}
+		}
+		if len(cycle) > 1 || graph.hasEdge(node.Name, node.Name) {
+			for _, name := range cycle {
+				inCycle[name] = true
+			}
+			sort.Strings(cycle)
+			graph.Cycles = append(graph.Cycles, cycle)
+		}
+	}
+}
+
+func (graph *DependencyGraph) hasEdge(from string, to string) bool {
+	for _, edge := range graph.Edges {
+		if edge.From == from && edge.To == to {
+			return true
+		}
+	}
+	return false
+}
+
+func (graph *DependencyGraph) getNode(name string) DependencyNode {
+	for _, node := range graph.Nodes {
+		if node.Name == name {
+			return node
+		}
+	}
+	return DependencyNode{Name: name}
+}
+
+// label returns the text displayed for the node in a graph
+func (node DependencyNode) label() string {
+	switch {
+	case node.Local:
+		return node.Name + " (local)"
+	case node.Missing:
+		return node.Name + " (missing)"
+	case node.Version != "":
+		return node.Name + " " + node.Version
+	default:
+		return node.Name
+	}
+}
+
+// label returns the text displayed for the edge in a graph
+func (edge DependencyEdge) label() string {
+	var parts []string
+	if edge.Locked != "" {
+		parts = append(parts, "locked "+edge.Locked)
+	}
+	if edge.Conflict != "" {
+		parts = append(parts, edge.Conflict)
+	}
+	return strings.Join(parts, ": ")
+}
+
+// toDot returns the graph in Graphviz DOT format
+func (graph *DependencyGraph) toDot() string {
+	var sb strings.Builder
+	sb.WriteString("digraph dependencies {\n  rankdir=LR;\n  node [shape=box];\n")
+	for _, node := range graph.Nodes {
+		attrs := []string{fmt.Sprintf("label=%q", node.label())}
+		switch {
+		case node.Missing:
+			attrs = append(attrs, "color=red", "style=dashed")
+		case node.Local:
+			attrs = append(attrs, "style=bold")
+		}
+		fmt.Fprintf(&sb, "  %q [%v];\n", node.Name, strings.Join(attrs, ", "))
+	}
+	for _, edge := range graph.Edges {
+		attrs := []string{}
+		if label := edge.label(); label != "" {
+			attrs = append(attrs, fmt.Sprintf("label=%q", label))
+		}
+		if edge.Conflict != "" || edge.InCycle {
+			attrs = append(attrs, "color=red", "fontcolor=red")
+		}
+		fmt.Fprintf(&sb, "  %q -> %q", edge.From, edge.To)
+		if len(attrs) > 0 {
+			fmt.Fprintf(&sb, " [%v]", strings.Join(attrs, ", "))
+		}
+		sb.WriteString(";\n")
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// toMermaid returns the graph as a Mermaid flowchart
+func (graph *DependencyGraph) toMermaid() string {
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+	ids := map[string]string{} // solution names may contain characters not allowed in Mermaid node IDs
+	problems := []string{}
+	for i, node := range graph.Nodes {
+		ids[node.Name] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(&sb, "  %v[\"%v\"]\n", ids[node.Name], mermaidEscape(node.label()))
+		if node.Missing {
+			problems = append(problems, ids[node.Name])
+		}
+	}
+	redLinks := []string{}
+	for i, edge := range graph.Edges {
+		if label := edge.label(); label != "" {
+			fmt.Fprintf(&sb, "  %v -->|\"%v\"| %v\n", ids[edge.From], mermaidEscape(label), ids[edge.To])
+		} else {
+			fmt.Fprintf(&sb, "  %v --> %v\n", ids[edge.From], ids[edge.To])
+		}
+		if edge.Conflict != "" || edge.InCycle {
+			redLinks = append(redLinks, fmt.Sprint(i))
+		}
+	}
+	if len(graph.Nodes) > 0 && graph.Nodes[0].Local {
+		fmt.Fprintf(&sb, "  style %v stroke-width:3px\n", ids[graph.Nodes[0].Name])
+	}
+	if len(problems) > 0 {
+		sb.WriteString("  classDef problem stroke:red,stroke-dasharray:5 5\n")
+		fmt.Fprintf(&sb, "  class %v problem\n", strings.Join(problems, ","))
+	}
+	if len(redLinks) > 0 {
+		fmt.Fprintf(&sb, "  linkStyle %v stroke:red,color:red\n", strings.Join(redLinks, ","))
+	}
+	return sb.String()
+}
+
+// mermaidEscape replaces the characters that cannot appear in quoted Mermaid labels
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
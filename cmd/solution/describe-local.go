@@ -0,0 +1,219 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/output"
+)
+
+// LocalSolutionDescription summarizes a solution in a local directory
+type LocalSolutionDescription struct {
+	Name            string                      `json:"name"`
+	Version         string                      `json:"version"`
+	SolutionType    string                      `json:"solutionType,omitempty"`
+	Dependencies    []string                    `json:"dependencies"`
+	PackageFiles    int                         `json:"packageFiles"`
+	PackageSize     int64                       `json:"packageSize"` // bytes, before compression
+	Objects         []objectsSummary            `json:"objects"`
+	Entities        []LocalEntityDescription    `json:"entities"`
+	DashuiTemplates []LocalDashuiTemplateTarget `json:"dashuiTemplates"`
+}
+
+// LocalEntityDescription is an entity type defined by the solution with the metric and event types it reports
+type LocalEntityDescription struct {
+	Name        string   `json:"name"`
+	MetricTypes []string `json:"metricTypes"`
+	EventTypes  []string `json:"eventTypes"`
+}
+
+// LocalDashuiTemplateTarget is a dashui template defined by the solution and the entity type it targets
+type LocalDashuiTemplateTarget struct {
+	Name   string `json:"name"`
+	Target string `json:"target"`
+	File   string `json:"file"`
+}
+
+// localObjectDescription is an object of a given type, as listed by describe --local <type>
+type localObjectDescription struct {
+	Name string `json:"name"`
+	File string `json:"file"`
+}
+
+// describeLocalSolution summarizes the solution in the local directory or, if a type is
+// given, lists the solution's objects of that type
+func describeLocalSolution(cmd *cobra.Command, args []string) {
+	solutionRootDirectory, _ := cmd.Flags().GetString("directory")
+	if solutionRootDirectory == "" {
+		solutionRootDirectory = "."
+	}
+	solutionRootDirectory = absolutizePath(solutionRootDirectory)
+	if !isSolutionPackageRoot(solutionRootDirectory) {
+		log.Fatalf("No solution manifest found in %q; please use -d flag", solutionRootDirectory)
+	}
+	manifest, err := getSolutionManifest(solutionRootDirectory)
+	if err != nil {
+		log.Fatalf("Failed to read the solution manifest: %v", err)
+	}
+	objectFiles, errs := loadManifestObjects(solutionRootDirectory, manifest)
+	for _, err := range errs {
+		log.Warnf("Skipping file %v", err)
+	}
+
+	if len(args) > 0 {
+		describeLocalObjects(cmd, objectFiles, args[0])
+		return
+	}
+
+	description := LocalSolutionDescription{
+		Name:            manifest.Name,
+		Version:         manifest.SolutionVersion,
+		SolutionType:    manifest.SolutionType,
+		Dependencies:    manifest.Dependencies,
+		Objects:         summarizeObjects(objectFiles, manifest),
+		Entities:        []LocalEntityDescription{},
+		DashuiTemplates: []LocalDashuiTemplateTarget{},
+	}
+	if description.Dependencies == nil {
+		description.Dependencies = []string{}
+	}
+	description.PackageFiles, description.PackageSize, err = getPackageSize(solutionRootDirectory)
+	if err != nil {
+		log.Fatalf("Failed to determine the solution package size: %v", err)
+	}
+
+	model := newLocalTestModel(objectFiles)
+	for _, name := range sortedKeys(model.objects["fmm:entity"]) {
+		entity := model.objects["fmm:entity"][name]
+		description.Entities = append(description.Entities, LocalEntityDescription{
+			Name:        name,
+			MetricTypes: toStringList(entity["metricTypes"]),
+			EventTypes:  toStringList(entity["eventTypes"]),
+		})
+	}
+	for _, name := range sortedKeys(model.objects["dashui:template"]) {
+		template := model.objects["dashui:template"][name]
+		target, _ := template["target"].(string)
+		description.DashuiTemplates = append(description.DashuiTemplates, LocalDashuiTemplateTarget{
+			Name:   name,
+			Target: target,
+			File:   model.files["dashui:template"][name],
+		})
+	}
+
+	output.PrintCmdOutputCustom(cmd, description, &output.Table{
+		Headers: []string{"Name", "Version", "Type", "Dependencies", "Package Files", "Package Size"},
+		Lines: [][]string{{
+			description.Name,
+			description.Version,
+			description.SolutionType,
+			strings.Join(description.Dependencies, ", "),
+			fmt.Sprint(description.PackageFiles),
+			fmt.Sprintf("%d bytes", description.PackageSize),
+		}},
+		Detail: true,
+	})
+
+	// machine-readable formats include the details in the document above
+	if format, _ := cmd.Flags().GetString("output"); format == "" || format == "auto" || format == "table" || format == "detail" {
+		printLocalSolutionDetails(cmd, &description)
+	}
+}
+
+// printLocalSolutionDetails displays the objects, entities and templates of a solution description
+func printLocalSolutionDetails(cmd *cobra.Command, description *LocalSolutionDescription) {
+	lines := [][]string{}
+	for _, item := range description.Objects {
+		lines = append(lines, []string{item.Type, fmt.Sprint(item.Objects), fmt.Sprint(item.Files)})
+	}
+	output.PrintCmdStatus(cmd, "\nObjects:\n")
+	output.PrintCmdOutputCustom(cmd, description.Objects, &output.Table{Headers: []string{"Type", "Objects", "Files"}, Lines: lines})
+
+	if len(description.Entities) > 0 {
+		lines = [][]string{}
+		for _, entity := range description.Entities {
+			lines = append(lines, []string{entity.Name, strings.Join(entity.MetricTypes, "\n"), strings.Join(entity.EventTypes, "\n")})
+		}
+		output.PrintCmdStatus(cmd, "\nEntities:\n")
+		output.PrintCmdOutputCustom(cmd, description.Entities, &output.Table{Headers: []string{"Entity", "Metric Types", "Event Types"}, Lines: lines})
+	}
+
+	if len(description.DashuiTemplates) > 0 {
+		lines = [][]string{}
+		for _, template := range description.DashuiTemplates {
+			lines = append(lines, []string{template.Name, template.Target, template.File})
+		}
+		output.PrintCmdStatus(cmd, "\nDashUI templates:\n")
+		output.PrintCmdOutputCustom(cmd, description.DashuiTemplates, &output.Table{Headers: []string{"Template", "Target", "File"}, Lines: lines})
+	}
+}
+
+// describeLocalObjects lists the solution's objects of a type
+func describeLocalObjects(cmd *cobra.Command, objectFiles []manifestObjectsFile, objType string) {
+	objects := []localObjectDescription{}
+	for _, file := range objectFiles {
+		if file.objType != objType {
+			continue
+		}
+		for _, obj := range file.objects {
+			objMap, _ := obj.(map[string]any)
+			objects = append(objects, localObjectDescription{Name: getLocalObjectName(objMap), File: file.path})
+		}
+	}
+	if len(objects) == 0 {
+		log.Fatalf("The solution has no objects of type %q", objType)
+	}
+	sort.SliceStable(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+
+	lines := [][]string{}
+	for _, obj := range objects {
+		lines = append(lines, []string{obj.Name, obj.File})
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []localObjectDescription `json:"items"`
+		Total int                      `json:"total"`
+	}{objects, len(objects)}, &output.Table{Headers: []string{"Name", "File"}, Lines: lines})
+}
+
+// getPackageSize returns the number and total size of the files that would be packaged
+func getPackageSize(solutionPath string) (int, int64, error) {
+	nFiles := 0
+	var size int64
+	err := filepath.Walk(solutionPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !isAllowedPath(solutionPath, path, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			nFiles++
+			size += info.Size()
+		}
+		return nil
+	})
+	return nFiles, size, err
+}
@@ -24,12 +24,20 @@ import (
 )
 
 var solutionDescribeCmd = &cobra.Command{
-	Use:     "describe <solution-name>",
-	Args:    cobra.MaximumNArgs(1),
-	Short:   "Describe solution",
-	Long:    `Obtain metadata about a solution`,
-	Example: `  fsoc solution describe spacefleet`,
-	Run:     solutionDescribe,
+	Use:   "describe <solution-name> | --local [<type>]",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Describe solution",
+	Long: `Obtain metadata about a solution.
+
+Use --local to summarize the solution in the current directory (or the one specified with -d) instead: its
+dependencies, the number of objects of each type, the entity types with their metric and event types, the
+dashui templates with their targets and the number and total size of the files that would be packaged.
+With --local, the optional argument is an object type, e.g., fmm:entity, to list the solution's objects of that type.`,
+	Example: `  fsoc solution describe spacefleet
+  fsoc solution describe --local
+  fsoc solution describe --local fmm:metric -d mysolution`,
+	Run:         solutionDescribe,
+	Annotations: map[string]string{config.AnnotationForConfigBypass: ""},
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		config.SetActiveProfile(cmd, args, false)
 		return getSolutionNames(toComplete), cobra.ShellCompDirectiveDefault
@@ -41,10 +49,28 @@ func getSolutionDescribeCmd() *cobra.Command {
 		String("solution", "", "The name of the solution to describe")
 	_ = solutionDescribeCmd.Flags().MarkDeprecated("solution", "please use argument instead.")
 
+	solutionDescribeCmd.Flags().
+		Bool("local", false, "Describe the solution in a local directory instead of the one in the tenant")
+	solutionDescribeCmd.Flags().
+		StringP("directory", "d", "", "Path to the solution root directory for --local (defaults to current dir)")
+	solutionDescribeCmd.MarkFlagsMutuallyExclusive("local", "solution")
+
 	return solutionDescribeCmd
 }
 
 func solutionDescribe(cmd *cobra.Command, args []string) {
+	if local, _ := cmd.Flags().GetBool("local"); local {
+		describeLocalSolution(cmd, args)
+		return
+	}
+	if cmd.Flags().Changed("directory") {
+		log.Fatal("The --directory flag can only be used with --local")
+	}
+
+	// the command bypasses the config check to allow local descriptions, so check here
+	if config.GetCurrentContext() == nil {
+		log.Fatal(`fsoc is not configured, please use "fsoc config create" to configure an initial context`)
+	}
 	solution := getSolutionNameFromArgs(cmd, args, "solution")
 
 	cfg := config.GetCurrentContext()
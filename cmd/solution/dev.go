@@ -0,0 +1,392 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+)
+
+var solutionDevCmd = &cobra.Command{
+	Use:   "dev",
+	Args:  cobra.ExactArgs(0),
+	Short: "Validate, bump and push the solution in a development loop",
+	Long: `This command runs the solution development loop: it validates the solution locally, increments its
+patch version and pushes it with a development tag, waiting for the installation to complete. After the first
+successful push, the tenant is subscribed to the solution with the development tag, unless --no-subscribe is specified.
+
+Each developer gets their own tag, so that developers working on the same solution in a shared tenant don't
+overwrite each other's versions. Unless --tag is specified, the tag is "dev" followed by the user name (e.g., devjdoe),
+truncated to the maximum tag length. The tag, the tenant and whether the tenant was subscribed are recorded in the
+.fsocdev file in the solution directory (which should NOT be version controlled and is not packaged), so the same
+tag is used in every run.
+
+With --watch, the command keeps running and repeats the loop every time files in the solution directory change.
+Changes are debounced, so that saving several files at once triggers a single push. Validation and push failures
+are reported and the command keeps watching for the next change. Press Ctrl-C to stop.
+
+Use the --profile flag to push to a development tenant.
+
+Use --cleanup when done to tear down the development version: the tenant is unsubscribed from the solution with the
+development tag, the solution with that tag is deleted and the .fsocdev file is removed.`,
+	Example: `  fsoc solution dev
+  fsoc solution dev --watch
+  fsoc solution dev --watch -d mysolution --tag mytag --profile devtenant
+  fsoc solution dev --watch --debounce 5s
+  fsoc solution dev --cleanup`,
+	Run: solutionDev,
+}
+
+func getSolutionDevCmd() *cobra.Command {
+	solutionDevCmd.Flags().
+		StringP("directory", "d", "", "Path to the solution root directory (defaults to current dir)")
+
+	solutionDevCmd.Flags().
+		String("tag", "", "Tag to push the solution with (defaults to a per-developer tag, e.g., devjdoe)")
+
+	solutionDevCmd.Flags().
+		Bool("watch", false, "Watch the solution directory and repeat on every change")
+
+	solutionDevCmd.Flags().
+		Duration("debounce", time.Second, "Time to wait for changes to settle before pushing")
+
+	solutionDevCmd.Flags().
+		Int("wait", 300, "Wait (in seconds) for each push to be installed")
+
+	solutionDevCmd.Flags().
+		Bool("no-subscribe", false, "Don't subscribe the tenant to the solution after the first push")
+
+	solutionDevCmd.Flags().
+		Bool("cleanup", false, "Unsubscribe from and delete the development version of the solution")
+	solutionDevCmd.MarkFlagsMutuallyExclusive("cleanup", "watch")
+
+	return solutionDevCmd
+}
+
+func solutionDev(cmd *cobra.Command, args []string) {
+	solutionRootDirectory, _ := cmd.Flags().GetString("directory")
+	if solutionRootDirectory == "" {
+		solutionRootDirectory = "."
+	}
+	solutionRootDirectory = absolutizePath(solutionRootDirectory)
+	if !isSolutionPackageRoot(solutionRootDirectory) {
+		log.Fatalf("No solution manifest found in %q; please use -d flag", solutionRootDirectory)
+	}
+
+	// use the tag from the last run, unless specified
+	state, err := loadDevState(solutionRootDirectory)
+	if err != nil {
+		log.Fatalf("Failed to read the development state: %v", err)
+	}
+	tag, _ := cmd.Flags().GetString("tag")
+	switch {
+	case tag != "":
+	case state != nil:
+		tag = state.Tag
+	default:
+		tag = getDeveloperTag()
+	}
+	if !IsValidSolutionTag(tag) {
+		log.Fatalf("Invalid tag %q", tag)
+	}
+	tenant := config.GetCurrentContext().Tenant
+	if state == nil || state.Tag != tag || state.Tenant != tenant {
+		state = &devState{Tag: tag, Tenant: tenant}
+	}
+	loop := &devLoop{cmd: cmd, root: solutionRootDirectory, tag: tag, state: state}
+
+	if cleanup, _ := cmd.Flags().GetBool("cleanup"); cleanup {
+		if !loop.cleanup() {
+			log.Fatal("Failed to clean up the development version of the solution")
+		}
+		return
+	}
+
+	watch, _ := cmd.Flags().GetBool("watch")
+	debounce, _ := cmd.Flags().GetDuration("debounce")
+	ok := loop.run()
+	if !watch {
+		if !ok {
+			log.Fatal("Development loop failed")
+		}
+		return
+	}
+
+	if err := loop.watch(debounce); err != nil {
+		log.Fatalf("Failed to watch solution directory: %v", err)
+	}
+}
+
+// devLoop runs the validate-bump-push cycle for a solution directory. Each step runs
+// as a separate fsoc process, so that failures are reported without ending the loop.
+type devLoop struct {
+	cmd             *cobra.Command
+	root            string
+	tag             string
+	state           *devState
+	manifestContent []byte // content of the manifest after the last cycle, to ignore the version bump
+}
+
+// DevStateFileName is the file in the solution directory recording the development tag and
+// subscription maintained by the dev command; it should NOT be version controlled
+const DevStateFileName = ".fsocdev"
+
+// devState is the development version of a solution in a tenant
+type devState struct {
+	Tag        string `json:"tag"`
+	Tenant     string `json:"tenant"`
+	Subscribed bool   `json:"subscribed"`
+}
+
+func loadDevState(solutionPath string) (*devState, error) {
+	content, err := os.ReadFile(filepath.Join(solutionPath, DevStateFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var state devState
+	if err := json.Unmarshal(content, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse %v: %w", DevStateFileName, err)
+	}
+	return &state, nil
+}
+
+func (state *devState) save(solutionPath string) error {
+	f, err := os.Create(filepath.Join(solutionPath, DevStateFileName))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return output.WriteJson(state, f)
+}
+
+// getDeveloperTag returns the per-developer tag: "dev" followed by the lowercase letters
+// and digits of the user name, truncated to the maximum tag length
+func getDeveloperTag() string {
+	username := os.Getenv("USER")
+	if current, err := user.Current(); err == nil {
+		username = current.Username
+	}
+	tag := "dev"
+	for _, r := range strings.ToLower(username) {
+		if len(tag) == 10 {
+			break
+		}
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			tag += string(r)
+		}
+	}
+	return tag
+}
+
+func (l *devLoop) run() bool {
+	startTime := time.Now()
+	output.PrintCmdStatus(l.cmd, fmt.Sprintf("\n--- %v: validating solution in %q\n", startTime.Format(time.TimeOnly), l.root))
+	if !l.runFsoc("solution", "validate", "--local", "-d", l.root) {
+		output.PrintCmdStatus(l.cmd, "--- Validation failed; fix the problems above to continue\n")
+		return false
+	}
+
+	wait, _ := l.cmd.Flags().GetInt("wait")
+	ok := l.runFsoc("solution", "push", "-d", l.root, "--tag", l.tag, "--bump", fmt.Sprintf("--wait=%d", wait))
+	l.manifestContent = l.readManifest()
+	if !ok {
+		output.PrintCmdStatus(l.cmd, "--- Push failed\n")
+		return false
+	}
+	output.PrintCmdStatus(l.cmd, fmt.Sprintf("--- Pushed successfully in %.0f seconds\n", time.Since(startTime).Seconds()))
+
+	if noSubscribe, _ := l.cmd.Flags().GetBool("no-subscribe"); !l.state.Subscribed && !noSubscribe {
+		if !l.runFsoc("solution", "subscribe", l.solutionName(), "--tag", l.tag) {
+			output.PrintCmdStatus(l.cmd, "--- Subscribing the tenant failed\n")
+			return false
+		}
+		l.state.Subscribed = true
+		output.PrintCmdStatus(l.cmd, fmt.Sprintf("--- Subscribed the tenant to the solution with tag %v\n", l.tag))
+	}
+	if err := l.state.save(l.root); err != nil {
+		log.Warnf("Failed to save the development state: %v", err)
+	}
+	return true
+}
+
+// cleanup unsubscribes the tenant from the development version of the solution, deletes it
+// and removes the development state
+func (l *devLoop) cleanup() bool {
+	name := l.solutionName()
+	output.PrintCmdStatus(l.cmd, fmt.Sprintf("Cleaning up solution %v with tag %v\n", name, l.tag))
+	if l.state.Subscribed {
+		if !l.runFsoc("solution", "unsubscribe", name, "--tag", l.tag, "--yes") {
+			output.PrintCmdStatus(l.cmd, "--- Unsubscribing the tenant failed\n")
+			return false
+		}
+		l.state.Subscribed = false
+		if err := l.state.save(l.root); err != nil {
+			log.Warnf("Failed to save the development state: %v", err)
+		}
+	}
+	if !l.runFsoc("solution", "delete", name, "--tag", l.tag, "--yes") {
+		output.PrintCmdStatus(l.cmd, "--- Deleting the solution failed\n")
+		return false
+	}
+	if err := os.Remove(filepath.Join(l.root, DevStateFileName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warnf("Failed to remove %v: %v", DevStateFileName, err)
+	}
+	output.PrintCmdStatus(l.cmd, fmt.Sprintf("--- Removed solution %v with tag %v\n", name, l.tag))
+	return true
+}
+
+func (l *devLoop) solutionName() string {
+	manifest, err := getSolutionManifest(l.root)
+	if err != nil {
+		log.Fatalf("Failed to read the solution manifest: %v", err)
+	}
+	return manifest.Name
+}
+
+// runFsoc runs an fsoc command in a subprocess, passing through the config-related flags
+func (l *devLoop) runFsoc(args ...string) bool {
+	executable, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Failed to locate the fsoc executable: %v", err)
+	}
+	for _, name := range []string{"config", "profile"} {
+		if flag := l.cmd.Flags().Lookup(name); flag != nil && flag.Changed {
+			args = append(args, "--"+name, flag.Value.String())
+		}
+	}
+	args = append(args, "--no-version-check")
+
+	subCmd := exec.Command(executable, args...)
+	subCmd.Stdin = os.Stdin
+	subCmd.Stdout = l.cmd.OutOrStdout()
+	subCmd.Stderr = l.cmd.ErrOrStderr()
+	log.WithField("command", strings.Join(subCmd.Args, " ")).Info("Running fsoc command")
+	if err := subCmd.Run(); err != nil {
+		log.Infof("Command failed: %v", err)
+		return false
+	}
+	return true
+}
+
+func (l *devLoop) readManifest() []byte {
+	manifest, err := getSolutionManifest(l.root)
+	if err != nil {
+		return nil
+	}
+	data, _ := os.ReadFile(filepath.Join(l.root, "manifest."+manifest.ManifestFormat.String()))
+	return data
+}
+
+// watch runs the loop whenever the solution's files change, until interrupted
+func (l *devLoop) watch(debounce time.Duration) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// fsnotify does not watch recursively, so add each directory
+	err = filepath.Walk(l.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if !isAllowedPath(l.root, path, info) {
+				return filepath.SkipDir
+			}
+			return watcher.Add(path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	output.PrintCmdStatus(l.cmd, fmt.Sprintf("\nWatching %q for changes; press Ctrl-C to stop\n", l.root))
+	var timer <-chan time.Time
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !l.isRelevantChange(watcher, event) {
+				continue
+			}
+			log.WithFields(log.Fields{"file": event.Name, "op": event.Op.String()}).Info("Solution file changed")
+			timer = time.After(debounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Warnf("File watch error: %v", err)
+		case <-timer:
+			timer = nil
+			l.run()
+			output.PrintCmdStatus(l.cmd, "\nWatching for changes...\n")
+		case <-interrupt:
+			output.PrintCmdStatus(l.cmd, "\nStopped watching.\n")
+			return nil
+		}
+	}
+}
+
+// isRelevantChange filters out changes that should not trigger a push: excluded files,
+// the manifest rewrite by the version bump, and chmod-only events. New directories
+// are added to the watch list.
+func (l *devLoop) isRelevantChange(watcher *fsnotify.Watcher, event fsnotify.Event) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	info, err := os.Stat(event.Name)
+	if err == nil {
+		if !isAllowedPath(l.root, event.Name, info) {
+			return false
+		}
+		if info.IsDir() && event.Has(fsnotify.Create) {
+			if err := watcher.Add(event.Name); err != nil {
+				log.Warnf("Failed to watch new directory %q: %v", event.Name, err)
+			}
+		}
+	}
+	if strings.HasPrefix(filepath.Base(event.Name), "manifest.") && l.manifestContent != nil {
+		if bytes.Equal(l.readManifest(), l.manifestContent) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,291 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/apex/log"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/cmdkit/archive"
+	"github.com/cisco-open/fsoc/cmdkit/jsondoc"
+	"github.com/cisco-open/fsoc/output"
+)
+
+var solutionDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Args:  cobra.ExactArgs(0),
+	Short: "Compare the local solution with the deployed version",
+	Long: `This command downloads the solution version currently deployed with the given tag and compares
+it with the solution in the local directory, showing which objects and fields a push would add, remove or change.
+
+Objects are matched by type and identity (namespace and name for FMM objects, id or name otherwise), so
+moving objects between files does not produce differences. JSON and YAML files are compared by content,
+not by formatting.`,
+	Example: `  fsoc solution diff
+  fsoc solution diff --tag dev
+  fsoc solution diff -d mysolution --stable
+  fsoc solution diff -o json`,
+	Run: diffSolution,
+	Annotations: map[string]string{
+		output.TableFieldsAnnotation: "change: .change, type: .type, object: .object, field: .field, local: .local, deployed: .deployed",
+	},
+}
+
+// SolutionChange is a single difference between the local and the deployed solution
+type SolutionChange struct {
+	Change   string `json:"change" yaml:"change"` // added, removed or modified
+	Type     string `json:"type" yaml:"type"`
+	Object   string `json:"object" yaml:"object"`
+	Field    string `json:"field,omitempty" yaml:"field,omitempty"`
+	Local    any    `json:"local,omitempty" yaml:"local,omitempty"`
+	Deployed any    `json:"deployed,omitempty" yaml:"deployed,omitempty"`
+}
+
+const (
+	changeAdded    = jsondoc.ChangeAdded
+	changeRemoved  = jsondoc.ChangeRemoved
+	changeModified = jsondoc.ChangeModified
+)
+
+func getSolutionDiffCmd() *cobra.Command {
+	addTagFlags(solutionDiffCmd) // tag, stable
+
+	solutionDiffCmd.Flags().
+		StringP("directory", "d", "", "Path to the solution root directory (defaults to current dir)")
+
+	solutionDiffCmd.Flags().
+		Bool("no-isolate", false, "Disable fsoc-supported solution pseudo-isolation")
+
+	addVariableFlags(solutionDiffCmd)
+
+	return solutionDiffCmd
+}
+
+func diffSolution(cmd *cobra.Command, args []string) {
+	localDirectory, deployedDirectory, solutionName, tag, cleanup := prepareLocalAndDeployed(cmd)
+	defer cleanup()
+
+	changes, err := diffSolutionDirectories(localDirectory, deployedDirectory)
+	if err != nil {
+		log.Fatalf("Failed to compare solutions: %v", err)
+	}
+
+	if len(changes) == 0 {
+		output.PrintCmdStatus(cmd, fmt.Sprintf("No differences between the local solution and the deployed %q with tag %q.\n", solutionName, tag))
+		return
+	}
+	output.PrintCmdOutput(cmd, struct {
+		Items []SolutionChange `json:"items"`
+		Total int              `json:"total"`
+	}{changes, len(changes)})
+}
+
+// prepareLocalAndDeployed renders the local solution the way it would be pushed and downloads
+// and extracts the version deployed with the tag determined from the command's flags. The
+// returned function removes the temporary directories and should be called when done.
+func prepareLocalAndDeployed(cmd *cobra.Command) (localDirectory string, deployedDirectory string, solutionName string, tag string, cleanup func()) {
+	cleanups := []func(){}
+	cleanup = func() {
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			cleanups[i]()
+		}
+	}
+
+	solutionRootDirectory, _ := cmd.Flags().GetString("directory")
+	if solutionRootDirectory == "" {
+		solutionRootDirectory = "."
+	}
+	solutionRootDirectory = absolutizePath(solutionRootDirectory)
+	if !isSolutionPackageRoot(solutionRootDirectory) {
+		log.Fatalf("No solution manifest found in %q; please use -d flag", solutionRootDirectory)
+	}
+
+	// render the local solution the way it would be pushed
+	localDirectory = solutionRootDirectory
+	manifest, err := getSolutionManifest(solutionRootDirectory)
+	if err != nil {
+		log.Fatalf("Failed to read the solution manifest: %v", err)
+	}
+	if manifest.HasPseudoIsolation() {
+		localDirectory, tag, err = embeddedConditionalIsolate(cmd, solutionRootDirectory)
+		if err != nil {
+			log.Fatalf("Failed to isolate solution with tag: %v", err)
+		}
+		if isolatedDirectory := localDirectory; isolatedDirectory != solutionRootDirectory {
+			cleanups = append(cleanups, func() { os.RemoveAll(isolatedDirectory) })
+		}
+		manifest, err = getSolutionManifest(localDirectory)
+		if err != nil {
+			log.Fatalf("Failed to read the isolated solution manifest: %v", err)
+		}
+	} else {
+		tag, err = getEmbeddedTag(cmd, solutionRootDirectory)
+		if err != nil {
+			log.Fatalf("Failed to determine tag: %v", err)
+		}
+	}
+	solutionName = manifest.GetSolutionName()
+
+	// apply the overlay, substitute template variables and convert files the way they would be packaged
+	stagedDirectory, err := stageSolution(localDirectory, "", getStageOptions(cmd))
+	if err != nil {
+		log.Fatalf("Failed to prepare the local solution: %v", err)
+	}
+	cleanups = append(cleanups, func() { os.RemoveAll(filepath.Dir(stagedDirectory)) })
+	localDirectory = stagedDirectory
+
+	// download and extract the deployed version
+	archivePath, err := DownloadSolutionPackage(solutionName, tag, "")
+	if err != nil {
+		log.Fatalf("Failed to download deployed solution %q with tag %q: %v", solutionName, tag, err)
+	}
+	defer os.Remove(archivePath)
+	deployedDirectory, err = os.MkdirTemp("", solutionName+"."+tag+"-")
+	if err != nil {
+		log.Fatalf("Failed to create a temporary directory: %v", err)
+	}
+	cleanups = append(cleanups, func() { os.RemoveAll(deployedDirectory) })
+	if err = archive.Extract(archivePath, afero.NewBasePathFs(afero.NewOsFs(), deployedDirectory), archive.ExtractOptions{SkipLevels: 1}); err != nil {
+		log.Fatalf("Failed to extract deployed solution archive: %v", err)
+	}
+	return localDirectory, deployedDirectory, solutionName, tag, cleanup
+}
+
+// diffSolutionDirectories compares two solution directories at the object and field level.
+// The "local" values come from the first directory, the "deployed" ones from the second.
+func diffSolutionDirectories(localDir string, deployedDir string) ([]SolutionChange, error) {
+	localManifest, err := getSolutionManifest(localDir)
+	if err != nil {
+		return nil, fmt.Errorf("local solution: %w", err)
+	}
+	deployedManifest, err := getSolutionManifest(deployedDir)
+	if err != nil {
+		return nil, fmt.Errorf("deployed solution: %w", err)
+	}
+
+	changes := []SolutionChange{}
+
+	// compare manifests (ignoring the format, which is not part of the serialized manifest)
+	var localManifestDoc, deployedManifestDoc any
+	if err := remarshal(localManifest, &localManifestDoc); err != nil {
+		return nil, err
+	}
+	if err := remarshal(deployedManifest, &deployedManifestDoc); err != nil {
+		return nil, err
+	}
+	for _, fc := range jsondoc.Diff(localManifestDoc, deployedManifestDoc) {
+		changes = append(changes, SolutionChange{Change: fc.Change, Type: "manifest", Object: localManifest.Name, Field: fc.Path, Local: fc.New, Deployed: fc.Old})
+	}
+
+	// compare objects
+	localObjects, err := indexSolutionObjects(localDir, localManifest)
+	if err != nil {
+		return nil, fmt.Errorf("local solution: %w", err)
+	}
+	deployedObjects, err := indexSolutionObjects(deployedDir, deployedManifest)
+	if err != nil {
+		return nil, fmt.Errorf("deployed solution: %w", err)
+	}
+	keys := make(map[solutionObjectKey]bool)
+	for key := range localObjects {
+		keys[key] = true
+	}
+	for key := range deployedObjects {
+		keys[key] = true
+	}
+	sortedKeys := make([]solutionObjectKey, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Slice(sortedKeys, func(i, j int) bool {
+		if sortedKeys[i].objType != sortedKeys[j].objType {
+			return sortedKeys[i].objType < sortedKeys[j].objType
+		}
+		return sortedKeys[i].id < sortedKeys[j].id
+	})
+	for _, key := range sortedKeys {
+		local, inLocal := localObjects[key]
+		deployed, inDeployed := deployedObjects[key]
+		switch {
+		case !inDeployed:
+			changes = append(changes, SolutionChange{Change: changeAdded, Type: key.objType, Object: key.id})
+		case !inLocal:
+			changes = append(changes, SolutionChange{Change: changeRemoved, Type: key.objType, Object: key.id})
+		default:
+			for _, fc := range jsondoc.Diff(local, deployed) {
+				changes = append(changes, SolutionChange{Change: fc.Change, Type: key.objType, Object: key.id, Field: fc.Path, Local: fc.New, Deployed: fc.Old})
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+type solutionObjectKey struct {
+	objType string
+	id      string
+}
+
+// indexSolutionObjects loads the solution's objects, normalized to JSON values, keyed by type and identity
+func indexSolutionObjects(root string, manifest *Manifest) (map[solutionObjectKey]any, error) {
+	files, errs := loadManifestObjects(root, manifest)
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	index := make(map[solutionObjectKey]any)
+	for _, file := range files {
+		for i, obj := range file.objects {
+			var normalized any
+			if err := remarshal(obj, &normalized); err != nil {
+				return nil, fmt.Errorf("%v: %w", file.path, err)
+			}
+			id := getObjectIdentity(normalized)
+			if id == "" {
+				id = fmt.Sprintf("%v[%d]", file.path, i)
+			}
+			index[solutionObjectKey{objType: file.objType, id: id}] = normalized
+		}
+	}
+	return index, nil
+}
+
+// getObjectIdentity returns a human-readable identity of an object, based on the
+// commonly used identifying fields, or "" if none is present
+func getObjectIdentity(obj any) string {
+	objMap, ok := obj.(map[string]any)
+	if !ok {
+		return ""
+	}
+	if ns, ok := objMap["namespace"].(map[string]any); ok {
+		if nsName, ok := ns["name"].(string); ok {
+			if name, ok := objMap["name"].(string); ok {
+				return nsName + ":" + name
+			}
+		}
+	}
+	for _, field := range []string{"id", "name"} {
+		if value, ok := objMap[field].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
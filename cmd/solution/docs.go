@@ -0,0 +1,236 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DocsDirName is the name of the solution's documentation directory, packaged with the solution
+const DocsDirName = "docs"
+
+// imageExtensions are the extensions of the image files whose size is checked
+var imageExtensions = []string{".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp"}
+
+// markdownLinkRegexp matches the links and images in markdown: [text](target "title") and
+// ![alt](target "title"), as well as <img src="target"> HTML tags
+var markdownLinkRegexp = regexp.MustCompile(`!?\[[^\]]*\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)|<img\s[^>]*src="([^"]+)"`)
+
+// checkSolutionDocs checks the solution's readme and documentation: the readme referenced by the
+// manifest (unless it is a URL) must be a file in the solution directory, the relative links in the
+// readme and in the markdown files of the docs directory must refer to files in the solution that are
+// packaged, and images may not exceed the maximum image size of the package budget.
+// It returns the problems found.
+func checkSolutionDocs(solutionPath string, manifest *Manifest, budget *PackageBudget) ([]string, error) {
+	problems := []string{}
+	markdownFiles := []string{} // relative, slash-separated paths
+	images := map[string]bool{}
+
+	if readme := manifest.Readme; readme != "" && !isUrl(readme) {
+		readmePath := path.Clean(filepath.ToSlash(readme))
+		info, err := os.Stat(filepath.Join(solutionPath, filepath.FromSlash(readmePath)))
+		switch {
+		case !filepath.IsLocal(readmePath):
+			problems = append(problems, fmt.Sprintf("the readme %q is outside of the solution directory", readme))
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("the readme %q does not exist", readme))
+		case !info.Mode().IsRegular():
+			problems = append(problems, fmt.Sprintf("the readme %q is not a file", readme))
+		case strings.EqualFold(path.Ext(readmePath), ".md"):
+			markdownFiles = append(markdownFiles, readmePath)
+		}
+	}
+
+	docsPath := filepath.Join(solutionPath, DocsDirName)
+	if _, err := os.Stat(docsPath); err == nil {
+		err := filepath.Walk(docsPath, func(filePath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !isAllowedPath(solutionPath, filePath, info) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			relPath, err := filepath.Rel(solutionPath, filePath)
+			if err != nil || info.IsDir() {
+				return err
+			}
+			relPath = filepath.ToSlash(relPath)
+			if strings.EqualFold(path.Ext(relPath), ".md") && !slices.Contains(markdownFiles, relPath) {
+				markdownFiles = append(markdownFiles, relPath)
+			}
+			if slices.Contains(imageExtensions, strings.ToLower(path.Ext(relPath))) {
+				images[relPath] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the %v directory: %w", DocsDirName, err)
+		}
+	}
+
+	for _, markdownFile := range markdownFiles {
+		content, err := os.ReadFile(filepath.Join(solutionPath, filepath.FromSlash(markdownFile)))
+		if err != nil {
+			return nil, err
+		}
+		for _, target := range getMarkdownLinks(string(content)) {
+			linkPath, problem := resolveDocLink(solutionPath, markdownFile, target)
+			if problem != "" {
+				problems = append(problems, fmt.Sprintf("%v: link %q %v", markdownFile, target, problem))
+			} else if linkPath != "" && slices.Contains(imageExtensions, strings.ToLower(path.Ext(linkPath))) {
+				images[linkPath] = true
+			}
+		}
+	}
+
+	imagePaths := make([]string, 0, len(images))
+	for imagePath := range images {
+		imagePaths = append(imagePaths, imagePath)
+	}
+	slices.Sort(imagePaths)
+	for _, imagePath := range imagePaths {
+		info, err := os.Stat(filepath.Join(solutionPath, filepath.FromSlash(imagePath)))
+		if err != nil {
+			return nil, err
+		}
+		if problem := checkBudgetLimit(fmt.Sprintf("image %v is", imagePath), info.Size(), budget.MaxImageSize, defaultMaxImageSize, "bytes"); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+
+	return problems, nil
+}
+
+// getMarkdownLinks returns the link and image targets in markdown content, outside of code blocks
+func getMarkdownLinks(content string) []string {
+	targets := []string{}
+	inCodeBlock := false
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if inCodeBlock {
+			continue
+		}
+		for _, match := range markdownLinkRegexp.FindAllStringSubmatch(line, -1) {
+			if match[1] != "" {
+				targets = append(targets, match[1])
+			} else {
+				targets = append(targets, match[2])
+			}
+		}
+	}
+	return targets
+}
+
+// resolveDocLink returns the slash-separated path, relative to the solution directory, of the
+// file a link in a markdown file refers to, or "" if the link is not to a file (e.g., a URL or an
+// anchor). It returns a description of the problem if the file is not in the solution package.
+func resolveDocLink(solutionPath string, markdownFile string, target string) (string, string) {
+	if isUrl(target) || strings.HasPrefix(target, "#") || strings.Contains(target, ":") {
+		return "", "" // URLs, anchors and other schemes (e.g., mailto:)
+	}
+	target, _, _ = strings.Cut(target, "#")
+	target, _, _ = strings.Cut(target, "?")
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+	var linkPath string
+	if strings.HasPrefix(target, "/") {
+		linkPath = path.Clean(strings.TrimPrefix(target, "/"))
+	} else {
+		linkPath = path.Join(path.Dir(markdownFile), target)
+	}
+	if !filepath.IsLocal(linkPath) {
+		return "", "refers to a file outside of the solution directory"
+	}
+	filePath := filepath.Join(solutionPath, filepath.FromSlash(linkPath))
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return "", "refers to a file that does not exist"
+	}
+	for p := filePath; p != solutionPath && p != filepath.Dir(p); p = filepath.Dir(p) {
+		pInfo, err := os.Stat(p)
+		if err == nil && !isAllowedPath(solutionPath, p, pInfo) {
+			return "", "refers to a file that is not packaged"
+		}
+	}
+	if info.IsDir() {
+		return "", ""
+	}
+	return linkPath, ""
+}
+
+// isUrl returns true if s is an absolute URL with a scheme and a host, e.g., https://example.com/readme
+func isUrl(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+// markdownImageRegexp and markdownInlineRegexp match the inline markdown elements replaced by their
+// text in descriptions: images are removed first (as they may be nested in links), then links are
+// replaced by their text and emphasis or code markers are removed
+var (
+	markdownImageRegexp  = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+	markdownInlineRegexp = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)|[*_` + "`" + `]+`)
+)
+
+// getReadmeDescription returns the first paragraph of text of a markdown readme, without the markdown
+// formatting, for use as the solution description; headings, badges, HTML and code blocks are skipped
+func getReadmeDescription(readmePath string) (string, error) {
+	content, err := os.ReadFile(readmePath)
+	if err != nil {
+		return "", err
+	}
+	paragraph := []string{}
+	inCodeBlock := false
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "```") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		isText := !inCodeBlock && line != "" && !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "<") &&
+			!strings.HasPrefix(line, "|") && !strings.HasPrefix(line, "---") && !strings.HasPrefix(line, "===")
+		if isText {
+			text := markdownImageRegexp.ReplaceAllString(line, "")
+			text = strings.TrimSpace(markdownInlineRegexp.ReplaceAllString(text, "$1"))
+			if text == "" {
+				isText = false // e.g., a line of badges
+			} else {
+				paragraph = append(paragraph, text)
+			}
+		}
+		if !isText && len(paragraph) > 0 {
+			break
+		}
+	}
+	return strings.Join(paragraph, " "), nil
+}
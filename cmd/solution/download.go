@@ -21,19 +21,26 @@ import (
 	"path/filepath"
 
 	"github.com/apex/log"
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 
+	"github.com/cisco-open/fsoc/cmdkit/archive"
 	"github.com/cisco-open/fsoc/config"
 	"github.com/cisco-open/fsoc/output"
 	"github.com/cisco-open/fsoc/platform/api"
 )
 
 var solutionDownloadCmd = &cobra.Command{
-	Use:              "download <solution-name>",
-	Args:             cobra.MaximumNArgs(1),
-	Short:            "Download solution",
-	Long:             `This downloads the indicated solution into the current directory. Also see the "fork" command.`,
-	Example:          `  fsoc solution download spacefleet`,
+	Use:   "download <solution-name>",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Download solution",
+	Long: `This downloads the indicated solution into the current directory. Also see the "fork" command.
+
+With --extract, the solution is extracted into a directory named after the solution instead. The archive is
+checked before anything is extracted: entries outside of the solution directory, symbolic links and archives
+whose extracted size exceeds the limits are rejected.`,
+	Example: `  fsoc solution download spacefleet
+  fsoc solution download spacefleet --extract`,
 	Run:              downloadSolution,
 	TraverseChildren: true,
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -47,13 +54,21 @@ func getSolutionDownloadCmd() *cobra.Command {
 	_ = solutionDownloadCmd.Flags().MarkDeprecated("name", "please use argument instead.")
 
 	solutionDownloadCmd.Flags().String("tag", "stable", "tag related to the solution to download")
+
+	solutionDownloadCmd.Flags().
+		Bool("extract", false, "Extract the solution into a directory named after it")
 	return solutionDownloadCmd
 }
 
 func downloadSolution(cmd *cobra.Command, args []string) {
 	solutionName := getSolutionNameFromArgs(cmd, args, "name")
 	solutionTagFlag, _ := cmd.Flags().GetString("tag")
+	extract, _ := cmd.Flags().GetBool("extract")
 
+	if extract {
+		downloadAndExtractSolution(cmd, solutionName, solutionTagFlag)
+		return
+	}
 	if _, err := DownloadSolutionPackage(solutionName, solutionTagFlag, "."); err != nil {
 		log.Fatal(err.Error())
 	}
@@ -62,6 +77,31 @@ func downloadSolution(cmd *cobra.Command, args []string) {
 	output.PrintCmdStatus(cmd, message)
 }
 
+// downloadAndExtractSolution downloads the solution package to a temporary file and extracts it
+// into a new directory, named after the solution, in the current directory
+func downloadAndExtractSolution(cmd *cobra.Command, solutionName string, tag string) {
+	targetDir := absolutizePath(solutionName)
+	if _, err := os.Stat(targetDir); err == nil {
+		log.Fatalf("Directory %q already exists", targetDir)
+	}
+	archivePath, err := DownloadSolutionPackage(solutionName, tag, "")
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	defer os.Remove(archivePath)
+
+	if err := os.Mkdir(targetDir, 0o755); err != nil {
+		log.Fatalf("Failed to create directory %q: %v", targetDir, err)
+	}
+	options := archive.ExtractOptions{SkipLevels: 1, Progress: archive.LogProgress("Extracting solution")}
+	if err := archive.Extract(archivePath, afero.NewBasePathFs(afero.NewOsFs(), targetDir), options); err != nil {
+		os.RemoveAll(targetDir)
+		log.Fatalf("Failed to extract solution archive: %v", err)
+	}
+
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Solution %q with tag %s downloaded and extracted into %v.\n", solutionName, tag, targetDir))
+}
+
 // DownloadSolutionPackage downloads the solution package into the specified target path
 // targetPath may be one of the following:
 // - the empty string: download to a temporary file
@@ -0,0 +1,403 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/itchyny/gojq"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+)
+
+var solutionEditCmd = &cobra.Command{
+	Use:   "edit --select <type> --set <jq-expression> [--where <jq-condition>]",
+	Args:  cobra.NoArgs,
+	Short: "Apply structured edits to the solution's objects",
+	Long: `This command applies jq expressions to the solution's objects of the selected types, in all object files
+referenced by the manifest, and writes the changed files back in their format (JSON or YAML).
+
+The --select flag specifies the object types to edit; it may contain wildcards, e.g., fmm:* for all FMM objects.
+The --where flag narrows the edit to the objects for which the jq condition is true. Each --set expression is
+applied to each selected object in turn and must produce a single object, e.g.:
+  .monitoredEntities += ["myns:host"]
+  .description = "Host CPU usage"
+  del(.isEnabled)
+
+Files are written only if their objects change. Fields keep their order in the file, with new fields added at the
+end; comments in YAML files are kept for the fields that remain. Use --dry-run to list the objects that would
+change without writing them.`,
+	Example: `  fsoc solution edit --select fmm:metric --set '.monitoredEntities += ["myns:host"]'
+  fsoc solution edit --select fmm:entity --where '.name | startswith("k8s")' --set '.lifecyleConfiguration.retentionPeriod = "PT1H"'
+  fsoc solution edit --select 'dashui:*' --set 'del(.view)' --dry-run`,
+	Run:         solutionEdit,
+	Annotations: map[string]string{config.AnnotationForConfigBypass: ""},
+}
+
+// editedObject is an object changed by an edit
+type editedObject struct {
+	File string `json:"file"`
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+func getSolutionEditCmd() *cobra.Command {
+	solutionEditCmd.Flags().
+		StringSlice("select", nil, "Types of the objects to edit (wildcards allowed)")
+	_ = solutionEditCmd.MarkFlagRequired("select")
+
+	solutionEditCmd.Flags().
+		StringArray("set", nil, "jq expression producing the edited object; can be repeated")
+	_ = solutionEditCmd.MarkFlagRequired("set")
+
+	solutionEditCmd.Flags().
+		String("where", "", "jq condition selecting the objects to edit")
+
+	solutionEditCmd.Flags().
+		Bool("dry-run", false, "List the objects that would change without writing them")
+
+	solutionEditCmd.Flags().
+		StringP("directory", "d", "", "Path to the solution root directory (defaults to current dir)")
+
+	return solutionEditCmd
+}
+
+func solutionEdit(cmd *cobra.Command, args []string) {
+	solutionRootDirectory, _ := cmd.Flags().GetString("directory")
+	if solutionRootDirectory == "" {
+		solutionRootDirectory = "."
+	}
+	solutionRootDirectory = absolutizePath(solutionRootDirectory)
+	manifest, err := getSolutionManifest(solutionRootDirectory)
+	if err != nil {
+		log.Fatalf("Failed to read the solution manifest: %v", err)
+	}
+
+	// compile the expressions
+	selectors, _ := cmd.Flags().GetStringSlice("select")
+	for _, selector := range selectors {
+		if _, err := path.Match(selector, ""); err != nil {
+			log.Fatalf("Invalid type selector %q: %v", selector, err)
+		}
+	}
+	var where *gojq.Code
+	if condition, _ := cmd.Flags().GetString("where"); condition != "" {
+		where = compileJq(condition)
+	}
+	edits := []*gojq.Code{}
+	expressions, _ := cmd.Flags().GetStringArray("set")
+	for _, expression := range expressions {
+		edits = append(edits, compileJq(expression))
+	}
+
+	// edit the objects of the selected types
+	objectFiles, errs := loadManifestObjects(solutionRootDirectory, manifest)
+	if len(errs) > 0 {
+		for _, err := range errs {
+			log.Errorf("Failed to load %v", err)
+		}
+		log.Fatalf("Failed to load %d object file(s)", len(errs))
+	}
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	edited := []editedObject{}
+	for _, file := range objectFiles {
+		if !matchesTypeSelector(file.objType, selectors) {
+			continue
+		}
+		changed := false
+		for i, obj := range file.objects {
+			obj = normalizeNumbers(obj)
+			if where != nil && !isJqConditionTrue(where, obj) {
+				continue
+			}
+			result := obj
+			for j, edit := range edits {
+				if result, err = runJqEdit(edit, result); err != nil {
+					log.Fatalf("Failed to apply %q to object #%d in %v: %v", expressions[j], i, file.path, err)
+				}
+			}
+			if reflect.DeepEqual(obj, result) {
+				continue
+			}
+			file.objects[i] = result
+			changed = true
+			objMap, _ := result.(map[string]any)
+			edited = append(edited, editedObject{File: file.path, Type: file.objType, Name: getLocalObjectName(objMap)})
+		}
+		if changed && !dryRun {
+			if err := writeEditedObjectsFile(filepath.Join(solutionRootDirectory, file.path), file.objects); err != nil {
+				log.Fatalf("Failed to write %v: %v", file.path, err)
+			}
+		}
+	}
+
+	lines := [][]string{}
+	for _, obj := range edited {
+		lines = append(lines, []string{obj.File, obj.Type, obj.Name})
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []editedObject `json:"items"`
+		Total int            `json:"total"`
+	}{edited, len(edited)}, &output.Table{Headers: []string{"File", "Type", "Name"}, Lines: lines})
+	if dryRun {
+		output.PrintCmdStatus(cmd, fmt.Sprintf("%d object(s) would be changed; no files were written\n", len(edited)))
+	} else {
+		output.PrintCmdStatus(cmd, fmt.Sprintf("Changed %d object(s)\n", len(edited)))
+	}
+}
+
+func compileJq(expression string) *gojq.Code {
+	query, err := gojq.Parse(expression)
+	if err != nil {
+		log.Fatalf("Failed to parse jq expression %q: %v", expression, err)
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		log.Fatalf("Failed to compile jq expression %q: %v", expression, err)
+	}
+	return code
+}
+
+func matchesTypeSelector(objType string, selectors []string) bool {
+	for _, selector := range selectors {
+		if matched, _ := path.Match(selector, objType); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func isJqConditionTrue(code *gojq.Code, obj any) bool {
+	result, ok := code.Run(obj).Next()
+	if !ok {
+		return false
+	}
+	if err, isErr := result.(error); isErr {
+		log.Warnf("Condition failed for an object, skipping it: %v", err)
+		return false
+	}
+	return result != nil && result != false
+}
+
+// runJqEdit applies an edit expression to an object; the expression must produce a single object
+func runJqEdit(code *gojq.Code, obj any) (any, error) {
+	iter := code.Run(obj)
+	result, ok := iter.Next()
+	if !ok {
+		return nil, fmt.Errorf("the expression produced no result")
+	}
+	if err, isErr := result.(error); isErr {
+		return nil, err
+	}
+	if _, more := iter.Next(); more {
+		return nil, fmt.Errorf("the expression produced more than one result")
+	}
+	if _, isObject := result.(map[string]any); !isObject {
+		return nil, fmt.Errorf("the expression produced %T instead of an object", result)
+	}
+	return result, nil
+}
+
+// normalizeNumbers converts the integral numbers parsed from JSON into ints, so that they
+// are processed and written back as integers
+func normalizeNumbers(v any) any {
+	switch typed := v.(type) {
+	case map[string]any:
+		for key, value := range typed {
+			typed[key] = normalizeNumbers(value)
+		}
+	case []any:
+		for i, value := range typed {
+			typed[i] = normalizeNumbers(value)
+		}
+	case float64:
+		if typed == math.Trunc(typed) && math.Abs(typed) < 1<<53 {
+			return int(typed)
+		}
+	}
+	return v
+}
+
+// writeEditedObjectsFile writes the objects into the file, in the file's format, keeping the
+// order of the fields (and the comments, in YAML) in the existing file
+func writeEditedObjectsFile(filePath string, objects []any) error {
+	original, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	var origNode yaml.Node // JSON is parsed as YAML, to get the field order
+	if err := yaml.Unmarshal(original, &origNode); err != nil {
+		return err
+	}
+	var orig *yaml.Node
+	if len(origNode.Content) > 0 {
+		orig = origNode.Content[0]
+	}
+
+	var doc any = objects
+	if orig == nil || orig.Kind != yaml.SequenceNode {
+		doc = objects[0] // file with a single object
+	}
+
+	var buf bytes.Buffer
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml":
+		node := toOrderedYamlNode(doc, orig)
+		node.HeadComment, node.FootComment = origNode.HeadComment, origNode.FootComment
+		err = writeComponent(node, &buf, FileFormatYAML)
+	default:
+		err = writeComponent(toOrderedValue(doc, orig), &buf, FileFormatJSON)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, buf.Bytes(), 0o644)
+}
+
+// orderedObject is a JSON object whose fields are written in a given order
+type orderedObject struct {
+	keys   []string
+	values map[string]any
+}
+
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		for _, v := range []any{key, o.values[key]} {
+			data, err := marshalJsonNoEscape(v)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(data)
+			if v == key {
+				buf.WriteByte(':')
+			}
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func marshalJsonNoEscape(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// orderedKeys returns the keys of the object in the order of the original mapping node,
+// followed by the new keys in alphabetical order
+func orderedKeys(obj map[string]any, orig *yaml.Node) ([]string, map[string][2]*yaml.Node) {
+	keys := []string{}
+	origFields := map[string][2]*yaml.Node{} // key -> key node, value node
+	if orig != nil && orig.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(orig.Content); i += 2 {
+			key := orig.Content[i].Value
+			origFields[key] = [2]*yaml.Node{orig.Content[i], orig.Content[i+1]}
+			if _, found := obj[key]; found {
+				keys = append(keys, key)
+			}
+		}
+	}
+	newKeys := []string{}
+	for key := range obj {
+		if _, found := origFields[key]; !found {
+			newKeys = append(newKeys, key)
+		}
+	}
+	sort.Strings(newKeys)
+	return append(keys, newKeys...), origFields
+}
+
+// toOrderedValue converts objects into orderedObject, following the field order of the original node
+func toOrderedValue(v any, orig *yaml.Node) any {
+	switch typed := v.(type) {
+	case map[string]any:
+		keys, origFields := orderedKeys(typed, orig)
+		values := make(map[string]any, len(typed))
+		for _, key := range keys {
+			values[key] = toOrderedValue(typed[key], origFields[key][1])
+		}
+		return orderedObject{keys: keys, values: values}
+	case []any:
+		items := make([]any, len(typed))
+		for i, item := range typed {
+			items[i] = toOrderedValue(item, getSequenceItem(orig, i))
+		}
+		return items
+	default:
+		return v
+	}
+}
+
+// toOrderedYamlNode converts a value into a YAML node, following the field order and keeping
+// the comments of the original node
+func toOrderedYamlNode(v any, orig *yaml.Node) *yaml.Node {
+	var node *yaml.Node
+	switch typed := v.(type) {
+	case map[string]any:
+		node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		keys, origFields := orderedKeys(typed, orig)
+		for _, key := range keys {
+			keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+			if origKey := origFields[key][0]; origKey != nil {
+				keyNode.HeadComment, keyNode.LineComment, keyNode.FootComment = origKey.HeadComment, origKey.LineComment, origKey.FootComment
+			}
+			node.Content = append(node.Content, keyNode, toOrderedYamlNode(typed[key], origFields[key][1]))
+		}
+	case []any:
+		node = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for i, item := range typed {
+			node.Content = append(node.Content, toOrderedYamlNode(item, getSequenceItem(orig, i)))
+		}
+	default:
+		node = &yaml.Node{}
+		if err := node.Encode(v); err != nil {
+			node = &yaml.Node{Kind: yaml.ScalarNode, Value: fmt.Sprint(v)}
+		}
+	}
+	if orig != nil && orig.Kind == node.Kind {
+		node.Style = orig.Style
+		node.HeadComment, node.LineComment, node.FootComment = orig.HeadComment, orig.LineComment, orig.FootComment
+	}
+	return node
+}
+
+func getSequenceItem(orig *yaml.Node, i int) *yaml.Node {
+	if orig != nil && orig.Kind == yaml.SequenceNode && i < len(orig.Content) {
+		return orig.Content[i]
+	}
+	return nil
+}
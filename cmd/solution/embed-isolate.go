@@ -50,7 +50,7 @@ func embeddedConditionalIsolate(cmd *cobra.Command, sourceDir string) (string, s
 	if err != nil {
 		return "", "", err
 	}
-	if !strings.Contains(manifest.Name, "${") {
+	if !manifest.HasPseudoIsolation() {
 		if envVarsFile != "" {
 			log.Warnf("Isolation env file %q is present for a solution that doesn't use isolation variables", envVarsFile)
 		}
@@ -62,7 +62,7 @@ func embeddedConditionalIsolate(cmd *cobra.Command, sourceDir string) (string, s
 		return "", "", fmt.Errorf("pseudo-isolation is supported only for JSON-formatted solutions")
 	}
 
-	log.Warnf("This solution uses fsoc-provided pseudo-isolation, which is now deprecated; please transition your solutions to native isolation soon to get the full isolation benefits.")
+	log.Info("This solution uses fsoc-provided pseudo-isolation; consider native isolation for new solutions")
 
 	// prepare target directory
 	// TODO: instead of fsoc as prefix, use as much as we can extract from the solution name
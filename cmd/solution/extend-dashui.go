@@ -17,6 +17,8 @@ package solution
 import (
 	"fmt"
 	"strings"
+
+	"github.com/apex/log"
 )
 
 func getEcpList(entity *FmmEntity) *DashuiTemplate {
@@ -85,7 +87,10 @@ func getEcpHome(manifest *Manifest) *DashuiTemplatePropsExtension {
 	}
 
 	entityRefs := make([]string, 0)
-	fmmEntities := manifest.GetFmmEntities()
+	fmmEntities, err := manifest.GetFmmEntities()
+	if err != nil {
+		log.Fatalf("Failed to read the entity definitions: %v", err)
+	}
 
 	ecpHomeEntities := make([]*DashuiEcpHomeEntity, 0)
 	for i, entity := range fmmEntities {
@@ -342,7 +347,10 @@ func getDashuiDetailsList(entity *FmmEntity, manifest *Manifest) *DashuiTemplate
 
 	elements = append(elements, logsWidget)
 
-	fmmMetrics := manifest.GetFmmMetrics()
+	fmmMetrics, err := manifest.GetFmmMetrics()
+	if err != nil {
+		log.Fatalf("Failed to read the metric definitions: %v", err)
+	}
 
 	for _, metricRef := range entity.MetricTypes {
 		cardTitle := ""
@@ -460,8 +468,13 @@ func getNamingAttribute(entity *FmmEntity) string {
 		return nameAttribute
 	}
 
-	nameAttribute = entity.AttributeDefinitions.Required[0]
-	return nameAttribute
+	if len(entity.AttributeDefinitions.Required) > 0 {
+		return entity.AttributeDefinitions.Required[0]
+	}
+
+	// fall back to the first attribute, in a stable order
+	attributes := sortedKeys(entity.AttributeDefinitions.Attributes)
+	return attributes[0]
 }
 
 func NewDashuiClickable() *DashuiClickable {
@@ -132,7 +132,10 @@ func getAssociationDeclaration(entity *FmmEntity, associationType string, toType
 }
 
 func findEntity(entityName string, manifest *Manifest) *FmmEntity {
-	entities := manifest.GetFmmEntities()
+	entities, err := manifest.GetFmmEntities()
+	if err != nil {
+		log.Fatalf("Failed to read the entity definitions: %v", err)
+	}
 	var entity *FmmEntity
 	for _, e := range entities {
 		if e.Name == entityName {
@@ -284,7 +287,7 @@ func getServiceComponent(serviceName string) *ServiceDef {
 func checkCreateSolutionNamespace(cmd *cobra.Command, manifest *Manifest, folderName string) {
 	componentType := "fmm:namespace"
 	namespaceName := manifest.GetNamespaceName()
-	fileName := manifest.GetSolutionName() + ".json"
+	fileName := componentFileName(cmd, manifest, manifest.GetSolutionName())
 	objFilePath := fmt.Sprintf("%s/%s", folderName, fileName)
 
 	componentDef := manifest.GetComponentDef(componentType)
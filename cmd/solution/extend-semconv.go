@@ -0,0 +1,415 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/cisco-open/fsoc/output"
+)
+
+// semconvModel is the content of an OpenTelemetry semantic conventions model file
+type semconvModel struct {
+	Groups []semconvGroup `yaml:"groups"`
+}
+
+type semconvGroup struct {
+	Id         string             `yaml:"id"`
+	Type       string             `yaml:"type"` // resource, entity, metric, span, attribute_group, ...
+	Prefix     string             `yaml:"prefix"`
+	Brief      string             `yaml:"brief"`
+	MetricName string             `yaml:"metric_name"`
+	Instrument string             `yaml:"instrument"`
+	Unit       string             `yaml:"unit"`
+	Attributes []semconvAttribute `yaml:"attributes"`
+}
+
+type semconvAttribute struct {
+	Id               string `yaml:"id"`
+	Ref              string `yaml:"ref"`
+	Type             any    `yaml:"type"` // a type name or an enum definition
+	Brief            string `yaml:"brief"`
+	RequirementLevel any    `yaml:"requirement_level"` // a level name or a map with the level's condition
+}
+
+// semconvTypes maps the semantic conventions attribute types to FMM attribute types;
+// arrays have no FMM equivalent and are represented as strings
+var semconvTypes = map[string]string{
+	"string":    "string",
+	"int":       "long",
+	"double":    "double",
+	"boolean":   "boolean",
+	"string[]":  "string",
+	"int[]":     "string",
+	"double[]":  "string",
+	"boolean[]": "string",
+}
+
+// generatedModel is the set of FMM types generated from semantic conventions or OTLP samples
+type generatedModel struct {
+	entities []*FmmEntity
+	metrics  []*FmmMetric
+}
+
+// extendFromSemconv adds the entities and metrics defined in semantic conventions model files
+func extendFromSemconv(cmd *cobra.Command, manifest *Manifest, files []string) {
+	model, err := generateFromSemconv(files, manifest.GetNamespaceName())
+	if err != nil {
+		log.Fatalf("Failed to generate FMM types from semantic conventions: %v", err)
+	}
+	addGeneratedModel(cmd, manifest, model)
+}
+
+// extendFromOtlp adds an entity and metrics matching the telemetry in an OTLP JSON sample file
+func extendFromOtlp(cmd *cobra.Command, manifest *Manifest, file string, entityName string) {
+	model, err := generateFromOtlp(file, entityName, manifest.GetNamespaceName())
+	if err != nil {
+		log.Fatalf("Failed to generate FMM types from OTLP sample %q: %v", file, err)
+	}
+	addGeneratedModel(cmd, manifest, model)
+}
+
+// generateFromSemconv converts resource (or entity) groups into entities and metric groups into
+// metrics. Attribute references are resolved across all files. Metrics are associated with the
+// entity whose name is the longest prefix of the metric name (e.g., k8s.pod.cpu.time with k8s.pod).
+func generateFromSemconv(files []string, namespaceName string) (*generatedModel, error) {
+	groups := []semconvGroup{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var model semconvModel
+		if err := yaml.Unmarshal(data, &model); err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %w", file, err)
+		}
+		groups = append(groups, model.Groups...)
+	}
+
+	// index all attribute definitions, for resolving references
+	registry := map[string]semconvAttribute{}
+	for _, group := range groups {
+		for _, attr := range group.Attributes {
+			if attr.Id != "" {
+				registry[semconvAttributeName(group.Prefix, attr.Id)] = attr
+			}
+		}
+	}
+
+	model := &generatedModel{}
+	for _, group := range groups {
+		switch group.Type {
+		case "resource", "entity":
+			entity := getEntityComponent(semconvObjectName(group.Id), namespaceName)
+			entity.AttributeDefinitions.Attributes = map[string]*FmmAttributeTypeDef{}
+			entity.AttributeDefinitions.Required = []string{}
+			for _, attr := range group.Attributes {
+				name, attrDef, required := resolveSemconvAttribute(group, attr, registry)
+				entity.AttributeDefinitions.Attributes[name] = attrDef
+				if required {
+					entity.AttributeDefinitions.Required = append(entity.AttributeDefinitions.Required, name)
+				}
+			}
+			sort.Strings(entity.AttributeDefinitions.Required)
+			model.entities = append(model.entities, entity)
+		case "metric":
+			name := group.MetricName
+			if name == "" {
+				name = strings.TrimPrefix(group.Id, "metric.")
+			}
+			metric := getSemconvMetric(name, group.Instrument, namespaceName)
+			metric.Unit = group.Unit
+			if len(group.Attributes) > 0 {
+				metric.AttributeDefinitions = &FmmAttributeDefinitionsTypeDef{
+					Optimized:  []string{},
+					Attributes: map[string]*FmmAttributeTypeDef{},
+				}
+				for _, attr := range group.Attributes {
+					name, attrDef, _ := resolveSemconvAttribute(group, attr, registry)
+					metric.AttributeDefinitions.Attributes[name] = attrDef
+				}
+			}
+			model.metrics = append(model.metrics, metric)
+		}
+	}
+	if len(model.entities) == 0 && len(model.metrics) == 0 {
+		return nil, fmt.Errorf("no resource, entity or metric groups found")
+	}
+
+	// associate metrics with entities by name prefix
+	for _, metric := range model.metrics {
+		var owner *FmmEntity
+		for _, entity := range model.entities {
+			if strings.HasPrefix(metric.Name, entity.Name+".") && (owner == nil || len(entity.Name) > len(owner.Name)) {
+				owner = entity
+			}
+		}
+		if owner != nil {
+			owner.MetricTypes = append(owner.MetricTypes, fmt.Sprintf("%s:%s", namespaceName, metric.Name))
+		}
+	}
+	return model, nil
+}
+
+// resolveSemconvAttribute returns the full name, FMM definition and requirement of a group's attribute
+func resolveSemconvAttribute(group semconvGroup, attr semconvAttribute, registry map[string]semconvAttribute) (string, *FmmAttributeTypeDef, bool) {
+	name := semconvAttributeName(group.Prefix, attr.Id)
+	definition := attr
+	if attr.Ref != "" {
+		name = attr.Ref
+		if registered, found := registry[attr.Ref]; found {
+			definition = registered
+		} else {
+			log.Warnf("Attribute %q referenced in group %q is not defined in the provided files; assuming string", attr.Ref, group.Id)
+		}
+		// the reference may override the brief and the requirement level
+		if attr.Brief != "" {
+			definition.Brief = attr.Brief
+		}
+		if attr.RequirementLevel != nil {
+			definition.RequirementLevel = attr.RequirementLevel
+		}
+	}
+	required, _ := definition.RequirementLevel.(string)
+	return name, &FmmAttributeTypeDef{
+		Type:        getSemconvAttributeType(definition.Type),
+		Description: strings.TrimSpace(definition.Brief),
+	}, required == "required"
+}
+
+// getSemconvAttributeType maps an attribute type, including enums, to an FMM attribute type
+func getSemconvAttributeType(semconvType any) string {
+	switch typed := semconvType.(type) {
+	case string:
+		if fmmType, found := semconvTypes[typed]; found {
+			return fmmType
+		}
+	case map[string]any:
+		// enum: the type of the members' values
+		members, _ := typed["members"].([]any)
+		for _, member := range members {
+			m, _ := member.(map[string]any)
+			switch m["value"].(type) {
+			case int:
+				return "long"
+			case float64:
+				return "double"
+			}
+		}
+	}
+	return "string"
+}
+
+func getSemconvMetric(name string, instrument string, namespaceName string) *FmmMetric {
+	switch instrument {
+	case "counter":
+		metric := getMetricComponent(name, ContentType_Sum, Type_Double, namespaceName)
+		metric.IsMonotonic = true
+		return metric
+	case "histogram":
+		metric := getMetricComponent(name, ContentType_Distribution, Type_Double, namespaceName)
+		metric.Category = Category_Average
+		metric.AggregationTemporality = "delta"
+		return metric
+	default:
+		// gauges and up-down counters report the current value
+		return getMetricComponent(name, ContentType_Gauge, Type_Double, namespaceName)
+	}
+}
+
+func semconvAttributeName(prefix string, id string) string {
+	if prefix == "" || strings.HasPrefix(id, prefix+".") {
+		return id
+	}
+	return prefix + "." + id
+}
+
+// semconvObjectName derives an FMM object name from a group id
+func semconvObjectName(id string) string {
+	id = strings.TrimPrefix(id, "registry.")
+	id = strings.TrimPrefix(id, "entity.")
+	return strings.ToLower(strings.ReplaceAll(id, "-", "_"))
+}
+
+// otlpMetricsData is the subset of the OTLP JSON metrics format needed to derive types
+type otlpMetricsData struct {
+	ResourceMetrics []struct {
+		Resource struct {
+			Attributes []otlpKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeMetrics []struct {
+			Metrics []otlpMetric `json:"metrics"`
+		} `json:"scopeMetrics"`
+	} `json:"resourceMetrics"`
+}
+
+type otlpKeyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+type otlpDataPoints struct {
+	DataPoints             []otlpDataPoint `json:"dataPoints"`
+	AggregationTemporality any             `json:"aggregationTemporality"` // number or enum name
+	IsMonotonic            bool            `json:"isMonotonic"`
+}
+
+type otlpDataPoint struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+	AsInt      any            `json:"asInt"`
+	AsDouble   any            `json:"asDouble"`
+}
+
+type otlpMetric struct {
+	Name      string          `json:"name"`
+	Unit      string          `json:"unit"`
+	Gauge     *otlpDataPoints `json:"gauge"`
+	Sum       *otlpDataPoints `json:"sum"`
+	Histogram *otlpDataPoints `json:"histogram"`
+}
+
+// otlpValueTypes maps the OTLP attribute value kinds to FMM attribute types
+var otlpValueTypes = map[string]string{
+	"stringValue": "string",
+	"intValue":    "long",
+	"doubleValue": "double",
+	"boolValue":   "boolean",
+}
+
+// generateFromOtlp derives an entity from the resource attributes and metrics from the
+// data points in an OTLP JSON metrics sample (e.g., as written by the collector's file exporter)
+func generateFromOtlp(file string, entityName string, namespaceName string) (*generatedModel, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var sample otlpMetricsData
+	if err := json.Unmarshal(data, &sample); err != nil {
+		return nil, fmt.Errorf("failed to parse OTLP JSON: %w", err)
+	}
+
+	entity := getEntityComponent(entityName, namespaceName)
+	entity.AttributeDefinitions.Attributes = map[string]*FmmAttributeTypeDef{}
+	entity.AttributeDefinitions.Required = []string{}
+	metrics := map[string]*FmmMetric{}
+	for _, rm := range sample.ResourceMetrics {
+		addOtlpAttributes(entity.AttributeDefinitions.FmmAttributeDefinitionsTypeDef, rm.Resource.Attributes)
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				if _, found := metrics[m.Name]; found {
+					continue
+				}
+				var metric *FmmMetric
+				var points *otlpDataPoints
+				switch {
+				case m.Gauge != nil:
+					metric, points = getMetricComponent(m.Name, ContentType_Gauge, Type_Long, namespaceName), m.Gauge
+				case m.Sum != nil:
+					metric, points = getMetricComponent(m.Name, ContentType_Sum, Type_Long, namespaceName), m.Sum
+					metric.IsMonotonic = m.Sum.IsMonotonic
+					if isOtlpCumulative(m.Sum.AggregationTemporality) {
+						metric.AggregationTemporality = "cumulative"
+					}
+				case m.Histogram != nil:
+					metric, points = getMetricComponent(m.Name, ContentType_Distribution, Type_Double, namespaceName), m.Histogram
+					metric.Category = Category_Average
+					metric.AggregationTemporality = "delta"
+				default:
+					log.Warnf("Skipping metric %q: unsupported data type", m.Name)
+					continue
+				}
+				metric.Unit = m.Unit
+				metric.AttributeDefinitions = &FmmAttributeDefinitionsTypeDef{
+					Optimized:  []string{},
+					Attributes: map[string]*FmmAttributeTypeDef{},
+				}
+				for _, point := range points.DataPoints {
+					if point.AsDouble != nil {
+						metric.Type = Type_Double
+					}
+					addOtlpAttributes(metric.AttributeDefinitions, point.Attributes)
+				}
+				metrics[m.Name] = metric
+			}
+		}
+	}
+	if len(sample.ResourceMetrics) == 0 {
+		return nil, fmt.Errorf("no resource metrics found")
+	}
+
+	model := &generatedModel{entities: []*FmmEntity{entity}}
+	for _, name := range sortedKeys(metrics) {
+		model.metrics = append(model.metrics, metrics[name])
+		entity.MetricTypes = append(entity.MetricTypes, fmt.Sprintf("%s:%s", namespaceName, name))
+	}
+	return model, nil
+}
+
+func addOtlpAttributes(attrDefs *FmmAttributeDefinitionsTypeDef, attributes []otlpKeyValue) {
+	for _, kv := range attributes {
+		if _, found := attrDefs.Attributes[kv.Key]; found {
+			continue
+		}
+		attrType := "string"
+		for kind := range kv.Value {
+			if fmmType, found := otlpValueTypes[kind]; found {
+				attrType = fmmType
+			}
+		}
+		attrDefs.Attributes[kv.Key] = &FmmAttributeTypeDef{Type: attrType}
+	}
+}
+
+func isOtlpCumulative(temporality any) bool {
+	switch typed := temporality.(type) {
+	case float64:
+		return typed == 2
+	case string:
+		return typed == "AGGREGATION_TEMPORALITY_CUMULATIVE"
+	}
+	return false
+}
+
+// addGeneratedModel writes the generated types into the solution, skipping types whose files
+// already exist, and adds them to the manifest
+func addGeneratedModel(cmd *cobra.Command, manifest *Manifest, model *generatedModel) {
+	checkCreateSolutionNamespace(cmd, manifest, "objects/model/namespaces")
+
+	add := func(definition any, name string, componentType string, folderName string) {
+		fileName := componentFileName(cmd, manifest, name)
+		if _, err := os.Stat(filepath.Join(folderName, fileName)); err == nil {
+			log.Warnf("Skipping %v %q: file %v already exists", componentType, name, filepath.Join(folderName, fileName))
+			return
+		}
+		addCompDefToManifest(cmd, manifest, componentType, folderName)
+		createComponentFile(definition, folderName, fileName)
+		output.PrintCmdStatus(cmd, fmt.Sprintf("Added file %s to your solution\n", filepath.Join(folderName, fileName)))
+	}
+	for _, entity := range model.entities {
+		add(entity, entity.Name, "fmm:entity", "objects/model/entities")
+	}
+	for _, metric := range model.metrics {
+		add(metric, metric.Name, "fmm:metric", "objects/model/metrics")
+	}
+}
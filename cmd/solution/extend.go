@@ -19,6 +19,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/apex/log"
@@ -29,11 +30,28 @@ import (
 )
 
 var solutionExtendCmd = &cobra.Command{
-	Use:              "extend [flags]",
-	Args:             cobra.NoArgs,
-	Short:            "Extends your solution by adding new components",
-	Long:             `This command allows you to easily add new components to your solution.`,
-	Example:          `  fsoc solution extend --add-knowledge=dataCollectorConfiguration --add-service=ingestor`,
+	Use:   "extend [flags]",
+	Args:  cobra.NoArgs,
+	Short: "Extends your solution by adding new components",
+	Long: `This command allows you to easily add new components to your solution.
+
+Entity and metric types can also be generated from existing telemetry definitions:
+  --from-semconv  converts the resource (or entity) and metric groups of OpenTelemetry semantic conventions
+                  model files (YAML) into entity and metric types, with their attributes, attribute types and
+                  required attributes. Attribute references are resolved across all provided files. Metrics
+                  are associated with the entity whose name is the longest prefix of the metric name.
+  --from-otlp     derives an entity type (named with --otlp-entity) from the resource attributes and metric
+                  types from the metrics in an OTLP JSON sample, e.g., as written by the collector's file exporter.
+Existing type files are not overwritten.
+
+Use --add-knowledge to add a knowledge type, with a JSON schema scaffold, in the types directory, and --add-objects
+to add an objects definition for any type to the manifest, with a sample object in the objects directory. The sample
+object of a knowledge type defined in this solution has the type's required properties (or all properties, if
+none is required); for other types it is left empty. The manifest is updated and kept formatted.`,
+	Example: `  fsoc solution extend --add-knowledge=dataCollectorConfiguration --add-service=ingestor
+  fsoc solution extend --add-objects mysolution:datacollectorconfiguration --object-name prod
+  fsoc solution extend --from-semconv model/registry/host.yaml,model/metrics/system-metrics.yaml
+  fsoc solution extend --from-otlp metrics.json --otlp-entity host`,
 	Run:              extendSolution,
 	Annotations:      map[string]string{config.AnnotationForConfigBypass: ""}, // this command does not require a valid context
 	TraverseChildren: true,
@@ -64,6 +82,19 @@ func getSolutionExtendCmd() *cobra.Command {
 		String("add-ecpDetails", "", "Add all template definition to build the details experience for a given entity within this solution")
 	solutionExtendCmd.Flags().
 		Bool("add-ecpHome", false, "Add a template extension definition to build the ecpHome experience for this solution")
+	solutionExtendCmd.Flags().
+		String("add-objects", "", "Add an objects definition for a type (e.g., mysolution:config) with a sample object to this solution")
+	solutionExtendCmd.Flags().
+		String("objects-dir", "", "Directory for the objects added with --add-objects (defaults to objects/<type-name>)")
+	solutionExtendCmd.Flags().
+		String("object-name", "default", "Name of the sample object file added with --add-objects")
+	solutionExtendCmd.Flags().
+		StringSlice("from-semconv", nil, "Add entity and metric type definitions generated from OpenTelemetry semantic conventions model files")
+	solutionExtendCmd.Flags().
+		String("from-otlp", "", "Add entity and metric type definitions generated from an OTLP JSON metrics sample file")
+	solutionExtendCmd.Flags().
+		String("otlp-entity", "", "Name of the entity type to generate from the OTLP sample's resource attributes")
+	solutionExtendCmd.MarkFlagsRequiredTogether("from-otlp", "otlp-entity")
 
 	// file format override flags (mutually exclusive)
 	solutionExtendCmd.Flags().
@@ -92,6 +123,11 @@ func extendSolution(cmd *cobra.Command, args []string) {
 		addNewKnowledgeComponent(cmd, manifest, getKnowledgeComponent(componentName))
 	}
 
+	if cmd.Flags().Changed("add-objects") {
+		typeName, _ := cmd.Flags().GetString("add-objects")
+		addNewObjectsComponent(cmd, manifest, typeName)
+	}
+
 	if cmd.Flags().Changed("add-service") {
 		componentName, _ := cmd.Flags().GetString("add-service")
 		componentName = strings.ToLower(componentName)
@@ -157,6 +193,17 @@ func extendSolution(cmd *cobra.Command, args []string) {
 		addNewComponent(cmd, manifest, folderName, "ecpHome", "dashui:ecpHome")
 	}
 
+	if cmd.Flags().Changed("from-semconv") {
+		files, _ := cmd.Flags().GetStringSlice("from-semconv")
+		extendFromSemconv(cmd, manifest, files)
+	}
+
+	if cmd.Flags().Changed("from-otlp") {
+		file, _ := cmd.Flags().GetString("from-otlp")
+		entityName, _ := cmd.Flags().GetString("otlp-entity")
+		extendFromOtlp(cmd, manifest, file, strings.ToLower(entityName))
+	}
+
 }
 
 func addNewComponent(cmd *cobra.Command, manifest *Manifest, folderName, componentName, componentType string) {
@@ -256,7 +303,10 @@ func addNewComponent(cmd *cobra.Command, manifest *Manifest, folderName, compone
 		{
 			entityName := strings.ToLower(componentName)
 			entity := findEntity(entityName, manifest)
-			dashuiTemplates := manifest.GetDashuiTemplates()
+			dashuiTemplates, err := manifest.GetDashuiTemplates()
+			if err != nil {
+				log.Fatalf("Failed to read the dashui template definitions: %v", err)
+			}
 
 			ecpList := &newComponent{
 				Filename:   componentFileName(cmd, manifest, "ecpList"),
@@ -318,7 +368,10 @@ func addNewComponent(cmd *cobra.Command, manifest *Manifest, folderName, compone
 			entityName := strings.ToLower(componentName)
 			entity := findEntity(entityName, manifest)
 
-			dashuiTemplates := manifest.GetDashuiTemplates()
+			dashuiTemplates, err := manifest.GetDashuiTemplates()
+			if err != nil {
+				log.Fatalf("Failed to read the dashui template definitions: %v", err)
+			}
 
 			ecpDetails := &newComponent{
 				Filename:   componentFileName(cmd, manifest, "ecpDetails"),
@@ -329,7 +382,7 @@ func addNewComponent(cmd *cobra.Command, manifest *Manifest, folderName, compone
 			newComponents = append(newComponents, ecpDetails)
 
 			ecpDetailsList := &newComponent{
-				Filename:   componentFileName(cmd, manifest, entity.Name+"DetailsList.json"),
+				Filename:   componentFileName(cmd, manifest, entity.Name+"DetailsList"),
 				Type:       "dashui:template",
 				Definition: getDashuiDetailsList(entity, manifest),
 			}
@@ -444,7 +497,9 @@ func addCompDefToManifest(cmd *cobra.Command, manifest *Manifest, componentType
 		}
 	}
 	solutionDep := strings.Split(componentType, ":")[0]
-	manifest.AppendDependency(solutionDep)
+	if solutionDep != manifest.Name {
+		manifest.AppendDependency(solutionDep)
+	}
 
 	extComponentDef := &ComponentDef{
 		Type:       componentType,
@@ -477,15 +532,8 @@ func addNewKnowledgeComponent(cmd *cobra.Command, manifest *Manifest, obj *Knowl
 	}
 
 	// add the file if not already in the list
-	found := false
-	for _, t := range manifest.Types {
-		if t == obj.Name {
-			found = true
-		}
-	}
-	if !found {
+	if !slices.Contains(manifest.Types, filePath) {
 		manifest.Types = append(manifest.Types, filePath)
-
 	}
 
 	// add type to manifest & create type file
@@ -496,6 +544,94 @@ func addNewKnowledgeComponent(cmd *cobra.Command, manifest *Manifest, obj *Knowl
 	output.PrintCmdStatus(cmd, statusMsg)
 }
 
+// addNewObjectsComponent adds an objects definition for a type to the manifest, unless one already exists,
+// and creates a sample object in its objects directory
+func addNewObjectsComponent(cmd *cobra.Command, manifest *Manifest, typeName string) {
+	typeName = strings.TrimSpace(typeName)
+	namespace, name, found := strings.Cut(typeName, ":")
+	if !found || namespace == "" || name == "" {
+		log.Fatalf("Invalid type name %q; expected <solution>:<type>, e.g., %s:%s", typeName, manifest.Name, "config")
+	}
+
+	// use the objects directory of an existing definition for the type, if any
+	folderName, _ := cmd.Flags().GetString("objects-dir")
+	if folderName == "" {
+		folderName = filepath.ToSlash(filepath.Join("objects", name))
+		for _, compDef := range manifest.GetComponentDefs(typeName) {
+			if compDef.ObjectsDir != "" {
+				folderName = compDef.ObjectsDir
+				break
+			}
+		}
+	}
+	folderName = filepath.ToSlash(filepath.Clean(folderName))
+	if filepath.IsAbs(folderName) || strings.HasPrefix(folderName, "..") {
+		log.Fatalf("Objects directory %q must be within the solution directory", folderName)
+	}
+
+	objectName, _ := cmd.Flags().GetString("object-name")
+	fileName := componentFileName(cmd, manifest, objectName)
+	if _, err := os.Stat(filepath.Join(folderName, fileName)); err == nil {
+		log.Fatalf("Object file %s already exists in the solution. Please use a different object name.", filepath.Join(folderName, fileName))
+	}
+
+	addCompDefToManifest(cmd, manifest, typeName, folderName)
+	createComponentFile(getSampleObject(manifest, typeName), folderName, fileName)
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Added file %s to your solution\n", filepath.Join(folderName, fileName)))
+}
+
+// getSampleObject returns a sample object for a type: for a knowledge type defined in the solution,
+// it has the required properties from the type's JSON schema (or all properties, if none is required)
+// with their default values or empty values of their type; for other types the object is empty.
+func getSampleObject(manifest *Manifest, typeName string) map[string]any {
+	object := map[string]any{}
+	namespace, name, _ := strings.Cut(typeName, ":")
+	if namespace != manifest.Name {
+		return object
+	}
+	for _, typeFile := range manifest.Types {
+		content, err := readObjectsFile(typeFile)
+		if err != nil {
+			log.Warnf("Failed to read type file %q: %v", typeFile, err)
+			continue
+		}
+		var typeDef KnowledgeDef
+		if err := remarshal(content, &typeDef); err != nil || typeDef.Name != name {
+			continue
+		}
+		properties, _ := typeDef.JsonSchema["properties"].(map[string]any)
+		required := toStringList(typeDef.JsonSchema["required"])
+		if len(required) == 0 {
+			required = sortedKeys(properties)
+		}
+		for _, propName := range required {
+			property, _ := properties[propName].(map[string]any)
+			object[propName] = getSamplePropertyValue(property)
+		}
+		return object
+	}
+	log.Warnf("Type %q is not defined in the solution's types; adding an empty sample object", typeName)
+	return object
+}
+
+func getSamplePropertyValue(property map[string]any) any {
+	if value, found := property["default"]; found {
+		return value
+	}
+	switch property["type"] {
+	case "integer", "number":
+		return 0
+	case "boolean":
+		return false
+	case "array":
+		return []any{}
+	case "object":
+		return map[string]any{}
+	default:
+		return ""
+	}
+}
+
 // componentFileName returns a file name for a component with a file extension reflecting the format.
 // The manfest and the optional cobra command are provided as means to determine the file format.
 // If the command is provided and a format is specified with a flag, that takes precedence
@@ -194,7 +194,7 @@ func changeManifestFormat(cmd *cobra.Command, manifest *Manifest, format string)
 	}
 
 	// Prevent changing pseudo-isolated solutions to YAML
-	if fileFormat == FileFormatYAML {
+	if fileFormat == FileFormatYAML && manifest.HasPseudoIsolation() {
 		return fmt.Errorf("changing pseudo-isolated solutions to YAML is not supported; pseudo-isolation is supported only for JSON-formatted solutions")
 	}
 
@@ -0,0 +1,340 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/cisco-open/fsoc/cmdkit/jsondoc"
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+)
+
+var solutionFmtCmd = &cobra.Command{
+	Use:   "fmt",
+	Args:  cobra.NoArgs,
+	Short: "Format the solution's manifest and object files canonically",
+	Long: `This command rewrites the solution manifest, the manifest files it includes, and the object and type definition
+files it references in a canonical format, so that reviewing changes to the solution shows only real changes:
+  - fields are sorted alphabetically, except in the manifest, where they follow the manifest's field order
+    (name, solutionVersion, dependencies, etc.);
+  - JSON files are indented with 4 spaces and YAML files use the block style with 4 spaces;
+  - lists whose order is irrelevant are sorted, e.g., the manifest's dependencies and types, the include and
+    exclude patterns of objectsDir entries, and the required and optimized attributes, metric types, event
+    types and associations of FMM entities.
+
+Files keep their format (JSON or YAML), and comments in YAML files are kept. All files are parsed before any is
+written, so a file that fails to parse leaves the solution unchanged.
+
+With --check, no files are changed: the command lists the files that are not formatted and fails if there are
+any, e.g., to enforce the formatting in CI.`,
+	Example: `  fsoc solution fmt
+  fsoc solution fmt --check
+  fsoc solution fmt -d mysolution`,
+	Run:         solutionFmt,
+	Annotations: map[string]string{config.AnnotationForConfigBypass: ""},
+}
+
+// formattedFile is a solution file checked by the fmt command
+type formattedFile struct {
+	Path   string `json:"path" yaml:"path"`
+	Status string `json:"status" yaml:"status"` // formatted, unformatted or unchanged
+	data   []byte
+}
+
+// fileFormatRules are the formatting rules for a kind of solution file
+type fileFormatRules struct {
+	keyOrder  map[string][]string // slash-separated path ("*" for any element) -> field order
+	unordered []string            // slash-separated paths of lists whose order is irrelevant
+}
+
+// manifestFormatRules are the formatting rules of the manifest and of the manifest files it includes
+var manifestFormatRules = fileFormatRules{
+	keyOrder: map[string][]string{
+		"":          jsonFieldNames(reflect.TypeOf(Manifest{})),
+		"objects/*": jsonFieldNames(reflect.TypeOf(ComponentDef{})),
+	},
+	unordered: []string{"dependencies", "types", "objects/*/include", "objects/*/exclude"},
+}
+
+// typeFormatRules are the formatting rules of the knowledge type definition files
+var typeFormatRules = fileFormatRules{
+	unordered: []string{"allowedLayers", "secureProperties"},
+}
+
+// objectFormatRules are the formatting rules of the objects of some types; other objects are
+// formatted with the default rules (sorted fields only)
+var objectFormatRules = map[string]fileFormatRules{
+	"fmm:entity": {
+		unordered: []string{"attributeDefinitions/required", "attributeDefinitions/optimized", "metricTypes", "eventTypes", "associationTypes/*"},
+	},
+}
+
+func getSolutionFmtCmd() *cobra.Command {
+	solutionFmtCmd.Flags().
+		StringP("directory", "d", "", "Path to the solution root directory (defaults to current dir)")
+
+	solutionFmtCmd.Flags().
+		Bool("check", false, "List the files that are not formatted, without changing them, and fail if there are any")
+
+	return solutionFmtCmd
+}
+
+func solutionFmt(cmd *cobra.Command, args []string) {
+	solutionRootDirectory, _ := cmd.Flags().GetString("directory")
+	if solutionRootDirectory == "" {
+		solutionRootDirectory = "."
+	}
+	solutionRootDirectory = absolutizePath(solutionRootDirectory)
+	check, _ := cmd.Flags().GetBool("check")
+
+	manifest, err := getSolutionManifest(solutionRootDirectory)
+	if err != nil {
+		log.Fatalf("Failed to read the solution manifest: %v", err)
+	}
+
+	// collect the files with their formatting rules
+	rules := map[string]fileFormatRules{"manifest." + manifest.ManifestFormat.String(): manifestFormatRules}
+	paths := []string{"manifest." + manifest.ManifestFormat.String()}
+	add := func(path string, fileRules fileFormatRules) {
+		path = filepath.Clean(path)
+		if _, found := rules[path]; !found {
+			rules[path] = fileRules
+			paths = append(paths, path)
+		}
+	}
+	if manifest.included != nil {
+		for _, path := range manifest.included.files {
+			add(path, manifestFormatRules)
+		}
+	}
+	for _, path := range manifest.Types {
+		add(path, typeFormatRules)
+	}
+	objectFiles, errs := loadManifestObjects(solutionRootDirectory, manifest)
+	if len(errs) > 0 {
+		log.Fatalf("Failed to format the solution, no files were changed: %v", errors.Join(errs...))
+	}
+	for _, file := range objectFiles {
+		add(file.path, objectFormatRules[file.objType])
+	}
+
+	// format all files before writing any
+	files := []formattedFile{}
+	nUnformatted := 0
+	for _, path := range paths {
+		data, err := os.ReadFile(filepath.Join(solutionRootDirectory, path))
+		if err != nil {
+			log.Fatalf("Failed to read %q, no files were changed: %v", path, err)
+		}
+		formatted, err := formatSolutionFile(data, path, rules[path])
+		if err != nil {
+			log.Fatalf("Failed to format %q, no files were changed: %v", path, err)
+		}
+		file := formattedFile{Path: path, Status: "unchanged"}
+		if !bytes.Equal(data, formatted) {
+			file.Status = "formatted"
+			if check {
+				file.Status = "unformatted"
+			}
+			file.data = formatted
+			nUnformatted++
+		}
+		files = append(files, file)
+	}
+
+	if !check {
+		for _, file := range files {
+			if file.data == nil {
+				continue
+			}
+			if err := os.WriteFile(filepath.Join(solutionRootDirectory, file.Path), file.data, 0o644); err != nil {
+				log.Fatalf("Failed to write %q: %v", file.Path, err)
+			}
+		}
+	}
+
+	lines := [][]string{}
+	for _, file := range files {
+		if file.data != nil {
+			lines = append(lines, []string{file.Path, file.Status})
+		}
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []formattedFile `json:"items"`
+		Total int             `json:"total"`
+	}{files, len(files)}, &output.Table{Headers: []string{"Path", "Status"}, Lines: lines})
+
+	switch {
+	case check && nUnformatted > 0:
+		log.Fatalf("%d of %d solution file(s) are not formatted; run fsoc solution fmt to format them", nUnformatted, len(files))
+	case check:
+		output.PrintCmdStatus(cmd, fmt.Sprintf("All %d solution file(s) are formatted.\n", len(files)))
+	default:
+		output.PrintCmdStatus(cmd, fmt.Sprintf("Formatted %d of %d solution file(s).\n", nUnformatted, len(files)))
+	}
+}
+
+// formatSolutionFile returns the canonical form of a JSON or YAML solution file
+func formatSolutionFile(data []byte, path string, rules fileFormatRules) ([]byte, error) {
+	if _, err := parseObjectsData(data, path); err != nil {
+		return nil, err
+	}
+	var doc yaml.Node // JSON is parsed as YAML, to get the field order and comments
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, jsondoc.NewYamlParseError(err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("the file is empty")
+	}
+	root := doc.Content[0]
+	if root.Kind == yaml.SequenceNode {
+		for _, item := range root.Content { // a list of objects
+			formatNode(item, "", rules)
+		}
+	} else {
+		formatNode(root, "", rules)
+	}
+
+	var buf bytes.Buffer
+	var err error
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		clearJsonNodeStyles(&doc)
+		err = writeComponent(&doc, &buf, FileFormatYAML)
+	} else {
+		var value any
+		if err = root.Decode(&value); err == nil {
+			err = writeComponent(toOrderedValue(value, root), &buf, FileFormatJSON)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// formatNode sorts the fields of the mappings and the items of the unordered lists in a node,
+// recursively; path is the node's slash-separated path in the file
+func formatNode(node *yaml.Node, path string, rules fileFormatRules) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		order := rules.getKeyOrder(path)
+		pairs := make([][2]*yaml.Node, 0, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			pairs = append(pairs, [2]*yaml.Node{node.Content[i], node.Content[i+1]})
+		}
+		sort.SliceStable(pairs, func(i, j int) bool {
+			return compareKeys(pairs[i][0].Value, pairs[j][0].Value, order) < 0
+		})
+		node.Content = node.Content[:0]
+		for _, pair := range pairs {
+			node.Content = append(node.Content, pair[0], pair[1])
+			formatNode(pair[1], joinNodePath(path, pair[0].Value), rules)
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			formatNode(item, joinNodePath(path, "*"), rules)
+		}
+		if rules.isUnordered(path) && isScalarSequence(node) {
+			sort.SliceStable(node.Content, func(i, j int) bool {
+				return node.Content[i].Value < node.Content[j].Value
+			})
+		}
+	}
+}
+
+// compareKeys orders the keys in the given order, followed by the other keys alphabetically
+func compareKeys(a string, b string, order []string) int {
+	ia, ib := slices.Index(order, a), slices.Index(order, b)
+	switch {
+	case ia >= 0 && ib >= 0:
+		return ia - ib
+	case ia >= 0:
+		return -1
+	case ib >= 0:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func (rules fileFormatRules) getKeyOrder(path string) []string {
+	for pattern, order := range rules.keyOrder {
+		if matchNodePath(pattern, path) {
+			return order
+		}
+	}
+	return nil
+}
+
+func (rules fileFormatRules) isUnordered(path string) bool {
+	return slices.ContainsFunc(rules.unordered, func(pattern string) bool {
+		return matchNodePath(pattern, path)
+	})
+}
+
+// matchNodePath matches a slash-separated node path with a pattern, in which "*" matches any
+// single element
+func matchNodePath(pattern string, path string) bool {
+	patternElements, pathElements := strings.Split(pattern, "/"), strings.Split(path, "/")
+	if len(patternElements) != len(pathElements) {
+		return false
+	}
+	for i, element := range patternElements {
+		if element != "*" && element != pathElements[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func joinNodePath(path string, element string) string {
+	if path == "" {
+		return element
+	}
+	return path + "/" + element
+}
+
+func isScalarSequence(node *yaml.Node) bool {
+	for _, item := range node.Content {
+		if item.Kind != yaml.ScalarNode {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonFieldNames returns the JSON names of a struct type's fields, in order
+func jsonFieldNames(t reflect.Type) []string {
+	names := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
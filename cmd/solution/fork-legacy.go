@@ -15,7 +15,6 @@
 package solution
 
 import (
-	"archive/zip"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -24,9 +23,9 @@ import (
 
 	"github.com/apex/log"
 	"github.com/spf13/afero"
-	"github.com/spf13/afero/zipfs"
 	"github.com/spf13/cobra"
 
+	"github.com/cisco-open/fsoc/cmdkit/archive"
 	"github.com/cisco-open/fsoc/output"
 	"github.com/cisco-open/fsoc/platform/api"
 )
@@ -45,7 +44,7 @@ func legacyFork(cmd *cobra.Command, solutionName string, solutionTag string, for
 	output.PrintCmdStatus(cmd, message)
 
 	// extract files into the newly created solution directory
-	err := extractZip(fileSystemRoot, fileSystem, solutionName)
+	err := extractZip(fileSystem, solutionName)
 	if err != nil {
 		log.Fatalf("Failed to copy files from the zip file to current directory: %v", err)
 	}
@@ -129,20 +128,9 @@ func ReplaceStringInFile(fileSystem afero.Fs, filePath string, searchValue strin
 	return nil
 }
 
-func extractZip(rootFileSystem afero.Fs, fileSystem afero.Fs, solutionName string) error {
-	zipFile, err := rootFileSystem.OpenFile("./"+solutionName+".zip", os.O_RDONLY, os.FileMode(0644))
-	if err != nil {
-		log.Fatalf("Error opening zip file: %v", err)
-	}
-	fileInfo, err := rootFileSystem.Stat("./" + solutionName + ".zip")
-	if err != nil {
-		log.Errorf("Err reading zip: %v", err)
-	}
-	reader, _ := zip.NewReader(zipFile, fileInfo.Size())
-	zipFileSystem := zipfs.New(reader)
-	dirInfo, _ := afero.ReadDir(zipFileSystem, "./")
-	err = copyFolderToLocal(zipFileSystem, fileSystem, dirInfo[0].Name())
-	return err
+// extractZip extracts the solution zip file downloaded into the current directory
+func extractZip(fileSystem afero.Fs, solutionName string) error {
+	return archive.Extract(solutionName+".zip", fileSystem, archive.ExtractOptions{SkipLevels: 1})
 }
 
 func downloadSolutionZip(cmd *cobra.Command, solutionName string, solutionTag string, forkName string) {
@@ -159,76 +147,3 @@ func downloadSolutionZip(cmd *cobra.Command, solutionName string, solutionTag st
 		log.Fatalf("Solution download failed: %v", err)
 	}
 }
-
-func ExtractZipToDirectory(archive string, targetFs afero.Fs) error {
-	archiveFile, err := os.OpenFile(archive, os.O_RDONLY, os.FileMode(0644))
-	if err != nil {
-		return fmt.Errorf("error opening zip file: %w", err)
-	}
-	defer archiveFile.Close()
-
-	archiveFileInfo, err := os.Stat(archive)
-	if err != nil {
-		return fmt.Errorf("error determining zip file size: %w", err)
-	}
-
-	reader, _ := zip.NewReader(archiveFile, archiveFileInfo.Size())
-	zipFileSystem := zipfs.New(reader)
-	dirInfo, _ := afero.ReadDir(zipFileSystem, "./")
-	err = copyFolderToLocal(zipFileSystem, targetFs, dirInfo[0].Name())
-	return err
-}
-
-func copyFolderToLocal(zipFileSystem afero.Fs, localFileSystem afero.Fs, subDirectory string) error {
-	dirInfo, err := afero.ReadDir(zipFileSystem, subDirectory)
-	if err != nil {
-		return err
-	}
-	for i := range dirInfo {
-		zipLoc := subDirectory + "/" + dirInfo[i].Name()
-		localLoc := convertZipLocToLocalLoc(subDirectory + "/" + dirInfo[i].Name())
-		if !dirInfo[i].IsDir() {
-			err = copyFile(zipFileSystem, localFileSystem, zipLoc, localLoc)
-			if err != nil {
-				return err
-			}
-		} else {
-			err = localFileSystem.Mkdir(localLoc, os.ModeDir)
-			if err != nil {
-				return err
-			}
-			println(localLoc)
-			err = localFileSystem.Chmod(localLoc, 0700)
-			if err != nil {
-				return err
-			}
-			err = copyFolderToLocal(zipFileSystem, localFileSystem, zipLoc)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	return nil
-}
-
-func copyFile(zipFileSystem afero.Fs, localFileSystem afero.Fs, zipLoc string, localLoc string) error {
-	data, err := afero.ReadFile(zipFileSystem, zipLoc)
-	if err != nil {
-		return err
-	}
-	_, err = localFileSystem.Create(localLoc)
-	if err != nil {
-		return err
-	}
-	err = afero.WriteFile(localFileSystem, localLoc, data, os.FileMode(os.O_RDWR))
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func convertZipLocToLocalLoc(zipLoc string) string {
-	secondSlashIndex := strings.Index(zipLoc[2:], "/")
-	return zipLoc[secondSlashIndex+3:]
-}
@@ -28,6 +28,7 @@ import (
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 
+	"github.com/cisco-open/fsoc/cmdkit/archive"
 	"github.com/cisco-open/fsoc/config"
 	"github.com/cisco-open/fsoc/output"
 )
@@ -38,9 +39,15 @@ var solutionForkCmd = &cobra.Command{
 	Use:   "fork [<solution-name>|--source-dir=<directory>] <target-name> [flags]",
 	Args:  cobra.MaximumNArgs(2),
 	Short: "Fork a solution into the specified directory",
-	Long:  `This command downloads the specified solution into the current directory and changes its name to <target-name>`,
+	Long: `This command downloads the specified solution into the current directory and changes its name to <target-name>.
+
+The solution name is replaced in the manifest and in the values of all object files, including FMM namespaces and
+type references, dashui template references and knowledge types. A namespace file named after the solution is
+renamed as well. The command reports every substitution made; use --dry-run to preview the substitutions without
+creating the forked solution.`,
 	Example: `  fsoc solution fork spacefleet myfleet
-  fsoc solution fork --source-dir=spacefleet myfleet`,
+  fsoc solution fork --source-dir=spacefleet myfleet
+  fsoc solution fork --source-dir=spacefleet myfleet --dry-run`,
 	Run: solutionForkCommand,
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if len(args) >= 1 {
@@ -64,9 +71,11 @@ func GetSolutionForkCommand() *cobra.Command {
 	solutionForkCmd.MarkFlagsMutuallyExclusive("source-dir", "source-name")
 
 	solutionForkCmd.Flags().BoolP("quiet", "q", false, "suppress output")
+	solutionForkCmd.Flags().Bool("dry-run", false, "show the substitutions that would be made without creating the forked solution")
 
 	solutionForkCmd.Flags().Bool("legacy-replace", false, "use pre-v0.68 fork algorithm (string replacement) (DEPRECATED)")
-	solutionForkCmd.MarkFlagsMutuallyExclusive("legacy-replace", "quiet") // legacy code doesn't support quiet mode
+	solutionForkCmd.MarkFlagsMutuallyExclusive("legacy-replace", "quiet")   // legacy code doesn't support quiet mode
+	solutionForkCmd.MarkFlagsMutuallyExclusive("legacy-replace", "dry-run") // nor dry run
 
 	return solutionForkCmd
 }
@@ -112,24 +121,35 @@ func solutionForkCommand(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	// create afero filesystem for the target directory
+	// create afero filesystem for the target directory (in memory for a dry run)
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
 	currentDirectory, err := filepath.Abs(".")
 	if err != nil {
 		log.Fatalf("Error getting current directory: %v", currentDirectory)
 	}
 	fileSystemRoot := afero.NewBasePathFs(afero.NewOsFs(), currentDirectory)
-	if createSolutionDirectoryOk(fileSystemRoot, forkName) { // TODO: use code from init
-		log.Fatalf(fmt.Sprintf("A non empty directory with the name %s already exists", forkName))
+	var fileSystem afero.Fs
+	if dryRun {
+		fileSystem = afero.NewMemMapFs()
+	} else {
+		if createSolutionDirectoryOk(fileSystemRoot, forkName) { // TODO: use code from init
+			log.Fatalf(fmt.Sprintf("A non empty directory with the name %s already exists", forkName))
+		}
+		fileSystem = afero.NewBasePathFs(afero.NewOsFs(), currentDirectory+"/"+forkName)
 	}
-	fileSystem := afero.NewBasePathFs(afero.NewOsFs(), currentDirectory+"/"+forkName)
 
 	// fork from disk (always using the new algorithm with proper word boundaries in values only)
 	if sourceDir != "" {
-		err := forkFromDisk(sourceDir, fileSystem, forkName, statusPrint)
+		substitutions, err := forkFromDisk(sourceDir, fileSystem, forkName, statusPrint)
 		if err != nil {
 			log.Fatalf("Failed to fork solution from disk: %v", err)
 		}
-		statusPrint("Successfully forked %q into %q.", sourceDir, forkName)
+		printForkReport(cmd, substitutions, quiet)
+		if dryRun {
+			statusPrint("Dry run: %q was not created.", forkName)
+		} else {
+			statusPrint("Successfully forked %q into %q.", sourceDir, forkName)
+		}
 		return
 	}
 
@@ -160,17 +180,48 @@ func solutionForkCommand(cmd *cobra.Command, args []string) {
 	// extract files from the archive into the source directory
 	// Note that archives have a top level directory that should be skipped at extraction)
 	sourceDirFs := afero.NewBasePathFs(afero.NewOsFs(), sourceDir)
-	if err = UnzipToAferoFs(archivePath, sourceDirFs, 1); err != nil {
+	if err = archive.Extract(archivePath, sourceDirFs, archive.ExtractOptions{SkipLevels: 1}); err != nil {
 		log.Fatalf("Failed to extract downloaded solution archive: %v", err)
 	}
 
 	// fork solution from the extracted directory
-	err = forkFromDisk(sourceDir, fileSystem, forkName, statusPrint)
+	substitutions, err := forkFromDisk(sourceDir, fileSystem, forkName, statusPrint)
 	if err != nil {
 		log.Fatalf("Failed to fork solution (consider using --legacy-replace flag as a workaround): %v", err)
 	}
 
-	statusPrint("Successfully forked %s to current directory as %s.", solutionName, forkName)
+	printForkReport(cmd, substitutions, quiet)
+	if dryRun {
+		statusPrint("Dry run: %q was not created.", forkName)
+	} else {
+		statusPrint("Successfully forked %s to current directory as %s.", solutionName, forkName)
+	}
+}
+
+// ForkSubstitution is a single change made when forking a solution
+type ForkSubstitution struct {
+	File string `json:"file" yaml:"file"`
+	Key  string `json:"key" yaml:"key"` // dotted path of the value within the file
+	Old  string `json:"old" yaml:"old"`
+	New  string `json:"new" yaml:"new"`
+}
+
+// printForkReport displays all substitutions made by a fork
+func printForkReport(cmd *cobra.Command, substitutions []ForkSubstitution, quiet bool) {
+	if quiet {
+		return
+	}
+	lines := make([][]string, 0, len(substitutions))
+	for _, s := range substitutions {
+		lines = append(lines, []string{s.File, s.Key, s.Old, s.New})
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []ForkSubstitution `json:"items"`
+		Total int                `json:"total"`
+	}{substitutions, len(substitutions)}, &output.Table{
+		Headers: []string{"File", "Key", "Old", "New"},
+		Lines:   lines,
+	})
 }
 
 func createSolutionDirectoryOk(fileSystem afero.Fs, forkName string) bool {
@@ -198,20 +249,23 @@ func createSolutionDirectoryOk(fileSystem afero.Fs, forkName string) bool {
 // 4. Renames the namespace file if it is the same as the solution name
 // 5. Logs detailed list of changes made to the solution, with key names and old/new values
 
-func forkFromDisk(sourceDir string, fileSystem afero.Fs, solutionName string, statusPrint func(string, ...any)) error {
+func forkFromDisk(sourceDir string, fileSystem afero.Fs, solutionName string, statusPrint func(string, ...any)) ([]ForkSubstitution, error) {
 	// load solution from disk
 	solution, err := NewSolutionDirectoryContentsFromDisk(sourceDir)
 	if err != nil {
-		return fmt.Errorf("error loading solution from disk: %w", err)
+		return nil, fmt.Errorf("error loading solution from disk: %w", err)
 	}
+	manifestFileName := "manifest." + solution.Manifest.ManifestFormat.String()
 
 	// get and replace solution name in the manifest, respecting pseudo-isolation
+	oldManifestName := solution.Manifest.Name
 	oldName, pseudoIsolated := strings.CutSuffix(solution.Manifest.Name, pseudoIsolationSuffix)
 	if pseudoIsolated {
 		solution.Manifest.Name = solutionName + pseudoIsolationSuffix
 	} else {
 		solution.Manifest.Name = solutionName
 	}
+	substitutions := []ForkSubstitution{{File: manifestFileName, Key: "name", Old: oldManifestName, New: solution.Manifest.Name}}
 	solution.Manifest.SolutionVersion = "1.0.0" // reset version
 	statusPrint("Forking %q to %q (version %v)...", oldName, solutionName, solution.Manifest.SolutionVersion)
 
@@ -247,6 +301,7 @@ func forkFromDisk(sourceDir string, fileSystem afero.Fs, solutionName string, st
 		oldPath := filepath.Join(dirName, oldFileName)
 		newPath := filepath.Join(dirName, file.Name)
 		statusPrint("Renamed namespace file %q to %q", oldPath, newPath)
+		substitutions = append(substitutions, ForkSubstitution{File: oldPath, Key: "(file name)", Old: oldPath, New: newPath})
 		log.WithFields(log.Fields{
 			"old_file": oldPath,
 			"new_file": newPath,
@@ -279,13 +334,14 @@ func forkFromDisk(sourceDir string, fileSystem afero.Fs, solutionName string, st
 		filePath := filepath.Join(dirName, file.Name)
 
 		// replace solution name in file buffer
-		newContents, nReplacements, err := forkFileInBuffer(file.Contents, file.Encoding, oldNameRe, solutionName)
+		newContents, fileSubstitutions, err := forkFileInBuffer(file.Contents, file.Encoding, oldNameRe, solutionName)
 		if err != nil {
-			dirName := "."
-			if dir != nil {
-				dirName = dir.Name
-			}
-			return fmt.Errorf("error forking file %q: %w", filepath.Join(dirName, file.Name), err)
+			return fmt.Errorf("error forking file %q: %w", filePath, err)
+		}
+		nReplacements := len(fileSubstitutions)
+		for _, substitution := range fileSubstitutions {
+			substitution.File = filePath
+			substitutions = append(substitutions, substitution)
 		}
 		if nReplacements > 0 {
 			// replace file contents
@@ -308,7 +364,7 @@ func forkFromDisk(sourceDir string, fileSystem afero.Fs, solutionName string, st
 		return nil
 	})
 	if err != nil {
-		return fmt.Errorf("error updating solution files: %w", err)
+		return nil, fmt.Errorf("error updating solution files: %w", err)
 	}
 
 	// change namespace of objects referenced in the manifest
@@ -317,15 +373,19 @@ func forkFromDisk(sourceDir string, fileSystem afero.Fs, solutionName string, st
 		key := fmt.Sprintf("objects[%v].type", i)
 		obj := &solution.Manifest.Objects[i]
 		oldType := obj.Type
-		nReplacements := 0
-		newType := replaceValues(oldType, oldNameRe, solutionName, key, &nReplacements).(string) // should be string, bug otherwise
-		if nReplacements > 0 {
+		typeSubstitutions := []ForkSubstitution{}
+		newType := replaceValues(oldType, oldNameRe, solutionName, key, &typeSubstitutions).(string) // should be string, bug otherwise
+		if len(typeSubstitutions) > 0 {
 			obj.Type = newType
 
 			// update the type in the file/dir that are being referenceds
 			solution.SetComponentDefType(obj, obj.Type)
 		}
-		nManifestReplaces += nReplacements
+		for _, substitution := range typeSubstitutions {
+			substitution.File = manifestFileName
+			substitutions = append(substitutions, substitution)
+		}
+		nManifestReplaces += len(typeSubstitutions)
 	}
 	pluralSuffix := ""
 	if nManifestReplaces != 1 {
@@ -334,11 +394,10 @@ func forkFromDisk(sourceDir string, fileSystem afero.Fs, solutionName string, st
 	statusPrint(`Made %v change%v in "manifest.%s"`, nManifestReplaces, pluralSuffix, solution.Manifest.ManifestFormat)
 
 	// write new solution to disk
-	statusPrint("The fsoc log file contains all changes made")
 	statusPrint("Writing solution %q...", solutionName)
 	err = solution.Write(fileSystem)
 	if err != nil {
-		return fmt.Errorf("error writing solution to disk: %w", err)
+		return nil, fmt.Errorf("error writing solution to disk: %w", err)
 	}
 
 	// mini-lint:
@@ -372,14 +431,14 @@ func forkFromDisk(sourceDir string, fileSystem afero.Fs, solutionName string, st
 
 	//solution.Dump(nil) //@@ debug
 
-	return nil
+	return substitutions, nil
 }
 
 // forkFileInBuffer replaces oldName with newName in the contents values, respecting
 // word boundaries. The old name is specified as a regular expression.
-// The function returns the new buffer with the replacements and the number of
-// replacements made, as well as an error.
-func forkFileInBuffer(buffer bytes.Buffer, encoding SolutionFileEncoding, oldNameRe *regexp.Regexp, newName string) (bytes.Buffer, int, error) {
+// The function returns the new buffer with the replacements and the list of
+// substitutions made (without the file name), as well as an error.
+func forkFileInBuffer(buffer bytes.Buffer, encoding SolutionFileEncoding, oldNameRe *regexp.Regexp, newName string) (bytes.Buffer, []ForkSubstitution, error) {
 	// decode buffer to map[string]interace{}
 	var contents any
 	var err error
@@ -389,19 +448,19 @@ func forkFileInBuffer(buffer bytes.Buffer, encoding SolutionFileEncoding, oldNam
 	case EncodingYAML:
 		err = yaml.Unmarshal(buffer.Bytes(), &contents)
 	default:
-		return bytes.Buffer{}, 0, fmt.Errorf("unsupported encoding: %v", encoding)
+		return bytes.Buffer{}, nil, fmt.Errorf("unsupported encoding: %v", encoding)
 	}
 	if err != nil {
-		return bytes.Buffer{}, 0, fmt.Errorf("error decoding %v file: %w", encoding, err)
+		return bytes.Buffer{}, nil, fmt.Errorf("error decoding %v file: %w", encoding, err)
 	}
 
 	// replace solution name in values, starting from the root base key ("")
-	nReplacements := 0
-	contents = replaceValues(contents, oldNameRe, newName, "", &nReplacements)
+	substitutions := []ForkSubstitution{}
+	contents = replaceValues(contents, oldNameRe, newName, "", &substitutions)
 
 	// return original buffer if no replacements were made
-	if nReplacements == 0 {
-		return buffer, 0, nil
+	if len(substitutions) == 0 {
+		return buffer, substitutions, nil
 	}
 
 	// encode map back to buffer
@@ -413,28 +472,32 @@ func forkFileInBuffer(buffer bytes.Buffer, encoding SolutionFileEncoding, oldNam
 		err = yaml.NewEncoder(&newBuffer).Encode(contents)
 	}
 	if err != nil {
-		return bytes.Buffer{}, nReplacements, fmt.Errorf("error re-encoding %v file with %v modifications: %w", encoding, nReplacements, err)
+		return bytes.Buffer{}, substitutions, fmt.Errorf("error re-encoding %v file with %v modifications: %w", encoding, len(substitutions), err)
 	}
 
-	return newBuffer, nReplacements, nil
+	return newBuffer, substitutions, nil
 }
 
-func replaceValues(contents any, oldNameRe *regexp.Regexp, newName string, base string, nReplacements *int) any {
+// replaceValues replaces oldName with newName in all string values within contents,
+// recording each substitution made (the file name is left for the caller to fill in).
+// Keys are visited in sorted order, so that the substitutions are reported in a stable order.
+func replaceValues(contents any, oldNameRe *regexp.Regexp, newName string, base string, substitutions *[]ForkSubstitution) any {
 	switch val := contents.(type) {
 	case map[string]any:
-		for k, v := range val {
+		for _, k := range sortedKeys(val) {
+			v := val[k]
 			var key string
 			if base == "" {
 				key = k
 			} else {
 				key = base + "." + k
 			}
-			val[k] = replaceValues(v, oldNameRe, newName, key, nReplacements)
+			val[k] = replaceValues(v, oldNameRe, newName, key, substitutions)
 		}
 	case []any:
 		for i, v := range val {
 			key := base + fmt.Sprintf("[%d]", i)
-			val[i] = replaceValues(v, oldNameRe, newName, key, nReplacements)
+			val[i] = replaceValues(v, oldNameRe, newName, key, substitutions)
 		}
 	case string:
 		// replace old name with the new one, counting the number of replacements
@@ -444,7 +507,7 @@ func replaceValues(contents any, oldNameRe *regexp.Regexp, newName string, base
 			return newName
 		})
 		if replacements > 0 {
-			*nReplacements += replacements
+			*substitutions = append(*substitutions, ForkSubstitution{Key: base, Old: val, New: newVal})
 			log.WithFields(log.Fields{
 				"key": base,
 				"old": val,
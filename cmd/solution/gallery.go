@@ -0,0 +1,275 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The gallery contains the solution templates built into fsoc, one per directory
+//
+//go:embed gallery
+var embeddedGallery embed.FS
+
+// SolutionTemplateFileName is the name of the file describing a solution template,
+// in the template's root directory
+const SolutionTemplateFileName = "template.yaml"
+
+// SolutionTemplate describes a solution template: the object and type files it provides
+// and how they are added to the manifest. The template's files, including this description,
+// are Go templates rendered with solutionTemplateData.
+type SolutionTemplate struct {
+	Description string                    `yaml:"description"`
+	Types       []string                  `yaml:"types,omitempty"`
+	Objects     []SolutionTemplateObjects `yaml:"objects"`
+}
+
+// SolutionTemplateObjects is a component definition provided by a template. Definitions with
+// a kind (e.g., entity or dashboard) can be selected or left out when creating a solution;
+// definitions without a kind are always included.
+type SolutionTemplateObjects struct {
+	Kind         string   `yaml:"kind,omitempty"`
+	Requires     []string `yaml:"requires,omitempty"` // kinds that must be included with this one
+	ComponentDef `yaml:",inline"`
+}
+
+// solutionTemplateData is the data available to the templates' files
+type solutionTemplateData struct {
+	Name         string          // solution name
+	Namespace    string          // namespace of the model objects (entity, metric and event types)
+	SolutionType string          // component, module or application
+	Kinds        map[string]bool // object kinds being scaffolded
+}
+
+// galleryTemplate is an entry of the built-in template gallery
+type galleryTemplate struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// listGalleryTemplates returns the templates built into fsoc
+func listGalleryTemplates() ([]galleryTemplate, error) {
+	entries, err := embeddedGallery.ReadDir("gallery")
+	if err != nil {
+		return nil, err
+	}
+	templates := []galleryTemplate{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		fsys, _ := fs.Sub(embeddedGallery, path.Join("gallery", entry.Name()))
+		tmpl, err := loadSolutionTemplate(fsys, solutionTemplateData{Name: "solution"})
+		if err != nil {
+			return nil, fmt.Errorf("(bug) template %q: %w", entry.Name(), err)
+		}
+		templates = append(templates, galleryTemplate{Name: entry.Name(), Description: tmpl.Description})
+	}
+	return templates, nil
+}
+
+// openSolutionTemplate returns the file system of a template given by name (built-in gallery),
+// git repository URL (optionally followed by #subdir) or local directory. The returned cleanup
+// function must be called when done with the template.
+func openSolutionTemplate(source string) (fs.FS, func(), error) {
+	noop := func() {}
+
+	// local directory
+	if info, err := os.Stat(source); err == nil && info.IsDir() {
+		return os.DirFS(source), noop, nil
+	}
+
+	// git repository
+	if isGitTemplateSource(source) {
+		repoUrl, subdir, _ := strings.Cut(source, "#")
+		cloneDir, err := os.MkdirTemp("", "fsoc-template")
+		if err != nil {
+			return nil, noop, fmt.Errorf("failed to create a temporary directory: %w", err)
+		}
+		cleanup := func() { os.RemoveAll(cloneDir) }
+		if _, err := runGit(cloneDir, "clone", "--quiet", "--depth", "1", repoUrl, "."); err != nil {
+			cleanup()
+			return nil, noop, err
+		}
+		templateDir := filepath.Join(cloneDir, filepath.FromSlash(subdir))
+		if _, err := os.Stat(filepath.Join(templateDir, SolutionTemplateFileName)); err != nil {
+			cleanup()
+			return nil, noop, fmt.Errorf("no %v found in %q", SolutionTemplateFileName, source)
+		}
+		return os.DirFS(templateDir), cleanup, nil
+	}
+
+	// built-in gallery
+	if _, err := fs.Stat(embeddedGallery, path.Join("gallery", source, SolutionTemplateFileName)); err != nil {
+		templates, _ := listGalleryTemplates()
+		names := []string{}
+		for _, t := range templates {
+			names = append(names, t.Name)
+		}
+		return nil, noop, fmt.Errorf("unknown template %q (expected one of %q, a git repository URL or a directory)", source, names)
+	}
+	fsys, err := fs.Sub(embeddedGallery, path.Join("gallery", source))
+	return fsys, noop, err
+}
+
+func isGitTemplateSource(source string) bool {
+	repoUrl, _, _ := strings.Cut(source, "#")
+	return strings.Contains(repoUrl, "://") || strings.HasPrefix(repoUrl, "git@") || strings.HasSuffix(repoUrl, ".git")
+}
+
+// loadSolutionTemplate reads the template description, rendered with the template data
+func loadSolutionTemplate(fsys fs.FS, data solutionTemplateData) (*SolutionTemplate, error) {
+	content, err := fs.ReadFile(fsys, SolutionTemplateFileName)
+	if err != nil {
+		return nil, err
+	}
+	content, err = renderTemplateFile(SolutionTemplateFileName, content, data)
+	if err != nil {
+		return nil, err
+	}
+	var tmpl SolutionTemplate
+	if err := yaml.Unmarshal(content, &tmpl); err != nil {
+		return nil, fmt.Errorf("failed to parse %v: %w", SolutionTemplateFileName, err)
+	}
+	return &tmpl, nil
+}
+
+// getTemplateKinds returns the sorted object kinds that can be selected in the template
+func (tmpl *SolutionTemplate) getTemplateKinds() []string {
+	kinds := []string{}
+	for _, obj := range tmpl.Objects {
+		if obj.Kind != "" && !slices.Contains(kinds, obj.Kind) {
+			kinds = append(kinds, obj.Kind)
+		}
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// selectTemplateKinds resolves the requested object kinds (all if none are requested),
+// adding the kinds they require
+func (tmpl *SolutionTemplate) selectTemplateKinds(requested []string) (map[string]bool, error) {
+	available := tmpl.getTemplateKinds()
+	if len(requested) == 0 {
+		requested = available
+	}
+	selected := map[string]bool{}
+	var add func(kind string) error
+	add = func(kind string) error {
+		if !slices.Contains(available, kind) {
+			return fmt.Errorf("unknown object kind %q (expected one of %q)", kind, available)
+		}
+		if selected[kind] {
+			return nil
+		}
+		selected[kind] = true
+		for _, obj := range tmpl.Objects {
+			if obj.Kind != kind {
+				continue
+			}
+			for _, required := range obj.Requires {
+				if err := add(required); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for _, kind := range requested {
+		if err := add(strings.TrimSpace(kind)); err != nil {
+			return nil, err
+		}
+	}
+	return selected, nil
+}
+
+// applySolutionTemplate renders the template's files into the solution directory and adds the
+// selected component definitions, types and the resulting dependencies to the manifest.
+// Files belonging to component definitions that were not selected are skipped.
+func applySolutionTemplate(fsys fs.FS, tmpl *SolutionTemplate, solutionDir string, manifest *Manifest, data solutionTemplateData) error {
+	excluded := []string{}
+	for _, obj := range tmpl.Objects {
+		if obj.Kind != "" && !data.Kinds[obj.Kind] {
+			excluded = append(excluded, obj.ObjectsDir, obj.ObjectsFile)
+			continue
+		}
+		manifest.Objects = append(manifest.Objects, obj.ComponentDef)
+		if dep, _, found := strings.Cut(obj.Type, ":"); found && dep != manifest.Name {
+			manifest.AppendDependency(dep)
+		}
+	}
+	manifest.Types = append(manifest.Types, tmpl.Types...)
+
+	isExcluded := func(filePath string) bool {
+		for _, prefix := range excluded {
+			if prefix != "" && (filePath == path.Clean(prefix) || strings.HasPrefix(filePath, path.Clean(prefix)+"/")) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return fs.WalkDir(fsys, ".", func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if filePath == "." {
+			return nil
+		}
+		if filePath == SolutionTemplateFileName || d.Name() == ".git" || isExcluded(filePath) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		targetPath := filepath.Join(solutionDir, filepath.FromSlash(filePath))
+		if d.IsDir() {
+			return os.MkdirAll(targetPath, os.ModePerm)
+		}
+		content, err := fs.ReadFile(fsys, filePath)
+		if err != nil {
+			return err
+		}
+		content, err = renderTemplateFile(filePath, content, data)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(targetPath, content, 0o644)
+	})
+}
+
+func renderTemplateFile(name string, content []byte, data solutionTemplateData) ([]byte, error) {
+	t, err := template.New(name).Option("missingkey=zero").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template file %q: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render template file %q: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+)
+
+var solutionGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Args:  cobra.ExactArgs(0),
+	Short: "Generate solution objects from the solution's definitions",
+	Long: `This command generates solution objects from the definitions already in the solution in the
+current directory, adding them to the solution manifest.`,
+	Example:          `  fsoc solution generate dashboard --entity host`,
+	Annotations:      map[string]string{config.AnnotationForConfigBypass: ""},
+	TraverseChildren: true,
+}
+
+var solutionGenerateDashboardCmd = &cobra.Command{
+	Use:   "dashboard",
+	Args:  cobra.ExactArgs(0),
+	Short: "Generate the default dashboard templates for an entity type",
+	Long: `This command generates the default dashui templates for an entity type defined in the solution:
+  - a list page, with a grid table of the entity's attributes and a relationship map
+  - a details page, with a chart for each metric type associated with the entity (its metricTypes)
+  - an inspector widget showing the entity's attributes, used by both pages
+The templates are written into objects/dashui/templates/<entity> and added to the manifest. Templates that
+already exist in that directory are regenerated, so the command can be re-run after changing the entity.
+
+Use --home to also add the entity to the solution's ecpHome page.`,
+	Example: `  fsoc solution generate dashboard --entity host
+  fsoc solution generate dashboard --entity mysolution:host --home --yaml`,
+	Run:         generateDashboard,
+	Annotations: map[string]string{config.AnnotationForConfigBypass: ""},
+}
+
+func getSolutionGenerateCmd() *cobra.Command {
+	solutionGenerateDashboardCmd.Flags().
+		String("entity", "", "Name of the entity type to generate the dashboard for")
+	_ = solutionGenerateDashboardCmd.MarkFlagRequired("entity")
+
+	solutionGenerateDashboardCmd.Flags().
+		Bool("home", false, "Add a template extension for the solution's ecpHome page")
+
+	// file format override flags (mutually exclusive)
+	solutionGenerateDashboardCmd.Flags().
+		Bool("json", false, "Use JSON format for the template files, even if the manifest is in YAML.")
+	solutionGenerateDashboardCmd.Flags().
+		Bool("yaml", false, "Use YAML format for the template files, even if the manifest is in JSON.")
+	solutionGenerateDashboardCmd.MarkFlagsMutuallyExclusive("json", "yaml")
+
+	solutionGenerateCmd.AddCommand(solutionGenerateDashboardCmd)
+
+	return solutionGenerateCmd
+}
+
+func generateDashboard(cmd *cobra.Command, args []string) {
+	manifest, err := GetManifest(".")
+	if err != nil {
+		log.Fatalf("Failed to read manifest file: %v", err)
+	}
+
+	// accept the entity name with or without the solution's namespace
+	entityName, _ := cmd.Flags().GetString("entity")
+	entityName = strings.ToLower(entityName)
+	if namespace, name, found := strings.Cut(entityName, ":"); found {
+		if namespace != manifest.GetNamespaceName() {
+			log.Fatalf("Entity type %q is not in the solution's namespace %q", entityName, manifest.GetNamespaceName())
+		}
+		entityName = name
+	}
+	entity := findEntity(entityName, manifest)
+	if entity.AttributeDefinitions == nil || len(entity.AttributeDefinitions.Attributes) == 0 {
+		log.Fatalf("Entity type %q has no attributes to display", entity.GetTypeName())
+	}
+
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Generating dashboard templates for %s\n", entity.GetTypeName()))
+	folderName := fmt.Sprintf("objects/dashui/templates/%s", entity.Name)
+	addNewComponent(cmd, manifest, folderName, entity.Name, "dashui:ecpList")
+	addNewComponent(cmd, manifest, folderName, entity.Name, "dashui:ecpDetails")
+	if home, _ := cmd.Flags().GetBool("home"); home {
+		addNewComponent(cmd, manifest, "objects/dashui/templatePropsExtensions", "ecpHome", "dashui:ecpHome")
+	}
+}
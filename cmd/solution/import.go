@@ -0,0 +1,218 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+var solutionImportCmd = &cobra.Command{
+	Use:   "import <solution-name> --type <type> [--type <type>...]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Create a solution from knowledge objects in the tenant",
+	Long: `This command creates a new solution in the current directory from existing knowledge objects in the
+tenant, e.g., dashboards or configuration objects created manually, so that they can be brought under source
+control and deployed with the solution.
+
+The objects of each type specified with --type are fetched from the tenant layer (objects provided by solutions
+are not imported) and saved, one file per object, in an objects directory named after the type, e.g.,
+objects/dashui/template for dashui:template objects. The solution manifest lists the objects directories and
+declares a dependency on the solutions that define the imported types.
+
+Use --filter to import only the objects matching a filter on their data, e.g., 'data.name eq "mydashboard"'.
+Review the imported objects before pushing the solution: objects that refer to other tenant objects by ID
+may need to be updated, and the imported objects should be deleted from the tenant once the solution is
+installed.`,
+	Example: `  fsoc solution import mydashboards --type dashui:template
+  fsoc solution import myconfig --type mysolution:config --yaml
+  fsoc solution import mydashboards --type dashui:template --filter 'data.name eq "Fleet Overview"'`,
+	Run:              solutionImport,
+	TraverseChildren: true,
+}
+
+// importedObject is a knowledge object fetched from the tenant
+type importedObject struct {
+	ID        string         `json:"id"`
+	LayerType string         `json:"layerType"`
+	Data      map[string]any `json:"data"`
+}
+
+// ImportedFile is an object imported into the solution
+type ImportedFile struct {
+	Type string `json:"type" yaml:"type"`
+	ID   string `json:"id" yaml:"id"`
+	File string `json:"file" yaml:"file"`
+}
+
+// importFileNameRegexp matches the characters not used in imported object file names
+var importFileNameRegexp = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+func getSolutionImportCmd() *cobra.Command {
+	solutionImportCmd.Flags().
+		StringSlice("type", nil, "Fully qualified name of the type of objects to import, e.g., dashui:template; can be repeated")
+	_ = solutionImportCmd.MarkFlagRequired("type")
+
+	solutionImportCmd.Flags().
+		String("filter", "", "Import only the objects matching the filter, in SCIM filter format")
+
+	solutionImportCmd.Flags().
+		Bool("yaml", false, "Use YAML format instead of JSON for the solution manifest and objects")
+
+	return solutionImportCmd
+}
+
+func solutionImport(cmd *cobra.Command, args []string) {
+	solutionName := args[0]
+	if !IsValidSolutionName(solutionName) {
+		log.Fatalf("Invalid solution name %q: must start with a lowercase letter and contain only lowercase letters and digits", solutionName)
+	}
+	typeNames, _ := cmd.Flags().GetStringSlice("type")
+	filter, _ := cmd.Flags().GetString("filter")
+	useYaml, _ := cmd.Flags().GetBool("yaml")
+	for _, typeName := range typeNames {
+		if namespace, name, found := strings.Cut(typeName, ":"); !found || namespace == "" || name == "" {
+			log.Fatalf("Invalid type name %q; expected <solution>:<type>, e.g., dashui:template", typeName)
+		}
+	}
+	if _, err := os.Stat(solutionName); err == nil {
+		log.Fatalf("Directory %q already exists", solutionName)
+	}
+
+	// fetch the objects before creating anything
+	objectsByType := map[string][]importedObject{}
+	nObjects := 0
+	for _, typeName := range typeNames {
+		objects, err := fetchTenantObjects(typeName, filter)
+		if err != nil {
+			log.Fatalf("Failed to fetch objects of type %v: %v", typeName, err)
+		}
+		if len(objects) == 0 {
+			log.Warnf("No objects of type %v found in the tenant layer", typeName)
+		}
+		objectsByType[typeName] = objects
+		nObjects += len(objects)
+	}
+	if nObjects == 0 {
+		log.Fatalf("No objects to import")
+	}
+
+	// create the solution
+	manifest := createInitialSolutionManifest(solutionName)
+	if useYaml {
+		manifest.ManifestFormat = FileFormatYAML
+	}
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Preparing the solution directory structure for %q... \n", solutionName))
+	if err := os.Mkdir(solutionName, os.ModePerm); err != nil {
+		log.Fatalf("Failed to create a new directory %q: %v", solutionName, err)
+	}
+	imported := []ImportedFile{}
+	for _, typeName := range typeNames {
+		objects := objectsByType[typeName]
+		if len(objects) == 0 {
+			continue
+		}
+		namespace, name, _ := strings.Cut(typeName, ":")
+		folderName := path.Join("objects", namespace, name)
+		if err := os.MkdirAll(filepath.Join(solutionName, folderName), os.ModePerm); err != nil {
+			log.Fatalf("Failed to create objects directory %q: %v", folderName, err)
+		}
+		usedNames := map[string]bool{}
+		for _, object := range objects {
+			fileName := getImportFileName(object.ID, usedNames) + "." + manifest.ManifestFormat.String()
+			if err := writeImportedObject(object.Data, filepath.Join(solutionName, folderName, fileName), manifest.ManifestFormat); err != nil {
+				log.Fatalf("Failed to save object %q: %v", object.ID, err)
+			}
+			imported = append(imported, ImportedFile{Type: typeName, ID: object.ID, File: path.Join(folderName, fileName)})
+		}
+		manifest.Objects = append(manifest.Objects, ComponentDef{Type: typeName, ObjectsDir: folderName})
+		if namespace != solutionName && !slices.Contains(manifest.Dependencies, namespace) {
+			manifest.Dependencies = append(manifest.Dependencies, namespace)
+		}
+	}
+	createSolutionManifestFile(solutionName, manifest)
+
+	lines := [][]string{}
+	for _, file := range imported {
+		lines = append(lines, []string{file.Type, file.ID, file.File})
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []ImportedFile `json:"items"`
+		Total int            `json:"total"`
+	}{imported, len(imported)}, &output.Table{Headers: []string{"Type", "ID", "File"}, Lines: lines})
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Solution %q created with %d imported objects.\n", solutionName, len(imported)))
+}
+
+// fetchTenantObjects returns the objects of a type that exist in the tenant layer, optionally filtered
+func fetchTenantObjects(typeName string, filter string) ([]importedObject, error) {
+	query := ""
+	if filter != "" {
+		query = "?filter=" + url.QueryEscape(filter)
+	}
+	headers := map[string]string{
+		"layer-type": "TENANT",
+		"layer-id":   config.GetCurrentContext().Tenant,
+	}
+	var res api.CollectionResult[importedObject]
+	if err := api.JSONGetCollection("knowledge-store/v1/objects/"+url.PathEscape(typeName)+query, &res, &api.Options{Headers: headers}); err != nil {
+		return nil, err
+	}
+
+	// objects inherited from solutions are visible in the tenant layer as well
+	objects := []importedObject{}
+	for _, object := range res.Items {
+		if object.LayerType == "TENANT" {
+			objects = append(objects, object)
+		}
+	}
+	return objects, nil
+}
+
+// getImportFileName returns a file name (without extension) for an imported object, based on
+// its ID, that is not in usedNames; the name is added to usedNames
+func getImportFileName(objectId string, usedNames map[string]bool) string {
+	base := strings.Trim(importFileNameRegexp.ReplaceAllString(objectId, "-"), "-.")
+	if base == "" {
+		base = "object"
+	}
+	name := base
+	for i := 2; usedNames[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%v-%d", base, i)
+	}
+	usedNames[strings.ToLower(name)] = true
+	return name
+}
+
+func writeImportedObject(data map[string]any, filePath string, format FileFormat) error {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return writeComponent(data, f, format)
+}
@@ -15,10 +15,12 @@
 package solution
 
 import (
+	"bufio"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -36,7 +38,7 @@ import (
 
 var solutionInitCmd = &cobra.Command{
 	Use:   "init <solution-name>",
-	Args:  cobra.ExactArgs(1),
+	Args:  cobra.RangeArgs(0, 1),
 	Short: "Create a new solution",
 	Long: `This command creates a skeleton of a solution in the current directory.
 
@@ -44,9 +46,27 @@ Solution names must start with a lowercase letter and contain only lowercase let
 
 It creates a subdirectory named <solution-name> in the current directory and
 a solution manifest. Once the solution is created, the "solution extend" command
-can be used to add types and objects to it.`,
+can be used to add types and objects to it.
+
+Use --template to start from a template that provides example objects, ready to push. Templates
+come from the gallery built into fsoc (see --list-templates), from a git repository URL (add
+#subdir to use a subdirectory of the repository) or from a local directory. A template is a
+directory with solution files and a template.yaml file that describes the objects they contain;
+the files are Go templates that can refer to the solution name as {{.Name}} and to the namespace
+of its model objects as {{.Namespace}}. Use --namespace to set the namespace (defaults to the
+solution name) and --with to select which kinds of objects of the template to create (e.g.,
+entity, metric, event, dashboard).
+
+Use --interactive to be prompted for the solution type, the template, the namespace, the object
+kinds and the file format.`,
 	Example: `  fsoc solution init mycomponent
-  fsoc solution init mymodule --solution-type=module --yaml`,
+  fsoc solution init mymodule --solution-type=module --yaml
+  fsoc solution init myapp --template observability
+  fsoc solution init myapp --template observability --with entity,dashboard
+  fsoc solution init myapp --template observability --namespace acme
+  fsoc solution init myapp --template https://github.com/myorg/solution-templates.git#basic
+  fsoc solution init myapp --interactive
+  fsoc solution init --list-templates`,
 	Run:              createNewSolution,
 	Annotations:      map[string]string{config.AnnotationForConfigBypass: ""}, // this command does not require a valid context
 	TraverseChildren: true,
@@ -57,6 +77,16 @@ func getInitSolutionCmd() *cobra.Command {
 		String("solution-type", "component", "The type of the solution you are creating (should be one of component, module, or application).")
 	solutionInitCmd.Flags().
 		Bool("yaml", false, "Use YAML format instead of JSON for the solution manifest and objects.")
+	solutionInitCmd.Flags().
+		String("template", "", "Create the solution from a template: gallery template name, git repository URL or local directory")
+	solutionInitCmd.Flags().
+		StringSlice("with", nil, "Kinds of objects of the template to create (defaults to all)")
+	solutionInitCmd.Flags().
+		String("namespace", "", "Namespace of the template's model objects (defaults to the solution name)")
+	solutionInitCmd.Flags().
+		BoolP("interactive", "i", false, "Prompt for the solution's settings")
+	solutionInitCmd.Flags().
+		Bool("list-templates", false, "List the templates in the built-in gallery")
 
 	return solutionInitCmd
 }
@@ -78,8 +108,19 @@ func IsValidSolutionName(name string) bool {
 }
 
 func createNewSolution(cmd *cobra.Command, args []string) {
+	if list, _ := cmd.Flags().GetBool("list-templates"); list {
+		listSolutionTemplates(cmd)
+		return
+	}
+	if len(args) != 1 {
+		log.Fatalf("Missing solution name")
+	}
 	solutionName := strings.ToLower(args[0])
 	solutionType, _ := cmd.Flags().GetString("solution-type") // checked when creating manifest
+	useYaml, _ := cmd.Flags().GetBool("yaml")
+	templateSource, _ := cmd.Flags().GetString("template")
+	kinds, _ := cmd.Flags().GetStringSlice("with")
+	namespace, _ := cmd.Flags().GetString("namespace")
 
 	// check solution name for validity / safety for creating a directory (incl. empty name)
 	match, err := regexp.Match(`^[a-z][a-z0-9]*$`, []byte(solutionName))
@@ -89,21 +130,180 @@ func createNewSolution(cmd *cobra.Command, args []string) {
 	if !match {
 		log.Fatalf("Invalid solution name %q: must start with a lowercase letter and contain only lowercase letters and digits", solutionName)
 	}
+	if namespace == "" {
+		namespace = solutionName
+	}
+
+	// prompt for the settings, using the flags as defaults
+	prompter := newInitPrompter(cmd)
+	if prompter != nil {
+		solutionType, err = prompter.choose("Solution type", knownSolutionTypes, solutionType)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		templates, err := listGalleryTemplates()
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		choices := []string{"none"}
+		for _, t := range templates {
+			choices = append(choices, t.Name)
+			fmt.Fprintf(cmd.ErrOrStderr(), "  %-16v %v\n", t.Name, t.Description)
+		}
+		if templateSource == "" {
+			templateSource = "none"
+		}
+		templateSource, err = prompter.ask("Template (gallery name, git URL or directory)", choices, templateSource)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		if templateSource == "none" {
+			templateSource = ""
+		}
+		if templateSource != "" {
+			namespace, err = prompter.ask("Namespace of the model objects", nil, namespace)
+			if err != nil {
+				log.Fatal(err.Error())
+			}
+		}
+	}
+	if !IsValidSolutionName(namespace) {
+		log.Fatalf("Invalid namespace %q: must start with a lowercase letter and contain only lowercase letters and digits", namespace)
+	}
+
+	// load the template, if any. A template cloned from git is in a temporary directory, which is
+	// removed explicitly on errors, as log.Fatalf exits without running deferred functions, along
+	// with the partially created solution directory.
+	var tmpl *SolutionTemplate
+	var templateFs fs.FS
+	cleanup := func() {}
+	fatalf := func(format string, args ...any) {
+		cleanup()
+		log.Fatalf(format, args...)
+	}
+	data := solutionTemplateData{Name: solutionName, Namespace: namespace, SolutionType: solutionType}
+	if templateSource != "" {
+		templateFs, cleanup, err = openSolutionTemplate(templateSource)
+		if err != nil {
+			fatalf("Failed to open template %q: %v", templateSource, err)
+		}
+		defer cleanup()
+		tmpl, err = loadSolutionTemplate(templateFs, data)
+		if err != nil {
+			fatalf("Failed to load template %q: %v", templateSource, err)
+		}
+		if available := tmpl.getTemplateKinds(); prompter != nil && len(available) > 0 {
+			if len(kinds) == 0 {
+				kinds = available
+			}
+			answer, err := prompter.ask("Object kinds to create (comma-separated)", available, strings.Join(kinds, ","))
+			if err != nil {
+				fatalf("%v", err)
+			}
+			kinds = strings.Split(answer, ",")
+		}
+		data.Kinds, err = tmpl.selectTemplateKinds(kinds)
+		if err != nil {
+			fatalf("Invalid --with value: %v", err)
+		}
+	} else if len(kinds) > 0 {
+		log.Fatalf("The --with flag requires a template")
+	}
+	if prompter != nil {
+		format := FileFormatJSON.String()
+		if useYaml {
+			format = FileFormatYAML.String()
+		}
+		format, err = prompter.choose("Manifest format", []string{FileFormatJSON.String(), FileFormatYAML.String()}, format)
+		if err != nil {
+			fatalf("%v", err)
+		}
+		useYaml = format == FileFormatYAML.String()
+	}
 
 	output.PrintCmdStatus(cmd, fmt.Sprintf("Preparing the solution directory structure for %q... \n", solutionName))
 	if err := os.Mkdir(solutionName, os.ModePerm); err != nil {
-		log.Fatalf("Failed to create a new directory %q: %v", solutionName, err)
+		fatalf("Failed to create a new directory %q: %v", solutionName, err)
+	}
+	removeTemplate := cleanup // the deferred cleanup removes only the template
+	cleanup = func() {
+		os.RemoveAll(solutionName)
+		removeTemplate()
 	}
 
 	manifest := createInitialSolutionManifest(solutionName, WithSolutionType(solutionType))
-	if useYaml, _ := cmd.Flags().GetBool("yaml"); useYaml {
+	if useYaml {
 		manifest.ManifestFormat = FileFormatYAML
 	}
-	createSolutionManifestFile(solutionName, manifest)
+	if tmpl != nil {
+		if err := applySolutionTemplate(templateFs, tmpl, solutionName, manifest, data); err != nil {
+			fatalf("Failed to create the solution from template %q: %v", templateSource, err)
+		}
+	}
+	if err := saveSolutionManifest(solutionName, manifest); err != nil {
+		fatalf("Failed to create the solution manifest: %v", err)
+	}
 
 	output.PrintCmdStatus(cmd, fmt.Sprintf("Solution %q created successfully.\n", solutionName))
 }
 
+func listSolutionTemplates(cmd *cobra.Command) {
+	templates, err := listGalleryTemplates()
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	lines := [][]string{}
+	for _, t := range templates {
+		lines = append(lines, []string{t.Name, t.Description})
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []galleryTemplate `json:"items"`
+		Total int               `json:"total"`
+	}{templates, len(templates)}, &output.Table{Headers: []string{"Name", "Description"}, Lines: lines})
+}
+
+// initPrompter asks the user for the solution's settings, one line per answer
+type initPrompter struct {
+	cmd     *cobra.Command
+	scanner *bufio.Scanner
+}
+
+// newInitPrompter returns a prompter if the command is interactive, nil otherwise
+func newInitPrompter(cmd *cobra.Command) *initPrompter {
+	if interactive, _ := cmd.Flags().GetBool("interactive"); !interactive {
+		return nil
+	}
+	return &initPrompter{cmd: cmd, scanner: bufio.NewScanner(cmd.InOrStdin())}
+}
+
+// ask prompts for a free-form answer, showing the suggested values, if any; an empty answer selects the default
+func (p *initPrompter) ask(question string, suggestions []string, defaultValue string) (string, error) {
+	if len(suggestions) > 0 {
+		fmt.Fprintf(p.cmd.ErrOrStderr(), "%v %v [%v]: ", question, suggestions, defaultValue)
+	} else {
+		fmt.Fprintf(p.cmd.ErrOrStderr(), "%v [%v]: ", question, defaultValue)
+	}
+	if !p.scanner.Scan() {
+		return "", fmt.Errorf("no answer provided for %q", question)
+	}
+	answer := strings.TrimSpace(p.scanner.Text())
+	if answer == "" {
+		return defaultValue, nil
+	}
+	return answer, nil
+}
+
+// choose prompts until one of the choices is selected
+func (p *initPrompter) choose(question string, choices []string, defaultValue string) (string, error) {
+	for {
+		answer, err := p.ask(question, choices, defaultValue)
+		if err != nil || slices.Contains(choices, answer) {
+			return answer, err
+		}
+		fmt.Fprintf(p.cmd.ErrOrStderr(), "Please enter one of %v\n", choices)
+	}
+}
+
 // --- Solution Manifest Helpers
 
 type solutionManifestOptions struct {
@@ -173,6 +373,7 @@ func createInitialSolutionManifest(solutionName string, options ...SolutionManif
 
 func writeSolutionManifest(manifest *Manifest, w io.Writer) error {
 	checkStructTags(reflect.TypeOf(manifest)) // ensure json/yaml struct tags are correct
+	manifest = manifest.withoutIncluded()     // the included files' content stays in them
 
 	// write the manifest into the file, in manifest's selected format
 	var err error
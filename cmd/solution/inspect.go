@@ -0,0 +1,272 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/cmdkit/archive"
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+)
+
+var solutionInspectCmd = &cobra.Command{
+	Use:   "inspect <archive> [--diff <other-archive>]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Inspect a solution archive",
+	Long: `This command lists the contents of a solution archive (zip file), such as one created by "fsoc solution package",
+with the size of each file, and audits it without installing it:
+  - all entries must be regular files or directories within a single top-level solution directory; absolute
+    paths, paths leaving the archive (e.g., "../x"), symbolic links and duplicate entries are reported
+  - the embedded manifest and the objects it refers to are validated offline, as with "fsoc solution validate --local"
+
+The command fails if any problem is found, so it can be used to check artifacts produced by CI before they are
+installed. With --diff, the command instead compares the archive with another one, listing the files and the
+objects (down to the field level) that were added, removed or modified in the other archive.`,
+	Example: `  fsoc solution inspect mysolution.zip
+  fsoc solution inspect mysolution.zip -o json
+  fsoc solution inspect mysolution-1.0.0.zip --diff mysolution-1.1.0.zip`,
+	Run:         inspectSolutionArchive,
+	Annotations: map[string]string{config.AnnotationForConfigBypass: ""},
+}
+
+// ArchiveFile is an entry of a solution archive
+type ArchiveFile struct {
+	Path           string   `json:"path" yaml:"path"`
+	Size           uint64   `json:"size" yaml:"size"`
+	CompressedSize uint64   `json:"compressedSize" yaml:"compressedSize"`
+	Mode           string   `json:"mode" yaml:"mode"`
+	Issues         []string `json:"issues,omitempty" yaml:"issues,omitempty"`
+	crc32          uint32
+}
+
+// ArchiveInspection is the result of inspecting a solution archive
+type ArchiveInspection struct {
+	Archive   string        `json:"archive" yaml:"archive"`
+	Solution  string        `json:"solution,omitempty" yaml:"solution,omitempty"`
+	Version   string        `json:"version,omitempty" yaml:"version,omitempty"`
+	Files     []ArchiveFile `json:"files" yaml:"files"`
+	TotalSize uint64        `json:"totalSize" yaml:"totalSize"`
+	Valid     bool          `json:"valid" yaml:"valid"`
+	Errors    []ErrorItem   `json:"errors" yaml:"errors"`
+	root      string        // top-level directory of the solution in the archive
+}
+
+// ArchiveChange is a difference between two solution archives
+type ArchiveChange struct {
+	Change string `json:"change" yaml:"change"` // added, removed or modified in the other archive
+	Type   string `json:"type" yaml:"type"`     // file, manifest or the object type
+	Object string `json:"object" yaml:"object"`
+	Field  string `json:"field,omitempty" yaml:"field,omitempty"`
+	Old    any    `json:"old,omitempty" yaml:"old,omitempty"`
+	New    any    `json:"new,omitempty" yaml:"new,omitempty"`
+}
+
+func getSolutionInspectCmd() *cobra.Command {
+	solutionInspectCmd.Flags().
+		String("diff", "", "Compare the archive with another archive")
+
+	return solutionInspectCmd
+}
+
+func inspectSolutionArchive(cmd *cobra.Command, args []string) {
+	inspection, dir, err := inspectArchive(args[0])
+	if dir != "" {
+		defer os.RemoveAll(dir)
+	}
+	if err != nil {
+		log.Fatalf("Failed to inspect archive %q: %v", args[0], err)
+	}
+
+	if otherArchive, _ := cmd.Flags().GetString("diff"); otherArchive != "" {
+		diffSolutionArchives(cmd, inspection, dir, otherArchive)
+		return
+	}
+
+	lines := [][]string{}
+	for _, file := range inspection.Files {
+		lines = append(lines, []string{file.Path, fmt.Sprint(file.Size), fmt.Sprint(file.CompressedSize), file.Mode, strings.Join(file.Issues, "; ")})
+	}
+	output.PrintCmdOutputCustom(cmd, inspection, &output.Table{
+		Headers: []string{"Path", "Size", "Compressed", "Mode", "Issues"},
+		Lines:   lines,
+	})
+
+	if !inspection.Valid {
+		output.PrintCmdStatus(cmd, getSolutionValidationErrorsString(len(inspection.Errors), Errors{Items: inspection.Errors, Total: len(inspection.Errors)}))
+		log.Fatalf("%d problem(s) found in archive %q", len(inspection.Errors), args[0])
+	}
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Archive of solution %v version %v is valid: %d files, %d bytes\n", inspection.Solution, inspection.Version, len(inspection.Files), inspection.TotalSize))
+}
+
+// inspectArchive lists and checks the entries of a solution archive and, if they are safe to
+// extract, extracts the archive into a temporary directory and validates the solution in it.
+// It returns the directory, which the caller must remove, or "" if the archive was not extracted.
+func inspectArchive(archivePath string) (*ArchiveInspection, string, error) {
+	zipReader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, "", err
+	}
+	defer zipReader.Close()
+
+	inspection := &ArchiveInspection{Archive: archivePath, Files: []ArchiveFile{}, Errors: []ErrorItem{}}
+	roots := map[string]bool{}
+	seen := map[string]bool{}
+	for _, entry := range zipReader.File {
+		file := ArchiveFile{
+			Path:           entry.Name,
+			Size:           entry.UncompressedSize64,
+			CompressedSize: entry.CompressedSize64,
+			Mode:           entry.Mode().String(),
+			crc32:          entry.CRC32,
+		}
+		mode := entry.Mode()
+		file.Issues = archive.EntryIssues(entry)
+		name := strings.TrimSuffix(entry.Name, "/")
+		if seen[name] {
+			file.Issues = append(file.Issues, "duplicate entry")
+		}
+		seen[name] = true
+		root, _, inDir := strings.Cut(name, "/")
+		if !inDir && !mode.IsDir() {
+			file.Issues = append(file.Issues, "file outside of the solution directory")
+		}
+		roots[root] = true
+
+		for _, issue := range file.Issues {
+			inspection.Errors = append(inspection.Errors, ErrorItem{Error: issue, Source: entry.Name})
+		}
+		inspection.TotalSize += file.Size
+		inspection.Files = append(inspection.Files, file)
+	}
+	if len(roots) != 1 {
+		inspection.Errors = append(inspection.Errors, ErrorItem{Error: fmt.Sprintf("expected a single top-level solution directory, found %d", len(roots)), Source: archivePath})
+	}
+	if len(inspection.Errors) > 0 {
+		inspection.Errors = append(inspection.Errors, ErrorItem{Error: "the solution was not validated because the archive is not safe to extract", Source: archivePath})
+		return inspection, "", nil
+	}
+	for root := range roots {
+		inspection.root = root
+	}
+
+	// extract and validate the solution
+	dir, err := os.MkdirTemp("", "fsoc-inspect-")
+	if err != nil {
+		return nil, "", err
+	}
+	if err := archive.Extract(archivePath, afero.NewBasePathFs(afero.NewOsFs(), dir), archive.ExtractOptions{}); err != nil {
+		return nil, dir, err
+	}
+	solutionDir := filepath.Join(dir, inspection.root)
+	if manifest, err := getSolutionManifest(solutionDir); err == nil {
+		inspection.Solution = manifest.Name
+		inspection.Version = manifest.SolutionVersion
+	}
+	result := validateSolutionLocally(solutionDir, "")
+	inspection.Errors = append(inspection.Errors, result.Errors.Items...)
+	inspection.Valid = len(inspection.Errors) == 0
+	return inspection, dir, nil
+}
+
+// diffSolutionArchives displays the differences between an inspected archive and another archive
+func diffSolutionArchives(cmd *cobra.Command, inspection *ArchiveInspection, dir string, otherArchive string) {
+	other, otherDir, err := inspectArchive(otherArchive)
+	if otherDir != "" {
+		defer os.RemoveAll(otherDir)
+	}
+	if err != nil {
+		log.Fatalf("Failed to inspect archive %q: %v", otherArchive, err)
+	}
+	if dir == "" || otherDir == "" {
+		log.Fatalf("Cannot compare archives that are not safe to extract; run inspect on each archive for details")
+	}
+
+	// compare files, ignoring the name of the top-level directory
+	changes := []ArchiveChange{}
+	files := map[string]ArchiveFile{}
+	for _, file := range inspection.Files {
+		if relPath := archiveRelPath(file.Path, inspection.root); relPath != "" {
+			files[relPath] = file
+		}
+	}
+	otherFiles := map[string]bool{}
+	for _, file := range other.Files {
+		relPath := archiveRelPath(file.Path, other.root)
+		if relPath == "" {
+			continue
+		}
+		otherFiles[relPath] = true
+		previous, found := files[relPath]
+		switch {
+		case !found:
+			changes = append(changes, ArchiveChange{Change: changeAdded, Type: "file", Object: relPath, New: file.Size})
+		case previous.crc32 != file.crc32 || previous.Size != file.Size:
+			changes = append(changes, ArchiveChange{Change: changeModified, Type: "file", Object: relPath, Old: previous.Size, New: file.Size})
+		}
+	}
+	for relPath, file := range files {
+		if !otherFiles[relPath] {
+			changes = append(changes, ArchiveChange{Change: changeRemoved, Type: "file", Object: relPath, Old: file.Size})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Object < changes[j].Object })
+
+	// compare the manifest and objects; the other archive is "local" as it is the newer one
+	objectChanges, err := diffSolutionDirectories(filepath.Join(otherDir, other.root), filepath.Join(dir, inspection.root))
+	if err != nil {
+		log.Fatalf("Failed to compare solutions: %v", err)
+	}
+	for _, change := range objectChanges {
+		changes = append(changes, ArchiveChange{Change: change.Change, Type: change.Type, Object: change.Object, Field: change.Field, Old: change.Deployed, New: change.Local})
+	}
+
+	if len(changes) == 0 {
+		output.PrintCmdStatus(cmd, fmt.Sprintf("No differences between %q and %q.\n", inspection.Archive, otherArchive))
+		return
+	}
+	lines := [][]string{}
+	for _, change := range changes {
+		lines = append(lines, []string{change.Change, change.Type, change.Object, change.Field, fmt.Sprint(valueOrEmpty(change.Old)), fmt.Sprint(valueOrEmpty(change.New))})
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []ArchiveChange `json:"items"`
+		Total int             `json:"total"`
+	}{changes, len(changes)}, &output.Table{
+		Headers: []string{"Change", "Type", "Object", "Field", "Old", "New"},
+		Lines:   lines,
+	})
+}
+
+// archiveRelPath returns the path of an archive entry relative to the solution directory
+func archiveRelPath(entryPath string, root string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(entryPath, root), "/")
+}
+
+func valueOrEmpty(v any) any {
+	if v == nil {
+		return ""
+	}
+	return v
+}
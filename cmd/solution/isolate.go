@@ -54,41 +54,48 @@ $dependency := function($name) {
 var rgxp *regexp.Regexp // TODO: consider removing the global var
 
 var solutionIsolateCmd = &cobra.Command{
-	Use:    "isolate [--source-dir=<solution-dir>]  (--target-dir=<target-dir>|--target-file=<target-file>) [--env-file=<env-file>|--tag=<tag>]",
-	Hidden: true,
-	Args:   cobra.NoArgs,
-	Short:  "Creates an isolated solution copy using values in an environment file",
-	Long: `EXPERIMENTAL! 
-This command creates an isolated solution using a tag specified in the env file (or --tag flag), starting from a solution directory with templated manifest and objects.
-The command produces a solution whose name has the tag as a suffix (e.g., solution "spacefleet" with tag "dev" becomes "spacefleetdev"), allowing multiple copies of the same solution with different tags to coexist in the same environment. The env file may contain additional variables, including tags to use for dependent solutions.
-Note that when the --tag flag is used, the env.json file is ignored and no dependency tags are defined (this is intended to work best with --tag=stable).
-
-The command takes the solution from current directory (or --source-dir) and produces the isolated version either in a directory (if --target-dir is specified) or in a solution zip file (if --target-file is specified).
-
-Note that this command is experimental and will likely be removed. Please use "push", "validate" or "package" directly.
-
-See documentation for manifest syntax and examples (link to be added here).
-`,
-	Example: `  
+	Use:   "isolate [--tag=<tag> | --env-file=<env-file>] [--source-dir=<solution-dir>] [--target-dir=<target-dir> | --target-file=<target-file>]",
+	Args:  cobra.NoArgs,
+	Short: "Create a pseudo-isolated copy of a solution",
+	Long: `This command creates a pseudo-isolated copy of a solution, starting from a solution directory whose manifest and
+objects refer to the solution and its dependencies using isolation expressions:
+  ${sys.solutionId}                 the isolated name of this solution (e.g., in type and object references)
+  ${$dependency('name')}            the isolated name of the dependency "name"
+  ${$toSuffix(env.tag)}             the tag as a name suffix (empty for the "stable" tag), used in the manifest's name
+
+The isolated solution's name has the tag as a suffix (e.g., solution "spacefleet" with tag "dev" becomes
+"spacefleetdev"), allowing multiple copies of the same solution with different tags to coexist in the same tenant.
+The tag comes from the --tag flag or from an env file, which may also contain the tags to use for the dependencies:
+  {"env": {"tag": "dev", "dependencyTags": {"spacefleetbase": "dev"}}}
+
+The references are rewritten consistently in the manifest and in all object and type files of the solution; other
+files are copied as is. The source directory is never modified: the isolated solution is written into a new target
+directory (by default, build/<isolated-name>) or into a solution zip file (--target-file).
+
+Use --name-only to display the isolated name without creating the copy, and "fsoc solution render" with the same
+--tag or --env-file flag to preview the isolated solution.`,
+	Example: `  fsoc solution isolate --tag dev
+  fsoc solution isolate --tag dev --name-only
   fsoc solution isolate --target-dir=../mysolution-joe  # tags come from the current directory's private copy of ./env.json
   fsoc solution isolate --target-file=../mysolution-release.zip --tag=stable
-  fsoc solution isolate --source-dir=mysolution --target-dir=mysolution-staging --env-file=staging-env.json
-	`,
+  fsoc solution isolate --source-dir=mysolution --target-dir=mysolution-staging --env-file=staging-env.json`,
 	Run:         solutionIsolateCommand,
 	Annotations: map[string]string{config.AnnotationForConfigBypass: ""},
-	Deprecated:  "please use `push`, `validate` or `package` instead",
 }
 
 func getsolutionIsolateCmd() *cobra.Command {
 	c := solutionIsolateCmd
 	c.Flags().String("source-dir", ".", "path to the source directory")
-	c.Flags().String("target-dir", "", "path to the target directory")
+	c.Flags().String("target-dir", "", "path to the target directory (defaults to build/<isolated-name>)")
 	c.Flags().String("target-file", "", "path to the target zip file")
 	c.Flags().String("tag", "", "tag for the solution")
 	c.Flags().String("env-file", "./env.json", "path to the env vars json file")
+	c.Flags().Bool("name-only", false, "display the isolated solution name without creating the isolated copy")
 
 	c.MarkFlagsMutuallyExclusive("tag", "env-file")
 	c.MarkFlagsMutuallyExclusive("target-file", "target-dir")
+	c.MarkFlagsMutuallyExclusive("name-only", "target-dir")
+	c.MarkFlagsMutuallyExclusive("name-only", "target-file")
 	return c
 }
 
@@ -101,9 +108,22 @@ func solutionIsolateCommand(cmd *cobra.Command, args []string) {
 	if tag != "" {
 		envVarsFile = "" // remove default when tag is specified
 	}
-	if targetFolder == "" && targetFile == "" {
-		_ = cmd.Usage()
-		log.Fatal("Either <target-dir> or <target-file> must be specified.")
+
+	// compute the isolated name, needed for the default target directory
+	if nameOnly, _ := cmd.Flags().GetBool("name-only"); nameOnly || (targetFolder == "" && targetFile == "") {
+		envVars, err := LoadEnvVars(cmd, tag, envVarsFile)
+		if err != nil {
+			log.Fatalf("Failed to isolate solution: %v", err)
+		}
+		name, err := isolatedSolutionName(srcFolder, envVars)
+		if err != nil {
+			log.Fatalf("Failed to isolate solution: %v", err)
+		}
+		if nameOnly {
+			output.PrintCmdStatus(cmd, name+"\n")
+			return
+		}
+		targetFolder = filepath.Join(BuildDirName, name)
 	}
 
 	solutionName, _, err := isolateSolution(cmd, srcFolder, targetFolder, targetFile, tag, envVarsFile)
@@ -115,6 +135,27 @@ func solutionIsolateCommand(cmd *cobra.Command, args []string) {
 	output.PrintCmdStatus(cmd, message)
 }
 
+// isolatedSolutionName returns the name the solution in srcFolder has when isolated with envVars
+func isolatedSolutionName(srcFolder string, envVars interface{}) (string, error) {
+	var err error
+	if rgxp, err = regexp.Compile(regexPattern); err != nil {
+		return "", fmt.Errorf("(likely bug) Error compiling regex /%v/: %w", regexPattern, err)
+	}
+	manifestFile, err := os.ReadFile(filepath.Join(srcFolder, "manifest.json"))
+	if err != nil {
+		return "", fmt.Errorf("error opening manifest file: %w", err)
+	}
+	manifestFile, err = evaluateJSONata(manifestFile, envVars, "manifest.json")
+	if err != nil {
+		return "", fmt.Errorf("error evaluating expressions in manifest: %w", err)
+	}
+	var manifest Manifest
+	if err = json.Unmarshal(manifestFile, &manifest); err != nil {
+		return "", fmt.Errorf("failed to parse solution manifest: %w", err)
+	}
+	return manifest.Name, nil
+}
+
 // isolateSolution returns path to directory with isolated artifacts, the tag used and error
 func isolateSolution(cmd *cobra.Command, srcFolder, targetFolder, targetFile, tag, envVarsFile string) (string, string, error) {
 	var err error
@@ -194,6 +235,10 @@ func prepareForIsolation(srcPath, targetPath, targetFile string, envVars interfa
 	if exists, _ := afero.DirExists(srcFs, "."); !exists {
 		return fmt.Errorf("source directory %q does not exist", srcPath)
 	}
+	// never isolate in place or into a directory containing the source
+	if rel, err := filepath.Rel(targetPath, srcPath); err == nil && !strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("target directory %q must not be the source directory or contain it", targetPath)
+	}
 	targetFs := afero.NewBasePathFs(afero.NewOsFs(), targetPath)
 	if exists, _ := afero.DirExists(targetFs, "."); exists {
 		if empty, _ := afero.IsEmpty(targetFs, "."); !empty {
@@ -249,42 +294,49 @@ func isolateManifest(cmd *cobra.Command, srcPath, targetPath string, envVars int
 	return &manifest, err
 }
 
+// isolateFiles evaluates the isolation expressions in the object and type files of the solution,
+// writing them into the target directory; other files are copied as is. The manifest, which is
+// isolated separately, the env file and the build directory (the default isolation target) are skipped.
 func isolateFiles(mf *Manifest, srcPath, targetPath, targetFile string, envVars interface{}) error {
-	var err error
-	// traverse objects
+	// files referenced by the manifest are evaluated regardless of their extension
+	referenced := map[string]bool{}
 	for _, objDef := range mf.Objects {
 		if objDef.ObjectsFile != "" {
-			err = evalAndCopyFile(objDef.ObjectsFile, srcPath, targetPath, envVars)
-		} else {
-			dirPath := filepath.Join(srcPath, objDef.ObjectsDir)
-			err = traverseSolutionFolder(dirPath, mf, srcPath, targetPath, envVars)
-		}
-		if err != nil {
-			return err
+			referenced[filepath.Clean(objDef.ObjectsFile)] = true
 		}
 	}
-	// traverse types
 	for _, typeFile := range mf.Types {
-		if err = evalAndCopyFile(typeFile, srcPath, targetPath, envVars); err != nil {
-			return err
-		}
+		referenced[filepath.Clean(typeFile)] = true
 	}
-	return nil
-}
 
-func traverseSolutionFolder(dirPath string, mf *Manifest, srcPath, targetPath string, envVars interface{}) error {
-	log.WithField("path", dirPath).Debug("Traversing directory")
-	err := filepath.Walk(dirPath,
-		func(path string, info os.FileInfo, err error) error {
-			// log.Infof("subfolder %v, err: %v", info, err)
-			if !info.IsDir() {
-				filePath := strings.Replace(path, srcPath, "", 1)
-				return evalAndCopyFile(filePath, srcPath, targetPath, envVars)
+	log.WithField("path", srcPath).Debug("Traversing directory")
+	return filepath.Walk(srcPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == targetPath || path == filepath.Join(srcPath, BuildDirName) || !isAllowedPath(srcPath, path, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
 			}
 			return nil
-		},
-	)
-	return err
+		}
+		relPath, err := filepath.Rel(srcPath, path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case info.IsDir() || relPath == "manifest.json" || relPath == "env.json":
+			return nil
+		case referenced[relPath] || isObjectsFile(path):
+			return evalAndCopyFile(relPath, srcPath, targetPath, envVars)
+		default:
+			targetFilePath := filepath.Join(targetPath, relPath)
+			if err := os.MkdirAll(filepath.Dir(targetFilePath), 0o755); err != nil {
+				return err
+			}
+			return copyLocalFile(path, targetFilePath)
+		}
+	})
 }
 
 func LoadEnvVars(cmd *cobra.Command, tag, envVarsFile string) (interface{}, error) {
@@ -389,5 +441,9 @@ func addSysVariables(mf *Manifest, envVars interface{}) interface{} {
 }
 
 func validExpression(expr string) bool {
+	// skip template variable references, they are substituted when packaging
+	if templateVariableRegexp.MatchString("${" + expr + "}") {
+		return false
+	}
 	return !strings.HasPrefix(strings.TrimSpace(expr), ".")
 }
@@ -0,0 +1,486 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+)
+
+// LintConfigFileName is the name of the optional file in the solution root directory
+// that configures the severity of lint rules
+const LintConfigFileName = ".fsoclint"
+
+const defaultLintMaxFileSize = 1024 * 1024 // bytes
+
+var solutionLintCmd = &cobra.Command{
+	Use:   "lint",
+	Args:  cobra.ExactArgs(0),
+	Short: "Check a solution for common problems",
+	Long: `This command checks the solution in the current directory for common problems, without uploading it.
+
+The following rules are checked:
+  naming               solution and object names follow the naming conventions
+  missing-description  the manifest and attribute definitions have descriptions
+  dangling-reference   all files and directories referenced in the manifest exist
+  unused-dependency    all dependencies declared in the manifest are used by the solution's objects
+  oversized-file       no file in the solution exceeds the maximum size
+
+The severity of each rule (error, warning, note or off) can be changed in a .fsoclint file in the solution
+directory, e.g.:
+
+  rules:
+    missing-description: off
+    unused-dependency: error
+  maxFileSize: 2097152
+
+The .fsoclint file also sets the package budget (see "fsoc solution package --help").
+
+The command fails if any finding with severity "error" is reported. Use -o json to get machine-readable
+findings or --sarif to write a SARIF log for CI code annotation. When the SARIF log is written to stdout,
+the findings are displayed on stderr.`,
+	Example: `  fsoc solution lint
+  fsoc solution lint -d mysolution
+  fsoc solution lint -o json
+  fsoc solution lint --sarif lint.sarif`,
+	Run: lintSolution,
+	Annotations: map[string]string{
+		output.TableFieldsAnnotation:     "severity: .severity, rule: .rule, file: .file, message: .message",
+		config.AnnotationForConfigBypass: "",
+	},
+}
+
+type lintSeverity string
+
+const (
+	lintSeverityError   lintSeverity = "error"
+	lintSeverityWarning lintSeverity = "warning"
+	lintSeverityNote    lintSeverity = "note"
+	lintSeverityOff     lintSeverity = "off"
+)
+
+// LintFinding is a single problem reported by the solution linter
+type LintFinding struct {
+	Rule     string       `json:"rule" yaml:"rule"`
+	Severity lintSeverity `json:"severity" yaml:"severity"`
+	File     string       `json:"file" yaml:"file"`
+	Message  string       `json:"message" yaml:"message"`
+}
+
+// LintConfig is the content of the .fsoclint file
+type LintConfig struct {
+	Rules         map[string]lintSeverity `yaml:"rules"`
+	MaxFileSize   int64                   `yaml:"maxFileSize"`
+	PackageBudget PackageBudget           `yaml:"packageBudget"` // checked when packaging, see the package command
+}
+
+type lintRule struct {
+	ID              string
+	Description     string
+	DefaultSeverity lintSeverity
+	check           func(lc *lintContext)
+}
+
+// lintContext holds the parsed solution for the rules to inspect
+type lintContext struct {
+	root     string
+	manifest *Manifest
+	objects  []manifestObjectsFile
+	config   *LintConfig
+	rule     *lintRule
+	findings []LintFinding
+}
+
+var lintRules = []*lintRule{
+	{"naming", "Solution and object names follow the naming conventions", lintSeverityWarning, lintNaming},
+	{"missing-description", "Manifest and attribute definitions have descriptions", lintSeverityWarning, lintMissingDescription},
+	{"dangling-reference", "Files and directories referenced in the manifest exist", lintSeverityError, lintDanglingReference},
+	{"unused-dependency", "Declared dependencies are used by the solution's objects", lintSeverityWarning, lintUnusedDependency},
+	{"oversized-file", "Files do not exceed the maximum size", lintSeverityWarning, lintOversizedFile},
+}
+
+var objectNameRegexp = regexp.MustCompile(`^[a-z][a-zA-Z0-9_.]*$`)
+
+func getSolutionLintCmd() *cobra.Command {
+	solutionLintCmd.Flags().
+		StringP("directory", "d", "", "Path to the solution root directory (defaults to current dir)")
+
+	solutionLintCmd.Flags().
+		String("lint-config", "", "Path to the lint configuration file (defaults to .fsoclint in the solution directory)")
+
+	solutionLintCmd.Flags().
+		String("sarif", "", `Write findings as a SARIF log to the specified file ("-" for stdout)`)
+
+	addReportFlag(solutionLintCmd)
+
+	return solutionLintCmd
+}
+
+func lintSolution(cmd *cobra.Command, args []string) {
+	sarifPath, _ := cmd.Flags().GetString("sarif")
+	if reportFormat, _ := cmd.Flags().GetString("report"); sarifPath == "-" && reportFormat != "" {
+		log.Fatalf("--sarif - and --report cannot be used together, as both are written to stdout")
+	}
+	report := startReport(cmd)
+	if sarifPath == "-" {
+		cmd.SetOut(os.Stderr) // keep stdout for the SARIF log
+	}
+	solutionRootDirectory, _ := cmd.Flags().GetString("directory")
+	if solutionRootDirectory == "" {
+		solutionRootDirectory = "."
+	}
+	solutionRootDirectory = absolutizePath(solutionRootDirectory)
+
+	configPath, _ := cmd.Flags().GetString("lint-config")
+	if configPath == "" {
+		configPath = filepath.Join(solutionRootDirectory, LintConfigFileName)
+		if _, err := os.Stat(configPath); err != nil {
+			configPath = ""
+		}
+	}
+	lintConfig, err := loadLintConfig(configPath)
+	if err != nil {
+		log.Fatalf("Failed to load lint configuration: %v", err)
+	}
+
+	manifest, err := getSolutionManifest(solutionRootDirectory)
+	if err != nil {
+		log.Fatalf("Failed to read the solution manifest: %v", err)
+	}
+
+	findings := runLintRules(solutionRootDirectory, manifest, lintConfig)
+	report.setSolution(manifest.Name, manifest.SolutionVersion, "")
+	report.addLintFindings(findings)
+
+	if sarifPath != "" {
+		if err := writeSarifLog(sarifPath, findings); err != nil {
+			log.Fatalf("Failed to write SARIF log: %v", err)
+		}
+	}
+
+	nErrors := 0
+	for _, f := range findings {
+		if f.Severity == lintSeverityError {
+			nErrors++
+		}
+	}
+
+	if len(findings) == 0 {
+		output.PrintCmdStatus(cmd, "No problems found.\n")
+		report.finish()
+		return
+	}
+	output.PrintCmdOutput(cmd, struct {
+		Items []LintFinding `json:"items"`
+		Total int           `json:"total"`
+	}{findings, len(findings)})
+	if nErrors > 0 {
+		report.setOutcome(reportOutcomeFindings)
+		log.Fatalf("%d error(s) found while linting the solution", nErrors)
+	}
+	report.finish()
+}
+
+func loadLintConfig(path string) (*LintConfig, error) {
+	lintConfig := &LintConfig{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(data, lintConfig); err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %w", path, err)
+		}
+	}
+	for id, severity := range lintConfig.Rules {
+		if getLintRule(id) == nil {
+			return nil, fmt.Errorf("unknown lint rule %q", id)
+		}
+		switch severity {
+		case lintSeverityError, lintSeverityWarning, lintSeverityNote, lintSeverityOff:
+		default:
+			return nil, fmt.Errorf("invalid severity %q for rule %q; should be error, warning, note or off", severity, id)
+		}
+	}
+	if lintConfig.MaxFileSize <= 0 {
+		lintConfig.MaxFileSize = defaultLintMaxFileSize
+	}
+	return lintConfig, nil
+}
+
+func getLintRule(id string) *lintRule {
+	for _, rule := range lintRules {
+		if rule.ID == id {
+			return rule
+		}
+	}
+	return nil
+}
+
+// runLintRules runs all enabled rules on the solution and returns the findings sorted by file
+func runLintRules(root string, manifest *Manifest, lintConfig *LintConfig) []LintFinding {
+	lc := &lintContext{root: root, manifest: manifest, config: lintConfig}
+	lc.loadObjects()
+
+	for _, rule := range lintRules {
+		if lc.severity(rule) == lintSeverityOff {
+			continue
+		}
+		lc.rule = rule
+		rule.check(lc)
+	}
+
+	sort.SliceStable(lc.findings, func(i, j int) bool {
+		return lc.findings[i].File < lc.findings[j].File
+	})
+	return lc.findings
+}
+
+func (lc *lintContext) severity(rule *lintRule) lintSeverity {
+	if severity, found := lc.config.Rules[rule.ID]; found {
+		return severity
+	}
+	return rule.DefaultSeverity
+}
+
+func (lc *lintContext) report(file string, format string, args ...any) {
+	lc.findings = append(lc.findings, LintFinding{
+		Rule:     lc.rule.ID,
+		Severity: lc.severity(lc.rule),
+		File:     file,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (lc *lintContext) manifestFileName() string {
+	return "manifest." + lc.manifest.ManifestFormat.String()
+}
+
+// loadObjects reads all object files referenced in the manifest; unreadable files are skipped
+// here and reported by the relevant rules
+func (lc *lintContext) loadObjects() {
+	var errs []error
+	lc.objects, errs = loadManifestObjects(lc.root, lc.manifest)
+	for _, err := range errs {
+		log.Warnf("Skipping file %v", err)
+	}
+}
+
+func lintNaming(lc *lintContext) {
+	if !lc.manifest.HasPseudoIsolation() && !IsValidSolutionName(lc.manifest.Name) {
+		lc.report(lc.manifestFileName(), "solution name %q should start with a lowercase letter, contain only lowercase letters and digits and be no longer than 25 characters", lc.manifest.Name)
+	}
+	for _, file := range lc.objects {
+		if !strings.HasPrefix(file.objType, "fmm:") || file.objType == "fmm:namespace" {
+			continue
+		}
+		for _, obj := range file.objects {
+			objMap, _ := obj.(map[string]any)
+			name, _ := objMap["name"].(string)
+			if name != "" && !objectNameRegexp.MatchString(name) {
+				lc.report(file.path, "%s name %q should start with a lowercase letter and contain only letters, digits, '_' and '.'", file.objType, name)
+			}
+		}
+	}
+}
+
+func lintMissingDescription(lc *lintContext) {
+	description := strings.TrimSpace(lc.manifest.Description)
+	if description == "" || description == "description of your solution" {
+		lc.report(lc.manifestFileName(), "solution has no description")
+	}
+	for _, file := range lc.objects {
+		for _, obj := range file.objects {
+			objMap, _ := obj.(map[string]any)
+			attrDefs, _ := objMap["attributeDefinitions"].(map[string]any)
+			attributes, _ := attrDefs["attributes"].(map[string]any)
+			names := make([]string, 0, len(attributes))
+			for name := range attributes {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				attr, _ := attributes[name].(map[string]any)
+				if desc, _ := attr["description"].(string); strings.TrimSpace(desc) == "" {
+					lc.report(file.path, "attribute %q of %v %q has no description", name, file.objType, objMap["name"])
+				}
+			}
+		}
+	}
+}
+
+func lintDanglingReference(lc *lintContext) {
+	check := func(path string, dir bool) {
+		info, err := os.Stat(filepath.Join(lc.root, path))
+		switch {
+		case err != nil:
+			lc.report(lc.manifestFileName(), "referenced path %q does not exist", path)
+		case dir && !info.IsDir():
+			lc.report(lc.manifestFileName(), "objectsDir %q is not a directory", path)
+		case !dir && info.IsDir():
+			lc.report(lc.manifestFileName(), "%q is a directory, expected a file", path)
+		}
+	}
+	for _, compDef := range lc.manifest.Objects {
+		if compDef.ObjectsFile != "" {
+			check(compDef.ObjectsFile, false)
+		}
+		if compDef.ObjectsDir != "" {
+			check(compDef.ObjectsDir, true)
+		}
+	}
+	for _, typeFile := range lc.manifest.Types {
+		check(typeFile, false)
+	}
+}
+
+func lintUnusedDependency(lc *lintContext) {
+	for _, dep := range lc.manifest.Dependencies {
+		prefix := dep + ":"
+		used := false
+		for _, file := range lc.objects {
+			if strings.HasPrefix(file.objType, prefix) || containsStringWithPrefix(file.objects, prefix) {
+				used = true
+				break
+			}
+		}
+		if !used {
+			lc.report(lc.manifestFileName(), "dependency %q is not used by any object in the solution", dep)
+		}
+	}
+}
+
+// containsStringWithPrefix returns true if any string (value or key) within the
+// object tree contains the prefix, e.g., a type reference such as "fmm:entity"
+func containsStringWithPrefix(v any, prefix string) bool {
+	switch typed := v.(type) {
+	case string:
+		return strings.Contains(typed, prefix)
+	case []any:
+		for _, item := range typed {
+			if containsStringWithPrefix(item, prefix) {
+				return true
+			}
+		}
+	case map[string]any:
+		for key, item := range typed {
+			if strings.Contains(key, prefix) || containsStringWithPrefix(item, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func lintOversizedFile(lc *lintContext) {
+	_ = filepath.Walk(lc.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !isAllowedPath(lc.root, path, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() && info.Size() > lc.config.MaxFileSize {
+			relPath, _ := filepath.Rel(lc.root, path)
+			lc.report(relPath, "file size %d bytes exceeds the maximum of %d bytes", info.Size(), lc.config.MaxFileSize)
+		}
+		return nil
+	})
+}
+
+// writeSarifLog writes the findings in the SARIF 2.1.0 format, for annotating code in CI systems
+func writeSarifLog(path string, findings []LintFinding) error {
+	type sarifMessage struct {
+		Text string `json:"text"`
+	}
+	type sarifRule struct {
+		ID                   string            `json:"id"`
+		ShortDescription     sarifMessage      `json:"shortDescription"`
+		DefaultConfiguration map[string]string `json:"defaultConfiguration"`
+	}
+	type sarifLocation struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+		} `json:"physicalLocation"`
+	}
+	type sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+
+	rules := []sarifRule{}
+	for _, rule := range lintRules {
+		rules = append(rules, sarifRule{
+			ID:                   rule.ID,
+			ShortDescription:     sarifMessage{rule.Description},
+			DefaultConfiguration: map[string]string{"level": string(rule.DefaultSeverity)},
+		})
+	}
+	results := []sarifResult{}
+	for _, f := range findings {
+		loc := sarifLocation{}
+		loc.PhysicalLocation.ArtifactLocation.URI = filepath.ToSlash(f.File)
+		results = append(results, sarifResult{
+			RuleID:    f.Rule,
+			Level:     string(f.Severity),
+			Message:   sarifMessage{f.Message},
+			Locations: []sarifLocation{loc},
+		})
+	}
+	sarifLog := map[string]any{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []any{
+			map[string]any{
+				"tool": map[string]any{
+					"driver": map[string]any{
+						"name":           "fsoc-solution-lint",
+						"informationUri": "https://github.com/cisco-open/fsoc",
+						"rules":          rules,
+					},
+				},
+				"results": results,
+			},
+		},
+	}
+
+	if path == "-" {
+		return output.WriteJson(sarifLog, os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return output.WriteJson(sarifLog, f)
+}
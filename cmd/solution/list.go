@@ -15,7 +15,10 @@
 package solution
 
 import (
+	"fmt"
 	"net/url"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/apex/log"
@@ -31,9 +34,20 @@ var solutionListCmd = &cobra.Command{
 	Use:   "list [--subscribed | --unsubscribed]",
 	Args:  cobra.ExactArgs(0),
 	Short: "List all solutions available in this tenant",
-	Long:  `This command list all the solutions that are deployed in the current tenant specified in the profile.`,
+	Long: `This command list all the solutions that are deployed in the current tenant specified in the profile.
+
+The list can be narrowed down with --name-prefix, --type (the solution type, e.g., component) and --author
+(a case-insensitive substring of the solution's contact), in addition to --subscribed and --unsubscribed.
+Use --sort to order the list by a column (prefix it with "-" for descending order) and --columns to select
+the columns to display. The available columns are: ` + strings.Join(sortedKeys(solutionListColumns), ", ") + `.
+
+Use --all-versions to show the history of uploaded versions of each listed solution, newest first.`,
 	Example: `  fsoc solution list
-  fsoc solution list -o json`,
+  fsoc solution list -o json
+  fsoc solution list --subscribed --name-prefix space
+  fsoc solution list --type module --sort -updateDate
+  fsoc solution list --columns name,tag,author,updateDate
+  fsoc solution list --name-prefix spacefleet --all-versions`,
 	Run:              getSolutionList,
 	TraverseChildren: true,
 	Annotations: map[string]string{
@@ -42,6 +56,36 @@ var solutionListCmd = &cobra.Command{
 	},
 }
 
+// solutionListColumn is a column that can be selected for display or sorting in the solution list
+type solutionListColumn struct {
+	header string
+	value  func(item map[string]any) string
+}
+
+var solutionListColumns = map[string]solutionListColumn{
+	"name":         {"Name", solutionDataField("name")},
+	"id":           {"ID", solutionField("id")},
+	"tag":          {"Tag", solutionDataField("tag")},
+	"type":         {"Type", solutionDataField("solutionType")},
+	"author":       {"Author", solutionDataField("contact")},
+	"isSystem":     {"Is System", solutionDataField("isSystem")},
+	"isSubscribed": {"Is Subscribed", solutionDataField("isSubscribed")},
+	"dependencies": {"Dependencies", solutionDataField("dependencies")},
+	"installDate":  {"Install Date", solutionField("createdAt")},
+	"updateDate":   {"Update Date", solutionField("updatedAt")},
+}
+
+// solutionVersionHistory is the upload history of a solution, newest version first
+type solutionVersionHistory struct {
+	Solution string            `json:"solution"`
+	Versions []solutionVersion `json:"versions"`
+}
+
+type solutionVersion struct {
+	Version    string `json:"version"`
+	UploadedAt string `json:"uploadedAt"`
+}
+
 func getSolutionListCmd() *cobra.Command {
 	solutionListCmd.Flags().
 		Bool("subscribed", false, "Use this to only see solutions that you are subscribed to")
@@ -50,6 +94,21 @@ func getSolutionListCmd() *cobra.Command {
 
 	solutionListCmd.MarkFlagsMutuallyExclusive("subscribed", "unsubscribed")
 
+	solutionListCmd.Flags().
+		String("name-prefix", "", "Only list solutions whose name starts with the prefix")
+	solutionListCmd.Flags().
+		String("type", "", "Only list solutions of the type (e.g., component, module or application)")
+	solutionListCmd.Flags().
+		String("author", "", "Only list solutions whose contact contains the value")
+	solutionListCmd.Flags().
+		String("sort", "", `Sort the list by a column; prefix with "-" for descending order`)
+	solutionListCmd.Flags().
+		StringSlice("columns", nil, "Columns to display (comma-separated)")
+	solutionListCmd.Flags().
+		Bool("all-versions", false, "Show the history of uploaded versions of each solution")
+
+	solutionListCmd.MarkFlagsMutuallyExclusive("columns", "all-versions")
+
 	return solutionListCmd
 
 }
@@ -76,7 +135,143 @@ func getSolutionList(cmd *cobra.Command, args []string) {
 	} else if unsubscribed {
 		filters = []string{"filter=" + url.QueryEscape("data.isSubscribed ne true")}
 	}
-	cmdkit.FetchAndPrint(cmd, solutionBaseURL, &cmdkit.FetchAndPrintOptions{Headers: headers, IsCollection: true, Filters: filters})
+
+	// fetch and print as is unless the list needs client-side processing
+	clientSide := false
+	for _, flag := range []string{"name-prefix", "type", "author", "sort", "columns", "all-versions"} {
+		clientSide = clientSide || cmd.Flags().Changed(flag)
+	}
+	if !clientSide {
+		cmdkit.FetchAndPrint(cmd, solutionBaseURL, &cmdkit.FetchAndPrintOptions{Headers: headers, IsCollection: true, Filters: filters})
+		return
+	}
+
+	// validate the column names before fetching
+	sortKey, _ := cmd.Flags().GetString("sort")
+	descending := strings.HasPrefix(sortKey, "-")
+	sortKey = strings.TrimPrefix(sortKey, "-")
+	columns, _ := cmd.Flags().GetStringSlice("columns")
+	for _, column := range append(slices.Clone(columns), sortKey) {
+		if _, found := solutionListColumns[column]; column != "" && !found {
+			log.Fatalf("Unknown column %q; valid columns are: %v", column, strings.Join(sortedKeys(solutionListColumns), ", "))
+		}
+	}
+
+	path := solutionBaseURL
+	if len(filters) > 0 {
+		path += "?" + strings.Join(filters, "&")
+	}
+	var result api.CollectionResult[map[string]any]
+	if err := api.JSONGetCollection[map[string]any](path, &result, &api.Options{Headers: headers}); err != nil {
+		log.Fatalf("Platform API call failed: %v", err)
+	}
+	items := filterSolutionList(cmd, result.Items)
+	if sortKey != "" {
+		value := solutionListColumns[sortKey].value
+		sort.SliceStable(items, func(i, j int) bool {
+			if descending {
+				return value(items[i]) > value(items[j])
+			}
+			return value(items[i]) < value(items[j])
+		})
+	}
+
+	if allVersions, _ := cmd.Flags().GetBool("all-versions"); allVersions {
+		printSolutionVersionHistory(cmd, items, headers)
+		return
+	}
+
+	var table *output.Table // use the command's fields annotations by default
+	if len(columns) > 0 {
+		table = &output.Table{}
+		for _, column := range columns {
+			table.Headers = append(table.Headers, solutionListColumns[column].header)
+		}
+		for _, item := range items {
+			line := []string{}
+			for _, column := range columns {
+				line = append(line, solutionListColumns[column].value(item))
+			}
+			table.Lines = append(table.Lines, line)
+		}
+	}
+	output.PrintCmdOutputCustom(cmd, api.CollectionResult[map[string]any]{Items: items, Total: len(items)}, table)
+}
+
+// filterSolutionList applies the client-side filters to the solution objects
+func filterSolutionList(cmd *cobra.Command, items []map[string]any) []map[string]any {
+	namePrefix, _ := cmd.Flags().GetString("name-prefix")
+	solutionType, _ := cmd.Flags().GetString("type")
+	author, _ := cmd.Flags().GetString("author")
+
+	filtered := []map[string]any{}
+	for _, item := range items {
+		if namePrefix != "" && !strings.HasPrefix(solutionDataField("name")(item), namePrefix) {
+			continue
+		}
+		if solutionType != "" && !strings.EqualFold(solutionDataField("solutionType")(item), solutionType) {
+			continue
+		}
+		if author != "" && !strings.Contains(strings.ToLower(solutionDataField("contact")(item)), strings.ToLower(author)) {
+			continue
+		}
+		filtered = append(filtered, item)
+	}
+	return filtered
+}
+
+// printSolutionVersionHistory displays the uploaded versions of each solution, newest first
+func printSolutionVersionHistory(cmd *cobra.Command, items []map[string]any, headers map[string]string) {
+	histories := []solutionVersionHistory{}
+	lines := [][]string{}
+	for _, item := range items {
+		solutionID := solutionField("id")(item)
+		filter := fmt.Sprintf(`data.solutionID eq "%s"`, solutionID)
+		query := fmt.Sprintf("?order=%s&filter=%s", url.QueryEscape("desc"), url.QueryEscape(filter))
+		var releases api.CollectionResult[StatusItem]
+		if err := api.JSONGetCollection[StatusItem](fmt.Sprintf(getSolutionReleaseUrl(), query), &releases, &api.Options{Headers: headers}); err != nil {
+			log.Fatalf("Failed to get the versions of solution %q: %v", solutionID, err)
+		}
+
+		history := solutionVersionHistory{Solution: solutionID, Versions: []solutionVersion{}}
+		for _, release := range releases.Items {
+			history.Versions = append(history.Versions, solutionVersion{Version: release.StatusData.SolutionVersion, UploadedAt: release.CreatedAt})
+			lines = append(lines, []string{solutionID, release.StatusData.SolutionVersion, release.CreatedAt})
+		}
+		histories = append(histories, history)
+	}
+	output.PrintCmdOutputCustom(cmd, api.CollectionResult[solutionVersionHistory]{Items: histories, Total: len(histories)},
+		&output.Table{Headers: []string{"Solution", "Version", "Uploaded At"}, Lines: lines})
+}
+
+// solutionField returns a function that extracts a top-level field of a solution object as a string
+func solutionField(name string) func(item map[string]any) string {
+	return func(item map[string]any) string {
+		return formatSolutionListValue(item[name])
+	}
+}
+
+// solutionDataField returns a function that extracts a field of a solution object's data as a string
+func solutionDataField(name string) func(item map[string]any) string {
+	return func(item map[string]any) string {
+		data, _ := item["data"].(map[string]any)
+		return formatSolutionListValue(data[name])
+	}
+}
+
+func formatSolutionListValue(v any) string {
+	switch typed := v.(type) {
+	case nil:
+		return ""
+	case []any:
+		values := make([]string, 0, len(typed))
+		for _, item := range typed {
+			values = append(values, fmt.Sprint(item))
+		}
+		return strings.Join(values, ", ")
+	default:
+		return fmt.Sprint(typed)
+	}
 }
 
 func getSolutionNames(prefix string) (names []string) {
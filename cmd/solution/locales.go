@@ -0,0 +1,121 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/apex/log"
+)
+
+// LocalesDirName is the name of the directory with the solution's locale bundles, one file per
+// locale (e.g., locales/en.yaml, locales/de.json), packaged with the solution
+const LocalesDirName = "locales"
+
+// defaultLocaleName is the default locale if the manifest doesn't specify one
+const defaultLocaleName = "en"
+
+// localeNameRegexp matches locale names, e.g., en, de or pt-BR
+var localeNameRegexp = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// localeBundles are the solution's locale bundles
+type localeBundles struct {
+	defaultLocale string
+	messages      map[string]map[string]string // locale -> message key -> text
+	files         []string                     // relative paths of the bundle files
+}
+
+// loadLocaleBundles reads the locale bundles in the solution's locales directory, which are flat
+// maps of message keys to texts, and verifies that they are complete: each bundle must have the
+// same keys as the default locale's bundle. It returns nil if the solution has no locales.
+func loadLocaleBundles(solutionPath string, manifest *Manifest) (*localeBundles, error) {
+	defaultLocale := manifest.DefaultLocale
+	if defaultLocale == "" {
+		defaultLocale = defaultLocaleName
+	}
+	entries, err := os.ReadDir(filepath.Join(solutionPath, LocalesDirName))
+	if errors.Is(err, os.ErrNotExist) {
+		if manifest.DefaultLocale != "" {
+			return nil, fmt.Errorf("the manifest specifies the default locale %q, but there is no %v directory", manifest.DefaultLocale, LocalesDirName)
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the %v directory: %w", LocalesDirName, err)
+	}
+
+	bundles := &localeBundles{defaultLocale: defaultLocale, messages: map[string]map[string]string{}}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		relPath := filepath.Join(LocalesDirName, entry.Name())
+		locale := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if !localeNameRegexp.MatchString(locale) {
+			return nil, fmt.Errorf("%v: %q is not a valid locale name, e.g., en or pt-BR", relPath, locale)
+		}
+		if _, found := bundles.messages[locale]; found {
+			return nil, fmt.Errorf("%v: locale %q has more than one bundle", relPath, locale)
+		}
+		doc, err := readObjectsFile(filepath.Join(solutionPath, relPath))
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", relPath, err)
+		}
+		messages := map[string]string{}
+		if err := remarshal(doc, &messages); err != nil {
+			return nil, fmt.Errorf("%v: a locale bundle must map message keys to texts: %w", relPath, err)
+		}
+		bundles.messages[locale] = messages
+		bundles.files = append(bundles.files, relPath)
+	}
+
+	// check completeness against the default locale
+	defaultMessages, found := bundles.messages[defaultLocale]
+	if !found {
+		return nil, fmt.Errorf("there is no bundle for the default locale %q in the %v directory", defaultLocale, LocalesDirName)
+	}
+	problems := []string{}
+	for _, locale := range sortedKeys(bundles.messages) {
+		messages := bundles.messages[locale]
+		for _, key := range sortedKeys(defaultMessages) {
+			if _, found := messages[key]; !found {
+				problems = append(problems, fmt.Sprintf("locale %q is missing message %q", locale, key))
+			}
+		}
+		for _, key := range sortedKeys(messages) {
+			if _, found := defaultMessages[key]; !found {
+				log.Warnf("Locale %q has message %q, which is not in the default locale %q", locale, key, defaultLocale)
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("the locale bundles are incomplete:\n  %v", strings.Join(problems, "\n  "))
+	}
+	return bundles, nil
+}
+
+// defaultMessages returns the texts of the default locale, substituted for the ${msg:key}
+// references in object files; it returns nil if the solution has no locales
+func (bundles *localeBundles) defaultMessages() map[string]string {
+	if bundles == nil {
+		return nil
+	}
+	return bundles.messages[bundles.defaultLocale]
+}
@@ -0,0 +1,290 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+// SolutionLockFileName is the name of the dependency lockfile in the solution root directory
+const SolutionLockFileName = "solution.lock"
+
+var solutionLockCmd = &cobra.Command{
+	Use:   "lock",
+	Args:  cobra.ExactArgs(0),
+	Short: "Resolve the solution's dependencies and record their versions in a lockfile",
+	Long: `This command resolves the dependencies declared in the solution manifest against the solutions
+available in the current tenant and records the exact resolved versions in the solution.lock file in the
+solution directory. The lockfile is meant to be version controlled; it is not included in the solution package.
+
+When the lockfile exists, "fsoc solution push" verifies the dependencies against it before uploading and fails
+if a dependency is missing from the tenant, was not locked, or its installed version is incompatible with the
+locked one. A version is compatible if it has the same major version and is not older than the locked version.
+
+Use --verify to check the dependencies against the lockfile without updating it.`,
+	Example: `  fsoc solution lock
+  fsoc solution lock -d mysolution
+  fsoc solution lock --verify`,
+	Run: solutionLock,
+}
+
+// SolutionLock is the content of the solution.lock file
+type SolutionLock struct {
+	Dependencies []LockedDependency `json:"dependencies" yaml:"dependencies"`
+}
+
+// LockedDependency is the resolved version of a dependency. The version is empty
+// for dependencies available in the tenant without an installation record (e.g., system solutions)
+type LockedDependency struct {
+	Name    string `json:"name" yaml:"name"`
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+}
+
+// DependencyCheck is the result of verifying a dependency against the lockfile
+type DependencyCheck struct {
+	Name     string `json:"name" yaml:"name"`
+	Locked   string `json:"locked" yaml:"locked"`
+	Resolved string `json:"resolved" yaml:"resolved"`
+	Status   string `json:"status" yaml:"status"`
+	ok       bool
+}
+
+// dependencyIsolationRegexp extracts the dependency name from pseudo-isolated references
+var dependencyIsolationRegexp = regexp.MustCompile(`\$dependency\('([^']+)'\)`)
+
+func getSolutionLockCmd() *cobra.Command {
+	solutionLockCmd.Flags().
+		StringP("directory", "d", "", "Path to the solution root directory (defaults to current dir)")
+
+	solutionLockCmd.Flags().
+		Bool("verify", false, "Verify the dependencies against the lockfile without updating it")
+
+	return solutionLockCmd
+}
+
+func solutionLock(cmd *cobra.Command, args []string) {
+	solutionRootDirectory, _ := cmd.Flags().GetString("directory")
+	if solutionRootDirectory == "" {
+		solutionRootDirectory = "."
+	}
+	solutionRootDirectory = absolutizePath(solutionRootDirectory)
+	manifest, err := getSolutionManifest(solutionRootDirectory)
+	if err != nil {
+		log.Fatalf("Failed to read the solution manifest: %v", err)
+	}
+
+	if verify, _ := cmd.Flags().GetBool("verify"); verify {
+		if _, err := os.Stat(filepath.Join(solutionRootDirectory, SolutionLockFileName)); err != nil {
+			log.Fatalf("No %v file found in %q; use fsoc solution lock to create it", SolutionLockFileName, solutionRootDirectory)
+		}
+		if err := verifySolutionLock(cmd, solutionRootDirectory, manifest); err != nil {
+			log.Fatal(err.Error())
+		}
+		output.PrintCmdStatus(cmd, fmt.Sprintf("All dependencies match %v\n", SolutionLockFileName))
+		return
+	}
+
+	lock := SolutionLock{Dependencies: []LockedDependency{}}
+	missing := []string{}
+	for _, name := range getDependencyNames(manifest) {
+		version, found, err := resolveDependencyVersion(name)
+		if err != nil {
+			log.Fatalf("Failed to resolve dependency %q: %v", name, err)
+		}
+		if !found {
+			missing = append(missing, name)
+			continue
+		}
+		lock.Dependencies = append(lock.Dependencies, LockedDependency{Name: name, Version: version})
+	}
+	if len(missing) > 0 {
+		log.Fatalf("Dependencies not available in the tenant: %v", missing)
+	}
+
+	if err := writeSolutionLock(solutionRootDirectory, &lock); err != nil {
+		log.Fatalf("Failed to write %v: %v", SolutionLockFileName, err)
+	}
+	lines := [][]string{}
+	for _, dep := range lock.Dependencies {
+		lines = append(lines, []string{dep.Name, dep.Version})
+	}
+	output.PrintCmdOutputCustom(cmd, lock, &output.Table{Headers: []string{"Name", "Version"}, Lines: lines})
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Locked %d dependencies in %v\n", len(lock.Dependencies), filepath.Join(solutionRootDirectory, SolutionLockFileName)))
+}
+
+// getDependencyNames returns the sorted names of the manifest's dependencies,
+// with pseudo-isolated references replaced by the dependency's name
+func getDependencyNames(manifest *Manifest) []string {
+	names := make([]string, 0, len(manifest.Dependencies))
+	for _, dep := range manifest.Dependencies {
+		if m := dependencyIsolationRegexp.FindStringSubmatch(dep); m != nil {
+			dep = m[1]
+		}
+		names = append(names, dep)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveDependencyVersion returns the latest successfully installed version of a solution
+// in the tenant. found is false if the solution is not available in the tenant.
+func resolveDependencyVersion(name string) (version string, found bool, err error) {
+	headers := getHeaders()
+	filter := fmt.Sprintf(`data.solutionName eq "%s" and data.isSuccessful eq "true"`, name)
+	query := fmt.Sprintf("?order=%s&filter=%s&max=1", url.QueryEscape("desc"), url.QueryEscape(filter))
+	var res ResponseBlob
+	if err := api.JSONGet(fmt.Sprintf(getSolutionInstallUrl(), query), &res, &api.Options{Headers: headers}); err != nil {
+		return "", false, err
+	}
+	if len(res.Items) > 0 {
+		return res.Items[0].StatusData.SolutionVersion, true, nil
+	}
+
+	// solutions without installation records (e.g., system solutions) are checked for availability only
+	_, err = getExtensibilitySolutionObject(getSolutionObjectUrl(name), headers)
+	var httpErr *api.HttpStatusError
+	switch {
+	case err == nil:
+		return "", true, nil
+	case errors.As(err, &httpErr) && httpErr.StatusCode == 404:
+		return "", false, nil
+	default:
+		return "", false, err
+	}
+}
+
+// checkSolutionLock verifies the manifest's dependencies against the lockfile and the tenant.
+// It returns nil, nil if the solution has no lockfile.
+func checkSolutionLock(solutionRoot string, manifest *Manifest) ([]DependencyCheck, error) {
+	lock, err := readSolutionLock(solutionRoot)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %v: %w", SolutionLockFileName, err)
+	}
+	locked := map[string]string{}
+	for _, dep := range lock.Dependencies {
+		locked[dep.Name] = dep.Version
+	}
+
+	checks := []DependencyCheck{}
+	for _, name := range getDependencyNames(manifest) {
+		check := DependencyCheck{Name: name}
+		lockedVersion, isLocked := locked[name]
+		if !isLocked {
+			check.Status = "not locked; run fsoc solution lock"
+			checks = append(checks, check)
+			continue
+		}
+		check.Locked = lockedVersion
+		version, found, err := resolveDependencyVersion(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve dependency %q: %w", name, err)
+		}
+		check.Resolved = version
+		switch {
+		case !found:
+			check.Status = "missing in tenant"
+		case lockedVersion == "" || version == lockedVersion:
+			check.Status, check.ok = "ok", true
+		default:
+			check.Status, check.ok = getVersionCompatibility(version, lockedVersion)
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// getVersionCompatibility checks if the installed version is compatible with the locked one
+func getVersionCompatibility(installed string, locked string) (string, bool) {
+	installedVersion, err := semver.NewVersion(installed)
+	if err != nil {
+		return fmt.Sprintf("invalid installed version: %v", err), false
+	}
+	lockedVersion, err := semver.NewVersion(locked)
+	if err != nil {
+		return fmt.Sprintf("invalid locked version: %v", err), false
+	}
+	switch {
+	case installedVersion.Major() != lockedVersion.Major():
+		return "incompatible major version", false
+	case installedVersion.LessThan(lockedVersion):
+		return "older than locked", false
+	default:
+		return "ok (newer)", true
+	}
+}
+
+// verifySolutionLock checks the dependencies against the lockfile, if present, and
+// displays a report if any dependency fails the check
+func verifySolutionLock(cmd *cobra.Command, solutionRoot string, manifest *Manifest) error {
+	checks, err := checkSolutionLock(solutionRoot, manifest)
+	if err != nil {
+		return err
+	}
+	nFailed := 0
+	lines := [][]string{}
+	for _, check := range checks {
+		if !check.ok {
+			nFailed++
+		}
+		lines = append(lines, []string{check.Name, check.Locked, check.Resolved, check.Status})
+	}
+	if nFailed == 0 {
+		log.WithField("dependencies", len(checks)).Info("Verified dependencies against the lockfile")
+		return nil
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []DependencyCheck `json:"items"`
+		Total int               `json:"total"`
+	}{checks, len(checks)}, &output.Table{Headers: []string{"Name", "Locked", "Resolved", "Status"}, Lines: lines})
+	return fmt.Errorf("%d dependencies failed verification against %v", nFailed, SolutionLockFileName)
+}
+
+func readSolutionLock(solutionRoot string) (*SolutionLock, error) {
+	data, err := os.ReadFile(filepath.Join(solutionRoot, SolutionLockFileName))
+	if err != nil {
+		return nil, err
+	}
+	var lock SolutionLock
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
+
+func writeSolutionLock(solutionRoot string, lock *SolutionLock) error {
+	data, err := yaml.Marshal(lock)
+	if err != nil {
+		return err
+	}
+	header := "# Generated by fsoc solution lock; do not edit\n"
+	return os.WriteFile(filepath.Join(solutionRoot, SolutionLockFileName), append([]byte(header), data...), 0o644)
+}
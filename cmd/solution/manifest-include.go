@@ -0,0 +1,143 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// manifestFragment is a part of the manifest, in a file included with the manifest's includes
+// field; the paths in it are relative to the solution root directory, as in the manifest
+type manifestFragment struct {
+	Dependencies []string       `json:"dependencies,omitempty" yaml:"dependencies,omitempty"`
+	Objects      []ComponentDef `json:"objects,omitempty" yaml:"objects,omitempty"`
+	Types        []string       `json:"types,omitempty" yaml:"types,omitempty"`
+	files        []string       // relative paths of the included files
+}
+
+// mergeManifestIncludes reads the files matching the manifest's includes patterns, in order,
+// and appends their dependencies, objects and types to the manifest. The merged content is
+// recorded in the manifest, so that it is not written back into the manifest when it is saved.
+func mergeManifestIncludes(solutionPath string, manifest *Manifest) error {
+	if len(manifest.Includes) == 0 {
+		return nil
+	}
+	included := &manifestFragment{}
+	seen := map[string]bool{}
+	for _, compDef := range manifest.Objects {
+		seen[compDef.ObjectsFile+"\x00"+compDef.ObjectsDir] = true
+	}
+
+	for _, pattern := range manifest.Includes {
+		if !filepath.IsLocal(filepath.FromSlash(pattern)) {
+			return fmt.Errorf("include %q is outside of the solution directory", pattern)
+		}
+		matches, err := filepath.Glob(filepath.Join(solutionPath, filepath.FromSlash(pattern)))
+		if err != nil {
+			return fmt.Errorf("invalid include pattern %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("include %q does not match any file", pattern)
+		}
+		slices.Sort(matches)
+		for _, match := range matches {
+			relPath, err := filepath.Rel(solutionPath, match)
+			if err != nil {
+				return err
+			}
+			if slices.Contains(included.files, relPath) {
+				continue // matched by several patterns
+			}
+			fragment, err := readManifestFragment(match)
+			if err != nil {
+				return fmt.Errorf("failed to read included manifest file %q: %w", relPath, err)
+			}
+			for _, compDef := range fragment.Objects {
+				key := compDef.ObjectsFile + "\x00" + compDef.ObjectsDir
+				if seen[key] {
+					return fmt.Errorf("included manifest file %q: objects %q are already listed in the manifest", relPath, compDef.ObjectsFile+compDef.ObjectsDir)
+				}
+				seen[key] = true
+			}
+			for _, dependency := range fragment.Dependencies {
+				if !slices.Contains(manifest.Dependencies, dependency) {
+					manifest.Dependencies = append(manifest.Dependencies, dependency)
+					included.Dependencies = append(included.Dependencies, dependency)
+				}
+			}
+			for _, typeFile := range fragment.Types {
+				if !slices.Contains(manifest.Types, typeFile) {
+					manifest.Types = append(manifest.Types, typeFile)
+					included.Types = append(included.Types, typeFile)
+				}
+			}
+			manifest.Objects = append(manifest.Objects, fragment.Objects...)
+			included.Objects = append(included.Objects, fragment.Objects...)
+			included.files = append(included.files, relPath)
+		}
+	}
+	manifest.included = included
+	return nil
+}
+
+// readManifestFragment parses an included manifest file, in JSON or YAML; unknown fields,
+// including nested includes, are rejected
+func readManifestFragment(path string) (*manifestFragment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fragment := &manifestFragment{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data)) // json is a subset of yaml
+	decoder.KnownFields(true)
+	if err := decoder.Decode(fragment); err != nil && err != io.EOF { // an empty file is an empty fragment
+		return nil, err
+	}
+	return fragment, nil
+}
+
+// withoutIncluded returns a copy of the manifest without the dependencies, objects and types
+// merged from its included files, for saving the manifest
+func (manifest *Manifest) withoutIncluded() *Manifest {
+	if manifest.included == nil {
+		return manifest
+	}
+	own := *manifest
+	own.included = nil
+	own.Dependencies = slices.DeleteFunc(slices.Clone(manifest.Dependencies), func(dependency string) bool {
+		return slices.Contains(manifest.included.Dependencies, dependency)
+	})
+	own.Types = slices.DeleteFunc(slices.Clone(manifest.Types), func(typeFile string) bool {
+		return slices.Contains(manifest.included.Types, typeFile)
+	})
+	own.Objects = nil
+	remaining := slices.Clone(manifest.included.Objects)
+	for _, compDef := range manifest.Objects {
+		if i := slices.IndexFunc(remaining, func(c ComponentDef) bool { return reflect.DeepEqual(c, compDef) }); i >= 0 {
+			remaining = slices.Delete(remaining, i, i+1)
+			continue
+		}
+		own.Objects = append(own.Objects, compDef)
+	}
+	return &own
+}
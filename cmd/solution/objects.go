@@ -0,0 +1,188 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// manifestObjectsFile is a parsed file with objects referenced from the manifest
+type manifestObjectsFile struct {
+	path    string // relative to the solution root
+	objType string
+	objects []any // files with a single object are represented as a list of one
+}
+
+// loadManifestObjects reads all object files referenced in the manifest, in the order
+// of the manifest's component definitions. Files are parsed concurrently. Files that fail
+// to parse are skipped and the errors are returned along with the successfully parsed files.
+func loadManifestObjects(root string, manifest *Manifest) ([]manifestObjectsFile, []error) {
+	listed := []manifestObjectsFile{}
+	errs := []error{}
+
+	// list the files first, then parse them concurrently
+	add := func(path string, objType string) {
+		listed = append(listed, manifestObjectsFile{path: path, objType: objType})
+	}
+	for _, compDef := range manifest.Objects {
+		if compDef.ObjectsFile != "" {
+			add(compDef.ObjectsFile, compDef.Type)
+		}
+		if compDef.ObjectsDir != "" {
+			relPaths, _, err := listObjectsDir(root, compDef)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%v: %w", compDef.ObjectsDir, err))
+			}
+			for _, relPath := range relPaths {
+				add(relPath, compDef.Type)
+			}
+		}
+	}
+
+	type parseResult struct {
+		objects []any
+		err     error
+	}
+	results := parallelMap(listed, func(file manifestObjectsFile) parseResult {
+		doc, err := readObjectsFile(filepath.Join(root, file.path))
+		if err != nil {
+			return parseResult{err: fmt.Errorf("%v: %w", file.path, err)}
+		}
+		objects, isArray := doc.([]any)
+		if !isArray {
+			objects = []any{doc}
+		}
+		return parseResult{objects: objects}
+	})
+	files := []manifestObjectsFile{}
+	for i, file := range listed {
+		if results[i].err != nil {
+			errs = append(errs, results[i].err)
+			continue
+		}
+		file.objects = results[i].objects
+		files = append(files, file)
+	}
+	return files, errs
+}
+
+// listObjectsDir returns the paths (relative to root) of the object files in a component
+// definition's objectsDir: by default, all files with a JSON or YAML extension or, if the
+// include field is set, the files matching any of its glob patterns; files matching any of the
+// exclude field's patterns are skipped. Patterns are matched against the path relative to
+// objectsDir, using "/" as the separator; "**" matches any number of directories, e.g.,
+// "**/*.json" matches all JSON files and "**/*_test.json" all JSON files ending with _test.
+// Hidden files and directories (such as editor backups) are never selected.
+// It also returns the files that are not selected but would be read as objects by the
+// platform (i.e., excluded files and unselected files with a JSON or YAML extension), which
+// are not packaged.
+func listObjectsDir(root string, compDef ComponentDef) ([]string, []string, error) {
+	for _, pattern := range append(slices.Clone(compDef.Include), compDef.Exclude...) {
+		if _, err := path.Match(strings.ReplaceAll(pattern, "/", ""), ""); err != nil {
+			return nil, nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	selected := []string{}
+	omitted := []string{}
+	dir := filepath.Join(root, compDef.ObjectsDir)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !isAllowedPath(root, path, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		dirRelPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		dirRelPath = filepath.ToSlash(dirRelPath)
+		hidden := false
+		for _, element := range strings.Split(dirRelPath, "/") {
+			hidden = hidden || strings.HasPrefix(element, ".")
+		}
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case isSelectedObjectsFile(compDef, dirRelPath) && !hidden:
+			selected = append(selected, relPath)
+		case matchAnyGlob(compDef.Exclude, dirRelPath) || isObjectsFile(path):
+			omitted = append(omitted, relPath)
+		}
+		return nil
+	})
+	return selected, omitted, err
+}
+
+// isSelectedObjectsFile returns true if the slash-separated path, relative to the component
+// definition's objectsDir, is selected by its include and exclude patterns
+func isSelectedObjectsFile(compDef ComponentDef, dirRelPath string) bool {
+	included := isObjectsFile(dirRelPath)
+	if len(compDef.Include) > 0 {
+		included = matchAnyGlob(compDef.Include, dirRelPath)
+	}
+	return included && !matchAnyGlob(compDef.Exclude, dirRelPath)
+}
+
+// matchAnyGlob returns true if the slash-separated path matches any of the glob patterns
+func matchAnyGlob(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob returns true if the slash-separated path matches the pattern, in which "**"
+// matches any number of path elements and the other elements are matched with path.Match
+func matchGlob(pattern string, name string) bool {
+	return matchGlobElements(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchGlobElements(pattern []string, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchGlobElements(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], name[0]); !matched {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
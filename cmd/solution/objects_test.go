@@ -0,0 +1,207 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"*.json", "host.json", true},
+		{"*.json", "host.yaml", false},
+		{"*.json", "model/host.json", false},
+		{"model/*.json", "model/host.json", true},
+		{"model/*.json", "other/host.json", false},
+		{"**/*.json", "host.json", true},
+		{"**/*.json", "model/host.json", true},
+		{"**/*.json", "model/nested/host.json", true},
+		{"**/*_test.json", "model/host_test.json", true},
+		{"**/*_test.json", "model/host.json", false},
+		{"model/**", "model/host.json", true},
+		{"model/**", "model/nested/host.json", true},
+		{"model/**", "other/host.json", false},
+		{"model/**/host.json", "model/host.json", true},
+		{"model/**/host.json", "model/a/b/host.json", true},
+		{"model/**/host.json", "model/a/b/other.json", false},
+		{"**", "model/host.json", true},
+		{"host.json", "host.json", true},
+		{"host.json", "model/host.json", false},
+		{"h?st.[jy]son", "host.json", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.match, matchGlob(tt.pattern, tt.name))
+		})
+	}
+}
+
+func TestMatchGlobElements(t *testing.T) {
+	tests := []struct {
+		pattern []string
+		name    []string
+		match   bool
+	}{
+		{[]string{}, []string{}, true},
+		{[]string{}, []string{"host.json"}, false},
+		{[]string{"*"}, []string{}, false},
+		{[]string{"**"}, []string{}, true},
+		{[]string{"**", "**"}, []string{"a", "b"}, true},
+		{[]string{"**", "b", "**"}, []string{"a", "b", "c"}, true},
+		{[]string{"**", "b", "**"}, []string{"a", "c"}, false},
+		{[]string{"a", "*"}, []string{"a", "b", "c"}, false},
+		{[]string{"[", "b"}, []string{"a", "b"}, false}, // invalid patterns do not match
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.pattern, "/")+" "+strings.Join(tt.name, "/"), func(t *testing.T) {
+			assert.Equal(t, tt.match, matchGlobElements(tt.pattern, tt.name))
+		})
+	}
+}
+
+func TestListObjectsDir(t *testing.T) {
+	root := createTestSolution(t, []string{
+		"objects/b.json",
+		"objects/a.yaml",
+		"objects/c.yml",
+		"objects/readme.md",
+		"objects/host_test.json",
+		"objects/model/z.json",
+		"objects/model/host_test.json",
+		"objects/model/nested/y.json",
+		"objects/.backup/old.json",
+		"objects/.host.json",
+		"other/elsewhere.json",
+	})
+
+	tests := []struct {
+		name     string
+		compDef  ComponentDef
+		selected []string
+		omitted  []string
+		err      bool
+	}{
+		{
+			name:    "default",
+			compDef: ComponentDef{ObjectsDir: "objects"},
+			selected: []string{
+				"objects/a.yaml",
+				"objects/b.json",
+				"objects/c.yml",
+				"objects/host_test.json",
+				"objects/model/host_test.json",
+				"objects/model/nested/y.json",
+				"objects/model/z.json",
+			},
+			omitted: []string{"objects/.backup/old.json", "objects/.host.json"},
+		},
+		{
+			name:     "include top level",
+			compDef:  ComponentDef{ObjectsDir: "objects", Include: []string{"*.json"}},
+			selected: []string{"objects/b.json", "objects/host_test.json"},
+			omitted: []string{
+				"objects/.backup/old.json",
+				"objects/.host.json",
+				"objects/a.yaml",
+				"objects/c.yml",
+				"objects/model/host_test.json",
+				"objects/model/nested/y.json",
+				"objects/model/z.json",
+			},
+		},
+		{
+			name:    "include any depth",
+			compDef: ComponentDef{ObjectsDir: "objects", Include: []string{"**/*.json"}},
+			selected: []string{
+				"objects/b.json",
+				"objects/host_test.json",
+				"objects/model/host_test.json",
+				"objects/model/nested/y.json",
+				"objects/model/z.json",
+			},
+			omitted: []string{"objects/.backup/old.json", "objects/.host.json", "objects/a.yaml", "objects/c.yml"},
+		},
+		{
+			name:    "exclude",
+			compDef: ComponentDef{ObjectsDir: "objects", Exclude: []string{"**/*_test.json"}},
+			selected: []string{
+				"objects/a.yaml",
+				"objects/b.json",
+				"objects/c.yml",
+				"objects/model/nested/y.json",
+				"objects/model/z.json",
+			},
+			omitted: []string{"objects/.backup/old.json", "objects/.host.json", "objects/host_test.json", "objects/model/host_test.json"},
+		},
+		{
+			name: "include and exclude",
+			compDef: ComponentDef{
+				ObjectsDir: "objects",
+				Include:    []string{"model/**", "*.md"},
+				Exclude:    []string{"**/*_test.json", "model/nested/**"},
+			},
+			selected: []string{"objects/model/z.json", "objects/readme.md"},
+			omitted: []string{
+				"objects/.backup/old.json",
+				"objects/.host.json",
+				"objects/a.yaml",
+				"objects/b.json",
+				"objects/c.yml",
+				"objects/host_test.json",
+				"objects/model/host_test.json",
+				"objects/model/nested/y.json",
+			},
+		},
+		{
+			name:    "invalid pattern",
+			compDef: ComponentDef{ObjectsDir: "objects", Include: []string{"[*.json"}},
+			err:     true,
+		},
+		{
+			name:    "missing directory",
+			compDef: ComponentDef{ObjectsDir: "missing"},
+			err:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, omitted, err := listObjectsDir(root, tt.compDef)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			// files are listed in lexical order, so that packaging is reproducible
+			assert.Equal(t, fromSlashAll(tt.selected), selected)
+			assert.Equal(t, fromSlashAll(tt.omitted), omitted)
+		})
+	}
+}
+
+func fromSlashAll(paths []string) []string {
+	result := []string{}
+	for _, path := range paths {
+		result = append(result, filepath.FromSlash(path))
+	}
+	return result
+}
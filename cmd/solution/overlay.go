@@ -0,0 +1,253 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apex/log"
+
+	"github.com/cisco-open/fsoc/cmdkit/jsondoc"
+)
+
+// OverlaysDirName is the solution directory containing the environment overlays, one
+// subdirectory per environment (e.g., overlays/prod)
+const OverlaysDirName = "overlays"
+
+// overlayPatchSuffix marks the JSON patch (RFC 6902) files in an overlay, e.g., host.patch.json
+const overlayPatchSuffix = ".patch"
+
+// overlayDeleteDirective is the field that removes a named object when merging an overlay,
+// e.g., {"name": "host", "$patch": "delete"}
+const overlayDeleteDirective = "$patch"
+
+// overlaySolution creates a copy of the solution with the overlay for the environment applied.
+// The overlay directory mirrors the solution directory; each file in it is applied to the
+// solution file with the same path and name, regardless of the file's extension:
+//   - a JSON patch file (name.patch.json or name.patch.yaml) is applied as an RFC 6902 patch
+//   - another JSON or YAML file is merged into the solution file, or added if there is none
+//   - any other file replaces or is added to the solution's files
+//
+// The copy has the same directory name as the solution, inside a new temporary directory,
+// and the caller should remove the copy's parent directory when done.
+func overlaySolution(solutionPath string, env string) (string, error) {
+	overlayPath := filepath.Join(solutionPath, OverlaysDirName, env)
+	if info, err := os.Stat(overlayPath); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("overlay %q not found: expected directory %q", env, filepath.Join(OverlaysDirName, env))
+	}
+
+	// copy the solution without its overlays
+	stagingRoot, err := os.MkdirTemp("", "fsoc")
+	if err != nil {
+		return "", fmt.Errorf("failed to create a temporary directory: %w", err)
+	}
+	overlaidPath := filepath.Join(stagingRoot, filepath.Base(solutionPath))
+	err = filepath.Walk(solutionPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(solutionPath, path)
+		if err != nil {
+			return err
+		}
+		if !isAllowedPath(solutionPath, path, info) || relPath == OverlaysDirName {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(overlaidPath, relPath), 0o755)
+		}
+		return copyLocalFile(path, filepath.Join(overlaidPath, relPath))
+	})
+
+	// apply the overlay's files
+	if err == nil {
+		log.WithFields(log.Fields{"env": env, "overlay_dir": overlayPath}).Info("Applying solution overlay")
+		err = filepath.Walk(overlayPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || !isAllowedPath(overlayPath, path, info) {
+				return nil
+			}
+			relPath, err := filepath.Rel(overlayPath, path)
+			if err != nil {
+				return err
+			}
+			if err := applyOverlayFile(path, overlaidPath, relPath); err != nil {
+				return fmt.Errorf("%v: %w", filepath.Join(OverlaysDirName, env, relPath), err)
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		os.RemoveAll(stagingRoot)
+		return "", fmt.Errorf("failed to apply overlay %q: %w", env, err)
+	}
+	return overlaidPath, nil
+}
+
+// applyOverlayFile applies a file from an overlay directory to the overlaid solution copy
+func applyOverlayFile(overlayFile string, overlaidPath string, relPath string) error {
+	targetPath := filepath.Join(overlaidPath, relPath)
+	if !isObjectsFile(overlayFile) {
+		if err := os.MkdirAll(filepath.Dir(targetPath), 0o755); err != nil {
+			return err
+		}
+		return copyLocalFile(overlayFile, targetPath)
+	}
+
+	stem, isPatch := strings.CutSuffix(strings.TrimSuffix(relPath, filepath.Ext(relPath)), overlayPatchSuffix)
+	patch, err := readObjectsFile(overlayFile)
+	if err != nil {
+		return err
+	}
+	basePath := findOverlayTarget(overlaidPath, stem)
+	if basePath == "" {
+		if isPatch {
+			return fmt.Errorf("no file %v.json, .yaml or .yml to patch", stem)
+		}
+		if err := os.MkdirAll(filepath.Dir(targetPath), 0o755); err != nil {
+			return err
+		}
+		return copyLocalFile(overlayFile, targetPath)
+	}
+
+	doc, err := readObjectsFile(basePath)
+	if err == nil {
+		err = remarshal(doc, &doc) // use the JSON value types, so that values compare regardless of format
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %v: %w", stem, err)
+	}
+	if isPatch {
+		var operations []jsondoc.PatchOperation
+		if err := remarshal(patch, &operations); err != nil {
+			return fmt.Errorf("invalid JSON patch, expected an array of operations: %w", err)
+		}
+		if doc, err = jsondoc.ApplyPatchOperations(doc, operations); err != nil {
+			return err
+		}
+	} else {
+		if err := remarshal(patch, &patch); err != nil {
+			return err
+		}
+		doc = mergeOverlay(doc, patch)
+	}
+
+	f, err := os.Create(basePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	format := FileFormatJSON
+	if isYamlFile(basePath) {
+		format = FileFormatYAML
+	}
+	return writeComponent(doc, f, format)
+}
+
+// findOverlayTarget returns the path of the JSON or YAML file with the path and name (without
+// extension), or "" if there is no such file
+func findOverlayTarget(solutionPath string, stem string) string {
+	for ext := range extensionMap {
+		path := filepath.Join(solutionPath, stem+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// mergeOverlay merges an overlay into a value, similarly to a Kubernetes strategic merge patch:
+// objects are merged field by field, with null removing the field; arrays of named objects are
+// merged by name, with the "$patch": "delete" field removing the named object; a single named
+// object is merged into the matching object of an array (e.g., in an objects file); any other
+// value is replaced.
+func mergeOverlay(base any, overlay any) any {
+	switch overlay := overlay.(type) {
+	case map[string]any:
+		if items, isArray := base.([]any); isArray && isNamedObject(overlay) {
+			return mergeNamedObjects(items, []any{overlay})
+		}
+		baseMap, isMap := base.(map[string]any)
+		if !isMap {
+			baseMap = map[string]any{}
+		}
+		for key, value := range overlay {
+			switch existing, found := baseMap[key]; {
+			case value == nil:
+				delete(baseMap, key)
+			case found:
+				baseMap[key] = mergeOverlay(existing, value)
+			default:
+				baseMap[key] = mergeOverlay(nil, value)
+			}
+		}
+		return baseMap
+	case []any:
+		if items, isArray := base.([]any); isArray && len(items) > 0 && allNamedObjects(items) && allNamedObjects(overlay) {
+			return mergeNamedObjects(items, overlay)
+		}
+	}
+	return overlay
+}
+
+// mergeNamedObjects merges overlay objects into the base objects with the same name, adding
+// the objects not in the base and removing the objects marked for deletion
+func mergeNamedObjects(items []any, overlay []any) []any {
+	for _, item := range overlay {
+		itemMap := item.(map[string]any)
+		name := itemMap["name"]
+		index := -1
+		for i, existing := range items {
+			if existing.(map[string]any)["name"] == name {
+				index = i
+				break
+			}
+		}
+		switch {
+		case itemMap[overlayDeleteDirective] == "delete":
+			if index >= 0 {
+				items = append(items[:index], items[index+1:]...)
+			}
+		case index >= 0:
+			items[index] = mergeOverlay(items[index], item)
+		default:
+			items = append(items, mergeOverlay(nil, item))
+		}
+	}
+	return items
+}
+
+func isNamedObject(v any) bool {
+	m, isMap := v.(map[string]any)
+	name, _ := m["name"].(string)
+	return isMap && name != ""
+}
+
+func allNamedObjects(items []any) bool {
+	for _, item := range items {
+		if !isNamedObject(item) {
+			return false
+		}
+	}
+	return true
+}
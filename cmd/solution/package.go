@@ -15,7 +15,6 @@
 package solution
 
 import (
-	"archive/zip"
 	"errors"
 	"fmt"
 	"io"
@@ -23,12 +22,14 @@ import (
 	"path/filepath"
 	"reflect"
 	"slices"
+	"sort"
 	"strings"
 
 	"github.com/apex/log"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 
+	"github.com/cisco-open/fsoc/cmdkit/archive"
 	"github.com/cisco-open/fsoc/config"
 	"github.com/cisco-open/fsoc/output"
 )
@@ -51,9 +52,77 @@ The following priority is available:
 2. A tag is defined in the FSOC_SOLUTION_TAG environment variable (ignores env file)
 3. An explicitly provided --env-file path
 4. Implicitly looking into env.json file in the solution directory (usually not version controlled)
+
+The package is reproducible: packaging the same solution directory content produces a byte-for-byte identical
+zip file (files are stored in a stable order, with fixed timestamps and normalized permissions). This allows
+signing and caching solution packages in CI pipelines.
+
+The package can be given a budget in the .fsoclint file: the maximum size of the archive, of any single object
+(JSON-encoded) and of the documentation images, and the maximum number of objects. A warning is displayed when a
+limit is almost reached (over 80%), and packaging (as well as pushing) fails when a limit is exceeded, before the
+archive is uploaded. The limits that are not set are only warned about when exceeded: by default, the archive
+should not exceed 10 MiB, any single object 1 MiB and images 1 MiB, and the solution should not have more than
+10000 objects. For example:
+
+  packageBudget:
+    maxArchiveSize: 20971520
+    maxObjectSize: 524288
+    maxObjects: 20000
+    maxImageSize: 2097152
+
+The readme referenced by the manifest, unless it is a URL, must be a file in the solution directory; it is
+packaged with the solution, as are the files in the docs directory. The relative links and images in the readme
+and in the markdown files of the docs directory must refer to packaged files of the solution, and images may not
+exceed the maxImageSize limit of the package budget, if set. Use --readme-description to set the
+solution description from the first paragraph of the readme when the manifest has no description.
+
+Objects of the solution's own types are validated against the jsonSchema of their type definition, and
+objects of dependency types against the type schemas vendored with "fsoc solution vendor", if any. Schema
+violations are reported with the JSON pointer of the offending value, e.g., "/spec/replicas", and fail packaging.
+
+The files of an objectsDir entry in the manifest are all JSON and YAML files in the directory and its
+subdirectories, except hidden files. The include and exclude fields of the entry select the files with glob
+patterns relative to the directory, in which "**" matches any number of subdirectories, e.g.:
+
+  {"type": "fmm:metric", "objectsDir": "objects/metrics", "include": ["**/*.json"], "exclude": ["**/*_test.json"]}
+
+Files that are not selected are not packaged, and the include and exclude fields are removed from the packaged
+manifest.
+
+A large manifest can be split into multiple files with the includes field of the manifest, listing files (or
+glob patterns) with dependencies, objects and types entries, e.g., one file per subsystem:
+
+  includes: ["manifests/*.yaml"]
+
+The entries of the included files are appended to the manifest's, in order, with paths relative to the solution
+root directory. The included files are merged into the packaged manifest and are not packaged themselves.
+
+A solution can ship texts in multiple languages with locale bundles in the locales directory: one JSON or YAML
+file per locale (e.g., locales/en.yaml, locales/de.yaml), mapping message keys to texts. Object files refer to
+the texts as ${msg:key}, e.g., in the displayName and description of dashui templates, and the references are
+replaced by the texts of the default locale, set with the defaultLocale field of the manifest ("en" by default).
+Every bundle must have all the messages of the default locale's bundle, otherwise packaging fails. The bundles are
+packaged with the solution, in JSON.
+
+Object and type files may be written in JSON or YAML (.yaml or .yml). YAML files are converted to JSON in the
+package, and the manifest references to them are updated accordingly; the solution directory is not modified.
+
+Environment overlays allow producing per-environment variants of a solution from one source tree. Each
+subdirectory of the overlays directory is an overlay, selected with --env (e.g., --env prod for overlays/prod),
+whose files are applied to the solution files with the same path and name (the extension may differ):
+  - name.patch.json or name.patch.yaml is applied as a JSON patch (RFC 6902), e.g.,
+    [{"op": "replace", "path": "/0/unit", "value": "ms"}]
+  - other JSON or YAML files are merged (strategic merge): fields are merged recursively and null removes a
+    field; arrays of objects with names are merged by name and {"name": "x", "$patch": "delete"} removes the
+    object named x; files without a match in the solution are added
+  - other files replace or are added to the solution files
+The overlays and build directories are not included in the package. The --env flag is also supported by the push, validate,
+diff and render commands.
 `,
-	Example: `  fsoc solution package --solution-bundle=../mysolution.zip
-  fsoc solution package -d mysolution --solution-bundle=/somepath/mysolution-1234.zip`,
+	Example: `  fsoc solution package --output mysolution.zip
+  fsoc solution package --solution-bundle=../mysolution.zip
+  fsoc solution package -d mysolution --solution-bundle=/somepath/mysolution-1234.zip
+  fsoc solution package --env prod --output mysolution-prod.zip`,
 	Run:         packageSolution,
 	Annotations: map[string]string{config.AnnotationForConfigBypass: ""},
 }
@@ -63,6 +132,9 @@ func getSolutionPackageCmd() *cobra.Command {
 	solutionPackageCmd.Flags().
 		String("solution-bundle", "", "Path to output directory or file to place solution zip into (defaults to temp dir)")
 
+	solutionPackageCmd.Flags().
+		String("output", "", "Path to the zip file to create; same as --solution-bundle")
+
 	solutionPackageCmd.Flags().
 		StringP("directory", "d", "", "Path to the solution root directory (defaults to current dir)")
 
@@ -75,12 +147,21 @@ func getSolutionPackageCmd() *cobra.Command {
 	solutionPackageCmd.Flags().
 		Bool("no-isolate", false, "Disable fsoc-supported solution isolation")
 	solutionPackageCmd.MarkFlagsMutuallyExclusive("tag", "stable", "env-file", "no-isolate")
+	solutionPackageCmd.MarkFlagsMutuallyExclusive("solution-bundle", "output")
+
+	solutionPackageCmd.Flags().
+		Bool("readme-description", false, "Set the solution description from the readme if the manifest has none")
+
+	addVariableFlags(solutionPackageCmd)
 
 	return solutionPackageCmd
 }
 
 func packageSolution(cmd *cobra.Command, args []string) {
 	outputFilePath, _ := cmd.Flags().GetString("solution-bundle")
+	if cmd.Flags().Changed("output") {
+		outputFilePath, _ = cmd.Flags().GetString("output")
+	}
 	solutionDirectoryPath, _ := cmd.Flags().GetString("directory")
 
 	// finalize solution path
@@ -107,6 +188,10 @@ func packageSolution(cmd *cobra.Command, args []string) {
 		log.Fatalf("Failed to read solution manifest: %v", err)
 	}
 
+	if len(manifest.Secrets) > 0 {
+		log.Fatalf("Solution %s declares secrets, which are provided only when pushing it; use fsoc solution push to deploy it", manifest.Name)
+	}
+
 	var message string
 	message = fmt.Sprintf("Packaging solution %s version %s with tag %s\n", manifest.Name, manifest.SolutionVersion, tag)
 	output.PrintCmdStatus(cmd, message)
@@ -127,7 +212,7 @@ func packageSolution(cmd *cobra.Command, args []string) {
 // If solutionPath is not specified, the current directory is assumed (it must contain the solution
 // manifest in its final form).
 func generateZip(cmd *cobra.Command, solutionPath string, outputPath string) *os.File {
-	var archive *os.File
+	var archiveFile *os.File
 	var err error
 	var archiveFileTemplate string
 	solutionName := filepath.Base(solutionPath)
@@ -146,69 +231,153 @@ func generateZip(cmd *cobra.Command, solutionPath string, outputPath string) *os
 		} else if err != nil && !errors.Is(err, os.ErrNotExist) {
 			log.Fatalf("Failed to access target path %q: %v", outputPath, err)
 		} // else treat as file path, possibly overwriting existing file
-		archive, err = os.Create(outputPath)
+		archiveFile, err = os.Create(outputPath)
 	} else {
 		archiveFileTemplate = fmt.Sprintf("%s*.zip", solutionName)
-		archive, err = os.CreateTemp("", archiveFileTemplate)
-		outputPath = archive.Name()
+		archiveFile, err = os.CreateTemp("", archiveFileTemplate)
+		outputPath = archiveFile.Name()
 	}
 	if err != nil {
 		log.Fatalf("failed to create file %s: %v", outputPath, err)
 		panic(err)
 	}
-	output.PrintCmdStatus(cmd, fmt.Sprintf("Creating solution zip: %q\n", archive.Name()))
-	log.WithField("path", archive.Name()).Info("Creating solution file")
-	defer archive.Close()
-	zipWriter := zip.NewWriter(archive)
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Creating solution zip: %q\n", archiveFile.Name()))
+	log.WithField("path", archiveFile.Name()).Info("Creating solution file")
+	defer archiveFile.Close()
+	zipWriter := archive.NewWriter(archiveFile)
 
 	// determine the solution directory's parent folder to start archiving from
 	solutionPath = absolutizePath(solutionPath)
+
+	// substitute template variables and convert YAML object files to JSON in a staged copy
+	stagedPath, err := stageSolution(solutionPath, "", getStageOptions(cmd))
+	if err != nil {
+		archiveFile.Close()
+		os.Remove(archiveFile.Name())
+		log.Fatalf("Failed to prepare solution for packaging: %v", err)
+	}
+	defer os.RemoveAll(filepath.Dir(stagedPath))
+
+	// the staged copy may contain the values of secrets, so it is removed along with the incomplete
+	// archive before exiting on errors, as log.Fatalf skips the deferred calls
+	fatalf := func(format string, args ...any) {
+		archiveFile.Close()
+		os.Remove(archiveFile.Name())
+		os.RemoveAll(filepath.Dir(stagedPath))
+		log.Fatalf(format, args...)
+	}
+
+	// check the package budget before the archive is created and uploaded
+	budget, err := loadPackageBudget(solutionPath)
+	if err != nil {
+		fatalf("Failed to load the package budget: %v", err)
+	}
+	problems, err := budget.checkObjectsBudget(stagedPath)
+	if err != nil {
+		fatalf("Failed to check the package budget: %v", err)
+	}
+	if len(problems) > 0 {
+		fatalf("%v", budgetError(problems))
+	}
+	stagedManifest, err := getSolutionManifest(stagedPath)
+	if err != nil {
+		fatalf("Failed to read the solution manifest: %v", err)
+	}
+	problems, err = checkSolutionDocs(stagedPath, stagedManifest, budget)
+	if err != nil {
+		fatalf("Failed to check the solution documentation: %v", err)
+	}
+	if len(problems) > 0 {
+		fatalf("The solution documentation has problems:\n  %v", strings.Join(problems, "\n  "))
+	}
+
+	// validate the objects of types with a JSON schema, to report violations before the upload
+	if errs := validateKnowledgeObjects(stagedPath, stagedManifest, filepath.Join(solutionPath, VendorDirName)); len(errs) > 0 {
+		output.PrintCmdStatus(cmd, getSolutionValidationErrorsString(len(errs), Errors{Items: errs, Total: len(errs)}))
+		fatalf("%d schema violation(s) found in objects of types with a JSON schema", len(errs))
+	}
+	solutionPath = stagedPath
 	solutionParentPath := filepath.Dir(solutionPath)
 
 	// switch cwd to the solution directory for archiving
 	fsocWorkingDir, err := os.Getwd()
 	if err != nil {
-		log.Fatalf("Couldn't get the current working directory: %v", err)
+		fatalf("Couldn't get the current working directory: %v", err)
 	}
 	err = os.Chdir(solutionParentPath)
 	if err != nil {
-		log.Fatalf("Couldn't switch working directory to solution root's parent directory %q: %v", solutionParentPath, err)
+		fatalf("Couldn't switch working directory to solution root's parent directory %q: %v", solutionParentPath, err)
 	}
 	defer func() {
 		// restore original working directory
 		err := os.Chdir(fsocWorkingDir)
 		if err != nil {
-			log.Fatalf("Couldn't switch working directory back to starting working directory: %v", err)
+			fatalf("Couldn't switch working directory back to starting working directory: %v", err)
 		}
 	}()
 
+	// collect the files first and add them in a stable order, so that the archive is reproducible
+	type zipEntry struct {
+		path string
+		info os.FileInfo
+	}
+	entries := []zipEntry{}
 	err = filepath.Walk(solutionName,
 		func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				return err
 			}
-			if isAllowedPath(path, info) {
-				addFileToZip(zipWriter, path, info)
+			if !isAllowedPath(solutionName, path, info) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
 			}
+			entries = append(entries, zipEntry{path, info})
 			return nil
 		})
 	if err != nil {
-		log.Fatalf("Error traversing the directory: %v", err)
+		fatalf("Error traversing the directory: %v", err)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return filepath.ToSlash(entries[i].path) < filepath.ToSlash(entries[j].path)
+	})
+	for _, entry := range entries {
+		if err := zipWriter.AddFile(entry.path, entry.path, entry.info); err != nil {
+			fatalf("Couldn't add file to archive: %v", err)
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		fatalf("Couldn't write archive: %v", err)
+	}
+	log.WithField("path", archiveFile.Name()).Info("Created a solution with path")
+
+	problems, err = budget.checkArchiveBudget(archiveFile.Name())
+	if err != nil {
+		fatalf("Failed to check the package budget: %v", err)
+	}
+	if len(problems) > 0 {
+		fatalf("%v", budgetError(problems))
 	}
-	zipWriter.Close()
-	log.WithField("path", archive.Name()).Info("Created a solution with path")
 
-	return archive
+	return archiveFile
 }
 
-func isAllowedPath(path string, info os.FileInfo) bool {
+// isAllowedPath returns whether a file or directory of the solution in solutionRoot is included in
+// its package
+func isAllowedPath(solutionRoot string, path string, info os.FileInfo) bool {
 	// blacklist files by adding them here.
-	excludeFiles := []string{".DS_Store", TagFileName} // .tag files should not be included in the zip
+	excludeFiles := []string{".DS_Store", TagFileName, LintConfigFileName, SolutionLockFileName, PushStateFileName, DevStateFileName} // .tag, .fsoclint, solution.lock, .fsocpush and .fsocdev files should not be included in the zip
 	// blacklist paths by adding them here.
 	excludePaths := []string{".git"}
 	allow := true
 
 	if info.IsDir() {
+		// vendored dependency schemas are for local use only; vendor directories elsewhere are the
+		// solution's own
+		if filepath.Clean(path) == filepath.Join(solutionRoot, VendorDirName) {
+			allow = false
+		}
 		// check for blacklisted dirs
 		for _, exclP := range excludePaths {
 			if strings.Contains(path, exclP) {
@@ -225,32 +394,6 @@ func isAllowedPath(path string, info os.FileInfo) bool {
 	return allow
 }
 
-func addFileToZip(zipWriter *zip.Writer, fileName string, info os.FileInfo) {
-	newFile, err := os.Open(fileName)
-	if err != nil {
-		log.Fatalf("Couldn't open file %q: %v", fileName, err)
-	}
-	defer newFile.Close()
-
-	if info.IsDir() {
-		fileName = fileName + string(os.PathSeparator)
-	}
-
-	fileName = filepath.ToSlash(fileName)
-
-	archWriter, err := zipWriter.Create(fileName)
-
-	if err != nil {
-		log.Fatalf("Couldn't create archive writer for file: %v", err)
-	}
-
-	if !info.IsDir() {
-		if _, err := io.Copy(archWriter, newFile); err != nil {
-			log.Fatalf("Couldn't write file to architve: %v", err)
-		}
-	}
-}
-
 func isSolutionPackageRoot(path string) bool {
 	_, err := getSolutionManifest(path)
 	if err != nil {
@@ -305,6 +448,11 @@ func getSolutionManifest(path string) (*Manifest, error) {
 		return nil, err
 	}
 
+	// Merge included manifest files
+	if err := mergeManifestIncludes(path, manifest); err != nil {
+		return nil, err
+	}
+
 	// Store manifest type
 	if jsonExists {
 		manifest.ManifestFormat = FileFormatJSON
@@ -0,0 +1,83 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createTestSolution writes the given files, with empty JSON objects as their content, under a
+// temporary solution root and returns its path
+func createTestSolution(t *testing.T, files []string) string {
+	root := t.TempDir()
+	for _, name := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte("{}"), 0o644))
+	}
+	return root
+}
+
+func TestIsAllowedPath(t *testing.T) {
+	root := createTestSolution(t, []string{
+		"manifest.json",
+		".fsoclint",
+		"vendor/dep/type.schema.json",
+		"objects/vendor/acme.json",
+		"objects/nested/vendor/acme.json",
+	})
+
+	tests := []struct {
+		path    string
+		allowed bool
+	}{
+		{"manifest.json", true},
+		{".fsoclint", false},
+		{"vendor", false},
+		{"objects/vendor", true},
+		{"objects/vendor/acme.json", true},
+		{"objects/nested/vendor", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			path := filepath.Join(root, filepath.FromSlash(tt.path))
+			info, err := os.Stat(path)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.allowed, isAllowedPath(root, path, info))
+		})
+	}
+}
+
+func TestListObjectsDirNestedVendor(t *testing.T) {
+	root := createTestSolution(t, []string{
+		"vendor/dep/type.schema.json",
+		"objects/vendor/acme.json",
+		"objects/nested/vendor/acme.json",
+		"objects/local.json",
+	})
+
+	selected, omitted, err := listObjectsDir(root, ComponentDef{ObjectsDir: "objects"})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		filepath.FromSlash("objects/local.json"),
+		filepath.FromSlash("objects/nested/vendor/acme.json"),
+		filepath.FromSlash("objects/vendor/acme.json"),
+	}, selected)
+	assert.Empty(t, omitted)
+}
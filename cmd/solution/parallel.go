@@ -0,0 +1,51 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"runtime"
+	"sync"
+)
+
+// parallelism is the number of files loaded, validated or rendered concurrently
+var parallelism = runtime.NumCPU()
+
+// parallelMap calls fn for each item using a pool of workers and returns the results
+// in the order of the items
+func parallelMap[T any, R any](items []T, fn func(T) R) []R {
+	return parallelMapN(items, parallelism, fn)
+}
+
+// parallelMapN is parallelMap with a pool of the given number of workers
+func parallelMapN[T any, R any](items []T, workers int, fn func(T) R) []R {
+	results := make([]R, len(items))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(max(workers, 1), len(items)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = fn(items[i])
+			}
+		}()
+	}
+	for i := range items {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
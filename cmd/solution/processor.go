@@ -268,7 +268,7 @@ func (s *SolutionDirectoryContents) readContents(rootPath string) error {
 			if strings.HasPrefix(relPath, "..") {
 				return fmt.Errorf("found %v %q that not under the root %q: %q", entryType, path, rootPath, relPath)
 			}
-			if !isAllowedPath(path, info) {
+			if !isAllowedPath(rootPath, path, info) {
 				log.Warnf("Found %v %q which cannot be bundled; it will still be processed", entryType, relPath)
 			}
 
@@ -387,7 +387,7 @@ func (s *SolutionDirectoryContents) annotateFiles() error {
 	}
 
 	// process files in the root directory
-	hiddenFiles := []string{"manifest.json", "manifest.yaml", "manifest.yml", ".tag"}
+	hiddenFiles := []string{"manifest.json", "manifest.yaml", "manifest.yml", TagFileName, LintConfigFileName, SolutionLockFileName}
 	for fileIndex := 0; fileIndex < len(s.RootFiles); fileIndex++ {
 		f := &s.RootFiles[fileIndex]
 		if slices.Contains(hiddenFiles, f.Name) {
@@ -0,0 +1,139 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+var solutionPromoteCmd = &cobra.Command{
+	Use:   "promote <solution-name> --from <tag> --to <tag>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Promote a solution version from one release stage to another",
+	Long: `This command moves the solution version currently released with one tag (release stage) to another tag,
+e.g., from dev to stable, by downloading the solution archive from the source stage and pushing the same archive
+to the target stage. The archive is inspected before it is pushed (see "fsoc solution inspect"); an archive
+with unsafe paths is not promoted.
+
+A typical release workflow with stages is:
+  1. push new versions to the dev stage:            fsoc solution push --tag dev --bump
+  2. test them in a tenant subscribed to dev:       fsoc solution subscribe mysolution --tag dev
+  3. promote a tested version to stable:            fsoc solution promote mysolution --from dev --to stable --version 1.2.3
+  4. subscribe tenants to the stable stage, pinning the expected version:
+                                                    fsoc solution subscribe mysolution --tag stable --version 1.2.3
+
+Use --version to make sure the version being promoted is the tested one; the command fails if the source stage
+has a different version. Use "fsoc solution list --all-versions" to see the versions released for a solution.`,
+	Example: `  fsoc solution promote mysolution --from dev --to stable
+  fsoc solution promote mysolution --from dev --to stable --version 1.2.3 --no-wait`,
+	Run:              promoteSolution,
+	TraverseChildren: true,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		config.SetActiveProfile(cmd, args, false)
+		return getSolutionNames(toComplete), cobra.ShellCompDirectiveDefault
+	},
+}
+
+func getSolutionPromoteCmd() *cobra.Command {
+	solutionPromoteCmd.Flags().
+		String("from", "dev", "Tag of the release stage to promote from")
+
+	solutionPromoteCmd.Flags().
+		String("to", "stable", "Tag of the release stage to promote to")
+
+	solutionPromoteCmd.Flags().
+		String("version", "", "Version expected in the source stage; the command fails if the stage has a different version")
+
+	solutionPromoteCmd.Flags().IntP("wait", "w", 300, "Wait (in seconds) for the promoted solution to be installed; 0 waits indefinitely")
+	solutionPromoteCmd.Flag("wait").NoOptDefVal = "300"
+
+	solutionPromoteCmd.Flags().
+		Bool("no-wait", false, "Don't wait for the promoted solution to be installed")
+	solutionPromoteCmd.MarkFlagsMutuallyExclusive("wait", "no-wait")
+
+	return solutionPromoteCmd
+}
+
+func promoteSolution(cmd *cobra.Command, args []string) {
+	name := getSolutionNameFromArgs(cmd, args, "")
+	fromTag, _ := cmd.Flags().GetString("from")
+	toTag, _ := cmd.Flags().GetString("to")
+	version, _ := cmd.Flags().GetString("version")
+	for _, tag := range []string{fromTag, toTag} {
+		if !IsValidSolutionTag(tag) {
+			log.Fatalf("Invalid tag %q", tag)
+		}
+	}
+	if fromTag == toTag {
+		log.Fatal("The source and target tags must be different")
+	}
+
+	// download the solution from the source stage and verify it
+	archivePath, err := DownloadSolutionPackage(name, fromTag, "")
+	if err != nil {
+		log.Fatalf("Failed to download solution %q with tag %q: %v", name, fromTag, err)
+	}
+	defer os.Remove(archivePath)
+	inspection, dir, err := inspectArchive(archivePath)
+	if dir != "" {
+		defer os.RemoveAll(dir)
+	}
+	if err != nil {
+		log.Fatalf("Failed to inspect the solution archive: %v", err)
+	}
+	if version != "" && inspection.Version != version {
+		log.Fatalf("Solution %q with tag %q has version %v, not %v; push version %v with tag %q first", name, fromTag, inspection.Version, version, version, fromTag)
+	}
+	if dir == "" {
+		output.PrintCmdStatus(cmd, getSolutionValidationErrorsString(len(inspection.Errors), Errors{Items: inspection.Errors, Total: len(inspection.Errors)}))
+		log.Fatalf("The solution archive with tag %q is not safe to extract; not promoting it", fromTag)
+	}
+	for _, item := range inspection.Errors {
+		// the platform accepted the archive in the source stage, so the fsoc schemas may be outdated
+		log.Warnf("Local validation: %v: %v", item.Source, item.Error)
+	}
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Promoting solution %v version %v from %v to %v\n", name, inspection.Version, fromTag, toTag))
+
+	uploadSolution(cmd, true,
+		WithSolutionZipPath(archivePath),
+		WithSolutionName(name),
+		WithSolutionInstallVersion(inspection.Version),
+		WithSolutionTag(toTag))
+}
+
+// getInstalledSolutionVersion returns the version of the last successful installation of a
+// solution (by solution ID, e.g., mysolution or mysolution.mytag) in the tenant of a profile
+// (the current profile if empty), or "" if none
+func getInstalledSolutionVersion(profile string, solutionId string) (string, error) {
+	filter := fmt.Sprintf(`data.solutionID eq "%s" and data.isSuccessful eq "true"`, solutionId)
+	query := fmt.Sprintf("?order=%s&filter=%s&max=1", url.QueryEscape("desc"), url.QueryEscape(filter))
+	var res ResponseBlob
+	if err := api.JSONGet(fmt.Sprintf(getSolutionInstallUrl(), query), &res, &api.Options{Headers: getProfileHeaders(profile), Profile: profile, Quiet: profile != ""}); err != nil {
+		return "", err
+	}
+	if len(res.Items) == 0 {
+		return "", nil
+	}
+	return res.Items[0].StatusData.SolutionVersion, nil
+}
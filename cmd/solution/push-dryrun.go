@@ -0,0 +1,168 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+)
+
+// dryRunPushSolution performs all checks that a push would do, without changing anything:
+// the client-side checks, the platform validation of the archive and the tenant-level
+// dependency and version checks. It then reports what the push would install.
+func dryRunPushSolution(cmd *cobra.Command) {
+	solutionBundlePath, _ := cmd.Flags().GetString("solution-bundle")
+	if solutionBundlePath != "" {
+		// nothing to check locally, the platform validates the archive
+		uploadSolution(cmd, false)
+		output.PrintCmdStatus(cmd, "Dry run complete: the solution archive is valid; nothing was deployed.\n")
+		return
+	}
+
+	// client-side checks (fail on errors)
+	validateSolutionOffline(cmd)
+
+	// platform validation of the archive, as it would be uploaded
+	uploadSolution(cmd, false)
+
+	// tenant-level checks
+	solutionRootDirectory, _ := cmd.Flags().GetString("directory")
+	if solutionRootDirectory == "" {
+		solutionRootDirectory = "."
+	}
+	solutionRootDirectory = absolutizePath(solutionRootDirectory)
+	manifest, err := getSolutionManifest(solutionRootDirectory)
+	if err != nil {
+		log.Fatalf("Failed to read the solution manifest: %v", err)
+	}
+	tag, _ := getEmbeddedTag(cmd, solutionRootDirectory) // already validated by the upload
+	nProblems := 0
+
+	// dependencies must be available and subscribed to in the tenant, matching the lockfile, if any
+	checks, err := checkDependencyAvailability(solutionRootDirectory, manifest)
+	if err != nil {
+		log.Fatalf("Failed to check dependencies: %v", err)
+	}
+	for _, check := range checks {
+		if check.ok {
+			output.PrintCmdStatus(cmd, fmt.Sprintf("Dependency %q is available\n", check.Name))
+			continue
+		}
+		message := fmt.Sprintf("Dependency %q is NOT ready in tenant %v: %v", check.Name, config.GetCurrentContext().Tenant, check.Status)
+		if check.Fix != "" {
+			message += fmt.Sprintf(" (fix: %v)", check.Fix)
+		}
+		output.PrintCmdStatus(cmd, message+"\n")
+		getReport(cmd).addFinding("error", "manifest."+manifest.ManifestFormat.String(), message)
+		nProblems++
+	}
+
+	// the version must be newer than the one installed (not applicable to pseudo-isolated solutions)
+	installedVersion := ""
+	if !manifest.HasPseudoIsolation() {
+		installedVersion = getDeployedSolutionVersion(manifest.Name, tag)
+	}
+	if installedVersion != "" {
+		newer, err := isNewerSolutionVersion(manifest.SolutionVersion, installedVersion)
+		if err != nil {
+			log.Warnf("Failed to compare solution versions: %v", err)
+		} else if !newer {
+			message := fmt.Sprintf("Solution version %v is not newer than the installed version %v; use --bump to increment it", manifest.SolutionVersion, installedVersion)
+			output.PrintCmdStatus(cmd, message+"\n")
+			getReport(cmd).addFinding("error", "manifest."+manifest.ManifestFormat.String(), message)
+			nProblems++
+		}
+	} else {
+		installedVersion = "none"
+	}
+
+	// report what would be installed
+	output.PrintCmdStatus(cmd, fmt.Sprintf("\nA push would install solution %v version %v with tag %v (currently installed: %v), with:\n", manifest.Name, manifest.SolutionVersion, tag, installedVersion))
+	files, errs := loadManifestObjects(solutionRootDirectory, manifest)
+	for _, err := range errs {
+		log.Warnf("Skipping file %v", err)
+	}
+	summary := summarizeObjects(files, manifest)
+	lines := [][]string{}
+	for _, item := range summary {
+		lines = append(lines, []string{item.Type, fmt.Sprint(item.Objects), fmt.Sprint(item.Files)})
+	}
+	output.PrintCmdOutputCustom(cmd, summary, &output.Table{Headers: []string{"Type", "Objects", "Files"}, Lines: lines})
+
+	if nProblems > 0 {
+		getReport(cmd).setOutcome(reportOutcomeFindings)
+		log.Fatalf("Dry run found %d problem(s) that would prevent a successful install", nProblems)
+	}
+	output.PrintCmdStatus(cmd, "Dry run complete; nothing was deployed.\n")
+}
+
+type objectsSummary struct {
+	Type    string `json:"type"`
+	Objects int    `json:"objects"`
+	Files   int    `json:"files"`
+}
+
+// summarizeObjects counts the objects and files for each type, sorted by type
+func summarizeObjects(files []manifestObjectsFile, manifest *Manifest) []objectsSummary {
+	byType := map[string]*objectsSummary{}
+	for _, file := range files {
+		item, found := byType[file.objType]
+		if !found {
+			item = &objectsSummary{Type: file.objType}
+			byType[file.objType] = item
+		}
+		item.Objects += len(file.objects)
+		item.Files++
+	}
+	if len(manifest.Types) > 0 {
+		byType["(type definitions)"] = &objectsSummary{Type: "(type definitions)", Objects: len(manifest.Types), Files: len(manifest.Types)}
+	}
+
+	summary := make([]objectsSummary, 0, len(byType))
+	for _, item := range byType {
+		summary = append(summary, *item)
+	}
+	sort.Slice(summary, func(i, j int) bool { return summary[i].Type < summary[j].Type })
+	return summary
+}
+
+// getDeployedSolutionVersion returns the version of the solution last installed successfully
+// with the given tag, or "" if the solution is not installed
+func getDeployedSolutionVersion(solutionName string, tag string) string {
+	filter := fmt.Sprintf(`data.solutionName eq "%s" and data.tag eq "%s" and data.isSuccessful eq "true"`, solutionName, tag)
+	query := fmt.Sprintf("?order=%s&filter=%s&max=1", url.QueryEscape("desc"), url.QueryEscape(filter))
+	return getObjects(fmt.Sprintf(getSolutionInstallUrl(), query), getHeaders()).StatusData.SolutionVersion
+}
+
+// isNewerSolutionVersion returns true if version is greater than baseVersion
+func isNewerSolutionVersion(version string, baseVersion string) (bool, error) {
+	v, err := semver.StrictNewVersion(version)
+	if err != nil {
+		return false, fmt.Errorf("invalid version %q: %w", version, err)
+	}
+	base, err := semver.StrictNewVersion(baseVersion)
+	if err != nil {
+		return false, fmt.Errorf("invalid version %q: %w", baseVersion, err)
+	}
+	return v.GreaterThan(base), nil
+}
@@ -0,0 +1,197 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+)
+
+// PushStateFileName is the file in the solution directory recording the state of the last
+// successful push, used by push --incremental; it should NOT be version controlled
+const PushStateFileName = ".fsocpush"
+
+// pushState records the content hashes of the solution's objects and files as last pushed
+// to a tenant with a tag
+type pushState struct {
+	Tenant          string            `json:"tenant"`
+	Tag             string            `json:"tag"`
+	SolutionVersion string            `json:"solutionVersion"`
+	Hashes          map[string]string `json:"hashes"` // object or file key -> content hash
+}
+
+// computePushState hashes the solution as it would be packaged: the solution is staged with
+// the given options, so that the environment overlay, the template variables and the
+// environment variables are applied, then its manifest (except its version), each object in
+// the object files referenced by the manifest and every other packaged file are hashed
+func computePushState(sourcePath string, options stageOptions, tenant string, tag string) (*pushState, error) {
+	stagedPath, err := stageSolution(sourcePath, "", options)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(filepath.Dir(stagedPath))
+	solutionPath := stagedPath
+	manifest, err := getSolutionManifest(solutionPath)
+	if err != nil {
+		return nil, err
+	}
+	state := &pushState{Tenant: tenant, Tag: tag, SolutionVersion: manifest.SolutionVersion, Hashes: map[string]string{}}
+
+	unversioned := *manifest
+	unversioned.SolutionVersion = ""
+	state.Hashes["manifest"] = hashContent(unversioned)
+
+	objectFiles, errs := loadManifestObjects(solutionPath, manifest)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	objectFilePaths := map[string]bool{}
+	for _, file := range objectFiles {
+		objectFilePaths[file.path] = true
+		for i, obj := range file.objects {
+			objMap, _ := obj.(map[string]any)
+			name := getLocalObjectName(objMap)
+			if name == "" {
+				name = fmt.Sprintf("%v#%d", filepath.ToSlash(file.path), i)
+			}
+			state.Hashes[file.objType+" "+name] = hashContent(obj)
+		}
+	}
+
+	manifestFile := "manifest." + manifest.ManifestFormat.String()
+	err = filepath.Walk(solutionPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !isAllowedPath(solutionPath, path, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		relPath, err := filepath.Rel(solutionPath, path)
+		if err != nil || info.IsDir() || relPath == manifestFile || objectFilePaths[relPath] {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(content)
+		state.Hashes["file "+filepath.ToSlash(relPath)] = hex.EncodeToString(sum[:])
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func hashContent(v any) string {
+	content, _ := json.Marshal(v) // maps are marshalled with sorted keys
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// loadPushState reads the state of the last push; it returns nil if there is none
+func loadPushState(solutionPath string) (*pushState, error) {
+	content, err := os.ReadFile(filepath.Join(solutionPath, PushStateFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var state pushState
+	if err := json.Unmarshal(content, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse %v: %w", PushStateFileName, err)
+	}
+	return &state, nil
+}
+
+func (state *pushState) save(solutionPath string) error {
+	f, err := os.Create(filepath.Join(solutionPath, PushStateFileName))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return output.WriteJson(state, f)
+}
+
+// diffPushStates returns the keys of the objects and files that were changed, added or removed
+func diffPushStates(previous *pushState, current *pushState) []string {
+	changes := []string{}
+	for _, key := range sortedKeys(current.Hashes) {
+		previousHash, found := previous.Hashes[key]
+		switch {
+		case !found:
+			changes = append(changes, "added "+key)
+		case previousHash != current.Hashes[key]:
+			changes = append(changes, "changed "+key)
+		}
+	}
+	for _, key := range sortedKeys(previous.Hashes) {
+		if _, found := current.Hashes[key]; !found {
+			changes = append(changes, "removed "+key)
+		}
+	}
+	return changes
+}
+
+// checkIncrementalPush compares the solution with the state of its last push to the same tenant
+// with the same tag. It returns the solution's current state and whether it needs to be pushed.
+// The platform accepts only complete solution archives, so any change results in a full push;
+// an unchanged solution is not pushed at all. Solutions with secrets are always pushed, as the
+// values of their secrets are not compared.
+func checkIncrementalPush(cmd *cobra.Command, solutionPath string, manifest *Manifest, tag string) (*pushState, bool) {
+	options := getStageOptions(cmd)
+	options.secrets = secretsRedacted // the secrets are prompted for only once, when packaging
+	current, err := computePushState(solutionPath, options, config.GetCurrentContext().Tenant, tag)
+	if err != nil {
+		log.Fatalf("Failed to compute the solution's content hashes: %v", err)
+	}
+	previous, err := loadPushState(solutionPath)
+	if err != nil {
+		log.Warnf("Ignoring the last push state: %v", err)
+	}
+	if previous == nil || previous.Tenant != current.Tenant || previous.Tag != current.Tag {
+		output.PrintCmdStatus(cmd, "No previous push of the solution to this tenant with this tag; pushing the full solution\n")
+		return current, true
+	}
+	if len(manifest.Secrets) > 0 {
+		output.PrintCmdStatus(cmd, "The values of the solution's secrets cannot be compared; pushing the full solution\n")
+		return current, true
+	}
+
+	changes := diffPushStates(previous, current)
+	if len(changes) == 0 {
+		output.PrintCmdStatus(cmd, fmt.Sprintf("No changes since version %v was pushed with tag %v; nothing to push\n", previous.SolutionVersion, tag))
+		return current, false
+	}
+	output.PrintCmdStatus(cmd, fmt.Sprintf("%d change(s) since version %v was pushed:\n  %v\n", len(changes), previous.SolutionVersion, strings.Join(changes, "\n  ")))
+	output.PrintCmdStatus(cmd, "The platform does not support object-level updates; pushing the full solution\n")
+	return current, true
+}
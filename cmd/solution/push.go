@@ -35,10 +35,54 @@ A tag may be defined in the following ways (in order of precedence):
   1. Specified flag --tag=xyz or --stable: use this tag, ignoring .tag file or env vars
   2. A tag is defined in the FSOC_SOLUTION_TAG environment variable (ignores .tag file)
   3. A tag is defined in the .tag file in the solution directory (usually not version controlled)
+
+With --wait, after uploading, the command waits for the solution to be installed, displaying the installation
+status as it changes, and fails if the installation fails or doesn't complete within the --wait timeout. If the
+installation fails, the errors the platform reported are displayed. Without --wait, the command returns as soon
+as the solution is uploaded.
+
+Before uploading, the command checks that each dependency is available in the tenant and subscribed to (system
+solutions are always available) and, if the solution has a solution.lock file, that the installed versions are
+compatible with the locked ones. The "fsoc solution subscribe" commands that fix missing subscriptions are displayed.
+Use --skip-dependency-check to upload without checking.
+
+Use --dry-run to perform all checks without deploying: the solution is validated locally and by the platform,
+its dependencies and version are checked against the tenant, and the objects that would be installed are listed.
+
+Use --incremental to skip pushing a solution that hasn't changed since it was last pushed to the same tenant with
+the same tag. The solution is compared as it would be packaged, i.e., with the --env overlay, the --set values
+and the environment variables applied: a content hash of each object and file is recorded in the .fsocpush file in
+the solution directory after each successful push (this file should not be version controlled); the changes are
+listed when pushing. The platform installs complete solutions only, so a changed solution is always pushed in full.
+Solutions with secrets are always pushed, as the values of the secrets are not compared.
+
+If the upload fails with a transient error, such as a connection dropped while uploading a large archive or
+a gateway timeout, it is retried up to --retries times with an increasing delay. Before each retry, the
+command checks whether the platform started installing the version since the push started, i.e., received
+the archive from the interrupted attempt, so that the same version is not uploaded twice. The platform accepts
+the archive in a single request, so an interrupted upload is not resumed: each retry sends the whole archive.
+The attempt that succeeded is displayed.
+
+Secrets, such as API keys, are declared in the secrets block of the manifest and referenced as ${secret:name} in
+object files. References are allowed only in the secure properties of the solution's own types, which the platform
+stores encrypted:
+
+  secrets:
+    apiKey:
+      description: API key of the monitored service
+      env: MYSERVICE_API_KEY   # defaults to FSOC_SECRET_APIKEY
+
+The value of each secret is read from its environment variable or, if not set, prompted for without echo (use
+--no-prompt to fail instead, e.g., in CI). Secrets are substituted only in the archive uploaded by this command,
+which is deleted after the upload and not kept in the local archive cache; they are never written to the solution
+directory. Other commands, such as validate and render, use placeholder values, and solutions with secrets
+cannot be packaged with "fsoc solution package".
 `,
 	Example: `
   fsoc solution push --tag=stable
   fsoc solution push --wait --tag=dev
+  fsoc solution push --dry-run --tag=dev
+  fsoc solution push --incremental --bump --tag=dev
   fsoc solution push --bump --wait=60
   fsoc solution push -d mysolution --stable --wait
   fsoc solution push --solution-bundle=mysolution-1.22.3.zip --tag=stable`,
@@ -49,7 +93,7 @@ A tag may be defined in the following ways (in order of precedence):
 func getSolutionPushCmd() *cobra.Command {
 	addTagFlags(solutionPushCmd) // --tag and --stable
 
-	solutionPushCmd.Flags().IntP("wait", "w", -1, "Wait (in seconds) for the solution to be deployed")
+	solutionPushCmd.Flags().IntP("wait", "w", -1, "Wait (in seconds) for the solution to be installed; 0 waits indefinitely")
 	solutionPushCmd.Flag("wait").NoOptDefVal = "300"
 
 	solutionPushCmd.Flags().
@@ -62,7 +106,7 @@ func getSolutionPushCmd() *cobra.Command {
 		String("solution-bundle", "", "Path to a prepackaged solution zip")
 
 	solutionPushCmd.Flags().
-		String("env-file", "", "Path to the env vars json file with pseudo-isolation tag and, optionally, dependency tags (DEPRECATED)")
+		String("env-file", "", "Path to the env vars json file with pseudo-isolation tag and, optionally, dependency tags")
 
 	solutionPushCmd.Flags().
 		Bool("no-isolate", false, "Disable fsoc-supported solution pseudo-isolation")
@@ -76,9 +120,45 @@ func getSolutionPushCmd() *cobra.Command {
 	solutionPushCmd.MarkFlagsMutuallyExclusive("solution-bundle", "subscribe") // TODO: allow when extracting manifest data
 	solutionPushCmd.MarkFlagsMutuallyExclusive("tag", "stable", "env-file")
 
+	solutionPushCmd.Flags().
+		Bool("dry-run", false, "Check and validate the solution and show what would be installed, without deploying it")
+	solutionPushCmd.MarkFlagsMutuallyExclusive("dry-run", "bump")      // a dry run doesn't modify the manifest
+	solutionPushCmd.MarkFlagsMutuallyExclusive("dry-run", "subscribe") // nor changes the subscriptions
+
+	solutionPushCmd.Flags().
+		Bool("skip-dependency-check", false, "Don't check that the dependencies are available and subscribed to in the tenant")
+	solutionPushCmd.MarkFlagsMutuallyExclusive("skip-dependency-check", "dry-run")
+
+	solutionPushCmd.Flags().
+		Bool("incremental", false, "Push only if the solution changed since it was last pushed with the same tag")
+	solutionPushCmd.MarkFlagsMutuallyExclusive("incremental", "solution-bundle")
+	solutionPushCmd.MarkFlagsMutuallyExclusive("incremental", "dry-run")
+
+	solutionPushCmd.Flags().
+		Int("retries", 3, "Number of times to retry the upload if it fails with a transient error, e.g., a dropped connection")
+
+	solutionPushCmd.Flags().
+		Bool("no-prompt", false, "Read the declared secrets from their environment variables only, without prompting for them")
+
+	solutionPushCmd.Flags().
+		Bool("readme-description", false, "Set the solution description from the readme if the manifest has none")
+
+	addVariableFlags(solutionPushCmd)
+	solutionPushCmd.MarkFlagsMutuallyExclusive("solution-bundle", "set") // cannot modify prepackaged zip
+	solutionPushCmd.MarkFlagsMutuallyExclusive("solution-bundle", "env") // nor apply an overlay
+
+	addReportFlag(solutionPushCmd)
+
 	return solutionPushCmd
 }
 
 func pushSolution(cmd *cobra.Command, args []string) {
+	report := startReport(cmd)
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		dryRunPushSolution(cmd)
+		report.finish()
+		return
+	}
 	uploadSolution(cmd, true)
+	report.finish()
 }
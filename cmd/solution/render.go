@@ -0,0 +1,256 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+)
+
+var solutionRenderCmd = &cobra.Command{
+	Use:   "render",
+	Args:  cobra.ExactArgs(0),
+	Short: "Preview the solution with template variables substituted",
+	Long: `This command renders the solution the way it would be packaged and pushed, and displays the manifest and
+object files with all template variables substituted.
+
+Template variables can be used in the manifest and in the object and type files it references:
+  ${var:name}  the value of the variable "name" declared in the manifest's variables block
+  ${env:NAME}  the value of the NAME environment variable
+
+The variables block in the manifest declares the variables and their default values; defaults may refer to
+environment variables. The values can be overridden with the --set flag, which is also supported by the
+package, push, validate and diff commands. The variables block is removed from the packaged manifest.
+
+Substituted values are escaped as needed to be placed within JSON strings. Values substituted in YAML files
+are inserted as is, so quote them in the YAML file if they may contain special characters.
+
+Solutions using pseudo-isolation are isolated first, using the same --tag or --env-file flag as the isolate
+command (or the FSOC_SOLUTION_TAG environment variable, .tag or env.json file in the solution directory), so that the
+isolated name and references can be previewed.
+
+Use --target-dir to write the rendered solution into a directory instead of displaying it.
+
+Use --materialize to write the exact payload that would be pushed to a build directory (--target-dir, defaulting
+to build/<solution-name> in the current directory), as the ground truth for code reviews and for debugging what
+the platform received. The build directory contains:
+  <solution-name>.zip   the solution package, as created by "fsoc solution package"
+  solution/             the content of the package: the manifest, object and type files after isolation,
+                        overlays and variable substitution, with YAML files converted to JSON
+  objects/<type>.json   the final set of objects of each type, assembled from all objectsFile and objectsDir
+                        entries of the manifest and sorted by name (":" in the type name is replaced by "_")`,
+	Example: `  fsoc solution render
+  fsoc solution render --set imageTag=1.2.3 --set endpoint=https://example.com
+  fsoc solution render -d mysolution --target-dir build/mysolution
+  fsoc solution render --tag dev
+  fsoc solution render --materialize --env prod --tag stable`,
+	Run:         renderSolution,
+	Annotations: map[string]string{config.AnnotationForConfigBypass: ""},
+}
+
+func getSolutionRenderCmd() *cobra.Command {
+	solutionRenderCmd.Flags().
+		StringP("directory", "d", "", "Path to the solution root directory (defaults to current dir)")
+
+	solutionRenderCmd.Flags().
+		String("target-dir", "", "Path to a new directory to write the rendered solution into")
+
+	solutionRenderCmd.Flags().
+		String("tag", "", "Isolation tag to use if using fsoc isolation; if specified, takes precedence over env vars and .tag file")
+	solutionRenderCmd.Flags().
+		Bool("stable", false, "Render the production-ready version of the solution.  This is equivalent to supplying --tag=stable")
+	solutionRenderCmd.Flags().
+		String("env-file", "", "Path to the env vars json file with isolation tag and, optionally, dependency tags")
+	solutionRenderCmd.Flags().
+		Bool("no-isolate", false, "Disable fsoc-supported solution isolation")
+	solutionRenderCmd.MarkFlagsMutuallyExclusive("tag", "stable", "env-file", "no-isolate")
+
+	solutionRenderCmd.Flags().
+		Bool("materialize", false, "Write the package, its content and the final objects of each type into a build directory")
+
+	addVariableFlags(solutionRenderCmd)
+
+	return solutionRenderCmd
+}
+
+// addVariableFlags adds the --set flag for overriding template variables and the --env flag
+// for selecting an environment overlay
+func addVariableFlags(cmd *cobra.Command) {
+	cmd.Flags().
+		StringToString("set", nil, "Set the value of a template variable (key=value); can be repeated")
+	cmd.Flags().
+		String("env", "", "Apply the environment overlay in the overlays/<env> directory")
+}
+
+func renderSolution(cmd *cobra.Command, args []string) {
+	solutionRootDirectory, _ := cmd.Flags().GetString("directory")
+	if solutionRootDirectory == "" {
+		solutionRootDirectory = "."
+	}
+	solutionRootDirectory = absolutizePath(solutionRootDirectory)
+	if !isSolutionPackageRoot(solutionRootDirectory) {
+		log.Fatalf("No solution manifest found in %q; please use -d flag", solutionRootDirectory)
+	}
+	targetDirectory, _ := cmd.Flags().GetString("target-dir")
+	if targetDirectory != "" {
+		targetDirectory = absolutizePath(targetDirectory)
+	}
+
+	// isolate pseudo-isolated solutions first, so that the isolated names are displayed
+	solutionDirectory, _, err := embeddedConditionalIsolate(cmd, solutionRootDirectory)
+	if err != nil {
+		log.Fatalf("Failed to isolate solution with tag: %v", err)
+	}
+	if solutionDirectory != solutionRootDirectory {
+		defer os.RemoveAll(solutionDirectory)
+	}
+
+	if materialize, _ := cmd.Flags().GetBool("materialize"); materialize {
+		materializeSolution(cmd, solutionDirectory, targetDirectory)
+		return
+	}
+
+	stagedDirectory, err := stageSolution(solutionDirectory, targetDirectory, getStageOptions(cmd))
+	if err != nil {
+		log.Fatalf("Failed to render solution: %v", err)
+	}
+	if targetDirectory != "" {
+		output.PrintCmdStatus(cmd, fmt.Sprintf("Rendered solution into %q\n", targetDirectory))
+		return
+	}
+	defer os.RemoveAll(filepath.Dir(stagedDirectory))
+
+	// display the manifest first, followed by the object and type files
+	manifest, err := getSolutionManifest(stagedDirectory)
+	if err != nil {
+		log.Fatalf("Failed to read the rendered manifest: %v", err)
+	}
+	files := []string{"manifest." + manifest.ManifestFormat.String()}
+	objectFiles, errs := loadManifestObjects(stagedDirectory, manifest)
+	for _, err := range errs {
+		log.Warnf("Skipping file %v", err)
+	}
+	for _, file := range objectFiles {
+		files = append(files, file.path)
+	}
+	files = append(files, manifest.Types...)
+
+	var sb strings.Builder
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Join(stagedDirectory, file))
+		if err != nil {
+			log.Fatalf("Failed to read rendered file %q: %v", file, err)
+		}
+		sb.WriteString(fmt.Sprintf("--- %v\n", filepath.ToSlash(file)))
+		sb.Write(content)
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			sb.WriteString("\n")
+		}
+	}
+	output.PrintCmdStatus(cmd, sb.String())
+}
+
+// BuildDirName is the directory for solutions built by fsoc (isolated or materialized); it is
+// not included when packaging the solution
+const BuildDirName = "build"
+
+// materializedType is the file with the final objects of a type in a materialized solution
+type materializedType struct {
+	Type    string `json:"type"`
+	Objects int    `json:"objects"`
+	File    string `json:"file"`
+}
+
+// materializeSolution writes the solution package, its content and the final objects of each
+// type into the build directory, which must not exist
+func materializeSolution(cmd *cobra.Command, solutionDirectory string, buildDirectory string) {
+	manifest, err := getSolutionManifest(solutionDirectory)
+	if err != nil {
+		log.Fatalf("Failed to read the solution manifest: %v", err)
+	}
+	if buildDirectory == "" {
+		buildDirectory = absolutizePath(filepath.Join(BuildDirName, manifest.Name))
+	}
+	if _, err := os.Stat(buildDirectory); err == nil {
+		log.Fatalf("Build directory %q already exists; please remove it or use --target-dir", buildDirectory)
+	}
+
+	// render the package content and create the package
+	stagedDirectory, err := stageSolution(solutionDirectory, filepath.Join(buildDirectory, "solution"), getStageOptions(cmd))
+	if err != nil {
+		log.Fatalf("Failed to render solution: %v", err)
+	}
+	archive := generateZip(cmd, solutionDirectory, filepath.Join(buildDirectory, manifest.Name+".zip"))
+	archive.Close()
+
+	// assemble the objects of each type, in the order of the manifest's component definitions
+	stagedManifest, err := getSolutionManifest(stagedDirectory)
+	if err != nil {
+		log.Fatalf("Failed to read the rendered manifest: %v", err)
+	}
+	objectFiles, errs := loadManifestObjects(stagedDirectory, stagedManifest)
+	if len(errs) > 0 {
+		log.Fatalf("Failed to read the rendered objects: %v", errors.Join(errs...))
+	}
+	objectsByType := map[string][]any{}
+	types := []string{}
+	for _, file := range objectFiles {
+		if _, found := objectsByType[file.objType]; !found {
+			types = append(types, file.objType)
+		}
+		objectsByType[file.objType] = append(objectsByType[file.objType], file.objects...)
+	}
+
+	objectsDirectory := filepath.Join(buildDirectory, "objects")
+	if err := os.MkdirAll(objectsDirectory, 0o755); err != nil {
+		log.Fatalf("Failed to create directory %q: %v", objectsDirectory, err)
+	}
+	items := []materializedType{}
+	lines := [][]string{}
+	for _, objType := range types {
+		objects := objectsByType[objType]
+		sort.SliceStable(objects, func(i, j int) bool {
+			objI, _ := objects[i].(map[string]any)
+			objJ, _ := objects[j].(map[string]any)
+			return getLocalObjectName(objI) < getLocalObjectName(objJ)
+		})
+		fileName := filepath.Join("objects", strings.ReplaceAll(objType, ":", "_")+".json")
+		f, err := os.Create(filepath.Join(buildDirectory, fileName))
+		if err == nil {
+			err = writeComponent(objects, f, FileFormatJSON)
+			f.Close()
+		}
+		if err != nil {
+			log.Fatalf("Failed to write %q: %v", fileName, err)
+		}
+		items = append(items, materializedType{Type: objType, Objects: len(objects), File: filepath.ToSlash(fileName)})
+		lines = append(lines, []string{objType, fmt.Sprint(len(objects)), filepath.ToSlash(fileName)})
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []materializedType `json:"items"`
+		Total int                `json:"total"`
+	}{items, len(items)}, &output.Table{Headers: []string{"Type", "Objects", "File"}, Lines: lines})
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Materialized solution %v version %v into %q\n", stagedManifest.Name, stagedManifest.SolutionVersion, buildDirectory))
+}
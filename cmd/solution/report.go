@@ -0,0 +1,240 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/output"
+)
+
+// reportHelp documents the --report flag, appended to the help of the commands supporting it
+const reportHelp = `
+
+With --report json, a machine-readable report is written to stdout when the command completes, successfully or
+not, and the command's usual output is written to stderr instead. The report has the command's outcome, the
+findings (with severity, file, JSON pointer and line, when known) and, when installing, the outcome for each
+object. The exit code depends on the outcome:
+  0  success
+  1  error: the command could not be completed, e.g., invalid arguments or a failed API call
+  2  findings: validation or lint errors were found
+  3  install-failed: the solution failed to install
+  4  timeout: the installation didn't complete in time`
+
+// report outcomes, in the order of their exit codes
+const (
+	reportOutcomeSuccess       = "success"
+	reportOutcomeError         = "error"
+	reportOutcomeFindings      = "findings"
+	reportOutcomeInstallFailed = "install-failed"
+	reportOutcomeTimeout       = "timeout"
+)
+
+var reportExitCodes = map[string]int{
+	reportOutcomeSuccess:       0,
+	reportOutcomeError:         1,
+	reportOutcomeFindings:      2,
+	reportOutcomeInstallFailed: 3,
+	reportOutcomeTimeout:       4,
+}
+
+// SolutionReport is the machine-readable report of a solution command, written with --report json
+type SolutionReport struct {
+	Command  string                    `json:"command"`
+	Solution string                    `json:"solution,omitempty"`
+	Version  string                    `json:"version,omitempty"`
+	Tag      string                    `json:"tag,omitempty"`
+	Outcome  string                    `json:"outcome"`
+	ExitCode int                       `json:"exitCode"`
+	Message  string                    `json:"message,omitempty"` // why the command failed
+	Findings []ReportFinding           `json:"findings"`
+	Objects  []SolutionInstallLogEntry `json:"objects,omitempty"` // installation state of each object
+}
+
+// ReportFinding is a problem found by a solution command
+type ReportFinding struct {
+	Severity string `json:"severity"` // error, warning or note
+	Rule     string `json:"rule,omitempty"`
+	File     string `json:"file,omitempty"`
+	Pointer  string `json:"pointer,omitempty"` // JSON pointer of the offending value in the file
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+}
+
+type reportContextKey struct{}
+
+// reportLogHandler writes the report and exits with the report's exit code when a fatal error is
+// logged, so that the commands' existing error handling produces the report
+type reportLogHandler struct {
+	handler log.Handler
+	report  *SolutionReport
+}
+
+// errorSourceRegexp parses the source of validation errors: a file, optionally followed by the
+// index of the object in the file or by the line and column
+var errorSourceRegexp = regexp.MustCompile(`^(.*?)(?:\[(\d+)\])?(?::(\d+):\d+)?$`)
+
+func addReportFlag(cmd *cobra.Command) {
+	cmd.Flags().
+		String("report", "", "Write a machine-readable report of the outcome to stdout; the only format is json")
+	cmd.Long = strings.TrimRight(cmd.Long, "\n") + reportHelp
+}
+
+// startReport returns a new report for the command if --report is specified, nil otherwise
+func startReport(cmd *cobra.Command) *SolutionReport {
+	format, _ := cmd.Flags().GetString("report")
+	switch format {
+	case "":
+		return nil
+	case "json":
+	default:
+		log.Fatalf("Unsupported report format %q; the only format is json", format)
+	}
+
+	report := &SolutionReport{Command: cmd.CommandPath(), Findings: []ReportFinding{}}
+	cmd.SetOut(os.Stderr) // keep stdout for the report
+	if logger, ok := log.Log.(*log.Logger); ok {
+		logger.Handler = &reportLogHandler{handler: logger.Handler, report: report}
+	}
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cmd.SetContext(context.WithValue(ctx, reportContextKey{}, report))
+	return report
+}
+
+// getReport returns the command's report, nil if --report is not specified
+func getReport(cmd *cobra.Command) *SolutionReport {
+	if cmd.Context() == nil {
+		return nil
+	}
+	report, _ := cmd.Context().Value(reportContextKey{}).(*SolutionReport)
+	return report
+}
+
+func (h *reportLogHandler) HandleLog(e *log.Entry) error {
+	err := h.handler.HandleLog(e)
+	if e.Level == log.FatalLevel {
+		if h.report.Outcome == "" {
+			h.report.setOutcome(reportOutcomeError)
+		}
+		h.report.Message = e.Message
+		h.report.write()
+		os.Exit(h.report.ExitCode)
+	}
+	return err
+}
+
+// setSolution records the solution the command works on
+func (r *SolutionReport) setSolution(name string, version string, tag string) {
+	if r == nil {
+		return
+	}
+	r.Solution, r.Version, r.Tag = name, version, tag
+}
+
+func (r *SolutionReport) setOutcome(outcome string) {
+	if r == nil {
+		return
+	}
+	r.Outcome = outcome
+	r.ExitCode = reportExitCodes[outcome]
+}
+
+// addErrorItems records validation errors as findings, extracting the file, object index, line
+// and JSON pointer from the error's source and message
+func (r *SolutionReport) addErrorItems(items []ErrorItem) {
+	if r == nil {
+		return
+	}
+	for _, item := range items {
+		finding := ReportFinding{Severity: "error", File: item.Source, Message: item.Error}
+		pointer := ""
+		if match := errorSourceRegexp.FindStringSubmatch(item.Source); match != nil {
+			finding.File = match[1]
+			if match[2] != "" {
+				pointer = "/" + match[2]
+			}
+			finding.Line, _ = strconv.Atoi(match[3])
+		}
+		if path, message, found := strings.Cut(item.Error, ": "); found && !strings.Contains(path, " ") {
+			switch {
+			case strings.HasPrefix(path, "/"): // JSON pointer
+				pointer += strings.TrimSuffix(path, "/")
+				finding.Message = message
+			case strings.HasPrefix(path, "(root)"): // JSON schema context, e.g., (root).spec.replicas
+				pointer += strings.ReplaceAll(strings.TrimPrefix(path, "(root)"), ".", "/")
+				finding.Message = message
+			}
+		}
+		if pointer != "" {
+			finding.Pointer = pointer
+		}
+		r.Findings = append(r.Findings, finding)
+	}
+}
+
+func (r *SolutionReport) addLintFindings(findings []LintFinding) {
+	if r == nil {
+		return
+	}
+	for _, f := range findings {
+		r.Findings = append(r.Findings, ReportFinding{Severity: string(f.Severity), Rule: f.Rule, File: f.File, Message: f.Message})
+	}
+}
+
+func (r *SolutionReport) addFinding(severity string, file string, message string) {
+	if r == nil {
+		return
+	}
+	r.Findings = append(r.Findings, ReportFinding{Severity: severity, File: file, Message: message})
+}
+
+// setInstallLog records the installation state of each object
+func (r *SolutionReport) setInstallLog(installLog []SolutionInstallLogEntry) {
+	if r == nil {
+		return
+	}
+	r.Objects = installLog
+}
+
+// finish writes the report of a command that completed and, if its outcome is not a success,
+// exits with the outcome's exit code
+func (r *SolutionReport) finish() {
+	if r == nil {
+		return
+	}
+	if r.Outcome == "" {
+		r.setOutcome(reportOutcomeSuccess)
+	}
+	r.write()
+	if r.ExitCode != 0 {
+		os.Exit(r.ExitCode)
+	}
+}
+
+func (r *SolutionReport) write() {
+	if err := output.WriteJson(r, os.Stdout); err != nil {
+		log.Errorf("Failed to write the report: %v", err) // not fatal, to avoid recursion
+	}
+}
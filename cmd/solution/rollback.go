@@ -0,0 +1,166 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+)
+
+var solutionRollbackCmd = &cobra.Command{
+	Use:   "rollback <solution-name> --to <version>",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Roll a solution back to a previous version",
+	Long: `This command restores a previously pushed version of a solution released with a tag (release stage).
+
+The platform installs only new versions, so the previous version's archive is pushed again with the patch version
+following the latest pushed version: e.g., rolling back from 1.4.2 to 1.3.0 installs the content of 1.3.0 as
+version 1.4.3. The archive of the previous version is taken from the local archive cache, where fsoc
+keeps the archives of the last versions pushed from this machine (see "fsoc solution versions"), or from the
+file specified with --archive, e.g., a build artifact of that version.
+
+The archive is inspected before it is pushed (see "fsoc solution inspect") and the rollback must be confirmed by
+typing the solution name, unless --yes is specified.`,
+	Example: `  fsoc solution rollback mysolution --to 1.3.0
+  fsoc solution rollback mysolution --to 1.3.0 --tag dev --yes
+  fsoc solution rollback mysolution --to 1.3.0 --archive build/mysolution-1.3.0.zip`,
+	Run:              rollbackSolution,
+	TraverseChildren: true,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		config.SetActiveProfile(cmd, args, false)
+		return getSolutionNames(toComplete), cobra.ShellCompDirectiveDefault
+	},
+}
+
+func getSolutionRollbackCmd() *cobra.Command {
+	solutionRollbackCmd.Flags().
+		String("to", "", "Version to roll back to")
+	_ = solutionRollbackCmd.MarkFlagRequired("to")
+
+	solutionRollbackCmd.Flags().
+		String("tag", "stable", "Tag (release stage) of the solution")
+
+	solutionRollbackCmd.Flags().
+		String("archive", "", "Path to the archive of the version to roll back to, instead of the cached archive")
+
+	solutionRollbackCmd.Flags().
+		BoolP("yes", "y", false, "Skip the confirmation step")
+
+	solutionRollbackCmd.Flags().IntP("wait", "w", 300, "Wait (in seconds) for the solution to be installed; 0 waits indefinitely")
+	solutionRollbackCmd.Flag("wait").NoOptDefVal = "300"
+
+	solutionRollbackCmd.Flags().
+		Bool("no-wait", false, "Don't wait for the solution to be installed")
+	solutionRollbackCmd.MarkFlagsMutuallyExclusive("wait", "no-wait")
+
+	return solutionRollbackCmd
+}
+
+func rollbackSolution(cmd *cobra.Command, args []string) {
+	name := getSolutionNameFromArgs(cmd, args, "")
+	toVersion, _ := cmd.Flags().GetString("to")
+	tag, _ := cmd.Flags().GetString("tag")
+	archivePath, _ := cmd.Flags().GetString("archive")
+	if !IsValidSolutionTag(tag) {
+		log.Fatalf("Invalid tag %q", tag)
+	}
+
+	// check the versions
+	deployedVersion := getDeployedSolutionVersion(name, tag)
+	if deployedVersion == "" {
+		log.Fatalf("Solution %q is not installed with tag %q", name, tag)
+	}
+	if deployedVersion == toVersion {
+		output.PrintCmdStatus(cmd, fmt.Sprintf("Solution %v version %v is already installed with tag %v.\n", name, toVersion, tag))
+		return
+	}
+	versions, err := getSolutionVersions(name, tag)
+	if err != nil {
+		log.Fatalf("Failed to get the versions of solution %q: %v", name, err)
+	}
+	if !slices.ContainsFunc(versions, func(v SolutionVersionInfo) bool { return v.Version == toVersion }) {
+		if archivePath == "" {
+			log.Fatalf("Version %v of solution %q was not pushed with tag %q; use \"fsoc solution versions %v --tag %v\" to list the versions", toVersion, name, tag, name, tag)
+		}
+		log.Warnf("Version %v of solution %q was not pushed with tag %q", toVersion, name, tag)
+	}
+
+	// locate and verify the archive
+	if archivePath == "" {
+		archivePath = getCachedSolutionArchive(name, tag, toVersion)
+		if archivePath == "" {
+			log.Fatalf("The archive of version %v is not in the local archive cache; use --archive to specify it", toVersion)
+		}
+	}
+	inspection, dir, err := inspectArchive(archivePath)
+	if dir != "" {
+		defer os.RemoveAll(dir)
+	}
+	if err != nil {
+		log.Fatalf("Failed to inspect the solution archive: %v", err)
+	}
+	if dir == "" {
+		output.PrintCmdStatus(cmd, getSolutionValidationErrorsString(len(inspection.Errors), Errors{Items: inspection.Errors, Total: len(inspection.Errors)}))
+		log.Fatalf("The solution archive %q is not safe to extract; not rolling back", archivePath)
+	}
+	if inspection.Solution != name || inspection.Version != toVersion {
+		log.Fatalf("The archive %q contains solution %q version %v, not %q version %v", archivePath, inspection.Solution, inspection.Version, name, toVersion)
+	}
+	for _, item := range inspection.Errors {
+		// the platform accepted the archive when it was pushed, so the fsoc schemas may be outdated
+		log.Warnf("Local validation: %v: %v", item.Source, item.Error)
+	}
+
+	// prepare the previous version's content with the version following the latest pushed one
+	latestVersion := deployedVersion
+	for _, version := range versions {
+		if newer, err := isNewerSolutionVersion(version.Version, latestVersion); err == nil && newer {
+			latestVersion = version.Version
+		}
+	}
+	solutionDir := filepath.Join(dir, inspection.root)
+	manifest, err := getSolutionManifest(solutionDir)
+	if err != nil {
+		log.Fatalf("Failed to read the solution manifest from the archive: %v", err)
+	}
+	manifest.SolutionVersion = latestVersion
+	if err := bumpManifestPatchVersion(manifest); err != nil {
+		log.Fatalf("Failed to determine the rollback version: %v", err)
+	}
+	if err := saveSolutionManifest(solutionDir, manifest); err != nil {
+		log.Fatalf("Failed to update the solution manifest: %v", err)
+	}
+
+	if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+		confirmUninstall(name, fmt.Sprintf("Rolling back will install the content of version %v of solution %s as version %v with tag %v, replacing version %v.", toVersion, name, manifest.SolutionVersion, tag, deployedVersion))
+	}
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Rolling back solution %v with tag %v from version %v to %v (as version %v)\n", name, tag, deployedVersion, toVersion, manifest.SolutionVersion))
+
+	rollbackArchive := generateZip(cmd, solutionDir, "")
+	defer os.Remove(rollbackArchive.Name())
+	uploadSolution(cmd, true,
+		WithSolutionZipPath(rollbackArchive.Name()),
+		WithSolutionName(name),
+		WithSolutionInstallVersion(manifest.SolutionVersion),
+		WithSolutionTag(tag))
+}
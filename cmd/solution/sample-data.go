@@ -0,0 +1,485 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/cisco-open/fsoc/cmdkit/jsondoc"
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/api"
+	"github.com/cisco-open/fsoc/platform/melt"
+)
+
+var solutionSampleDataCmd = &cobra.Command{
+	Use:   "generate-sample-data",
+	Args:  cobra.ExactArgs(0),
+	Short: "Generate sample MELT data for the solution's FMM model",
+	Long: `This command reads the FMM entities, metrics and events defined in the solution and generates a
+fsoc telemetry data file with sample entities of each type, populated with realistic attribute values,
+metric data points and events. The file can be sent to the platform with "fsoc melt send", so that the
+solution's dashboards can be seen populated with data right away.
+
+Attribute values are chosen based on the attribute's name and type, e.g., host names, IP addresses,
+versions or regions. Metric values are chosen based on the metric's unit and content type, e.g.,
+percentages stay between 0 and 100 and monotonic sums only increase. The data points and events have no
+timestamps; "fsoc melt send" assigns them based on the time the data is sent.
+
+Use --seed to generate the same data every time.`,
+	Example: `  fsoc solution generate-sample-data
+  fsoc solution generate-sample-data --entities 5 --datapoints 10 --output-file sample.yaml
+  fsoc solution generate-sample-data --output-file - | fsoc melt send --profile myagent`,
+	Run:         solutionGenerateSampleData,
+	Annotations: map[string]string{config.AnnotationForConfigBypass: ""},
+}
+
+func getSolutionGenerateSampleDataCmd() *cobra.Command {
+	solutionSampleDataCmd.Flags().
+		StringP("directory", "d", "", "Path to the solution root directory (defaults to current dir)")
+
+	solutionSampleDataCmd.Flags().
+		String("output-file", "", `File to write the data into, "-" for stdout (defaults to <solution>-<version>-sample.yaml)`)
+
+	solutionSampleDataCmd.Flags().
+		Int("entities", 3, "Number of sample entities of each entity type")
+
+	solutionSampleDataCmd.Flags().
+		Int("datapoints", 5, "Number of data points of each metric, one minute apart")
+
+	solutionSampleDataCmd.Flags().
+		Int("events", 2, "Number of events of each event type, per entity")
+
+	solutionSampleDataCmd.Flags().
+		Int64("seed", 0, "Seed for the random values, to generate the same data every time (defaults to a random seed)")
+
+	solutionSampleDataCmd.Flags().
+		String("tag", "", "Isolation tag to use if using fsoc isolation; if specified, takes precedence over env vars and .tag file")
+	solutionSampleDataCmd.Flags().
+		String("env-file", "", "Path to the env vars json file with isolation tag and, optionally, dependency tags")
+	solutionSampleDataCmd.MarkFlagsMutuallyExclusive("tag", "env-file")
+
+	addVariableFlags(solutionSampleDataCmd)
+
+	return solutionSampleDataCmd
+}
+
+func solutionGenerateSampleData(cmd *cobra.Command, args []string) {
+	solutionRootDirectory, _ := cmd.Flags().GetString("directory")
+	if solutionRootDirectory == "" {
+		solutionRootDirectory = "."
+	}
+	solutionRootDirectory = absolutizePath(solutionRootDirectory)
+	if !isSolutionPackageRoot(solutionRootDirectory) {
+		log.Fatalf("No solution manifest found in %q; please use -d flag", solutionRootDirectory)
+	}
+	generator := &sampleDataGenerator{}
+	generator.nEntities, _ = cmd.Flags().GetInt("entities")
+	generator.nDataPoints, _ = cmd.Flags().GetInt("datapoints")
+	generator.nEvents, _ = cmd.Flags().GetInt("events")
+	if generator.nEntities < 1 || generator.nDataPoints < 1 || generator.nEvents < 0 {
+		log.Fatalf("The number of entities and data points must be at least 1 and the number of events must not be negative")
+	}
+	seed, _ := cmd.Flags().GetInt64("seed")
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	generator.rand = rand.New(rand.NewSource(seed))
+
+	// render the solution's final form, so that isolation and template variables are resolved
+	solutionDirectory, _, err := embeddedConditionalIsolate(cmd, solutionRootDirectory)
+	if err != nil {
+		log.Fatalf("Failed to isolate solution with tag: %v", err)
+	}
+	if solutionDirectory != solutionRootDirectory {
+		defer os.RemoveAll(solutionDirectory)
+	}
+	stagedDirectory, err := stageSolution(solutionDirectory, "", getStageOptions(cmd))
+	if err != nil {
+		log.Fatalf("Failed to render solution: %v", err)
+	}
+	defer os.RemoveAll(filepath.Dir(stagedDirectory))
+	manifest, err := getSolutionManifest(stagedDirectory)
+	if err != nil {
+		log.Fatalf("Failed to read the rendered manifest: %v", err)
+	}
+	if generator.FmmModel, err = loadFmmModel(stagedDirectory, manifest); err != nil {
+		log.Fatalf("Failed to read the solution's model definitions:\n%v", err)
+	}
+	if len(generator.Entities) == 0 {
+		log.Fatalf("The solution does not define any FMM entities")
+	}
+	data := generator.generate()
+
+	// write the data
+	outputFile, _ := cmd.Flags().GetString("output-file")
+	if outputFile == "" {
+		outputFile = fmt.Sprintf("%s-%s-sample.yaml", manifest.Name, manifest.SolutionVersion)
+	}
+	var w io.Writer = cmd.OutOrStdout()
+	if outputFile != "-" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			log.Fatalf("Failed to create file %q: %v", outputFile, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	content, err := yaml.Marshal(data)
+	if err != nil {
+		log.Fatalf("(bug) Failed to marshal the sample data: %v", err)
+	}
+	if _, err := w.Write(content); err != nil {
+		log.Fatalf("Failed to write the sample data: %v", err)
+	}
+	if outputFile != "-" {
+		output.PrintCmdStatus(cmd, fmt.Sprintf("Generated %d entities with %d metric and %d event types into %v\nUse \"fsoc melt send %v\" to send the data\n",
+			len(data.Melt), len(generator.Metrics), len(generator.Events), outputFile, outputFile))
+	}
+}
+
+// FmmModel - the FMM entity, metric and event definitions of a solution
+type FmmModel struct {
+	Entities []*FmmEntity
+	Metrics  map[string]*FmmMetric // by fully qualified type name
+	Events   map[string]*FmmEvent  // by fully qualified type name
+}
+
+// sampleDataGenerator generates sample MELT data for FMM entity, metric and event definitions
+type sampleDataGenerator struct {
+	*FmmModel
+	nEntities   int
+	nDataPoints int
+	nEvents     int
+	rand        *rand.Rand
+}
+
+// LoadFmmModel reads the FMM entity, metric and event definitions of the solution in a directory,
+// as they are in its files, i.e., without resolving isolation or template variables
+func LoadFmmModel(solutionPath string) (*FmmModel, error) {
+	manifest, err := getSolutionManifest(solutionPath)
+	if err != nil {
+		return nil, err
+	}
+	return loadFmmModel(solutionPath, manifest)
+}
+
+// loadFmmModel reads the FMM entity, metric and event definitions of a (staged) solution
+func loadFmmModel(solutionPath string, manifest *Manifest) (*FmmModel, error) {
+	objectFiles, errs := loadManifestObjects(solutionPath, manifest)
+	model := newFmmModel()
+	for _, file := range objectFiles {
+		for _, obj := range file.objects {
+			if err := model.add(file.objType, obj); err != nil {
+				errs = append(errs, fmt.Errorf("%v: %w", file.path, err))
+			}
+		}
+	}
+	return model, jsondoc.JoinParseErrors(errs...)
+}
+
+// FetchFmmModel fetches the FMM entity, metric and event definitions visible in the tenant, i.e.,
+// those of the solutions it is subscribed to, from the knowledge store
+func FetchFmmModel() (*FmmModel, error) {
+	model := newFmmModel()
+	for _, fmmType := range fmmDataTypes {
+		var res api.CollectionResult[struct {
+			Data map[string]any `json:"data"`
+		}]
+		if err := api.JSONGetCollection("knowledge-store/v1/objects/"+url.PathEscape(fmmType), &res, &api.Options{Headers: getHeaders()}); err != nil {
+			return nil, fmt.Errorf("failed to fetch the %v definitions: %w", fmmType, err)
+		}
+		for _, item := range res.Items {
+			if err := model.add(fmmType, item.Data); err != nil {
+				return nil, fmt.Errorf("invalid %v definition: %w", fmmType, err)
+			}
+		}
+	}
+	return model, nil
+}
+
+func newFmmModel() *FmmModel {
+	return &FmmModel{Metrics: map[string]*FmmMetric{}, Events: map[string]*FmmEvent{}}
+}
+
+// add adds an FMM object of the given type to the model, ignoring objects of other types
+func (model *FmmModel) add(objType string, obj any) error {
+	var err error
+	switch objType {
+	case "fmm:entity":
+		entity := &FmmEntity{}
+		if err = remarshal(obj, entity); err == nil {
+			model.Entities = append(model.Entities, entity)
+		}
+	case "fmm:metric":
+		metric := &FmmMetric{}
+		if err = remarshal(obj, metric); err == nil && metric.Namespace != nil {
+			model.Metrics[metric.Namespace.Name+":"+metric.Name] = metric
+		}
+	case "fmm:event":
+		event := &FmmEvent{}
+		if err = remarshal(obj, event); err == nil && event.Namespace != nil {
+			model.Events[event.Namespace.Name+":"+event.Name] = event
+		}
+	}
+	return err
+}
+
+// Merge adds the definitions of another model whose types are not defined in the model
+func (model *FmmModel) Merge(other *FmmModel) {
+	for _, entity := range other.Entities {
+		if entity.FmmTypeDef != nil && entity.Namespace != nil && model.Entity(entity.GetTypeName()) == nil {
+			model.Entities = append(model.Entities, entity)
+		}
+	}
+	for name, metric := range other.Metrics {
+		if _, found := model.Metrics[name]; !found {
+			model.Metrics[name] = metric
+		}
+	}
+	for name, event := range other.Events {
+		if _, found := model.Events[name]; !found {
+			model.Events[name] = event
+		}
+	}
+}
+
+// Entity returns the definition of an entity type by its fully qualified name, or nil if not defined
+func (model *FmmModel) Entity(typeName string) *FmmEntity {
+	for _, entity := range model.Entities {
+		if entity.FmmTypeDef != nil && entity.Namespace != nil && entity.GetTypeName() == typeName {
+			return entity
+		}
+	}
+	return nil
+}
+
+// generate creates the sample entities with their metrics and events
+func (g *sampleDataGenerator) generate() *melt.FsocData {
+	data := &melt.FsocData{Melt: []*melt.Entity{}}
+	for _, fmmEntity := range g.Entities {
+		if fmmEntity.Namespace == nil {
+			continue
+		}
+		entityType := fmmEntity.Namespace.Name + ":" + fmmEntity.Name
+		for i := 1; i <= g.nEntities; i++ {
+			entity := melt.NewEntity(entityType)
+			if fmmEntity.AttributeDefinitions != nil && fmmEntity.AttributeDefinitions.FmmAttributeDefinitionsTypeDef != nil {
+				for _, name := range sortedAttributeNames(fmmEntity.AttributeDefinitions.Attributes) {
+					attrName := name
+					if !strings.Contains(name, fmmEntity.Namespace.Name) {
+						attrName = fmt.Sprintf("%s.%s.%s", fmmEntity.Namespace.Name, fmmEntity.Name, name)
+					}
+					entity.SetAttribute(attrName, g.attributeValue(name, fmmEntity.AttributeDefinitions.Attributes[name], fmmEntity.Name, i))
+				}
+			}
+			for _, metricType := range fmmEntity.MetricTypes {
+				if fmmMetric, found := g.Metrics[metricType]; found {
+					entity.AddMetric(g.metric(metricType, fmmMetric, i))
+				}
+			}
+			for _, eventType := range fmmEntity.EventTypes {
+				if fmmEvent, found := g.Events[eventType]; found {
+					for j := 1; j <= g.nEvents; j++ {
+						entity.AddLog(g.event(eventType, fmmEvent, j))
+					}
+				}
+			}
+			data.Melt = append(data.Melt, entity)
+		}
+	}
+	return data
+}
+
+// metric creates a metric with data points whose values match the metric's unit and content type
+func (g *sampleDataGenerator) metric(metricType string, fmmMetric *FmmMetric, index int) *melt.Metric {
+	metric := melt.NewMetric(metricType, fmmMetric.Unit, string(fmmMetric.ContentType), string(fmmMetric.Type))
+	metric.IsMonotonic = fmmMetric.IsMonotonic
+	switch strings.ToLower(fmmMetric.AggregationTemporality) {
+	case "delta":
+		metric.AggregationTemporality = melt.AggregationTemporalityDelta
+	case "cumulative":
+		metric.AggregationTemporality = melt.AggregationTemporalityCumulative
+	}
+	if fmmMetric.AttributeDefinitions != nil {
+		for _, name := range sortedAttributeNames(fmmMetric.AttributeDefinitions.Attributes) {
+			metric.SetAttribute(name, g.attributeValue(name, fmmMetric.AttributeDefinitions.Attributes[name], fmmMetric.Name, index))
+		}
+	}
+
+	low, high := metricValueRange(fmmMetric.Unit)
+	value := low + g.rand.Float64()*(high-low)
+	cumulative := 0.0
+	values := make([]float64, g.nDataPoints)
+	for i := range values {
+		if fmmMetric.IsMonotonic && fmmMetric.ContentType == ContentType_Sum {
+			// monotonic sums only increase, by an amount in the unit's range
+			cumulative += low + g.rand.Float64()*(high-low)/10
+			value = cumulative
+		} else {
+			// other values drift within the range
+			value = math.Max(low, math.Min(high, value+(g.rand.Float64()-0.5)*(high-low)/10))
+		}
+		if fmmMetric.Type == Type_Long {
+			values[i] = math.Round(value)
+		} else {
+			values[i] = math.Round(value*100) / 100
+		}
+	}
+
+	// "fsoc melt send" assigns the timestamps of data points backwards from the current time
+	for i := len(values) - 1; i >= 0; i-- {
+		value := values[i]
+		if fmmMetric.ContentType == ContentType_Distribution {
+			count := int64(1 + g.rand.Intn(100))
+			quantiles := []*melt.QuantileValue{{Quantile: 0.0, Value: math.Round(value*50) / 100}, {Quantile: 1.0, Value: math.Round(value*150) / 100}}
+			metric.AddDistributionDataPoint(0, 0, value*float64(count), count, quantiles)
+		} else {
+			metric.AddDataPoint(0, 0, value)
+		}
+	}
+	return metric
+}
+
+// event creates an event with values for all attributes of its type
+func (g *sampleDataGenerator) event(eventType string, fmmEvent *FmmEvent, index int) *melt.Log {
+	event := melt.NewEvent(eventType)
+	if fmmEvent.AttributeDefinitions != nil {
+		for _, name := range sortedAttributeNames(fmmEvent.AttributeDefinitions.Attributes) {
+			event.SetAttribute(name, g.attributeValue(name, fmmEvent.AttributeDefinitions.Attributes[name], fmmEvent.Name, index))
+		}
+	}
+	return event
+}
+
+// values used for region and severity attributes
+var (
+	sampleRegions    = []string{"us-east-1", "us-west-2", "eu-central-1", "ap-southeast-1"}
+	sampleSeverities = []string{"INFO", "WARNING", "ERROR"}
+)
+
+// SampleAttributeValue returns a realistic value for an attribute, as generated for the sample data
+// (see attributeValue)
+func SampleAttributeValue(rnd *rand.Rand, name string, def *FmmAttributeTypeDef, owner string, index int) any {
+	return (&sampleDataGenerator{rand: rnd}).attributeValue(name, def, owner, index)
+}
+
+// SampleMetricValueRange returns a realistic range of values for a metric unit (see metricValueRange)
+func SampleMetricValueRange(unit string) (float64, float64) {
+	return metricValueRange(unit)
+}
+
+// attributeValue returns a realistic value for an attribute, based on the last segment of its name
+// and its type; index distinguishes the values of different entities of the same type
+func (g *sampleDataGenerator) attributeValue(name string, def *FmmAttributeTypeDef, owner string, index int) any {
+	key := strings.ToLower(name[strings.LastIndex(name, ".")+1:])
+	attrType := "string"
+	if def != nil && def.Type != "" {
+		attrType = def.Type
+	}
+	switch attrType {
+	case "boolean":
+		return index%2 == 1
+	case "long":
+		switch {
+		case strings.Contains(key, "port"):
+			return 8080 + index - 1
+		case strings.Contains(key, "status"):
+			return 200
+		}
+		return int64(g.rand.Intn(1000))
+	case "double":
+		return math.Round(g.rand.Float64()*10000) / 100
+	}
+
+	switch {
+	case strings.Contains(key, "host"):
+		return fmt.Sprintf("%s-%d.example.com", owner, index)
+	case key == "ip" || strings.HasSuffix(key, "ip") || strings.Contains(key, "address"):
+		return fmt.Sprintf("10.0.%d.%d", index, 1+g.rand.Intn(254))
+	case strings.Contains(key, "port"):
+		return fmt.Sprint(8080 + index - 1)
+	case strings.Contains(key, "url") || strings.Contains(key, "endpoint"):
+		return fmt.Sprintf("https://%s-%d.example.com", owner, index)
+	case strings.Contains(key, "version"):
+		return fmt.Sprintf("1.%d.%d", index, g.rand.Intn(10))
+	case strings.Contains(key, "region"):
+		return sampleRegions[(index-1)%len(sampleRegions)]
+	case strings.Contains(key, "env"):
+		return "production"
+	case strings.Contains(key, "status") || strings.Contains(key, "state"):
+		return "running"
+	case strings.Contains(key, "severity") || strings.Contains(key, "level"):
+		return sampleSeverities[g.rand.Intn(len(sampleSeverities))]
+	case strings.Contains(key, "message") || strings.Contains(key, "description"):
+		return fmt.Sprintf("Sample %s %s %d", owner, key, index)
+	case key == "id" || strings.HasSuffix(key, "id"):
+		return fmt.Sprintf("%08x-%04x", g.rand.Uint32(), index)
+	case strings.Contains(key, "name"):
+		return fmt.Sprintf("%s-%d", owner, index)
+	default:
+		return fmt.Sprintf("%s-%d", key, index)
+	}
+}
+
+// metricValueRange returns a realistic range of values for a metric unit
+func metricValueRange(unit string) (float64, float64) {
+	switch strings.ToLower(strings.Trim(unit, "{}")) {
+	case "%", "percent":
+		return 0, 100
+	case "1", "ratio":
+		return 0, 1
+	case "ns":
+		return 1e5, 5e8
+	case "us":
+		return 100, 5e5
+	case "ms":
+		return 1, 500
+	case "s":
+		return 0.01, 5
+	case "by", "bytes":
+		return 1e6, 1e9
+	case "kby", "kib":
+		return 1e3, 1e6
+	case "mby", "mib":
+		return 1, 1e3
+	default:
+		return 0, 100
+	}
+}
+
+// sortedAttributeNames returns the attribute names in a stable order, so that the same seed
+// generates the same data
+func sortedAttributeNames(attributes map[string]*FmmAttributeTypeDef) []string {
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,196 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// SecretDef declares a secret parameter of the solution, referenced as ${secret:name} in object
+// files; its value is provided when the solution is pushed and is never stored in the solution
+type SecretDef struct {
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Env         string `json:"env,omitempty" yaml:"env,omitempty"` // environment variable with the value, defaults to FSOC_SECRET_<NAME>
+}
+
+// secretsMode defines how the values of the manifest's declared secrets are obtained when staging
+type secretsMode int
+
+const (
+	secretsRedacted secretsMode = iota // placeholder values, e.g., for validation or rendering
+	secretsPrompted                    // from the environment or, if not set, prompted for
+	secretsFromEnv                     // from the environment only
+)
+
+// redactedSecretValue replaces the secrets' values when they are not needed
+const redactedSecretValue = "********"
+
+var secretEnvNameRegexp = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// getSecretEnvName returns the name of the environment variable providing the secret's value
+func getSecretEnvName(name string, secret SecretDef) string {
+	if secret.Env != "" {
+		return secret.Env
+	}
+	return "FSOC_SECRET_" + secretEnvNameRegexp.ReplaceAllString(strings.ToUpper(name), "_")
+}
+
+// resolveSecrets determines the values of the manifest's declared secrets. Values are read from
+// the secrets' environment variables and, if not set, prompted for without echo when mode is
+// secretsPrompted and the input is a terminal.
+func resolveSecrets(manifest *Manifest, mode secretsMode) (map[string]string, error) {
+	secrets := make(map[string]string, len(manifest.Secrets))
+	for _, name := range sortedKeys(manifest.Secrets) {
+		if mode == secretsRedacted {
+			secrets[name] = redactedSecretValue
+			continue
+		}
+		secret := manifest.Secrets[name]
+		envName := getSecretEnvName(name, secret)
+		if value, found := os.LookupEnv(envName); found {
+			secrets[name] = value
+			continue
+		}
+		if mode == secretsFromEnv || !term.IsTerminal(int(os.Stdin.Fd())) {
+			return nil, fmt.Errorf("secret %q is not provided: set the %v environment variable", name, envName)
+		}
+		value, err := promptSecret(name, secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secret %q: %w", name, err)
+		}
+		secrets[name] = value
+	}
+	return secrets, nil
+}
+
+// promptSecret reads a secret's value from the terminal, without echo
+func promptSecret(name string, secret SecretDef) (string, error) {
+	prompt := fmt.Sprintf("Enter the value of secret %q", name)
+	if secret.Description != "" {
+		prompt += fmt.Sprintf(" (%v)", secret.Description)
+	}
+	fmt.Fprintf(os.Stderr, "%v: ", prompt)
+	value, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if len(value) == 0 {
+		return "", fmt.Errorf("no value entered")
+	}
+	return string(value), nil
+}
+
+// checkSecretReferences fails if an object file refers to a secret outside of the secure
+// properties of its objects' type, since only secure properties are stored encrypted by the
+// platform. The secure properties are known for the solution's own types only, so objects of
+// other types may not refer to secrets. objectTypes maps the relative paths of the object files
+// to their objects' type; values are used to render the other template variables before parsing.
+func checkSecretReferences(solutionPath string, manifest *Manifest, objectTypes map[string]string, values templateValues) error {
+	secureProperties := map[string][]string{} // type -> secure properties
+	for _, typeFile := range manifest.Types {
+		doc, err := readObjectsFile(filepath.Join(solutionPath, typeFile))
+		if err != nil {
+			return fmt.Errorf("%v: %w", typeFile, err)
+		}
+		typeDefs, isArray := doc.([]any)
+		if !isArray {
+			typeDefs = []any{doc}
+		}
+		for _, typeDef := range typeDefs {
+			var knowledgeDef KnowledgeDef
+			if err := remarshal(typeDef, &knowledgeDef); err == nil && knowledgeDef.Name != "" {
+				secureProperties[manifest.Name+":"+knowledgeDef.Name] = knowledgeDef.SecureProperties
+			}
+		}
+	}
+
+	// keep the secret references as they are, to find them in the parsed objects
+	values.secrets = make(map[string]string, len(manifest.Secrets))
+	for name := range manifest.Secrets {
+		values.secrets[name] = "${secret:" + name + "}"
+	}
+	problems := []string{}
+	for _, relPath := range sortedKeys(objectTypes) {
+		content, err := os.ReadFile(filepath.Join(solutionPath, relPath))
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(string(content), "${secret:") {
+			continue
+		}
+		isJson := extensionMap[strings.ToLower(filepath.Ext(relPath))] == EncodingJSON
+		content, err = substituteTemplateVariables(content, values, isJson)
+		if err != nil {
+			continue // reported when rendering
+		}
+		doc, err := parseObjectsData(content, relPath)
+		if err != nil {
+			continue // reported when rendering
+		}
+		objType := objectTypes[relPath]
+		for _, path := range findSecretReferences("$", doc) {
+			if !isSecurePropertyPath(path, secureProperties[objType]) {
+				problems = append(problems, fmt.Sprintf("%v: %v is not a secure property of type %v", relPath, path, objType))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("secrets may be referenced only in the secure properties of the solution's own types:\n  %v", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// findSecretReferences returns the paths (e.g., $.config.apiKey) of the string values that refer
+// to a secret, in a parsed objects file; the paths of array elements are relative to the object
+func findSecretReferences(path string, value any) []string {
+	paths := []string{}
+	switch v := value.(type) {
+	case string:
+		if strings.Contains(v, "${secret:") {
+			paths = append(paths, path)
+		}
+	case map[string]any:
+		for _, key := range sortedKeys(v) {
+			paths = append(paths, findSecretReferences(path+"."+key, v[key])...)
+		}
+	case []any:
+		for i, item := range v {
+			if path == "$" {
+				// an objects file with a list of objects
+				paths = append(paths, findSecretReferences(path, item)...)
+			} else {
+				paths = append(paths, findSecretReferences(fmt.Sprintf("%v[%d]", path, i), item)...)
+			}
+		}
+	}
+	return paths
+}
+
+// isSecurePropertyPath returns true if the path is one of the secure properties or within one
+func isSecurePropertyPath(path string, secureProperties []string) bool {
+	for _, property := range secureProperties {
+		if path == property || strings.HasPrefix(path, property+".") || strings.HasPrefix(path, property+"[") {
+			return true
+		}
+	}
+	return false
+}
@@ -52,12 +52,31 @@ func NewSubCmd() *cobra.Command {
 	solutionCmd.AddCommand(getSubscribeSolutionCmd())
 	solutionCmd.AddCommand(getUnsubscribeSolutionCmd())
 	solutionCmd.AddCommand(getSolutionExtendCmd())
+	solutionCmd.AddCommand(getSolutionEditCmd())
+	solutionCmd.AddCommand(getSolutionGenerateCmd())
+	solutionCmd.AddCommand(getSolutionGenerateSampleDataCmd())
 	solutionCmd.AddCommand(getSolutionFixCmd())
+	solutionCmd.AddCommand(getSolutionConvertCmd())
+	solutionCmd.AddCommand(getSolutionFmtCmd())
 	solutionCmd.AddCommand(getSolutionPackageCmd())
+	solutionCmd.AddCommand(getSolutionInspectCmd())
 	solutionCmd.AddCommand(getSolutionPushCmd())
+	solutionCmd.AddCommand(getSolutionPromoteCmd())
+	solutionCmd.AddCommand(getSolutionVersionsCmd())
+	solutionCmd.AddCommand(getSolutionRollbackCmd())
 	solutionCmd.AddCommand(getSolutionDownloadCmd())
 	solutionCmd.AddCommand(getSolutionValidateCmd())
+	solutionCmd.AddCommand(getSolutionLintCmd())
+	solutionCmd.AddCommand(getSolutionDiffCmd())
+	solutionCmd.AddCommand(getSolutionCompatCmd())
+	solutionCmd.AddCommand(getSolutionRenderCmd())
+	solutionCmd.AddCommand(getSolutionLockCmd())
+	solutionCmd.AddCommand(getSolutionBumpDepsCmd())
+	solutionCmd.AddCommand(getSolutionDepsCmd())
+	solutionCmd.AddCommand(getSolutionVendorCmd())
+	solutionCmd.AddCommand(getSolutionDevCmd())
 	solutionCmd.AddCommand(GetSolutionForkCommand())
+	solutionCmd.AddCommand(getSolutionImportCmd())
 	solutionCmd.AddCommand(getSolutionCheckCmd())
 	solutionCmd.AddCommand(getSolutionStatusCmd())
 	solutionCmd.AddCommand(getSolutionDescribeCmd())
@@ -126,7 +145,19 @@ func getSolutionObjectUrl(solutionId string) string {
 
 // getHeaders returns the tenant-level headers required for accessing solution objects
 func getHeaders() map[string]string {
+	return getProfileHeaders("")
+}
+
+// getProfileHeaders returns the tenant layer headers for the tenant of a profile (the current
+// profile if empty)
+func getProfileHeaders(profile string) map[string]string {
 	cfg := config.GetCurrentContext()
+	if profile != "" {
+		var err error
+		if cfg, err = config.GetContext(profile); err != nil {
+			log.Fatalf("Failed to use profile: %v", err)
+		}
+	}
 
 	return map[string]string{
 		"layer-type": "TENANT",
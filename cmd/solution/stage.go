@@ -0,0 +1,433 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/output"
+)
+
+// templateVariableRegexp matches the template variable references in the manifest and object
+// files: ${var:name} for variables declared in the manifest, ${env:NAME} for environment variables,
+// and, in object files only, ${secret:name} for secrets declared in the manifest and ${msg:key}
+// for the default locale's text of a message
+var templateVariableRegexp = regexp.MustCompile(`\$\{(var|env|secret|msg):([A-Za-z_][A-Za-z0-9_.-]*)\}`)
+
+// templateValues are the values substituted for the template variable references in a file;
+// secrets and messages are nil if the file may not refer to them
+type templateValues struct {
+	variables map[string]string
+	secrets   map[string]string
+	messages  map[string]string
+}
+
+// stageOptions define how a solution is transformed into its final form
+type stageOptions struct {
+	variables     map[string]string // variable values overriding the manifest's variables block
+	convertToJson bool              // convert YAML object and type files to JSON
+	env           string            // environment overlay to apply, if any
+	readmeDesc    bool              // set the manifest's description from the readme if it has none
+	secrets       secretsMode       // how the values of the declared secrets are obtained
+}
+
+// getStageOptions returns the staging options for packaging, based on the command's
+// flags (the --set, --env and --readme-description flags are ignored if not defined).
+// The values of the declared secrets are provided only by commands with the --no-prompt
+// flag, i.e., push; other commands use placeholder values.
+func getStageOptions(cmd *cobra.Command) stageOptions {
+	variables, _ := cmd.Flags().GetStringToString("set")
+	env, _ := cmd.Flags().GetString("env")
+	readmeDesc, _ := cmd.Flags().GetBool("readme-description")
+	secrets := secretsRedacted
+	if noPrompt, err := cmd.Flags().GetBool("no-prompt"); err == nil {
+		secrets = secretsPrompted
+		if noPrompt {
+			secrets = secretsFromEnv
+		}
+	}
+	return stageOptions{variables: variables, convertToJson: true, env: env, readmeDesc: readmeDesc, secrets: secrets}
+}
+
+// stageSolution prepares a copy of the solution in its final form: the environment overlay, if
+// any, is applied, template variables are substituted in the manifest and in the object and
+// type files it references, the variables block is removed from the manifest and, if requested,
+// YAML object and type files are converted to JSON, with the manifest references updated to match.
+// If targetPath is empty, the copy has the same directory name as the solution, inside a new
+// temporary directory, and the caller should remove the copy's parent directory when done;
+// otherwise, the copy is created in targetPath, which must not exist.
+func stageSolution(solutionPath string, targetPath string, options stageOptions) (string, error) {
+	if options.env != "" {
+		overlaidPath, err := overlaySolution(solutionPath, options.env)
+		if err != nil {
+			return "", err
+		}
+		defer os.RemoveAll(filepath.Dir(overlaidPath))
+		solutionPath = overlaidPath
+	}
+	manifest, err := getSolutionManifest(solutionPath)
+	if err != nil {
+		return "", err
+	}
+	variables, err := resolveTemplateVariables(manifest, options.variables)
+	if err != nil {
+		return "", err
+	}
+	secrets, err := resolveSecrets(manifest, options.secrets)
+	if err != nil {
+		return "", err
+	}
+	locales, err := loadLocaleBundles(solutionPath, manifest)
+	if err != nil {
+		return "", err
+	}
+
+	// determine which files need processing
+	manifestFile := "manifest." + manifest.ManifestFormat.String()
+	render := map[string]bool{manifestFile: true} // relative paths of files to render
+	omit := map[string]bool{}                     // relative paths of files not selected in objects directories
+	includes := map[string]bool{}                 // relative paths of included manifest files, rendered but not converted
+	typeFiles := map[string]bool{}                // relative paths of type files, which may not refer to secrets
+	objectTypes := map[string]string{}            // relative paths of object files -> type of their objects
+	localeFiles := map[string]bool{}              // relative paths of locale bundles, converted to JSON
+	if manifest.included != nil {
+		for _, file := range manifest.included.files {
+			render[file] = true
+			includes[file] = true
+		}
+	}
+	if locales != nil {
+		for _, file := range locales.files {
+			render[file] = true
+			localeFiles[file] = true
+		}
+	}
+	for _, typeFile := range manifest.Types {
+		render[filepath.Clean(typeFile)] = true
+		typeFiles[filepath.Clean(typeFile)] = true
+	}
+	for _, compDef := range manifest.Objects {
+		if compDef.ObjectsFile != "" {
+			render[filepath.Clean(compDef.ObjectsFile)] = true
+			objectTypes[filepath.Clean(compDef.ObjectsFile)] = compDef.Type
+		}
+		if compDef.ObjectsDir != "" {
+			selected, omitted, err := listObjectsDir(solutionPath, compDef)
+			if err != nil {
+				return "", fmt.Errorf("failed to read objects directory %q: %w", compDef.ObjectsDir, err)
+			}
+			for _, relPath := range selected {
+				render[relPath] = true
+				objectTypes[relPath] = compDef.Type
+			}
+			for _, relPath := range omitted {
+				omit[relPath] = true
+			}
+		}
+	}
+
+	if len(manifest.Secrets) > 0 {
+		if err := checkSecretReferences(solutionPath, manifest, objectTypes, templateValues{variables: variables, messages: locales.defaultMessages()}); err != nil {
+			return "", err
+		}
+	}
+
+	// copy the solution, rendering files as needed
+	stagingRoot, stagedPath := targetPath, targetPath
+	if targetPath == "" {
+		stagingRoot, err = os.MkdirTemp("", "fsoc")
+		if err != nil {
+			return "", fmt.Errorf("failed to create a temporary directory: %w", err)
+		}
+		stagedPath = filepath.Join(stagingRoot, filepath.Base(solutionPath))
+	} else if _, err := os.Stat(targetPath); err == nil {
+		return "", fmt.Errorf("target directory %q already exists", targetPath)
+	}
+	log.WithFields(log.Fields{"source_dir": solutionPath, "staging_dir": stagedPath}).Info("Staging solution")
+	type renderJob struct {
+		sourcePath    string
+		targetPath    string
+		convertToJson bool
+		values        templateValues
+	}
+	renderJobs := []renderJob{} // rendered concurrently after the directories are created
+	err = filepath.Walk(solutionPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(solutionPath, path)
+		if err != nil {
+			return err
+		}
+		if !isAllowedPath(solutionPath, path, info) || relPath == OverlaysDirName || relPath == BuildDirName {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		targetPath := filepath.Join(stagedPath, relPath)
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(targetPath, 0o755)
+		case omit[relPath] && !render[relPath]:
+			log.WithField("file", relPath).Info("Skipping file not selected in objects directory")
+			return nil
+		case render[relPath]:
+			isObjectsFile := relPath != manifestFile && !includes[relPath] && !typeFiles[relPath] && !localeFiles[relPath]
+			job := renderJob{path, targetPath, options.convertToJson && relPath != manifestFile && !includes[relPath], templateValues{variables: variables}}
+			if isObjectsFile {
+				job.values.secrets = secrets
+				job.values.messages = locales.defaultMessages()
+			}
+			renderJobs = append(renderJobs, job)
+			return nil
+		default:
+			return copyLocalFile(path, targetPath)
+		}
+	})
+	if err == nil {
+		// files converted to JSON must not collide, as they are written concurrently
+		converted := map[string]string{}
+		for _, job := range renderJobs {
+			targetPath := job.targetPath
+			if job.convertToJson && isYamlFile(targetPath) {
+				targetPath = jsonFileName(targetPath)
+			}
+			if other, found := converted[targetPath]; found {
+				err = fmt.Errorf("cannot convert %q to JSON: %q would be written to the same file", job.sourcePath, other)
+				break
+			}
+			converted[targetPath] = job.sourcePath
+		}
+	}
+	if err == nil {
+		errs := parallelMap(renderJobs, func(job renderJob) error {
+			return renderObjectsFile(job.sourcePath, job.targetPath, job.values, job.convertToJson)
+		})
+		err = errors.Join(errs...)
+	}
+	if err == nil {
+		err = finalizeStagedManifest(stagedPath, options)
+	}
+	if err != nil {
+		os.RemoveAll(stagingRoot)
+		return "", fmt.Errorf("failed to stage solution: %w", err)
+	}
+
+	return stagedPath, nil
+}
+
+// finalizeStagedManifest removes the variables and secrets blocks, the default locale and the
+// objects directories' include and exclude patterns from the staged manifest, merges the included
+// manifest files into it and, if requested, updates its references to YAML files that were
+// converted to JSON and sets its description from the readme. The manifest is saved only if changed.
+func finalizeStagedManifest(stagedPath string, options stageOptions) error {
+	manifest, err := getSolutionManifest(stagedPath)
+	if err != nil {
+		return err
+	}
+	changed := false
+	if manifest.Variables != nil || manifest.Secrets != nil || manifest.DefaultLocale != "" {
+		manifest.Variables = nil
+		manifest.Secrets = nil
+		manifest.DefaultLocale = ""
+		changed = true
+	}
+	for i, compDef := range manifest.Objects {
+		if compDef.Include != nil || compDef.Exclude != nil {
+			manifest.Objects[i].Include = nil
+			manifest.Objects[i].Exclude = nil
+			changed = true
+		}
+	}
+	if manifest.included != nil {
+		// the included files' content becomes part of the packaged manifest
+		for _, file := range manifest.included.files {
+			if err := os.Remove(filepath.Join(stagedPath, file)); err != nil {
+				return fmt.Errorf("failed to remove included manifest file: %w", err)
+			}
+			for dir := filepath.Dir(file); dir != "."; dir = filepath.Dir(dir) {
+				_ = os.Remove(filepath.Join(stagedPath, dir)) // only if left empty
+			}
+		}
+		manifest.Includes = nil
+		manifest.included = nil
+		changed = true
+	}
+	if options.readmeDesc && manifest.Description == "" && manifest.Readme != "" {
+		if isUrl(manifest.Readme) {
+			log.Warnf("The description cannot be set from the readme %q, which is not in the solution directory", manifest.Readme)
+		} else if filepath.IsLocal(filepath.FromSlash(manifest.Readme)) {
+			description, err := getReadmeDescription(filepath.Join(stagedPath, filepath.FromSlash(manifest.Readme)))
+			if err != nil {
+				return fmt.Errorf("failed to read the readme: %w", err)
+			}
+			if description != "" {
+				manifest.Description = description
+				changed = true
+			}
+		}
+	}
+	if options.convertToJson {
+		for i, typeFile := range manifest.Types {
+			if isYamlFile(typeFile) {
+				manifest.Types[i] = jsonFileName(typeFile)
+				changed = true
+			}
+		}
+		for i, compDef := range manifest.Objects {
+			if compDef.ObjectsFile != "" && isYamlFile(compDef.ObjectsFile) {
+				manifest.Objects[i].ObjectsFile = jsonFileName(compDef.ObjectsFile)
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return saveSolutionManifest(stagedPath, manifest)
+}
+
+// resolveTemplateVariables determines the values of the manifest's template variables:
+// the defaults in the variables block (which may refer to environment variables) are
+// replaced by the values in overrides; overrides may also define new variables.
+func resolveTemplateVariables(manifest *Manifest, overrides map[string]string) (map[string]string, error) {
+	variables := make(map[string]string, len(manifest.Variables)+len(overrides))
+	for name, value := range manifest.Variables {
+		rendered, err := substituteTemplateVariables([]byte(value), templateValues{}, false)
+		if err != nil {
+			return nil, fmt.Errorf("variable %q: %w", name, err)
+		}
+		variables[name] = string(rendered)
+	}
+	for name, value := range overrides {
+		if _, declared := manifest.Variables[name]; !declared {
+			log.Warnf("Variable %q is not declared in the manifest's variables block", name)
+		}
+		variables[name] = value
+	}
+	return variables, nil
+}
+
+// substituteTemplateVariables replaces the template variable references in content with their values.
+// Values are escaped as needed to be placed within JSON strings if jsonEscape is true. All references
+// must be resolvable, otherwise an error listing the undefined variables is returned.
+func substituteTemplateVariables(content []byte, values templateValues, jsonEscape bool) ([]byte, error) {
+	undefined := map[string]bool{}
+	result := templateVariableRegexp.ReplaceAllFunc(content, func(ref []byte) []byte {
+		match := templateVariableRegexp.FindSubmatch(ref)
+		kind, name := string(match[1]), string(match[2])
+
+		var value string
+		var found bool
+		switch kind {
+		case "env":
+			value, found = os.LookupEnv(name)
+		case "secret":
+			value, found = values.secrets[name]
+		case "msg":
+			value, found = values.messages[name]
+		default:
+			value, found = values.variables[name]
+		}
+		if !found {
+			undefined[string(ref)] = true
+			return ref
+		}
+		if jsonEscape {
+			quoted, _ := json.Marshal(value)
+			value = string(quoted[1 : len(quoted)-1])
+		}
+		return []byte(value)
+	})
+
+	if len(undefined) > 0 {
+		refs := make([]string, 0, len(undefined))
+		for ref := range undefined {
+			refs = append(refs, ref)
+		}
+		sort.Strings(refs)
+		return nil, fmt.Errorf("undefined variable(s): %v", strings.Join(refs, ", "))
+	}
+	return result, nil
+}
+
+// renderObjectsFile substitutes the template variables in a manifest, object or type file,
+// optionally converting it from YAML to JSON
+func renderObjectsFile(sourcePath string, targetPath string, values templateValues, convertToJson bool) error {
+	content, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return err
+	}
+	isJson := extensionMap[strings.ToLower(filepath.Ext(sourcePath))] == EncodingJSON
+	content, err = substituteTemplateVariables(content, values, isJson)
+	if err != nil {
+		return fmt.Errorf("%v: %w", sourcePath, err)
+	}
+	if !convertToJson || !isYamlFile(sourcePath) {
+		return os.WriteFile(targetPath, content, 0o644)
+	}
+
+	// convert from YAML to JSON
+	doc, err := parseObjectsData(content, sourcePath)
+	if err != nil {
+		return fmt.Errorf("%v: %w", sourcePath, err)
+	}
+	targetPath = jsonFileName(targetPath)
+	if _, err := os.Stat(targetPath); err == nil {
+		return fmt.Errorf("cannot convert %q to JSON: file %q already exists", sourcePath, targetPath)
+	}
+	f, err := os.Create(targetPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return output.WriteJson(doc, f)
+}
+
+func isYamlFile(path string) bool {
+	return extensionMap[strings.ToLower(filepath.Ext(path))] == EncodingYAML
+}
+
+// jsonFileName replaces the file's extension with .json
+func jsonFileName(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".json"
+}
+
+func copyLocalFile(sourcePath string, targetPath string) error {
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	target, err := os.Create(targetPath)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+	_, err = io.Copy(target, source)
+	return err
+}
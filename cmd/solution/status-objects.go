@@ -0,0 +1,169 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/cmdkit/archive"
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+// SolutionInstallLogEntry is the installation state of a single object of an installed solution version
+type SolutionInstallLogEntry struct {
+	ObjectType string `json:"objectType"`
+	ObjectId   string `json:"objectId"`
+	Action     string `json:"action"`              // installed or missing
+	Timestamp  string `json:"timestamp,omitempty"` // when the installed object was last updated
+}
+
+const (
+	installLogInstalled = "installed"
+	installLogMissing   = "missing"
+)
+
+// solutionLayerObject is a knowledge object in the layer of a solution, as returned by the knowledge store
+type solutionLayerObject struct {
+	Id        string `json:"id"`
+	Data      any    `json:"data"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// getSolutionInstallLog returns the installation state of each object of an installed solution
+// version: the objects of the version's package are looked up in the solution's layer of the
+// knowledge store, where the platform installs them. The package is taken from the archives
+// cached by push or, if the version was not pushed from here, downloaded if it is the latest
+// version with the tag.
+func getSolutionInstallLog(solutionName string, solutionID string, tag string, version string) ([]SolutionInstallLogEntry, error) {
+	packageDir, err := getSolutionVersionPackage(solutionName, tag, version)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(packageDir)
+	manifest, err := getSolutionManifest(packageDir)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.SolutionVersion != version {
+		return nil, fmt.Errorf("the package of version %v is not available (the latest version with tag %v is %v)", version, tag, manifest.SolutionVersion)
+	}
+	objects, err := indexSolutionObjects(packageDir, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	// fetch the installed objects of each type
+	installed := map[solutionObjectKey]solutionLayerObject{}
+	headers := map[string]string{
+		"layer-type": "SOLUTION",
+		"layer-id":   solutionID,
+	}
+	for _, objType := range getObjectTypes(objects) {
+		var res api.CollectionResult[solutionLayerObject]
+		path := "knowledge-store/v1/objects/" + url.PathEscape(objType)
+		if err := api.JSONGetCollection[solutionLayerObject](path, &res, &api.Options{Headers: headers}); err != nil {
+			return nil, fmt.Errorf("failed to fetch the installed %v objects: %w", objType, err)
+		}
+		for _, obj := range res.Items {
+			id := getObjectIdentity(obj.Data)
+			if id == "" {
+				id = obj.Id
+			}
+			installed[solutionObjectKey{objType: objType, id: id}] = obj
+		}
+	}
+
+	installLog := []SolutionInstallLogEntry{}
+	for key := range objects {
+		entry := SolutionInstallLogEntry{ObjectType: key.objType, ObjectId: key.id, Action: installLogMissing}
+		if obj, found := installed[key]; found {
+			entry.Action = installLogInstalled
+			entry.Timestamp = obj.UpdatedAt
+			if entry.Timestamp == "" {
+				entry.Timestamp = obj.CreatedAt
+			}
+		}
+		installLog = append(installLog, entry)
+	}
+	sort.Slice(installLog, func(i, j int) bool {
+		if installLog[i].ObjectType != installLog[j].ObjectType {
+			return installLog[i].ObjectType < installLog[j].ObjectType
+		}
+		return installLog[i].ObjectId < installLog[j].ObjectId
+	})
+	return installLog, nil
+}
+
+// getSolutionVersionPackage extracts the package of a solution version into a temporary
+// directory, which the caller must remove
+func getSolutionVersionPackage(solutionName string, tag string, version string) (string, error) {
+	archivePath := getCachedSolutionArchive(solutionName, tag, version)
+	if archivePath == "" {
+		var err error
+		archivePath, err = DownloadSolutionPackage(solutionName, tag, "")
+		if err != nil {
+			return "", err
+		}
+		defer os.Remove(archivePath)
+	}
+	dir, err := os.MkdirTemp("", solutionName+"."+tag+"-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create a temporary directory: %w", err)
+	}
+	if err = archive.Extract(archivePath, afero.NewBasePathFs(afero.NewOsFs(), dir), archive.ExtractOptions{SkipLevels: 1}); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("failed to extract the solution archive: %w", err)
+	}
+	return dir, nil
+}
+
+// getObjectTypes returns the sorted types of the indexed objects
+func getObjectTypes(objects map[solutionObjectKey]any) []string {
+	types := map[string]bool{}
+	for key := range objects {
+		types[key.objType] = true
+	}
+	return sortedKeys(types)
+}
+
+// printInstallLog displays the installation state of each object, missing objects first
+func printInstallLog(cmd *cobra.Command, installLog []SolutionInstallLogEntry) {
+	if len(installLog) == 0 {
+		return
+	}
+	entries := make([]SolutionInstallLogEntry, len(installLog))
+	copy(entries, installLog)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Action == installLogMissing && entries[j].Action != installLogMissing
+	})
+
+	lines := [][]string{}
+	for _, entry := range entries {
+		lines = append(lines, []string{entry.Action, entry.ObjectType, entry.ObjectId, entry.Timestamp})
+	}
+	output.PrintCmdStatus(cmd, "Installation log:\n")
+	output.PrintCmdOutputCustom(cmd, entries, &output.Table{
+		Headers: []string{"Action", "Type", "Object", "Timestamp"},
+		Lines:   lines,
+	})
+}
@@ -35,6 +35,14 @@ type StatusData struct {
 	SuccessfulInstall bool   `json:"isSuccessful,omitempty"`
 	SolutionName      string `json:"solutionName,omitempty"`
 	SolutionVersion   string `json:"solutionVersion,omitempty"`
+	InstalledBy       string `json:"installedBy,omitempty"`
+}
+
+// solutionStatusOutput is the displayed status of a solution install, with the installation
+// state of each object of the installed version
+type solutionStatusOutput struct {
+	StatusData `yaml:",inline"`
+	InstallLog []SolutionInstallLogEntry `json:"installLog,omitempty" yaml:"installLog,omitempty"`
 }
 
 type StatusItem struct {
@@ -62,13 +70,28 @@ var solutionStatusCmd = &cobra.Command{
 	Use:   "status <solution-name> [flags]",
 	Args:  cobra.MaximumNArgs(1),
 	Short: "Get the status of a solution",
-	Long:  `This command provides the ability to see the installation and upload status of a solution.`,
+	Long: `This command provides the ability to see the installation and upload status of a solution.
+
+The installation log of the displayed install is shown after the status: each object of the installed version
+is looked up in the solution's layer of the knowledge store and shown as installed, with the time it was last
+updated, or missing. The objects of the version are read from the archive cached when it was pushed from this
+machine or, otherwise, from the latest version with the tag. Use --failed-only to show only the missing objects.
+The errors of a failed install are shown in its install message.
+
+With -o json or -o yaml, the status and the installation log are returned as a single document, which makes it
+easy to gate CI pipelines on the installation outcome, e.g.:
+
+  fsoc solution status spacefleet -o json | jq -e .isSuccessful`,
 	Example: `  fsoc solution status spacefleet
-  fsoc solution status spacefleet --solution-version 1.0.0`,
+  fsoc solution status spacefleet --solution-version 1.0.0
+  fsoc solution status spacefleet --failed-only
+  fsoc solution status spacefleet -o json`,
 	Run: func(cmd *cobra.Command, args []string) {
+		report := startReport(cmd)
 		if err := getSolutionStatus(cmd, args); err != nil {
 			log.Fatalf(err.Error())
 		}
+		report.finish()
 	},
 	TraverseChildren: true,
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -92,6 +115,11 @@ func getSolutionStatusCmd() *cobra.Command {
 	solutionStatusCmd.Flags().
 		String("tag", "", "The tag associated with the solution for which you would like to view the status for")
 
+	solutionStatusCmd.Flags().
+		Bool("failed-only", false, "Show only the objects that are missing in the installation log")
+
+	addReportFlag(solutionStatusCmd)
+
 	return solutionStatusCmd
 }
 
@@ -262,15 +290,15 @@ func getSolutionStatus(cmd *cobra.Command, args []string) error {
 		values = append(values, value)
 	}
 
-	if solutionTag != "" {
-		appendValue("Solution Tag", solutionTag)
-	} else {
+	displayTag := solutionTag
+	if displayTag == "" {
 		if !strings.Contains(solutionID, ".") {
-			appendValue("Solution Tag", "stable")
+			displayTag = "stable"
 		} else {
-			appendValue("Solution Tag", strings.SplitAfter(solutionID, ".")[1])
+			displayTag = strings.SplitAfter(solutionID, ".")[1]
 		}
 	}
+	appendValue("Solution Tag", displayTag)
 
 	if isTenantSubscribedToSolution {
 		appendValue("Solution Subscription Status", "Subscribed")
@@ -289,13 +317,44 @@ func getSolutionStatus(cmd *cobra.Command, args []string) error {
 	}
 	appendValue(fmt.Sprintf("%s Install Time", solutionInstallationMessagePrefix), installStatusData.InstallTime)
 	appendValue(fmt.Sprintf("%s Install Message", solutionInstallationMessagePrefix), installStatusData.InstallMessage)
+	appendValue(fmt.Sprintf("%s Installed By", solutionInstallationMessagePrefix), installStatusData.InstalledBy)
+
+	// fetch the installation state of each object of the displayed install
+	var installLog []SolutionInstallLogEntry
+	if installStatusData.SolutionVersion != "" {
+		installLog, err = getSolutionInstallLog(solutionName, solutionID, displayTag, installStatusData.SolutionVersion)
+		if err != nil {
+			log.Warnf("The installation log of version %v is not available: %v", installStatusData.SolutionVersion, err)
+		}
+	}
+	if failedOnly, _ := cmd.Flags().GetBool("failed-only"); failedOnly {
+		missing := []SolutionInstallLogEntry{}
+		for _, entry := range installLog {
+			if entry.Action == installLogMissing {
+				missing = append(missing, entry)
+			}
+		}
+		installLog = missing
+	}
 
-	output.PrintCmdOutputCustom(cmd, installStatusData, &output.Table{
+	report := getReport(cmd)
+	report.setSolution(solutionID, installStatusData.SolutionVersion, solutionTag)
+	report.setInstallLog(installLog)
+	if installStatusData.SolutionVersion != "" && !installStatusData.SuccessfulInstall {
+		report.setOutcome(reportOutcomeInstallFailed)
+	}
+
+	output.PrintCmdOutputCustom(cmd, solutionStatusOutput{installStatusData, installLog}, &output.Table{
 		Headers: headers,
 		Lines:   [][]string{values},
 		Detail:  true,
 	})
 
+	// machine-readable formats include the log in the status document above
+	if format, _ := cmd.Flags().GetString("output"); format == "" || format == "auto" || format == "table" || format == "detail" {
+		printInstallLog(cmd, installLog)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,214 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+// tenantsFile lists the tenants, by profile, to change the subscription of in bulk
+type tenantsFile struct {
+	Tenants []bulkTenant `yaml:"tenants"`
+}
+
+type bulkTenant struct {
+	Profile string `yaml:"profile"`
+	Tag     string `yaml:"tag,omitempty"` // overrides the --tag flag for this tenant
+}
+
+// BulkSubscriptionResult is the outcome of a subscription change for one tenant
+type BulkSubscriptionResult struct {
+	Profile  string `json:"profile" yaml:"profile"`
+	Tenant   string `json:"tenant" yaml:"tenant"`
+	Solution string `json:"solution,omitempty" yaml:"solution,omitempty"`
+	Result   string `json:"result" yaml:"result"`
+	Error    string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// addBulkSubscriptionFlags adds the flags selecting the tenants to subscribe or unsubscribe in bulk
+func addBulkSubscriptionFlags(cmd *cobra.Command) {
+	cmd.Flags().
+		String("tenants-file", "", "Path to a YAML file listing the profiles of the tenants to apply the change to")
+	cmd.Flags().
+		String("contexts", "", "Apply the change to the tenants of all profiles whose name matches a pattern, e.g., 'prod-*'")
+	cmd.Flags().
+		Int("concurrency", 4, "Maximum number of tenants updated concurrently with --tenants-file or --contexts")
+	cmd.MarkFlagsMutuallyExclusive("tenants-file", "contexts")
+}
+
+// isBulkSubscription returns true if the subscription change applies to multiple tenants
+func isBulkSubscription(cmd *cobra.Command) bool {
+	return cmd.Flags().Changed("tenants-file") || cmd.Flags().Changed("contexts")
+}
+
+// manageBulkSubscription subscribes or unsubscribes the tenants selected with the --tenants-file or
+// --contexts flags, concurrently, and displays the result for each tenant
+func manageBulkSubscription(cmd *cobra.Command, name string, tag string, isSubscribed bool) {
+	tenants, err := getBulkTenants(cmd, tag)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	version, _ := cmd.Flags().GetString("version") // not defined for unsubscribe
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+
+	// the impact of unsubscribing is not checked for each tenant
+	if !isSubscribed {
+		if force, _ := cmd.Flags().GetBool("force"); !force {
+			log.Fatalf("Dependent solutions are not checked when unsubscribing multiple tenants; use --force to unsubscribe anyway")
+		}
+		profiles := make([]string, len(tenants))
+		for i, tenant := range tenants {
+			profiles[i] = tenant.Profile
+		}
+		confirmUnsubscribe(cmd, name, fmt.Sprintf("WARNING! Unsubscribing the tenants of profiles %v from solution %s will disable the solution's objects for these tenants.", strings.Join(profiles, ", "), name))
+	}
+
+	results := parallelMapN(tenants, concurrency, func(tenant bulkTenant) BulkSubscriptionResult {
+		return changeTenantSubscription(tenant, name, version, isSubscribed)
+	})
+
+	// display results
+	failed := 0
+	lines := make([][]string, len(results))
+	for i, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+		lines[i] = []string{result.Profile, result.Tenant, result.Solution, result.Result, result.Error}
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []BulkSubscriptionResult `json:"items"`
+		Total int                      `json:"total"`
+	}{results, len(results)}, &output.Table{
+		Headers: []string{"Profile", "Tenant", "Solution", "Result", "Error"},
+		Lines:   lines,
+	})
+	action := "subscribed to"
+	if !isSubscribed {
+		action = "unsubscribed from"
+	}
+	output.PrintCmdStatus(cmd, fmt.Sprintf("%d of %d tenant(s) %s solution %s.\n", len(results)-failed, len(results), action, name))
+	if failed > 0 {
+		log.Fatalf("Failed to change the subscription of %d tenant(s)", failed)
+	}
+}
+
+// getBulkTenants returns the tenants selected with the --tenants-file or --contexts flags;
+// all profiles must exist
+func getBulkTenants(cmd *cobra.Command, tag string) ([]bulkTenant, error) {
+	tenants := []bulkTenant{}
+	if filePath, _ := cmd.Flags().GetString("tenants-file"); filePath != "" {
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tenants file: %w", err)
+		}
+		var contents tenantsFile
+		if err := yaml.Unmarshal(data, &contents); err != nil {
+			return nil, fmt.Errorf("failed to parse tenants file %q: %w", filePath, err)
+		}
+		for _, tenant := range contents.Tenants {
+			if tenant.Profile == "" {
+				return nil, fmt.Errorf("tenants file %q has an entry without a profile", filePath)
+			}
+			if _, err := config.GetContext(tenant.Profile); err != nil {
+				return nil, fmt.Errorf("tenants file %q: %w", filePath, err)
+			}
+			if tenant.Tag == "" {
+				tenant.Tag = tag
+			}
+			tenants = append(tenants, tenant)
+		}
+	} else {
+		pattern, _ := cmd.Flags().GetString("contexts")
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid profile name pattern %q: %w", pattern, err)
+		}
+		profiles := config.ListAllContexts()
+		slices.Sort(profiles)
+		for _, profile := range profiles {
+			if matched, _ := path.Match(pattern, profile); matched {
+				tenants = append(tenants, bulkTenant{Profile: profile, Tag: tag})
+			}
+		}
+	}
+	if len(tenants) == 0 {
+		return nil, fmt.Errorf("no tenants selected")
+	}
+	return tenants, nil
+}
+
+// changeTenantSubscription subscribes or unsubscribes the tenant of a profile; errors are
+// returned in the result
+func changeTenantSubscription(tenant bulkTenant, name string, version string, isSubscribed bool) BulkSubscriptionResult {
+	result := BulkSubscriptionResult{Profile: tenant.Profile, Result: "failed"}
+	if cfg, err := config.GetContext(tenant.Profile); err == nil {
+		result.Tenant = cfg.Tenant
+	}
+	logger := log.WithFields(log.Fields{"profile": tenant.Profile, "solution": name, "tag": tenant.Tag})
+
+	objectUrl := locateSolutionUrl(tenant.Profile, name, tenant.Tag)
+	result.Solution = path.Base(objectUrl)
+
+	if isSubscribed && version != "" {
+		installedVersion, err := getInstalledSolutionVersion(tenant.Profile, result.Solution)
+		if err != nil {
+			result.Error = fmt.Sprintf("failed to get the installed version: %v", err)
+			return result
+		}
+		if installedVersion != version {
+			result.Error = fmt.Sprintf("version %q installed, not %q", installedVersion, version)
+			return result
+		}
+	}
+	if !isSubscribed {
+		isSystem, err := isSystemSolution(tenant.Profile, objectUrl)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		if isSystem {
+			result.Error = "cannot unsubscribe from a system solution"
+			return result
+		}
+	}
+
+	logger.Info("Changing subscription")
+	var res any
+	subscribe := subscriptionStruct{IsSubscribed: isSubscribed}
+	options := &api.Options{Headers: getProfileHeaders(tenant.Profile), Profile: tenant.Profile, Quiet: true}
+	if err := api.JSONPatch(objectUrl, &subscribe, &res, options); err != nil {
+		logger.WithError(err).Warn("Failed to change subscription")
+		result.Error = err.Error()
+		return result
+	}
+	if isSubscribed {
+		result.Result = "subscribed"
+	} else {
+		result.Result = "unsubscribed"
+	}
+	return result
+}
@@ -16,6 +16,7 @@ package solution
 
 import (
 	"fmt"
+	"path"
 
 	"github.com/apex/log"
 	"github.com/spf13/cobra"
@@ -30,11 +31,31 @@ type subscriptionStruct struct {
 }
 
 var solutionSubscribeCmd = &cobra.Command{
-	Use:              "subscribe <solution-name>",
-	Args:             cobra.MaximumNArgs(1),
-	Short:            "Subscribe to a solution",
-	Long:             `This command allows the current tenant specified in the profile to subscribe to a solution.`,
-	Example:          `	fsoc solution subscribe spacefleet`,
+	Use:   "subscribe <solution-name>",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Subscribe to a solution",
+	Long: `This command allows the current tenant specified in the profile to subscribe to a solution.
+
+The tenant subscribes to the solution released with a tag (release stage), e.g., dev or stable, and receives
+the versions subsequently pushed or promoted with that tag. Use --version to pin the subscription to the
+expected version: the command fails if a different version is currently installed with the tag.
+
+Use --tenants-file or --contexts to subscribe multiple tenants, e.g., all production tenants, instead of the
+current one. The tenants file lists the profiles of the tenants, optionally with a tag for each:
+
+  tenants:
+    - profile: prod-us
+    - profile: prod-eu
+      tag: stable
+
+The --contexts flag selects the profiles whose name matches a pattern. Up to --concurrency tenants are updated
+at the same time; the result for each tenant is displayed at the end and the command fails if any tenant
+could not be subscribed.`,
+	Example: `	fsoc solution subscribe spacefleet
+	fsoc solution subscribe spacefleet --tag dev
+	fsoc solution subscribe spacefleet --tag stable --version 1.2.3
+	fsoc solution subscribe spacefleet --tenants-file tenants.yaml
+	fsoc solution subscribe spacefleet --contexts 'prod-*' --concurrency 8`,
 	Run:              subscribeToSolution,
 	TraverseChildren: true,
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -49,6 +70,9 @@ func getSubscribeSolutionCmd() *cobra.Command {
 	_ = solutionSubscribeCmd.Flags().MarkDeprecated("name", "please use argument instead.")
 	solutionSubscribeCmd.Flags().
 		String("tag", "", "The tag related to the solution to subscribe to. This will default to the stable version of the solution if not specified")
+	solutionSubscribeCmd.Flags().
+		String("version", "", "Subscribe only if this version of the solution is installed with the tag")
+	addBulkSubscriptionFlags(solutionSubscribeCmd)
 
 	return solutionSubscribeCmd
 
@@ -72,19 +96,40 @@ func manageSubscription(cmd *cobra.Command, args []string, isSubscribed bool) {
 		log.Fields{"solution": name, "tag": tag},
 	).Info(message)
 
+	if isBulkSubscription(cmd) {
+		manageBulkSubscription(cmd, name, tag, isSubscribed)
+		return
+	}
+
 	// locate solution object (temporary support for now-deprecated
 	// pseudo-isolation)
-	objectUrl := locateSolutionUrl(name, tag)
+	objectUrl := locateSolutionUrl("", name, tag)
+
+	// verify the pinned version, if any
+	if version, _ := cmd.Flags().GetString("version"); isSubscribed && version != "" {
+		solutionId := path.Base(objectUrl)
+		installedVersion, err := getInstalledSolutionVersion("", solutionId)
+		if err != nil {
+			log.Fatalf("Failed to get the installed version of solution %s: %v", solutionId, err)
+		}
+		if installedVersion != version {
+			log.Fatalf("Solution %s has version %q installed, not %q; promote or push version %v first", solutionId, installedVersion, version, version)
+		}
+	}
 
 	// reject attempts to unsubscribe from a system solution
 	if !isSubscribed {
-		isSystemSolution, err := isSystemSolution(objectUrl)
+		isSystemSolution, err := isSystemSolution("", objectUrl)
 		if err != nil {
 			log.Fatalf("Failed to get solution status: %v", err)
 		}
 		if isSystemSolution {
 			log.Fatalf("Cannot unsubscribe tenant from solution %s because it is a system solution", name)
 		}
+
+		force, _ := cmd.Flags().GetBool("force")
+		checkUninstall(cmd, name, tag, force)
+		confirmUnsubscribe(cmd, name, fmt.Sprintf("WARNING! Unsubscribing tenant %s from solution %s will disable the solution's objects for the tenant.", config.GetCurrentContext().Tenant, name))
 	}
 
 	// update subscription status in solution object at the tenant layer
@@ -105,7 +150,10 @@ func manageSubscription(cmd *cobra.Command, args []string, isSubscribed bool) {
 	output.PrintCmdStatus(cmd, message)
 }
 
-func locateSolutionUrl(name string, tag string) string {
+// locateSolutionUrl returns the URL of the solution object for a solution released with a tag,
+// in the tenant of a profile (the current profile if empty); calls for other profiles are made
+// concurrently, without a spinner
+func locateSolutionUrl(profile string, name string, tag string) string {
 	// handle stable tag where solution ID == solution name
 	if tag == "" || tag == "stable" || tag == "dev" {
 		return getSolutionObjectUrl(name)
@@ -114,7 +162,7 @@ func locateSolutionUrl(name string, tag string) string {
 	// first, try to find the solution using native isolation
 	url := getSolutionObjectUrl(name + "." + tag)
 	var data any
-	err := api.JSONGet(url, &data, &api.Options{Headers: getHeaders(), ExpectedErrors: []int{404}})
+	err := api.JSONGet(url, &data, &api.Options{Headers: getProfileHeaders(profile), Profile: profile, Quiet: profile != "", ExpectedErrors: []int{404}})
 	if err == nil {
 		return url
 	}
@@ -122,7 +170,11 @@ func locateSolutionUrl(name string, tag string) string {
 	// next, construct a pseudo-isolated solution's name
 	// respecting different rules for dev and prod environments
 	// (dev environments don't allow 'dev' tag)
-	if config.GetCurrentContext().EnvType == "dev" {
+	cfg := config.GetCurrentContext()
+	if profile != "" {
+		cfg, _ = config.GetContext(profile) // validated when getting the headers above
+	}
+	if cfg.EnvType == "dev" {
 		name = name + tag // no ".dev" is needed for dev environments
 	} else if tag == "dev" {
 		name = name + ".dev" // no pseudo-isolation, just set the ".dev" suffix
@@ -133,12 +185,12 @@ func locateSolutionUrl(name string, tag string) string {
 	return getSolutionObjectUrl(name)
 }
 
-func isSystemSolution(objUrl string) (bool, error) {
+func isSystemSolution(profile string, objUrl string) (bool, error) {
 	var solData struct {
 		Data SolutionDef `json:"data"`
 	}
 
-	err := api.JSONGet(objUrl, &solData, &api.Options{Headers: getHeaders()})
+	err := api.JSONGet(objUrl, &solData, &api.Options{Headers: getProfileHeaders(profile), Profile: profile, Quiet: profile != ""})
 	if err != nil {
 		return false, fmt.Errorf("failed to get solution info: %v", err)
 	}
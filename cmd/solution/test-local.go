@@ -0,0 +1,361 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/output"
+)
+
+// LocalTestFile is the content of an assertion file for local solution tests
+type LocalTestFile struct {
+	Tests []LocalTestAssertion `json:"tests" yaml:"tests"`
+}
+
+// LocalTestAssertion describes the expectations for a single object of the solution.
+// The object is identified by its type (e.g., fmm:entity or dashui:template) and its
+// name, qualified with the namespace for FMM types (e.g., k8s:deployment).
+type LocalTestAssertion struct {
+	Name        string         `json:"name,omitempty" yaml:"name,omitempty"`
+	Type        string         `json:"type" yaml:"type"`
+	Object      string         `json:"object" yaml:"object"`
+	Attributes  []string       `json:"attributes,omitempty" yaml:"attributes,omitempty"`   // attributes the object defines
+	Required    []string       `json:"required,omitempty" yaml:"required,omitempty"`       // attributes the object requires
+	MetricTypes []string       `json:"metricTypes,omitempty" yaml:"metricTypes,omitempty"` // metrics an entity reports
+	EventTypes  []string       `json:"eventTypes,omitempty" yaml:"eventTypes,omitempty"`   // events an entity reports
+	Fields      map[string]any `json:"fields,omitempty" yaml:"fields,omitempty"`           // expected field values, by dotted path
+}
+
+// LocalTestResult is the outcome of a cross-reference check or an assertion
+type LocalTestResult struct {
+	Test    string         `json:"test" yaml:"test"`
+	File    string         `json:"file" yaml:"file"`
+	Passed  bool           `json:"passed" yaml:"passed"`
+	Message string         `json:"message,omitempty" yaml:"message,omitempty"`
+	object  map[string]any // the referencing object, for cross-reference checks
+	ref     string         // the referenced type, for cross-reference checks
+}
+
+// localTestModel indexes the solution's objects by type and qualified name
+type localTestModel struct {
+	objects    map[string]map[string]map[string]any // type -> name -> object
+	files      map[string]map[string]string         // type -> name -> file
+	namespaces map[string]bool                      // FMM namespaces defined by the solution
+}
+
+// testSolutionLocally loads the solution's objects, checks the references between them and
+// runs the assertion files, without access to the platform
+func testSolutionLocally(cmd *cobra.Command) {
+	solutionRootDirectory, _ := cmd.Flags().GetString("directory")
+	if solutionRootDirectory == "" {
+		solutionRootDirectory = "."
+	}
+	solutionRootDirectory = absolutizePath(solutionRootDirectory)
+	manifest, err := getSolutionManifest(solutionRootDirectory)
+	if err != nil {
+		log.Fatalf("Failed to read the solution manifest: %v", err)
+	}
+	objectFiles, errs := loadManifestObjects(solutionRootDirectory, manifest)
+	if len(errs) > 0 {
+		for _, err := range errs {
+			log.Errorf("Failed to load %v", err)
+		}
+		log.Fatalf("Failed to load %d object file(s)", len(errs))
+	}
+	model := newLocalTestModel(objectFiles)
+
+	results := checkLocalReferences(model)
+	assertionPaths, _ := cmd.Flags().GetStringSlice("assertions")
+	for _, path := range assertionPaths {
+		files, err := findAssertionFiles(path)
+		if err != nil {
+			log.Fatalf("Failed to find assertion files in %q: %v", path, err)
+		}
+		for _, file := range files {
+			var testFile LocalTestFile
+			doc, err := readObjectsFile(file)
+			if err == nil {
+				err = remarshal(doc, &testFile)
+			}
+			if err != nil {
+				log.Fatalf("Failed to read assertion file %q: %v", file, err)
+			}
+			for _, assertion := range testFile.Tests {
+				results = append(results, model.runAssertion(file, &assertion))
+			}
+		}
+	}
+
+	nFailed := 0
+	lines := [][]string{}
+	for _, result := range results {
+		status := "pass"
+		if !result.Passed {
+			status = "FAIL"
+			nFailed++
+		}
+		lines = append(lines, []string{status, result.Test, result.File, result.Message})
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []LocalTestResult `json:"items"`
+		Total int               `json:"total"`
+	}{results, len(results)}, &output.Table{Headers: []string{"Status", "Test", "File", "Message"}, Lines: lines})
+	if nFailed > 0 {
+		log.Fatalf("%d of %d test(s) failed", nFailed, len(results))
+	}
+	output.PrintCmdStatus(cmd, fmt.Sprintf("All %d test(s) passed\n", len(results)))
+}
+
+func newLocalTestModel(objectFiles []manifestObjectsFile) *localTestModel {
+	model := &localTestModel{
+		objects:    map[string]map[string]map[string]any{},
+		files:      map[string]map[string]string{},
+		namespaces: map[string]bool{},
+	}
+	for _, file := range objectFiles {
+		if model.objects[file.objType] == nil {
+			model.objects[file.objType] = map[string]map[string]any{}
+			model.files[file.objType] = map[string]string{}
+		}
+		for _, obj := range file.objects {
+			objMap, _ := obj.(map[string]any)
+			name := getLocalObjectName(objMap)
+			if name == "" {
+				continue
+			}
+			model.objects[file.objType][name] = objMap
+			model.files[file.objType][name] = file.path
+			if namespace, _, found := strings.Cut(name, ":"); found && strings.HasPrefix(file.objType, "fmm:") {
+				model.namespaces[namespace] = true
+			}
+		}
+	}
+	return model
+}
+
+// getLocalObjectName returns the object's name, qualified with the namespace for FMM objects
+func getLocalObjectName(obj map[string]any) string {
+	name, _ := obj["name"].(string)
+	if name == "" {
+		name, _ = obj["id"].(string)
+	}
+	namespace, _ := obj["namespace"].(map[string]any)
+	if nsName, _ := namespace["name"].(string); nsName != "" && name != "" {
+		return nsName + ":" + name
+	}
+	return name
+}
+
+// isLocalReference returns true if the type name refers to a namespace defined by the solution;
+// references to other namespaces belong to dependencies and cannot be checked locally
+func (model *localTestModel) isLocalReference(typeName string) bool {
+	namespace, _, found := strings.Cut(typeName, ":")
+	return found && model.namespaces[namespace]
+}
+
+func (model *localTestModel) exists(objType string, name string) bool {
+	_, found := model.objects[objType][name]
+	return found
+}
+
+// checkLocalReferences verifies that the types referenced by the solution's objects
+// within the solution's own namespaces are defined by the solution
+func checkLocalReferences(model *localTestModel) []LocalTestResult {
+	results := []LocalTestResult{}
+	check := func(objType string, name string, refType string, refs []string) {
+		for _, ref := range refs {
+			if !model.isLocalReference(ref) {
+				continue
+			}
+			result := LocalTestResult{
+				Test:   fmt.Sprintf("%v %v references %v %v", objType, name, refType, ref),
+				File:   model.files[objType][name],
+				Passed: model.exists(refType, ref),
+				object: model.objects[objType][name],
+				ref:    ref,
+			}
+			if !result.Passed {
+				result.Message = fmt.Sprintf("%v %q is not defined in the solution", refType, ref)
+			}
+			results = append(results, result)
+		}
+	}
+
+	for _, name := range sortedKeys(model.objects["fmm:entity"]) {
+		var entity FmmEntity
+		if err := remarshal(model.objects["fmm:entity"][name], &entity); err != nil {
+			continue
+		}
+		check("fmm:entity", name, "fmm:metric", entity.MetricTypes)
+		check("fmm:entity", name, "fmm:event", entity.EventTypes)
+		if entity.AssociationTypes != nil {
+			assoc := entity.AssociationTypes
+			for _, targets := range [][]string{assoc.Aggregates_of, assoc.Consists_of, assoc.Is_a, assoc.Has, assoc.Relates_to, assoc.Uses} {
+				check("fmm:entity", name, "fmm:entity", targets)
+			}
+		}
+	}
+	for _, name := range sortedKeys(model.objects["fmm:resourceMapping"]) {
+		var mapping FmmResourceMapping
+		if err := remarshal(model.objects["fmm:resourceMapping"][name], &mapping); err == nil {
+			check("fmm:resourceMapping", name, "fmm:entity", []string{mapping.EntityType})
+		}
+	}
+	for _, name := range sortedKeys(model.objects["fmm:associationDeclaration"]) {
+		var declaration FmmAssociationDeclaration
+		if err := remarshal(model.objects["fmm:associationDeclaration"][name], &declaration); err == nil {
+			check("fmm:associationDeclaration", name, "fmm:entity", []string{declaration.FromType, declaration.ToType})
+		}
+	}
+	for _, name := range sortedKeys(model.objects["dashui:template"]) {
+		var template DashuiTemplate
+		if err := remarshal(model.objects["dashui:template"][name], &template); err == nil {
+			check("dashui:template", name, "fmm:entity", []string{template.Target})
+		}
+	}
+	for _, name := range sortedKeys(model.objects["dashui:templatePropsExtension"]) {
+		var extension DashuiTemplatePropsExtension
+		if err := remarshal(model.objects["dashui:templatePropsExtension"][name], &extension); err == nil {
+			check("dashui:templatePropsExtension", name, "fmm:entity", append([]string{extension.Target}, extension.RequiredEntityTypes...))
+		}
+	}
+	return results
+}
+
+// runAssertion checks a single assertion against the solution's objects
+func (model *localTestModel) runAssertion(file string, assertion *LocalTestAssertion) LocalTestResult {
+	result := LocalTestResult{Test: assertion.Name, File: file}
+	if result.Test == "" {
+		result.Test = fmt.Sprintf("%v %v", assertion.Type, assertion.Object)
+	}
+	obj, found := model.objects[assertion.Type][assertion.Object]
+	if !found {
+		result.Message = fmt.Sprintf("%v %q is not defined in the solution", assertion.Type, assertion.Object)
+		return result
+	}
+
+	failures := []string{}
+	attrDefs, _ := obj["attributeDefinitions"].(map[string]any)
+	attributes, _ := attrDefs["attributes"].(map[string]any)
+	for _, attr := range assertion.Attributes {
+		if _, found := attributes[attr]; !found {
+			failures = append(failures, fmt.Sprintf("attribute %q is not defined", attr))
+		}
+	}
+	required := toStringList(attrDefs["required"])
+	for _, attr := range assertion.Required {
+		if !slices.Contains(required, attr) {
+			failures = append(failures, fmt.Sprintf("attribute %q is not required", attr))
+		}
+	}
+	metricTypes := toStringList(obj["metricTypes"])
+	for _, metric := range assertion.MetricTypes {
+		if !slices.Contains(metricTypes, metric) {
+			failures = append(failures, fmt.Sprintf("metric %q is not reported", metric))
+		}
+	}
+	eventTypes := toStringList(obj["eventTypes"])
+	for _, event := range assertion.EventTypes {
+		if !slices.Contains(eventTypes, event) {
+			failures = append(failures, fmt.Sprintf("event %q is not reported", event))
+		}
+	}
+	for _, path := range sortedKeys(assertion.Fields) {
+		actual, found := getFieldByPath(obj, path)
+		if !found {
+			failures = append(failures, fmt.Sprintf("field %q is not set", path))
+			continue
+		}
+		expectedJson, _ := json.Marshal(assertion.Fields[path])
+		actualJson, _ := json.Marshal(actual)
+		if string(expectedJson) != string(actualJson) {
+			failures = append(failures, fmt.Sprintf("field %q is %s, expected %s", path, actualJson, expectedJson))
+		}
+	}
+
+	result.Passed = len(failures) == 0
+	result.Message = strings.Join(failures, "; ")
+	return result
+}
+
+// getFieldByPath returns the value at the dotted path within the object,
+// e.g., "lifecycleConfiguration.purgeTtlInMinutes"
+func getFieldByPath(obj map[string]any, path string) (any, bool) {
+	var current any = obj
+	for _, key := range strings.Split(path, ".") {
+		m, isMap := current.(map[string]any)
+		if !isMap {
+			return nil, false
+		}
+		var found bool
+		if current, found = m[key]; !found {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// findAssertionFiles returns the assertion file at path or, if path is a directory,
+// all object files within it
+func findAssertionFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	files := []string{}
+	err = filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && isObjectsFile(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+func toStringList(v any) []string {
+	items, _ := v.([]any)
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
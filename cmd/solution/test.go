@@ -26,18 +26,44 @@ import (
 	"github.com/apex/log"
 	"github.com/spf13/cobra"
 
+	"github.com/cisco-open/fsoc/config"
 	"github.com/cisco-open/fsoc/output"
 	"github.com/cisco-open/fsoc/platform/api"
 )
 
 var solutionTestCmd = &cobra.Command{
-	Use:              "test",
-	Args:             cobra.ExactArgs(0),
-	Short:            "Test Solution",
-	Long:             "This command allows the current tenant specified in the profile to run tests against an already deployed solution",
-	Example:          `  fsoc solution test`,
+	Use:   "test",
+	Args:  cobra.ExactArgs(0),
+	Short: "Test Solution",
+	Long: `This command allows the current tenant specified in the profile to run tests against an already deployed solution.
+
+With the --local flag, the solution's objects are tested offline, without deploying the solution. The FMM
+entities, metrics, events, resource mappings and association declarations and the dashui templates are loaded
+and the references between them are checked: every metric, event, entity or template target referenced within
+the solution's own namespaces must be defined by the solution. References to other namespaces belong to
+dependencies and are not checked.
+
+Use --assertions to also run assertion files (YAML or JSON; a directory is searched for all such files), e.g.:
+
+  tests:
+    - name: host entity has name attribute
+      type: fmm:entity
+      object: infra:host
+      attributes: [host.name]
+      required: [host.name]
+      metricTypes: [infra:cpu.utilization]
+      fields:
+        lifecycleConfiguration.purgeTtlInMinutes: 4200
+
+The command fails if any check or assertion fails, making it suitable for CI.`,
+	Example: `  fsoc solution test
+  fsoc solution test --local
+  fsoc solution test --local -d mysolution --assertions mysolution-tests/`,
 	Run:              testSolution,
 	TraverseChildren: true,
+	Annotations: map[string]string{
+		config.AnnotationForConfigBypass: "", // needed only when not --local, checked in testSolution
+	},
 }
 
 var solutionTestStatusCmd = &cobra.Command{
@@ -55,6 +81,18 @@ func getSolutionTestCmd() *cobra.Command {
 	solutionTestCmd.Flags().String("initial-delay", "", "Time duration (in seconds) that the Test Runner should wait before making first call to UQL")
 	solutionTestCmd.Flags().String("max-retry-count", "", "Maximum Number of times the Test Runner should call UQL to get latest data. Depending on the error code returned by UQL, retry will be initiated.")
 	solutionTestCmd.Flags().String("retry-delay", "", "Time duration (in seconds) that the Test Runner should wait between retries")
+
+	solutionTestCmd.Flags().
+		Bool("local", false, "Test the solution's objects offline, without deploying the solution")
+	solutionTestCmd.Flags().
+		StringP("directory", "d", "", "Path to the solution root directory, for --local (defaults to current dir)")
+	solutionTestCmd.Flags().
+		StringSlice("assertions", nil, "Assertion files or directories to run, for --local; can be repeated")
+	solutionTestCmd.MarkFlagsMutuallyExclusive("local", "test-bundle")
+	solutionTestCmd.MarkFlagsMutuallyExclusive("local", "initial-delay")
+	solutionTestCmd.MarkFlagsMutuallyExclusive("local", "max-retry-count")
+	solutionTestCmd.MarkFlagsMutuallyExclusive("local", "retry-delay")
+
 	return solutionTestCmd
 }
 
@@ -72,6 +110,21 @@ func getSolutionTestStatusCmd() *cobra.Command {
 // Once all this parsing is done, the command will prepare the payload for test-runner; Make http call to it and print the `test-run-id“ string that it gets from the test-runner.
 // The test-run-id returned by this command should be used to check status of the test using `fsoc solution test-status` command.
 func testSolution(cmd *cobra.Command, args []string) {
+	if local, _ := cmd.Flags().GetBool("local"); local {
+		testSolutionLocally(cmd)
+		return
+	}
+	for _, flag := range []string{"directory", "assertions"} {
+		if cmd.Flags().Changed(flag) {
+			log.Fatalf("The --%v flag requires --local", flag)
+		}
+	}
+
+	// the command bypasses the config check to allow local tests, so check here
+	if config.GetCurrentContext() == nil {
+		log.Fatal(`fsoc is not configured, please use "fsoc config create" to configure an initial context`)
+	}
+
 	var testBundleDir string
 	testBundlePath, _ := cmd.Flags().GetString("test-bundle")
 	initialDelay, _ := cmd.Flags().GetString("initial-delay")
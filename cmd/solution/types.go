@@ -16,13 +16,13 @@ package solution
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
-	"github.com/apex/log"
+	"github.com/cisco-open/fsoc/cmdkit/jsondoc"
 )
 
 type FileFormat int8
@@ -45,25 +45,32 @@ const (
 )
 
 type Manifest struct {
-	ManifestVersion string         `json:"manifestVersion,omitempty" yaml:"manifestVersion,omitempty"`
-	ManifestFormat  FileFormat     `json:"-" yaml:"-"` // not serialized, in memory
-	Name            string         `json:"name,omitempty" yaml:"name,omitempty"`
-	SolutionVersion string         `json:"solutionVersion,omitempty" yaml:"solutionVersion,omitempty"`
-	SolutionType    string         `json:"solutionType,omitempty" yaml:"solutionType,omitempty"`
-	Dependencies    []string       `json:"dependencies" yaml:"dependencies"`
-	Description     string         `json:"description,omitempty" yaml:"description,omitempty"`
-	Contact         string         `json:"contact,omitempty" yaml:"contact,omitempty"`
-	HomePage        string         `json:"homepage,omitempty" yaml:"homepage,omitempty"`
-	GitRepoUrl      string         `json:"gitRepoUrl,omitempty" yaml:"gitRepoUrl,omitempty"`
-	Readme          string         `json:"readme,omitempty" yaml:"readme,omitempty"`
-	Objects         []ComponentDef `json:"objects,omitempty" yaml:"objects,omitempty"`
-	Types           []string       `json:"types,omitempty" yaml:"types,omitempty"`
+	ManifestVersion string               `json:"manifestVersion,omitempty" yaml:"manifestVersion,omitempty"`
+	ManifestFormat  FileFormat           `json:"-" yaml:"-"` // not serialized, in memory
+	Name            string               `json:"name,omitempty" yaml:"name,omitempty"`
+	SolutionVersion string               `json:"solutionVersion,omitempty" yaml:"solutionVersion,omitempty"`
+	SolutionType    string               `json:"solutionType,omitempty" yaml:"solutionType,omitempty"`
+	Dependencies    []string             `json:"dependencies" yaml:"dependencies"`
+	Description     string               `json:"description,omitempty" yaml:"description,omitempty"`
+	Contact         string               `json:"contact,omitempty" yaml:"contact,omitempty"`
+	HomePage        string               `json:"homepage,omitempty" yaml:"homepage,omitempty"`
+	GitRepoUrl      string               `json:"gitRepoUrl,omitempty" yaml:"gitRepoUrl,omitempty"`
+	Readme          string               `json:"readme,omitempty" yaml:"readme,omitempty"`
+	Objects         []ComponentDef       `json:"objects,omitempty" yaml:"objects,omitempty"`
+	Types           []string             `json:"types,omitempty" yaml:"types,omitempty"`
+	Variables       map[string]string    `json:"variables,omitempty" yaml:"variables,omitempty"`         // template variables, removed when packaging
+	Includes        []string             `json:"includes,omitempty" yaml:"includes,omitempty"`           // manifest files merged at load time, removed when packaging
+	Secrets         map[string]SecretDef `json:"secrets,omitempty" yaml:"secrets,omitempty"`             // secrets provided when pushing, removed when packaging
+	DefaultLocale   string               `json:"defaultLocale,omitempty" yaml:"defaultLocale,omitempty"` // locale of the ${msg:key} texts, removed when packaging
+	included        *manifestFragment    // content merged from the included files, not saved
 }
 
 type ComponentDef struct {
-	Type        string `json:"type,omitempty" yaml:"type,omitempty"`
-	ObjectsFile string `json:"objectsFile,omitempty" yaml:"objectsFile,omitempty"`
-	ObjectsDir  string `json:"objectsDir,omitempty" yaml:"objectsDir,omitempty"`
+	Type        string   `json:"type,omitempty" yaml:"type,omitempty"`
+	ObjectsFile string   `json:"objectsFile,omitempty" yaml:"objectsFile,omitempty"`
+	ObjectsDir  string   `json:"objectsDir,omitempty" yaml:"objectsDir,omitempty"`
+	Include     []string `json:"include,omitempty" yaml:"include,omitempty"` // glob patterns of the files in objectsDir, removed when packaging
+	Exclude     []string `json:"exclude,omitempty" yaml:"exclude,omitempty"` // glob patterns of the files in objectsDir to skip, removed when packaging
 }
 
 type ServiceDef struct {
@@ -114,94 +121,21 @@ func (manifest *Manifest) GetSolutionName() string {
 }
 
 func (manifest *Manifest) HasPseudoIsolation() bool {
-	return strings.Contains(manifest.Name, "${")
+	// template variable references (${var:x}, ${env:X}) are not isolation expressions
+	name := templateVariableRegexp.ReplaceAllString(manifest.Name, "")
+	return strings.Contains(name, "${")
 }
 
-func (manifest *Manifest) GetFmmEntities() []*FmmEntity {
-	fmmEntities := make([]*FmmEntity, 0)
-	entityComponentDefs := manifest.GetComponentDefs("fmm:entity")
-	for _, compDef := range entityComponentDefs {
-		if compDef.ObjectsFile != "" {
-			filePath := compDef.ObjectsFile
-			fmmEntities = append(fmmEntities, getFmmEntitiesFromFile(filePath)...)
-		}
-		if compDef.ObjectsDir != "" {
-			filePath := compDef.ObjectsDir
-			err := filepath.Walk(filePath,
-				func(path string, info os.FileInfo, err error) error {
-					if err != nil {
-						return err
-					}
-					if strings.Contains(path, ".json") {
-						fmmEntities = append(fmmEntities, getFmmEntitiesFromFile(path)...)
-					}
-					return nil
-				})
-			if err != nil {
-				log.Fatalf("Error traversing the directory: %v", err)
-			}
-		}
-
-	}
-	return fmmEntities
+func (manifest *Manifest) GetFmmEntities() ([]*FmmEntity, error) {
+	return getComponentObjects[FmmEntity](manifest, "fmm:entity")
 }
 
-func (manifest *Manifest) GetFmmMetrics() []*FmmMetric {
-	fmmMetrics := make([]*FmmMetric, 0)
-	entityComponentDefs := manifest.GetComponentDefs("fmm:metric")
-	for _, compDef := range entityComponentDefs {
-		if compDef.ObjectsFile != "" {
-			filePath := compDef.ObjectsFile
-			fmmMetrics = append(fmmMetrics, getFmmMetricsFromFile(filePath)...)
-		}
-		if compDef.ObjectsDir != "" {
-			filePath := compDef.ObjectsDir
-			err := filepath.Walk(filePath,
-				func(path string, info os.FileInfo, err error) error {
-					if err != nil {
-						return err
-					}
-					if strings.Contains(path, ".json") {
-						fmmMetrics = append(fmmMetrics, getFmmMetricsFromFile(path)...)
-					}
-					return nil
-				})
-			if err != nil {
-				log.Fatalf("Error traversing the directory: %v", err)
-			}
-		}
-
-	}
-	return fmmMetrics
+func (manifest *Manifest) GetFmmMetrics() ([]*FmmMetric, error) {
+	return getComponentObjects[FmmMetric](manifest, "fmm:metric")
 }
 
-func (manifest *Manifest) GetFmmEvents() []*FmmEvent {
-	fmmEvents := make([]*FmmEvent, 0)
-	entityComponentDefs := manifest.GetComponentDefs("fmm:event")
-	for _, compDef := range entityComponentDefs {
-		if compDef.ObjectsFile != "" {
-			filePath := compDef.ObjectsFile
-			fmmEvents = append(fmmEvents, getFmmEventsFromFile(filePath)...)
-		}
-		if compDef.ObjectsDir != "" {
-			filePath := compDef.ObjectsDir
-			err := filepath.Walk(filePath,
-				func(path string, info os.FileInfo, err error) error {
-					if err != nil {
-						return err
-					}
-					if strings.Contains(path, ".json") {
-						fmmEvents = append(fmmEvents, getFmmEventsFromFile(path)...)
-					}
-					return nil
-				})
-			if err != nil {
-				log.Fatalf("Error traversing the directory: %v", err)
-			}
-		}
-
-	}
-	return fmmEvents
+func (manifest *Manifest) GetFmmEvents() ([]*FmmEvent, error) {
+	return getComponentObjects[FmmEvent](manifest, "fmm:event")
 }
 
 func (manifest *Manifest) CheckDependencyExists(solutionName string) bool {
@@ -248,131 +182,86 @@ func (manifest *Manifest) GetComponentDefs(typeName string) []ComponentDef {
 	return componentDefs
 }
 
-func (manifest *Manifest) GetDashuiTemplates() []*DashuiTemplate {
-	dashuiTemplates := make([]*DashuiTemplate, 0)
-	objectDefs := manifest.GetComponentDefs("dashui:template")
-	for _, objDef := range objectDefs {
-		if objDef.ObjectsFile != "" {
-			filePath := objDef.ObjectsFile
-			dashuiTemplates = append(dashuiTemplates, getDashuiTemplatesFromFile(filePath)...)
+func (manifest *Manifest) GetDashuiTemplates() ([]*DashuiTemplate, error) {
+	return getComponentObjects[DashuiTemplate](manifest, "dashui:template")
+}
+
+// getComponentObjects reads all objects of the given component type from the files and
+// directories referenced in the manifest. Object files may be in JSON or YAML format.
+// All files are read, even if some fail to parse; the errors are returned as jsondoc.ParseErrors.
+func getComponentObjects[T any](manifest *Manifest, typeName string) ([]*T, error) {
+	objects := make([]*T, 0)
+	var errs jsondoc.ParseErrors
+	for _, compDef := range manifest.GetComponentDefs(typeName) {
+		if compDef.ObjectsFile != "" {
+			fileObjects, err := getObjectsFromFile[T](compDef.ObjectsFile)
+			if err != nil {
+				errs.Add(compDef.ObjectsFile, err)
+			}
+			objects = append(objects, fileObjects...)
 		}
-		if objDef.ObjectsDir != "" {
-			filePath := objDef.ObjectsDir
-			err := filepath.Walk(filePath,
-				func(path string, info os.FileInfo, err error) error {
-					if err != nil {
-						return err
-					}
-					if strings.Contains(path, ".json") {
-						dashuiTemplates = append(dashuiTemplates, getDashuiTemplatesFromFile(path)...)
-					}
-					return nil
-				})
+		if compDef.ObjectsDir != "" {
+			files, _, err := listObjectsDir(".", compDef)
 			if err != nil {
-				log.Fatalf("Error traversing the directory: %v", err)
+				errs.Add(compDef.ObjectsDir, fmt.Errorf("error traversing the directory: %w", err))
+			}
+			for _, path := range files {
+				fileObjects, err := getObjectsFromFile[T](path)
+				if err != nil {
+					errs.Add(path, err)
+				}
+				objects = append(objects, fileObjects...)
 			}
 		}
-
 	}
-	return dashuiTemplates
+	return objects, errs.ErrorOrNil()
 }
 
-func getDashuiTemplatesFromFile(filePath string) []*DashuiTemplate {
-	dashuiTemplates := make([]*DashuiTemplate, 0)
-	objDefFile := openFile(filePath)
-	defer objDefFile.Close()
-	objDefBytes, _ := io.ReadAll(objDefFile)
-	objDefContent := string(objDefBytes)
-
-	if strings.Index(objDefContent, "[") == 0 {
-		objectsArray := make([]*DashuiTemplate, 0)
-		err := json.Unmarshal(objDefBytes, &objectsArray)
-		if err != nil {
-			log.Fatalf("Can't parse an array of dashui:template definition objects from the %q file:\n %v", filePath, err)
-		}
-		dashuiTemplates = append(dashuiTemplates, objectsArray...)
-	} else {
-		var event *DashuiTemplate
-		err := json.Unmarshal(objDefBytes, &event)
-		if err != nil {
-			log.Fatalf("Can't parse dashui:template definition objects from the %q file:\n %v ", filePath, err)
-		}
-		dashuiTemplates = append(dashuiTemplates, event)
-	}
-	return dashuiTemplates
+// isObjectsFile returns true if the file has one of the extensions supported for object files
+func isObjectsFile(path string) bool {
+	_, supported := extensionMap[strings.ToLower(filepath.Ext(path))]
+	return supported
 }
 
-func getFmmEntitiesFromFile(filePath string) []*FmmEntity {
-	fmmEntities := make([]*FmmEntity, 0)
-	entityDefFile := openFile(filePath)
-	defer entityDefFile.Close()
-	entityDefBytes, _ := io.ReadAll(entityDefFile)
-	entityDefContent := string(entityDefBytes)
-
-	if strings.Index(entityDefContent, "[") == 0 {
-		entitiesArray := make([]*FmmEntity, 0)
-		err := json.Unmarshal(entityDefBytes, &entitiesArray)
-		if err != nil {
-			log.Fatalf("Can't parse an array of entity definition objects from the %q file:\n %v", filePath, err)
-		}
-		fmmEntities = append(fmmEntities, entitiesArray...)
-	} else {
-		var entity *FmmEntity
-		err := json.Unmarshal(entityDefBytes, &entity)
-		if err != nil {
-			log.Fatalf("Can't parse an entity definition objects from the %q file:\n %v", filePath, err)
-		}
-		fmmEntities = append(fmmEntities, entity)
+// getObjectsFromFile parses a JSON or YAML file containing either a single object or an array of objects.
+// Errors are returned as *jsondoc.ParseError, with the position of the error in JSON files.
+func getObjectsFromFile[T any](filePath string) ([]*T, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, &jsondoc.ParseError{File: filePath, Err: err}
+	}
+	doc, err := parseObjectsData(data, filePath)
+	if err != nil {
+		return nil, err
 	}
-	return fmmEntities
-}
 
-func getFmmMetricsFromFile(filePath string) []*FmmMetric {
-	fmmMetrics := make([]*FmmMetric, 0)
-	metricDefFile := openFile(filePath)
-	defer metricDefFile.Close()
-	metricDefBytes, _ := io.ReadAll(metricDefFile)
-	metricDefContent := string(metricDefBytes)
-
-	if strings.Index(metricDefContent, "[") == 0 {
-		metricsArray := make([]*FmmMetric, 0)
-		err := json.Unmarshal(metricDefBytes, &metricsArray)
-		if err != nil {
-			log.Fatalf("Can't parse an array of metric definition objects from the %q file:\n %v", filePath, err)
-		}
-		fmmMetrics = append(fmmMetrics, metricsArray...)
-	} else {
-		var metric *FmmMetric
-		err := json.Unmarshal(metricDefBytes, &metric)
-		if err != nil {
-			log.Fatalf("Can't parse a metric definition objects from the %q file:\n %v ", filePath, err)
+	// decode JSON directly from the file's content, so that structure errors have a position
+	isJson := strings.EqualFold(filepath.Ext(filePath), ".json")
+	decode := func(target any) error {
+		if isJson {
+			if err := json.Unmarshal(data, target); err != nil {
+				return jsondoc.NewJsonParseError(data, err)
+			}
+			return nil
 		}
-		fmmMetrics = append(fmmMetrics, metric)
+		return remarshal(doc, target)
 	}
-	return fmmMetrics
-}
 
-func getFmmEventsFromFile(filePath string) []*FmmEvent {
-	fmmEvents := make([]*FmmEvent, 0)
-	eventsDefFile := openFile(filePath)
-	defer eventsDefFile.Close()
-	eventDefBytes, _ := io.ReadAll(eventsDefFile)
-	eventDefContent := string(eventDefBytes)
-
-	if strings.Index(eventDefContent, "[") == 0 {
-		eventsArray := make([]*FmmEvent, 0)
-		err := json.Unmarshal(eventDefBytes, &eventsArray)
-		if err != nil {
-			log.Fatalf("Can't parse an array of event definition objects from the %q file:\n %v", filePath, err)
-		}
-		fmmEvents = append(fmmEvents, eventsArray...)
+	objects := make([]*T, 0)
+	if _, isArray := doc.([]any); isArray {
+		err = decode(&objects)
 	} else {
-		var event *FmmEvent
-		err := json.Unmarshal(eventDefBytes, &event)
-		if err != nil {
-			log.Fatalf("Can't parse a event` definition objects from the %q file:\n %v ", filePath, err)
+		var object *T
+		err = decode(&object)
+		objects = append(objects, object)
+	}
+	if err != nil {
+		var parseErr *jsondoc.ParseError
+		if !errors.As(err, &parseErr) {
+			parseErr = jsondoc.NewJsonParseError(nil, err) // YAML decoded via JSON, no position
+			parseErr.Line, parseErr.Column = 0, 0
 		}
-		fmmEvents = append(fmmEvents, event)
+		return nil, parseErr
 	}
-	return fmmEvents
+	return objects, nil
 }
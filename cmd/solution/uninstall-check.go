@@ -0,0 +1,192 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/cmdkit/term"
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+// UninstallImpact is what depends on a solution in the tenant, as found by the pre-flight
+// check before deleting or unsubscribing from the solution
+type UninstallImpact struct {
+	Solution           string              `json:"solution" yaml:"solution"`
+	DependentSolutions []DependentSolution `json:"dependentSolutions" yaml:"dependentSolutions"`
+	TenantObjects      []TypeObjectCount   `json:"tenantObjects" yaml:"tenantObjects"`         // objects of the solution's types in the tenant
+	OrphanedDataTypes  []TypeObjectCount   `json:"orphanedDataTypes" yaml:"orphanedDataTypes"` // FMM types whose data will be orphaned
+}
+
+// DependentSolution is a solution that declares a dependency on the solution being removed
+type DependentSolution struct {
+	Name         string `json:"name" yaml:"name"`
+	IsSubscribed bool   `json:"isSubscribed" yaml:"isSubscribed"`
+}
+
+// TypeObjectCount is the number of objects of a type
+type TypeObjectCount struct {
+	Type    string `json:"type" yaml:"type"`
+	Objects int    `json:"objects" yaml:"objects"`
+}
+
+// fmmDataTypes are the FMM types that define the data reported for a namespace
+var fmmDataTypes = []string{"fmm:entity", "fmm:metric", "fmm:event"}
+
+// checkUninstallImpact finds the solutions that depend on the solution, the tenant objects
+// of the solution's knowledge types and the FMM types in the solution's namespace
+func checkUninstallImpact(name string, tag string) (*UninstallImpact, error) {
+	solutionId := path.Base(locateSolutionUrl("", name, tag))
+	impact := &UninstallImpact{
+		Solution:           solutionId,
+		DependentSolutions: []DependentSolution{},
+		TenantObjects:      []TypeObjectCount{},
+		OrphanedDataTypes:  []TypeObjectCount{},
+	}
+	headers := getHeaders()
+
+	var solutions api.CollectionResult[struct {
+		ID   string      `json:"id"`
+		Data SolutionDef `json:"data"`
+	}]
+	if err := api.JSONGetCollection(getSolutionObjectUrl(""), &solutions, &api.Options{Headers: headers}); err != nil {
+		return nil, fmt.Errorf("failed to list solutions: %w", err)
+	}
+	for _, solution := range solutions.Items {
+		if solution.ID != solutionId && (slices.Contains(solution.Data.Dependencies, solutionId) || slices.Contains(solution.Data.Dependencies, name)) {
+			impact.DependentSolutions = append(impact.DependentSolutions, DependentSolution{Name: solution.ID, IsSubscribed: solution.Data.IsSubscribed})
+		}
+	}
+
+	types, err := getSolutionTypes(solutionId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the solution's types: %w", err)
+	}
+	for _, typeDef := range types {
+		fqtn := solutionId + ":" + typeDef.Name
+		count, err := countObjects(fqtn, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to count objects of type %v: %w", fqtn, err)
+		}
+		if count > 0 {
+			impact.TenantObjects = append(impact.TenantObjects, TypeObjectCount{Type: fqtn, Objects: count})
+		}
+	}
+
+	for _, fmmType := range fmmDataTypes {
+		count, err := countObjects(fmmType, fmt.Sprintf(`data.namespace.name eq "%s"`, solutionId))
+		if err != nil {
+			return nil, fmt.Errorf("failed to count %v objects: %w", fmmType, err)
+		}
+		if count > 0 {
+			impact.OrphanedDataTypes = append(impact.OrphanedDataTypes, TypeObjectCount{Type: fmmType, Objects: count})
+		}
+	}
+	return impact, nil
+}
+
+// countObjects returns the number of objects of a type visible in the tenant, optionally filtered
+func countObjects(fqtn string, filter string) (int, error) {
+	query := "?max=1"
+	if filter != "" {
+		query += "&filter=" + url.QueryEscape(filter)
+	}
+	var res api.CollectionResult[any]
+	if err := api.JSONGet("knowledge-store/v1/objects/"+url.PathEscape(fqtn)+query, &res, &api.Options{Headers: getHeaders()}); err != nil {
+		return 0, err
+	}
+	return res.Total, nil
+}
+
+// nSubscribedDependents returns the number of dependent solutions the tenant is subscribed to
+func (impact *UninstallImpact) nSubscribedDependents() int {
+	n := 0
+	for _, dependent := range impact.DependentSolutions {
+		if dependent.IsSubscribed {
+			n++
+		}
+	}
+	return n
+}
+
+// printUninstallImpact displays what will be affected by removing the solution
+func printUninstallImpact(cmd *cobra.Command, impact *UninstallImpact) {
+	if len(impact.DependentSolutions) == 0 && len(impact.TenantObjects) == 0 && len(impact.OrphanedDataTypes) == 0 {
+		output.PrintCmdStatus(cmd, fmt.Sprintf("No solutions, objects or data types in the tenant depend on solution %v.\n", impact.Solution))
+		return
+	}
+	lines := [][]string{}
+	for _, dependent := range impact.DependentSolutions {
+		status := "not subscribed"
+		if dependent.IsSubscribed {
+			status = "subscribed; will break"
+		}
+		lines = append(lines, []string{"dependent solution", dependent.Name, status})
+	}
+	for _, count := range impact.TenantObjects {
+		lines = append(lines, []string{"tenant objects", count.Type, fmt.Sprintf("%d object(s)", count.Objects)})
+	}
+	for _, count := range impact.OrphanedDataTypes {
+		lines = append(lines, []string{"orphaned data types", count.Type, fmt.Sprintf("%d type(s)", count.Objects)})
+	}
+	output.PrintCmdStatus(cmd, fmt.Sprintf("The following depend on solution %v:\n", impact.Solution))
+	output.PrintCmdOutputCustom(cmd, impact, &output.Table{Headers: []string{"Dependency", "Name", "Impact"}, Lines: lines})
+}
+
+// checkUninstall runs the pre-flight check before deleting or unsubscribing from a solution and
+// displays its results. It fails if subscribed solutions depend on the solution, unless force is set.
+func checkUninstall(cmd *cobra.Command, name string, tag string, force bool) {
+	impact, err := checkUninstallImpact(name, tag)
+	if err != nil {
+		log.Warnf("Could not complete the dependency check: %v", err)
+		return
+	}
+	printUninstallImpact(cmd, impact)
+	if n := impact.nSubscribedDependents(); n > 0 && !force {
+		log.Fatalf("%d subscribed solution(s) depend on solution %v; unsubscribe from them first or use --force", n, impact.Solution)
+	}
+}
+
+// confirmUninstall requires the user to type the solution's name to confirm the operation
+func confirmUninstall(name string, warning string) {
+	var confirmationAnswer string
+	fmt.Printf("%v\nPlease type the name of the solution, %s, and hit enter to confirm: ", warning, name)
+	fmt.Scanln(&confirmationAnswer)
+	if confirmationAnswer != name {
+		log.Fatal("Operation not confirmed, exiting command")
+	}
+}
+
+// confirmUnsubscribe requires the user to type the solution's name to confirm unsubscribing, unless
+// --yes is specified or the standard input is not a terminal: scripts are not prompted, as
+// unsubscribing can be undone by subscribing again
+func confirmUnsubscribe(cmd *cobra.Command, name string, warning string) {
+	if yes, _ := cmd.Flags().GetBool("yes"); yes {
+		return
+	}
+	if !term.IsTerminal(os.Stdin) {
+		log.Info("Not prompting for confirmation, as the standard input is not a terminal")
+		return
+	}
+	confirmUninstall(name, warning)
+}
@@ -21,11 +21,22 @@ import (
 )
 
 var solutionUnsubscribeCmd = &cobra.Command{
-	Use:              "unsubscribe <solution-name>",
-	Args:             cobra.MaximumNArgs(1),
-	Short:            "Unsubscribe from a solution",
-	Long:             `This command allows the current tenant specified in the profile to unsubscribe from a solution.`,
-	Example:          `  fsoc solution unsubscribe spacefleet`,
+	Use:   "unsubscribe <solution-name>",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Unsubscribe from a solution",
+	Long: `This command allows the current tenant specified in the profile to unsubscribe from a solution.
+
+Before unsubscribing, the command lists the solutions that depend on the solution, the tenant objects of the
+solution's types and the FMM types whose data will be orphaned; it fails if any subscribed solution depends on
+the solution, unless --force is specified. The operation must be confirmed by typing the solution name, unless
+--yes is specified or the standard input is not a terminal, e.g., in scripts.
+
+Use --tenants-file or --contexts to unsubscribe multiple tenants instead of the current one, as with the
+"fsoc solution subscribe" command. The dependent solutions are not checked for each tenant, so --force is
+required; the operation is confirmed once for all tenants.`,
+	Example: `  fsoc solution unsubscribe spacefleet
+  fsoc solution unsubscribe spacefleet --yes
+  fsoc solution unsubscribe spacefleet --contexts 'staging-*' --force --yes`,
 	Run:              unsubscribeFromSolution,
 	TraverseChildren: true,
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -40,6 +51,11 @@ func getUnsubscribeSolutionCmd() *cobra.Command {
 	_ = solutionUnsubscribeCmd.Flags().MarkDeprecated("name", "please use argument instead.")
 	solutionUnsubscribeCmd.Flags().
 		String("tag", "", "The tag related to the solution to unsubscribe from. This will default to the stable version of the solution if not specified")
+	solutionUnsubscribeCmd.Flags().
+		BoolP("yes", "y", false, "Skip the confirmation step")
+	solutionUnsubscribeCmd.Flags().
+		Bool("force", false, "Unsubscribe even if subscribed solutions depend on the solution")
+	addBulkSubscriptionFlags(solutionUnsubscribeCmd)
 
 	return solutionUnsubscribeCmd
 
@@ -22,6 +22,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/apex/log"
@@ -34,10 +35,14 @@ import (
 
 const MAX_SUBSCRIBE_TRIES = 4
 
+// uploadRetryDelay is the delay before the first retry of a failed upload; it doubles on each retry
+var uploadRetryDelay = 2 * time.Second
+
 type uploadOptions struct {
 	solutionName           string
 	solutionZipPath        string
 	solutionInstallVersion string
+	solutionTag            string
 }
 
 type uploadOption func(*uploadOptions)
@@ -60,6 +65,14 @@ func WithSolutionInstallVersion(version string) uploadOption {
 	}
 }
 
+// WithSolutionTag sets the tag to push with, instead of the one from the command's flags,
+// environment or .tag file
+func WithSolutionTag(tag string) uploadOption {
+	return func(opts *uploadOptions) {
+		opts.solutionTag = tag
+	}
+}
+
 func bumpSolutionVersionInManifest(cmd *cobra.Command, manifest *Manifest, manifestPath string) {
 	if err := bumpManifestPatchVersion(manifest); err != nil {
 		log.Fatal(err.Error())
@@ -70,6 +83,22 @@ func bumpSolutionVersionInManifest(cmd *cobra.Command, manifest *Manifest, manif
 	output.PrintCmdStatus(cmd, fmt.Sprintf("Solution version updated to %v\n", manifest.SolutionVersion))
 }
 
+// checkSolutionVersionBumped fails if the solution version is not newer than the deployed one
+func checkSolutionVersionBumped(manifest *Manifest, tag string) {
+	deployedVersion := getDeployedSolutionVersion(manifest.Name, tag)
+	if deployedVersion == "" {
+		return
+	}
+	newer, err := isNewerSolutionVersion(manifest.SolutionVersion, deployedVersion)
+	if err != nil {
+		log.Warnf("Failed to compare solution versions: %v", err)
+		return
+	}
+	if !newer {
+		log.Fatalf("Solution version %v is not newer than the deployed version %v with tag %q; use fsoc solution bump or the --bump flag to increment it", manifest.SolutionVersion, deployedVersion, tag)
+	}
+}
+
 func uploadSolution(cmd *cobra.Command, push bool, options ...uploadOption) {
 	opts := uploadOptions{}
 	for _, option := range options {
@@ -83,12 +112,19 @@ func uploadSolution(cmd *cobra.Command, push bool, options ...uploadOption) {
 	var solutionAlreadyZipped bool
 	var solutionDisplayText string
 	var logFields map[string]interface{}
+	var incrementalState *pushState // state to record after pushing with --incremental
+	var sourceDirectory string      // solution directory, before pseudo-isolation
+	var sourceSolutionName string   // solution name, before pseudo-isolation
+	removeArchive := func() {}      // removes the archive, if it must not be kept
 	cfg := config.GetCurrentContext()
 
 	waitFlag, err := cmd.Flags().GetInt("wait")
 	if err != nil { // if the "wait" flag is not defined for this command, set to no-wait
 		waitFlag = -1
 	}
+	if noWait, _ := cmd.Flags().GetBool("no-wait"); noWait {
+		waitFlag = -1
+	}
 	bumpFlag, _ := cmd.Flags().GetBool("bump")
 	solutionBundlePath, _ := cmd.Flags().GetString("solution-bundle")
 	if solutionBundlePath == "" && opts.solutionZipPath != "" {
@@ -99,9 +135,12 @@ func uploadSolution(cmd *cobra.Command, push bool, options ...uploadOption) {
 	solutionNameFromOptions := opts.solutionName
 
 	// prepare tag-related values
-	solutionTag, err := getEmbeddedTag(cmd, solutionRootDirectory) // flag, env var or .tag file
-	if err != nil {
-		log.Fatalf("Failed to get solution tag: %v", err)
+	solutionTag := opts.solutionTag
+	if solutionTag == "" {
+		solutionTag, err = getEmbeddedTag(cmd, solutionRootDirectory) // flag, env var or .tag file
+		if err != nil {
+			log.Fatalf("Failed to get solution tag: %v", err)
+		}
 	}
 	requestedSolutionTag := solutionTag // mostly for display, as solutionTagFlag may be changed to comply with supported API values (pseudo-isolation only)
 	// TODO remove `requestedSolutionTag` when solution pseudo-isolation is removed
@@ -144,9 +183,24 @@ func uploadSolution(cmd *cobra.Command, push bool, options ...uploadOption) {
 		if err != nil {
 			log.Fatalf("Failed to read the solution manifest from %q: %v", solutionRootDirectory, err)
 		}
+		sourceDirectory, sourceSolutionName = solutionRootDirectory, manifest.GetSolutionName()
+		if skipCheck, _ := cmd.Flags().GetBool("skip-dependency-check"); push && !skipCheck {
+			if err := verifyDependencyAvailability(cmd, solutionRootDirectory, manifest); err != nil {
+				log.Fatalf("Dependency check failed: %v", err)
+			}
+		}
+		if incremental, _ := cmd.Flags().GetBool("incremental"); push && incremental {
+			var changed bool
+			if incrementalState, changed = checkIncrementalPush(cmd, solutionRootDirectory, manifest, solutionTag); !changed {
+				return
+			}
+		}
 		if bumpFlag {
 			bumpSolutionVersionInManifest(cmd, manifest, solutionRootDirectory)
 		}
+		if push && !manifest.HasPseudoIsolation() {
+			checkSolutionVersionBumped(manifest, solutionTag)
+		}
 
 		// pseudo-isolate if needed (update tag values to reflect env var and/or env file settings)
 		solutionIsolateDirectory, tag, err := embeddedConditionalIsolate(cmd, solutionRootDirectory)
@@ -177,6 +231,10 @@ func uploadSolution(cmd *cobra.Command, push bool, options ...uploadOption) {
 		// create archive
 		solutionArchive := generateZip(cmd, solutionRootDirectory, "")
 		solutionBundlePath = solutionArchive.Name()
+		if len(manifest.Secrets) > 0 {
+			// the archive contains the secrets' values; it is removed once read, as log.Fatalf skips defers
+			removeArchive = func() { os.Remove(solutionBundlePath) }
+		}
 
 		// fill in details
 		solutionName = manifest.Name
@@ -204,22 +262,24 @@ func uploadSolution(cmd *cobra.Command, push bool, options ...uploadOption) {
 		output.PrintCmdStatus(cmd, fmt.Sprintf("Validating %s\n", solutionDisplayText))
 	}
 	log.WithFields(log.Fields(logFields)).Info("Solution details")
+	getReport(cmd).setSolution(solutionName, solutionVersion, requestedSolutionTag)
 
 	// --- Upload archive
 
 	// read zip file into a buffer
 	file, err := os.Open(solutionBundlePath)
 	if err != nil {
+		removeArchive()
 		log.Fatalf("Failed to open file %q: %v", solutionBundlePath, err)
 	}
-	defer file.Close()
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 	fw, err := writer.CreateFormFile("file", solutionBundlePath)
-	if err != nil {
-		log.Fatalf("Failed to create form file: %v", err)
+	if err == nil {
+		_, err = io.Copy(fw, file)
 	}
-	_, err = io.Copy(fw, file)
+	file.Close()
+	removeArchive()
 	if err != nil {
 		log.Fatalf("Failed to copy file %q into file writer: %v", solutionBundlePath, err)
 	}
@@ -238,11 +298,22 @@ func uploadSolution(cmd *cobra.Command, push bool, options ...uploadOption) {
 		"Content-Type": writer.FormDataContentType(),
 	}
 	var res Result
-	err = api.HTTPPost(getSolutionPushUrl(), body.Bytes(), &res, &api.Options{Headers: headers})
+	retries, _ := cmd.Flags().GetInt("retries") // no retries if the flag is not defined for this command
+	var alreadyUploaded func() bool
+	if push && solutionName != "" && solutionVersion != "" {
+		uploadStartTime := time.Now()
+		alreadyUploaded = func() bool {
+			return isSolutionVersionUploaded(solutionName, solutionVersion, solutionTag, uploadStartTime)
+		}
+	}
+	attempt, err := postSolutionArchive(cmd, body.Bytes(), headers, &res, retries, alreadyUploaded)
 	if err != nil {
-		log.Fatalf("Solution %s command failed: %v", operation, err)
+		log.Fatalf("Solution %s command failed after %d attempt(s): %v", operation, attempt, err)
 	}
 	if !push && !res.Valid {
+		report := getReport(cmd)
+		report.addErrorItems(res.Errors.Items)
+		report.setOutcome(reportOutcomeFindings)
 		message := getSolutionValidationErrorsString(res.Errors.Total, res.Errors)
 		output.PrintCmdStatus(cmd, message)
 		log.Fatalf("%d error(s) found while validating the solution", res.Errors.Total)
@@ -281,40 +352,135 @@ func uploadSolution(cmd *cobra.Command, push bool, options ...uploadOption) {
 
 	}
 
+	// keep the pushed archive, so that the version can be rolled back to
+	if push && solutionName != "" && solutionVersion != "" && (manifest == nil || len(manifest.Secrets) == 0) {
+		if err := cacheSolutionArchive(solutionBundlePath, solutionName, solutionTag, solutionVersion); err != nil {
+			log.Warnf("Failed to cache the solution archive: %v", err)
+		}
+	}
+
 	// wait for installation, if requested (and possible)
 	if push && waitFlag >= 0 && solutionName != "" && solutionVersion != "" {
-		var duration string
-		if waitFlag > 0 {
-			duration = fmt.Sprintf("up to %d seconds", waitFlag)
-		} else {
-			duration = "indefinitely"
+		waitForSolutionInstall(cmd, solutionName, solutionVersion, solutionTag, solutionDisplayText, waitFlag)
+	}
+
+	// record what was pushed, for the next incremental push
+	if incrementalState != nil {
+		incrementalState.SolutionVersion = solutionVersion
+		if err := incrementalState.save(sourceDirectory); err != nil {
+			log.Warnf("Failed to save %v: %v", PushStateFileName, err)
 		}
-		output.PrintCmdStatus(cmd, fmt.Sprintf("Waiting %s for %s to be installed...\n", duration, solutionDisplayText))
+	}
 
-		filter := fmt.Sprintf(`data.solutionName eq "%s" and data.solutionVersion eq "%s" and data.tag eq "%s"`, solutionName, solutionVersion, solutionTag)
-		query := fmt.Sprintf("?order=%s&filter=%s&max=1", url.QueryEscape("desc"), url.QueryEscape(filter))
+	// record the pushed commit, for the changelog of the next version
+	if push && sourceDirectory != "" {
+		recordPushedCommit(sourceDirectory, sourceSolutionName)
+	}
+}
 
-		headers := map[string]string{
-			"layer-type": "TENANT",
-			"layer-id":   config.GetCurrentContext().Tenant,
+// waitForSolutionInstall polls the installation status object of the solution until the
+// installation of the given version reaches a terminal state, displaying status changes
+// as they appear. A timeout of 0 means waiting indefinitely.
+func waitForSolutionInstall(cmd *cobra.Command, solutionName string, solutionVersion string, solutionTag string, solutionDisplayText string, timeout int) {
+	var duration string
+	if timeout > 0 {
+		duration = fmt.Sprintf("up to %d seconds", timeout)
+	} else {
+		duration = "indefinitely"
+	}
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Waiting %s for %s to be installed...\n", duration, solutionDisplayText))
+
+	filter := fmt.Sprintf(`data.solutionName eq "%s" and data.solutionVersion eq "%s" and data.tag eq "%s"`, solutionName, solutionVersion, solutionTag)
+	query := fmt.Sprintf("?order=%s&filter=%s&max=1", url.QueryEscape("desc"), url.QueryEscape(filter))
+
+	headers := map[string]string{
+		"layer-type": "TENANT",
+		"layer-id":   config.GetCurrentContext().Tenant,
+	}
+	var statusData StatusData
+	var lastMessage string
+	waitStartTime := time.Now()
+	for {
+		status := getObjects(fmt.Sprintf(getSolutionInstallUrl(), query), headers)
+		statusData = status.StatusData
+		if statusData.InstallMessage != "" && statusData.InstallMessage != lastMessage {
+			lastMessage = statusData.InstallMessage
+			output.PrintCmdStatus(cmd, fmt.Sprintf("[%3.0fs] %s\n", time.Since(waitStartTime).Seconds(), statusData.InstallMessage))
 		}
-		var statusData StatusData
-		waitStartTime := time.Now()
-		for statusData.SolutionVersion != solutionVersion {
-			if waitFlag > 0 {
-				if time.Since(waitStartTime).Seconds() > float64(waitFlag) {
-					log.Fatalf("Failed to validate %s was installed: timed out", solutionDisplayText)
-				}
+		if statusData.SolutionVersion == solutionVersion {
+			break
+		}
+		if timeout > 0 && time.Since(waitStartTime).Seconds() > float64(timeout) {
+			getReport(cmd).setOutcome(reportOutcomeTimeout)
+			log.Fatalf("Failed to validate %s was installed: timed out after %d seconds; use \"fsoc solution status %s\" to check later", solutionDisplayText, timeout, solutionName)
+		}
+		time.Sleep(3 * time.Second)
+	}
+	if !statusData.SuccessfulInstall {
+		getReport(cmd).setOutcome(reportOutcomeInstallFailed)
+		// the installation message holds the errors the platform reported, one per line
+		for _, line := range strings.Split(statusData.InstallMessage, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				output.PrintCmdStatus(cmd, fmt.Sprintf("  - %s\n", line))
 			}
-			status := getObjects(fmt.Sprintf(getSolutionInstallUrl(), query), headers)
-			statusData = status.StatusData
-			time.Sleep(3 * time.Second)
 		}
-		if !statusData.SuccessfulInstall {
-			log.Fatalf("Failed to install %s: %s", solutionDisplayText, statusData.InstallMessage)
+		log.Fatalf("Failed to install %s", solutionDisplayText)
+	}
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Installed %v successfully in %.0f seconds.\n", solutionDisplayText, time.Since(waitStartTime).Seconds()))
+}
+
+// postSolutionArchive sends the solution archive to the platform, retrying up to the given number of
+// times if the request fails with a transient error, e.g., a connection dropped while uploading a large
+// archive. The platform accepts the archive in a single request, so an interrupted upload cannot be resumed
+// and each retry sends the whole archive.
+// Retries are idempotent: if alreadyUploaded is provided, it is used before each retry to check whether
+// the platform received the archive from an interrupted attempt, in which case it is not sent again.
+// Returns the number of the last attempt made.
+func postSolutionArchive(cmd *cobra.Command, body []byte, headers map[string]string, res *Result, retries int, alreadyUploaded func() bool) (int, error) {
+	delay := uploadRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := api.HTTPPost(getSolutionPushUrl(), body, res, &api.Options{Headers: headers, UploadProgress: true})
+		if err == nil && attempt > 1 {
+			output.PrintCmdStatus(cmd, fmt.Sprintf("Upload succeeded on attempt %d of %d.\n", attempt, retries+1))
 		}
-		output.PrintCmdStatus(cmd, fmt.Sprintf("Installed %v successfully.\n", solutionDisplayText))
+		if err == nil || attempt > retries || !api.IsTransientError(err) {
+			return attempt, err
+		}
+		log.WithFields(log.Fields{"attempt": attempt, "error": err.Error()}).Warnf("Upload failed, retrying in %v", delay)
+		time.Sleep(delay)
+		delay *= 2
+		if alreadyUploaded != nil && alreadyUploaded() {
+			output.PrintCmdStatus(cmd, fmt.Sprintf("Upload succeeded on attempt %d of %d: the platform received the archive before the connection failed.\n", attempt, retries+1))
+			return attempt, nil
+		}
+	}
+}
+
+// isSolutionVersionUploaded checks whether the platform has started installing the given solution
+// version with the given tag since the given time, i.e., whether it has been uploaded by this push:
+// an installation of the same version by an earlier push doesn't count. Errors are treated as not
+// uploaded.
+func isSolutionVersionUploaded(solutionName string, solutionVersion string, solutionTag string, since time.Time) bool {
+	filter := fmt.Sprintf(`data.solutionName eq "%s" and data.solutionVersion eq "%s" and data.tag eq "%s"`, solutionName, solutionVersion, solutionTag)
+	query := fmt.Sprintf("?order=%s&filter=%s&max=1", url.QueryEscape("desc"), url.QueryEscape(filter))
+	headers := map[string]string{
+		"layer-type": "TENANT",
+		"layer-id":   config.GetCurrentContext().Tenant,
+	}
+	var res ResponseBlob
+	if err := api.JSONGet(fmt.Sprintf(getSolutionInstallUrl(), query), &res, &api.Options{Headers: headers, Quiet: true}); err != nil {
+		log.Warnf("Failed to check whether the solution was uploaded: %v", err)
+		return false
+	}
+	if len(res.Items) == 0 || res.Items[0].StatusData.SolutionVersion != solutionVersion {
+		return false
+	}
+	createdAt, err := time.Parse(time.RFC3339, res.Items[0].CreatedAt)
+	if err != nil {
+		log.Warnf("Failed to check whether the solution was uploaded: invalid installation time %q: %v", res.Items[0].CreatedAt, err)
+		return false
 	}
+	return !createdAt.Before(since.Truncate(time.Second)) // the creation time may have a precision of a second
 }
 
 func getSolutionValidationErrorsString(total int, errors Errors) string {
@@ -0,0 +1,187 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// FMM naming rules: namespace names are lowercase identifiers, attribute names may have
+// dot-separated segments (e.g., k8s.cluster.name); type names follow objectNameRegexp
+var (
+	fmmNamespaceNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	fmmAttributeNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z0-9_]+)*$`)
+)
+
+// fmmAttributeTypes are the types of entity, metric and event attributes supported by FMM
+var fmmAttributeTypes = []string{"string", "long", "double", "boolean"}
+
+// fmmMetricCategories are the categories allowed for each metric content type
+var fmmMetricCategories = map[FmmMetricContentType][]FmmMetricCategory{
+	ContentType_Sum:          {Category_Sum, Category_Rate},
+	ContentType_Gauge:        {Category_Current, Category_Average},
+	ContentType_Distribution: {Category_Sum, Category_Average, Category_Rate},
+}
+
+// checkFmmRules verifies the solution's FMM entities, metrics and events against the rules the
+// platform enforces when installing them: names, attribute types and required or optimized
+// attributes, the combination of the metrics' content type, category, temporality and unit, and
+// the namespaces of the types referenced by entities, which must be defined by the solution or
+// be the namespace of one of its dependencies.
+func (v *localValidator) checkFmmRules(manifest *Manifest) {
+	objectFiles, _ := loadManifestObjects(v.root, manifest) // unreadable files are reported by the syntax checks
+	model := newLocalTestModel(objectFiles)
+
+	for _, file := range objectFiles {
+		if !strings.HasPrefix(file.objType, "fmm:") {
+			continue
+		}
+		for _, obj := range file.objects {
+			objMap, _ := obj.(map[string]any)
+			name := getLocalObjectName(objMap)
+			if name == "" {
+				continue // reported by the schema checks
+			}
+			v.checkFmmNames(file.path, file.objType, objMap)
+
+			switch file.objType {
+			case "fmm:entity":
+				var entity FmmEntity
+				if err := remarshal(objMap, &entity); err != nil {
+					continue
+				}
+				if entity.AttributeDefinitions != nil {
+					v.checkFmmAttributes(file.path, objMap, name, entity.AttributeDefinitions.FmmAttributeDefinitionsTypeDef, entity.AttributeDefinitions.Required)
+				}
+				refs := append(slices.Clone(entity.MetricTypes), entity.EventTypes...)
+				if assoc := entity.AssociationTypes; assoc != nil {
+					for _, targets := range [][]string{assoc.Aggregates_of, assoc.Consists_of, assoc.Is_a, assoc.Has, assoc.Relates_to, assoc.Uses} {
+						refs = append(refs, targets...)
+					}
+				}
+				v.checkFmmReferences(file.path, objMap, name, manifest, model, refs)
+			case "fmm:metric":
+				var metric FmmMetric
+				if err := remarshal(objMap, &metric); err != nil {
+					continue
+				}
+				v.checkFmmAttributes(file.path, objMap, name, metric.AttributeDefinitions, nil)
+				v.checkFmmMetric(file.path, objMap, name, &metric)
+			case "fmm:event":
+				var event FmmEvent
+				if err := remarshal(objMap, &event); err != nil {
+					continue
+				}
+				v.checkFmmAttributes(file.path, objMap, name, event.AttributeDefinitions, nil)
+			case "fmm:resourceMapping":
+				var mapping FmmResourceMapping
+				if err := remarshal(objMap, &mapping); err == nil {
+					v.checkFmmReferences(file.path, objMap, name, manifest, model, []string{mapping.EntityType})
+				}
+			case "fmm:associationDeclaration":
+				var declaration FmmAssociationDeclaration
+				if err := remarshal(objMap, &declaration); err == nil {
+					v.checkFmmReferences(file.path, objMap, name, manifest, model, []string{declaration.FromType, declaration.ToType})
+				}
+			}
+		}
+	}
+}
+
+// checkFmmNames verifies the names of an FMM object and of its namespace; names resolved at
+// install time (e.g., ${sys.solutionId} in isolated solutions) are not checked
+func (v *localValidator) checkFmmNames(file string, objType string, obj map[string]any) {
+	name, _ := obj["name"].(string)
+	nsName := name
+	if objType != "fmm:namespace" {
+		namespace, _ := obj["namespace"].(map[string]any)
+		nsName, _ = namespace["name"].(string)
+		if name != "" && !strings.Contains(name, "${") && !objectNameRegexp.MatchString(name) {
+			v.addError(v.locateReference(file, obj, name), "%v name %q must start with a lowercase letter and contain only letters, digits, '_' and '.'", objType, name)
+		}
+	}
+	if nsName != "" && !strings.Contains(nsName, "${") && !fmmNamespaceNameRegexp.MatchString(nsName) {
+		v.addError(v.locateReference(file, obj, nsName), "namespace name %q must start with a lowercase letter and contain only lowercase letters, digits and '_'", nsName)
+	}
+}
+
+// checkFmmAttributes verifies the attribute definitions of an entity, metric or event: attribute
+// names and types, and that the required and optimized attributes are defined
+func (v *localValidator) checkFmmAttributes(file string, obj map[string]any, name string, defs *FmmAttributeDefinitionsTypeDef, required []string) {
+	if defs == nil {
+		return
+	}
+	for _, attrName := range sortedKeys(defs.Attributes) {
+		if !fmmAttributeNameRegexp.MatchString(attrName) {
+			v.addError(v.locateReference(file, obj, attrName), "%v: attribute name %q must consist of '.'-separated segments of letters, digits and '_'", name, attrName)
+		}
+		if attr := defs.Attributes[attrName]; attr != nil && attr.Type != "" && !slices.Contains(fmmAttributeTypes, attr.Type) {
+			v.addError(v.locateReference(file, obj, attr.Type), "%v: attribute %q has type %q; the supported types are %v", name, attrName, attr.Type, strings.Join(fmmAttributeTypes, ", "))
+		}
+	}
+	for _, attrs := range []struct {
+		kind  string
+		names []string
+	}{{"required", required}, {"optimized", defs.Optimized}} {
+		for _, attrName := range attrs.names {
+			if _, found := defs.Attributes[attrName]; !found {
+				v.addError(v.locateReference(file, obj, attrName), "%v: %v attribute %q is not defined", name, attrs.kind, attrName)
+			}
+		}
+	}
+}
+
+// checkFmmMetric verifies that the metric's category, aggregation temporality, monotonicity and
+// unit are consistent with its content type
+func (v *localValidator) checkFmmMetric(file string, obj map[string]any, name string, metric *FmmMetric) {
+	categories, found := fmmMetricCategories[metric.ContentType]
+	if !found {
+		return // reported by the schema checks
+	}
+	if metric.Category != "" && !slices.Contains(categories, metric.Category) {
+		v.addError(v.locateReference(file, obj, string(metric.Category)), "%v: category %q is not allowed for %v metrics; use one of %v", name, metric.Category, metric.ContentType, categories)
+	}
+	switch temporality := metric.AggregationTemporality; {
+	case metric.ContentType == ContentType_Gauge && temporality != "" && temporality != string(Temp_False):
+		v.addError(v.locateReference(file, obj, temporality), "%v: gauge metrics must have aggregation temporality %q, not %q", name, Temp_False, temporality)
+	case metric.ContentType != ContentType_Gauge && temporality != string(Temp_Delta) && temporality != "cumulative":
+		v.addError(v.locateReference(file, obj, string(metric.ContentType)), "%v: %v metrics must have aggregation temporality %q or %q", name, metric.ContentType, Temp_Delta, "cumulative")
+	}
+	if metric.IsMonotonic && metric.ContentType != ContentType_Sum {
+		v.addError(v.locateReference(file, obj, string(metric.ContentType)), "%v: only sum metrics can be monotonic", name)
+	}
+	if strings.ContainsAny(metric.Unit, " \t\n") || strings.Count(metric.Unit, "{") != strings.Count(metric.Unit, "}") {
+		v.addError(v.locateReference(file, obj, metric.Unit), "%v: unit %q is not a valid UCUM unit, e.g., ms, By or {requests}", name, metric.Unit)
+	}
+}
+
+// checkFmmReferences verifies that the types referenced by an FMM object are qualified with a
+// namespace that is defined by the solution or is the namespace of one of its dependencies
+func (v *localValidator) checkFmmReferences(file string, obj map[string]any, name string, manifest *Manifest, model *localTestModel, refs []string) {
+	for _, ref := range refs {
+		if ref == "" {
+			continue
+		}
+		namespace, _, found := strings.Cut(ref, ":")
+		switch {
+		case !found:
+			v.addError(v.locateReference(file, obj, ref), "%v: type %q must be qualified with its namespace, e.g., %v:%v", name, ref, manifest.GetNamespaceName(), ref)
+		case !model.namespaces[namespace] && !slices.Contains(manifest.Dependencies, namespace) && !strings.Contains(namespace, "${"):
+			v.addError(v.locateReference(file, obj, ref), "%v: type %q is in namespace %q, which is neither defined by the solution nor one of its dependencies", name, ref, namespace)
+		}
+	}
+}
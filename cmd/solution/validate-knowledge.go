@@ -0,0 +1,97 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// ownTypeSchemaPrefix prefixes the schema keys of the solution's own types, whose JSON schemas
+// are read from the type definitions rather than from schema files
+const ownTypeSchemaPrefix = "type:"
+
+// validateKnowledgeObjects validates the objects of the knowledge types that have a JSON schema,
+// i.e., the solution's own types and the dependency types vendored in vendorDir (if provided),
+// against their type's schema. Schema violations are reported with the JSON pointer of the
+// offending value within the object.
+func validateKnowledgeObjects(solutionPath string, manifest *Manifest, vendorDir string) []ErrorItem {
+	v := &localValidator{
+		root:      solutionPath,
+		vendorDir: vendorDir,
+		schemas:   map[string]*gojsonschema.Schema{},
+	}
+	v.loadOwnTypeSchemas(manifest)
+	for _, compDef := range manifest.Objects {
+		schemaFile := v.getTypeSchemaFile(compDef.Type)
+		if schemaFile == "" {
+			continue
+		}
+		if compDef.ObjectsFile != "" {
+			v.checkFileContents(compDef.ObjectsFile, schemaFile)
+		}
+		if compDef.ObjectsDir != "" {
+			files, _, _ := listObjectsDir(v.root, compDef) // unreadable directories are reported by the validation
+			for _, file := range files {
+				v.checkFileContents(file, schemaFile)
+			}
+		}
+	}
+	v.runFileChecks()
+	return v.errors
+}
+
+// loadOwnTypeSchemas compiles the JSON schemas of the solution's own types, so that objects of
+// these types are validated against them; invalid schemas are reported as errors of the type file
+func (v *localValidator) loadOwnTypeSchemas(manifest *Manifest) {
+	for _, typeFile := range manifest.Types {
+		doc, err := readObjectsFile(filepath.Join(v.root, typeFile))
+		if err != nil {
+			continue // reported by the syntax checks
+		}
+		typeDefs, isArray := doc.([]any)
+		if !isArray {
+			typeDefs = []any{doc}
+		}
+		for _, typeDef := range typeDefs {
+			var knowledgeDef KnowledgeDef
+			if err := remarshal(typeDef, &knowledgeDef); err != nil || knowledgeDef.Name == "" || knowledgeDef.JsonSchema == nil {
+				continue
+			}
+			schema, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(knowledgeDef.JsonSchema))
+			if err != nil {
+				v.addError(typeFile, "type %q has an invalid jsonSchema: %v", knowledgeDef.Name, err)
+				continue
+			}
+			v.schemas[ownTypeSchemaPrefix+manifest.Name+":"+knowledgeDef.Name] = schema
+		}
+	}
+}
+
+// getTypeSchemaFile returns the key of the schema of a knowledge type: the solution's own type
+// or a vendored dependency type; it returns "" if the type has no known schema
+func (v *localValidator) getTypeSchemaFile(objType string) string {
+	if _, found := v.schemas[ownTypeSchemaPrefix+objType]; found {
+		return ownTypeSchemaPrefix + objType
+	}
+	return findVendoredSchema(v.vendorDir, objType)
+}
+
+// isTypeSchema returns true if the schema is a knowledge type's schema, rather than one built into fsoc
+func isTypeSchema(schemaFile string) bool {
+	return filepath.IsAbs(schemaFile) || strings.HasPrefix(schemaFile, ownTypeSchemaPrefix)
+}
@@ -0,0 +1,329 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/apex/log"
+	"github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v3"
+
+	"github.com/cisco-open/fsoc/cmdkit/jsondoc"
+)
+
+//go:embed schemas
+var embeddedSchemas embed.FS
+
+// componentSchemaFiles maps the component types known to fsoc to the embedded
+// JSON schemas used to validate them offline. Objects of other types are checked
+// only for syntax.
+var componentSchemaFiles = map[string]string{
+	"fmm:namespace":              "schemas/types/fmm.namespace.json",
+	"fmm:entity":                 "schemas/types/fmm.entity.json",
+	"fmm:metric":                 "schemas/types/fmm.metric.json",
+	"fmm:event":                  "schemas/types/fmm.event.json",
+	"fmm:resourceMapping":        "schemas/types/fmm.resourceMapping.json",
+	"fmm:associationDeclaration": "schemas/types/fmm.associationDeclaration.json",
+	"dashui:template":            "schemas/types/dashui.template.json",
+}
+
+const knowledgeTypeSchemaFile = "schemas/types/knowledge.type.json"
+
+// localValidator collects the errors found while validating a solution
+// directory without access to the platform
+type localValidator struct {
+	root        string
+	vendorDir   string // directory with vendored dependency type schemas, if any
+	errors      []ErrorItem
+	schemas     map[string]*gojsonschema.Schema
+	schemasLock sync.Mutex
+	fileChecks  []fileCheck // queued checks of file contents, run concurrently
+}
+
+// fileCheck is a queued check of an object or type file's contents against a schema
+type fileCheck struct {
+	file       string
+	schemaFile string
+}
+
+// validateSolutionLocally checks the solution in the given directory without
+// contacting the platform: manifest structure, presence of all referenced object
+// files and directories, file syntax, the references between objects and, for
+// known types, the objects' required fields. Objects of the solution's own types are
+// validated against their type's JSON schema and objects of dependency types against
+// the vendored schemas in vendorDir, if provided, instead of the built-in ones. The result has the same shape as the platform's validation response.
+func validateSolutionLocally(solutionPath string, vendorDir string) *Result {
+	v := &localValidator{
+		root:      solutionPath,
+		vendorDir: vendorDir,
+		schemas:   map[string]*gojsonschema.Schema{},
+	}
+	v.validate()
+
+	return &Result{
+		Errors: Errors{Items: v.errors, Total: len(v.errors)},
+		Valid:  len(v.errors) == 0,
+	}
+}
+
+func (v *localValidator) addError(source string, format string, args ...any) {
+	v.errors = append(v.errors, ErrorItem{Error: fmt.Sprintf(format, args...), Source: source})
+}
+
+func (v *localValidator) validate() {
+	// locate manifest
+	var manifestName string
+	for _, name := range []string{"manifest.json", "manifest.yaml"} {
+		if _, err := os.Stat(filepath.Join(v.root, name)); err == nil {
+			if manifestName != "" {
+				v.addError(name, "found both JSON and YAML manifests; only one can exist")
+				return
+			}
+			manifestName = name
+		}
+	}
+	if manifestName == "" {
+		v.addError("manifest.json", "no solution manifest found in %q", v.root)
+		return
+	}
+
+	// parse and check manifest structure
+	doc, err := readObjectsFile(filepath.Join(v.root, manifestName))
+	if err != nil {
+		v.addError(manifestName, "%v", err)
+		return
+	}
+	manifest := &Manifest{}
+	if err := remarshal(doc, manifest); err != nil {
+		v.addError(manifestName, "manifest structure is not valid: %v", err)
+		return
+	}
+	if !slices.Contains(knownManifestVersions, manifest.ManifestVersion) {
+		v.addError(manifestName, "unsupported manifest version %q; should be one of %q", manifest.ManifestVersion, knownManifestVersions)
+		return
+	}
+	v.checkAgainstSchema(manifestName, fmt.Sprintf("schemas/manifest-%s.json", manifest.ManifestVersion), doc)
+	if !IsValidSolutionName(manifest.Name) && !strings.Contains(manifest.Name, "${") {
+		v.addError(manifestName, "invalid solution name %q: must start with a lowercase letter, contain only lowercase letters and digits and be no longer than 25 characters", manifest.Name)
+	}
+
+	// check objects referenced from the manifest, using the schemas of the solution's own types
+	v.loadOwnTypeSchemas(manifest)
+	for _, compDef := range manifest.Objects {
+		switch {
+		case compDef.ObjectsFile != "":
+			if v.checkPath(manifestName, compDef.ObjectsFile, false) {
+				v.checkObjectsFile(compDef.ObjectsFile, compDef.Type)
+			}
+		case compDef.ObjectsDir != "":
+			if v.checkPath(manifestName, compDef.ObjectsDir, true) {
+				v.checkObjectsDir(compDef)
+			}
+		}
+	}
+
+	// check type definitions
+	for _, typeFile := range manifest.Types {
+		if v.checkPath(manifestName, typeFile, false) {
+			v.checkFileContents(typeFile, knowledgeTypeSchemaFile)
+		}
+	}
+	v.runFileChecks()
+
+	// check references between objects
+	v.checkReferences(manifestName, manifest)
+
+	// check FMM objects against the rules enforced at install
+	v.checkFmmRules(manifest)
+}
+
+// checkPath verifies that a path referenced from the manifest stays within the solution
+// directory and refers to an existing file (or directory, if dir is true)
+func (v *localValidator) checkPath(source string, path string, dir bool) bool {
+	rel, err := filepath.Rel(v.root, filepath.Join(v.root, path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(path) {
+		v.addError(source, "path %q is outside of the solution directory", path)
+		return false
+	}
+	info, err := os.Stat(filepath.Join(v.root, path))
+	if err != nil {
+		v.addError(source, "referenced path %q does not exist", path)
+		return false
+	}
+	if dir && !info.IsDir() {
+		v.addError(source, "objectsDir %q is not a directory", path)
+		return false
+	}
+	if !dir && info.IsDir() {
+		v.addError(source, "%q is a directory, expected a file", path)
+		return false
+	}
+	return true
+}
+
+func (v *localValidator) checkObjectsDir(compDef ComponentDef) {
+	files, _, err := listObjectsDir(v.root, compDef)
+	if err != nil {
+		v.addError(compDef.ObjectsDir, "failed to read objects directory: %v", err)
+	}
+	if len(files) == 0 && err == nil {
+		log.Warnf("No object files selected in objectsDir %q", compDef.ObjectsDir)
+	}
+	for _, relPath := range files {
+		v.checkObjectsFile(relPath, compDef.Type)
+	}
+}
+
+func (v *localValidator) checkObjectsFile(file string, objType string) {
+	if schemaFile := v.getTypeSchemaFile(objType); schemaFile != "" {
+		v.checkFileContents(file, schemaFile)
+		return
+	}
+	v.checkFileContents(file, componentSchemaFiles[objType])
+}
+
+// checkFileContents queues the check of the file's contents; the queued checks are run
+// concurrently by runFileChecks
+func (v *localValidator) checkFileContents(file string, schemaFile string) {
+	v.fileChecks = append(v.fileChecks, fileCheck{file: file, schemaFile: schemaFile})
+}
+
+// runFileChecks runs the queued file checks concurrently and adds their errors in the
+// order in which the checks were queued
+func (v *localValidator) runFileChecks() {
+	results := parallelMap(v.fileChecks, func(check fileCheck) []ErrorItem {
+		return v.validateFileContents(check.file, check.schemaFile)
+	})
+	v.fileChecks = nil
+	for _, errs := range results {
+		v.errors = append(v.errors, errs...)
+	}
+}
+
+// validateFileContents parses the file and, if a schema is provided, validates each object in it.
+// A file may contain a single object or an array of objects.
+func (v *localValidator) validateFileContents(file string, schemaFile string) []ErrorItem {
+	doc, err := readObjectsFile(filepath.Join(v.root, file))
+	if err != nil {
+		return []ErrorItem{{Error: err.Error(), Source: file}}
+	}
+	if schemaFile == "" {
+		return nil
+	}
+	if objects, isArray := doc.([]any); isArray {
+		errs := []ErrorItem{}
+		for i, obj := range objects {
+			errs = append(errs, v.validateAgainstSchema(fmt.Sprintf("%v[%d]", file, i), schemaFile, obj)...)
+		}
+		return errs
+	}
+	return v.validateAgainstSchema(file, schemaFile, doc)
+}
+
+func (v *localValidator) checkAgainstSchema(source string, schemaFile string, doc any) {
+	v.errors = append(v.errors, v.validateAgainstSchema(source, schemaFile, doc)...)
+}
+
+func (v *localValidator) validateAgainstSchema(source string, schemaFile string, doc any) []ErrorItem {
+	schema, err := v.getSchema(schemaFile)
+	if err != nil {
+		if filepath.IsAbs(schemaFile) {
+			return []ErrorItem{{Error: fmt.Sprintf("failed to load vendored schema %q: %v", schemaFile, err), Source: source}}
+		}
+		log.Fatalf("(bug) Failed to load embedded schema %q: %v", schemaFile, err)
+	}
+	result, err := schema.Validate(gojsonschema.NewGoLoader(doc))
+	if err != nil {
+		return []ErrorItem{{Error: fmt.Sprintf("failed to validate: %v", err), Source: source}}
+	}
+	errs := []ErrorItem{}
+	for _, resultErr := range result.Errors() {
+		if isTypeSchema(schemaFile) {
+			errs = append(errs, ErrorItem{Error: jsondoc.FormatSchemaPointerError(resultErr), Source: source})
+		} else {
+			errs = append(errs, ErrorItem{Error: fmt.Sprint(resultErr), Source: source})
+		}
+	}
+	return errs
+}
+
+// getSchema loads a schema, either embedded (relative path) or vendored (absolute path)
+func (v *localValidator) getSchema(schemaFile string) (*gojsonschema.Schema, error) {
+	v.schemasLock.Lock()
+	defer v.schemasLock.Unlock()
+	if schema, found := v.schemas[schemaFile]; found {
+		return schema, nil
+	}
+	readFile := embeddedSchemas.ReadFile
+	if filepath.IsAbs(schemaFile) {
+		readFile = os.ReadFile
+	}
+	schemaBytes, err := readFile(schemaFile)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(schemaBytes))
+	if err != nil {
+		return nil, err
+	}
+	v.schemas[schemaFile] = schema
+	return schema, nil
+}
+
+// readObjectsFile parses a JSON or YAML file (based on its extension) into
+// generic maps and slices suitable for JSON schema validation
+func readObjectsFile(path string) (any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseObjectsData(data, path)
+}
+
+// parseObjectsData parses the content of an objects file, using the file path's extension to
+// determine the format. Syntax errors are returned as *jsondoc.ParseError, with the error's position.
+func parseObjectsData(data []byte, path string) (any, error) {
+	var doc any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, jsondoc.NewJsonParseError(data, err)
+		}
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, jsondoc.NewYamlParseError(err)
+		}
+	default:
+		return nil, fmt.Errorf("unrecognized file extension, expected .json, .yaml or .yml")
+	}
+	return doc, nil
+}
+
+// remarshal converts a generic document into a typed value using its JSON tags
+func remarshal(doc any, target any) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
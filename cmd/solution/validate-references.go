@@ -0,0 +1,134 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// checkReferences verifies that the references between the solution's objects resolve:
+// FMM objects belong to namespaces defined by the solution, the types referenced within the
+// solution's namespaces (metrics and events reported by entities, association targets, dashui
+// template targets, etc.) are defined and the objects of the solution's own knowledge types
+// have a type definition. Errors are reported with the location of the failing reference.
+func (v *localValidator) checkReferences(manifestName string, manifest *Manifest) {
+	objectFiles, _ := loadManifestObjects(v.root, manifest) // unreadable files are reported by the syntax checks
+	model := newLocalTestModel(objectFiles)
+
+	// objects must be in the namespaces the solution defines
+	for _, file := range objectFiles {
+		if !strings.HasPrefix(file.objType, "fmm:") || file.objType == "fmm:namespace" {
+			continue
+		}
+		for _, obj := range file.objects {
+			objMap, _ := obj.(map[string]any)
+			namespace, _ := objMap["namespace"].(map[string]any)
+			nsName, _ := namespace["name"].(string)
+			if nsName != "" && !model.exists("fmm:namespace", nsName) {
+				v.addError(v.locateReference(file.path, objMap, nsName), "%v %q is in namespace %q, which is not defined by the solution", file.objType, getLocalObjectName(objMap), nsName)
+			}
+		}
+	}
+
+	// referenced types within the solution's namespaces must be defined
+	for _, result := range checkLocalReferences(model) {
+		if !result.Passed {
+			v.addError(v.locateReference(result.File, result.object, result.ref), "%v: %v", result.Test, result.Message)
+		}
+	}
+
+	// objects of the solution's own types must have a type definition
+	definedTypes := map[string]bool{}
+	for _, typeFile := range manifest.Types {
+		doc, err := readObjectsFile(filepath.Join(v.root, typeFile))
+		if err != nil {
+			continue // reported by the syntax checks
+		}
+		typeDefs, isArray := doc.([]any)
+		if !isArray {
+			typeDefs = []any{doc}
+		}
+		for _, typeDef := range typeDefs {
+			typeMap, _ := typeDef.(map[string]any)
+			if name, _ := typeMap["name"].(string); name != "" {
+				definedTypes[manifest.Name+":"+name] = true
+			}
+		}
+	}
+	for _, compDef := range manifest.Objects {
+		if strings.HasPrefix(compDef.Type, manifest.Name+":") && !definedTypes[compDef.Type] {
+			v.addError(v.locateReference(manifestName, nil, compDef.Type), "objects of type %q are not defined by any of the solution's types", compDef.Type)
+		}
+	}
+}
+
+// locateReference returns the location (file:line:column) of the string value within the
+// file; if obj is given, the value is searched for within that object. It returns just the
+// file if the value cannot be located.
+func (v *localValidator) locateReference(file string, obj map[string]any, value string) string {
+	data, err := os.ReadFile(filepath.Join(v.root, file))
+	if err != nil {
+		return file
+	}
+	var doc yaml.Node // JSON is parsed as YAML, to get the positions
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return file
+	}
+	root := doc.Content[0]
+	if name, _ := obj["name"].(string); name != "" && root.Kind == yaml.SequenceNode {
+		for _, item := range root.Content {
+			if findScalarNode(item, "name", name) != nil {
+				root = item
+				break
+			}
+		}
+	}
+	if node := findScalarNode(root, "", value); node != nil {
+		return fmt.Sprintf("%v:%d:%d", file, node.Line, node.Column)
+	}
+	return file
+}
+
+// findScalarNode returns the first scalar node with the value, optionally only as the value
+// of the given key, in a depth-first search of the node tree
+func findScalarNode(node *yaml.Node, key string, value string) *yaml.Node {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if key == "" && node.Value == value {
+			return node
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if key != "" && node.Content[i].Value == key && node.Content[i+1].Value == value {
+				return node.Content[i+1]
+			}
+			if found := findScalarNode(node.Content[i+1], key, value); found != nil {
+				return found
+			}
+		}
+	default:
+		for _, child := range node.Content {
+			if found := findScalarNode(child, key, value); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
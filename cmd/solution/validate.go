@@ -15,7 +15,15 @@
 package solution
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/apex/log"
 	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
 )
 
 type ErrorItem struct {
@@ -37,15 +45,23 @@ var solutionValidateCmd = &cobra.Command{
 	Use:   "validate",
 	Args:  cobra.ExactArgs(0),
 	Short: "Validate solution",
-	Long:  `This command allows the current tenant specified in the profile to upload the solution in the current directory just to validate its contents.  The --stable flag provides a default value of 'stable' for the tag associated with the given solution.  `,
+	Long: `This command allows the current tenant specified in the profile to upload the solution in the current directory just to validate its contents.  The --stable flag provides a default value of 'stable' for the tag associated with the given solution.
+
+With the --local flag, the solution is validated offline, without uploading it: the manifest and all objects it refers to are checked for syntax, structure and required fields using schemas built into fsoc. The references between objects are also checked: FMM objects must be in namespaces the solution defines, the types referenced within them (e.g., the metrics an entity reports or a dashui template's target) must be defined and the solution's own object types must have type definitions. Objects of the solution's own types are validated against their type's jsonSchema, reporting each violation with its JSON pointer. FMM entities, metrics and events are checked against the rules the platform enforces at install: names, attribute types, the combination of a metric's content type, category, temporality and unit, and the namespaces of referenced types, which must be defined by the solution or be one of its dependencies. All errors are reported together, with the file and line of each. No login is required, making it suitable for pre-commit checks.
+Objects of dependency types are checked against the schemas vendored with "fsoc solution vendor", if present.`,
 	Example: `  fsoc solution validate
   fsoc solution validate --bump --tag preprod
   fsoc solution validate --tag dev
   fsoc solution validate --stable
   fsoc solution validate -d mysolution --tag dev
-  fsoc solution validate --solution-bundle=mysolution-1.22.3.zip --tag stable`,
+  fsoc solution validate --solution-bundle=mysolution-1.22.3.zip --tag stable
+  fsoc solution validate --local
+  fsoc solution validate --local -d mysolution`,
 	Run:              validateSolution,
 	TraverseChildren: true,
+	Annotations: map[string]string{
+		config.AnnotationForConfigBypass: "", // needed only when not --local, checked in validateSolution
+	},
 }
 
 func getSolutionValidateCmd() *cobra.Command {
@@ -61,19 +77,102 @@ func getSolutionValidateCmd() *cobra.Command {
 		String("solution-bundle", "", "Path to a prepackaged solution zip")
 
 	solutionValidateCmd.Flags().
-		String("env-file", "", "Path to the env vars json file with pseudo-isolation tag and, optionally, dependency tags (DEPRECATED)")
+		String("env-file", "", "Path to the env vars json file with pseudo-isolation tag and, optionally, dependency tags")
 
 	solutionValidateCmd.Flags().
 		Bool("no-isolate", false, "Disable fsoc-supported solution pseudo-isolation")
 
+	solutionValidateCmd.Flags().
+		Bool("local", false, "Validate offline, using the schemas built into fsoc, without uploading the solution")
+
 	solutionValidateCmd.MarkFlagsMutuallyExclusive("solution-bundle", "directory")
+	solutionValidateCmd.MarkFlagsMutuallyExclusive("solution-bundle", "local")
+	solutionValidateCmd.MarkFlagsMutuallyExclusive("bump", "local")
 	solutionValidateCmd.MarkFlagsMutuallyExclusive("solution-bundle", "bump")
 
 	solutionValidateCmd.MarkFlagsMutuallyExclusive("tag", "stable", "env-file")
 
+	addVariableFlags(solutionValidateCmd)
+	solutionValidateCmd.MarkFlagsMutuallyExclusive("solution-bundle", "set")
+	solutionValidateCmd.MarkFlagsMutuallyExclusive("solution-bundle", "env")
+
+	addReportFlag(solutionValidateCmd)
+
 	return solutionValidateCmd
 }
 
 func validateSolution(cmd *cobra.Command, args []string) {
+	report := startReport(cmd)
+	if local, _ := cmd.Flags().GetBool("local"); local {
+		validateSolutionOffline(cmd)
+		report.finish()
+		return
+	}
+
+	// the command bypasses the config check to allow offline validation, so check here
+	if config.GetCurrentContext() == nil {
+		log.Fatal(`fsoc is not configured, please use "fsoc config create" to configure an initial context`)
+	}
 	uploadSolution(cmd, false)
+	report.finish()
+}
+
+func validateSolutionOffline(cmd *cobra.Command) {
+	solutionRootDirectory, _ := cmd.Flags().GetString("directory")
+	if solutionRootDirectory == "" {
+		solutionRootDirectory = "."
+	}
+	solutionRootDirectory = absolutizePath(solutionRootDirectory)
+	if !isSolutionPackageRoot(solutionRootDirectory) {
+		log.Fatalf("No solution manifest found in %q; please use -d flag", solutionRootDirectory)
+	}
+
+	// the temporary directories are removed explicitly before exiting on errors, as log.Fatalf exits
+	// without running deferred functions
+	tempDirs := []string{}
+	cleanup := func() {
+		for _, dir := range tempDirs {
+			os.RemoveAll(dir)
+		}
+	}
+	defer cleanup()
+
+	// render pseudo-isolated solutions first, so that the final objects are validated
+	solutionDirectory := solutionRootDirectory
+	manifest, err := getSolutionManifest(solutionRootDirectory)
+	if err == nil && manifest.HasPseudoIsolation() {
+		solutionDirectory, _, err = embeddedConditionalIsolate(cmd, solutionRootDirectory)
+		if err != nil {
+			log.Fatalf("Failed to isolate solution with tag: %v", err)
+		}
+		if solutionDirectory != solutionRootDirectory {
+			tempDirs = append(tempDirs, solutionDirectory)
+		}
+	}
+
+	// apply the overlay and substitute template variables, keeping the file names for clarity in the reported errors
+	options := getStageOptions(cmd)
+	options.convertToJson = false
+	stagedDirectory, err := stageSolution(solutionDirectory, "", options)
+	if err != nil {
+		cleanup()
+		log.Fatalf("Failed to render the solution: %v", err)
+	}
+	tempDirs = append(tempDirs, filepath.Dir(stagedDirectory))
+
+	res := validateSolutionLocally(stagedDirectory, filepath.Join(solutionRootDirectory, VendorDirName))
+	report := getReport(cmd)
+	if manifest != nil {
+		report.setSolution(manifest.Name, manifest.SolutionVersion, "")
+	}
+	report.addErrorItems(res.Errors.Items)
+	if !res.Valid {
+		report.setOutcome(reportOutcomeFindings)
+		message := getSolutionValidationErrorsString(res.Errors.Total, res.Errors)
+		output.PrintCmdStatus(cmd, message)
+		cleanup()
+		log.Fatalf("%d error(s) found while validating the solution locally", res.Errors.Total)
+	}
+
+	output.PrintCmdStatus(cmd, fmt.Sprintf("Successfully validated solution %q locally.\n", solutionRootDirectory))
 }
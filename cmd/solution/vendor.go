@@ -0,0 +1,163 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+// VendorDirName is the name of the directory in the solution root that contains
+// the vendored type schemas of the solution's dependencies
+const VendorDirName = "vendor"
+
+var solutionVendorCmd = &cobra.Command{
+	Use:   "vendor",
+	Args:  cobra.ExactArgs(0),
+	Short: "Download the type schemas of the solution's dependencies",
+	Long: `This command downloads the knowledge type definitions of all solutions the solution depends on, and
+stores their JSON schemas in the vendor/ directory of the solution, as vendor/<solution>/<type>.schema.json.
+
+The vendored schemas are used by "fsoc solution validate --local" to check objects of dependency types (e.g.,
+fmm:entity or dashui:template) without network access; they take precedence over the schemas built into fsoc.
+The vendor/ directory is not included in the solution package. Re-run the command to update the schemas,
+e.g., after the dependencies are upgraded.`,
+	Example: `  fsoc solution vendor
+  fsoc solution vendor -d mysolution`,
+	Run: solutionVendor,
+}
+
+// vendoredType is a knowledge type, as returned by the types API
+type vendoredType struct {
+	Name       string `json:"name"`
+	Solution   string `json:"solution"`
+	JsonSchema any    `json:"jsonSchema"`
+}
+
+type vendoredTypeSummary struct {
+	Type string `json:"type"`
+	File string `json:"file"`
+}
+
+func getSolutionVendorCmd() *cobra.Command {
+	solutionVendorCmd.Flags().
+		StringP("directory", "d", "", "Path to the solution root directory (defaults to current dir)")
+
+	return solutionVendorCmd
+}
+
+func solutionVendor(cmd *cobra.Command, args []string) {
+	solutionRootDirectory, _ := cmd.Flags().GetString("directory")
+	if solutionRootDirectory == "" {
+		solutionRootDirectory = "."
+	}
+	solutionRootDirectory = absolutizePath(solutionRootDirectory)
+	manifest, err := getSolutionManifest(solutionRootDirectory)
+	if err != nil {
+		log.Fatalf("Failed to read the solution manifest: %v", err)
+	}
+
+	vendorDirectory := filepath.Join(solutionRootDirectory, VendorDirName)
+	summary := []vendoredTypeSummary{}
+	for _, dep := range getDependencyNames(manifest) {
+		types, err := getSolutionTypes(dep)
+		if err != nil {
+			log.Fatalf("Failed to get the types of solution %q: %v", dep, err)
+		}
+		if len(types) == 0 {
+			log.Warnf("Solution %q has no types or is not available in the tenant", dep)
+			continue
+		}
+
+		// replace the solution's vendored schemas, so that removed types don't linger
+		solutionDirectory := filepath.Join(vendorDirectory, dep)
+		if err := os.RemoveAll(solutionDirectory); err != nil {
+			log.Fatalf("Failed to clean up vendor directory %q: %v", solutionDirectory, err)
+		}
+		if err := os.MkdirAll(solutionDirectory, 0o755); err != nil {
+			log.Fatalf("Failed to create vendor directory %q: %v", solutionDirectory, err)
+		}
+		for _, typeDef := range types {
+			path := getVendoredSchemaPath(vendorDirectory, dep, typeDef.Name)
+			if err := writeVendoredSchema(path, typeDef.JsonSchema); err != nil {
+				log.Fatalf("Failed to write schema for type %v:%v: %v", dep, typeDef.Name, err)
+			}
+			relPath, _ := filepath.Rel(solutionRootDirectory, path)
+			summary = append(summary, vendoredTypeSummary{Type: dep + ":" + typeDef.Name, File: relPath})
+		}
+	}
+
+	lines := [][]string{}
+	for _, item := range summary {
+		lines = append(lines, []string{item.Type, item.File})
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []vendoredTypeSummary `json:"items"`
+		Total int                   `json:"total"`
+	}{summary, len(summary)}, &output.Table{Headers: []string{"Type", "File"}, Lines: lines})
+}
+
+// getSolutionTypes returns all knowledge types defined by a solution
+func getSolutionTypes(solutionName string) ([]vendoredType, error) {
+	query := "?filter=" + url.QueryEscape(fmt.Sprintf(`solution eq "%s"`, solutionName))
+	var res api.CollectionResult[vendoredType]
+	if err := api.JSONGetCollection[vendoredType]("knowledge-store/v1/types"+query, &res, &api.Options{Headers: getHeaders()}); err != nil {
+		return nil, err
+	}
+	return res.Items, nil
+}
+
+func writeVendoredSchema(path string, schema any) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return output.WriteJson(schema, f)
+}
+
+// getVendoredSchemaPath returns the path of the vendored schema file for a type
+func getVendoredSchemaPath(vendorDirectory string, solutionName string, typeName string) string {
+	return filepath.Join(vendorDirectory, solutionName, typeName+".schema.json")
+}
+
+// findVendoredSchema returns the path of the vendored schema for a fully qualified
+// type name (e.g., fmm:entity), or "" if the type's schema is not vendored
+func findVendoredSchema(vendorDirectory string, fqtn string) string {
+	if vendorDirectory == "" {
+		return ""
+	}
+	solutionName, typeName, found := strings.Cut(fqtn, ":")
+	if !found {
+		return ""
+	}
+	if m := dependencyIsolationRegexp.FindStringSubmatch(solutionName); m != nil {
+		solutionName = m[1]
+	}
+	path := getVendoredSchemaPath(vendorDirectory, solutionName, typeName)
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
@@ -0,0 +1,150 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package solution
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+var solutionVersionsCmd = &cobra.Command{
+	Use:   "versions <solution-name>",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "List the versions pushed for a solution",
+	Long: `This command lists the versions of a solution pushed with a tag (release stage), newest first, with the
+time each version was uploaded, who pushed it and the outcome of its installation. The version currently
+installed is marked as current.
+
+The Cached column shows whether the archive of the version is in the local archive cache, where fsoc keeps the
+archives of the last versions pushed from this machine; cached versions can be restored with
+"fsoc solution rollback".`,
+	Example: `  fsoc solution versions mysolution
+  fsoc solution versions mysolution --tag dev -o json`,
+	Run:              listSolutionVersions,
+	TraverseChildren: true,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		config.SetActiveProfile(cmd, args, false)
+		return getSolutionNames(toComplete), cobra.ShellCompDirectiveDefault
+	},
+}
+
+// SolutionVersionInfo describes a version pushed for a solution
+type SolutionVersionInfo struct {
+	Version    string `json:"version" yaml:"version"`
+	UploadedAt string `json:"uploadedAt" yaml:"uploadedAt"`
+	PushedBy   string `json:"pushedBy,omitempty" yaml:"pushedBy,omitempty"`
+	Install    string `json:"install,omitempty" yaml:"install,omitempty"` // current, installed or failed
+	Cached     bool   `json:"cached" yaml:"cached"`
+}
+
+func getSolutionVersionsCmd() *cobra.Command {
+	solutionVersionsCmd.Flags().
+		String("tag", "stable", "Tag (release stage) of the solution")
+
+	return solutionVersionsCmd
+}
+
+func listSolutionVersions(cmd *cobra.Command, args []string) {
+	name := getSolutionNameFromArgs(cmd, args, "")
+	tag, _ := cmd.Flags().GetString("tag")
+	if !IsValidSolutionTag(tag) {
+		log.Fatalf("Invalid tag %q", tag)
+	}
+
+	versions, err := getSolutionVersions(name, tag)
+	if err != nil {
+		log.Fatalf("Failed to get the versions of solution %q: %v", name, err)
+	}
+	if len(versions) == 0 {
+		log.Fatalf("No versions of solution %q found with tag %q", name, tag)
+	}
+
+	lines := make([][]string, len(versions))
+	for i, version := range versions {
+		cached := ""
+		if version.Cached {
+			cached = "yes"
+		}
+		lines[i] = []string{version.Version, version.UploadedAt, version.PushedBy, version.Install, cached}
+	}
+	output.PrintCmdOutputCustom(cmd, struct {
+		Items []SolutionVersionInfo `json:"items"`
+		Total int                   `json:"total"`
+	}{versions, len(versions)}, &output.Table{
+		Headers: []string{"Version", "Uploaded At", "Pushed By", "Install", "Cached"},
+		Lines:   lines,
+	})
+}
+
+// getSolutionVersions returns the versions pushed for a solution with a tag, newest first,
+// combining the release history with the installation records of the versions
+func getSolutionVersions(name string, tag string) ([]SolutionVersionInfo, error) {
+	solutionId := path.Base(locateSolutionUrl("", name, tag))
+	filter := fmt.Sprintf(`data.solutionID eq "%s"`, solutionId)
+	query := fmt.Sprintf("?order=%s&filter=%s", url.QueryEscape("desc"), url.QueryEscape(filter))
+	headers := getHeaders()
+
+	var releases api.CollectionResult[StatusItem]
+	if err := api.JSONGetCollection[StatusItem](fmt.Sprintf(getSolutionReleaseUrl(), query), &releases, &api.Options{Headers: headers}); err != nil {
+		return nil, err
+	}
+	var installs api.CollectionResult[StatusItem]
+	if err := api.JSONGetCollection[StatusItem](fmt.Sprintf(getSolutionInstallUrl(), query), &installs, &api.Options{Headers: headers}); err != nil {
+		return nil, err
+	}
+
+	// the most recent installation of each version determines its outcome
+	installed := map[string]StatusData{}
+	current := ""
+	for _, install := range installs.Items {
+		version := install.StatusData.SolutionVersion
+		if _, found := installed[version]; !found {
+			installed[version] = install.StatusData
+		}
+		if current == "" && install.StatusData.SuccessfulInstall {
+			current = version
+		}
+	}
+
+	versions := []SolutionVersionInfo{}
+	for _, release := range releases.Items {
+		version := SolutionVersionInfo{
+			Version:    release.StatusData.SolutionVersion,
+			UploadedAt: release.CreatedAt,
+			Cached:     getCachedSolutionArchive(name, tag, release.StatusData.SolutionVersion) != "",
+		}
+		if install, found := installed[version.Version]; found {
+			version.PushedBy = install.InstalledBy
+			switch {
+			case version.Version == current:
+				version.Install = "current"
+			case install.SuccessfulInstall:
+				version.Install = "installed"
+			default:
+				version.Install = "failed"
+			}
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
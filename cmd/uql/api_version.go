@@ -15,9 +15,22 @@
 package uql
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/platform/api"
 )
 
 // UQL API version type, supporting a limited set of values.
@@ -27,8 +40,11 @@ type ApiVersion string
 
 // constants for direct use
 const (
-	ApiVersion1 ApiVersion = ApiVersion("v1")
-	//ApiVersion2Beta ApiVersion = ApiVersion("v2beta")
+	ApiVersion1     ApiVersion = ApiVersion("v1")
+	ApiVersion2Beta ApiVersion = ApiVersion("v2beta")
+
+	// ApiVersionAuto selects the newest API version supported by the tenant, detected on first use
+	ApiVersionAuto ApiVersion = ApiVersion("auto")
 
 	ApiVersionDefault ApiVersion = ApiVersion1
 )
@@ -38,9 +54,19 @@ const (
 
 var supportedApiVersions = []string{
 	string(ApiVersion1),
-	//string(ApiVersion2Beta),
+	string(ApiVersion2Beta),
+	string(ApiVersionAuto),
 }
 
+// detectableApiVersions are the API versions tried by ApiVersionAuto, newest first
+var detectableApiVersions = []ApiVersion{ApiVersion2Beta, ApiVersion1}
+
+// apiVersionCacheTTL is how long the API version detected for a tenant is used before detecting it again
+const apiVersionCacheTTL = 24 * time.Hour
+
+// detectedApiVersions caches the API versions detected in this process, by tenant
+var detectedApiVersions sync.Map
+
 func (a *ApiVersion) ValidateAndSet(v any) error {
 	s, ok := v.(string)
 	if !ok {
@@ -53,6 +79,16 @@ func (a *ApiVersion) ValidateAndSet(v any) error {
 	return nil
 }
 
+// Set implements pflag.Value, for the --api-version flag
+func (a *ApiVersion) Set(v string) error {
+	return a.ValidateAndSet(v)
+}
+
+// Type implements pflag.Value
+func (a *ApiVersion) Type() string {
+	return "version"
+}
+
 func (a *ApiVersion) String() string {
 	if a == nil || string(*a) == "" {
 		return string(ApiVersionDefault)
@@ -64,3 +100,158 @@ func (a *ApiVersion) String() string {
 func GetAPIEndpoint(apiVersion ApiVersion) string {
 	return fmt.Sprintf("/monitoring/%v/query/execute", apiVersion)
 }
+
+// configuredApiVersion returns the API version set with --api-version or in the fsoc config file,
+// or the default version
+func configuredApiVersion() ApiVersion {
+	switch {
+	case apiVersionFlag != "":
+		return apiVersionFlag
+	case GlobalConfig.ApiVersion != nil && *GlobalConfig.ApiVersion != "":
+		return *GlobalConfig.ApiVersion // from fsoc config file
+	}
+	return ApiVersionDefault
+}
+
+// decodeChunks decodes the data chunks of a response
+func decodeChunks(raw json.RawMessage) ([]parsedChunk, error) {
+	raw, err := unwrapChunks(raw)
+	if err != nil {
+		return nil, err
+	}
+	var chunks []parsedChunk
+	err = json.Unmarshal(raw, &chunks)
+	return chunks, err
+}
+
+// unwrapChunks returns the array of the data chunks of a response. API version v1 responds with
+// an array of chunks; newer versions may wrap the array in an object, e.g., along with metadata
+// about the response, in which case the chunks are the first array of objects with a type in the
+// object.
+func unwrapChunks(raw json.RawMessage) (json.RawMessage, error) {
+	var chunks []parsedChunk
+	arrayErr := json.Unmarshal(raw, &chunks)
+	if arrayErr == nil {
+		return raw, nil
+	}
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return nil, arrayErr
+	}
+	keys := make([]string, 0, len(envelope))
+	for key := range envelope {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		if err := json.Unmarshal(envelope[key], &chunks); err == nil && len(chunks) > 0 && chunks[0].Type != "" {
+			return envelope[key], nil
+		}
+	}
+	return nil, fmt.Errorf("the response has no data chunks")
+}
+
+// isUnsupportedApiVersion returns whether an error indicates that the API version is not supported
+// by the tenant, i.e., its endpoint does not exist
+func isUnsupportedApiVersion(err error) bool {
+	var statusErr *api.HttpStatusError
+	return errors.As(err, &statusErr) && slices.Contains(unsupportedApiVersionStatuses, statusErr.StatusCode)
+}
+
+// unsupportedApiVersionStatuses are the HTTP statuses of the endpoint of an API version that the
+// tenant does not support
+var unsupportedApiVersionStatuses = []int{http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented}
+
+// executeWithDetectedVersion executes a query with the newest API version supported by the
+// tenant, trying the versions in turn unless one was detected recently
+func executeWithDetectedVersion(query *Query, backend uqlService) (*Response, error) {
+	tenantKey, cachePath := apiVersionCacheKey()
+	candidates := detectableApiVersions
+	if detected := loadDetectedApiVersion(tenantKey, cachePath); detected != "" {
+		candidates = append([]ApiVersion{detected}, slices.DeleteFunc(slices.Clone(candidates), func(v ApiVersion) bool { return v == detected })...)
+	}
+
+	for _, version := range candidates {
+		response, err := backend.Execute(query, version)
+		if isUnsupportedApiVersion(err) {
+			log.WithFields(log.Fields{"apiVersion": version}).Info("UQL API version not supported by the tenant, trying the next one")
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		saveDetectedApiVersion(tenantKey, cachePath, version)
+		return processResponse(response)
+	}
+	return nil, fmt.Errorf("the tenant supports none of the UQL API versions %v", detectableApiVersions)
+}
+
+// apiVersionCacheKey returns the key identifying the tenant of the current profile, and the path
+// of the file caching the API version detected for it; both are empty if there is no profile
+func apiVersionCacheKey() (string, string) {
+	cfg := config.GetCurrentContext()
+	if cfg == nil {
+		return "", ""
+	}
+	key := cfg.URL + "|" + cfg.Tenant
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return key, ""
+	}
+	return key, filepath.Join(cacheDir, "fsoc", "uql_api_version", url.PathEscape(cfg.Tenant)+".json")
+}
+
+// detectedApiVersionFile is the content of the file caching the API version detected for a tenant
+type detectedApiVersionFile struct {
+	Tenant   string     `json:"tenant"` // the key of the tenant, with its URL
+	Version  ApiVersion `json:"version"`
+	Detected time.Time  `json:"detected"`
+}
+
+func loadDetectedApiVersion(key string, path string) ApiVersion {
+	if key == "" {
+		return ""
+	}
+	if version, found := detectedApiVersions.Load(key); found {
+		return version.(ApiVersion)
+	}
+	if path == "" {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	var cached detectedApiVersionFile
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return ""
+	}
+	if cached.Tenant != key || time.Since(cached.Detected) > apiVersionCacheTTL || !slices.Contains(detectableApiVersions, cached.Version) {
+		return ""
+	}
+	detectedApiVersions.Store(key, cached.Version)
+	return cached.Version
+}
+
+func saveDetectedApiVersion(key string, path string, version ApiVersion) {
+	if key == "" {
+		return
+	}
+	if previous, found := detectedApiVersions.Swap(key, version); found && previous == version {
+		return
+	}
+	log.WithFields(log.Fields{"apiVersion": version}).Info("detected the UQL API version supported by the tenant")
+	if path == "" {
+		return
+	}
+	data, err := json.Marshal(detectedApiVersionFile{Tenant: key, Version: version, Detected: time.Now()})
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0o700)
+	}
+	if err == nil {
+		err = os.WriteFile(path, data, 0o600)
+	}
+	if err != nil {
+		log.Warnf("Failed to cache the detected UQL API version: %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+// language=json
+const apiVersionResponse = `[
+  { "type": "model", "model": { "name": "m:main", "fields": [ { "alias": "id", "type": "string" } ] } },
+  { "type": "data", "model": { "$jsonPath": "", "$model": "m:main" }, "dataset": "d:main", "data": [ [ "w1" ] ] }
+]`
+
+func TestDecodeChunks(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{"array", apiVersionResponse},
+		{"wrapped", `{ "metadata": { "took": 5 }, "chunks": ` + apiVersionResponse + ` }`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// when
+			chunks, err := decodeChunks(json.RawMessage(test.raw))
+
+			// then
+			assert.NoError(t, err)
+			assert.Len(t, chunks, 2)
+			assert.Equal(t, "model", chunks[0].Type)
+			assert.Equal(t, "d:main", chunks[1].Dataset)
+		})
+	}
+}
+
+func TestDecodeChunks_NoChunks(t *testing.T) {
+	// when
+	_, err := decodeChunks(json.RawMessage(`{ "items": [ 1, 2 ] }`))
+
+	// then
+	assert.ErrorContains(t, err, "no data chunks")
+}
+
+func TestExecuteUqlQuery_DetectsApiVersion(t *testing.T) {
+	// given
+	var versions []ApiVersion
+	backend := &mockUqlService{
+		executeBehavior: func(query *Query, version ApiVersion) (parsedResponse, error) {
+			versions = append(versions, version)
+			if version != ApiVersion1 {
+				return parsedResponse{}, &api.HttpStatusError{StatusCode: 404}
+			}
+			raw := json.RawMessage(apiVersionResponse)
+			chunks, err := decodeChunks(raw)
+			return parsedResponse{chunks: chunks, rawJson: &raw}, err
+		},
+	}
+
+	// when
+	response, err := executeUqlQuery(&Query{Str: "FETCH id"}, ApiVersionAuto, backend)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []ApiVersion{ApiVersion2Beta, ApiVersion1}, versions)
+	assert.Equal(t, [][]any{{"w1"}}, response.Main().Values())
+}
+
+func TestExecuteUqlQuery_AutoReportsOtherErrors(t *testing.T) {
+	// given
+	var versions []ApiVersion
+	backend := &mockUqlService{
+		executeBehavior: func(query *Query, version ApiVersion) (parsedResponse, error) {
+			versions = append(versions, version)
+			return parsedResponse{}, &api.HttpStatusError{StatusCode: 400, Message: "bad query"}
+		},
+	}
+
+	// when
+	_, err := executeUqlQuery(&Query{Str: "FETCH id"}, ApiVersionAuto, backend)
+
+	// then
+	assert.ErrorContains(t, err, "bad query")
+	assert.Equal(t, []ApiVersion{ApiVersion2Beta}, versions)
+}
+
+func TestApiVersionFlag(t *testing.T) {
+	// given
+	var version ApiVersion
+
+	// when
+	err := version.Set("v3")
+
+	// then
+	assert.ErrorContains(t, err, `API version "v3" is not supported`)
+	assert.NoError(t, version.Set("auto"))
+	assert.Equal(t, ApiVersionAuto, version)
+}
@@ -17,6 +17,7 @@ package uql
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
 
 	"github.com/apex/log"
 	"github.com/pkg/errors"
@@ -36,16 +37,26 @@ type defaultBackend struct {
 func (b defaultBackend) Execute(query *Query, apiVersion ApiVersion) (parsedResponse, error) {
 	log.WithFields(log.Fields{"query": query.Str, "apiVersion": apiVersion}).Info("executing UQL query")
 
+	// versions other than v1 may not be supported by the tenant, which is reported to the caller
+	// rather than logged as an error, e.g., while detecting the version
+	options := b.apiOptions
+	if apiVersion != ApiVersion1 {
+		options = &api.Options{}
+		if b.apiOptions != nil {
+			*options = *b.apiOptions
+		}
+		options.ExpectedErrors = append(slices.Clone(options.ExpectedErrors), unsupportedApiVersionStatuses...)
+	}
+
 	var rawJson json.RawMessage
-	err := api.JSONPost(GetAPIEndpoint(apiVersion), query, &rawJson, b.apiOptions)
+	err := api.JSONPost(GetAPIEndpoint(apiVersion), query, &rawJson, options)
 	if err != nil {
 		if problem, ok := err.(api.Problem); ok {
 			return parsedResponse{}, makeUqlProblem(problem)
 		}
 		return parsedResponse{}, errors.Wrap(err, fmt.Sprintf("failed to execute UQL Query: '%s'", query.Str))
 	}
-	var chunks []parsedChunk
-	err = json.Unmarshal(rawJson, &chunks)
+	chunks, err := decodeChunks(rawJson)
 	if err != nil {
 		return parsedResponse{}, errors.Wrap(err, fmt.Sprintf("failed to parse response for UQL Query: '%s'", query.Str))
 	}
@@ -63,8 +74,7 @@ func (b defaultBackend) Continue(link *Link) (parsedResponse, error) {
 	if err != nil {
 		return parsedResponse{}, errors.Wrap(err, fmt.Sprintf("failed follow link: '%s'", link.Href))
 	}
-	var chunks []parsedChunk
-	err = json.Unmarshal(rawJson, &chunks)
+	chunks, err := decodeChunks(rawJson)
 	if err != nil {
 		return parsedResponse{}, errors.Wrap(err, fmt.Sprintf("failed to parse response for link: '%s'", link.Href))
 	}
@@ -0,0 +1,269 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	fsoc "github.com/cisco-open/fsoc/output"
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench [-n <runs>] <query> [<query>]",
+	Short: "Measure the latency of queries by executing them repeatedly",
+	Long: `Execute a query repeatedly and report the percentiles of its latency, with the number of pages, data chunks,
+top-level rows and bytes of the responses per run, e.g., to tune the queries of expensive dashboards.
+
+Each run fetches all the pages of results, up to --max-rows top-level rows, and is never served from the cache.
+The first --warmup runs are not measured. With two queries, e.g., two variants of a query, the runs of the
+queries alternate, so that both are measured under the same conditions, and their latencies are compared.
+
+The --since, --until, --param and --page-size flags apply to the queries as with the other uql commands.`,
+	Example: `  fsoc uql bench -n 20 "FETCH id, metrics(infra:cpu.used) FROM entities(k8s:workload)"
+  fsoc uql bench -n 20 --since 1d "FETCH id, metrics(infra:cpu.used) FROM entities(k8s:workload)" "FETCH id, metrics(infra:cpu.used) {timestamp, value} FROM entities(k8s:workload) LIMITS metrics.granularityDuration(PT1H)"
+  fsoc uql bench -n 50 -o json "FETCH count(*) FROM entities(apm:service)"`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: benchQueries,
+}
+
+func init() {
+	benchCmd.Flags().IntP("runs", "n", 10, "Number of measured executions of each query")
+	benchCmd.Flags().Int("warmup", 1, "Number of executions of each query before the measured ones")
+	uqlCmd.AddCommand(benchCmd)
+}
+
+// benchResult is the result of the benchmark of a query; latencies are in milliseconds and the
+// pages, chunks, rows and bytes are per run
+type benchResult struct {
+	Query   string       `json:"query"`
+	Runs    int          `json:"runs"`
+	Latency benchLatency `json:"latency"`
+	Pages   float64      `json:"pages"`
+	Chunks  float64      `json:"chunks"`
+	Rows    float64      `json:"rows"`
+	Bytes   float64      `json:"bytes"`
+
+	latencies []time.Duration
+}
+
+type benchLatency struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+func benchQueries(cmd *cobra.Command, args []string) error {
+	runs, _ := cmd.Flags().GetInt("runs")
+	warmup, _ := cmd.Flags().GetInt("warmup")
+	if runs <= 0 {
+		return fmt.Errorf("the number of runs must be positive")
+	}
+	if warmup < 0 {
+		return fmt.Errorf("the number of warmup runs cannot be negative")
+	}
+	params, err := queryParams(nil)
+	if err != nil {
+		return err
+	}
+	queries := make([]string, len(args))
+	for i, query := range args {
+		if queries[i], err = prepareQuery(query, params); err != nil {
+			return fmt.Errorf("query %d: %w", i+1, err)
+		}
+	}
+
+	results := make([]*benchResult, len(queries))
+	for i, query := range queries {
+		results[i] = &benchResult{Query: query}
+	}
+	for run := 1; run <= warmup+runs; run++ {
+		for i, query := range queries {
+			log.WithFields(log.Fields{"query": i + 1, "run": run, "warmup": run <= warmup}).Info("executing query")
+			client := &meteredClient{client: Client}
+			start := time.Now()
+			rows, err := executeBenchRun(client, query)
+			elapsed := time.Since(start)
+			if problem, ok := err.(uqlProblem); ok {
+				printProblemDescription(cmd, problem, query)
+			}
+			if err != nil {
+				return fmt.Errorf("query %d, run %d: %w", i+1, run, err)
+			}
+			if run > warmup {
+				results[i].record(elapsed, client, rows)
+			}
+		}
+	}
+
+	lines := make([][]string, len(results))
+	for i, result := range results {
+		result.summarize()
+		lines[i] = []string{
+			strconv.Itoa(i + 1),
+			strconv.Itoa(result.Runs),
+			formatMillis(result.Latency.Min),
+			formatMillis(result.Latency.Mean),
+			formatMillis(result.Latency.P50),
+			formatMillis(result.Latency.P90),
+			formatMillis(result.Latency.P95),
+			formatMillis(result.Latency.P99),
+			formatMillis(result.Latency.Max),
+			formatPerRun(result.Pages),
+			formatPerRun(result.Chunks),
+			formatPerRun(result.Rows),
+			formatPerRun(result.Bytes),
+		}
+	}
+	fsoc.PrintCmdOutputCustom(cmd, struct {
+		Items []*benchResult `json:"items"`
+		Total int            `json:"total"`
+	}{results, len(results)}, &fsoc.Table{
+		Headers: []string{"Query", "Runs", "Min (ms)", "Mean (ms)", "P50 (ms)", "P90 (ms)", "P95 (ms)", "P99 (ms)", "Max (ms)", "Pages", "Chunks", "Rows", "Bytes"},
+		Lines:   lines,
+	})
+	if len(results) == 2 {
+		fsoc.PrintCmdStatus(cmd, compareBenchResults(results[0], results[1]))
+	}
+	return nil
+}
+
+// executeBenchRun executes a query and fetches all the pages of its results, returning the number
+// of top-level rows fetched
+func executeBenchRun(client UqlClient, query string) (int, error) {
+	response, err := client.ExecuteQuery(&Query{Str: query})
+	if err != nil {
+		return 0, err
+	}
+	response, err = fetchAllPages(client, response, maxRowsFlag)
+	if err != nil || response.Main() == nil {
+		return 0, err
+	}
+	if partial := response.Partial(); partial != nil {
+		return 0, errors.New(partial.Detail)
+	}
+	return len(response.Main().Data), nil
+}
+
+func (r *benchResult) record(elapsed time.Duration, client *meteredClient, rows int) {
+	r.latencies = append(r.latencies, elapsed)
+	r.Runs++
+	r.Pages += float64(client.pages)
+	r.Chunks += float64(client.chunks)
+	r.Rows += float64(rows)
+	r.Bytes += float64(client.bytes)
+}
+
+// summarize computes the latency statistics and the averages per run of the recorded runs
+func (r *benchResult) summarize() {
+	if r.Runs == 0 {
+		return
+	}
+	sorted := slices.Clone(r.latencies)
+	slices.Sort(sorted)
+	var total time.Duration
+	for _, latency := range sorted {
+		total += latency
+	}
+	r.Latency = benchLatency{
+		Min:  millis(sorted[0]),
+		Mean: millis(total / time.Duration(len(sorted))),
+		P50:  millis(percentile(sorted, 50)),
+		P90:  millis(percentile(sorted, 90)),
+		P95:  millis(percentile(sorted, 95)),
+		P99:  millis(percentile(sorted, 99)),
+		Max:  millis(sorted[len(sorted)-1]),
+	}
+	runs := float64(r.Runs)
+	r.Pages /= runs
+	r.Chunks /= runs
+	r.Rows /= runs
+	r.Bytes /= runs
+}
+
+// percentile returns the p-th percentile of sorted latencies, by the nearest-rank method
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// compareBenchResults describes how the median latency of the second query compares to the first
+func compareBenchResults(first *benchResult, second *benchResult) string {
+	a, b := first.Latency.P50, second.Latency.P50
+	switch {
+	case a == b:
+		return fmt.Sprintf("Both queries have a median latency of %vms\n", formatMillis(a))
+	case b < a:
+		return fmt.Sprintf("Query 2 is %.2fx faster than query 1 (median %vms vs %vms)\n", a/b, formatMillis(b), formatMillis(a))
+	default:
+		return fmt.Sprintf("Query 2 is %.2fx slower than query 1 (median %vms vs %vms)\n", b/a, formatMillis(b), formatMillis(a))
+	}
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func formatMillis(ms float64) string {
+	return strconv.FormatFloat(ms, 'f', 1, 64)
+}
+
+func formatPerRun(value float64) string {
+	return strconv.FormatFloat(math.Round(value*10)/10, 'f', -1, 64)
+}
+
+// meteredClient counts the pages, data chunks and bytes of the responses of a client
+type meteredClient struct {
+	client UqlClient
+	pages  int
+	chunks int
+	bytes  int
+}
+
+func (c *meteredClient) ExecuteQuery(query *Query) (*Response, error) {
+	response, err := c.client.ExecuteQuery(query)
+	c.count(response)
+	return response, err
+}
+
+func (c *meteredClient) ContinueQuery(dataSet *DataSet, rel string) (*Response, error) {
+	response, err := c.client.ContinueQuery(dataSet, rel)
+	c.count(response)
+	return response, err
+}
+
+func (c *meteredClient) count(response *Response) {
+	if response == nil || response.raw == nil {
+		return
+	}
+	c.pages++
+	c.bytes += len(*response.raw)
+	var chunks []json.RawMessage
+	if err := unmarshalChunks(*response.raw, &chunks); err == nil {
+		c.chunks += len(chunks)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecuteBenchRun(t *testing.T) {
+	// given
+	first, second := pageResponse("/page2", "a", "b"), pageResponse("", "c")
+	backend := mockPagedService(t, first, map[string]string{"/page2": second})
+	client := &meteredClient{client: defaultClient{backend: backend}}
+
+	// when
+	rows, err := executeBenchRun(client, "FETCH id")
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, 3, rows)
+	assert.Equal(t, 2, client.pages)
+	assert.Equal(t, 4, client.chunks)
+	assert.Equal(t, len(first)+len(second), client.bytes)
+}
+
+func TestBenchResultSummarize(t *testing.T) {
+	// given
+	result := &benchResult{Query: "FETCH id"}
+	for i := 10; i >= 1; i-- {
+		client := &meteredClient{pages: 2, chunks: 4, bytes: 100 * i}
+		result.record(time.Duration(i)*time.Millisecond, client, 5)
+	}
+
+	// when
+	result.summarize()
+
+	// then
+	assert.Equal(t, benchLatency{Min: 1, Mean: 5.5, P50: 5, P90: 9, P95: 10, P99: 10, Max: 10}, result.Latency)
+	assert.Equal(t, 10, result.Runs)
+	assert.Equal(t, 2.0, result.Pages)
+	assert.Equal(t, 4.0, result.Chunks)
+	assert.Equal(t, 5.0, result.Rows)
+	assert.Equal(t, 550.0, result.Bytes)
+}
+
+func TestCompareBenchResults(t *testing.T) {
+	// given
+	slow := &benchResult{Latency: benchLatency{P50: 300}}
+	fast := &benchResult{Latency: benchLatency{P50: 120}}
+
+	// then
+	assert.Equal(t, "Query 2 is 2.50x faster than query 1 (median 120.0ms vs 300.0ms)\n", compareBenchResults(slow, fast))
+	assert.Equal(t, "Query 2 is 2.50x slower than query 1 (median 300.0ms vs 120.0ms)\n", compareBenchResults(fast, slow))
+	assert.Equal(t, "Both queries have a median latency of 120.0ms\n", compareBenchResults(fast, fast))
+}
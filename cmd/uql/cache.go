@@ -0,0 +1,206 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/apex/log"
+
+	"github.com/cisco-open/fsoc/config"
+)
+
+// resultCacheKey identifies cached results: the query, the settings affecting its results and the
+// tenant it is executed for
+type resultCacheKey struct {
+	queryKey
+	MaxRows    int    `json:"maxRows,omitempty"`
+	Profile    string `json:"profile"`
+	URL        string `json:"url"`
+	Tenant     string `json:"tenant"`
+	ApiVersion string `json:"apiVersion,omitempty"`
+}
+
+// resultCacheFile is the content of a file with cached results
+type resultCacheFile struct {
+	Created time.Time         `json:"created"`
+	Expires time.Time         `json:"expires"` // with the TTL of the execution that cached the results
+	Pages   []json.RawMessage `json:"pages"`
+}
+
+// resultCacheExpiry is the part of a cached results file read when pruning the cache
+type resultCacheExpiry struct {
+	Expires time.Time `json:"expires"`
+}
+
+// resultCache caches the results of a query for a short time, so that repeated executions of the
+// same query, e.g., by scripts refreshing a display, do not execute it again
+type resultCache struct {
+	path  string
+	ttl   time.Duration
+	pages []json.RawMessage // the pages recorded for saving
+}
+
+// newResultCache returns the cache for the results of a query executed for the current profile
+func newResultCache(key queryKey, maxRows int, ttl time.Duration) (*resultCache, error) {
+	cfg := config.GetCurrentContext()
+	if cfg == nil {
+		return nil, fmt.Errorf("no current profile")
+	}
+	cacheKey := resultCacheKey{queryKey: key, MaxRows: maxRows, Profile: cfg.Name, URL: cfg.URL, Tenant: cfg.Tenant, ApiVersion: string(configuredApiVersion())}
+	data, err := json.Marshal(cacheKey)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(data)
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &resultCache{path: filepath.Join(cacheDir, "fsoc", "uql_results", hex.EncodeToString(hash[:])+".json"), ttl: ttl}, nil
+}
+
+// load returns the cached results, or nil if there are none or they are older than the TTL or
+// have expired with the TTL they were cached with
+func (c *resultCache) load() *Response {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Warnf("Failed to read the cached results, ignoring them: %v", err)
+		}
+		return nil
+	}
+	var cached resultCacheFile
+	if err := json.Unmarshal(data, &cached); err != nil {
+		log.Warnf("Failed to parse the cached results, ignoring them: %v", err)
+		return nil
+	}
+	age := time.Since(cached.Created)
+	if age > c.ttl || time.Now().After(cached.Expires) || len(cached.Pages) == 0 {
+		return nil
+	}
+
+	var response *Response
+	for _, page := range cached.Pages {
+		pageResponse, err := parsePage(page)
+		if err == nil && response != nil {
+			err = mergePage(response, pageResponse)
+		}
+		if err != nil {
+			log.Warnf("Failed to parse the cached results, ignoring them: %v", err)
+			return nil
+		}
+		if response == nil {
+			response = pageResponse
+		}
+	}
+	log.WithFields(log.Fields{"age": age.Round(time.Second), "path": c.path}).Info("using cached results (use --no-cache to execute the query)")
+	return response
+}
+
+// record adds a page of results to the results to save
+func (c *resultCache) record(page *Response) error {
+	if page.raw != nil {
+		c.pages = append(c.pages, *page.raw)
+	}
+	return nil
+}
+
+// save saves the recorded pages of results, if any, and removes the results that have expired
+// from the cache. Failures are logged, as the cache is an optimization.
+func (c *resultCache) save() {
+	if len(c.pages) == 0 {
+		return
+	}
+	dir := filepath.Dir(c.path)
+	now := time.Now()
+	data, err := json.Marshal(resultCacheFile{Created: now, Expires: now.Add(c.ttl), Pages: c.pages})
+	if err == nil {
+		err = os.MkdirAll(dir, 0o700)
+	}
+	if err == nil {
+		err = os.WriteFile(c.path, data, 0o600)
+	}
+	if err != nil {
+		log.Warnf("Failed to cache the results: %v", err)
+		return
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if path != c.path && isExpiredCacheFile(path) {
+			_ = os.Remove(path)
+		}
+	}
+}
+
+// isExpiredCacheFile returns true if the cached results in the file have expired, according to
+// the TTL they were cached with; files that cannot be parsed are considered expired
+func isExpiredCacheFile(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false // e.g., removed concurrently
+	}
+	var cached resultCacheExpiry
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return true
+	}
+	return time.Now().After(cached.Expires)
+}
+
+// normalizeQuery collapses the white space in a query, outside of string literals, so that queries
+// that differ only in their layout have the same key
+func normalizeQuery(query string) string {
+	var result strings.Builder
+	var quote rune // the quote of the string literal the scan is in, 0 if none
+	space := false // whether white space is pending
+	runes := []rune(strings.TrimSpace(query))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == 0 && unicode.IsSpace(r):
+			space = true
+			continue
+		case quote != 0 && r == '\\' && i+1 < len(runes):
+			result.WriteRune(r)
+			i++
+			r = runes[i]
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		}
+		if space {
+			result.WriteRune(' ')
+			space = false
+		}
+		result.WriteRune(r)
+	}
+	return result.String()
+}
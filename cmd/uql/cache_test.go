@@ -0,0 +1,81 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	assert.Equal(t, `FETCH id FROM entities(k8s:workload)[attributes("name") = "a  b"]`,
+		normalizeQuery("\n  FETCH id\n\tFROM   entities(k8s:workload)[attributes(\"name\") = \"a  b\"]  \n"))
+	assert.Equal(t, `FETCH id FROM entities[attributes('x') = 'it\'s  here']`,
+		normalizeQuery(`FETCH  id FROM entities[attributes('x') = 'it\'s  here']`))
+}
+
+func TestResultCache(t *testing.T) {
+	// given: results of two pages
+	backend := mockPagedService(t, pageResponse("/page2", "a", "b"), map[string]string{
+		"/page2": pageResponse("", "c"),
+	})
+	client := defaultClient{backend: backend}
+	cache := &resultCache{path: filepath.Join(t.TempDir(), "uql_results", "query.json"), ttl: time.Minute}
+	assert.Nil(t, cache.load())
+
+	response, err := client.ExecuteQuery(&Query{"ignored"})
+	assert.NoError(t, err)
+	assert.NoError(t, cache.record(response))
+	_, err = fetchAllPages(recordingClient{UqlClient: client, record: cache.record}, response, 0)
+	assert.NoError(t, err)
+
+	// when
+	cache.save()
+	cached := cache.load()
+
+	// then
+	assert.NotNil(t, cached)
+	assert.Equal(t, [][]any{{"a"}, {"b"}, {"c"}}, cached.Main().Values())
+	assert.JSONEq(t, response.Raw(), cached.Raw())
+
+	// expired results are not used
+	expired := &resultCache{path: cache.path, ttl: time.Nanosecond}
+	assert.Nil(t, expired.load())
+}
+
+func TestResultCache_PruneWithOwnExpiry(t *testing.T) {
+	// given: results cached with a long TTL and results that have expired
+	dir := filepath.Join(t.TempDir(), "uql_results")
+	page := json.RawMessage(pageResponse("", "a"))
+	longLived := &resultCache{path: filepath.Join(dir, "long.json"), ttl: time.Hour, pages: []json.RawMessage{page}}
+	longLived.save()
+	expired := &resultCache{path: filepath.Join(dir, "expired.json"), ttl: time.Nanosecond, pages: []json.RawMessage{page}}
+	expired.save()
+
+	// when: results are cached with a TTL shorter than the long-lived results' age
+	time.Sleep(10 * time.Millisecond)
+	short := &resultCache{path: filepath.Join(dir, "short.json"), ttl: 5 * time.Millisecond, pages: []json.RawMessage{page}}
+	short.save()
+
+	// then: only the expired results are removed
+	assert.FileExists(t, longLived.path)
+	assert.NoFileExists(t, expired.path)
+	assert.FileExists(t, short.path)
+	assert.NotNil(t, longLived.load())
+}
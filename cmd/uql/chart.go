@@ -0,0 +1,344 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// chart styles for --chart
+const (
+	chartLine      = "line"
+	chartSparkline = "sparkline"
+)
+
+const (
+	defaultChartWidth = 80 // used when the output is not a terminal
+	minChartWidth     = 10
+	lineChartHeight   = 8 // in lines of text, each with 4 rows of braille dots
+)
+
+// sparkLevels are the characters of a sparkline, from the lowest value to the highest
+var sparkLevels = []rune("▁▂▃▄▅▆▇█")
+
+// brailleDots are the bits of the braille dots of a character, by row and column of the dot
+var brailleDots = [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+// timeSeries is a series of numeric values over time found in the results, e.g., a metric of an entity
+type timeSeries struct {
+	label  string
+	points []timePoint // sorted by time
+}
+
+type timePoint struct {
+	time  time.Time
+	value float64
+}
+
+func parseChartStyle(style string) (string, error) {
+	switch strings.ToLower(style) {
+	case chartLine, "":
+		return chartLine, nil
+	case chartSparkline:
+		return chartSparkline, nil
+	}
+	return "", fmt.Errorf("unsupported chart style %q: must be %v or %v", style, chartLine, chartSparkline)
+}
+
+// checkChartOutput checks that charts, if requested, can be displayed in the output format
+func checkChartOutput(output format) error {
+	if chartFlag == "" {
+		return nil
+	}
+	if output != tableFormat && output != autoFormat {
+		return fmt.Errorf("--chart cannot be used with the %v output format", outputFlag)
+	}
+	_, err := parseChartStyle(chartFlag)
+	return err
+}
+
+// printCharts prints the time series in the results as charts, as wide as the terminal
+func printCharts(cmd *cobra.Command, response *Response, style string) error {
+	style, err := parseChartStyle(style)
+	if err != nil {
+		return err
+	}
+	var series []timeSeries
+	if response.Main() != nil {
+		series = findTimeSeries(response.Main(), response.Model(), nil)
+	}
+	if len(series) == 0 {
+		return fmt.Errorf("the results have no time series to chart: a data set with a timestamp and numeric values, e.g., from FETCH metrics(infra:cpu.used) {timestamp, value}")
+	}
+
+	width := chartWidth(cmd.OutOrStdout())
+	if style == chartSparkline {
+		cmd.Print(renderSparklines(series, width))
+		return nil
+	}
+	for i, s := range series {
+		if i > 0 {
+			cmd.Println()
+		}
+		cmd.Println(s.label)
+		cmd.Print(renderLineChart(s, width, lineChartHeight))
+	}
+	return nil
+}
+
+// chartWidth returns the width of the terminal the output is written to, or a default width
+func chartWidth(w io.Writer) int {
+	if file, ok := w.(*os.File); ok && term.IsTerminal(int(file.Fd())) {
+		if width, _, err := term.GetSize(int(file.Fd())); err == nil && width > 0 {
+			return width
+		}
+	}
+	return defaultChartWidth
+}
+
+// findTimeSeries returns the time series in a data set: a series for each numeric column of a data
+// set with a timestamp column, or the time series in the nested data sets of its rows. The series
+// are labeled with the text values of the rows they are found in, e.g., the entity ID.
+func findTimeSeries(data Complex, model *Model, labels []string) []timeSeries {
+	if complexIsEmpty(data) {
+		return nil
+	}
+	timeColumn := -1
+	var valueColumns []int
+	for c, field := range model.Fields {
+		switch {
+		case field.Model != nil:
+		case field.Type == "timestamp" && timeColumn < 0:
+			timeColumn = c
+		case field.Type == "number" || field.Type == "long" || field.Type == "double":
+			valueColumns = append(valueColumns, c)
+		}
+	}
+	if timeColumn >= 0 && len(valueColumns) > 0 {
+		var series []timeSeries
+		for _, c := range valueColumns {
+			s := timeSeries{label: strings.Join(labels, " / ")}
+			if len(valueColumns) > 1 || s.label == "" {
+				s.label = strings.Join(append(append([]string{}, labels...), model.Fields[c].Alias), " / ")
+			}
+			for _, row := range data.Values() {
+				t, isTime := row[timeColumn].(time.Time)
+				value, isNumber := toFloat(row[c])
+				if isTime && isNumber {
+					s.points = append(s.points, timePoint{time: t, value: value})
+				}
+			}
+			sort.Slice(s.points, func(i, j int) bool { return s.points[i].time.Before(s.points[j].time) })
+			if len(s.points) > 0 {
+				series = append(series, s)
+			}
+		}
+		return series
+	}
+
+	var series []timeSeries
+	for _, row := range data.Values() {
+		rowLabels := labels
+		var texts []string
+		for c, field := range model.Fields {
+			if text, ok := row[c].(string); ok && field.Model == nil && text != "" {
+				texts = append(texts, text)
+			}
+		}
+		if len(texts) > 0 {
+			rowLabels = append(append([]string{}, labels...), strings.Join(texts, " "))
+		}
+		for c, field := range model.Fields {
+			if nested, ok := row[c].(Complex); ok && field.Model != nil {
+				series = append(series, findTimeSeries(nested, field.Model, rowLabels)...)
+			}
+		}
+	}
+	return series
+}
+
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, !math.IsNaN(v) && !math.IsInf(v, 0)
+	}
+	return 0, false
+}
+
+// valueRange returns the lowest and the highest values of a series
+func (s timeSeries) valueRange() (float64, float64) {
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, p := range s.points {
+		low = math.Min(low, p.value)
+		high = math.Max(high, p.value)
+	}
+	return low, high
+}
+
+// buckets averages the values of a series in n buckets of equal duration; buckets without values
+// are NaN
+func (s timeSeries) buckets(n int) []float64 {
+	sums := make([]float64, n)
+	counts := make([]int, n)
+	start, end := s.points[0].time, s.points[len(s.points)-1].time
+	for _, p := range s.points {
+		i := 0
+		if end.After(start) {
+			i = min(int(float64(n)*float64(p.time.Sub(start))/float64(end.Sub(start))), n-1)
+		}
+		sums[i] += p.value
+		counts[i]++
+	}
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = math.NaN()
+		if counts[i] > 0 {
+			values[i] = sums[i] / float64(counts[i])
+		}
+	}
+	return values
+}
+
+// scale returns the position of a value in the range from low to high, from 0 to steps-1
+func scale(value float64, low float64, high float64, steps int) int {
+	if high == low {
+		return steps / 2
+	}
+	return int(math.Round((value - low) / (high - low) * float64(steps-1)))
+}
+
+// renderSparklines renders each series as a sparkline on a line of its own, with its label and
+// its lowest, highest and last values
+func renderSparklines(series []timeSeries, width int) string {
+	labelWidth, statsWidth := 0, 0
+	stats := make([]string, len(series))
+	for i, s := range series {
+		low, high := s.valueRange()
+		stats[i] = fmt.Sprintf("min %v  max %v  last %v", formatChartValue(low), formatChartValue(high), formatChartValue(s.points[len(s.points)-1].value))
+		labelWidth = max(labelWidth, len([]rune(s.label)))
+		statsWidth = max(statsWidth, len(stats[i]))
+	}
+	sparkWidth := width - labelWidth - statsWidth - 4
+	if sparkWidth < minChartWidth {
+		sparkWidth = minChartWidth
+	}
+
+	var b strings.Builder
+	for i, s := range series {
+		low, high := s.valueRange()
+		values := s.buckets(min(sparkWidth, len(s.points)))
+		spark := make([]rune, len(values))
+		for j, value := range values {
+			spark[j] = ' '
+			if !math.IsNaN(value) {
+				spark[j] = sparkLevels[scale(value, low, high, len(sparkLevels))]
+			}
+		}
+		fmt.Fprintf(&b, "%-*s  %-*s  %s\n", labelWidth, s.label, sparkWidth, string(spark), stats[i])
+	}
+	return b.String()
+}
+
+// renderLineChart renders a series as a line chart drawn with braille dots, with the range of its
+// values on the left and its time range below
+func renderLineChart(s timeSeries, width int, height int) string {
+	low, high := s.valueRange()
+	highLabel, lowLabel := formatChartValue(high), formatChartValue(low)
+	axisWidth := max(len(highLabel), len(lowLabel))
+	chartWidth := max(width-axisWidth-2, minChartWidth)
+
+	// plot the average values of buckets one dot wide, joining them with straight lines
+	cells := make([][]rune, height)
+	for i := range cells {
+		cells[i] = make([]rune, chartWidth)
+	}
+	dotsHigh := height * 4
+	plot := func(x int, y int) {
+		row := dotsHigh - 1 - y
+		cells[row/4][x/2] |= brailleDots[row%4][x%2]
+	}
+	lastX, lastY := -1, 0
+	for x, value := range s.buckets(chartWidth * 2) {
+		if math.IsNaN(value) {
+			continue
+		}
+		y := scale(value, low, high, dotsHigh)
+		if lastX < 0 {
+			plot(x, y)
+		}
+		// each column between the last value and this one gets the dots from the line's height in
+		// the previous column to its height in this column
+		from := lastY
+		for px := lastX + 1; lastX >= 0 && px <= x; px++ {
+			to := lastY + int(math.Round(float64((y-lastY)*(px-lastX))/float64(x-lastX)))
+			plot(px, to)
+			for dy := min(from, to) + 1; dy < max(from, to); dy++ {
+				plot(px, dy)
+			}
+			from = to
+		}
+		lastX, lastY = x, y
+	}
+
+	var b strings.Builder
+	for i, line := range cells {
+		label, tick := "", '│'
+		switch i {
+		case 0:
+			label, tick = highLabel, '┤'
+		case height - 1:
+			label, tick = lowLabel, '┤'
+		}
+		for j := range line {
+			line[j] += 0x2800
+		}
+		fmt.Fprintf(&b, "%*s %c%s\n", axisWidth, label, tick, string(line))
+	}
+	fmt.Fprintf(&b, "%*s └%s\n", axisWidth, "", strings.Repeat("─", chartWidth))
+	start, end := formatChartTimes(s.points[0].time, s.points[len(s.points)-1].time)
+	fmt.Fprintf(&b, "%*s  %s%*s\n", axisWidth, "", start, max(chartWidth-len(start), len(end)+1), end)
+	return b.String()
+}
+
+// formatChartValue formats a value for the axis or statistics of a chart, with 2 decimals, or 3
+// significant digits for values below 1
+func formatChartValue(value float64) string {
+	if math.Abs(value) < 1 {
+		return strconv.FormatFloat(value, 'g', 3, 64)
+	}
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+}
+
+// formatChartTimes formats the start and end of a time range, with the date only if it spans days
+func formatChartTimes(start time.Time, end time.Time) (string, string) {
+	start, end = start.Local(), end.Local()
+	layout := "15:04"
+	if start.YearDay() != end.YearDay() || start.Year() != end.Year() {
+		layout = "Jan 2 15:04"
+	}
+	return start.Format(layout), end.Format(layout)
+}
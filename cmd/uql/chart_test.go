@@ -0,0 +1,99 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// language=json
+const chartResponse = `[
+  { "type": "model", "model": { "name": "m:main", "fields": [
+    { "alias": "id", "type": "string" },
+    { "alias": "metrics", "type": "complex", "form": "reference", "model": { "name": "m:metrics", "fields": [
+      { "alias": "source", "type": "string" },
+      { "alias": "metrics", "type": "timeseries", "form": "inline", "model": { "name": "m:metrics_2", "fields": [
+        { "alias": "timestamp", "type": "timestamp" },
+        { "alias": "value", "type": "number" }
+      ] } }
+    ] } }
+  ] } },
+  { "type": "data", "model": { "$jsonPath": "", "$model": "m:main" }, "dataset": "d:main", "data": [
+    [ "w1", { "$dataset": "d:metrics-1", "$jsonPath": "" } ],
+    [ "w2", { "$dataset": "d:metrics-2", "$jsonPath": "" } ]
+  ] },
+  { "type": "data", "model": { "$jsonPath": "", "$model": "m:metrics" }, "dataset": "d:metrics-1", "data": [
+    [ "infra", [ [ "2024-01-13T13:02:00Z", 3 ], [ "2024-01-13T13:00:00Z", 1 ], [ "2024-01-13T13:01:00Z", 2.5 ] ] ]
+  ] },
+  { "type": "data", "model": { "$jsonPath": "", "$model": "m:metrics" }, "dataset": "d:metrics-2", "data": [] }
+]`
+
+func TestFindTimeSeries(t *testing.T) {
+	// given
+	response, err := parsePage([]byte(chartResponse))
+	assert.NoError(t, err)
+
+	// when
+	series := findTimeSeries(response.Main(), response.Model(), nil)
+
+	// then
+	start := time.Date(2024, 1, 13, 13, 0, 0, 0, time.UTC)
+	assert.Equal(t, []timeSeries{{
+		label: "w1 / infra",
+		points: []timePoint{
+			{time: start, value: 1},
+			{time: start.Add(time.Minute), value: 2.5},
+			{time: start.Add(2 * time.Minute), value: 3},
+		},
+	}}, series)
+}
+
+func TestRenderSparklines(t *testing.T) {
+	// given
+	start := time.Date(2024, 1, 13, 13, 0, 0, 0, time.UTC)
+	series := []timeSeries{
+		{label: "up", points: []timePoint{{start, 0}, {start.Add(time.Minute), 7}, {start.Add(2 * time.Minute), 14}}},
+		{label: "flat", points: []timePoint{{start, 0.5}, {start.Add(time.Minute), 0.5}}},
+	}
+
+	// when
+	rendered := renderSparklines(series, 20)
+
+	// then
+	assert.Equal(t, "up    ▁▅█         min 0  max 14  last 14\n"+
+		"flat  ▅▅          min 0.5  max 0.5  last 0.5\n", rendered)
+}
+
+func TestRenderLineChart(t *testing.T) {
+	// given
+	start := time.Date(2024, 1, 13, 13, 0, 0, 0, time.Local)
+	series := timeSeries{label: "up", points: []timePoint{{start, 1}, {start.Add(30 * time.Minute), 100}}}
+
+	// when
+	rendered := renderLineChart(series, 20, 2)
+
+	// then
+	lines := strings.Split(strings.TrimSuffix(rendered, "\n"), "\n")
+	assert.Len(t, lines, 4)
+	assert.Equal(t, "100 ┤⠀⠀⠀⠀⠀⠀⠀⢀⣀⡠⠤⠔⠒⠊⠉", lines[0])
+	assert.Equal(t, "  1 ┤⣀⡠⠤⠔⠒⠊⠉⠁⠀⠀⠀⠀⠀⠀⠀", lines[1])
+	assert.Equal(t, "    └───────────────", lines[2])
+	assert.Equal(t, "     13:00     13:30", lines[3])
+	assert.Equal(t, 20, len([]rune(lines[0])))
+}
@@ -0,0 +1,239 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/ipc"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+	"github.com/apache/arrow/go/v15/parquet"
+	"github.com/apache/arrow/go/v15/parquet/compress"
+	"github.com/apache/arrow/go/v15/parquet/pqarrow"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/cmdkit/term"
+)
+
+// isColumnar returns whether an output format writes the results in a binary, columnar form
+func isColumnar(output format) bool {
+	return output == parquetFormat || output == arrowFormat
+}
+
+// checkColumnarOutput checks that the results can be written in a columnar output format: they
+// make a single binary file, which is not written to a terminal
+func checkColumnarOutput(cmd *cobra.Command, output format) error {
+	if !isColumnar(output) {
+		return nil
+	}
+	if followFlag {
+		return fmt.Errorf("--follow cannot be used with the %v output format", outputFlag)
+	}
+	if term.IsTerminal(cmd.OutOrStdout()) {
+		return fmt.Errorf("the %v output format is binary and cannot be written to a terminal; redirect the output to a file", outputFlag)
+	}
+	return nil
+}
+
+// writeColumnar writes the main data set of the response as a Parquet file or an Arrow IPC file,
+// with a column for each column of the data set, typed after its UQL type: long and double
+// columns are 64-bit integers and floats, number columns are integers unless one of their
+// values is not, timestamps are UTC timestamps in nanoseconds and nested data sets are lists of
+// structs. Values of other types, e.g., json, are written as strings, like in CSV output.
+func writeColumnar(w io.Writer, response *Response, output format) error {
+	record, err := makeRecord(response)
+	if err != nil {
+		return err
+	}
+	defer record.Release()
+
+	switch output {
+	case parquetFormat:
+		props := parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Snappy))
+		writer, err := pqarrow.NewFileWriter(record.Schema(), w, props, pqarrow.NewArrowWriterProperties(pqarrow.WithStoreSchema()))
+		if err != nil {
+			return err
+		}
+		if err := writer.Write(record); err != nil {
+			_ = writer.Close()
+			return err
+		}
+		return writer.Close()
+	case arrowFormat:
+		writer, err := ipc.NewFileWriter(&positionWriter{w: w}, ipc.WithSchema(record.Schema()))
+		if err != nil {
+			return err
+		}
+		if err := writer.Write(record); err != nil {
+			_ = writer.Close()
+			return err
+		}
+		return writer.Close()
+	}
+	return fmt.Errorf("unsupported columnar output format %v", outputFlag)
+}
+
+// makeRecord returns the main data set of the response as an Arrow record
+func makeRecord(response *Response) (arrow.Record, error) {
+	model := response.Model()
+	if model == nil {
+		return nil, fmt.Errorf("the response has no data model")
+	}
+	var rows [][]any
+	if main := response.Main(); main != nil {
+		rows = main.Values()
+	}
+
+	schema := arrow.NewSchema(arrowFields(model, rows), nil)
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	for _, row := range rows {
+		for c, field := range model.Fields {
+			if err := appendArrowValue(builder.Field(c), field, row[c]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return builder.NewRecord(), nil
+}
+
+// arrowFields returns the Arrow fields of the columns of a model, given the rows of its data
+func arrowFields(model *Model, rows [][]any) []arrow.Field {
+	fields := make([]arrow.Field, len(model.Fields))
+	for c, field := range model.Fields {
+		values := make([]any, len(rows))
+		for r, row := range rows {
+			values[r] = row[c]
+		}
+		fields[c] = arrow.Field{Name: field.Alias, Type: arrowType(field, values), Nullable: true}
+	}
+	return fields
+}
+
+// arrowType returns the Arrow type of a column, given its values
+func arrowType(field ModelField, values []any) arrow.DataType {
+	if field.Model != nil {
+		var rows [][]any
+		for _, value := range values {
+			if nested, ok := value.(Complex); ok && !complexIsNil(nested) {
+				rows = append(rows, nested.Values()...)
+			}
+		}
+		return arrow.ListOf(arrow.StructOf(arrowFields(field.Model, rows)...))
+	}
+	switch field.Type {
+	case "long":
+		return arrow.PrimitiveTypes.Int64
+	case "double":
+		return arrow.PrimitiveTypes.Float64
+	case "number":
+		for _, value := range values {
+			if _, ok := value.(float64); ok {
+				return arrow.PrimitiveTypes.Float64
+			}
+		}
+		return arrow.PrimitiveTypes.Int64
+	case "boolean":
+		return arrow.FixedWidthTypes.Boolean
+	case "timestamp":
+		return &arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"}
+	}
+	return arrow.BinaryTypes.String
+}
+
+// appendArrowValue appends the value of a column to the builder of its Arrow type
+func appendArrowValue(builder array.Builder, field ModelField, value any) error {
+	if raw, isJson := value.(jsonObject); value == nil || isJson && string(raw) == "null" {
+		builder.AppendNull()
+		return nil
+	}
+	ok := true
+	switch b := builder.(type) {
+	case *array.Int64Builder:
+		var v int
+		if v, ok = value.(int); ok {
+			b.Append(int64(v))
+		}
+	case *array.Float64Builder:
+		switch v := value.(type) {
+		case float64:
+			b.Append(v)
+		case int:
+			b.Append(float64(v))
+		default:
+			ok = false
+		}
+	case *array.BooleanBuilder:
+		var v bool
+		if v, ok = value.(bool); ok {
+			b.Append(v)
+		}
+	case *array.TimestampBuilder:
+		var v time.Time
+		if v, ok = value.(time.Time); ok {
+			b.Append(arrow.Timestamp(v.UnixNano()))
+		}
+	case *array.StringBuilder:
+		b.Append(formatCell(value))
+	case *array.ListBuilder:
+		var nested Complex
+		if nested, ok = value.(Complex); ok {
+			if complexIsNil(nested) {
+				b.AppendNull()
+				return nil
+			}
+			b.Append(true)
+			structBuilder := b.ValueBuilder().(*array.StructBuilder)
+			for _, row := range nested.Values() {
+				structBuilder.Append(true)
+				for c, nestedField := range field.Model.Fields {
+					if err := appendArrowValue(structBuilder.FieldBuilder(c), nestedField, row[c]); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	default:
+		ok = false
+	}
+	if !ok {
+		return fmt.Errorf("unexpected value %v for column %q of type %v", value, field.Alias, field.Type)
+	}
+	return nil
+}
+
+// positionWriter keeps track of the position in the output of the Arrow IPC file writer, which
+// needs it to locate the record batches in the footer, so that the file can be written to a pipe
+type positionWriter struct {
+	w   io.Writer
+	pos int64
+}
+
+func (p *positionWriter) Write(data []byte) (int, error) {
+	n, err := p.w.Write(data)
+	p.pos += int64(n)
+	return n, err
+}
+
+func (p *positionWriter) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekCurrent {
+		return 0, fmt.Errorf("the output does not support seeking")
+	}
+	return p.pos, nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/ipc"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+	"github.com/apache/arrow/go/v15/parquet/pqarrow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// language=json
+const typedServerResponse = `[
+  {
+	"type": "model",
+	"model": {
+	  "name": "m:main",
+	  "fields": [
+		{ "alias": "id", "type": "string" },
+		{ "alias": "count", "type": "long" },
+		{ "alias": "ratio", "type": "double" },
+		{ "alias": "total", "type": "number" },
+		{ "alias": "mean", "type": "number" },
+		{ "alias": "healthy", "type": "boolean" },
+		{ "alias": "seen", "type": "timestamp" },
+		{ "alias": "attributes", "type": "json" },
+		{ "alias": "events", "type": "timeseries", "form": "reference", "model": {
+			"name": "m:events-1",
+			"fields": [
+			  { "alias": "timestamp", "type": "timestamp" },
+			  { "alias": "size", "type": "long" }
+			]
+		  }
+		}
+	  ]
+	}
+  },
+  {
+	"type": "data",
+	"model": { "$jsonPath": "", "$model": "m:main" },
+	"dataset": "d:main",
+	"data": [
+	  [ "a", 3, 0.5, 7, 1.5, true, "2022-12-05T07:30:56Z", { "k": "v" }, { "$dataset": "d:events-1", "$jsonPath": "" } ],
+	  [ "b", 4, 2.0, 8, 2, false, "2022-12-05T07:31:00Z", null, { "$dataset": "d:events-2", "$jsonPath": "" } ]
+	]
+  },
+  {
+	"type": "data",
+	"model": { "$jsonPath": "", "$model": "m:events-1" },
+	"dataset": "d:events-1",
+	"data": [
+	  [ "2022-12-05T07:30:56Z", 10 ],
+	  [ "2022-12-05T07:30:57Z", 20 ]
+	]
+  },
+  {
+	"type": "data",
+	"model": { "$jsonPath": "", "$model": "m:events-1" },
+	"dataset": "d:events-2",
+	"data": []
+  }
+]`
+
+func TestMakeRecord(t *testing.T) {
+	response, err := executeUqlQuery(&Query{"ignored"}, ApiVersion1, mockExecuteResponse(typedServerResponse))
+	require.NoError(t, err)
+
+	record, err := makeRecord(response)
+	require.NoError(t, err)
+	defer record.Release()
+
+	timestampType := &arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"}
+	expectedSchema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "count", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "ratio", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: "total", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "mean", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: "healthy", Type: arrow.FixedWidthTypes.Boolean, Nullable: true},
+		{Name: "seen", Type: timestampType, Nullable: true},
+		{Name: "attributes", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "events", Type: arrow.ListOf(arrow.StructOf(
+			arrow.Field{Name: "timestamp", Type: timestampType, Nullable: true},
+			arrow.Field{Name: "size", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		)), Nullable: true},
+	}, nil)
+	assert.True(t, expectedSchema.Equal(record.Schema()), "unexpected schema %v", record.Schema())
+	assert.Equal(t, int64(2), record.NumRows())
+
+	assert.Equal(t, []int64{3, 4}, record.Column(1).(*array.Int64).Int64Values())
+	assert.Equal(t, 2.0, record.Column(4).(*array.Float64).Value(1))
+	assert.Equal(t, time.Date(2022, 12, 5, 7, 31, 0, 0, time.UTC), record.Column(6).(*array.Timestamp).Value(1).ToTime(arrow.Nanosecond))
+	assert.Equal(t, `{ "k": "v" }`, record.Column(7).(*array.String).Value(0))
+	assert.True(t, record.Column(7).IsNull(1))
+
+	events := record.Column(8).(*array.List)
+	start, end := events.ValueOffsets(0)
+	assert.Equal(t, int64(2), end-start)
+	start, end = events.ValueOffsets(1)
+	assert.Equal(t, int64(0), end-start)
+	sizes := events.ListValues().(*array.Struct).Field(1).(*array.Int64)
+	assert.Equal(t, []int64{10, 20}, sizes.Int64Values())
+}
+
+func TestWriteColumnar(t *testing.T) {
+	response, err := executeUqlQuery(&Query{"ignored"}, ApiVersion1, mockExecuteResponse(typedServerResponse))
+	require.NoError(t, err)
+
+	t.Run("arrow", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, writeColumnar(&buf, response, arrowFormat))
+
+		reader, err := ipc.NewFileReader(bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		defer reader.Close()
+		assert.Equal(t, 1, reader.NumRecords())
+		record, err := reader.Record(0)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), record.NumRows())
+		assert.Equal(t, "b", record.Column(0).(*array.String).Value(1))
+	})
+
+	t.Run("parquet", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, writeColumnar(&buf, response, parquetFormat))
+
+		table, err := pqarrow.ReadTable(context.Background(), bytes.NewReader(buf.Bytes()), nil, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+		require.NoError(t, err)
+		defer table.Release()
+		assert.Equal(t, int64(2), table.NumRows())
+		assert.Equal(t, arrow.PrimitiveTypes.Int64, table.Schema().Field(1).Type)
+		assert.Equal(t, arrow.PrimitiveTypes.Float64, table.Schema().Field(4).Type)
+		assert.Equal(t, arrow.LIST, table.Schema().Field(8).Type.ID())
+	})
+}
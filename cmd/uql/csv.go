@@ -0,0 +1,177 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// nestedMode defines how the nested data sets of a row are written in CSV and TSV output
+type nestedMode string
+
+const (
+	// nestedJson writes each nested data set as a JSON array of objects in a single cell
+	nestedJson nestedMode = "json"
+	// nestedExpand writes a row for each row of the nested data sets, repeating the values of
+	// the parent row, with a column for each column of the nested data sets
+	nestedExpand nestedMode = "expand"
+)
+
+func parseNestedMode(mode string) (nestedMode, error) {
+	switch nestedMode(strings.ToLower(mode)) {
+	case nestedJson:
+		return nestedJson, nil
+	case nestedExpand:
+		return nestedExpand, nil
+	}
+	return "", fmt.Errorf("unsupported mode %q for nested data; supported modes are: %v, %v", mode, nestedJson, nestedExpand)
+}
+
+// writeDelimited writes the main data set of the response as rows of delimiter-separated
+// values, with a header row of column names
+func writeDelimited(w io.Writer, response *Response, delimiter rune, mode nestedMode) error {
+	header, rows, err := makeDelimitedRows(response, mode)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Comma = delimiter
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(rows); err != nil { // WriteAll flushes
+		return err
+	}
+	return writer.Error()
+}
+
+// makeDelimitedRows returns the header and the rows of the response's main data set, with the
+// nested data sets encoded or expanded depending on the mode
+func makeDelimitedRows(response *Response, mode nestedMode) ([]string, [][]string, error) {
+	model := response.Model()
+	if model == nil {
+		return nil, nil, fmt.Errorf("the response has no data model")
+	}
+	var main Complex = response.Main()
+	if mode == nestedExpand {
+		return expandedColumns(model, ""), expandRows(main, model), nil
+	}
+
+	header := make([]string, len(model.Fields))
+	mappers := make([]fieldMapper, len(model.Fields))
+	for i, field := range model.Fields {
+		header[i] = field.Alias
+		if field.Model != nil {
+			mappers[i] = makeFieldMapper(field)
+		}
+	}
+	var rows [][]string
+	if complexIsNil(main) {
+		return header, rows, nil
+	}
+	for _, row := range main.Values() {
+		cells := make([]string, len(model.Fields))
+		for c, field := range model.Fields {
+			if field.Model == nil || row[c] == nil {
+				cells[c] = formatCell(row[c])
+				continue
+			}
+			encoded, err := json.Marshal(mappers[c].valueExtractor(row[c]))
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to encode the value of column %q: %w", field.Alias, err)
+			}
+			cells[c] = string(encoded)
+		}
+		rows = append(rows, cells)
+	}
+	return header, rows, nil
+}
+
+// expandedColumns returns the names of the scalar columns of a model and of its nested models,
+// prefixed with the path of the nested models, e.g., events.timestamp
+func expandedColumns(model *Model, prefix string) []string {
+	var columns []string
+	for _, field := range model.Fields {
+		if field.Model != nil {
+			columns = append(columns, expandedColumns(field.Model, prefix+field.Alias+".")...)
+		} else {
+			columns = append(columns, prefix+field.Alias)
+		}
+	}
+	return columns
+}
+
+// expandRows returns the rows of a data set with its nested data sets expanded: each row is
+// combined with each row of its nested data sets. A row whose nested data set is empty is kept,
+// with empty values for the nested columns.
+func expandRows(data Complex, model *Model) [][]string {
+	var rows [][]string
+	if complexIsEmpty(data) {
+		return rows
+	}
+	for _, row := range data.Values() {
+		expanded := [][]string{{}}
+		for c, field := range model.Fields {
+			var values [][]string
+			if field.Model == nil {
+				values = [][]string{{formatCell(row[c])}}
+			} else {
+				nested, _ := row[c].(Complex)
+				if nested != nil {
+					values = expandRows(nested, field.Model)
+				}
+				if len(values) == 0 {
+					values = [][]string{make([]string, len(expandedColumns(field.Model, "")))}
+				}
+			}
+			var combined [][]string
+			for _, prefix := range expanded {
+				for _, suffix := range values {
+					combined = append(combined, append(append([]string{}, prefix...), suffix...))
+				}
+			}
+			expanded = combined
+		}
+		rows = append(rows, expanded...)
+	}
+	return rows
+}
+
+// formatCell formats a scalar value for CSV or TSV output
+func formatCell(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case jsonObject:
+		return v.String()
+	case map[string]any, []any:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(encoded)
+	}
+	return fmt.Sprint(value)
+}
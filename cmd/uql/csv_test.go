@@ -0,0 +1,114 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// language=json
+const nestedServerResponse = `[
+  {
+	"type": "model",
+	"model": {
+	  "name": "m:main",
+	  "fields": [
+		{ "alias": "id", "type": "string", "hints": {} },
+		{ "alias": "events", "type": "timeseries", "form": "reference", "model": {
+			"name": "m:events-1",
+			"fields": [
+			  { "alias": "timestamp", "type": "timestamp" },
+			  { "alias": "raw", "type": "string" }
+			]
+		  }
+		}
+	  ]
+	}
+  },
+  {
+	"type": "data",
+	"model": { "$jsonPath": "", "$model": "m:main" },
+	"dataset": "d:main",
+	"data": [
+	  [ "a", { "$dataset": "d:events-1", "$jsonPath": "" } ],
+	  [ "b", { "$dataset": "d:events-2", "$jsonPath": "" } ]
+	]
+  },
+  {
+	"type": "data",
+	"model": { "$jsonPath": "", "$model": "m:events-1" },
+	"dataset": "d:events-1",
+	"data": [
+	  [ "2022-12-05T07:30:56Z", "first, with a comma" ],
+	  [ "2022-12-05T07:30:57Z", "second" ]
+	]
+  },
+  {
+	"type": "data",
+	"model": { "$jsonPath": "", "$model": "m:events-1" },
+	"dataset": "d:events-2",
+	"data": []
+  }
+]`
+
+func TestWriteDelimited(t *testing.T) {
+	response, err := executeUqlQuery(&Query{"ignored"}, ApiVersion1, mockExecuteResponse(nestedServerResponse))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		delimiter rune
+		mode      nestedMode
+		expected  string
+	}{
+		{
+			name:      "csv with nested json",
+			delimiter: ',',
+			mode:      nestedJson,
+			expected: "id,events\n" +
+				`a,"[{""timestamp"":""2022-12-05T07:30:56Z"",""raw"":""first, with a comma""},{""timestamp"":""2022-12-05T07:30:57Z"",""raw"":""second""}]"` + "\n" +
+				"b,[]\n",
+		},
+		{
+			name:      "csv with nested rows expanded",
+			delimiter: ',',
+			mode:      nestedExpand,
+			expected: "id,events.timestamp,events.raw\n" +
+				"a,2022-12-05T07:30:56Z,\"first, with a comma\"\n" +
+				"a,2022-12-05T07:30:57Z,second\n" +
+				"b,,\n",
+		},
+		{
+			name:      "tsv with nested rows expanded",
+			delimiter: '\t',
+			mode:      nestedExpand,
+			expected: "id\tevents.timestamp\tevents.raw\n" +
+				"a\t2022-12-05T07:30:56Z\tfirst, with a comma\n" +
+				"a\t2022-12-05T07:30:57Z\tsecond\n" +
+				"b\t\t\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := writeDelimited(&buf, response, tt.delimiter, tt.mode)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
@@ -63,10 +63,10 @@ func executeUqlQuery(query *Query, apiVersion ApiVersion, backend uqlService) (*
 	}
 
 	if apiVersion == "" {
-		apiVersion = ApiVersionDefault
-		if GlobalConfig.ApiVersion != nil && *GlobalConfig.ApiVersion != "" {
-			apiVersion = *GlobalConfig.ApiVersion // from fsoc config file
-		}
+		apiVersion = configuredApiVersion()
+	}
+	if apiVersion == ApiVersionAuto {
+		return executeWithDetectedVersion(query, backend)
 	}
 
 	response, err := backend.Execute(query, apiVersion)
@@ -113,16 +113,27 @@ func processResponse(response parsedResponse) (*Response, error) {
 	var model *Model
 	var dataSets = make(map[string]*DataSet)
 	var errorSets []*Error
-	var modelIndex map[string]*Model
+	var modelIndex = make(map[string]*Model)
+	var dataSetNames []string // in the order of the response
+	var dataSetErrors = make(map[string][]*Error)
 
 	for _, dataset := range response.chunks {
 		switch dataset.Type {
 		case "model":
-			err := json.Unmarshal(dataset.Model, &model)
+			// a response with data sets of their own, e.g., related entities, has a model for each;
+			// the model of the main data set is the first one
+			var chunkModel *Model
+			err := json.Unmarshal(dataset.Model, &chunkModel)
 			if err != nil {
 				return nil, err
 			}
-			modelIndex = createModelIndex(model)
+			if chunkModel == nil {
+				continue
+			}
+			if model == nil {
+				model = chunkModel
+			}
+			appendModelToIndex(chunkModel, modelIndex)
 		case "data":
 			var modelRef modelRef
 			err := json.Unmarshal(dataset.Model, &modelRef)
@@ -141,20 +152,55 @@ func processResponse(response parsedResponse) (*Response, error) {
 				Data:      values,
 				Links:     parseLinks(dataset.Links),
 			}
+			dataSetNames = append(dataSetNames, dataset.Dataset)
 		case "error":
 			errorSets = append(errorSets, dataset.Error)
+			if dataset.Dataset != "" {
+				dataSetErrors[dataset.Dataset] = append(dataSetErrors[dataset.Dataset], dataset.Error)
+			}
+		}
+	}
+	for name, errs := range dataSetErrors {
+		if dataSet, found := dataSets[name]; found {
+			dataSet.Errors = errs
+		}
+	}
+
+	// the data sets that are neither the main data set nor nested in another one, e.g., related
+	// entities, are kept as data sets of their own
+	referenced := referencedDataSets(dataSets)
+	var others []*DataSet
+	for _, name := range dataSetNames {
+		if name != mainDataSetName && !referenced[name] {
+			others = append(others, resolveRefs(dataSets[name], dataSets))
 		}
 	}
 
 	resp := &Response{
 		model:       model,
-		mainDataSet: resolveRefs(dataSets["d:main"], dataSets),
+		mainDataSet: resolveRefs(dataSets[mainDataSetName], dataSets),
+		dataSets:    others,
 		errors:      errorSets,
 		raw:         response.rawJson,
 	}
 	return resp, nil
 }
 
+// referencedDataSets returns the names of the data sets referenced by the values of data sets
+func referencedDataSets(dataSets map[string]*DataSet) map[string]bool {
+	referenced := make(map[string]bool)
+	for _, dataSet := range dataSets {
+		for _, row := range dataSet.Data {
+			for _, value := range row {
+				if ref, ok := value.(DataSetRef); ok {
+					referenced[ref.Dataset] = true
+				}
+			}
+		}
+	}
+	return referenced
+}
+
 func processValues(values [][]json.RawMessage, model *Model) ([][]any, error) {
 	var processedData [][]any
 	for rowIndex := range values {
@@ -257,12 +303,6 @@ func parseLinks(rawLinks map[string]rawLink) map[string]Link {
 	return links
 }
 
-func createModelIndex(model *Model) map[string]*Model {
-	var index = make(map[string]*Model)
-	appendModelToIndex(model, index)
-	return index
-}
-
 func appendModelToIndex(model *Model, index map[string]*Model) {
 	index[model.Name] = model
 	for _, field := range model.Fields {
@@ -161,6 +161,62 @@ func TestExecuteUqlQuery_Errors(t *testing.T) {
 	check.EqualValues(&Error{Type: "internal-server-error", Title: "downstream failure", Detail: "service not available"}, response.Errors()[0], "errors do not match")
 }
 
+func TestExecuteUqlQuery_OtherDataSets(t *testing.T) {
+	// given: events with their related entities in a data set of its own
+	// language=json
+	serverResponse := `[
+	  { "type": "model", "model": { "name": "m:main", "fields": [
+		{ "alias": "timestamp", "type": "timestamp" },
+		{ "alias": "entityId", "type": "string" }
+	  ] } },
+	  { "type": "model", "model": { "name": "m:entities", "fields": [
+		{ "alias": "id", "type": "string" },
+		{ "alias": "tags", "type": "complex", "form": "reference", "model": { "name": "m:tags", "fields": [
+		  { "alias": "tag", "type": "string" }
+		] } }
+	  ] } },
+	  { "type": "data", "model": { "$jsonPath": "", "$model": "m:main" }, "dataset": "d:main", "data": [
+		[ "2024-01-13T13:00:00Z", "k8s:workload:a" ]
+	  ] },
+	  { "type": "data", "model": { "$jsonPath": "", "$model": "m:entities" }, "dataset": "d:entities", "data": [
+		[ "k8s:workload:a", { "$dataset": "d:tags-1", "$jsonPath": "" } ]
+	  ] },
+	  { "type": "data", "model": { "$jsonPath": "", "$model": "m:tags" }, "dataset": "d:tags-1", "data": [ [ "prod" ] ] },
+	  { "type": "error", "dataset": "d:entities", "error": { "type": "timeout", "title": "Partial data", "detail": "some entities are missing" } }
+	]`
+
+	// when
+	response, err := executeUqlQuery(&Query{"ignored"}, ApiVersion1, mockExecuteResponse(serverResponse))
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, "m:main", response.Model().Name)
+	assert.Equal(t, [][]any{{time.Date(2024, 1, 13, 13, 0, 0, 0, time.UTC), "k8s:workload:a"}}, response.Main().Values())
+
+	// the data set nested in the related entities is not a data set of its own
+	assert.Len(t, response.DataSets(), 1)
+	entities := response.DataSets()[0]
+	assert.Equal(t, "d:entities", entities.Name)
+	assert.Equal(t, "m:entities", entities.Model().Name)
+	assert.Equal(t, "k8s:workload:a", entities.Values()[0][0])
+	tags := entities.Values()[0][1].(Complex)
+	assert.Equal(t, [][]any{{"prod"}}, tags.Values())
+	assert.Equal(t, []*Error{{Type: "timeout", Title: "Partial data", Detail: "some entities are missing"}}, entities.Errors)
+	assert.Len(t, response.Errors(), 1)
+
+	result, err := transformForJsonOutput(response)
+	assert.NoError(t, err)
+	dataSets, err := json.Marshal(result.DataSets)
+	assert.NoError(t, err)
+	// language=json
+	assert.JSONEq(t, `[ {
+	  "name": "d:entities",
+	  "model": { "id": "string", "tags": { "tag": "string" } },
+	  "data": [ { "id": "k8s:workload:a", "tags": [ { "tag": "prod" } ] } ],
+	  "errors": [ { "type": "timeout", "title": "Partial data", "detail": "some entities are missing" } ]
+	} ]`, string(dataSets))
+}
+
 func TestExecuteUqlQuery_DataTypes(t *testing.T) {
 	// given
 	serverResponseTemplate := template.Must(template.New("response-template").Parse(`[
@@ -0,0 +1,184 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/itchyny/gojq"
+)
+
+// filterValueAlias is the name of the column of filter results that are not objects
+const filterValueAlias = "value"
+
+// filterResponse evaluates a jq expression against the JSON form of a response, as displayed with
+// --output json, and returns a response with its results, so that they are displayed in any format.
+// The results are the rows of the returned response; if there is a single result that is an array,
+// its elements are the rows. Rows that are all objects have a column for each key, other rows have
+// a single value column.
+func filterResponse(response *Response, expression string) (*Response, error) {
+	code, err := compileFilter(expression)
+	if err != nil {
+		return nil, err
+	}
+	input, err := filterInput(response)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []any
+	iter := code.Run(input)
+	for {
+		result, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, isErr := result.(error); isErr {
+			return nil, fmt.Errorf("failed to evaluate the filter %q: %w", expression, err)
+		}
+		results = append(results, result)
+	}
+	if len(results) == 1 {
+		if array, ok := results[0].([]any); ok {
+			results = array
+		}
+	}
+
+	fields, objects := filterFields(results)
+	model := &Model{Name: "m:filter", Fields: fields}
+	rows := make([][]any, len(results))
+	for i, result := range results {
+		if !objects {
+			rows[i] = []any{filterCell(result)}
+			continue
+		}
+		row := make([]any, len(fields))
+		for c, field := range fields {
+			row[c] = filterCell(result.(map[string]any)[field.Alias])
+		}
+		rows[i] = row
+	}
+	return &Response{
+		model:       model,
+		mainDataSet: &DataSet{Name: "d:filter", DataModel: model, Data: rows},
+		errors:      response.Errors(),
+	}, nil
+}
+
+func compileFilter(expression string) (*gojq.Code, error) {
+	query, err := gojq.Parse(expression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the filter %q: %w", expression, err)
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile the filter %q: %w", expression, err)
+	}
+	return code, nil
+}
+
+// filterInput returns the JSON form of a response as plain maps and slices, as required by gojq
+func filterInput(response *Response) (any, error) {
+	result, err := transformForJsonOutput(response)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	var input any
+	if err := json.Unmarshal(data, &input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+// filterFields returns the columns of the filter results: the keys of the results, sorted, if all
+// the results are objects, otherwise a single value column; it returns whether the results are objects
+func filterFields(results []any) ([]ModelField, bool) {
+	keys := map[string]bool{}
+	for _, result := range results {
+		object, ok := result.(map[string]any)
+		if !ok {
+			return []ModelField{{Alias: filterValueAlias, Type: filterType(results, "")}}, false
+		}
+		for key := range object {
+			keys[key] = true
+		}
+	}
+	if len(keys) == 0 {
+		return []ModelField{{Alias: filterValueAlias, Type: filterType(results, "")}}, false
+	}
+	aliases := make([]string, 0, len(keys))
+	for key := range keys {
+		aliases = append(aliases, key)
+	}
+	sort.Strings(aliases)
+	fields := make([]ModelField, len(aliases))
+	for i, alias := range aliases {
+		fields[i] = ModelField{Alias: alias, Type: filterType(results, alias)}
+	}
+	return fields, true
+}
+
+// filterType returns the UQL type of the values of a column of the filter results (of the
+// results themselves if the key is empty); it is string if the values have different types
+func filterType(results []any, key string) string {
+	typ := ""
+	for _, result := range results {
+		value := result
+		if key != "" {
+			value = result.(map[string]any)[key]
+		}
+		var valueType string
+		switch value.(type) {
+		case nil:
+			continue
+		case bool:
+			valueType = "boolean"
+		case int, float64, *big.Int:
+			valueType = "number"
+		case string:
+			valueType = "string"
+		default:
+			valueType = "json"
+		}
+		if typ != "" && typ != valueType {
+			return "string"
+		}
+		typ = valueType
+	}
+	if typ == "" {
+		return "string"
+	}
+	return typ
+}
+
+// filterCell returns a value of the filter results as a cell value, with objects and arrays as JSON
+func filterCell(value any) any {
+	switch value.(type) {
+	case map[string]any, []any:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return jsonObject(data)
+	}
+	return value
+}
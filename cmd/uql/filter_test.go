@@ -0,0 +1,88 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// language=json
+const filterResponseJson = `[
+  { "type": "model", "model": { "name": "m:main", "fields": [
+    { "alias": "id", "type": "string" },
+    { "alias": "count", "type": "number" },
+    { "alias": "attributes", "type": "json" }
+  ] } },
+  { "type": "data", "model": { "$jsonPath": "", "$model": "m:main" }, "dataset": "d:main", "data": [
+    [ "w1", 7, { "kind": "Deployment" } ], [ "w2", 5, { "kind": "StatefulSet" } ], [ "w3", 8, { "kind": "Deployment" } ]
+  ] }
+]`
+
+func TestFilterResponse_Objects(t *testing.T) {
+	// given
+	response, err := parsePage([]byte(filterResponseJson))
+	assert.NoError(t, err)
+
+	// when
+	filtered, err := filterResponse(response, `.data[] | select(.attributes.kind == "Deployment") | {name: .id, count}`)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"count", "name"}, fieldAliases(filtered.Model().Fields))
+	assert.Equal(t, "number", filtered.Model().Fields[0].Type)
+	assert.Equal(t, [][]any{{float64(7), "w1"}, {float64(8), "w3"}}, filtered.Main().Values())
+}
+
+func TestFilterResponse_Array(t *testing.T) {
+	// given
+	response, err := parsePage([]byte(filterResponseJson))
+	assert.NoError(t, err)
+
+	// when
+	filtered, err := filterResponse(response, `[.data[].attributes]`)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"kind"}, fieldAliases(filtered.Model().Fields))
+	assert.Equal(t, [][]any{{"Deployment"}, {"StatefulSet"}, {"Deployment"}}, filtered.Main().Values())
+}
+
+func TestFilterResponse_Values(t *testing.T) {
+	// given
+	response, err := parsePage([]byte(filterResponseJson))
+	assert.NoError(t, err)
+
+	// when
+	filtered, err := filterResponse(response, `(.data | map(.count) | add), [.data[].id]`)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []ModelField{{Alias: "value", Type: "string"}}, filtered.Model().Fields)
+	assert.Equal(t, [][]any{{float64(20)}, {jsonObject(`["w1","w2","w3"]`)}}, filtered.Main().Values())
+}
+
+func TestFilterResponse_InvalidExpression(t *testing.T) {
+	// given
+	response, err := parsePage([]byte(filterResponseJson))
+	assert.NoError(t, err)
+
+	// when
+	_, err = filterResponse(response, `.data[`)
+
+	// then
+	assert.ErrorContains(t, err, "failed to parse the filter")
+}
@@ -0,0 +1,139 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+)
+
+// followRel is the relation of the link to the rows added to a data set since it was returned
+const followRel = "follow"
+
+// followQuery polls for the rows added to the query's results until interrupted, printing only
+// the rows that were not in the previous results. If the main data set has a follow link, the
+// new rows are fetched with it; otherwise, the query is re-executed and its rows are compared
+// with those of the previous execution.
+func followQuery(cmd *cobra.Command, query string, response *Response, output format, interval time.Duration) error {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	seen := rowKeys(response.Main())
+	for {
+		select {
+		case <-interrupt:
+			return nil
+		case <-ticker.C:
+		}
+
+		next, err := pollQuery(query, response)
+		if err != nil {
+			log.Errorf("Failed to fetch new results, will retry in %v: %v", interval, err)
+			continue
+		}
+		for _, e := range next.Errors() {
+			log.Errorf("%s: %s", e.Title, e.Detail)
+		}
+		response = next
+
+		newResponse, keys := newRows(next, seen)
+		seen = keys
+		if newResponse == nil {
+			continue
+		}
+		if err := printResponse(cmd, newResponse, output); err != nil {
+			return err
+		}
+	}
+}
+
+// pollQuery fetches the latest results of the query, using the previous response's follow link
+// if it has one
+func pollQuery(query string, previous *Response) (*Response, error) {
+	var response *Response
+	var err error
+	if main := previous.Main(); main != nil && extractLink(main, followRel) != nil {
+		response, err = Client.ContinueQuery(main, followRel)
+	} else {
+		response, err = runQuery(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	response, err = fetchAllPages(Client, response, maxRowsFlag)
+	if err == nil && response.Partial() != nil {
+		// new rows would be found again once the rows missing from partial results are fetched
+		return nil, errors.New(response.Partial().Detail)
+	}
+	return response, err
+}
+
+// newRows returns a response with only the rows of the main data set that are not in the
+// previously seen rows, or nil if there are none, along with the keys of all rows of the response
+func newRows(response *Response, seen map[string]bool) (*Response, map[string]bool) {
+	main := response.Main()
+	keys := rowKeys(main)
+	if main == nil {
+		return nil, keys
+	}
+
+	var rows [][]any
+	for _, row := range main.Data {
+		if !seen[rowKey(row)] {
+			rows = append(rows, row)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, keys
+	}
+
+	dataSet := *main
+	dataSet.Data = rows
+	filtered := *response
+	filtered.mainDataSet = &dataSet
+	return &filtered, keys
+}
+
+// rowKeys returns the keys of the rows of a data set
+func rowKeys(dataSet *DataSet) map[string]bool {
+	keys := map[string]bool{}
+	if dataSet == nil {
+		return keys
+	}
+	for _, row := range dataSet.Data {
+		keys[rowKey(row)] = true
+	}
+	return keys
+}
+
+// rowKey identifies a row by its values, including those of its nested data sets
+func rowKey(row []any) string {
+	data, err := json.Marshal(row)
+	if err != nil {
+		log.Warnf("Failed to compare a row with previous results: %v", err)
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,41 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRows(t *testing.T) {
+	// given
+	previous, err := executeUqlQuery(&Query{"ignored"}, ApiVersion1, mockExecuteResponse(pageResponse("", "a", "b")))
+	assert.NoError(t, err)
+	latest, err := executeUqlQuery(&Query{"ignored"}, ApiVersion1, mockExecuteResponse(pageResponse("", "b", "c", "d")))
+	assert.NoError(t, err)
+
+	// when
+	filtered, keys := newRows(latest, rowKeys(previous.Main()))
+
+	// then
+	assert.Equal(t, [][]any{{"c"}, {"d"}}, filtered.Main().Values())
+	assert.Equal(t, [][]any{{"b"}, {"c"}, {"d"}}, latest.Main().Values(), "the response should not be modified")
+	assert.Len(t, keys, 3)
+
+	// no new rows
+	filtered, _ = newRows(latest, keys)
+	assert.Nil(t, filtered)
+}
@@ -0,0 +1,266 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+)
+
+var joinCmd = &cobra.Command{
+	Use:   "join --on <column>[,<column>...] <query> <query> [<query>...]",
+	Short: "Join the results of multiple queries on key columns",
+	Long: `Execute multiple queries in parallel and join their results on key columns, e.g., id, into a single table.
+This combines data that a single query cannot, e.g., the attributes of entities with their metrics fetched
+by another query.
+
+The key columns must be top-level columns of all the queries. Each row of the first query is combined with
+each row of the other queries with the same key values; with --left, rows of the first query without a match
+are kept, with empty values for the columns of the other query. The other columns of the queries follow the
+columns of the first query; a column with the same name as a previous column is renamed <column>_<n>, where
+n is the number of the query.
+
+The uql flags, e.g., --since, --param, --max-rows, --columns and --output, apply to all the queries and to
+the joined results.`,
+	Example: `  fsoc uql join --on id "FETCH id, attributes(\"k8s.workload.name\") FROM entities(k8s:workload)" "FETCH id, metrics(infra:cpu.used) FROM entities(k8s:workload)"
+  fsoc uql join --on id --left --since 1h -o csv "FETCH id, attributes FROM entities(apm:service)" "FETCH id, events(k8s:event) FROM entities(apm:service)"`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: joinQueries,
+}
+
+func init() {
+	joinCmd.Flags().StringSlice("on", nil, "Key columns to join the results on, e.g., id")
+	_ = joinCmd.MarkFlagRequired("on")
+	joinCmd.Flags().Bool("left", false, "Keep the rows of the first query without a match in another query")
+	uqlCmd.AddCommand(joinCmd)
+}
+
+func joinQueries(cmd *cobra.Command, args []string) error {
+	if rawFlag {
+		return fmt.Errorf("--raw cannot be used to join queries")
+	}
+	output, err := outputFormat(outputFlag, false)
+	if err != nil {
+		return err
+	}
+	if _, err := parseNestedMode(nestedFlag); err != nil {
+		return err
+	}
+	if err := checkChartOutput(output); err != nil {
+		return err
+	}
+	if filterFlag != "" {
+		if _, err := compileFilter(filterFlag); err != nil {
+			return err
+		}
+	}
+	keys, _ := cmd.Flags().GetStringSlice("on")
+	left, _ := cmd.Flags().GetBool("left")
+	params, err := queryParams(nil)
+	if err != nil {
+		return err
+	}
+
+	// execute the queries in parallel
+	responses := make([]*Response, len(args))
+	queries := make([]string, len(args))
+	errs := make([]error, len(args))
+	var wg sync.WaitGroup
+	for i, query := range args {
+		wg.Add(1)
+		go func(i int, query string) {
+			defer wg.Done()
+			queries[i], responses[i], errs[i] = fetchQuery(query, params)
+		}(i, query)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if problem, ok := err.(uqlProblem); ok {
+			printProblemDescription(cmd, problem, queries[i])
+		}
+		if err != nil {
+			return fmt.Errorf("query %d: %w", i+1, err)
+		}
+	}
+
+	joined, err := joinResponses(responses, keys, left)
+	if err != nil {
+		return err
+	}
+	if joined.HasErrors() {
+		log.Error("Execution of the queries encountered errors. Returned data are not complete!")
+		for _, e := range joined.Errors() {
+			log.Errorf("%s: %s", e.Title, e.Detail)
+		}
+	}
+	return printResponse(cmd, joined, output)
+}
+
+// fetchQuery executes a query with the parameters and the time range and page size flags, and
+// fetches all the pages of its results; it returns the query executed and its results
+func fetchQuery(query string, params map[string]string) (string, *Response, error) {
+	query, err := prepareQuery(query, params)
+	if err != nil {
+		return query, nil, err
+	}
+	response, err := runQuery(query)
+	if err != nil {
+		return query, nil, err
+	}
+	response, err = fetchAllPages(Client, response, maxRowsFlag)
+	return query, response, err
+}
+
+// prepareQuery checks a query and applies the parameters and the time range and page size flags
+// to it
+func prepareQuery(query string, params map[string]string) (string, error) {
+	err := checkQuery(query)
+	if err == nil {
+		query, err = substituteParams(query, params)
+	}
+	if err == nil {
+		query, err = withTimeRange(query, sinceFlag, untilFlag, time.Now())
+	}
+	if err == nil {
+		query, err = withPageSize(query, pageSizeFlag)
+	}
+	return query, err
+}
+
+// joinResponses joins the main data sets of the responses on the key columns. The rows of the
+// first response are combined with the matching rows of each other response in turn; rows
+// without a match are dropped, unless left is true.
+func joinResponses(responses []*Response, keys []string, left bool) (*Response, error) {
+	first := responses[0]
+	if first.Model() == nil {
+		return nil, fmt.Errorf("query 1 has no data model")
+	}
+	fields := append([]ModelField{}, first.Model().Fields...)
+	keyColumns, err := joinKeyColumns(fields, keys, 1)
+	if err != nil {
+		return nil, err
+	}
+	var rows [][]any
+	if first.Main() != nil {
+		rows = first.Main().Data
+	}
+	errors := append([]*Error{}, first.Errors()...)
+
+	for i, response := range responses[1:] {
+		n := i + 2
+		if response.Model() == nil {
+			return nil, fmt.Errorf("query %d has no data model", n)
+		}
+		otherFields := response.Model().Fields
+		otherKeyColumns, err := joinKeyColumns(otherFields, keys, n)
+		if err != nil {
+			return nil, err
+		}
+		errors = append(errors, response.Errors()...)
+
+		// index the rows of the other response by key
+		index := map[string][][]any{}
+		if response.Main() != nil {
+			for _, row := range response.Main().Data {
+				if key, ok := joinKey(row, otherKeyColumns); ok {
+					index[key] = append(index[key], row)
+				}
+			}
+		}
+
+		// add the other columns, renaming those with the same name as a previous column
+		var added []int
+		aliases := map[string]bool{}
+		for _, field := range fields {
+			aliases[field.Alias] = true
+		}
+		for c, field := range otherFields {
+			if slices.Contains(otherKeyColumns, c) {
+				continue
+			}
+			if aliases[field.Alias] {
+				field.Alias = fmt.Sprintf("%s_%d", field.Alias, n)
+			}
+			aliases[field.Alias] = true
+			fields = append(fields, field)
+			added = append(added, c)
+		}
+
+		var joined [][]any
+		for _, row := range rows {
+			key, ok := joinKey(row, keyColumns)
+			matches := index[key]
+			if !ok || len(matches) == 0 {
+				if left {
+					joined = append(joined, append(append([]any{}, row...), make([]any, len(added))...))
+				}
+				continue
+			}
+			for _, match := range matches {
+				combined := append([]any{}, row...)
+				for _, c := range added {
+					combined = append(combined, match[c])
+				}
+				joined = append(joined, combined)
+			}
+		}
+		rows = joined
+	}
+
+	model := &Model{Name: first.Model().Name, Fields: fields}
+	return &Response{
+		model:       model,
+		mainDataSet: &DataSet{Name: mainDataSetName, DataModel: model, Data: rows},
+		errors:      errors,
+	}, nil
+}
+
+// joinKeyColumns returns the indexes of the key columns in the fields of query n
+func joinKeyColumns(fields []ModelField, keys []string, n int) ([]int, error) {
+	columns := make([]int, len(keys))
+	for i, key := range keys {
+		columns[i] = -1
+		for c, field := range fields {
+			if field.Alias == key {
+				columns[i] = c
+			}
+		}
+		if columns[i] < 0 {
+			return nil, fmt.Errorf("query %d has no column %q to join on; the columns are: %v", n, key, strings.Join(fieldAliases(fields), ", "))
+		}
+		if fields[columns[i]].Model != nil {
+			return nil, fmt.Errorf("column %q of query %d cannot be joined on, as it has nested data", key, n)
+		}
+	}
+	return columns, nil
+}
+
+// joinKey returns the key of a row for joining, or false if a key column has no value
+func joinKey(row []any, columns []int) (string, bool) {
+	values := make([]string, len(columns))
+	for i, c := range columns {
+		if row[c] == nil {
+			return "", false
+		}
+		values[i] = formatCell(row[c])
+	}
+	return strings.Join(values, "\x00"), true
+}
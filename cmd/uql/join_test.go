@@ -0,0 +1,89 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// language=json
+const joinNamesResponse = `[
+  { "type": "model", "model": { "name": "m:main", "fields": [
+    { "alias": "id", "type": "string" },
+    { "alias": "name", "type": "string" }
+  ] } },
+  { "type": "data", "model": { "$jsonPath": "", "$model": "m:main" }, "dataset": "d:main", "data": [
+    [ "w1", "frontend" ], [ "w2", "backend" ], [ "w3", "db" ]
+  ] }
+]`
+
+// language=json
+const joinCountsResponse = `[
+  { "type": "model", "model": { "name": "m:main", "fields": [
+    { "alias": "name", "type": "string" },
+    { "alias": "id", "type": "string" },
+    { "alias": "count", "type": "number" }
+  ] } },
+  { "type": "data", "model": { "$jsonPath": "", "$model": "m:main" }, "dataset": "d:main", "data": [
+    [ "x", "w2", 5 ], [ "y", "w1", 7 ], [ "z", "w1", 8 ], [ "u", null, 1 ]
+  ] }
+]`
+
+func TestJoinResponses(t *testing.T) {
+	// given
+	names, err := parsePage([]byte(joinNamesResponse))
+	assert.NoError(t, err)
+	counts, err := parsePage([]byte(joinCountsResponse))
+	assert.NoError(t, err)
+
+	// when
+	joined, err := joinResponses([]*Response{names, counts}, []string{"id"}, false)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "name", "name_2", "count"}, fieldAliases(joined.Model().Fields))
+	assert.Equal(t, [][]any{
+		{"w1", "frontend", "y", 7},
+		{"w1", "frontend", "z", 8},
+		{"w2", "backend", "x", 5},
+	}, joined.Main().Values())
+}
+
+func TestJoinResponses_Left(t *testing.T) {
+	// given
+	names, err := parsePage([]byte(joinNamesResponse))
+	assert.NoError(t, err)
+	counts, err := parsePage([]byte(joinCountsResponse))
+	assert.NoError(t, err)
+
+	// when
+	joined, err := joinResponses([]*Response{names, counts}, []string{"id"}, true)
+
+	// then
+	assert.NoError(t, err)
+	assert.Len(t, joined.Main().Values(), 4)
+	assert.Equal(t, []any{"w3", "db", nil, nil}, joined.Main().Values()[3])
+}
+
+func TestJoinResponses_MissingKey(t *testing.T) {
+	names, err := parsePage([]byte(joinNamesResponse))
+	assert.NoError(t, err)
+
+	_, err = joinResponses([]*Response{names, names}, []string{"type"}, false)
+
+	assert.ErrorContains(t, err, `query 1 has no column "type"`)
+}
@@ -17,8 +17,18 @@ import (
 // In Go, only structs allow control over the order of JSON fields. For that reason, we dynamically generate
 // anonymous struct types with fields based on the model of the response data.
 type jsonResult struct {
-	Model any   `json:"model"`
-	Data  []any `json:"data"`
+	Model    any           `json:"model"`
+	Data     []any         `json:"data"`
+	DataSets []jsonDataSet `json:"dataSets,omitempty" yaml:"dataSets,omitempty"`
+	Partial  *Error        `json:"partial,omitempty" yaml:"partial,omitempty"`
+}
+
+// jsonDataSet is a data set of the response other than the main data set, e.g., related entities
+type jsonDataSet struct {
+	Name   string   `json:"name"`
+	Model  any      `json:"model"`
+	Data   []any    `json:"data"`
+	Errors []*Error `json:"errors,omitempty" yaml:"errors,omitempty"`
 }
 
 // valueExtractor transforms value from UQL Response to something serializable as a part of jsonResult.
@@ -115,21 +125,37 @@ func jsonTypeForUqlType(uqlType string) jsonDataType {
 
 // transformForJsonOutput produces jsonResult from UqlResponse.
 func transformForJsonOutput(response *Response) (jsonResult, error) {
-	mainModel := response.Model()
-	if err := checkAliasCollisions(mainModel); err != nil {
+	model, data, err := transformDataSet(response.Model(), response.Main())
+	if err != nil {
 		return jsonResult{}, err
 	}
-	columnMappers := make([]fieldMapper, len(mainModel.Fields))
-	for i, field := range mainModel.Fields {
+	result := jsonResult{
+		Model:   model,
+		Data:    data,
+		Partial: response.Partial(),
+	}
+	for _, dataSet := range response.DataSets() {
+		model, data, err := transformDataSet(dataSet.Model(), dataSet)
+		if err != nil {
+			return jsonResult{}, fmt.Errorf("data set %v: %w", dataSet.Name, err)
+		}
+		result.DataSets = append(result.DataSets, jsonDataSet{Name: dataSet.Name, Model: model, Data: data, Errors: dataSet.Errors})
+	}
+	return result, nil
+}
+
+// transformDataSet produces the model and the data of a data set in the form of jsonResult
+func transformDataSet(dataModel *Model, dataSet Complex) (any, []any, error) {
+	if err := checkAliasCollisions(dataModel); err != nil {
+		return nil, nil, err
+	}
+	columnMappers := make([]fieldMapper, len(dataModel.Fields))
+	for i, field := range dataModel.Fields {
 		columnMappers[i] = makeFieldMapper(field)
 	}
-	model, _ := transformModel(mainModel.Fields)
+	model, _ := transformModel(dataModel.Fields)
 	arrayMapping := makeArrayMapper("_", columnMappers)
-	dataArray := arrayMapping.valueExtractor(response.Main()).([]any)
-	return jsonResult{
-		Model: model,
-		Data:  dataArray,
-	}, nil
+	return model, arrayMapping.valueExtractor(dataSet).([]any), nil
 }
 
 // checkAliasCollisions checks for duplicate column aliases in a single table.
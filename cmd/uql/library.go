@@ -0,0 +1,324 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/cisco-open/fsoc/config"
+	fsoc "github.com/cisco-open/fsoc/output"
+)
+
+// query sources in the library
+const (
+	userQuerySource = "user"
+	teamQuerySource = "team"
+)
+
+// queryNameRegexp matches valid names of saved queries
+var queryNameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// SavedQuery is a query in the query library, with the output settings to use when running it
+type SavedQuery struct {
+	Name        string            `json:"name" yaml:"name"`
+	Description string            `json:"description,omitempty" yaml:"description,omitempty"`
+	Query       string            `json:"query" yaml:"query"`
+	Params      map[string]string `json:"params,omitempty" yaml:"params,omitempty"` // default parameter values
+	Output      string            `json:"output,omitempty" yaml:"output,omitempty"`
+	Columns     []string          `json:"columns,omitempty" yaml:"columns,omitempty"`
+	Flatten     []string          `json:"flatten,omitempty" yaml:"flatten,omitempty"`
+	Nested      string            `json:"nested,omitempty" yaml:"nested,omitempty"`
+	MaxRows     int               `json:"maxRows,omitempty" yaml:"maxRows,omitempty"`
+	PageSize    int               `json:"pageSize,omitempty" yaml:"pageSize,omitempty"`
+	Limit       int               `json:"limit,omitempty" yaml:"limit,omitempty"`
+	Source      string            `json:"source,omitempty" yaml:"source,omitempty"` // user or team, not stored
+}
+
+// queryLibraryFile is the content of a file with saved queries
+type queryLibraryFile struct {
+	Queries []SavedQuery `json:"queries" yaml:"queries"`
+}
+
+var saveCmd = &cobra.Command{
+	Use:   "save <name> <query>",
+	Short: "Save a query in the query library",
+	Long: `Save a query in the query library, along with the output settings specified with the uql flags: --output,
+--columns, --flatten, --nested, --max-rows, --page-size and --limit. The values of the query parameters, set with
+--param and --params-file, are saved as defaults, used unless set when running the query. The query can then be run by name with "fsoc uql run".
+
+Saved queries are stored in the fsoc directory of the user's configuration directory (e.g., ~/.config/fsoc/queries.yaml
+on Linux). Queries can also be shared with a team file, a YAML file with the same format whose location is set
+with "fsoc config set uql.teamqueries=PATH". Team queries are read-only; a user query with the same name takes
+precedence.`,
+	Example: `  fsoc uql save workloads "FETCH id, attributes FROM entities(k8s:workload)" --flatten "attributes.*" -o csv
+  fsoc uql save workloads "FETCH id FROM entities(k8s:workload)" --description "All workloads" --force`,
+	Args:        cobra.ExactArgs(2),
+	RunE:        saveQuery,
+	Annotations: map[string]string{config.AnnotationForConfigBypass: ""},
+}
+
+var runCmd = &cobra.Command{
+	Use:   "run <name>",
+	Short: "Run a query from the query library",
+	Long: `Run a query from the query library with its saved output settings. Flags specified on the command line
+override the saved settings.`,
+	Example: `  fsoc uql run workloads
+  fsoc uql run workloads -o json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSavedQuery,
+}
+
+var listCmd = &cobra.Command{
+	Use:         "list",
+	Short:       "List the queries in the query library",
+	Example:     `  fsoc uql list`,
+	Args:        cobra.NoArgs,
+	RunE:        listQueries,
+	Annotations: map[string]string{config.AnnotationForConfigBypass: ""},
+}
+
+func init() {
+	saveCmd.Flags().String("description", "", "Description of the query")
+	saveCmd.Flags().Bool("force", false, "Replace a saved query with the same name")
+	uqlCmd.AddCommand(saveCmd, runCmd, listCmd)
+}
+
+func saveQuery(cmd *cobra.Command, args []string) error {
+	name, query := args[0], args[1]
+	if !queryNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid query name %q: it must start with a letter or digit and contain only letters, digits, '.', '_' and '-'", name)
+	}
+	if strings.TrimSpace(query) == "" {
+		return fmt.Errorf("the query is empty")
+	}
+	if _, err := outputFormat(outputFlag, false); err != nil {
+		return err
+	}
+	if _, err := parseNestedMode(nestedFlag); err != nil {
+		return err
+	}
+
+	saved := SavedQuery{Name: name, Query: query}
+	saved.Description, _ = cmd.Flags().GetString("description")
+	if cmd.Flags().Changed("output") {
+		saved.Output = outputFlag
+	}
+	if cmd.Flags().Changed("nested") {
+		saved.Nested = nestedFlag
+	}
+	params, err := queryParams(nil)
+	if err != nil {
+		return err
+	}
+	if len(params) > 0 {
+		saved.Params = params
+	}
+	saved.Columns, saved.Flatten, saved.MaxRows, saved.PageSize, saved.Limit = columnsFlag, flattenFlag, maxRowsFlag, pageSizeFlag, limitFlag
+
+	path, err := userQueriesPath()
+	if err != nil {
+		return err
+	}
+	library, err := readQueryLibrary(path)
+	if err != nil {
+		return err
+	}
+	force, _ := cmd.Flags().GetBool("force")
+	replaced := false
+	for i := range library.Queries {
+		if library.Queries[i].Name == name {
+			if !force {
+				return fmt.Errorf("a query named %q is already saved; use --force to replace it", name)
+			}
+			library.Queries[i] = saved
+			replaced = true
+		}
+	}
+	if !replaced {
+		library.Queries = append(library.Queries, saved)
+	}
+	if err := writeQueryLibrary(path, library); err != nil {
+		return err
+	}
+
+	fsoc.PrintCmdStatus(cmd, fmt.Sprintf("Saved query %q in %v\n", name, path))
+	return nil
+}
+
+func runSavedQuery(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	saved, err := findSavedQuery(name)
+	if err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{"name": name, "source": saved.Source, "query": saved.Query}).Info("Running saved UQL query")
+
+	// apply the saved settings, unless overridden on the command line
+	flags := cmd.Flags()
+	if saved.Output != "" && !flags.Changed("output") && !flags.Changed("raw") {
+		outputFlag = saved.Output
+	}
+	if saved.Nested != "" && !flags.Changed("nested") {
+		nestedFlag = saved.Nested
+	}
+	if len(saved.Columns) > 0 && !flags.Changed("columns") {
+		columnsFlag = saved.Columns
+	}
+	if len(saved.Flatten) > 0 && !flags.Changed("flatten") {
+		flattenFlag = saved.Flatten
+	}
+	if saved.MaxRows > 0 && !flags.Changed("max-rows") {
+		maxRowsFlag = saved.MaxRows
+	}
+	if saved.PageSize > 0 && !flags.Changed("page-size") {
+		pageSizeFlag = saved.PageSize
+	}
+	if saved.Limit > 0 && !flags.Changed("limit") && !flags.Changed("sample") {
+		limitFlag = saved.Limit
+	}
+
+	return executeAndPrint(cmd, saved.Query, saved.Params)
+}
+
+func listQueries(cmd *cobra.Command, args []string) error {
+	queries, err := loadSavedQueries()
+	if err != nil {
+		return err
+	}
+
+	lines := make([][]string, len(queries))
+	for i, q := range queries {
+		lines[i] = []string{q.Name, q.Source, q.Description, strings.Join(strings.Fields(q.Query), " ")}
+	}
+	fsoc.PrintCmdOutputCustom(cmd, struct {
+		Items []SavedQuery `json:"items"`
+		Total int          `json:"total"`
+	}{queries, len(queries)}, &fsoc.Table{
+		Headers: []string{"Name", "Source", "Description", "Query"},
+		Lines:   lines,
+	})
+	return nil
+}
+
+// findSavedQuery returns the saved query with the given name, preferring user queries over team queries
+func findSavedQuery(name string) (*SavedQuery, error) {
+	queries, err := loadSavedQueries()
+	if err != nil {
+		return nil, err
+	}
+	for _, q := range queries {
+		if q.Name == name {
+			return &q, nil
+		}
+	}
+	return nil, fmt.Errorf("no saved query named %q; use \"fsoc uql list\" to list the saved queries", name)
+}
+
+// loadSavedQueries returns the user and team queries, sorted by name; team queries with the same
+// name as a user query are omitted
+func loadSavedQueries() ([]SavedQuery, error) {
+	path, err := userQueriesPath()
+	if err != nil {
+		return nil, err
+	}
+	userLibrary, err := readQueryLibrary(path)
+	if err != nil {
+		return nil, err
+	}
+	queries := []SavedQuery{}
+	names := map[string]bool{}
+	for _, q := range userLibrary.Queries {
+		q.Source = userQuerySource
+		queries = append(queries, q)
+		names[q.Name] = true
+	}
+
+	if teamPath := teamQueriesPath(); teamPath != "" {
+		teamLibrary, err := readQueryLibrary(teamPath)
+		if err != nil {
+			log.Warnf("Failed to read the team queries, ignoring them: %v", err)
+		}
+		for _, q := range teamLibrary.Queries {
+			if !names[q.Name] {
+				q.Source = teamQuerySource
+				queries = append(queries, q)
+				names[q.Name] = true
+			}
+		}
+	}
+
+	sort.SliceStable(queries, func(i, j int) bool { return queries[i].Name < queries[j].Name })
+	return queries, nil
+}
+
+// userQueriesPath returns the path of the file with the user's saved queries
+func userQueriesPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine the configuration directory: %w", err)
+	}
+	return filepath.Join(configDir, "fsoc", "queries.yaml"), nil
+}
+
+// teamQueriesPath returns the path of the team queries file, if configured
+func teamQueriesPath() string {
+	path := GlobalConfig.TeamQueries
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
+
+// readQueryLibrary reads a file with saved queries; a missing file has no queries
+func readQueryLibrary(path string) (queryLibraryFile, error) {
+	var library queryLibraryFile
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return library, nil
+	}
+	if err != nil {
+		return library, fmt.Errorf("failed to read the saved queries file %q: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, &library); err != nil {
+		return library, fmt.Errorf("failed to parse the saved queries file %q: %w", path, err)
+	}
+	return library, nil
+}
+
+func writeQueryLibrary(path string, library queryLibraryFile) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create directory for the saved queries file %q: %w", path, err)
+	}
+	data, err := yaml.Marshal(library)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write the saved queries file %q: %w", path, err)
+	}
+	return nil
+}
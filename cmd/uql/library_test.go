@@ -0,0 +1,68 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadSavedQueries(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the user configuration directory is set with XDG_CONFIG_HOME on Linux only")
+	}
+
+	// given
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	userPath, err := userQueriesPath()
+	assert.NoError(t, err)
+	assert.NoError(t, writeQueryLibrary(userPath, queryLibraryFile{Queries: []SavedQuery{
+		{Name: "workloads", Query: "FETCH id FROM entities(k8s:workload)", Output: "csv"},
+	}}))
+
+	teamPath := filepath.Join(t.TempDir(), "team.yaml")
+	// language=yaml
+	assert.NoError(t, os.WriteFile(teamPath, []byte(`
+queries:
+  - name: workloads
+    query: FETCH id, type FROM entities(k8s:workload)
+  - name: services
+    description: All services
+    query: FETCH id FROM entities(apm:service)
+    columns: [id]
+`), 0o600))
+	GlobalConfig.TeamQueries = teamPath
+	defer func() { GlobalConfig.TeamQueries = "" }()
+
+	// when
+	queries, err := loadSavedQueries()
+
+	// then: user queries take precedence over team queries with the same name
+	assert.NoError(t, err)
+	assert.Equal(t, []SavedQuery{
+		{Name: "services", Description: "All services", Query: "FETCH id FROM entities(apm:service)", Columns: []string{"id"}, Source: teamQuerySource},
+		{Name: "workloads", Query: "FETCH id FROM entities(k8s:workload)", Output: "csv", Source: userQuerySource},
+	}, queries)
+
+	saved, err := findSavedQuery("services")
+	assert.NoError(t, err)
+	assert.Equal(t, "FETCH id FROM entities(apm:service)", saved.Query)
+	_, err = findSavedQuery("missing")
+	assert.ErrorContains(t, err, `no saved query named "missing"`)
+}
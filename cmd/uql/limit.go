@@ -0,0 +1,69 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"math/rand"
+	"slices"
+
+	"github.com/apex/log"
+)
+
+// limitPageSize returns the page size of a query whose results are limited to limit top-level
+// rows (0 for no limit): without a page size or a LIMITS clause of its own, the query gets a
+// page of limit rows, so that the backend does not return more rows than displayed
+func limitPageSize(query string, pageSize int, limit int) int {
+	if pageSize > 0 || limit <= 0 || limitsClauseRegexp.MatchString(query) {
+		return pageSize
+	}
+	return limit
+}
+
+// fetchRowLimit returns the number of top-level rows to fetch, the lowest of maxRows and limit,
+// where 0 is no limit
+func fetchRowLimit(maxRows int, limit int) int {
+	if limit > 0 && (maxRows <= 0 || limit < maxRows) {
+		return limit
+	}
+	return maxRows
+}
+
+// limitRows keeps the first limit top-level rows of the response's main data set (0 for no limit),
+// warning with the number of rows dropped
+func limitRows(response *Response, limit int) {
+	main := response.Main()
+	if main == nil || limit <= 0 || len(main.Data) <= limit {
+		return
+	}
+	log.Warnf("Showing the first %d of the %d rows of the results (use --limit to change the limit)", limit, len(main.Data))
+	main.Data = main.Data[:limit]
+}
+
+// sampleRows keeps size top-level rows of the response's main data set, chosen at random, in their
+// order in the results, warning with the number of rows sampled from
+func sampleRows(response *Response, size int, rnd *rand.Rand) {
+	main := response.Main()
+	if main == nil || size <= 0 || len(main.Data) <= size {
+		return
+	}
+	log.Warnf("Showing a sample of %d of the %d rows of the results", size, len(main.Data))
+	indexes := rnd.Perm(len(main.Data))[:size]
+	slices.Sort(indexes)
+	sample := make([][]any, size)
+	for i, index := range indexes {
+		sample[i] = main.Data[index]
+	}
+	main.Data = sample
+}
@@ -0,0 +1,105 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitPageSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		pageSize int
+		limit    int
+		expected int
+	}{
+		{"no limit", "FETCH id FROM entities", 0, 0, 0},
+		{"limit", "FETCH id FROM entities", 0, 10, 10},
+		{"page size", "FETCH id FROM entities", 50, 10, 50},
+		{"limits clause", "FETCH id FROM entities LIMITS topLevelItems.count(5)", 0, 10, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, limitPageSize(test.query, test.pageSize, test.limit))
+		})
+	}
+}
+
+func TestFetchRowLimit(t *testing.T) {
+	assert.Equal(t, 0, fetchRowLimit(0, 0))
+	assert.Equal(t, 10, fetchRowLimit(0, 10))
+	assert.Equal(t, 5, fetchRowLimit(5, 10))
+	assert.Equal(t, 10, fetchRowLimit(20, 10))
+}
+
+func TestLimitRows(t *testing.T) {
+	// given: a page of more rows than the limit, e.g., from a LIMITS clause of the query
+	response, err := parsePage([]byte(pageResponse("", "a", "b", "c")))
+	assert.NoError(t, err)
+
+	// when
+	limitRows(response, 2)
+
+	// then
+	assert.Equal(t, [][]any{{"a"}, {"b"}}, response.Main().Values())
+}
+
+func TestFetchAllPages_Limit(t *testing.T) {
+	// given
+	backend := mockPagedService(t, pageResponse("/page2", "a", "b"), map[string]string{
+		"/page2": pageResponse("/page3", "c", "d"),
+	})
+	client := defaultClient{backend: backend}
+	response, err := client.ExecuteQuery(&Query{"ignored"})
+	assert.NoError(t, err)
+
+	// when: the limit is lower than --max-rows
+	response, err = fetchAllPages(client, response, fetchRowLimit(100, 2))
+
+	// then: no other page is fetched
+	assert.NoError(t, err)
+	assert.Equal(t, [][]any{{"a"}, {"b"}}, response.Main().Values())
+	assert.True(t, hasNextPage(response.Main()))
+}
+
+func TestSampleRows(t *testing.T) {
+	// given
+	response, err := parsePage([]byte(pageResponse("", "a", "b", "c", "d", "e")))
+	assert.NoError(t, err)
+
+	// when
+	sampleRows(response, 3, rand.New(rand.NewSource(1)))
+
+	// then: the rows sampled keep their order
+	values := response.Main().Values()
+	assert.Len(t, values, 3)
+	assert.IsIncreasing(t, []string{values[0][0].(string), values[1][0].(string), values[2][0].(string)})
+}
+
+func TestSampleRows_FewerRows(t *testing.T) {
+	// given
+	response, err := parsePage([]byte(pageResponse("", "a", "b")))
+	assert.NoError(t, err)
+
+	// when
+	sampleRows(response, 3, rand.New(rand.NewSource(1)))
+
+	// then
+	assert.Equal(t, [][]any{{"a"}, {"b"}}, response.Main().Values())
+}
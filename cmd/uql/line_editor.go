@@ -0,0 +1,246 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// errInterrupted is returned by readLine when the user presses Ctrl-C
+var errInterrupted = errors.New("interrupted")
+
+// completeFunc returns the completions of the word ending at the cursor
+type completeFunc func(word string) []string
+
+// lineEditor reads lines from a terminal in raw mode, with basic editing (cursor movement,
+// deletion), history navigation and tab completion. The terminal must be put in raw mode by
+// the caller.
+type lineEditor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	history  []string
+	complete completeFunc // optional
+
+	line []rune
+	pos  int
+}
+
+// control keys
+const (
+	keyCtrlA     = 1
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyBackspace = 8
+	keyTab       = 9
+	keyLF        = 10
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyCR        = 13
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+func newLineEditor(in io.Reader, out io.Writer, history []string, complete completeFunc) *lineEditor {
+	return &lineEditor{in: bufio.NewReader(in), out: out, history: history, complete: complete}
+}
+
+// readLine reads a line, displaying the prompt. It returns io.EOF on Ctrl-D in an empty line and
+// errInterrupted on Ctrl-C.
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	e.line, e.pos = nil, 0
+	historyPos := len(e.history)
+	var pending []rune // the line being edited while browsing the history
+	e.refresh(prompt)
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case keyCR, keyLF:
+			fmt.Fprint(e.out, "\r\n")
+			return string(e.line), nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(e.line) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			e.deleteAt(e.pos)
+		case keyBackspace, keyDelete:
+			if e.pos > 0 {
+				e.pos--
+				e.deleteAt(e.pos)
+			}
+		case keyCtrlA:
+			e.pos = 0
+		case keyCtrlE:
+			e.pos = len(e.line)
+		case keyCtrlK:
+			e.line = e.line[:e.pos]
+		case keyCtrlU:
+			e.line, e.pos = e.line[e.pos:], 0
+		case keyCtrlL:
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		case keyTab:
+			e.completeWord()
+		case keyEscape:
+			switch e.readEscape() {
+			case "[A": // up
+				if historyPos > 0 {
+					if historyPos == len(e.history) {
+						pending = e.line
+					}
+					historyPos--
+					e.setLine([]rune(e.history[historyPos]))
+				}
+			case "[B": // down
+				if historyPos < len(e.history) {
+					historyPos++
+					if historyPos == len(e.history) {
+						e.setLine(pending)
+					} else {
+						e.setLine([]rune(e.history[historyPos]))
+					}
+				}
+			case "[C": // right
+				if e.pos < len(e.line) {
+					e.pos++
+				}
+			case "[D": // left
+				if e.pos > 0 {
+					e.pos--
+				}
+			case "[H", "[1~", "OH": // home
+				e.pos = 0
+			case "[F", "[4~", "OF": // end
+				e.pos = len(e.line)
+			case "[3~": // delete
+				e.deleteAt(e.pos)
+			}
+		default:
+			if unicode.IsPrint(r) {
+				e.line = append(e.line[:e.pos], append([]rune{r}, e.line[e.pos:]...)...)
+				e.pos++
+			}
+		}
+		e.refresh(prompt)
+	}
+}
+
+// addHistory adds a line to the history, unless it is empty or the same as the last line
+func (e *lineEditor) addHistory(line string) {
+	if line == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+	e.history = append(e.history, line)
+}
+
+// readEscape reads the rest of an escape sequence, e.g., "[A" for the up arrow key
+func (e *lineEditor) readEscape() string {
+	var seq strings.Builder
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return seq.String()
+		}
+		seq.WriteRune(r)
+		// sequences end with a letter or "~", except for their first character ("[" or "O")
+		if seq.Len() > 1 && (unicode.IsLetter(r) || r == '~') {
+			return seq.String()
+		}
+	}
+}
+
+func (e *lineEditor) setLine(line []rune) {
+	e.line = append([]rune{}, line...)
+	e.pos = len(e.line)
+}
+
+func (e *lineEditor) deleteAt(pos int) {
+	if pos < len(e.line) {
+		e.line = append(e.line[:pos], e.line[pos+1:]...)
+	}
+}
+
+// completeWord completes the word before the cursor: a single completion replaces the word, while
+// multiple completions replace it with their common prefix or, if there is none longer than the
+// word, are listed
+func (e *lineEditor) completeWord() {
+	if e.complete == nil {
+		return
+	}
+	start := e.pos
+	for start > 0 && isWordRune(e.line[start-1]) {
+		start--
+	}
+	word := string(e.line[start:e.pos])
+	completions := e.complete(word)
+	if len(completions) == 0 {
+		return
+	}
+
+	completion := commonPrefix(completions)
+	if len(completions) == 1 {
+		completion += " "
+	}
+	if len([]rune(completion)) > len([]rune(word)) {
+		// replace the word, as completions may differ in case, e.g., "fe" completes to "FETCH"
+		replacement := []rune(completion)
+		e.line = append(append(append([]rune{}, e.line[:start]...), replacement...), e.line[e.pos:]...)
+		e.pos = start + len(replacement)
+		return
+	}
+	if len(completions) > 1 {
+		fmt.Fprintf(e.out, "\r\n%v\r\n", strings.Join(completions, "  "))
+	}
+}
+
+// refresh redraws the line and moves the cursor to its position
+func (e *lineEditor) refresh(prompt string) {
+	fmt.Fprintf(e.out, "\r%v%v\x1b[K", prompt, string(e.line))
+	if back := len(e.line) - e.pos; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
+
+// isWordRune returns true for the characters of words that can be completed, e.g., k8s:workload
+// or FETCH
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_:.-", r)
+}
+
+func commonPrefix(words []string) string {
+	prefix := []rune(words[0])
+	for _, word := range words[1:] {
+		runes := []rune(word)
+		n := 0
+		for n < len(prefix) && n < len(runes) && unicode.ToLower(prefix[n]) == unicode.ToLower(runes[n]) {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	return string(prefix)
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineEditorEditing(t *testing.T) {
+	// given: "FETC", two left arrows, "x", end, "H", home, delete, "F" and enter
+	e := newLineEditor(strings.NewReader("FETC\x1b[D\x1b[Dx\x1b[FH\x01\x1b[3~F\r"), io.Discard, nil, nil)
+
+	// when
+	line, err := e.readLine("uql> ")
+
+	// then
+	assert.Nil(t, err)
+	assert.Equal(t, "FExTCH", line)
+}
+
+func TestLineEditorHistory(t *testing.T) {
+	// given
+	e := newLineEditor(strings.NewReader("\x1b[A\x1b[A\r\x1b[A\x1b[B\r"), io.Discard, []string{"first", "second"}, nil)
+
+	// when
+	first, err1 := e.readLine("")
+	second, err2 := e.readLine("")
+
+	// then
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.Equal(t, "first", first)
+	assert.Equal(t, "", second)
+
+	e.addHistory("third")
+	e.addHistory("third")
+	assert.Equal(t, []string{"first", "second", "third"}, e.history)
+}
+
+func TestLineEditorCompletion(t *testing.T) {
+	// given
+	complete := func(word string) []string {
+		var matches []string
+		for _, c := range []string{"FETCH", "FROM", "k8s:workload", "k8s:pod"} {
+			if strings.HasPrefix(strings.ToLower(c), strings.ToLower(word)) {
+				matches = append(matches, c)
+			}
+		}
+		return matches
+	}
+	var out bytes.Buffer
+	e := newLineEditor(strings.NewReader("fe\tid f\t\nk8s:w\t\r"), &out, nil, complete)
+
+	// when
+	line, err := e.readLine("")
+	line2, err2 := e.readLine("")
+
+	// then
+	assert.Nil(t, err)
+	assert.Nil(t, err2)
+	assert.Equal(t, "FETCH id f", line)
+	assert.Contains(t, out.String(), "FETCH  FROM")
+	assert.Equal(t, "k8s:workload ", line2)
+}
+
+func TestLineEditorControlKeys(t *testing.T) {
+	// Ctrl-C interrupts, Ctrl-D ends the input in an empty line
+	e := newLineEditor(strings.NewReader("abc\x03\x04"), io.Discard, nil, nil)
+
+	_, err := e.readLine("")
+	assert.ErrorIs(t, err, errInterrupted)
+	_, err = e.readLine("")
+	assert.ErrorIs(t, err, io.EOF)
+}
@@ -0,0 +1,362 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	fsoc "github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/melt"
+)
+
+const (
+	telemetryMetric = "metric"
+	telemetryEvent  = "event"
+)
+
+var exportOtlpCmd = &cobra.Command{
+	Use:   "export-otlp <query>",
+	Short: "Export the metrics and events in the results of a query as OTLP",
+	Long: `Execute a query and convert the metrics and events in its results into OTLP, e.g., to replicate platform
+data into another backend for comparison or migration testing. The data are written to a file with --file, as
+lines of OTLP JSON that the OTLP JSON file receiver of the OpenTelemetry collector reads, or sent to an OTLP/HTTP
+receiver with --endpoint.
+
+Each top-level row of the results becomes a resource, with the values of its columns, e.g., id, and its
+attributes as the resource attributes. The metrics in the row, e.g., metrics(infra:cpu.used), become gauges
+with a data point per value, and its events, e.g., events(k8s:event), become log records marked as events, with
+their raw text as the body. The other values in the rows that the metrics and events are nested in, e.g., the
+source of a metric, become attributes of the data points and of the log records.
+
+All the pages of results are fetched, up to --max-rows top-level rows; the --since, --until and --param flags
+apply as with the other uql commands.`,
+	Example: `  fsoc uql export-otlp --since 1h -f metrics.jsonl "FETCH id, attributes, metrics(infra:cpu.used) FROM entities(k8s:workload)"
+  fsoc uql export-otlp --endpoint http://localhost:4318 "FETCH id, attributes, events(k8s:event) FROM entities(k8s:workload)"
+  fsoc uql export-otlp --endpoint https://otlp.example.com --header "Authorization=Bearer $TOKEN" "FETCH id, metrics(infra:cpu.used) FROM entities(k8s:workload)"`,
+	Args: cobra.ExactArgs(1),
+	RunE: exportOtlp,
+}
+
+func init() {
+	exportOtlpCmd.Flags().StringP("file", "f", "", `File to write the OTLP JSON to, "-" for stdout`)
+	exportOtlpCmd.Flags().String("endpoint", "", "URL of an OTLP/HTTP receiver to send the data to, e.g., http://localhost:4318")
+	exportOtlpCmd.Flags().StringArray("header", nil, "Header to send to the OTLP receiver, as name=value; may be repeated")
+	exportOtlpCmd.MarkFlagsMutuallyExclusive("file", "endpoint")
+	exportOtlpCmd.MarkFlagsOneRequired("file", "endpoint")
+	uqlCmd.AddCommand(exportOtlpCmd)
+}
+
+func exportOtlp(cmd *cobra.Command, args []string) error {
+	file, _ := cmd.Flags().GetString("file")
+	endpoint, _ := cmd.Flags().GetString("endpoint")
+	headerValues, _ := cmd.Flags().GetStringArray("header")
+	headers := map[string]string{}
+	for _, header := range headerValues {
+		name, value, found := strings.Cut(header, "=")
+		if !found || name == "" {
+			return fmt.Errorf("invalid header %q: must be name=value", header)
+		}
+		headers[name] = value
+	}
+	if len(headers) > 0 && endpoint == "" {
+		return fmt.Errorf("--header can only be used with --endpoint")
+	}
+	params, err := queryParams(nil)
+	if err != nil {
+		return err
+	}
+
+	query, response, err := fetchQuery(args[0], params)
+	if problem, ok := err.(uqlProblem); ok {
+		printProblemDescription(cmd, problem, query)
+	}
+	if err != nil {
+		return err
+	}
+	if response.HasErrors() {
+		log.Error("Execution of the query encountered errors. Exported data are not complete!")
+		for _, e := range response.Errors() {
+			log.Errorf("%s: %s", e.Title, e.Detail)
+		}
+	}
+	entities := resultsToEntities(response)
+	stats := countTelemetry(entities)
+	if stats.metrics == 0 && stats.events == 0 {
+		return fmt.Errorf("the results have no metrics or events to export, e.g., from FETCH id, metrics(infra:cpu.used) or FETCH id, events(k8s:event)")
+	}
+
+	exporter := &melt.Exporter{Endpoint: endpoint, Headers: headers}
+	if file != "" {
+		var w io.Writer = cmd.OutOrStdout()
+		if file != stdinFileName {
+			f, err := os.Create(file)
+			if err != nil {
+				return fmt.Errorf("failed to create %q: %w", file, err)
+			}
+			defer f.Close()
+			w = f
+		}
+		exporter.DryRun = true
+		exporter.DumpFormat = melt.DumpFormatOtlpJson
+		exporter.DumpFunc = func(text string) { _, _ = io.WriteString(w, text) }
+	}
+	if err := exporter.ExportMetrics(entities); err != nil {
+		return err
+	}
+	if err := exporter.ExportEvents(entities); err != nil {
+		return err
+	}
+
+	destination := endpoint
+	if file != "" {
+		destination = file
+	}
+	if file != stdinFileName {
+		fsoc.PrintCmdStatus(cmd, fmt.Sprintf("Exported %d metrics with %d data points and %d events of %d resources to %v\n",
+			stats.metrics, stats.dataPoints, stats.events, stats.resources, destination))
+	}
+	return nil
+}
+
+// telemetrySource describes the data set that metrics or events are found in
+type telemetrySource struct {
+	kind       string         // telemetryMetric or telemetryEvent, empty until a metric or an event column is found
+	name       string         // the type of the metric or event, e.g., infra:cpu.used
+	attributes map[string]any // the values of the rows the data set is nested in, e.g., the source of a metric
+}
+
+// resultsToEntities converts each top-level row of the results into an entity, with the values of
+// its columns as attributes and the metrics and events nested in it
+func resultsToEntities(response *Response) []*melt.Entity {
+	main := response.Main()
+	if complexIsEmpty(main) {
+		return nil
+	}
+	model := response.Model()
+	var entities []*melt.Entity
+	for _, row := range main.Values() {
+		entity := melt.NewEntity(entityType(model))
+		for c, field := range model.Fields {
+			if field.Model == nil {
+				if row[c] != nil {
+					entity.SetAttribute(attributeName(field.Alias), attributeValue(row[c]))
+				}
+				continue
+			}
+			nested, ok := row[c].(Complex)
+			if !ok {
+				continue
+			}
+			source := nestedSource(field, telemetrySource{})
+			if source.kind == "" && isNameValueModel(field.Model) {
+				for _, pair := range nested.Values() {
+					if name, ok := pair[0].(string); ok && pair[1] != nil {
+						entity.SetAttribute(name, attributeValue(pair[1]))
+					}
+				}
+				continue
+			}
+			collectTelemetry(entity, nested, field.Model, source)
+		}
+		entities = append(entities, entity)
+	}
+	return entities
+}
+
+// collectTelemetry adds the metrics and events in a data set, or nested in its rows, to an entity
+func collectTelemetry(entity *melt.Entity, data Complex, model *Model, source telemetrySource) {
+	if complexIsEmpty(data) {
+		return
+	}
+	timeColumn := -1
+	for c, field := range model.Fields {
+		if field.Type == "timestamp" && field.Model == nil {
+			timeColumn = c
+			break
+		}
+	}
+	switch {
+	case source.kind == telemetryMetric && timeColumn >= 0:
+		if metric := toMetric(data, model, timeColumn, source); len(metric.DataPoints) > 0 {
+			entity.AddMetric(metric)
+		}
+		return
+	case source.kind == telemetryEvent && timeColumn >= 0:
+		for _, row := range data.Values() {
+			entity.AddLog(toEvent(row, model, timeColumn, source))
+		}
+		return
+	}
+
+	for _, row := range data.Values() {
+		rowSource := source
+		rowSource.attributes = copyAttributes(source.attributes)
+		for c, field := range model.Fields {
+			if field.Model == nil && row[c] != nil {
+				rowSource.attributes[attributeName(field.Alias)] = attributeValue(row[c])
+			}
+		}
+		for c, field := range model.Fields {
+			if nested, ok := row[c].(Complex); ok && field.Model != nil {
+				collectTelemetry(entity, nested, field.Model, nestedSource(field, rowSource))
+			}
+		}
+	}
+}
+
+// toMetric converts a time series into a gauge, with the values of its "value" column, or of its
+// first numeric column
+func toMetric(data Complex, model *Model, timeColumn int, source telemetrySource) *melt.Metric {
+	valueColumn := -1
+	for c, field := range model.Fields {
+		isNumber := field.Type == "number" || field.Type == "long" || field.Type == "double"
+		if isNumber && (valueColumn < 0 || field.Alias == "value" || (field.Hints != nil && field.Hints.Field == "value")) {
+			valueColumn = c
+		}
+	}
+	metric := melt.NewMetric(source.name, "", "gauge", "double")
+	metric.Attributes = copyAttributes(source.attributes)
+	if valueColumn < 0 {
+		return metric
+	}
+	for _, row := range data.Values() {
+		t, isTime := row[timeColumn].(time.Time)
+		value, isNumber := toFloat(row[valueColumn])
+		if isTime && isNumber {
+			metric.AddDataPoint(t.UnixNano(), t.UnixNano(), value)
+		}
+	}
+	return metric
+}
+
+// toEvent converts a row of events into an event, with its raw text as the body and its other
+// values as attributes
+func toEvent(row []any, model *Model, timeColumn int, source telemetrySource) *melt.Log {
+	event := melt.NewEvent(source.name)
+	event.Attributes = copyAttributes(source.attributes)
+	if t, ok := row[timeColumn].(time.Time); ok {
+		event.Timestamp = t.UnixNano()
+	}
+	for c, field := range model.Fields {
+		switch {
+		case c == timeColumn || row[c] == nil:
+		case field.Alias == "raw" || (field.Hints != nil && field.Hints.Field == "raw"):
+			event.Body = fmt.Sprint(row[c])
+		case field.Model == nil:
+			event.Attributes[attributeName(field.Alias)] = attributeValue(row[c])
+		}
+	}
+	return event
+}
+
+// nestedSource returns the source of the data nested in a column: a column of metrics or events,
+// e.g., metrics(infra:cpu.used), starts a new source, while other columns keep the current one
+func nestedSource(field ModelField, source telemetrySource) telemetrySource {
+	kind, name := "", ""
+	if field.Hints != nil && field.Hints.Field == "" {
+		kind, name = field.Hints.Kind, field.Hints.Type
+	}
+	function, argument, _ := strings.Cut(strings.TrimSuffix(field.Alias, ")"), "(")
+	if kind == "" {
+		switch function {
+		case "metrics":
+			kind = telemetryMetric
+		case "events", "logs":
+			kind = telemetryEvent
+		}
+	}
+	if kind != telemetryMetric && kind != telemetryEvent {
+		return source
+	}
+	if name == "" {
+		name = strings.Trim(argument, `"'`)
+	}
+	if name == "" {
+		name = field.Alias
+	}
+	if kind == source.kind && source.name != "" {
+		name = source.name // e.g., the time series of a metric nested in its sources
+	}
+	return telemetrySource{kind: kind, name: name, attributes: source.attributes}
+}
+
+// entityType returns the type of the entities in the results, if hinted by their id column
+func entityType(model *Model) string {
+	for _, field := range model.Fields {
+		if field.Hints != nil && field.Hints.Kind == "entity" && field.Hints.Type != "" {
+			return field.Hints.Type
+		}
+	}
+	return ""
+}
+
+// isNameValueModel returns whether a data set holds name/value pairs, e.g., the attributes of entities
+func isNameValueModel(model *Model) bool {
+	return len(model.Fields) == 2 && model.Fields[0].Type == "string" && model.Fields[1].Model == nil
+}
+
+// attributeName returns the name of the attribute for a column, e.g., k8s.workload.name for the
+// column attributes("k8s.workload.name")
+func attributeName(alias string) string {
+	if name, found := strings.CutPrefix(alias, "attributes("); found {
+		return strings.Trim(strings.TrimSuffix(name, ")"), `"'`)
+	}
+	return alias
+}
+
+// attributeValue converts a value of the results into a value the exporter supports
+func attributeValue(value any) any {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case time.Duration:
+		return v.String()
+	}
+	return value
+}
+
+func copyAttributes(attributes map[string]any) map[string]any {
+	result := make(map[string]any, len(attributes))
+	for name, value := range attributes {
+		result[name] = value
+	}
+	return result
+}
+
+type telemetryStats struct {
+	resources, metrics, dataPoints, events int
+}
+
+func countTelemetry(entities []*melt.Entity) telemetryStats {
+	var stats telemetryStats
+	for _, entity := range entities {
+		if len(entity.Metrics) > 0 || len(entity.Logs) > 0 {
+			stats.resources++
+		}
+		stats.metrics += len(entity.Metrics)
+		stats.events += len(entity.Logs)
+		for _, metric := range entity.Metrics {
+			stats.dataPoints += len(metric.DataPoints)
+		}
+	}
+	return stats
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cisco-open/fsoc/platform/melt"
+)
+
+// language=json
+const otlpResponse = `[
+  { "type": "model", "model": { "name": "m:main", "fields": [
+    { "alias": "id", "type": "string", "hints": { "kind": "entity", "field": "id", "type": "infra:container" } },
+    { "alias": "attributes(\"container.name\")", "type": "string" },
+    { "alias": "attributes", "type": "complex", "form": "inline", "model": { "name": "m:attributes", "fields": [
+      { "alias": "name", "type": "string" },
+      { "alias": "value", "type": "string" }
+    ] } },
+    { "alias": "metrics", "type": "complex", "hints": { "kind": "metric", "type": "infra:cpu.used" }, "form": "reference", "model": { "name": "m:metrics", "fields": [
+      { "alias": "source", "type": "string", "hints": { "kind": "metric", "field": "source" } },
+      { "alias": "metrics", "type": "timeseries", "hints": { "kind": "metric", "type": "infra:cpu.used" }, "form": "inline", "model": { "name": "m:metrics_2", "fields": [
+        { "alias": "timestamp", "type": "timestamp", "hints": { "kind": "metric", "field": "timestamp" } },
+        { "alias": "value", "type": "number", "hints": { "kind": "metric", "field": "value" } }
+      ] } }
+    ] } },
+    { "alias": "events(k8s:event)", "type": "timeseries", "form": "reference", "model": { "name": "m:events", "fields": [
+      { "alias": "timestamp", "type": "timestamp" },
+      { "alias": "reason", "type": "string" },
+      { "alias": "raw", "type": "string" }
+    ] } }
+  ] } },
+  { "type": "data", "model": { "$jsonPath": "", "$model": "m:main" }, "dataset": "d:main", "data": [
+    [ "infra:container:c1", "cart", [ [ "k8s.pod.name", "cart-1" ] ], { "$dataset": "d:metrics-1", "$jsonPath": "" }, { "$dataset": "d:events-1", "$jsonPath": "" } ]
+  ] },
+  { "type": "data", "model": { "$jsonPath": "", "$model": "m:metrics" }, "dataset": "d:metrics-1", "data": [
+    [ "infra-agent", [ [ "2024-01-13T13:00:00Z", 1.5 ], [ "2024-01-13T13:01:00Z", 2 ] ] ]
+  ] },
+  { "type": "data", "model": { "$jsonPath": "", "$model": "m:events" }, "dataset": "d:events-1", "data": [
+    [ "2024-01-13T13:00:30Z", "Pulled", "pulled image cart:1.2" ]
+  ] }
+]`
+
+func TestResultsToEntities(t *testing.T) {
+	// given
+	response, err := parsePage([]byte(otlpResponse))
+	assert.NoError(t, err)
+
+	// when
+	entities := resultsToEntities(response)
+
+	// then
+	start := time.Date(2024, 1, 13, 13, 0, 0, 0, time.UTC)
+	assert.Len(t, entities, 1)
+	entity := entities[0]
+	assert.Equal(t, "infra:container", entity.TypeName)
+	assert.Equal(t, map[string]any{"id": "infra:container:c1", "container.name": "cart", "k8s.pod.name": "cart-1"}, entity.Attributes)
+
+	metric := melt.NewMetric("infra:cpu.used", "", "gauge", "double").
+		AddDataPoint(start.UnixNano(), start.UnixNano(), 1.5).
+		AddDataPoint(start.Add(time.Minute).UnixNano(), start.Add(time.Minute).UnixNano(), 2)
+	metric.Attributes = map[string]any{"source": "infra-agent"}
+	assert.Equal(t, []*melt.Metric{metric}, entity.Metrics)
+
+	event := melt.NewEvent("k8s:event")
+	event.Timestamp = start.Add(30 * time.Second).UnixNano()
+	event.Body = "pulled image cart:1.2"
+	event.Attributes = map[string]any{"reason": "Pulled"}
+	assert.Equal(t, []*melt.Log{event}, entity.Logs)
+
+	assert.Equal(t, telemetryStats{resources: 1, metrics: 1, dataPoints: 2, events: 1}, countTelemetry(entities))
+}
+
+func TestResultsToEntities_NoTelemetry(t *testing.T) {
+	// given
+	response, err := executeUqlQuery(&Query{"ignored"}, ApiVersion1, mockExecuteResponse(attributesServerResponse))
+	assert.NoError(t, err)
+
+	// when
+	entities := resultsToEntities(response)
+
+	// then
+	assert.Len(t, entities, 2)
+	assert.Equal(t, "checkout", entities[1].Attributes["service.name"])
+	assert.Equal(t, telemetryStats{}, countTelemetry(entities))
+}
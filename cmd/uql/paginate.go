@@ -0,0 +1,184 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"slices"
+	"syscall"
+
+	"github.com/apex/log"
+)
+
+// nextPageRel is the relation of the link to the next page of the main data set
+const nextPageRel = "next"
+
+// selfRel is the relation of the link to a data set's own page
+const selfRel = "self"
+
+// limitsClauseRegexp detects a LIMITS clause in a query
+var limitsClauseRegexp = regexp.MustCompile(`(?i)\bLIMITS\b`)
+
+// withPageSize returns the query with a LIMITS clause setting the number of top-level rows
+// returned in each page. Queries that already have a LIMITS clause are rejected, as the two
+// would conflict.
+func withPageSize(query string, pageSize int) (string, error) {
+	if pageSize <= 0 {
+		return query, nil
+	}
+	if limitsClauseRegexp.MatchString(query) {
+		return "", fmt.Errorf("the page size cannot be set for a query with a LIMITS clause; use LIMITS topLevelItems.count(n) in the query instead")
+	}
+	return fmt.Sprintf("%s\nLIMITS topLevelItems.count(%d)", query, pageSize), nil
+}
+
+// fetchAllPages follows the next-page links of the response's main data set, merging the rows
+// of each page into the response, until there are no more pages or the response has maxRows
+// rows (0 for no limit). The main data set keeps the links of the last page fetched, so that a
+// remaining next link indicates that there are more results. If a page fails, or fetching the
+// pages is interrupted, e.g., with Ctrl-C, the response keeps the rows of the pages fetched
+// before, with a partial result error (see Response.Partial).
+func fetchAllPages(client UqlClient, response *Response, maxRows int) (*Response, error) {
+	main := response.Main()
+	if main == nil || !hasNextPage(main) {
+		return response, nil
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for page := 2; hasNextPage(main) && (maxRows <= 0 || len(main.Data) < maxRows); page++ {
+		log.WithFields(log.Fields{"page": page, "rows": len(main.Data)}).Info("fetching next page of results")
+		next, err := continueUntilDone(ctx, client, main)
+		if err != nil {
+			markPartial(response, page, err)
+			return response, nil
+		}
+		if err := mergePage(response, next); err != nil {
+			return nil, fmt.Errorf("failed to merge page %d of the results: %w", page, err)
+		}
+		if next.Main() == nil {
+			log.Warnf("Page %d of the results has no data, stopping", page)
+			main.Links = nil
+			break
+		}
+	}
+
+	fetched := len(main.Data)
+	if maxRows > 0 && fetched > maxRows {
+		main.Data = main.Data[:maxRows]
+	}
+	switch {
+	case hasNextPage(main) && fetched > len(main.Data):
+		log.Warnf("Showing the first %d of the %d rows fetched; there are more results (use --max-rows or --limit to change the limit)", len(main.Data), fetched)
+	case hasNextPage(main):
+		log.Warnf("Showing the first %d rows only; there are more results (use --max-rows or --limit to change the limit)", len(main.Data))
+	}
+	return response, nil
+}
+
+// continueUntilDone fetches the next page of the results of a data set, unless the context is
+// done first
+func continueUntilDone(ctx context.Context, client UqlClient, dataSet *DataSet) (*Response, error) {
+	type result struct {
+		response *Response
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		response, err := client.ContinueQuery(dataSet, nextPageRel)
+		done <- result{response, err}
+	}()
+	select {
+	case r := <-done:
+		return r.response, r.err
+	case <-ctx.Done():
+		return nil, errInterrupted
+	}
+}
+
+// markPartial adds a partial result error to a response, for the page that failed
+func markPartial(response *Response, page int, err error) {
+	detail := fmt.Sprintf("the results have only the %d rows fetched before page %d failed: %v", len(response.Main().Data), page, err)
+	if errors.Is(err, errInterrupted) {
+		detail = fmt.Sprintf("the results have only the %d rows fetched before page %d was interrupted", len(response.Main().Data), page)
+	}
+	response.errors = append(response.errors, &Error{Type: partialResultErrorType, Title: "Partial result", Detail: detail})
+}
+
+// mergePage adds the rows, errors and raw chunks of a page to the response. The page's other
+// data sets are merged into the data sets they continue, i.e., whose next link is their self link;
+// data sets are numbered per page, so ones with the same name are not necessarily the same.
+func mergePage(response *Response, page *Response) error {
+	response.errors = append(response.errors, page.errors...)
+	if pageMain := page.Main(); pageMain != nil {
+		response.mainDataSet.Data = append(response.mainDataSet.Data, pageMain.Data...)
+		response.mainDataSet.Links = pageMain.Links
+	}
+	for _, dataSet := range page.dataSets {
+		i := slices.IndexFunc(response.dataSets, func(d *DataSet) bool { return isContinuedBy(d, dataSet) })
+		if i < 0 {
+			response.dataSets = append(response.dataSets, dataSet)
+			continue
+		}
+		response.dataSets[i].Data = append(response.dataSets[i].Data, dataSet.Data...)
+		response.dataSets[i].Errors = append(response.dataSets[i].Errors, dataSet.Errors...)
+		response.dataSets[i].Links = dataSet.Links
+	}
+	if response.raw == nil || page.raw == nil {
+		return nil
+	}
+
+	// the raw response is the list of the chunks of all pages
+	var chunks, pageChunks []json.RawMessage
+	if err := unmarshalChunks(*response.raw, &chunks); err != nil {
+		return err
+	}
+	if err := unmarshalChunks(*page.raw, &pageChunks); err != nil {
+		return err
+	}
+	merged, err := json.Marshal(append(chunks, pageChunks...))
+	if err != nil {
+		return err
+	}
+	raw := json.RawMessage(merged)
+	response.raw = &raw
+	return nil
+}
+
+// unmarshalChunks unmarshals the chunks of a raw response, whether wrapped in an object or not
+func unmarshalChunks(raw json.RawMessage, chunks *[]json.RawMessage) error {
+	raw, err := unwrapChunks(raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, chunks)
+}
+
+// isContinuedBy returns true if the data set is the next page of the previous one, i.e., the
+// previous data set's next link is the data set's self link
+func isContinuedBy(previous *DataSet, dataSet *DataSet) bool {
+	next, self := extractLink(previous, nextPageRel), extractLink(dataSet, selfRel)
+	return next != nil && self != nil && next.Href == self.Href
+}
+
+func hasNextPage(dataSet *DataSet) bool {
+	return extractLink(dataSet, nextPageRel) != nil
+}
@@ -0,0 +1,231 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pageResponse returns a response page with the given ids and, if next is not empty, a link to the next page
+func pageResponse(next string, ids ...string) string {
+	rows := make([]string, len(ids))
+	for i, id := range ids {
+		rows[i] = fmt.Sprintf(`["%s"]`, id)
+	}
+	links := ""
+	if next != "" {
+		links = fmt.Sprintf(`"_links": { "next": { "href": "%s" } },`, next)
+	}
+	// language=json
+	return fmt.Sprintf(`[
+	  { "type": "model", "model": { "name": "m:main", "fields": [ { "alias": "id", "type": "string" } ] } },
+	  { "type": "data", %s "model": { "$jsonPath": "", "$model": "m:main" }, "dataset": "d:main", "data": [ %s ] }
+	]`, links, strings.Join(rows, ", "))
+}
+
+// mockPagedService returns a service whose execute response is the first page and whose
+// continue responses are the pages with the requested links
+func mockPagedService(t *testing.T, first string, pages map[string]string) *mockUqlService {
+	parse := func(response string) (parsedResponse, error) {
+		rawJson := json.RawMessage(response)
+		chunks, err := decodeChunks(rawJson)
+		return parsedResponse{chunks: chunks, rawJson: &rawJson}, err
+	}
+	return &mockUqlService{
+		executeBehavior: func(query *Query, version ApiVersion) (parsedResponse, error) {
+			return parse(first)
+		},
+		continueBehavior: func(link *Link) (parsedResponse, error) {
+			page, found := pages[link.Href]
+			if !found {
+				t.Fatalf("unexpected link %q", link.Href)
+			}
+			return parse(page)
+		},
+	}
+}
+
+func TestFetchAllPages(t *testing.T) {
+	// given
+	backend := mockPagedService(t, pageResponse("/page2", "a", "b"), map[string]string{
+		"/page2": pageResponse("/page3", "c", "d"),
+		"/page3": pageResponse("", "e"),
+	})
+	client := defaultClient{backend: backend}
+	response, err := client.ExecuteQuery(&Query{"ignored"})
+	assert.NoError(t, err)
+
+	// when
+	response, err = fetchAllPages(client, response, 0)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, [][]any{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}, response.Main().Values())
+	assert.False(t, hasNextPage(response.Main()))
+
+	var chunks []json.RawMessage
+	assert.NoError(t, json.Unmarshal([]byte(response.Raw()), &chunks))
+	assert.Len(t, chunks, 6, "raw response should have the chunks of all pages")
+}
+
+func TestFetchAllPages_WrappedChunks(t *testing.T) {
+	// given: pages with the chunks wrapped in an object, as with newer API versions
+	wrap := func(page string) string { return `{ "chunks": ` + page + ` }` }
+	backend := mockPagedService(t, wrap(pageResponse("/page2", "a")), map[string]string{
+		"/page2": wrap(pageResponse("", "b")),
+	})
+	client := defaultClient{backend: backend}
+	response, err := client.ExecuteQuery(&Query{"ignored"})
+	assert.NoError(t, err)
+
+	// when
+	response, err = fetchAllPages(client, response, 0)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, [][]any{{"a"}, {"b"}}, response.Main().Values())
+	var chunks []json.RawMessage
+	assert.NoError(t, json.Unmarshal([]byte(response.Raw()), &chunks))
+	assert.Len(t, chunks, 4)
+}
+
+func TestFetchAllPages_MaxRows(t *testing.T) {
+	// given
+	backend := mockPagedService(t, pageResponse("/page2", "a", "b"), map[string]string{
+		"/page2": pageResponse("/page3", "c", "d"),
+	})
+	client := defaultClient{backend: backend}
+	response, err := client.ExecuteQuery(&Query{"ignored"})
+	assert.NoError(t, err)
+
+	// when
+	response, err = fetchAllPages(client, response, 3)
+
+	// then: the third page is not fetched
+	assert.NoError(t, err)
+	assert.Equal(t, [][]any{{"a"}, {"b"}, {"c"}}, response.Main().Values())
+	assert.True(t, hasNextPage(response.Main()))
+}
+
+func TestFetchAllPages_PartialResult(t *testing.T) {
+	// given: the third page fails
+	backend := mockPagedService(t, pageResponse("/page2", "a", "b"), map[string]string{
+		"/page2": pageResponse("/page3", "c", "d"),
+	})
+	continuePage := backend.continueBehavior
+	backend.continueBehavior = func(link *Link) (parsedResponse, error) {
+		if link.Href == "/page3" {
+			return parsedResponse{}, errors.New("chunk failed")
+		}
+		return continuePage(link)
+	}
+	client := defaultClient{backend: backend}
+	response, err := client.ExecuteQuery(&Query{"ignored"})
+	assert.NoError(t, err)
+
+	// when
+	response, err = fetchAllPages(client, response, 0)
+
+	// then: the rows of the first two pages are kept
+	assert.NoError(t, err)
+	assert.Equal(t, [][]any{{"a"}, {"b"}, {"c"}, {"d"}}, response.Main().Values())
+	assert.Equal(t, &Error{
+		Type:   partialResultErrorType,
+		Title:  "Partial result",
+		Detail: "the results have only the 4 rows fetched before page 3 failed: chunk failed",
+	}, response.Partial())
+	assert.True(t, response.HasErrors())
+
+	result, err := transformForJsonOutput(response)
+	assert.NoError(t, err)
+	assert.Equal(t, response.Partial(), result.Partial)
+}
+
+func TestMergePage_DataSets(t *testing.T) {
+	links := func(rels ...string) map[string]Link {
+		result := map[string]Link{}
+		for i := 0; i+1 < len(rels); i += 2 {
+			result[rels[i]] = Link{Href: rels[i+1]}
+		}
+		return result
+	}
+	response := &Response{
+		mainDataSet: &DataSet{Name: mainDataSetName, Data: [][]any{{"a"}}},
+		dataSets: []*DataSet{
+			{Name: "d:entities", Data: [][]any{{"e1"}}, Links: links(selfRel, "/entities/1", nextPageRel, "/entities/2")},
+			{Name: "d:other", Data: [][]any{{"o1"}}},
+		},
+	}
+	page := &Response{
+		mainDataSet: &DataSet{Name: mainDataSetName, Data: [][]any{{"b"}}},
+		dataSets: []*DataSet{
+			// the next page of d:entities, under another name
+			{Name: "d:entities-2", Data: [][]any{{"e2"}}, Links: links(selfRel, "/entities/2")},
+			// a data set of the page with the same name as one of the first page
+			{Name: "d:other", Data: [][]any{{"o2"}}},
+		},
+	}
+
+	assert.NoError(t, mergePage(response, page))
+
+	assert.Equal(t, [][]any{{"a"}, {"b"}}, response.Main().Values())
+	assert.Len(t, response.DataSets(), 3)
+	assert.Equal(t, "d:entities", response.DataSets()[0].Name)
+	assert.Equal(t, [][]any{{"e1"}, {"e2"}}, response.DataSets()[0].Values())
+	assert.False(t, hasNextPage(response.DataSets()[0]), "the merged data set should have the links of its last page")
+	assert.Equal(t, [][]any{{"o1"}}, response.DataSets()[1].Values())
+	assert.Equal(t, [][]any{{"o2"}}, response.DataSets()[2].Values())
+}
+
+func TestContinueUntilDone_Interrupted(t *testing.T) {
+	// given: a page that is never returned
+	blocked := make(chan struct{})
+	defer close(blocked)
+	backend := &mockUqlService{
+		continueBehavior: func(link *Link) (parsedResponse, error) {
+			<-blocked
+			return parsedResponse{}, nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// when
+	dataSet := &DataSet{Links: map[string]Link{nextPageRel: {Href: "/page2"}}}
+	_, err := continueUntilDone(ctx, defaultClient{backend: backend}, dataSet)
+
+	// then
+	assert.ErrorIs(t, err, errInterrupted)
+}
+
+func TestWithPageSize(t *testing.T) {
+	query, err := withPageSize("FETCH id FROM entities(k8s:workload)", 50)
+	assert.NoError(t, err)
+	assert.Equal(t, "FETCH id FROM entities(k8s:workload)\nLIMITS topLevelItems.count(50)", query)
+
+	query, err = withPageSize("FETCH id FROM entities(k8s:workload)", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "FETCH id FROM entities(k8s:workload)", query)
+
+	_, err = withPageSize("FETCH id FROM entities(k8s:workload) limits topLevelItems.count(5)", 50)
+	assert.ErrorContains(t, err, "LIMITS clause")
+}
@@ -0,0 +1,186 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/apex/log"
+	"gopkg.in/yaml.v3"
+)
+
+// paramNameRegexp matches a parameter placeholder's name, following the "$" (and "{" for ${name})
+var paramNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*`)
+
+// bareValueRegexp matches the values that are substituted as they are for placeholders outside of
+// string literals: numbers, durations, dates and times, booleans and function calls without
+// arguments, e.g., 10, 2.5, -1h, 2023-01-13T13:57:20Z, true, now() or now()-2h
+var bareValueRegexp = regexp.MustCompile(`^(-?\d+(\.\d+)?|-?(\d+[a-z]+)+|\d{4}-\d{2}-\d{2}(T[0-9:.]+(Z|[+-]\d{2}:\d{2})?)?|true|false|[a-z]+\(\)([+-](\d+[a-z]+)+)?)$`)
+
+// parseParams parses name=value parameter bindings into the given map, overriding its values
+func parseParams(bindings []string, params map[string]string) error {
+	for _, binding := range bindings {
+		name, value, found := strings.Cut(binding, "=")
+		if !found || paramNameRegexp.FindString(name) != name {
+			return fmt.Errorf("invalid parameter %q: it must be in the form name=value, where the name has letters, digits and '_'", binding)
+		}
+		params[name] = value
+	}
+	return nil
+}
+
+// readParamsFile reads a YAML or JSON file mapping parameter names to values into the given map,
+// overriding its values
+func readParamsFile(path string, params map[string]string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read the parameters file %q: %w", path, err)
+	}
+	var values map[string]any
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("failed to parse the parameters file %q: %w", path, err)
+	}
+	for name, value := range values {
+		switch value.(type) {
+		case map[string]any, []any:
+			return fmt.Errorf("parameter %q in %q: the value must be a string, a number or a boolean", name, path)
+		case nil:
+			params[name] = ""
+		default:
+			params[name] = fmt.Sprint(value)
+		}
+	}
+	return nil
+}
+
+// queryParams returns the values of the query parameters: the defaults, overridden by the values
+// in the --params-file file, overridden by the --param flags
+func queryParams(defaults map[string]string) (map[string]string, error) {
+	params := map[string]string{}
+	for name, value := range defaults {
+		params[name] = value
+	}
+	if paramsFileFlag != "" {
+		if err := readParamsFile(paramsFileFlag, params); err != nil {
+			return nil, err
+		}
+	}
+	if err := parseParams(paramsFlag, params); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// substituteParams replaces the $name and ${name} placeholders in a query with the values of the
+// parameters. In string literals, the values are escaped for the literal. Elsewhere, numbers,
+// durations, times and function calls are substituted as they are, while other values are
+// substituted as string literals, so that a value cannot change the structure of the query.
+// A "$$" stands for a "$".
+func substituteParams(query string, params map[string]string) (string, error) {
+	var result strings.Builder
+	var quote rune // the quote of the string literal the scan is in, 0 if none
+	used := map[string]bool{}
+	missing := map[string]bool{}
+	runes := []rune(query)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0 && r == '\\' && i+1 < len(runes): // escaped character in a literal
+			result.WriteRune(r)
+			result.WriteRune(runes[i+1])
+			i++
+			continue
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case r == '$' && i+1 < len(runes) && runes[i+1] == '$':
+			result.WriteRune('$')
+			i++
+			continue
+		case r == '$':
+			name, length := placeholderAt(runes[i+1:])
+			if name == "" {
+				break // not a placeholder
+			}
+			i += length
+			value, found := params[name]
+			if !found {
+				missing[name] = true
+				continue
+			}
+			used[name] = true
+			result.WriteString(formatParam(value, quote))
+			continue
+		}
+		result.WriteRune(r)
+	}
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing value for query parameter(s): %v; use --param name=value", strings.Join(sortedNames(missing), ", "))
+	}
+	for name := range params {
+		if !used[name] {
+			log.Warnf("Parameter %q is not used in the query", name)
+		}
+	}
+	return result.String(), nil
+}
+
+// placeholderAt returns the name of the placeholder following a "$" and the number of runes it
+// takes, or an empty name if there is no placeholder
+func placeholderAt(runes []rune) (string, int) {
+	s := string(runes)
+	if strings.HasPrefix(s, "{") {
+		name := paramNameRegexp.FindString(s[1:])
+		if name == "" || !strings.HasPrefix(s[1+len(name):], "}") {
+			return "", 0
+		}
+		return name, len([]rune(name)) + 2
+	}
+	name := paramNameRegexp.FindString(s)
+	return name, len([]rune(name))
+}
+
+// formatParam formats a parameter value for a placeholder in a string literal with the given
+// quote, or outside of literals if the quote is 0
+func formatParam(value string, quote rune) string {
+	if quote != 0 {
+		return escapeString(value, quote)
+	}
+	if bareValueRegexp.MatchString(value) {
+		return value
+	}
+	return `"` + escapeString(value, '"') + `"`
+}
+
+// escapeString escapes a value for a string literal with the given quote
+func escapeString(value string, quote rune) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return strings.ReplaceAll(value, string(quote), `\`+string(quote))
+}
+
+func sortedNames(names map[string]bool) []string {
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
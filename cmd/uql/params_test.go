@@ -0,0 +1,88 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubstituteParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		params   map[string]string
+		expected string
+	}{
+		{
+			name:     "bare values",
+			query:    "FETCH id FROM entities LIMITS topLevelItems.count($count) SINCE $since UNTIL ${until}",
+			params:   map[string]string{"count": "10", "since": "-2h", "until": "now()"},
+			expected: "FETCH id FROM entities LIMITS topLevelItems.count(10) SINCE -2h UNTIL now()",
+		},
+		{
+			name:     "value in a string literal",
+			query:    `FETCH id FROM entities[attributes("service.name") = "$service"]`,
+			params:   map[string]string{"service": `cart "v2" \ shop`},
+			expected: `FETCH id FROM entities[attributes("service.name") = "cart \"v2\" \\ shop"]`,
+		},
+		{
+			name:     "value in a single-quoted string literal",
+			query:    `FETCH id FROM entities[attributes('service.name') = '${service}-prod']`,
+			params:   map[string]string{"service": "o'brien"},
+			expected: `FETCH id FROM entities[attributes('service.name') = 'o\'brien-prod']`,
+		},
+		{
+			name:     "string value outside of literals",
+			query:    `FETCH id FROM entities[attributes("service.name") = $service]`,
+			params:   map[string]string{"service": `cart"] || true`},
+			expected: `FETCH id FROM entities[attributes("service.name") = "cart\"] || true"]`,
+		},
+		{
+			name:     "escaped dollar and literal dollars",
+			query:    `FETCH id FROM entities[attributes("price") = "$$5" && attributes("x") = "\$service"]`,
+			params:   map[string]string{},
+			expected: `FETCH id FROM entities[attributes("price") = "$5" && attributes("x") = "\$service"]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := substituteParams(tt.query, tt.params)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+
+	_, err := substituteParams("FETCH id FROM entities SINCE $since UNTIL $until", map[string]string{"since": "-1h"})
+	assert.ErrorContains(t, err, "missing value for query parameter(s): until")
+}
+
+func TestQueryParams(t *testing.T) {
+	paramsFile := filepath.Join(t.TempDir(), "params.yaml")
+	assert.NoError(t, os.WriteFile(paramsFile, []byte("since: -1h\ncount: 5\nservice: cart\n"), 0o600))
+	paramsFileFlag, paramsFlag = paramsFile, []string{"count=10", "expr=a=b"}
+	defer func() { paramsFileFlag, paramsFlag = "", nil }()
+
+	params, err := queryParams(map[string]string{"since": "-2h", "until": "now()"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"since": "-1h", "until": "now()", "count": "10", "service": "cart", "expr": "a=b"}, params)
+
+	paramsFlag = []string{"bad name=x"}
+	_, err = queryParams(nil)
+	assert.ErrorContains(t, err, `invalid parameter "bad name=x"`)
+}
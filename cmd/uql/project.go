@@ -0,0 +1,204 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// projectResponse returns a response whose main data set has only the given columns, in the given
+// order, after flattening the columns matching the flatten patterns. A pattern is either
+// "<column>.*", which replaces a column of key/value pairs (e.g., attributes) with a column for
+// each key found in the rows, or "<column>.<key>", which adds a column for a single key. The
+// flattened columns are named "<column>.<key>". The response is not modified.
+func projectResponse(response *Response, columns []string, flatten []string) (*Response, error) {
+	if len(columns) == 0 && len(flatten) == 0 {
+		return response, nil
+	}
+	main := response.Main()
+	model := response.Model()
+	if main == nil || model == nil {
+		return response, nil
+	}
+
+	fields := model.Fields
+	rows := main.Data
+	for _, pattern := range flatten {
+		var err error
+		fields, rows, err = flattenColumn(fields, rows, pattern)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(columns) > 0 {
+		var err error
+		fields, rows, err = selectColumns(fields, rows, columns)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	projectedModel := &Model{Name: model.Name, Fields: fields}
+	projectedMain := *main
+	projectedMain.DataModel = projectedModel
+	projectedMain.Data = rows
+	projected := *response
+	projected.model = projectedModel
+	projected.mainDataSet = &projectedMain
+	return &projected, nil
+}
+
+// flattenColumn replaces or complements a column of key/value pairs with a column for each key,
+// as specified by a flatten pattern
+func flattenColumn(fields []ModelField, rows [][]any, pattern string) ([]ModelField, [][]any, error) {
+	column, key, found := cutFlattenPattern(fields, pattern)
+	if !found {
+		return nil, nil, fmt.Errorf("cannot flatten %q: it must be <column>.* or <column>.<key>, where <column> is one of: %v", pattern, strings.Join(fieldAliases(fields), ", "))
+	}
+
+	entries := make([]map[string]any, len(rows))
+	for r, row := range rows {
+		var err error
+		entries[r], err = keyValueEntries(row[column])
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot flatten column %q: %w", fields[column].Alias, err)
+		}
+	}
+	keys := []string{key}
+	if key == "*" {
+		keys = sortedEntryKeys(entries)
+	}
+
+	// the flattened columns replace the original column, unless a single key is extracted
+	newFields := append([]ModelField{}, fields[:column]...)
+	for _, k := range keys {
+		newFields = append(newFields, ModelField{Alias: fields[column].Alias + "." + k, Type: "object"})
+	}
+	after := column + 1
+	if key != "*" {
+		after = column
+	}
+	newFields = append(newFields, fields[after:]...)
+
+	newRows := make([][]any, len(rows))
+	for r, row := range rows {
+		newRow := append([]any{}, row[:column]...)
+		for _, k := range keys {
+			newRow = append(newRow, entries[r][k])
+		}
+		newRows[r] = append(newRow, row[after:]...)
+	}
+	return newFields, newRows, nil
+}
+
+// cutFlattenPattern returns the index of the column and the key of a flatten pattern. Column
+// aliases may contain dots, so the longest alias matching the pattern is used.
+func cutFlattenPattern(fields []ModelField, pattern string) (int, string, bool) {
+	column, key := -1, ""
+	for i, field := range fields {
+		if k, found := strings.CutPrefix(pattern, field.Alias+"."); found && k != "" {
+			if column < 0 || len(field.Alias) > len(fields[column].Alias) {
+				column, key = i, k
+			}
+		}
+	}
+	return column, key, column >= 0
+}
+
+// keyValueEntries returns the entries of a cell with key/value pairs: a JSON object, or a nested
+// data set with two columns, the key and the value
+func keyValueEntries(value any) (map[string]any, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case map[string]any:
+		return v, nil
+	case jsonObject:
+		var entries map[string]any
+		if err := json.Unmarshal(v, &entries); err != nil {
+			return nil, fmt.Errorf("the value is not a JSON object")
+		}
+		return entries, nil
+	case Complex:
+		if complexIsNil(v) {
+			return nil, nil
+		}
+		if len(v.Model().Fields) != 2 {
+			return nil, fmt.Errorf("nested data must have 2 columns, a key and a value, found %d", len(v.Model().Fields))
+		}
+		entries := map[string]any{}
+		for _, row := range v.Values() {
+			entries[fmt.Sprint(row[0])] = row[1]
+		}
+		return entries, nil
+	}
+	return nil, fmt.Errorf("the value is not a set of key/value pairs")
+}
+
+// selectColumns returns the given columns, in the given order
+func selectColumns(fields []ModelField, rows [][]any, columns []string) ([]ModelField, [][]any, error) {
+	indexes := make([]int, len(columns))
+	for i, column := range columns {
+		indexes[i] = -1
+		for f, field := range fields {
+			if field.Alias == column {
+				indexes[i] = f
+				break
+			}
+		}
+		if indexes[i] < 0 {
+			return nil, nil, fmt.Errorf("column %q not found; available columns: %v", column, strings.Join(fieldAliases(fields), ", "))
+		}
+	}
+
+	newFields := make([]ModelField, len(indexes))
+	for i, index := range indexes {
+		newFields[i] = fields[index]
+	}
+	newRows := make([][]any, len(rows))
+	for r, row := range rows {
+		newRows[r] = make([]any, len(indexes))
+		for i, index := range indexes {
+			newRows[r][i] = row[index]
+		}
+	}
+	return newFields, newRows, nil
+}
+
+func fieldAliases(fields []ModelField) []string {
+	aliases := make([]string, len(fields))
+	for i, field := range fields {
+		aliases[i] = field.Alias
+	}
+	return aliases
+}
+
+func sortedEntryKeys(entries []map[string]any) []string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, e := range entries {
+		for k := range e {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
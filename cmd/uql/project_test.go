@@ -0,0 +1,93 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// language=json
+const attributesServerResponse = `[
+  {
+	"type": "model",
+	"model": {
+	  "name": "m:main",
+	  "fields": [
+		{ "alias": "id", "type": "string" },
+		{ "alias": "type", "type": "string" },
+		{ "alias": "attributes", "type": "complex", "form": "inline", "model": {
+			"name": "m:attributes",
+			"fields": [
+			  { "alias": "name", "type": "string" },
+			  { "alias": "value", "type": "string" }
+			]
+		  }
+		}
+	  ]
+	}
+  },
+  {
+	"type": "data",
+	"model": { "$jsonPath": "", "$model": "m:main" },
+	"dataset": "d:main",
+	"data": [
+	  [ "e1", "service", [ [ "service.name", "cart" ], [ "service.namespace", "shop" ] ] ],
+	  [ "e2", "service", [ [ "service.name", "checkout" ], [ "telemetry.sdk", "go" ] ] ]
+	]
+  }
+]`
+
+func TestProjectResponse(t *testing.T) {
+	response, err := executeUqlQuery(&Query{"ignored"}, ApiVersion1, mockExecuteResponse(attributesServerResponse))
+	assert.NoError(t, err)
+
+	t.Run("columns", func(t *testing.T) {
+		projected, err := projectResponse(response, []string{"type", "id"}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"type", "id"}, fieldAliases(projected.Model().Fields))
+		assert.Equal(t, [][]any{{"service", "e1"}, {"service", "e2"}}, projected.Main().Values())
+		assert.Len(t, response.Model().Fields, 3, "the response should not be modified")
+	})
+
+	t.Run("flatten all keys", func(t *testing.T) {
+		projected, err := projectResponse(response, nil, []string{"attributes.*"})
+		assert.NoError(t, err)
+		assert.Equal(t,
+			[]string{"id", "type", "attributes.service.name", "attributes.service.namespace", "attributes.telemetry.sdk"},
+			fieldAliases(projected.Model().Fields),
+		)
+		assert.Equal(t, [][]any{
+			{"e1", "service", "cart", "shop", nil},
+			{"e2", "service", "checkout", nil, "go"},
+		}, projected.Main().Values())
+	})
+
+	t.Run("flatten a key and select columns", func(t *testing.T) {
+		projected, err := projectResponse(response, []string{"id", "attributes.service.name"}, []string{"attributes.service.name"})
+		assert.NoError(t, err)
+		assert.Equal(t, [][]any{{"e1", "cart"}, {"e2", "checkout"}}, projected.Main().Values())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := projectResponse(response, []string{"name"}, nil)
+		assert.ErrorContains(t, err, `column "name" not found; available columns: id, type, attributes`)
+		_, err = projectResponse(response, nil, []string{"labels.*"})
+		assert.ErrorContains(t, err, `cannot flatten "labels.*"`)
+		_, err = projectResponse(response, nil, []string{"id.*"})
+		assert.ErrorContains(t, err, `cannot flatten column "id"`)
+	})
+}
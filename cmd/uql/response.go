@@ -26,9 +26,13 @@ import (
 )
 
 // Response represents a parsed UQL response body
+// mainDataSetName is the name of the data set with the top-level rows of the results
+const mainDataSetName = "d:main"
+
 type Response struct {
 	model       *Model
 	mainDataSet *DataSet
+	dataSets    []*DataSet // the data sets not nested in the main data set, e.g., related entities
 	errors      []*Error
 	raw         *json.RawMessage
 }
@@ -76,6 +80,12 @@ func (resp *Response) Main() *DataSet {
 	return resp.mainDataSet
 }
 
+// DataSets returns the data sets of the response other than the main data set and the data sets
+// nested in it, e.g., related entities, in the order of the response
+func (resp *Response) DataSets() []*DataSet {
+	return resp.dataSets
+}
+
 func (resp *Response) HasErrors() bool {
 	return len(resp.errors) > 0
 }
@@ -84,6 +94,17 @@ func (resp *Response) Errors() []*Error {
 	return resp.errors
 }
 
+// Partial returns the error that made the results partial, e.g., the failure of a page of results,
+// or nil if the results are complete
+func (resp *Response) Partial() *Error {
+	for _, e := range resp.errors {
+		if e.Type == partialResultErrorType {
+			return e
+		}
+	}
+	return nil
+}
+
 func (resp *Response) Raw() string {
 	data, err := resp.raw.MarshalJSON()
 	if err != nil {
@@ -119,6 +140,7 @@ type DataSet struct {
 	Metadata  map[string]any
 	Data      [][]any
 	Links     map[string]Link
+	Errors    []*Error // the errors reported for the data set
 }
 
 func (d DataSet) Model() *Model {
@@ -152,6 +174,10 @@ type DataSetRef struct {
 	Dataset  string `json:"$dataset"`
 }
 
+// partialResultErrorType is the type of the error added to a response whose results are partial,
+// with the rows fetched before a page of results failed or fetching them was interrupted
+const partialResultErrorType = "fsoc:partial-result"
+
 type Error struct {
 	Type   string `json:"type"`
 	Title  string `json:"title"`
@@ -0,0 +1,179 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/apex/log"
+)
+
+// progressFile records the pages of the results of a query as they are fetched, so that an
+// interrupted download can be resumed from the last page fetched instead of executing the query
+// again. The file has a header line identifying the query, followed by a line with the raw JSON of
+// each page fetched.
+type progressFile struct {
+	path string
+	file *os.File
+}
+
+// queryKey identifies the query whose results are recorded, e.g., in a progress file. The query is
+// recorded before its time range is resolved, so that a relative time range, e.g., --since 2h,
+// still matches later on.
+type queryKey struct {
+	Query    string `json:"query"`
+	Since    string `json:"since,omitempty"`
+	Until    string `json:"until,omitempty"`
+	PageSize int    `json:"pageSize,omitempty"`
+}
+
+// recordingClient is a UQL client recording the next pages it fetches, e.g., in a progress file
+type recordingClient struct {
+	UqlClient
+	record func(page *Response) error
+}
+
+// openProgressFile opens the progress file at the path, creating it if it does not exist. It
+// returns the response restored from the pages already recorded, or nil if there are none.
+func openProgressFile(path string, key queryKey) (*progressFile, *Response, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return createProgressFile(path, key)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read the progress file %q: %w", path, err)
+	}
+
+	// the last line is incomplete if the download was interrupted while writing it
+	lines := bytes.Split(data, []byte("\n"))
+	complete := len(data) - len(lines[len(lines)-1])
+	lines = lines[:len(lines)-1]
+	if len(lines) == 0 {
+		return createProgressFile(path, key)
+	}
+	var recorded queryKey
+	if err := json.Unmarshal(lines[0], &recorded); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the progress file %q: %w", path, err)
+	}
+	if recorded != key {
+		return nil, nil, fmt.Errorf("the progress file %q is for a different query: %q; remove it or use another file", path, recorded.Query)
+	}
+
+	var response *Response
+	for i, line := range lines[1:] {
+		page, err := parsePage(line)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse page %d in the progress file %q: %w", i+1, path, err)
+		}
+		if response == nil {
+			response = page
+		} else if err := mergePage(response, page); err != nil {
+			return nil, nil, fmt.Errorf("failed to merge page %d in the progress file %q: %w", i+1, path, err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY, 0o600)
+	if err == nil {
+		err = file.Truncate(int64(complete))
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekEnd)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open the progress file %q: %w", path, err)
+	}
+	if response != nil {
+		log.WithFields(log.Fields{"path": path, "pages": len(lines) - 1}).Info("resuming the download of the results")
+	}
+	return &progressFile{path: path, file: file}, response, nil
+}
+
+func createProgressFile(path string, key queryKey) (*progressFile, *Response, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create the progress file %q: %w", path, err)
+	}
+	progress := &progressFile{path: path, file: file}
+	data, err := json.Marshal(key)
+	if err == nil {
+		err = progress.writeLine(data)
+	}
+	if err != nil {
+		progress.close()
+		return nil, nil, err
+	}
+	return progress, nil, nil
+}
+
+// parsePage parses the raw JSON of a page of results
+func parsePage(data []byte) (*Response, error) {
+	chunks, err := decodeChunks(data)
+	if err != nil {
+		return nil, err
+	}
+	raw := json.RawMessage(data)
+	return processResponse(parsedResponse{chunks: chunks, rawJson: &raw})
+}
+
+// record appends a page of results to the progress file
+func (p *progressFile) record(page *Response) error {
+	if page.raw == nil {
+		return fmt.Errorf("the page has no raw data to record")
+	}
+	var line bytes.Buffer
+	if err := json.Compact(&line, *page.raw); err != nil {
+		return err
+	}
+	return p.writeLine(line.Bytes())
+}
+
+func (p *progressFile) writeLine(data []byte) error {
+	if _, err := p.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write the progress file %q: %w", p.path, err)
+	}
+	return p.file.Sync()
+}
+
+// close closes the progress file, keeping it for resuming the download; it can be called again
+func (p *progressFile) close() {
+	if p.file == nil {
+		return
+	}
+	if err := p.file.Close(); err != nil {
+		log.Warnf("Failed to close the progress file %q: %v", p.path, err)
+	}
+	p.file = nil
+}
+
+// remove closes and removes the progress file, once the download is complete
+func (p *progressFile) remove() {
+	p.close()
+	if err := os.Remove(p.path); err != nil {
+		log.Warnf("Failed to remove the progress file %q: %v", p.path, err)
+	}
+}
+
+func (c recordingClient) ContinueQuery(dataSet *DataSet, rel string) (*Response, error) {
+	response, err := c.UqlClient.ContinueQuery(dataSet, rel)
+	if err != nil {
+		return nil, err
+	}
+	return response, c.record(response)
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressFile_Resume(t *testing.T) {
+	// given: a download interrupted by a failure to fetch the third page
+	path := filepath.Join(t.TempDir(), "query.progress")
+	key := queryKey{Query: "FETCH id FROM entities", Since: "2h"}
+	backend := mockPagedService(t, pageResponse("/page2", "a", "b"), map[string]string{
+		"/page2": pageResponse("/page3", "c", "d"),
+	})
+	continuePage := backend.continueBehavior
+	backend.continueBehavior = func(link *Link) (parsedResponse, error) {
+		if link.Href == "/page3" {
+			return parsedResponse{}, errors.New("connection reset")
+		}
+		return continuePage(link)
+	}
+	client := defaultClient{backend: backend}
+
+	progress, restored, err := openProgressFile(path, key)
+	assert.NoError(t, err)
+	assert.Nil(t, restored)
+	response, err := client.ExecuteQuery(&Query{"ignored"})
+	assert.NoError(t, err)
+	assert.NoError(t, progress.record(response))
+	response, err = fetchAllPages(recordingClient{UqlClient: client, record: progress.record}, response, 0)
+	assert.NoError(t, err)
+	assert.NotNil(t, response.Partial())
+	progress.close()
+
+	// when: the download is resumed, with a partially written line at the end of the file
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	assert.NoError(t, err)
+	_, err = file.WriteString(`[{"type": "mod`)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+
+	progress, restored, err = openProgressFile(path, key)
+	assert.NoError(t, err)
+	defer progress.close()
+	resumed := defaultClient{backend: mockPagedService(t, "", map[string]string{"/page3": pageResponse("", "e")})}
+	response, err = fetchAllPages(recordingClient{UqlClient: resumed, record: progress.record}, restored, 0)
+
+	// then: only the third page is fetched
+	assert.NoError(t, err)
+	assert.Equal(t, [][]any{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}, response.Main().Values())
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, strings.Count(string(data), "\n"), "the header and the three pages should be recorded")
+
+	progress.remove()
+	assert.NoFileExists(t, path)
+}
+
+func TestProgressFile_DifferentQuery(t *testing.T) {
+	// given
+	path := filepath.Join(t.TempDir(), "query.progress")
+	progress, _, err := openProgressFile(path, queryKey{Query: "FETCH id FROM entities"})
+	assert.NoError(t, err)
+	progress.close()
+
+	// when
+	_, _, err = openProgressFile(path, queryKey{Query: "FETCH id FROM entities", PageSize: 10})
+
+	// then
+	assert.ErrorContains(t, err, "different query")
+}
@@ -0,0 +1,293 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+
+	"github.com/cisco-open/fsoc/config"
+	fsoc "github.com/cisco-open/fsoc/output"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+// schemaCacheTTL is how long the schema fetched from the knowledge store is cached
+const schemaCacheTTL = 24 * time.Hour
+
+// schemaObjectTypes are the knowledge object types of the schema, by kind
+var schemaObjectTypes = map[string]string{
+	"entities": "fmm:entity",
+	"metrics":  "fmm:metric",
+	"events":   "fmm:event",
+}
+
+// SchemaType is an entity, metric or event type that can be queried
+type SchemaType struct {
+	Name        string            `json:"name" yaml:"name"` // fully qualified, e.g., k8s:workload
+	Namespace   string            `json:"namespace" yaml:"namespace"`
+	DisplayName string            `json:"displayName,omitempty" yaml:"displayName,omitempty"`
+	Unit        string            `json:"unit,omitempty" yaml:"unit,omitempty"`               // metrics only
+	ContentType string            `json:"contentType,omitempty" yaml:"contentType,omitempty"` // metrics only
+	ValueType   string            `json:"valueType,omitempty" yaml:"valueType,omitempty"`     // metrics only
+	MetricTypes []string          `json:"metricTypes,omitempty" yaml:"metricTypes,omitempty"` // entities only
+	EventTypes  []string          `json:"eventTypes,omitempty" yaml:"eventTypes,omitempty"`   // entities only
+	Attributes  []SchemaAttribute `json:"attributes" yaml:"attributes"`
+}
+
+// SchemaAttribute is an attribute of an entity, metric or event type
+type SchemaAttribute struct {
+	Name        string `json:"name" yaml:"name"`
+	Type        string `json:"type" yaml:"type"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// fmmObject has the fields of the FMM entity, metric and event definitions used in the schema
+type fmmObject struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+	Namespace   struct {
+		Name string `json:"name"`
+	} `json:"namespace"`
+	Unit                 string   `json:"unit"`
+	ContentType          string   `json:"contentType"`
+	Type                 string   `json:"type"`
+	MetricTypes          []string `json:"metricTypes"`
+	EventTypes           []string `json:"eventTypes"`
+	AttributeDefinitions *struct {
+		Attributes map[string]struct {
+			Type        string `json:"type"`
+			Description string `json:"description"`
+		} `json:"attributes"`
+	} `json:"attributeDefinitions"`
+}
+
+// schemaCacheFile is the content of the file caching the schema types of a kind
+type schemaCacheFile struct {
+	Fetched time.Time    `json:"fetched"`
+	Types   []SchemaType `json:"types"`
+}
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema {entities|metrics|events} [<type>]",
+	Short: "List the entity, metric and event types that can be queried",
+	Long: `List the entity, metric or event types that can be queried, with their attributes and, for metrics, their
+units. With a type name, e.g., k8s:workload, list the attributes of that type.
+
+The types are defined in the tenant's knowledge store and cached for a day in the fsoc directory of the user's
+cache directory; use --refresh to fetch them again, e.g., after installing a solution.`,
+	Example: `  fsoc uql schema entities
+  fsoc uql schema metrics --namespace k8s
+  fsoc uql schema entities k8s:workload
+  fsoc uql schema events -o yaml --refresh`,
+	ValidArgs: []string{"entities", "metrics", "events"},
+	Args:      cobra.MatchAll(cobra.RangeArgs(1, 2), validSchemaKind),
+	RunE:      showSchema,
+}
+
+func init() {
+	schemaCmd.Flags().String("namespace", "", "List only the types of a namespace, e.g., k8s")
+	schemaCmd.Flags().Bool("refresh", false, "Fetch the types from the knowledge store instead of the cache")
+	uqlCmd.AddCommand(schemaCmd)
+}
+
+func validSchemaKind(cmd *cobra.Command, args []string) error {
+	if _, found := schemaObjectTypes[args[0]]; !found {
+		return fmt.Errorf("invalid kind %q: must be one of entities, metrics or events", args[0])
+	}
+	return nil
+}
+
+func showSchema(cmd *cobra.Command, args []string) error {
+	kind := args[0]
+	namespace, _ := cmd.Flags().GetString("namespace")
+	refresh, _ := cmd.Flags().GetBool("refresh")
+	types, err := loadSchema(kind, refresh)
+	if err != nil {
+		return err
+	}
+
+	if len(args) > 1 {
+		for _, t := range types {
+			if t.Name == args[1] {
+				printSchemaAttributes(cmd, t)
+				return nil
+			}
+		}
+		return fmt.Errorf("no %v type named %q; use \"fsoc uql schema %v\" to list the types", kind, args[1], kind)
+	}
+
+	if namespace != "" {
+		var filtered []SchemaType
+		for _, t := range types {
+			if t.Namespace == namespace {
+				filtered = append(filtered, t)
+			}
+		}
+		types = filtered
+	}
+	printSchemaTypes(cmd, kind, types)
+	return nil
+}
+
+func printSchemaTypes(cmd *cobra.Command, kind string, types []SchemaType) {
+	var headers []string
+	switch kind {
+	case "metrics":
+		headers = []string{"Type", "Unit", "Content Type", "Value Type", "Attributes"}
+	default:
+		headers = []string{"Type", "Display Name", "Attributes"}
+	}
+	lines := make([][]string, len(types))
+	for i, t := range types {
+		attributes := make([]string, len(t.Attributes))
+		for j, a := range t.Attributes {
+			attributes[j] = a.Name
+		}
+		if kind == "metrics" {
+			lines[i] = []string{t.Name, t.Unit, t.ContentType, t.ValueType, strings.Join(attributes, ", ")}
+		} else {
+			lines[i] = []string{t.Name, t.DisplayName, strings.Join(attributes, ", ")}
+		}
+	}
+	fsoc.PrintCmdOutputCustom(cmd, struct {
+		Items []SchemaType `json:"items"`
+		Total int          `json:"total"`
+	}{types, len(types)}, &fsoc.Table{Headers: headers, Lines: lines})
+}
+
+func printSchemaAttributes(cmd *cobra.Command, t SchemaType) {
+	lines := make([][]string, len(t.Attributes))
+	for i, a := range t.Attributes {
+		lines[i] = []string{a.Name, a.Type, a.Description}
+	}
+	fsoc.PrintCmdOutputCustom(cmd, t, &fsoc.Table{Headers: []string{"Attribute", "Type", "Description"}, Lines: lines})
+}
+
+// loadSchema returns the types of a kind (entities, metrics or events), sorted by name, from the
+// cache unless it is stale or refresh is true
+func loadSchema(kind string, refresh bool) ([]SchemaType, error) {
+	cfg := config.GetCurrentContext()
+	if cfg == nil {
+		return nil, fmt.Errorf("no current profile")
+	}
+	path, err := schemaCachePath(cfg.Tenant, kind)
+	if err != nil {
+		log.Warnf("The schema will not be cached: %v", err)
+	}
+	if path != "" && !refresh {
+		if cached, err := readSchemaCache(path); err != nil {
+			log.Warnf("Failed to read the schema cache, ignoring it: %v", err)
+		} else if cached != nil && time.Since(cached.Fetched) < schemaCacheTTL {
+			log.WithFields(log.Fields{"kind": kind, "path": path}).Info("using cached schema")
+			return cached.Types, nil
+		}
+	}
+
+	types, err := fetchSchema(cfg.Tenant, schemaObjectTypes[kind])
+	if err != nil {
+		return nil, err
+	}
+	if path != "" {
+		if err := writeSchemaCache(path, schemaCacheFile{Fetched: time.Now(), Types: types}); err != nil {
+			log.Warnf("Failed to cache the schema: %v", err)
+		}
+	}
+	return types, nil
+}
+
+// fetchSchema fetches the definitions of an FMM object type, e.g., fmm:entity, from the tenant's
+// knowledge store
+func fetchSchema(tenant string, objType string) ([]SchemaType, error) {
+	headers := map[string]string{"layer-type": "TENANT", "layer-id": tenant}
+	var res api.CollectionResult[struct {
+		Data fmmObject `json:"data"`
+	}]
+	if err := api.JSONGetCollection("knowledge-store/v1/objects/"+url.PathEscape(objType), &res, &api.Options{Headers: headers}); err != nil {
+		return nil, fmt.Errorf("failed to fetch the %v definitions: %w", objType, err)
+	}
+	types := make([]SchemaType, 0, len(res.Items))
+	for _, item := range res.Items {
+		types = append(types, makeSchemaType(item.Data))
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+	return types, nil
+}
+
+func makeSchemaType(obj fmmObject) SchemaType {
+	t := SchemaType{
+		Name:        obj.Namespace.Name + ":" + obj.Name,
+		Namespace:   obj.Namespace.Name,
+		DisplayName: obj.DisplayName,
+		Unit:        obj.Unit,
+		ContentType: obj.ContentType,
+		ValueType:   obj.Type,
+		MetricTypes: obj.MetricTypes,
+		EventTypes:  obj.EventTypes,
+		Attributes:  []SchemaAttribute{},
+	}
+	if obj.AttributeDefinitions != nil {
+		for name, attr := range obj.AttributeDefinitions.Attributes {
+			t.Attributes = append(t.Attributes, SchemaAttribute{Name: name, Type: attr.Type, Description: attr.Description})
+		}
+	}
+	sort.Slice(t.Attributes, func(i, j int) bool { return t.Attributes[i].Name < t.Attributes[j].Name })
+	return t
+}
+
+// schemaCachePath returns the path of the file caching the types of a kind for a tenant
+func schemaCachePath(tenant string, kind string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "fsoc", "uql_schema", url.PathEscape(tenant), kind+".json"), nil
+}
+
+// readSchemaCache reads a schema cache file; it returns nil if the file does not exist
+func readSchemaCache(path string) (*schemaCacheFile, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cached schemaCacheFile
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %w", path, err)
+	}
+	return &cached, nil
+}
+
+func writeSchemaCache(path string, cached schemaCacheFile) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
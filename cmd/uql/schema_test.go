@@ -0,0 +1,78 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeSchemaType(t *testing.T) {
+	// given
+	var obj fmmObject
+	// language=json
+	err := json.Unmarshal([]byte(`{
+	  "namespace": { "name": "k8s", "version": 1 },
+	  "kind": "metric",
+	  "name": "cpu_usage",
+	  "unit": "{cores}",
+	  "contentType": "gauge",
+	  "type": "double",
+	  "attributeDefinitions": {
+	    "optimized": [],
+	    "attributes": {
+	      "k8s.pod.name": { "type": "string", "description": "Pod name" },
+	      "k8s.container.name": { "type": "string" }
+	    }
+	  }
+	}`), &obj)
+	assert.NoError(t, err)
+
+	// when
+	schemaType := makeSchemaType(obj)
+
+	// then
+	assert.Equal(t, SchemaType{
+		Name:        "k8s:cpu_usage",
+		Namespace:   "k8s",
+		Unit:        "{cores}",
+		ContentType: "gauge",
+		ValueType:   "double",
+		Attributes: []SchemaAttribute{
+			{Name: "k8s.container.name", Type: "string"},
+			{Name: "k8s.pod.name", Type: "string", Description: "Pod name"},
+		},
+	}, schemaType)
+}
+
+func TestSchemaCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "uql_schema", "tenant", "entities.json")
+
+	cached, err := readSchemaCache(path)
+	assert.NoError(t, err)
+	assert.Nil(t, cached)
+
+	fetched := time.Date(2024, 1, 13, 14, 0, 0, 0, time.UTC)
+	types := []SchemaType{{Name: "k8s:workload", Namespace: "k8s", Attributes: []SchemaAttribute{}}}
+	assert.NoError(t, writeSchemaCache(path, schemaCacheFile{Fetched: fetched, Types: types}))
+
+	cached, err = readSchemaCache(path)
+	assert.NoError(t, err)
+	assert.Equal(t, &schemaCacheFile{Fetched: fetched, Types: types}, cached)
+}
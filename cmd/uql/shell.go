@@ -0,0 +1,334 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// maxShellHistory is the number of queries kept in the shell's history file
+const maxShellHistory = 1000
+
+// shellKeywords are the UQL keywords and functions offered as completions in the shell
+var shellKeywords = []string{
+	"FETCH", "FROM", "SINCE", "UNTIL", "LIMITS", "ORDER",
+	"entities", "events", "metrics", "spans", "attributes", "associations", "isActive",
+	"topLevelItems", "count", "asc", "desc", "now", "id", "type", "timestamp", "raw",
+}
+
+const shellHelp = `Enter a UQL query, over multiple lines if needed, and end it with a semicolon (or an empty line) to execute it.
+Commands:
+  \o FORMAT     set the output format (` + availableFormats + `)
+  \since TIME   set the start of the time range of queries without their own, e.g., 15m, 3d or 2024-01-13T13:57:20Z;
+                without a value, use the default time range
+  \until TIME   set the end of the time range of queries without their own; without a value, up to now
+  \timing       toggle the display of the number of rows and execution time of each query
+  \help         display this help
+  \quit         exit the shell (or Ctrl-D)
+Keys: arrows to move and browse the history, Tab to complete keywords, entity types and metrics, Ctrl-C
+to discard the query being entered.`
+
+var shellCmd = &cobra.Command{
+	Use:   "shell",
+	Short: "Interactive UQL shell",
+	Long: `Start an interactive shell for executing UQL queries, with a persistent history of queries, multi-line
+queries, tab completion of keywords, entity types and metrics, and the timing of each query.
+
+` + shellHelp + `
+
+The uql flags, e.g., --output, --since and --max-rows, set the initial settings of the shell. The history is kept in the
+fsoc directory of the user's cache directory.`,
+	Example: `  fsoc uql shell
+  fsoc uql shell -o json`,
+	Args: cobra.NoArgs,
+	RunE: uqlShell,
+}
+
+// shell is the state of an interactive UQL shell
+type shell struct {
+	cmd         *cobra.Command
+	since       string
+	until       string
+	timing      bool
+	completions []string // loaded on first use
+}
+
+func init() {
+	uqlCmd.AddCommand(shellCmd)
+}
+
+func uqlShell(cmd *cobra.Command, args []string) error {
+	if _, err := outputFormat(outputFlag, rawFlag); err != nil {
+		return err
+	}
+	sh := &shell{cmd: cmd, since: sinceFlag, until: untilFlag, timing: true}
+	historyPath, err := shellHistoryPath()
+	if err != nil {
+		log.Warnf("The shell history will not be saved: %v", err)
+	}
+
+	// read lines with the line editor if the input is a terminal, as they are otherwise
+	var readLine func(prompt string) (string, error)
+	var editor *lineEditor
+	stdin, isFile := cmd.InOrStdin().(*os.File)
+	if isFile && term.IsTerminal(int(stdin.Fd())) {
+		editor = newLineEditor(stdin, cmd.OutOrStdout(), loadShellHistory(historyPath), sh.complete)
+		readLine = func(prompt string) (string, error) {
+			state, err := term.MakeRaw(int(stdin.Fd()))
+			if err != nil {
+				return "", err
+			}
+			defer func() { _ = term.Restore(int(stdin.Fd()), state) }()
+			return editor.readLine(prompt)
+		}
+		cmd.Println(`UQL shell; enter \help for help, \quit to exit`)
+	} else {
+		scanner := bufio.NewScanner(cmd.InOrStdin())
+		readLine = func(prompt string) (string, error) {
+			if !scanner.Scan() {
+				if scanner.Err() != nil {
+					return "", scanner.Err()
+				}
+				return "", io.EOF
+			}
+			return scanner.Text(), nil
+		}
+	}
+
+	var lines []string // lines of the query being entered
+	for {
+		prompt := "uql> "
+		if len(lines) > 0 {
+			prompt = "...> "
+		}
+		line, err := readLine(prompt)
+		if errors.Is(err, errInterrupted) {
+			lines = nil
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if len(lines) == 0 && strings.HasPrefix(trimmed, `\`) {
+			if sh.runCommand(trimmed) {
+				break
+			}
+			continue
+		}
+		if trimmed != "" {
+			lines = append(lines, line)
+		}
+		if len(lines) == 0 || (trimmed != "" && !strings.HasSuffix(trimmed, ";")) {
+			continue // the query continues on the next line
+		}
+
+		text := strings.Join(lines, "\n")
+		lines = nil
+		if editor != nil {
+			entry := strings.Join(strings.Fields(text), " ")
+			editor.addHistory(entry)
+			appendShellHistory(historyPath, entry)
+		}
+		for _, statement := range splitStatements(text) {
+			sh.execute(statement.query)
+		}
+	}
+	return nil
+}
+
+// runCommand runs a shell command, returning true if the shell must exit
+func (sh *shell) runCommand(line string) bool {
+	command, value, _ := strings.Cut(line, " ")
+	value = strings.TrimSpace(value)
+	switch command {
+	case `\q`, `\quit`:
+		return true
+	case `\?`, `\h`, `\help`:
+		sh.cmd.Println(shellHelp)
+	case `\o`, `\output`:
+		if _, err := outputFormat(value, false); err != nil {
+			sh.cmd.PrintErrln(err.Error())
+			break
+		}
+		outputFlag, rawFlag = value, false
+	case `\since`, `\until`:
+		if value != "" {
+			if _, err := resolveTime(value, time.Now()); err != nil {
+				sh.cmd.PrintErrln(err.Error())
+				break
+			}
+		}
+		if command == `\since` {
+			sh.since = value
+		} else {
+			sh.until = value
+		}
+	case `\timing`:
+		sh.timing = !sh.timing
+		sh.cmd.Printf("Timing is %v\n", map[bool]string{true: "on", false: "off"}[sh.timing])
+	default:
+		sh.cmd.PrintErrf("Unknown command %v; enter \\help for help\n", command)
+	}
+	return false
+}
+
+// execute executes a query and prints its results; errors are printed, not fatal
+func (sh *shell) execute(query string) {
+	output, err := outputFormat(outputFlag, rawFlag)
+	if err == nil {
+		err = checkQuery(query)
+	}
+	if err == nil {
+		query, err = withTimeRange(query, sh.since, sh.until, time.Now())
+	}
+	if err == nil {
+		query, err = withPageSize(query, pageSizeFlag)
+	}
+	if err != nil {
+		sh.cmd.PrintErrf("Error: %v\n", err)
+		return
+	}
+
+	start := time.Now()
+	response, err := runQuery(query)
+	if err == nil {
+		response, err = fetchAllPages(Client, response, maxRowsFlag)
+	}
+	elapsed := time.Since(start)
+	if err != nil {
+		if problem, ok := err.(uqlProblem); ok {
+			printProblemDescription(sh.cmd, problem, query)
+		} else {
+			sh.cmd.PrintErrf("Error: %v\n", err)
+		}
+		return
+	}
+	for _, e := range response.Errors() {
+		log.Errorf("%s: %s", e.Title, e.Detail)
+	}
+	if err := printResponse(sh.cmd, response, output); err != nil {
+		sh.cmd.PrintErrf("Error: %v\n", err)
+	}
+	if sh.timing {
+		rows := 0
+		if response.Main() != nil {
+			rows = len(response.Main().Data)
+		}
+		sh.cmd.Printf("(%d rows in %v)\n", rows, elapsed.Round(time.Millisecond))
+	}
+}
+
+// complete returns the keywords, entity types and metrics starting with the word, ignoring case
+func (sh *shell) complete(word string) []string {
+	if word == "" {
+		return nil
+	}
+	if sh.completions == nil {
+		sh.completions = append(append([]string{}, shellKeywords...), fetchSchemaNames()...)
+	}
+	var matches []string
+	for _, completion := range sh.completions {
+		if strings.HasPrefix(strings.ToLower(completion), strings.ToLower(word)) {
+			matches = append(matches, completion)
+		}
+	}
+	return matches
+}
+
+// fetchSchemaNames returns the names of the entity types and metrics, e.g., k8s:workload, for
+// completions; failures are logged, as completions are a convenience
+func fetchSchemaNames() []string {
+	names := []string{}
+	for _, kind := range []string{"entities", "metrics"} {
+		types, err := loadSchema(kind, false)
+		if err != nil {
+			log.Warnf("Failed to load the %v for completions: %v", kind, err)
+			continue
+		}
+		for _, t := range types {
+			names = append(names, t.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// shellHistoryPath returns the path of the shell's history file
+func shellHistoryPath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "fsoc", "uql_history"), nil
+}
+
+// loadShellHistory returns the latest queries in the history file, trimming it if it is too long
+func loadShellHistory(path string) []string {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Warnf("Failed to read the shell history: %v", err)
+		}
+		return nil
+	}
+	history := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(history) > maxShellHistory {
+		history = history[len(history)-maxShellHistory:]
+		if err := os.WriteFile(path, []byte(strings.Join(history, "\n")+"\n"), 0o600); err != nil {
+			log.Warnf("Failed to trim the shell history: %v", err)
+		}
+	}
+	return history
+}
+
+// appendShellHistory adds a query to the history file
+func appendShellHistory(path string, entry string) {
+	if path == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		log.Warnf("Failed to save the shell history: %v", err)
+		return
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		log.Warnf("Failed to save the shell history: %v", err)
+		return
+	}
+	defer file.Close()
+	if _, err := fmt.Fprintln(file, entry); err != nil {
+		log.Warnf("Failed to save the shell history: %v", err)
+	}
+}
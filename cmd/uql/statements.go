@@ -0,0 +1,89 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// stdinFileName is the file name that stands for the standard input
+const stdinFileName = "-"
+
+// statement is a query in a file with one or more queries
+type statement struct {
+	query string
+	line  int // line number in the file where the query starts
+}
+
+// readQueryFile reads the text of a query file, or of the standard input for "-"
+func readQueryFile(path string, stdin io.Reader) (string, error) {
+	var data []byte
+	var err error
+	if path == stdinFileName {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read the query from %q: %w", path, err)
+	}
+	return string(data), nil
+}
+
+// splitStatements splits the text of a query file into statements separated by semicolons outside
+// of string literals; empty statements are skipped
+func splitStatements(text string) []statement {
+	var statements []statement
+	var current strings.Builder
+	var quote rune // the quote of the string literal the scan is in, 0 if none
+	line, startLine := 1, 1
+	add := func() {
+		query := strings.TrimSpace(current.String())
+		if query != "" {
+			// start at the line of the first non-blank character
+			startLine += strings.Count(current.String()[:strings.Index(current.String(), query)], "\n")
+			statements = append(statements, statement{query: query, line: startLine})
+		}
+		current.Reset()
+		startLine = line
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0 && r == '\\' && i+1 < len(runes):
+			current.WriteRune(r)
+			i++
+			r = runes[i]
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case quote == 0 && r == ';':
+			add()
+			continue
+		}
+		if r == '\n' {
+			line++
+		}
+		current.WriteRune(r)
+	}
+	add()
+	return statements
+}
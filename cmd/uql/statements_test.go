@@ -0,0 +1,49 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStatements(t *testing.T) {
+	text := `FETCH id
+FROM entities(k8s:workload);
+
+FETCH id FROM entities[attributes("name") = "a;b\";c"];
+  FETCH id FROM entities[attributes('x') = ';'] ;
+;
+`
+	assert.Equal(t, []statement{
+		{query: "FETCH id\nFROM entities(k8s:workload)", line: 1},
+		{query: `FETCH id FROM entities[attributes("name") = "a;b\";c"]`, line: 4},
+		{query: `FETCH id FROM entities[attributes('x') = ';']`, line: 5},
+	}, splitStatements(text))
+
+	assert.Equal(t, []statement{{query: "FETCH id FROM entities", line: 2}}, splitStatements("\nFETCH id FROM entities\n"))
+	assert.Empty(t, splitStatements(" ;\n"))
+}
+
+func TestReadQueryFile(t *testing.T) {
+	text, err := readQueryFile(stdinFileName, strings.NewReader("FETCH id FROM entities"))
+	assert.NoError(t, err)
+	assert.Equal(t, "FETCH id FROM entities", text)
+
+	_, err = readQueryFile("missing.uql", nil)
+	assert.ErrorContains(t, err, `failed to read the query from "missing.uql"`)
+}
@@ -0,0 +1,432 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// The syntax of UQL queries is checked locally, before a query is sent, so that syntax errors are
+// reported at once, with their position. The grammar is deliberately lenient: it checks the
+// structure of the clauses and of the expressions in them, while the backend checks their meaning,
+// e.g., the names of types and functions. In EBNF:
+//
+//	query      = clause { clause } ;  (* FETCH is required, each clause may appear once *)
+//	clause     = "FETCH" fields | "FROM" expression | "SINCE" expression | "UNTIL" expression
+//	           | "LIMITS" expression { "," expression } | "ORDER" expression { "," expression } ;
+//	fields     = field { "," field } ;
+//	field      = "*" | [ identifier ":" ] expression ;
+//	expression = and { ( "||" | "OR" ) and } ;
+//	and        = comparison { ( "&&" | "AND" ) comparison } ;
+//	comparison = sum [ ( "=" | "!=" | "<>" | "<" | "<=" | ">" | ">=" | "~" | "!~" | "IN" ) sum ] ;
+//	sum        = product { ( "+" | "-" ) product } ;
+//	product    = unary { ( "*" | "/" | "%" ) unary } ;
+//	unary      = ( "!" | "NOT" | "-" ) unary | postfix ;
+//	postfix    = primary { "(" [ expression { "," expression } ] ")" | "[" expression "]"
+//	           | "{" fields "}" | "." identifier } ;
+//	primary    = identifier | string | number | duration | timestamp | parameter
+//	           | "(" expression ")" | "[" [ expression { "," expression } ] "]" ;
+//
+// Identifiers may be qualified by a namespace, e.g., k8s:workload; keywords are case-insensitive.
+// Parameters are $name or ${name}, as substituted by --param.
+
+// queryClauses are the clauses of a UQL query
+var queryClauses = []string{"FETCH", "FROM", "SINCE", "UNTIL", "LIMITS", "ORDER"}
+
+// numberLiteralRegexp matches numbers, e.g., 5 or 0.25, durations, e.g., 2h or 1h30m, and
+// timestamps, e.g., 2024-01-13 or 2024-01-13T13:57:20.123+01:00
+var numberLiteralRegexp = regexp.MustCompile(`^(\d+(\.\d+)?([eE][+-]?\d+)?|(\d+[a-zA-Z]+)+|\d{4}-\d{2}-\d{2}(T\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}:?\d{2})?)?)$`)
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenIdentifier
+	tokenString
+	tokenNumber
+	tokenParameter
+	tokenSymbol
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	offset int // offset of the token in the query, in runes
+}
+
+// syntaxError is a syntax error in a query, with its position and the line of the query it is on
+type syntaxError struct {
+	line    int // starting with 1
+	column  int // starting with 1
+	message string
+	source  string // the line of the query with the error
+}
+
+func (e *syntaxError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.line, e.column, e.message)
+}
+
+// describe returns the error followed by the line of the query with a caret under the error
+func (e *syntaxError) describe() string {
+	var indent strings.Builder
+	for i, r := range []rune(e.source) {
+		if i >= e.column-1 {
+			break
+		}
+		if r == '\t' {
+			indent.WriteRune('\t')
+		} else {
+			indent.WriteRune(' ')
+		}
+	}
+	return fmt.Sprintf("%v\n  %s\n  %s^", e, e.source, indent.String())
+}
+
+// validateQuery checks the syntax of a query; it returns a *syntaxError if the query is invalid
+func validateQuery(query string) error {
+	p := &parser{query: []rune(query)}
+	if err := p.lex(); err != nil {
+		return err
+	}
+	return p.parseQuery()
+}
+
+type parser struct {
+	query  []rune
+	tokens []token
+	pos    int
+}
+
+func (p *parser) errorAt(offset int, format string, args ...any) *syntaxError {
+	line, start := 1, 0
+	for i := 0; i < offset && i < len(p.query); i++ {
+		if p.query[i] == '\n' {
+			line++
+			start = i + 1
+		}
+	}
+	end := start
+	for end < len(p.query) && p.query[end] != '\n' {
+		end++
+	}
+	return &syntaxError{
+		line:    line,
+		column:  offset - start + 1,
+		message: fmt.Sprintf(format, args...),
+		source:  string(p.query[start:end]),
+	}
+}
+
+// lex splits the query into tokens
+func (p *parser) lex() error {
+	q := p.query
+	for i := 0; i < len(q); {
+		r := q[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '"' || r == '\'':
+			for i++; i < len(q) && q[i] != r; i++ {
+				if q[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(q) {
+				return p.errorAt(start, "unterminated string literal")
+			}
+			i++
+			p.tokens = append(p.tokens, token{kind: tokenString, text: string(q[start:i]), offset: start})
+			continue
+		case isIdentifierStart(r):
+			for i < len(q) && (isIdentifierPart(q[i]) || q[i] == ':' && i+1 < len(q) && isIdentifierPart(q[i+1])) {
+				i++
+			}
+			p.tokens = append(p.tokens, token{kind: tokenIdentifier, text: string(q[start:i]), offset: start})
+			continue
+		case unicode.IsDigit(r):
+			for i < len(q) && (isIdentifierPart(q[i]) || q[i] == '.' || q[i] == ':' ||
+				(q[i] == '-' || q[i] == '+') && i+1 < len(q) && unicode.IsDigit(q[i+1]) && isTimestampPrefix(q[start:i])) {
+				i++
+			}
+			text := string(q[start:i])
+			if !numberLiteralRegexp.MatchString(text) {
+				return p.errorAt(start, "invalid number, duration or time %q", text)
+			}
+			p.tokens = append(p.tokens, token{kind: tokenNumber, text: text, offset: start})
+			continue
+		case r == '$':
+			i++
+			if i < len(q) && q[i] == '{' {
+				for i < len(q) && q[i] != '}' {
+					i++
+				}
+				i++
+			} else {
+				for i < len(q) && isIdentifierPart(q[i]) {
+					i++
+				}
+			}
+			if i > len(q) || i == start+1 {
+				return p.errorAt(start, "invalid parameter")
+			}
+			p.tokens = append(p.tokens, token{kind: tokenParameter, text: string(q[start:i]), offset: start})
+			continue
+		}
+
+		// symbols, with the two-character ones first
+		if i+1 < len(q) {
+			if pair := string(q[i : i+2]); slices.Contains([]string{"&&", "||", "!=", "<>", "<=", ">=", "!~"}, pair) {
+				p.tokens = append(p.tokens, token{kind: tokenSymbol, text: pair, offset: start})
+				i += 2
+				continue
+			}
+		}
+		if !strings.ContainsRune("()[]{},.:=<>~!+-*/%", r) {
+			return p.errorAt(start, "unexpected character %q", r)
+		}
+		p.tokens = append(p.tokens, token{kind: tokenSymbol, text: string(r), offset: start})
+		i++
+	}
+	p.tokens = append(p.tokens, token{kind: tokenEnd, offset: len(q)})
+	return nil
+}
+
+func isIdentifierStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_'
+}
+
+func isIdentifierPart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// isTimestampPrefix returns whether the text scanned so far is the start of a timestamp, where a
+// "-" or "+" is part of the literal
+func isTimestampPrefix(text []rune) bool {
+	return len(text) >= 4 && strings.IndexFunc(string(text[:4]), func(r rune) bool { return !unicode.IsDigit(r) }) < 0
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEnd {
+		p.pos++
+	}
+	return t
+}
+
+// isSymbol returns whether the next token is one of the symbols
+func (p *parser) isSymbol(symbols ...string) bool {
+	t := p.peek()
+	return t.kind == tokenSymbol && slices.Contains(symbols, t.text)
+}
+
+// isKeyword returns whether the next token is one of the keywords, ignoring case
+func (p *parser) isKeyword(keywords ...string) bool {
+	t := p.peek()
+	return t.kind == tokenIdentifier && slices.Contains(keywords, strings.ToUpper(t.text))
+}
+
+func (p *parser) expect(symbol string, context string) error {
+	if !p.isSymbol(symbol) {
+		return p.unexpected(fmt.Sprintf("%q %s", symbol, context))
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) unexpected(expected string) *syntaxError {
+	t := p.peek()
+	if t.kind == tokenEnd {
+		return p.errorAt(t.offset, "unexpected end of the query, expected %s", expected)
+	}
+	return p.errorAt(t.offset, "unexpected %q, expected %s", t.text, expected)
+}
+
+func (p *parser) parseQuery() error {
+	if p.peek().kind == tokenEnd {
+		return p.errorAt(0, "the query is empty")
+	}
+	seen := map[string]bool{}
+	for p.peek().kind != tokenEnd {
+		t := p.peek()
+		clause := strings.ToUpper(t.text)
+		if t.kind != tokenIdentifier || !slices.Contains(queryClauses, clause) {
+			return p.unexpected("a clause: " + strings.Join(queryClauses, ", "))
+		}
+		if seen[clause] {
+			return p.errorAt(t.offset, "the query has more than one %v clause", clause)
+		}
+		seen[clause] = true
+		p.next()
+
+		var err error
+		switch clause {
+		case "FETCH":
+			err = p.parseFields()
+		case "LIMITS", "ORDER":
+			err = p.parseList(p.parseExpression)
+		default:
+			err = p.parseExpression()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if !seen["FETCH"] {
+		return p.errorAt(0, "the query has no FETCH clause")
+	}
+	return nil
+}
+
+func (p *parser) parseList(parse func() error) error {
+	for {
+		if err := parse(); err != nil {
+			return err
+		}
+		if !p.isSymbol(",") {
+			return nil
+		}
+		p.next()
+	}
+}
+
+func (p *parser) parseFields() error {
+	return p.parseList(p.parseField)
+}
+
+func (p *parser) parseField() error {
+	if p.isSymbol("*") {
+		p.next()
+		return nil
+	}
+	// an alias, e.g., name: attributes("k8s.workload.name")
+	if p.peek().kind == tokenIdentifier && p.tokens[p.pos+1].kind == tokenSymbol && p.tokens[p.pos+1].text == ":" {
+		p.pos += 2
+	}
+	return p.parseExpression()
+}
+
+func (p *parser) parseExpression() error {
+	return p.parseBinary(0)
+}
+
+// binaryOperators are the binary operators by precedence, lowest first; keywords are upper case
+var binaryOperators = [][]string{
+	{"||", "OR"},
+	{"&&", "AND"},
+	{"=", "!=", "<>", "<", "<=", ">", ">=", "~", "!~", "IN"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *parser) parseBinary(level int) error {
+	if level == len(binaryOperators) {
+		return p.parseUnary()
+	}
+	for {
+		if err := p.parseBinary(level + 1); err != nil {
+			return err
+		}
+		if !p.isSymbol(binaryOperators[level]...) && !p.isKeyword(binaryOperators[level]...) {
+			return nil
+		}
+		p.next()
+	}
+}
+
+func (p *parser) parseUnary() error {
+	if p.isSymbol("!", "-") || p.isKeyword("NOT") {
+		p.next()
+		return p.parseUnary()
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() error {
+	if err := p.parsePrimary(); err != nil {
+		return err
+	}
+	for {
+		var err error
+		switch {
+		case p.isSymbol("("):
+			p.next()
+			if !p.isSymbol(")") {
+				err = p.parseList(p.parseExpression)
+			}
+			if err == nil {
+				err = p.expect(")", "to close the arguments")
+			}
+		case p.isSymbol("["):
+			p.next()
+			err = p.parseExpression()
+			if err == nil {
+				err = p.expect("]", "to close the filter")
+			}
+		case p.isSymbol("{"):
+			p.next()
+			err = p.parseFields()
+			if err == nil {
+				err = p.expect("}", "to close the fields")
+			}
+		case p.isSymbol("."):
+			p.next()
+			if p.peek().kind != tokenIdentifier {
+				return p.unexpected("a name after \".\"")
+			}
+			p.next()
+		default:
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (p *parser) parsePrimary() error {
+	t := p.peek()
+	switch {
+	case t.kind == tokenIdentifier && slices.Contains(queryClauses, strings.ToUpper(t.text)):
+		return p.unexpected("an expression")
+	case t.kind == tokenIdentifier, t.kind == tokenString, t.kind == tokenNumber, t.kind == tokenParameter:
+		p.next()
+		return nil
+	case p.isSymbol("("):
+		p.next()
+		if err := p.parseExpression(); err != nil {
+			return err
+		}
+		return p.expect(")", "to close the parenthesis")
+	case p.isSymbol("["):
+		p.next()
+		if !p.isSymbol("]") {
+			if err := p.parseList(p.parseExpression); err != nil {
+				return err
+			}
+		}
+		return p.expect("]", "to close the list")
+	}
+	return p.unexpected("an expression")
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateQuery_Valid(t *testing.T) {
+	queries := []string{
+		"FETCH id, type, attributes FROM entities(k8s:workload)",
+		"fetch count, events(logs:generic_record) from entities",
+		"FETCH id\nFROM entities(k8s:workload)\nLIMITS topLevelItems.count(50)",
+		"SINCE -7d FETCH id FROM entities(k8s:workload)[isActive = true][attributes(k8s.workload.name) = 'it\\'s']",
+		"FETCH id, events(k8s:event) {timestamp, raw} FROM entities(k8s:workload) SINCE -1h UNTIL now()",
+		"FETCH id, metrics(infra:cpu.used) {timestamp, value} FROM entities(k8s:workload).out.to(k8s:pod)",
+		"SINCE 2024-01-13T13:00:00Z\nUNTIL 2024-01-13T13:55:00.5+01:00\nFETCH name: attributes(\"k8s.workload.name\"), events(k8s:event){*}",
+		`FETCH id FROM entities[attributes("service.name") = $service && (attributes("x") != "y" || !isActive)] SINCE ${since}`,
+		`FETCH id FROM entities[attributes("x") IN ["a", "b"]] SINCE now() - 1h30m`,
+	}
+	for _, query := range queries {
+		assert.NoError(t, validateQuery(query), query)
+	}
+}
+
+func TestValidateQuery_Invalid(t *testing.T) {
+	tests := []struct {
+		query   string
+		line    int
+		column  int
+		message string
+	}{
+		{"", 1, 1, "the query is empty"},
+		{"FROM entities", 1, 1, "the query has no FETCH clause"},
+		{"FETCH id FETCH type", 1, 10, "the query has more than one FETCH clause"},
+		{"FETCH id FROM entities(", 1, 24, "unexpected end of the query, expected an expression"},
+		{"FETCH id FROM entities(k8s:workload]", 1, 36, `unexpected "]", expected ")" to close the arguments`},
+		{"FETCH id,\nFROM entities", 2, 1, `unexpected "FROM", expected an expression`},
+		{"FETCH id WHERE x = 1", 1, 10, `unexpected "WHERE", expected a clause: FETCH, FROM, SINCE, UNTIL, LIMITS, ORDER`},
+		{"FETCH id FROM entities[x = 'a]", 1, 28, "unterminated string literal"},
+		{"FETCH id SINCE 2024-13", 1, 16, `invalid number, duration or time "2024-13"`},
+		{"FETCH id FROM entities[x # 1]", 1, 26, `unexpected character '#'`},
+	}
+	for _, test := range tests {
+		err := validateQuery(test.query)
+		if assert.IsType(t, &syntaxError{}, err, test.query) {
+			syntaxErr := err.(*syntaxError)
+			assert.Equal(t, test.line, syntaxErr.line, test.query)
+			assert.Equal(t, test.column, syntaxErr.column, test.query)
+			assert.Equal(t, test.message, syntaxErr.message, test.query)
+		}
+	}
+}
+
+func TestSyntaxErrorDescribe(t *testing.T) {
+	// given
+	err := validateQuery("FETCH id\n\tFROM entities())")
+
+	// when
+	description := err.(*syntaxError).describe()
+
+	// then
+	assert.Equal(t, "line 2, column 17: unexpected \")\", expected a clause: FETCH, FROM, SINCE, UNTIL, LIMITS, ORDER\n"+
+		"  \tFROM entities())\n"+
+		"  \t               ^", description)
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeRangeClauseRegexp detects a SINCE or UNTIL clause in a query
+var timeRangeClauseRegexp = regexp.MustCompile(`(?i)\b(SINCE|UNTIL)\b`)
+
+// relativeTimeRegexp matches a duration before now, e.g., 15m, 2h, 3d, 1w or 1h30m, with an
+// optional leading "-"
+var relativeTimeRegexp = regexp.MustCompile(`^-?(\d+[smhdw])+$`)
+
+// durationPartRegexp matches each number and unit of a relative time
+var durationPartRegexp = regexp.MustCompile(`(\d+)([smhdw])`)
+
+// timeUnits are the units of relative times; days and weeks are not supported by time.ParseDuration
+var timeUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// absoluteTimeLayouts are the accepted layouts of absolute times; times without a zone are local
+var absoluteTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// resolveTime converts the value of --since or --until, a duration before now (e.g., 15m, 2h or 3d),
+// "now" or an absolute time, to a UTC timestamp for a SINCE or UNTIL clause
+func resolveTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(value, "now") {
+		return now.UTC(), nil
+	}
+	if relativeTimeRegexp.MatchString(value) {
+		var d time.Duration
+		for _, part := range durationPartRegexp.FindAllStringSubmatch(value, -1) {
+			n, err := strconv.Atoi(part[1])
+			if err != nil {
+				return time.Time{}, fmt.Errorf("invalid time %q: %w", value, err)
+			}
+			d += time.Duration(n) * timeUnits[part[2]]
+		}
+		return now.Add(-d).UTC(), nil
+	}
+	for _, layout := range absoluteTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use a duration before now (e.g., 15m, 2h or 3d), now, or a time such as 2024-01-13T13:57:20Z or 2024-01-13 13:57", value)
+}
+
+// ResolveTime converts a duration before now (e.g., 15m, 2h or 3d), "now" or an absolute time, as
+// accepted by --since and --until, to a UTC time
+func ResolveTime(value string) (time.Time, error) {
+	return resolveTime(value, time.Now())
+}
+
+// withTimeRange returns the query with SINCE and UNTIL clauses for the given times, if not empty,
+// resolved relative to now. Queries that already have a SINCE or UNTIL clause are rejected, as the
+// two would conflict.
+func withTimeRange(query string, since string, until string, now time.Time) (string, error) {
+	if since == "" && until == "" {
+		return query, nil
+	}
+	if timeRangeClauseRegexp.MatchString(query) {
+		return "", fmt.Errorf("the time range cannot be set for a query with a SINCE or UNTIL clause")
+	}
+
+	// UQL clauses may be in any order, so the time range is put first, where it is easy to see
+	var clauses strings.Builder
+	var sinceTime time.Time
+	if since != "" {
+		t, err := resolveTime(since, now)
+		if err != nil {
+			return "", err
+		}
+		sinceTime = t
+		fmt.Fprintf(&clauses, "SINCE %v\n", t.Format(time.RFC3339))
+	}
+	if until != "" {
+		t, err := resolveTime(until, now)
+		if err != nil {
+			return "", err
+		}
+		if !sinceTime.IsZero() && !t.After(sinceTime) {
+			return "", fmt.Errorf("the end of the time range, %q, must be after its start, %q", until, since)
+		}
+		fmt.Fprintf(&clauses, "UNTIL %v\n", t.Format(time.RFC3339))
+	}
+	return clauses.String() + query, nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveTime(t *testing.T) {
+	now := time.Date(2024, 1, 13, 14, 0, 0, 0, time.UTC)
+	tests := map[string]time.Time{
+		"15m":                       time.Date(2024, 1, 13, 13, 45, 0, 0, time.UTC),
+		"-2h":                       time.Date(2024, 1, 13, 12, 0, 0, 0, time.UTC),
+		"3d":                        time.Date(2024, 1, 10, 14, 0, 0, 0, time.UTC),
+		"1w":                        time.Date(2024, 1, 6, 14, 0, 0, 0, time.UTC),
+		"1h30m":                     time.Date(2024, 1, 13, 12, 30, 0, 0, time.UTC),
+		"now":                       now,
+		"2024-01-12T10:00:00Z":      time.Date(2024, 1, 12, 10, 0, 0, 0, time.UTC),
+		"2024-01-12T10:00:00+02:00": time.Date(2024, 1, 12, 8, 0, 0, 0, time.UTC),
+	}
+	for value, expected := range tests {
+		actual, err := resolveTime(value, now)
+		assert.Nil(t, err, value)
+		assert.Equal(t, expected, actual, value)
+	}
+
+	local, err := resolveTime("2024-01-12 10:00", now)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2024, 1, 12, 10, 0, 0, 0, time.Local).UTC(), local)
+
+	for _, value := range []string{"", "yesterday", "15", "2h-", "2024-13-01"} {
+		_, err := resolveTime(value, now)
+		assert.NotNil(t, err, value)
+	}
+}
+
+func TestWithTimeRange(t *testing.T) {
+	query := "FETCH id FROM entities"
+	now := time.Date(2024, 1, 13, 14, 0, 0, 0, time.UTC)
+
+	result, err := withTimeRange(query, "", "", now)
+	assert.Nil(t, err)
+	assert.Equal(t, query, result)
+
+	result, err = withTimeRange(query, "15m", "", now)
+	assert.Nil(t, err)
+	assert.Equal(t, "SINCE 2024-01-13T13:45:00Z\n"+query, result)
+
+	result, err = withTimeRange(query, "2024-01-13T13:00:00Z", "5m", now)
+	assert.Nil(t, err)
+	assert.Equal(t, "SINCE 2024-01-13T13:00:00Z\nUNTIL 2024-01-13T13:55:00Z\n"+query, result)
+
+	_, err = withTimeRange("FETCH id FROM entities since -1h", "15m", "", now)
+	assert.NotNil(t, err)
+
+	_, err = withTimeRange(query, "1h", "2h", now)
+	assert.NotNil(t, err)
+}
@@ -16,8 +16,10 @@ package uql
 
 import (
 	"fmt"
+	"math/rand"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/apex/log"
 	"github.com/charmbracelet/lipgloss"
@@ -30,17 +32,38 @@ import (
 
 var outputFlag string
 var rawFlag bool
+var maxRowsFlag int
+var pageSizeFlag int
+var limitFlag int
+var sampleFlag int
+var followFlag bool
+var intervalFlag time.Duration
+var nestedFlag string
+var columnsFlag []string
+var flattenFlag []string
+var paramsFlag []string
+var paramsFileFlag string
+var sinceFlag string
+var untilFlag string
+var resumeFileFlag string
+var noCacheFlag bool
+var cacheTTLFlag time.Duration
+var filterFlag string
+var noValidateFlag bool
+var chartFlag string
+var apiVersionFlag ApiVersion
 
 // Config defines the subsystem configuration under fsoc
 type Config struct {
 	// TODO
-	ApiVersion *ApiVersion `mapstructure:"apiver,omitempty" fsoc-help:"API version to use for UQL queries. The default is \"v1\"."`
+	ApiVersion  *ApiVersion `mapstructure:"apiver,omitempty" fsoc-help:"API version to use for UQL queries: v1, v2beta or auto, to detect the newest version supported by the tenant. The default is \"v1\"."`
+	TeamQueries string      `mapstructure:"teamqueries,omitempty" fsoc-help:"Path to a YAML file with saved queries shared by a team, listed and run along with the user's saved queries."`
 }
 
 var GlobalConfig Config
 
 const (
-	availableFormats string = "auto, table, json, yaml"
+	availableFormats string = "auto, table, json, yaml, csv, tsv, parquet, arrow"
 )
 
 // uqlCmd represents the uql command
@@ -54,10 +77,112 @@ for more information on the unified query language for the Cisco Observability P
 
 Parsed response data are displayed in a table by default.
 Available output formats: ` + availableFormats + `.
-If the "raw" flag is provided, the actual response from the backend API is displayed instead.`,
+If the "raw" flag is provided, the actual response from the backend API is displayed instead.
+
+The query can also be read from a file with --file, or from the standard input with --file - (or with "-" as
+the query). A file can have multiple queries separated by semicolons, which are executed in order, each
+with its results preceded by a label identifying it. For the table format, the labels are displayed with
+the results; for the other formats, they are written to stderr.
+
+Queries can have parameters, written $name or ${name}, whose values are set with --param name=value or
+read from a YAML or JSON file with --params-file. In string literals, the values are escaped as needed.
+Elsewhere, numbers, durations (e.g., -1h), times and function calls (e.g., now()) are used as they are,
+while other values are written as string literals. Write $$ for a $.
+
+The time range of a query can be set with --since and --until, which add SINCE and UNTIL clauses to the
+query (which must not have any). They take a duration before now, e.g., 15m, 2h, 3d or 1w, or a time, e.g.,
+2024-01-13T13:57:20Z or 2024-01-13 13:57 (in the local time zone); --until also takes "now".
+
+The displayed columns can be selected, and ordered, with --columns. Columns with key/value pairs, e.g.,
+attributes, can be flattened with --flatten into a column per key, named <column>.<key>: --flatten
+attributes.* makes a column for each attribute found, while --flatten attributes.<key> adds a column for a
+single attribute.
+
+The results can be reshaped with --filter, a jq expression evaluated against the results as displayed with
+--output json, i.e., an object with the model and the data of the results. The results of the expression
+are displayed in the selected format: a result that is an array, or multiple results, make the rows, with a
+column for each key if they are all objects, or a single value column otherwise. The filter is applied after
+--columns and --flatten.
+
+Time series in the results, e.g., the metrics of entities, can be displayed as charts with --chart: as line
+charts drawn with braille characters (--chart or --chart=line), or as sparklines, one per line, with their
+lowest, highest and last values (--chart=sparkline). The charts are as wide as the terminal.
+
+The csv and tsv formats write the rows with a header of column names, e.g., for import into a spreadsheet.
+Nested data (e.g., the events of an entity) are written as a JSON array in a single cell by default. With
+--nested expand, each nested row gets a row of its own, repeating the values of its parent row, with a
+column for each nested column, e.g., "events.timestamp".
+
+The parquet and arrow formats write the rows as a Parquet file or an Arrow IPC file, e.g., for analysis
+with pandas or duckdb, and must be redirected to a file. The columns are typed after the results: long,
+double, boolean and timestamp columns keep their types, and nested data are lists of structs with typed
+fields. Other values, e.g., json, are written as strings.
+
+Data sets of the results that are not nested in the main one, e.g., the entities of the events, are
+displayed after it as separate tables, labeled with their names, and under "dataSets" in the json and
+yaml output.
+
+Results that span multiple pages are fetched page by page and merged into one result, up to --max-rows
+top-level rows. The number of rows in each page can be set with --page-size, which adds a LIMITS clause
+to the query. If a page fails, or fetching the pages is interrupted with Ctrl-C, the rows of the pages fetched
+before are displayed, marked as a partial result (with a "partial" field in the json and yaml output), and
+the command fails.
+
+To avoid flooding the terminal with huge results, --limit displays the first top-level rows only, and stops
+fetching pages once they are fetched; queries without --page-size or a LIMITS clause of their own get one
+as well, so that the backend does not return more rows than displayed. Alternatively, --sample displays
+rows chosen at random among the rows fetched, in their order in the results. A warning tells how many rows
+were displayed out of how many, when the results were truncated.
+
+With --follow, the query is executed again at each --interval and only the rows that were not in the
+previous results are displayed, until interrupted, e.g., to watch the latest events during an incident.
+If the results provide a follow link, it is used instead of executing the query again.
+
+For downloads of many pages of results, --resume-file records each page in a file as it is fetched. If the
+download is interrupted, running the same command again resumes it from the last page fetched instead of
+executing the query again, provided the backend still accepts the link to the next page. The file is removed
+once the results are displayed.
+
+The results of a query are cached for a short time, set with --cache-ttl, so that executing the same query
+again, e.g., in a loop refreshing a display, uses the cached results. The results are cached by query, time
+range and profile; use --no-cache to execute the query regardless.
+
+The syntax of a query is checked before it is executed, so that syntax errors are reported with their
+position without a round trip to the backend; see "fsoc uql validate". Use --no-validate to skip the check.
+
+Queries are executed with version v1 of the UQL API, unless another version is set with --api-version or
+with the uql.apiver setting of the profile, e.g., "fsoc config set uql.apiver=auto". With "auto", the newest
+version supported by the tenant is detected by trying the versions in turn; it is remembered for a day.`,
 	Example: `# Get parsed results
-  fsoc uql "FETCH id, type, attributes FROM entities(k8s:workload)"`,
-	Args:             cobra.ExactArgs(1),
+  fsoc uql "FETCH id, type, attributes FROM entities(k8s:workload)"
+
+# Execute the queries in a file
+  fsoc uql -f queries.uql
+  cat query.uql | fsoc uql -
+
+# Set the time range
+  fsoc uql --since 2h "FETCH id, events(k8s:event) {timestamp, raw} FROM entities(k8s:workload)"
+  fsoc uql --since "2024-01-13 09:00" --until "2024-01-13 10:00" "FETCH events(k8s:event) {timestamp, raw}"
+
+# Use parameters
+  fsoc uql --param workload=frontend --param since=-2h "FETCH id FROM entities(k8s:workload)[attributes(\"k8s.workload.name\") = \"$workload\"] SINCE $since"
+
+# Show selected attributes only
+  fsoc uql --flatten "attributes.*" --columns id,attributes.k8s.workload.name "FETCH id, attributes FROM entities(k8s:workload)"
+
+# Chart the CPU usage of workloads
+  fsoc uql --chart --since 1h "FETCH id, metrics(infra:cpu.used) {timestamp, value} FROM entities(k8s:workload)"
+  fsoc uql --chart=sparkline --since 1d "FETCH id, metrics(infra:cpu.used) {timestamp, value} FROM entities(k8s:workload)"
+
+# Reshape the results with a jq expression
+  fsoc uql --filter '.data[] | select(.attributes["k8s.workload.kind"] == "Deployment") | {id, name: .attributes["k8s.workload.name"]}' "FETCH id, attributes FROM entities(k8s:workload)"
+
+# Download many results, resuming the download if interrupted
+  fsoc uql --resume-file events.progress -o csv "FETCH events(k8s:event) {timestamp, raw} SINCE -1d" > events.csv
+
+# Watch new events
+  fsoc uql --follow --interval 30s "FETCH events(k8s:event) {timestamp, raw} SINCE -5m"`,
+	Args:             cobra.MaximumNArgs(1),
 	RunE:             uqlQuery,
 	TraverseChildren: true,
 }
@@ -70,19 +195,53 @@ const (
 	rawFormat
 	jsonFormat
 	yamlFormat
+	csvFormat
+	tsvFormat
+	parquetFormat
+	arrowFormat
 )
 
 func init() {
-	uqlCmd.Flags().StringVarP(&outputFlag, "output", "o", "table", "overridden")
-	uqlCmd.Flags().BoolVar(&rawFlag, "raw", false, "Display actual response from the backend. Cannot be used together with the output flag.")
+	uqlCmd.Flags().StringP("file", "f", "", `File with the query or queries to execute, "-" for stdin`)
+	uqlCmd.PersistentFlags().StringVarP(&outputFlag, "output", "o", "table", "overridden")
+	uqlCmd.PersistentFlags().BoolVar(&rawFlag, "raw", false, "Display actual response from the backend. Cannot be used together with the output flag.")
 	uqlCmd.MarkFlagsMutuallyExclusive("output", "raw")
+	uqlCmd.PersistentFlags().StringArrayVar(&paramsFlag, "param", nil, "Value of a query parameter, as name=value; may be repeated")
+	uqlCmd.PersistentFlags().StringVar(&paramsFileFlag, "params-file", "", "YAML or JSON file with the values of query parameters; --param values take precedence")
+	uqlCmd.PersistentFlags().StringVar(&sinceFlag, "since", "", "Start of the time range of the query: a duration before now (e.g., 15m, 2h, 3d) or a time (e.g., 2024-01-13T13:57:20Z)")
+	uqlCmd.PersistentFlags().StringVar(&untilFlag, "until", "", "End of the time range of the query: a duration before now, now or a time (defaults to now)")
+	uqlCmd.PersistentFlags().StringSliceVar(&columnsFlag, "columns", nil, "Columns to display, in order, e.g., id,attributes.service.name (defaults to all columns)")
+	uqlCmd.PersistentFlags().StringSliceVar(&flattenFlag, "flatten", nil, "Columns with key/value pairs to flatten into a column per key, e.g., attributes.* or attributes.service.name")
+	uqlCmd.MarkFlagsMutuallyExclusive("columns", "raw")
+	uqlCmd.MarkFlagsMutuallyExclusive("flatten", "raw")
+	uqlCmd.PersistentFlags().BoolVar(&noValidateFlag, "no-validate", false, "Execute the query without checking its syntax first (see \"fsoc uql validate\")")
+	uqlCmd.PersistentFlags().StringVar(&filterFlag, "filter", "", "jq expression reshaping the results, evaluated against their JSON form (see --output json)")
+	uqlCmd.MarkFlagsMutuallyExclusive("filter", "raw")
+	uqlCmd.PersistentFlags().StringVar(&chartFlag, "chart", "", "Display the time series in the results, e.g., metrics, as charts: --chart=line (the default) or --chart=sparkline")
+	uqlCmd.PersistentFlags().Lookup("chart").NoOptDefVal = chartLine
+	uqlCmd.MarkFlagsMutuallyExclusive("chart", "raw")
+	uqlCmd.PersistentFlags().StringVar(&nestedFlag, "nested", string(nestedJson), "How nested data are written in csv and tsv output: json (in a single cell) or expand (in rows of their own)")
+	uqlCmd.PersistentFlags().IntVar(&maxRowsFlag, "max-rows", 0, "Maximum number of top-level rows to fetch when following result pages (0 for no limit)")
+	uqlCmd.PersistentFlags().IntVar(&pageSizeFlag, "page-size", 0, "Number of top-level rows in each page of results (defaults to the backend's page size)")
+	uqlCmd.PersistentFlags().IntVar(&limitFlag, "limit", 0, "Maximum number of top-level rows to display (0 for no limit)")
+	uqlCmd.PersistentFlags().IntVar(&sampleFlag, "sample", 0, "Number of top-level rows to display, chosen at random among the rows fetched (0 to display all rows)")
+	uqlCmd.MarkFlagsMutuallyExclusive("limit", "sample")
+	uqlCmd.PersistentFlags().BoolVar(&followFlag, "follow", false, "Keep executing the query and display new rows as they appear, until interrupted")
+	uqlCmd.PersistentFlags().DurationVar(&intervalFlag, "interval", 30*time.Second, "Interval between executions of the query with --follow")
+	uqlCmd.MarkFlagsMutuallyExclusive("follow", "raw")
+	uqlCmd.MarkFlagsMutuallyExclusive("follow", "chart")
+	uqlCmd.PersistentFlags().StringVar(&resumeFileFlag, "resume-file", "", "File recording the pages of results as they are fetched, to resume an interrupted download; removed when complete")
+	uqlCmd.MarkFlagsMutuallyExclusive("follow", "resume-file")
+	uqlCmd.PersistentFlags().BoolVar(&noCacheFlag, "no-cache", false, "Execute the query instead of using results cached by a recent execution of the same query")
+	uqlCmd.PersistentFlags().DurationVar(&cacheTTLFlag, "cache-ttl", 30*time.Second, "How long the results of a query are cached (0 to disable the cache)")
+	uqlCmd.PersistentFlags().Var(&apiVersionFlag, "api-version", `UQL API version: v1, v2beta or auto, to use the newest version supported by the tenant (overrides the uql.apiver setting of the profile)`)
 	uqlCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
-		changeFlagUsage(cmd.Parent())
-		cmd.Parent().HelpFunc()(cmd, args)
+		changeFlagUsage(uqlCmd)
+		uqlCmd.Parent().HelpFunc()(cmd, args)
 	})
 	uqlCmd.SetUsageFunc(func(cmd *cobra.Command) error {
-		changeFlagUsage(cmd.Parent())
-		return cmd.Parent().UsageFunc()(cmd)
+		changeFlagUsage(uqlCmd)
+		return uqlCmd.Parent().UsageFunc()(cmd)
 	})
 }
 
@@ -91,22 +250,155 @@ func NewSubCmd() *cobra.Command {
 }
 
 func uqlQuery(cmd *cobra.Command, args []string) error {
-	log.WithFields(log.Fields{"command": cmd.Name(), "args": args[0]}).Info("Performing UQL query")
+	file, _ := cmd.Flags().GetString("file")
+	switch {
+	case file != "" && len(args) > 0:
+		return fmt.Errorf("the query cannot be specified both as an argument and with --file")
+	case file == "" && len(args) == 0:
+		return fmt.Errorf("missing query: specify it as an argument or with --file")
+	case file == "" && args[0] != stdinFileName:
+		log.WithFields(log.Fields{"command": cmd.Name(), "args": args[0]}).Info("Performing UQL query")
+		return executeAndPrint(cmd, args[0], nil)
+	case file == "":
+		file = stdinFileName
+	}
 
-	output, err := outputFormat(outputFlag, rawFlag)
+	text, err := readQueryFile(file, cmd.InOrStdin())
 	if err != nil {
 		return err
 	}
-	queryStr := args[0]
-	response, err := runQuery(queryStr)
+	statements := splitStatements(text)
+	log.WithFields(log.Fields{"command": cmd.Name(), "file": file, "queries": len(statements)}).Info("Performing UQL queries from file")
+	switch {
+	case len(statements) == 0:
+		return fmt.Errorf("no query found in %q", file)
+	case len(statements) == 1:
+		return executeAndPrint(cmd, statements[0].query, nil)
+	case followFlag:
+		return fmt.Errorf("--follow cannot be used with multiple queries")
+	case resumeFileFlag != "":
+		return fmt.Errorf("--resume-file cannot be used with multiple queries")
+	}
+
+	output, err := outputFormat(outputFlag, rawFlag)
 	if err != nil {
-		if problem, ok := err.(uqlProblem); ok {
-			printProblemDescription(cmd, problem, queryStr)
-			os.Exit(1)
+		return err
+	}
+	if isColumnar(output) {
+		return fmt.Errorf("the %v output format cannot be used with multiple queries, as each query's results make a file of their own", outputFlag)
+	}
+	for i, statement := range statements {
+		label := fmt.Sprintf("-- query %d of %d (line %d): %v", i+1, len(statements), statement.line, strings.Join(strings.Fields(statement.query), " "))
+		if output == tableFormat || output == autoFormat {
+			cmd.Println(label)
 		} else {
-			log.Fatal(err.Error())
+			cmd.PrintErrln(label)
+		}
+		if err := executeAndPrint(cmd, statement.query, nil); err != nil {
+			return fmt.Errorf("query %d (line %d): %w", i+1, statement.line, err)
 		}
 	}
+	return nil
+}
+
+// executeAndPrint executes a query, with the given default parameter values, and prints its results
+// as specified by the command's flags
+func executeAndPrint(cmd *cobra.Command, query string, defaultParams map[string]string) error {
+	output, err := outputFormat(outputFlag, rawFlag)
+	if err != nil {
+		return err
+	}
+	if _, err := parseNestedMode(nestedFlag); err != nil {
+		return err
+	}
+	if filterFlag != "" {
+		if _, err := compileFilter(filterFlag); err != nil {
+			return err
+		}
+	}
+	if err := checkChartOutput(output); err != nil {
+		return err
+	}
+	if err := checkColumnarOutput(cmd, output); err != nil {
+		return err
+	}
+	if followFlag && intervalFlag <= 0 {
+		return fmt.Errorf("the interval must be positive")
+	}
+	if limitFlag < 0 || sampleFlag < 0 {
+		return fmt.Errorf("the number of rows to display cannot be negative")
+	}
+	if err := checkQuery(query); err != nil {
+		return err
+	}
+	params, err := queryParams(defaultParams)
+	if err != nil {
+		return err
+	}
+	query, err = substituteParams(query, params)
+	if err != nil {
+		return err
+	}
+	pageSize := limitPageSize(query, pageSizeFlag, limitFlag)
+	maxRows := fetchRowLimit(maxRowsFlag, limitFlag)
+	key := queryKey{Query: normalizeQuery(query), Since: sinceFlag, Until: untilFlag, PageSize: pageSize}
+	query, err = withTimeRange(query, sinceFlag, untilFlag, time.Now())
+	if err != nil {
+		return err
+	}
+	queryStr, err := withPageSize(query, pageSize)
+	if err != nil {
+		return err
+	}
+
+	// with a resume file, the pages already fetched are read from it instead of executing the query;
+	// otherwise, recent results of the same query may be read from the cache
+	client := Client
+	var response *Response
+	var progress *progressFile
+	var cache *resultCache
+	var record func(page *Response) error
+	switch {
+	case resumeFileFlag != "":
+		progress, response, err = openProgressFile(resumeFileFlag, key)
+		if err != nil {
+			return err
+		}
+		defer progress.close()
+		record = progress.record
+	case !noCacheFlag && cacheTTLFlag > 0 && !followFlag:
+		if cache, err = newResultCache(key, maxRows, cacheTTLFlag); err != nil {
+			log.Warnf("The results will not be cached: %v", err)
+			break
+		}
+		response = cache.load()
+		record = cache.record
+	}
+	if record != nil {
+		client = recordingClient{UqlClient: Client, record: record}
+	}
+	if response == nil {
+		response, err = runQuery(queryStr)
+		if err != nil {
+			if problem, ok := err.(uqlProblem); ok {
+				printProblemDescription(cmd, problem, queryStr)
+				os.Exit(1)
+			} else {
+				log.Fatal(err.Error())
+			}
+		}
+		if record != nil {
+			if err := record(response); err != nil {
+				return err
+			}
+		}
+	}
+	response, err = fetchAllPages(client, response, maxRows)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	limitRows(response, limitFlag)
+	sampleRows(response, sampleFlag, rand.New(rand.NewSource(time.Now().UnixNano())))
 	if response.HasErrors() {
 		log.Error("Execution of query encountered errors. Returned data are not complete!")
 		for _, e := range response.Errors() {
@@ -117,6 +409,22 @@ func uqlQuery(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if response.Partial() != nil {
+		if progress != nil {
+			progress.close()
+			log.Fatalf("The results are partial; the pages fetched are kept in %v: run the command again to fetch the remaining pages", resumeFileFlag)
+		}
+		log.Fatal("The results are partial")
+	}
+	if progress != nil {
+		progress.remove()
+	}
+	if cache != nil && !response.HasErrors() {
+		cache.save()
+	}
+	if followFlag {
+		return followQuery(cmd, queryStr, response, output, intervalFlag)
+	}
 	return nil
 }
 
@@ -133,6 +441,14 @@ func outputFormat(output string, useRaw bool) (format, error) {
 		return jsonFormat, nil
 	case "yaml":
 		return yamlFormat, nil
+	case "csv":
+		return csvFormat, nil
+	case "tsv":
+		return tsvFormat, nil
+	case "parquet":
+		return parquetFormat, nil
+	case "arrow":
+		return arrowFormat, nil
 
 	default:
 		return -1, fmt.Errorf(
@@ -155,10 +471,30 @@ func runQuery(query string) (*Response, error) {
 }
 
 func printResponse(cmd *cobra.Command, response *Response, output format) error {
+	if output != rawFormat {
+		var err error
+		response, err = projectResponse(response, columnsFlag, flattenFlag)
+		if err != nil {
+			return err
+		}
+		if filterFlag != "" {
+			response, err = filterResponse(response, filterFlag)
+			if err != nil {
+				return err
+			}
+		}
+	}
 	switch output {
 	case tableFormat, autoFormat:
+		if chartFlag != "" {
+			return printCharts(cmd, response, chartFlag)
+		}
+		if len(response.DataSets()) > 0 && response.Main() != nil {
+			cmd.Printf("%v (%d rows):\n", response.Main().Name, len(response.Main().Data))
+		}
 		t := makeFlatTable(response)
 		cmd.Println(t.Render())
+		printDataSetTables(cmd, response.DataSets())
 	case jsonFormat:
 		json, err := transformForJsonOutput(response)
 		if err != nil {
@@ -171,14 +507,45 @@ func printResponse(cmd *cobra.Command, response *Response, output format) error
 			return err
 		}
 		return fsoc.PrintYaml(cmd, json)
+	case csvFormat, tsvFormat:
+		mode, err := parseNestedMode(nestedFlag)
+		if err != nil {
+			return err
+		}
+		delimiter := ','
+		if output == tsvFormat {
+			delimiter = '\t'
+		}
+		if n := len(response.DataSets()); n > 0 {
+			log.Warnf("The data sets of the results other than %v (%d) are not written in the %v output; use the table, json or yaml output to see them", mainDataSetName, n, outputFlag)
+		}
+		return writeDelimited(cmd.OutOrStdout(), response, delimiter, mode)
+	case parquetFormat, arrowFormat:
+		if n := len(response.DataSets()); n > 0 {
+			log.Warnf("The data sets of the results other than %v (%d) are not written in the %v output; use the table, json or yaml output to see them", mainDataSetName, n, outputFlag)
+		}
+		return writeColumnar(cmd.OutOrStdout(), response, output)
 	case rawFormat:
 		fsoc.PrintCmdOutput(cmd, string(*response.raw))
 	}
 	return nil
 }
 
+// printDataSetTables prints the data sets other than the main data set, e.g., related entities,
+// each as a table labeled with the name of the data set and followed by its errors
+func printDataSetTables(cmd *cobra.Command, dataSets []*DataSet) {
+	for _, dataSet := range dataSets {
+		cmd.Printf("\n%v (%d rows):\n", dataSet.Name, len(dataSet.Data))
+		t := makeFlatTable(&Response{model: dataSet.Model(), mainDataSet: dataSet})
+		cmd.Println(t.Render())
+		for _, e := range dataSet.Errors {
+			cmd.Printf("Error in %v: %s: %s\n", dataSet.Name, e.Title, e.Detail)
+		}
+	}
+}
+
 func changeFlagUsage(cmd *cobra.Command) {
-	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+	cmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
 		if flag.Name == "output" {
 			flag.Usage = fmt.Sprintf("output format (%s)", availableFormats)
 		}
@@ -0,0 +1,105 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uql
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate [<query>]",
+	Short: "Check the syntax of UQL queries without executing them",
+	Long: `Check the syntax of UQL queries locally, without sending them to the backend, and report syntax errors
+with their position in the query. The queries can be read from a file with --file, which may have multiple
+queries separated by semicolons, or from the standard input with --file - (or with "-" as the query).
+
+The syntax is checked leniently: the structure of the clauses and of the expressions in them is checked,
+while names, e.g., of types, functions and attributes, are checked by the backend when the query is executed.
+Parameters, e.g., $since, are accepted wherever a value is.
+
+Queries are also checked before they are executed by the other uql commands; use --no-validate to send a
+query to the backend regardless, e.g., if it uses syntax that is not known locally.`,
+	Example: `  fsoc uql validate "FETCH id, attributes FROM entities(k8s:workload)[attributes(\"k8s.cluster.name\") = \"prod\"]"
+  fsoc uql validate -f queries.uql`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: validateQueries,
+}
+
+func init() {
+	validateCmd.Flags().StringP("file", "f", "", `File with the query or queries to check, "-" for stdin`)
+	uqlCmd.AddCommand(validateCmd)
+}
+
+func validateQueries(cmd *cobra.Command, args []string) error {
+	file, _ := cmd.Flags().GetString("file")
+	switch {
+	case file != "" && len(args) > 0:
+		return fmt.Errorf("the query cannot be specified both as an argument and with --file")
+	case file == "" && len(args) == 0:
+		return fmt.Errorf("missing query: specify it as an argument or with --file")
+	case file == "" && args[0] != stdinFileName:
+		if err := validateQuery(args[0]); err != nil {
+			return describeSyntaxError(err)
+		}
+		cmd.Println("The query is valid")
+		return nil
+	case file == "":
+		file = stdinFileName
+	}
+
+	text, err := readQueryFile(file, cmd.InOrStdin())
+	if err != nil {
+		return err
+	}
+	statements := splitStatements(text)
+	if len(statements) == 0 {
+		return fmt.Errorf("no query found in %q", file)
+	}
+	invalid := 0
+	for i, statement := range statements {
+		if err := validateQuery(statement.query); err != nil {
+			invalid++
+			cmd.Printf("query %d of %d (line %d): %v\n", i+1, len(statements), statement.line, describeSyntaxError(err))
+		}
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d queries are invalid", invalid, len(statements))
+	}
+	cmd.Printf("All %d queries are valid\n", len(statements))
+	return nil
+}
+
+// checkQuery checks the syntax of a query before it is executed, unless disabled with --no-validate
+func checkQuery(query string) error {
+	if noValidateFlag {
+		return nil
+	}
+	if err := validateQuery(query); err != nil {
+		return fmt.Errorf("invalid query: %w\n(use --no-validate to execute it regardless)", describeSyntaxError(err))
+	}
+	return nil
+}
+
+// describeSyntaxError returns a syntax error with the line of the query marked where the error is
+func describeSyntaxError(err error) error {
+	var syntaxErr *syntaxError
+	if errors.As(err, &syntaxErr) {
+		return errors.New(syntaxErr.describe())
+	}
+	return err
+}
@@ -0,0 +1,295 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package archive creates and extracts zip archives, such as solution packages. Archives are
+// created reproducibly and extracted defensively: entries that would be written outside of the
+// target directory (zip slip), symbolic links and other special files are rejected, and the
+// number and size of the extracted files are limited.
+package archive
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/spf13/afero"
+)
+
+// Limits are the limits on the content of an archive being extracted; a zero value means the
+// corresponding default limit
+type Limits struct {
+	MaxFiles     int   // maximum number of entries, including directories
+	MaxFileSize  int64 // maximum uncompressed size of a single file, in bytes
+	MaxTotalSize int64 // maximum uncompressed size of all files, in bytes
+}
+
+// DefaultLimits are the limits applied when an extraction doesn't specify them
+var DefaultLimits = Limits{
+	MaxFiles:     100000,
+	MaxFileSize:  100 * 1024 * 1024,
+	MaxTotalSize: 1024 * 1024 * 1024,
+}
+
+// ProgressFunc is called as an archive's files are extracted, with the number of (uncompressed)
+// bytes extracted so far and the total
+type ProgressFunc func(done int64, total int64)
+
+// LogProgress returns a progress function that logs the progress of an operation in steps of 10%
+func LogProgress(message string) ProgressFunc {
+	lastStep := int64(-1)
+	return func(done int64, total int64) {
+		if total <= 0 {
+			return
+		}
+		step := done * 10 / total
+		if step == lastStep {
+			return
+		}
+		lastStep = step
+		log.WithFields(log.Fields{"done": done, "total": total}).Infof("%v: %d%%", message, step*10)
+	}
+}
+
+// ExtractOptions control the extraction of an archive
+type ExtractOptions struct {
+	// SkipLevels specifies how many directories from the top level are skipped over when
+	// constructing the target path (similar to the -p flag of the patch command); there may be
+	// no files in the skipped levels
+	SkipLevels int
+
+	Limits   Limits
+	Progress ProgressFunc // optional
+}
+
+// entryTimestamp is the fixed modification time for all archive entries created by Writer;
+// it is the earliest time representable in the zip format
+var entryTimestamp = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Extract extracts the files of a zip archive into a target file system. All entries are checked
+// before anything is extracted, so that an unsafe archive leaves the target untouched.
+func Extract(archivePath string, targetFs afero.Fs, options ExtractOptions) error {
+	zipReader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open zip file %q: %w", archivePath, err)
+	}
+	defer zipReader.Close()
+
+	limits := options.Limits.withDefaults()
+	if len(zipReader.File) > limits.MaxFiles {
+		return fmt.Errorf("zip file %q has %d entries, more than the limit of %d", archivePath, len(zipReader.File), limits.MaxFiles)
+	}
+
+	// check the entries and determine their target paths
+	targets := make([]string, len(zipReader.File))
+	var total int64
+	for i, file := range zipReader.File {
+		name := strings.ReplaceAll(file.Name, `\`, "/") // Windows zips
+		if issues := pathIssues(name); len(issues) > 0 {
+			return fmt.Errorf("%q: illegal entry in zip file %q: %v", file.Name, archivePath, strings.Join(issues, ", "))
+		}
+		if issues := modeIssues(file.Mode()); len(issues) > 0 {
+			return fmt.Errorf("%q: illegal entry in zip file %q: %v", file.Name, archivePath, strings.Join(issues, ", "))
+		}
+		if file.UncompressedSize64 > uint64(limits.MaxFileSize) {
+			return fmt.Errorf("%q: file size %d exceeds the limit of %d bytes", file.Name, file.UncompressedSize64, limits.MaxFileSize)
+		}
+		total += int64(file.UncompressedSize64)
+		if total > limits.MaxTotalSize {
+			return fmt.Errorf("zip file %q exceeds the limit of %d bytes of extracted files", archivePath, limits.MaxTotalSize)
+		}
+
+		elements := strings.Split(strings.Trim(path.Clean(name), "/"), "/")
+		if len(elements) <= options.SkipLevels {
+			if !file.FileInfo().IsDir() {
+				return fmt.Errorf("found a file, %q, in skipped levels (%v); not supported", file.Name, options.SkipLevels)
+			}
+			continue // a skipped directory
+		}
+		targets[i] = filepath.Join(elements[options.SkipLevels:]...)
+	}
+
+	// extract
+	var done int64
+	for i, file := range zipReader.File {
+		if targets[i] == "" {
+			continue
+		}
+		permissions := file.Mode() & os.ModePerm // ensure only permissions bits are used
+		if file.FileInfo().IsDir() {
+			if err := targetFs.MkdirAll(targets[i], permissions|0o700); err != nil { // ensure accessible by owner
+				return fmt.Errorf("failed to create directory %q: %w", targets[i], err)
+			}
+			continue
+		}
+		if err := targetFs.MkdirAll(filepath.Dir(targets[i]), 0o755); err != nil {
+			return fmt.Errorf("failed to create directory %q: %w", filepath.Dir(targets[i]), err)
+		}
+		n, err := extractFile(file, targetFs, targets[i], permissions, limits.MaxFileSize)
+		if err != nil {
+			return err
+		}
+		done += n
+		if done > limits.MaxTotalSize {
+			return fmt.Errorf("zip file %q exceeds the limit of %d bytes of extracted files", archivePath, limits.MaxTotalSize)
+		}
+		if options.Progress != nil {
+			options.Progress(done, total)
+		}
+	}
+	log.WithFields(log.Fields{"archive": archivePath, "files": len(zipReader.File), "bytes": done}).Info("Extracted zip file")
+
+	return nil
+}
+
+// extractFile copies a file from the archive, enforcing the size limit regardless of the size
+// declared in the archive, and returns the number of bytes written
+func extractFile(file *zip.File, targetFs afero.Fs, targetPath string, permissions fs.FileMode, maxSize int64) (int64, error) {
+	srcFile, err := file.Open()
+	if err != nil {
+		return 0, fmt.Errorf("failed to open zipped file %q: %w", file.Name, err)
+	}
+	defer srcFile.Close()
+
+	dstFile, err := targetFs.OpenFile(targetPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, permissions)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create destination file: %w", err)
+	}
+	defer dstFile.Close()
+
+	n, err := io.Copy(dstFile, io.LimitReader(srcFile, maxSize+1))
+	if err != nil {
+		return n, fmt.Errorf("failed to copy file contents of %q: %w", file.Name, err)
+	}
+	if n > maxSize {
+		return n, fmt.Errorf("%q: file size exceeds the limit of %d bytes", file.Name, maxSize)
+	}
+	return n, nil
+}
+
+// EntryIssues returns the reasons why an archive entry is not safe to extract, if any
+func EntryIssues(file *zip.File) []string {
+	issues := modeIssues(file.Mode())
+	if strings.Contains(file.Name, `\`) {
+		issues = append(issues, "path contains backslashes")
+	}
+	return append(issues, pathIssues(file.Name)...)
+}
+
+// modeIssues checks that an entry is a regular file or a directory
+func modeIssues(mode fs.FileMode) []string {
+	switch {
+	case mode&os.ModeSymlink != 0:
+		return []string{"symbolic link"}
+	case !mode.IsRegular() && !mode.IsDir():
+		return []string{"not a regular file or directory"}
+	}
+	return nil
+}
+
+// pathIssues checks that a slash-separated entry path stays within the target directory
+func pathIssues(name string) []string {
+	name = strings.TrimSuffix(name, "/")
+	switch {
+	case path.IsAbs(name) || filepath.VolumeName(name) != "" || strings.HasPrefix(name, `\`):
+		return []string{"absolute path"}
+	case hasParentElement(name):
+		return []string{"path leaves the archive"}
+	}
+	return nil
+}
+
+// hasParentElement returns true if any element of the slash-separated path is ".."
+func hasParentElement(name string) bool {
+	for _, element := range strings.Split(name, "/") {
+		if element == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+func (limits Limits) withDefaults() Limits {
+	if limits.MaxFiles <= 0 {
+		limits.MaxFiles = DefaultLimits.MaxFiles
+	}
+	if limits.MaxFileSize <= 0 {
+		limits.MaxFileSize = DefaultLimits.MaxFileSize
+	}
+	if limits.MaxTotalSize <= 0 {
+		limits.MaxTotalSize = DefaultLimits.MaxTotalSize
+	}
+	return limits
+}
+
+// Writer creates reproducible zip archives: the entries have fixed timestamps and normalized
+// permissions, so the archive depends only on the names and contents of the files added to it
+// (and on the order in which they are added)
+type Writer struct {
+	zipWriter *zip.Writer
+}
+
+// NewWriter returns a writer creating a zip archive in w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{zipWriter: zip.NewWriter(w)}
+}
+
+// AddFile adds a file or directory to the archive under the given slash- or OS-separated name
+func (w *Writer) AddFile(name string, sourcePath string, info fs.FileInfo) error {
+	if info.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("%q: symbolic links are not supported", sourcePath)
+	}
+	name = filepath.ToSlash(name)
+	header := &zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: entryTimestamp,
+	}
+	if info.IsDir() {
+		header.Name = strings.TrimSuffix(name, "/") + "/"
+		header.Method = zip.Store
+		header.SetMode(os.ModeDir | 0o755)
+	} else {
+		header.SetMode(0o644)
+	}
+
+	entryWriter, err := w.zipWriter.CreateHeader(header)
+	if err != nil {
+		return fmt.Errorf("failed to add %q to the archive: %w", name, err)
+	}
+	if info.IsDir() {
+		return nil
+	}
+
+	file, err := os.Open(sourcePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file %q: %w", sourcePath, err)
+	}
+	defer file.Close()
+	if _, err := io.Copy(entryWriter, file); err != nil {
+		return fmt.Errorf("failed to write file %q to the archive: %w", sourcePath, err)
+	}
+	return nil
+}
+
+// Close finishes writing the archive; it does not close the underlying writer
+func (w *Writer) Close() error {
+	return w.zipWriter.Close()
+}
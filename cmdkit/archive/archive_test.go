@@ -0,0 +1,172 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+type testEntry struct {
+	name    string
+	content string
+	mode    os.FileMode
+}
+
+// createTestArchive writes a zip file with the given entries and returns its path
+func createTestArchive(t *testing.T, entries []testEntry) string {
+	archivePath := filepath.Join(t.TempDir(), "test.zip")
+	file, err := os.Create(archivePath)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	zipWriter := zip.NewWriter(file)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate}
+		mode := entry.mode
+		if mode == 0 {
+			mode = 0o644
+		}
+		header.SetMode(mode)
+		writer, err := zipWriter.CreateHeader(header)
+		assert.NoError(t, err)
+		_, err = writer.Write([]byte(entry.content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zipWriter.Close())
+	return archivePath
+}
+
+func TestExtract(t *testing.T) {
+	archivePath := createTestArchive(t, []testEntry{
+		{name: "mysolution/", mode: os.ModeDir | 0o755},
+		{name: "mysolution/manifest.json", content: `{"name": "mysolution"}`},
+		{name: "mysolution/objects/a.json", content: `{}`},
+	})
+
+	targetFs := afero.NewMemMapFs()
+	var progress []int64
+	err := Extract(archivePath, targetFs, ExtractOptions{
+		SkipLevels: 1,
+		Progress:   func(done int64, total int64) { progress = append(progress, done) },
+	})
+	assert.NoError(t, err)
+
+	content, err := afero.ReadFile(targetFs, "manifest.json")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name": "mysolution"}`, string(content))
+	exists, _ := afero.Exists(targetFs, filepath.Join("objects", "a.json"))
+	assert.True(t, exists)
+	assert.Equal(t, []int64{22, 24}, progress)
+}
+
+func TestExtractRejectsUnsafeEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []testEntry
+		want    string
+	}{
+		{
+			name:    "zip slip",
+			entries: []testEntry{{name: "mysolution/../../evil.sh", content: "x"}},
+			want:    "path leaves the archive",
+		},
+		{
+			name:    "zip slip with backslashes",
+			entries: []testEntry{{name: `mysolution\..\..\evil.sh`, content: "x"}},
+			want:    "path leaves the archive",
+		},
+		{
+			name:    "absolute path",
+			entries: []testEntry{{name: "/etc/evil", content: "x"}},
+			want:    "absolute path",
+		},
+		{
+			name:    "symbolic link",
+			entries: []testEntry{{name: "mysolution/link", content: "/etc/passwd", mode: os.ModeSymlink | 0o777}},
+			want:    "symbolic link",
+		},
+		{
+			name:    "file in skipped level",
+			entries: []testEntry{{name: "manifest.json", content: "{}"}},
+			want:    "in skipped levels",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archivePath := createTestArchive(t, append([]testEntry{{name: "mysolution/ok.json", content: "{}"}}, tt.entries...))
+			targetFs := afero.NewMemMapFs()
+			err := Extract(archivePath, targetFs, ExtractOptions{SkipLevels: 1})
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.want)
+			}
+
+			// nothing is extracted from an unsafe archive
+			exists, _ := afero.Exists(targetFs, "ok.json")
+			assert.False(t, exists)
+		})
+	}
+}
+
+func TestExtractLimits(t *testing.T) {
+	archivePath := createTestArchive(t, []testEntry{
+		{name: "mysolution/a.json", content: strings.Repeat("a", 100)},
+		{name: "mysolution/b.json", content: strings.Repeat("b", 100)},
+	})
+
+	err := Extract(archivePath, afero.NewMemMapFs(), ExtractOptions{SkipLevels: 1, Limits: Limits{MaxFiles: 1}})
+	assert.ErrorContains(t, err, "more than the limit of 1")
+
+	err = Extract(archivePath, afero.NewMemMapFs(), ExtractOptions{SkipLevels: 1, Limits: Limits{MaxFileSize: 99}})
+	assert.ErrorContains(t, err, "exceeds the limit of 99 bytes")
+
+	err = Extract(archivePath, afero.NewMemMapFs(), ExtractOptions{SkipLevels: 1, Limits: Limits{MaxTotalSize: 150}})
+	assert.ErrorContains(t, err, "exceeds the limit of 150 bytes")
+
+	err = Extract(archivePath, afero.NewMemMapFs(), ExtractOptions{SkipLevels: 1, Limits: Limits{MaxFileSize: 100, MaxTotalSize: 200}})
+	assert.NoError(t, err)
+}
+
+func TestWriterIsReproducible(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "manifest.json"), []byte("{}"), 0o600))
+	info, err := os.Stat(filepath.Join(dir, "manifest.json"))
+	assert.NoError(t, err)
+
+	archives := []string{}
+	for i := 0; i < 2; i++ {
+		archivePath := filepath.Join(t.TempDir(), "test.zip")
+		file, err := os.Create(archivePath)
+		assert.NoError(t, err)
+		writer := NewWriter(file)
+		assert.NoError(t, writer.AddFile("mysolution/manifest.json", filepath.Join(dir, "manifest.json"), info))
+		assert.NoError(t, writer.Close())
+		file.Close()
+		content, err := os.ReadFile(archivePath)
+		assert.NoError(t, err)
+		archives = append(archives, string(content))
+
+		// change the file's metadata, which must not affect the archive
+		assert.NoError(t, os.Chmod(filepath.Join(dir, "manifest.json"), 0o755))
+		info, _ = os.Stat(filepath.Join(dir, "manifest.json"))
+	}
+	assert.Equal(t, archives[0], archives[1])
+}
@@ -0,0 +1,120 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsondoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Kinds of changes between two values
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// FieldChange is a difference between two JSON values at the leaf level
+type FieldChange struct {
+	Change string // added, removed or modified
+	Path   string // jq-style path of the field, e.g., `.attributeDefinitions.required[0]`
+	New    any    // new value, nil if removed
+	Old    any    // old value, nil if added
+}
+
+// Diff compares two normalized JSON values (e.g., two versions of a knowledge object) and
+// returns the changes from the old value to the new one, at the leaf level. Composite values
+// of added, removed or modified fields are converted to compact JSON strings.
+func Diff(newValue any, oldValue any) []FieldChange {
+	return diffValues("", newValue, oldValue)
+}
+
+func diffValues(path string, newValue any, oldValue any) []FieldChange {
+	switch newTyped := newValue.(type) {
+	case map[string]any:
+		oldTyped, ok := oldValue.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(newTyped)+len(oldTyped))
+		for key := range newTyped {
+			keys = append(keys, key)
+		}
+		for key := range oldTyped {
+			if _, found := newTyped[key]; !found {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		changes := []FieldChange{}
+		for _, key := range keys {
+			subPath := path + "." + key
+			if strings.ContainsAny(key, ".:[] ") {
+				subPath = fmt.Sprintf("%s[%q]", path, key)
+			}
+			newField, inNew := newTyped[key]
+			oldField, inOld := oldTyped[key]
+			switch {
+			case !inOld:
+				changes = append(changes, FieldChange{ChangeAdded, subPath, CompactValue(newField), nil})
+			case !inNew:
+				changes = append(changes, FieldChange{ChangeRemoved, subPath, nil, CompactValue(oldField)})
+			default:
+				changes = append(changes, diffValues(subPath, newField, oldField)...)
+			}
+		}
+		return changes
+	case []any:
+		oldTyped, ok := oldValue.([]any)
+		if !ok {
+			break
+		}
+		changes := []FieldChange{}
+		for i := 0; i < len(newTyped) || i < len(oldTyped); i++ {
+			subPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(oldTyped):
+				changes = append(changes, FieldChange{ChangeAdded, subPath, CompactValue(newTyped[i]), nil})
+			case i >= len(newTyped):
+				changes = append(changes, FieldChange{ChangeRemoved, subPath, nil, CompactValue(oldTyped[i])})
+			default:
+				changes = append(changes, diffValues(subPath, newTyped[i], oldTyped[i])...)
+			}
+		}
+		return changes
+	}
+
+	if reflect.DeepEqual(newValue, oldValue) {
+		return nil
+	}
+	if path == "" {
+		path = "."
+	}
+	return []FieldChange{{ChangeModified, path, CompactValue(newValue), CompactValue(oldValue)}}
+}
+
+// CompactValue converts composite values to compact JSON strings for display
+func CompactValue(v any) any {
+	switch v.(type) {
+	case map[string]any, []any:
+		if data, err := json.Marshal(v); err == nil {
+			return string(data)
+		}
+	}
+	return v
+}
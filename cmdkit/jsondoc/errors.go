@@ -0,0 +1,143 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsondoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ParseError is a syntax or structure error in a manifest or objects file, with
+// the position and the failing field, when known
+type ParseError struct {
+	File   string // path of the file, empty if not known
+	Line   int    // 1-based line, 0 if not known
+	Column int    // 1-based column, 0 if not known
+	Field  string // dotted path of the failing field, for structure errors
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	var sb strings.Builder
+	switch {
+	case e.File != "" && e.Line > 0:
+		sb.WriteString(fmt.Sprintf("%v:%d:%d: ", e.File, e.Line, e.Column))
+	case e.File != "":
+		sb.WriteString(e.File + ": ")
+	case e.Line > 0:
+		sb.WriteString(fmt.Sprintf("line %d, column %d: ", e.Line, e.Column))
+	}
+	if e.Field != "" {
+		sb.WriteString(fmt.Sprintf("field %q: ", e.Field))
+	}
+	sb.WriteString(e.Err.Error())
+	return sb.String()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseErrors is the report of all errors found while loading a set of files
+type ParseErrors []*ParseError
+
+func (errs ParseErrors) Error() string {
+	lines := make([]string, 0, len(errs))
+	for _, err := range errs {
+		lines = append(lines, err.Error())
+	}
+	return fmt.Sprintf("%d error(s) found:\n  %v", len(errs), strings.Join(lines, "\n  "))
+}
+
+// Add appends an error to the report, converting it to a ParseError for the file if needed
+func (errs *ParseErrors) Add(file string, err error) {
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		parseErr = &ParseError{Err: err}
+	}
+	if parseErr.File == "" {
+		parseErr.File = file
+	}
+	*errs = append(*errs, parseErr)
+}
+
+// ErrorOrNil returns the report as an error, or nil if there are no errors
+func (errs ParseErrors) ErrorOrNil() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// JoinParseErrors merges the errors from loading several sets of files into a single report;
+// it returns nil if all errors are nil
+func JoinParseErrors(errs ...error) error {
+	var report ParseErrors
+	for _, err := range errs {
+		var parseErrs ParseErrors
+		if errors.As(err, &parseErrs) {
+			report = append(report, parseErrs...)
+		} else if err != nil {
+			report.Add("", err)
+		}
+	}
+	return report.ErrorOrNil()
+}
+
+var yamlLineRegexp = regexp.MustCompile(`line (\d+)`)
+
+// NewJsonParseError converts an error from decoding JSON data into a ParseError with the
+// position of the error in the data
+func NewJsonParseError(data []byte, err error) *ParseError {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		line, column := getLineColumn(data, syntaxErr.Offset)
+		return &ParseError{Line: line, Column: column, Err: fmt.Errorf("syntax error: %w", err)}
+	case errors.As(err, &typeErr):
+		line, column := getLineColumn(data, typeErr.Offset)
+		return &ParseError{Line: line, Column: column, Field: typeErr.Field, Err: fmt.Errorf("expected %v, found %v", typeErr.Type, typeErr.Value)}
+	default:
+		return &ParseError{Err: err}
+	}
+}
+
+// NewYamlParseError converts an error from decoding YAML data into a ParseError with the
+// line of the (first) error
+func NewYamlParseError(err error) *ParseError {
+	parseErr := &ParseError{Err: fmt.Errorf("syntax error: %w", err)}
+	if m := yamlLineRegexp.FindStringSubmatch(err.Error()); m != nil {
+		parseErr.Line, _ = strconv.Atoi(m[1])
+		parseErr.Column = 1
+	}
+	return parseErr
+}
+
+// getLineColumn returns the 1-based line and column of a byte offset in the data
+func getLineColumn(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
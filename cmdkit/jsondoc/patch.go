@@ -0,0 +1,236 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsondoc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// PatchOperation is an operation of a JSON patch (RFC 6902)
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// ApplyPatchOperations applies the operations of a JSON patch (RFC 6902) to a document of
+// JSON values. Containers in the document may be modified in place.
+func ApplyPatchOperations(doc any, operations []PatchOperation) (any, error) {
+	for i, operation := range operations {
+		var err error
+		var value any
+		if operation.Value != nil {
+			if err := json.Unmarshal(operation.Value, &value); err != nil {
+				return nil, fmt.Errorf("operation %d: invalid value: %w", i, err)
+			}
+		}
+		switch operation.Op {
+		case "add":
+			doc, err = addJsonPointerValue(doc, operation.Path, value)
+		case "remove":
+			doc, _, err = removeJsonPointerValue(doc, operation.Path)
+		case "replace":
+			if operation.Path == "" {
+				doc = value
+			} else if doc, _, err = removeJsonPointerValue(doc, operation.Path); err == nil {
+				doc, err = addJsonPointerValue(doc, operation.Path, value)
+			}
+		case "move":
+			if doc, value, err = removeJsonPointerValue(doc, operation.From); err == nil {
+				doc, err = addJsonPointerValue(doc, operation.Path, value)
+			}
+		case "copy":
+			if value, err = getJsonPointerValue(doc, operation.From); err == nil {
+				value, err = deepCopy(value)
+			}
+			if err == nil {
+				doc, err = addJsonPointerValue(doc, operation.Path, value)
+			}
+		case "test":
+			var current any
+			if current, err = getJsonPointerValue(doc, operation.Path); err == nil && !reflect.DeepEqual(current, value) {
+				err = fmt.Errorf("test failed: value at %q is %v, not %v", operation.Path, current, value)
+			}
+		default:
+			err = fmt.Errorf("unknown operation %q", operation.Op)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%v %v): %w", i, operation.Op, operation.Path, err)
+		}
+	}
+	return doc, nil
+}
+
+// ApplyPatch applies a JSON patch (RFC 6902), given as a JSON array of operations, to a
+// document of JSON values, e.g., the data of a knowledge object
+func ApplyPatch(doc any, patch []byte) (any, error) {
+	var operations []PatchOperation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, fmt.Errorf("invalid JSON patch, expected an array of operations: %w", err)
+	}
+	return ApplyPatchOperations(doc, operations)
+}
+
+// deepCopy copies a JSON value, so that the copy shares no containers with the value
+func deepCopy(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var copied any
+	err = json.Unmarshal(data, &copied)
+	return copied, err
+}
+
+// parseJsonPointer splits a JSON pointer (RFC 6901) into its unescaped reference tokens
+func parseJsonPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q: must start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// getArrayIndex converts a reference token to an index in an array of the length; the index
+// may be equal to the length (or "-") only if forAdd is true
+func getArrayIndex(token string, length int, forAdd bool) (int, error) {
+	if token == "-" && forAdd {
+		return length, nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > length || (index == length && !forAdd) || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return index, nil
+}
+
+func getJsonPointerValue(doc any, pointer string) (any, error) {
+	tokens, err := parseJsonPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range tokens {
+		switch container := doc.(type) {
+		case map[string]any:
+			value, found := container[token]
+			if !found {
+				return nil, fmt.Errorf("field %q not found", token)
+			}
+			doc = value
+		case []any:
+			index, err := getArrayIndex(token, len(container), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = container[index]
+		default:
+			return nil, fmt.Errorf("cannot reference %q in a value that is not an object or array", token)
+		}
+	}
+	return doc, nil
+}
+
+// updateJsonPointerParent replaces the value at the pointer's parent with the result of update,
+// which is called with the parent value and the pointer's last reference token
+func updateJsonPointerParent(doc any, pointer string, update func(parent any, token string) (any, error)) (any, error) {
+	tokens, err := parseJsonPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("the operation cannot be applied to the whole document")
+	}
+	parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+	parent, err := getJsonPointerValue(doc, parentPointer)
+	if err != nil {
+		return nil, err
+	}
+	newParent, err := update(parent, tokens[len(tokens)-1])
+	if err != nil || parentPointer == "" {
+		return newParent, err
+	}
+
+	// arrays may have been reallocated, so set the new parent in its own parent
+	grandParent, _ := getJsonPointerValue(doc, parentPointer[:strings.LastIndex(parentPointer, "/")])
+	parentToken := tokens[len(tokens)-2]
+	switch container := grandParent.(type) {
+	case map[string]any:
+		container[parentToken] = newParent
+	case []any:
+		index, _ := getArrayIndex(parentToken, len(container), false)
+		container[index] = newParent
+	}
+	return doc, nil
+}
+
+func addJsonPointerValue(doc any, pointer string, value any) (any, error) {
+	if pointer == "" {
+		return value, nil
+	}
+	return updateJsonPointerParent(doc, pointer, func(parent any, token string) (any, error) {
+		switch container := parent.(type) {
+		case map[string]any:
+			container[token] = value
+			return container, nil
+		case []any:
+			index, err := getArrayIndex(token, len(container), true)
+			if err != nil {
+				return nil, err
+			}
+			container = append(container, nil)
+			copy(container[index+1:], container[index:])
+			container[index] = value
+			return container, nil
+		}
+		return nil, fmt.Errorf("cannot add %q to a value that is not an object or array", token)
+	})
+}
+
+func removeJsonPointerValue(doc any, pointer string) (any, any, error) {
+	var removed any
+	doc, err := updateJsonPointerParent(doc, pointer, func(parent any, token string) (any, error) {
+		switch container := parent.(type) {
+		case map[string]any:
+			value, found := container[token]
+			if !found {
+				return nil, fmt.Errorf("field %q not found", token)
+			}
+			removed = value
+			delete(container, token)
+			return container, nil
+		case []any:
+			index, err := getArrayIndex(token, len(container), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = container[index]
+			return append(container[:index], container[index+1:]...), nil
+		}
+		return nil, fmt.Errorf("cannot remove %q from a value that is not an object or array", token)
+	})
+	return doc, removed, err
+}
@@ -0,0 +1,35 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jsondoc provides the helpers shared by the commands that work with JSON and YAML
+// documents, such as solution files and knowledge objects: parse and schema errors, field-level
+// differences and JSON patches.
+package jsondoc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// FormatSchemaPointerError describes a schema violation with the JSON pointer of the offending
+// value, e.g., "/spec/replicas: Invalid type. Expected: integer, given: string"
+func FormatSchemaPointerError(resultErr gojsonschema.ResultError) string {
+	pointer := strings.TrimPrefix(resultErr.Context().String("/"), gojsonschema.STRING_CONTEXT_ROOT)
+	if pointer == "" {
+		pointer = "/"
+	}
+	return fmt.Sprintf("%v: %v", pointer, resultErr.Description())
+}
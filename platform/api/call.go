@@ -55,6 +55,12 @@ type Options struct {
 
 	// Context provides a Go context for the API call (nil is accepted and will be replaced with a default context)
 	Context context.Context
+
+	// UploadProgress displays the progress of sending the request body in the interactive spinner
+	UploadProgress bool
+
+	// Profile selects the context (access profile) to use for the call instead of the current one
+	Profile string
 }
 
 // JSONGet performs a GET request and parses the response as JSON
@@ -214,7 +220,7 @@ func httpRequest(method string, path string, body any, out any, options *Options
 		options = &Options{}
 	}
 
-	callCtx := newCallContext(options.Context, options.Quiet)
+	callCtx := newCallContext(options.Context, options.Quiet, options.Profile)
 	defer callCtx.stopSpinner(false) // ensure the spinner is not running when returning (belt & suspenders)
 
 	// force login if no token
@@ -240,7 +246,11 @@ func httpRequest(method string, path string, body any, out any, options *Options
 	}
 
 	// execute request, speculatively, assuming the auth token is valid
-	callCtx.startSpinner(fmt.Sprintf("Platform API call (%v %v)", req.Method, urlDisplayPath(req.URL)))
+	spinnerMsg := fmt.Sprintf("Platform API call (%v %v)", req.Method, urlDisplayPath(req.URL))
+	callCtx.startSpinner(spinnerMsg)
+	if options.UploadProgress {
+		trackUploadProgress(callCtx, req, spinnerMsg)
+	}
 	resp, err := client.Do(req)
 	if err != nil {
 		// nb: spinner will be stopped by defer
@@ -272,7 +282,11 @@ func httpRequest(method string, path string, body any, out any, options *Options
 		if err != nil {
 			return err // error should have enough context
 		}
-		callCtx.startSpinner(fmt.Sprintf("Platform API call, retry after login (%v %v)", req.Method, urlDisplayPath(req.URL)))
+		spinnerMsg = fmt.Sprintf("Platform API call, retry after login (%v %v)", req.Method, urlDisplayPath(req.URL))
+		callCtx.startSpinner(spinnerMsg)
+		if options.UploadProgress {
+			trackUploadProgress(callCtx, req, spinnerMsg)
+		}
 		resp, err = client.Do(req)
 		// leave the spinner until the outcome is finalized, return will stop/fail it
 		if err != nil {
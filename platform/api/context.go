@@ -39,12 +39,22 @@ var statusChar = map[bool]string{
 	true:  color.GreenString("\u2713"), // checkmark
 }
 
-func newCallContext(goContext context.Context, quiet bool) *callContext {
-	// get current config context
-	cfg := config.GetCurrentContext()
-	if cfg == nil {
-		log.Fatal(`Missing context; use "fsoc config create" to configure your context`)
-		panic("unreachable") // keep golintci happy (until it recognizes apex/log fatals)
+func newCallContext(goContext context.Context, quiet bool, profile string) *callContext {
+	// get current config context, unless a profile is specified
+	var cfg *config.Context
+	if profile == "" {
+		cfg = config.GetCurrentContext()
+		if cfg == nil {
+			log.Fatal(`Missing context; use "fsoc config create" to configure your context`)
+			panic("unreachable") // keep golintci happy (until it recognizes apex/log fatals)
+		}
+	} else {
+		var err error
+		cfg, err = config.GetContext(profile)
+		if err != nil {
+			log.Fatalf("Failed to use profile: %v", err)
+			panic("unreachable")
+		}
 	}
 	log.WithFields(log.Fields{"context": cfg.Name, "url": cfg.URL, "tenant": cfg.Tenant}).Info("Using context")
 
@@ -84,6 +94,14 @@ func (c *callContext) startSpinner(msg string) {
 	}
 }
 
+func (c *callContext) updateSpinner(msg string) {
+	if c.spinner != nil {
+		c.spinner.Lock()
+		c.spinner.Suffix = " " + msg
+		c.spinner.Unlock()
+	}
+}
+
 func (c *callContext) stopSpinner(ok bool) {
 	c.stopSpinnerHide()
 	if c.spinner != nil {
@@ -14,6 +14,13 @@
 
 package api
 
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+)
+
 type HttpStatusError struct {
 	Message    string // used only if WrappedError is nil
 	StatusCode int
@@ -30,3 +37,20 @@ func (e *HttpStatusError) Error() string {
 func (e *HttpStatusError) Unwrap() error {
 	return e.WrappedErr
 }
+
+// IsTransientError returns true if the error returned by an API call is likely to be
+// temporary, so that the call may succeed if retried: the request failed to reach the
+// platform or to complete (e.g., a dropped connection), or the platform responded with
+// a status indicating a temporary condition (rate limiting, gateway errors or unavailability).
+func IsTransientError(err error) bool {
+	var statusErr *HttpStatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
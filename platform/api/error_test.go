@@ -0,0 +1,48 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTransientError(t *testing.T) {
+	connErr := &url.Error{Op: "Post", URL: "https://example.com", Err: syscall.ECONNRESET}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection reset", fmt.Errorf("POST request failed: %w", connErr), true},
+		{"truncated response", fmt.Errorf("failed reading response: %w", io.ErrUnexpectedEOF), true},
+		{"too many requests", &HttpStatusError{StatusCode: 429}, true},
+		{"bad gateway", &HttpStatusError{StatusCode: 502}, true},
+		{"service unavailable", &HttpStatusError{StatusCode: 503}, true},
+		{"gateway timeout", &HttpStatusError{StatusCode: 504}, true},
+		{"bad request", &HttpStatusError{StatusCode: 400}, false},
+		{"internal server error", &HttpStatusError{StatusCode: 500}, false},
+		{"other error", errors.New("failed to marshal body data"), false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, IsTransientError(tt.err), tt.name)
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/apex/log"
 
@@ -35,6 +36,9 @@ var requiredSettings = map[string][]string{
 	config.AuthMethodJWT:              {"URL", "Token"}, // tenant is desired but may not be mandatory for all requests
 }
 
+// loginMutex serializes the updates of the config file with the credentials obtained by logins
+var loginMutex sync.Mutex
+
 // fieldToFlag maps a config.Context field to CLI flag name, so that we can display better
 // help/error message for missing fields
 var fieldToFlag = map[string]string{
@@ -52,7 +56,7 @@ var fieldToFlag = map[string]string{
 // Login respects different access profile types (when supported) to provide the correct
 // login mechanism for each.
 func Login() error {
-	callCtx := newCallContext(context.Background(), false)
+	callCtx := newCallContext(context.Background(), false, "")
 	defer callCtx.stopSpinner(false) // ensure not running when returning
 
 	return login(callCtx)
@@ -88,11 +92,22 @@ func login(callCtx *callContext) error {
 		return authErr
 	}
 
-	// update current context with logged in credentials (token(s)) to use
-	config.ReplaceCurrentContext(cfg)
+	// update the context with logged in credentials (token(s)) to use; logins
+	// may happen concurrently when calls use different profiles
+	loginMutex.Lock()
+	defer loginMutex.Unlock()
+	if cfg.Name == config.GetCurrentProfileName() {
+		config.ReplaceCurrentContext(cfg)
+	} else if err := config.UpsertContext(cfg); err != nil {
+		return err
+	}
 
 	// reload context
-	callCtx.cfg = config.GetCurrentContext()
+	reloaded, err := config.GetContext(cfg.Name)
+	if err != nil {
+		return err
+	}
+	callCtx.cfg = reloaded
 
 	return nil
 }
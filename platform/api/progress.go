@@ -0,0 +1,80 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/apex/log"
+)
+
+// progressReader counts the bytes read through it and reports the progress
+type progressReader struct {
+	reader     io.ReadCloser
+	total      int64
+	sent       int64
+	onProgress func(sent int64, total int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.sent += int64(n)
+	r.onProgress(r.sent, r.total)
+	return n, err
+}
+
+func (r *progressReader) Close() error {
+	return r.reader.Close()
+}
+
+// trackUploadProgress replaces the request body with one that displays the upload
+// progress in the call context's spinner
+func trackUploadProgress(callCtx *callContext, req *http.Request, msg string) {
+	if req.Body == nil || req.ContentLength <= 0 {
+		return
+	}
+	lastPercent := int64(-1)
+	req.Body = &progressReader{
+		reader: req.Body,
+		total:  req.ContentLength,
+		onProgress: func(sent int64, total int64) {
+			percent := sent * 100 / total
+			if percent == lastPercent {
+				return
+			}
+			lastPercent = percent
+			callCtx.updateSpinner(fmt.Sprintf("%v: uploaded %d%% of %v", msg, percent, humanizeBytes(total)))
+			if sent == total {
+				log.WithFields(log.Fields{"bytes": total}).Info("Request body uploaded")
+			}
+		},
+	}
+}
+
+// humanizeBytes formats a byte count using binary units (e.g., 1.5 MiB)
+func humanizeBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
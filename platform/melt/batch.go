@@ -0,0 +1,106 @@
+package melt
+
+// ItemCounts returns the numbers of data points, logs (including events) and spans (including
+// their children) of the data
+func (d *FsocData) ItemCounts() (dataPoints int, logs int, spans int) {
+	for _, e := range d.Melt {
+		for _, m := range e.Metrics {
+			dataPoints += len(m.DataPoints)
+		}
+		logs += len(e.Logs)
+		for _, s := range e.Spans {
+			spans += s.treeSize()
+		}
+	}
+	return dataPoints, logs, spans
+}
+
+// ItemCounts returns the numbers of data points, log records and spans of the OTLP requests
+func (d *OtlpData) ItemCounts() (dataPoints int, logs int, spans int) {
+	for _, req := range d.Metrics {
+		for _, rm := range req.ResourceMetrics {
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					dataPoints += len(m.GetGauge().GetDataPoints()) + len(m.GetSum().GetDataPoints()) + len(m.GetHistogram().GetDataPoints()) +
+						len(m.GetExponentialHistogram().GetDataPoints()) + len(m.GetSummary().GetDataPoints())
+				}
+			}
+		}
+	}
+	for _, req := range d.Logs {
+		for _, rl := range req.ResourceLogs {
+			for _, sl := range rl.ScopeLogs {
+				logs += len(sl.LogRecords)
+			}
+		}
+	}
+	for _, req := range d.Spans {
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans += len(ss.Spans)
+			}
+		}
+	}
+	return dataPoints, logs, spans
+}
+
+// SplitBySize splits the data into batches of up to a given number of data points, logs and spans,
+// in order, e.g., to send large data in several requests. The entities and metrics in each batch
+// are copies with only the items of the batch; a span is kept in the same batch as its children,
+// even if they are more than the batch size. A size of 0 or less returns the data as it is.
+func (d *FsocData) SplitBySize(size int) []*FsocData {
+	if size <= 0 {
+		return []*FsocData{d}
+	}
+	batches := []*FsocData{}
+	var batch *FsocData
+	var entity *Entity // copy of the current entity in the current batch
+	n := 0             // items in the current batch
+	entityFor := func(e *Entity, items int) *Entity {
+		if batch == nil || (n > 0 && n+items > size) {
+			batch = &FsocData{Melt: []*Entity{}}
+			batches = append(batches, batch)
+			entity, n = nil, 0
+		}
+		if entity == nil {
+			entity = &Entity{TypeName: e.TypeName, ID: e.ID, Attributes: e.Attributes, Relationships: e.Relationships}
+			batch.Melt = append(batch.Melt, entity)
+		}
+		n += items
+		return entity
+	}
+
+	for _, e := range d.Melt {
+		entity = nil
+		for _, m := range e.Metrics {
+			var metric *Metric // copy of the metric in the current batch
+			for _, dp := range m.DataPoints {
+				current := entity
+				ec := entityFor(e, 1)
+				if metric == nil || ec != current {
+					mc := *m
+					mc.DataPoints = []*DataPoint{}
+					metric = &mc
+					ec.AddMetric(metric)
+				}
+				metric.DataPoints = append(metric.DataPoints, dp)
+			}
+		}
+		for _, l := range e.Logs {
+			entityFor(e, 1).AddLog(l)
+		}
+		for _, s := range e.Spans {
+			entityFor(e, s.treeSize()).AddSpan(s)
+		}
+	}
+	return batches
+}
+
+// treeSize returns the number of spans of the span's tree, i.e., the span with its children
+func (s *Span) treeSize() int {
+	n := 1
+	for _, c := range s.Children {
+		n += c.treeSize()
+	}
+	return n
+}
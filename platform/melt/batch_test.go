@@ -0,0 +1,111 @@
+package melt
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	collmetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestFsocDataSplitBySize(t *testing.T) {
+	data := newTestReplayData()
+	root := NewSpan("", "", "root").AddChild(NewSpan("", "", "child"))
+	data.Melt[0].AddSpan(root)
+	dataPoints, logs, spans := data.ItemCounts()
+	require.Equal(t, []int{3, 1, 3}, []int{dataPoints, logs, spans})
+
+	batches := data.SplitBySize(2)
+
+	require.Len(t, batches, 4)
+	require.Len(t, batches[0].Melt[0].Metrics[0].DataPoints, 2)
+	require.Len(t, batches[1].Melt[0].Metrics[0].DataPoints, 1)
+	require.Len(t, batches[1].Melt[0].Logs, 1)
+	require.Len(t, batches[2].Melt[0].Spans, 1)
+	require.Equal(t, []*Span{root}, batches[3].Melt[0].Spans) // with its child, although bigger than the batch size
+	require.Equal(t, data.Melt[0].Attributes, batches[3].Melt[0].Attributes)
+
+	require.Equal(t, []*FsocData{data}, data.SplitBySize(0))
+	require.Len(t, data.Melt[0].Metrics[0].DataPoints, 3) // the original data is unchanged
+}
+
+func TestExportCompressed(t *testing.T) {
+	var received collmetrics.ExportMetricsServiceRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		reader, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		require.NoError(t, proto.Unmarshal(body, &received))
+	}))
+	defer server.Close()
+
+	e := newTestEntity()
+	e.AddMetric(NewMetric("geometry:area", "m2", "gauge", "double").AddDataPoint(1, 2, 100))
+	var kinds []string
+	exp := &Exporter{
+		Endpoint:    server.URL,
+		Compress:    true,
+		MaxInFlight: 1,
+		OnSent: func(kind string, size int, latency time.Duration) {
+			kinds = append(kinds, kind)
+			require.Positive(t, size)
+		},
+	}
+	require.NoError(t, exp.ExportMetrics([]*Entity{e}))
+
+	require.Equal(t, "geometry:area", received.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Name)
+	require.Equal(t, []string{pathMetrics}, kinds)
+}
+
+func TestExportRetries(t *testing.T) {
+	origRetryDelay := retryDelay
+	t.Cleanup(func() { retryDelay = origRetryDelay })
+	retryDelay = time.Millisecond
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch {
+		case r.URL.Path == "/v1/logs":
+			http.Error(w, "bad logs", http.StatusBadRequest)
+		case requests == 1:
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+		default:
+			resp, _ := proto.Marshal(&collmetrics.ExportMetricsServiceResponse{
+				PartialSuccess: &collmetrics.ExportMetricsPartialSuccess{RejectedDataPoints: 1, ErrorMessage: "unknown metric"},
+			})
+			w.Header().Set("Content-Type", "application/x-protobuf")
+			_, _ = w.Write(resp)
+		}
+	}))
+	defer server.Close()
+
+	e := newTestEntity()
+	e.AddMetric(NewMetric("geometry:area", "m2", "gauge", "double").AddDataPoint(1, 2, 100))
+	e.AddLog(NewLog())
+	var retries []int
+	var rejected int64
+	var failed []proto.Message
+	exp := &Exporter{
+		Endpoint:   server.URL,
+		Retries:    2,
+		OnRetry:    func(kind string, attempt int, err error) { retries = append(retries, attempt) },
+		OnRejected: func(kind string, n int64, message string) { rejected += n },
+		OnFailed:   func(kind string, payload proto.Message, err error) { failed = append(failed, payload) },
+	}
+
+	require.NoError(t, exp.ExportMetrics([]*Entity{e}))
+	require.Equal(t, []int{1}, retries)
+	require.Equal(t, int64(1), rejected)
+
+	require.Error(t, exp.ExportLogs([]*Entity{e}))
+	require.Equal(t, []int{1}, retries) // not transient
+	require.Len(t, failed, 1)
+	require.Equal(t, 3, requests)
+}
@@ -1,12 +1,20 @@
 package melt
 
 import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"reflect"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/apex/log"
 	colllogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
@@ -17,6 +25,10 @@ import (
 	metrics "go.opentelemetry.io/proto/otlp/metrics/v1"
 	resource "go.opentelemetry.io/proto/otlp/resource/v1"
 	spans "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/encoding/prototext"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
@@ -36,6 +48,8 @@ const (
 	keyAppdFMMEntityRelationships = "appd.fmm.entity.relations"
 	keyAppdIsEvent                = "appd.isevent"
 	keyAppdEventType              = "appd.event.type"
+	traceIDSize                   = 16
+	spanIDSize                    = 8
 )
 
 const (
@@ -44,15 +58,68 @@ const (
 	DumpFormatJson  = "json"
 	DumpFormatYaml  = "yaml"
 	DumpFormatHex   = "hex"
+
+	// DumpFormatOtlpJson dumps each payload as a line of OTLP JSON, as in the files of the OTLP
+	// JSON file exporter of the collector
+	DumpFormatOtlpJson = "otlp-json"
 )
 
+// otlpPaths maps the kinds of MELT data to their paths in the OTLP/HTTP protocol
+var otlpPaths = map[string]string{
+	pathMetrics: "v1/metrics",
+	pathLogs:    "v1/logs",
+	pathSpans:   "v1/traces",
+}
+
 // Exporter -  exporter for entities, metrics and logs
 type Exporter struct {
 	DumpFunc   func(text string)
 	DumpFormat string
 	DryRun     bool
+
+	// Endpoint is the URL of an OTLP/HTTP receiver, e.g., http://localhost:4318, to send the data
+	// to instead of the platform ingestion API, with the additional Headers
+	Endpoint string
+	Headers  map[string]string
+
+	// Protocol is how the data is sent to the platform: ProtocolHTTP (default) to the ingestion API
+	// or ProtocolGrpc to the OTLP/gRPC collector endpoint, at GrpcEndpoint if set (host:port or URL)
+	Protocol     string
+	GrpcEndpoint string
+	grpcConn     *grpc.ClientConn
+	grpcMutex    sync.Mutex // guards the creation of the connection by concurrent exports
+
+	// Compress gzips the payloads (with gRPC's gzip compressor for ProtocolGrpc)
+	Compress bool
+
+	// MaxInFlight limits the number of requests being sent at once by concurrent exports, 0 for
+	// no limit; Quiet suppresses the progress spinner of the API calls, e.g., for concurrent exports
+	MaxInFlight  int
+	Quiet        bool
+	inFlight     chan struct{}
+	inFlightOnce sync.Once
+
+	// OnSent, if set, is called after each request is sent successfully, with the kind of data
+	// (metrics, logs or trace), the size of the payload (compressed, if sent compressed over HTTP)
+	// and the time it took to send it; it may be called concurrently by concurrent exports
+	OnSent func(kind string, size int, latency time.Duration)
+
+	// Retries is the number of times a request that fails with a transient error, e.g., a dropped
+	// connection, rate limiting or an unavailable endpoint, is retried, with an increasing delay;
+	// OnRetry, if set, is called before each retry
+	Retries int
+	OnRetry func(kind string, attempt int, err error)
+
+	// OnRejected, if set, is called when the receiver accepts a request only partially, with the
+	// number of items it rejected and its message; OnFailed, if set, is called when a request fails,
+	// after its retries, with its payload, e.g., to save the data that was not sent
+	OnRejected func(kind string, rejected int64, message string)
+	OnFailed   func(kind string, payload proto.Message, err error)
 }
 
+// retryDelay is the delay before the first retry of a failed request; it doubles on each retry
+var retryDelay = time.Second
+
 // ExportMetrics - export metrics
 func (exp *Exporter) ExportMetrics(entities []*Entity) error {
 	emsr := exp.buildMetricsPayload(entities)
@@ -250,7 +317,7 @@ func (exp *Exporter) buildSpansPayload(entities []*Entity) *collspans.ExportTrac
 		}
 
 		sl := []*spans.Span{}
-		for _, s := range e.Spans {
+		for _, s := range flattenSpans(e.Spans, nil) {
 			sl = append(sl, exp.createOtelSpan(s))
 		}
 		ss := &spans.ScopeSpans{
@@ -384,18 +451,71 @@ func (exp *Exporter) createOtelLog(l *Log) *logs.LogRecord {
 	}
 	if l.Severity != "" {
 		otl.SeverityText = l.Severity
+		otl.SeverityNumber = severityNumber(l.Severity)
 	}
 
 	return otl
 }
 
+// severityNumber returns the OTLP severity number of a severity text, by its common names
+func severityNumber(severity string) logs.SeverityNumber {
+	severity = strings.ToLower(severity)
+	switch {
+	case strings.HasPrefix(severity, "trace"):
+		return logs.SeverityNumber_SEVERITY_NUMBER_TRACE
+	case strings.HasPrefix(severity, "debug"):
+		return logs.SeverityNumber_SEVERITY_NUMBER_DEBUG
+	case strings.HasPrefix(severity, "info"), severity == "notice":
+		return logs.SeverityNumber_SEVERITY_NUMBER_INFO
+	case strings.HasPrefix(severity, "warn"):
+		return logs.SeverityNumber_SEVERITY_NUMBER_WARN
+	case strings.HasPrefix(severity, "err"):
+		return logs.SeverityNumber_SEVERITY_NUMBER_ERROR
+	case strings.HasPrefix(severity, "fatal"), strings.HasPrefix(severity, "crit"), severity == "panic", severity == "emergency", severity == "alert":
+		return logs.SeverityNumber_SEVERITY_NUMBER_FATAL
+	default:
+		return logs.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED
+	}
+}
+
+// flattenSpans returns spans with their children, recursively, setting the trace id, parent span id
+// and missing times of children from their parents, and generating missing trace and span ids
+func flattenSpans(spanList []*Span, parent *Span) []*Span {
+	flattened := []*Span{}
+	for _, s := range spanList {
+		if parent != nil {
+			if s.TraceID == "" {
+				s.TraceID = parent.TraceID
+			}
+			if s.ParentSpanID == "" {
+				s.ParentSpanID = parent.SpanID
+			}
+			if s.StartTime == 0 {
+				s.StartTime = parent.StartTime
+			}
+			if s.EndTime == 0 {
+				s.EndTime = parent.EndTime
+			}
+		}
+		if s.TraceID == "" {
+			s.TraceID = randomID(traceIDSize)
+		}
+		if s.SpanID == "" {
+			s.SpanID = randomID(spanIDSize)
+		}
+		flattened = append(flattened, s)
+		flattened = append(flattened, flattenSpans(s.Children, s)...)
+	}
+	return flattened
+}
+
 func (exp *Exporter) createOtelSpan(t *Span) *spans.Span {
 	ots := &spans.Span{
 		Name:              t.Name,
-		TraceId:           []byte(t.TraceID),
-		SpanId:            []byte(t.SpanID),
+		TraceId:           otlpID(t.TraceID, traceIDSize),
+		SpanId:            otlpID(t.SpanID, spanIDSize),
 		TraceState:        t.TraceState,
-		ParentSpanId:      []byte(t.ParentSpanID),
+		ParentSpanId:      otlpID(t.ParentSpanID, spanIDSize),
 		Kind:              spans.Span_SpanKind(t.Kind),
 		StartTimeUnixNano: uint64(t.StartTime),
 		EndTimeUnixNano:   uint64(t.EndTime),
@@ -414,8 +534,8 @@ func (exp *Exporter) createOtelSpan(t *Span) *spans.Span {
 	// links
 	for _, l := range t.Links {
 		ots.Links = append(ots.Links, &spans.Span_Link{
-			TraceId:    []byte(l.TraceID),
-			SpanId:     []byte(l.SpanID),
+			TraceId:    otlpID(l.TraceID, traceIDSize),
+			SpanId:     otlpID(l.SpanID, spanIDSize),
 			TraceState: l.TraceState,
 			Attributes: toKeyValueList(l.Attributes),
 		})
@@ -431,14 +551,28 @@ func (exp *Exporter) createOtelSpan(t *Span) *spans.Span {
 	return ots
 }
 
-func (exp *Exporter) exportHTTP(path string, m protoreflect.ProtoMessage) error {
-
-	options := api.Options{
-		Headers: map[string]string{
-			"Content-Type": "application/x-protobuf",
-			"Accept":       "application/x-protobuf",
-		},
+// otlpID converts a trace or span id to its OTLP bytes: hex ids (possibly with dashes, e.g., UUIDs)
+// are decoded, truncated to the size if longer, and other ids, e.g., human-readable names, are
+// hashed, so that spans can refer to their parents by any id
+func otlpID(id string, size int) []byte {
+	if id == "" {
+		return nil
+	}
+	if b, err := hex.DecodeString(strings.ReplaceAll(id, "-", "")); err == nil && len(b) >= size {
+		return b[:size]
 	}
+	hash := sha256.Sum256([]byte(id))
+	return hash[:size]
+}
+
+// randomID returns a random hex id of the size in bytes
+func randomID(size int) string {
+	b := make([]byte, size)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (exp *Exporter) exportHTTP(path string, m protoreflect.ProtoMessage) error {
 
 	// marshal into protobuf
 	data, err := proto.Marshal(m)
@@ -450,29 +584,193 @@ func (exp *Exporter) exportHTTP(path string, m protoreflect.ProtoMessage) error
 	if exp.DumpFunc != nil {
 		dumpPayload(m, exp.DumpFormat, exp.DumpFunc)
 	}
+	if exp.DryRun {
+		return nil
+	}
 
-	// send data
-	if !exp.DryRun {
-		apiPath := "data/v1/" + path
-		// post to API
-		err = api.HTTPPost(apiPath, data, nil, &options)
-		if err != nil {
-			hintAboutPermissions(err)
+	// compress data if requested, unless gRPC does it
+	if exp.Compress && (exp.Endpoint != "" || exp.Protocol != ProtocolGrpc) {
+		if data, err = gzipPayload(data); err != nil {
 			return err
 		}
+	}
+
+	// send data, within the limit of requests in flight
+	exp.inFlightOnce.Do(func() {
+		if exp.MaxInFlight > 0 {
+			exp.inFlight = make(chan struct{}, exp.MaxInFlight)
+		}
+	})
+	if exp.inFlight != nil {
+		exp.inFlight <- struct{}{}
+		defer func() { <-exp.inFlight }()
+	}
+	delay := retryDelay
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		switch {
+		case exp.Endpoint != "":
+			err = exp.exportOTLP(path, data)
+		case exp.Protocol == ProtocolGrpc:
+			err = exp.exportGrpc(path, m)
+		default:
+			err = exp.exportAPI(path, data)
+		}
+		if err == nil && exp.OnSent != nil {
+			exp.OnSent(path, len(data), time.Since(start))
+		}
+		if err == nil || attempt > exp.Retries || !isTransientError(err) {
+			break
+		}
+		log.WithFields(log.Fields{"kind": path, "attempt": attempt, "error": err.Error()}).Warnf("Failed to send MELT data, retrying in %v", delay)
+		if exp.OnRetry != nil {
+			exp.OnRetry(path, attempt, err)
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+	if err != nil && exp.OnFailed != nil {
+		exp.OnFailed(path, m, err)
+	}
+	return err
+}
+
+// isTransientError returns whether a request that failed may succeed if retried: API and OTLP/HTTP
+// errors are classified as for API calls, gRPC errors by their status code
+func isTransientError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded, codes.Aborted:
+		return true
+	}
+	return api.IsTransientError(err)
+}
+
+// exportResponse returns an empty response to the export request of a kind of MELT data
+func exportResponse(path string) proto.Message {
+	switch path {
+	case pathMetrics:
+		return &collmetrics.ExportMetricsServiceResponse{}
+	case pathLogs:
+		return &colllogs.ExportLogsServiceResponse{}
+	default:
+		return &collspans.ExportTraceServiceResponse{}
+	}
+}
+
+// reportPartialSuccess reports the items that the receiver of a request rejected, if any, as given
+// in its response
+func (exp *Exporter) reportPartialSuccess(path string, resp proto.Message) {
+	var rejected int64
+	var message string
+	switch r := resp.(type) {
+	case *collmetrics.ExportMetricsServiceResponse:
+		rejected, message = r.GetPartialSuccess().GetRejectedDataPoints(), r.GetPartialSuccess().GetErrorMessage()
+	case *colllogs.ExportLogsServiceResponse:
+		rejected, message = r.GetPartialSuccess().GetRejectedLogRecords(), r.GetPartialSuccess().GetErrorMessage()
+	case *collspans.ExportTraceServiceResponse:
+		rejected, message = r.GetPartialSuccess().GetRejectedSpans(), r.GetPartialSuccess().GetErrorMessage()
+	}
+	if rejected == 0 && message == "" {
+		return
+	}
+	log.Warnf("The receiver rejected %d of the %v: %v", rejected, path, message)
+	if exp.OnRejected != nil {
+		exp.OnRejected(path, rejected, message)
+	}
+}
+
+// exportAPI posts a protobuf payload to the platform ingestion API
+func (exp *Exporter) exportAPI(path string, data []byte) error {
+	options := api.Options{
+		Headers: map[string]string{
+			"Content-Type": "application/x-protobuf",
+			"Accept":       "application/x-protobuf",
+		},
+		Quiet: exp.Quiet,
+	}
+	if exp.Compress {
+		options.Headers["Content-Encoding"] = "gzip"
+	}
 
-		// log traceresponse
-		tr := ""
-		if trh, ok := options.ResponseHeaders["Traceresponse"]; ok {
-			tr = trh[0] // first value only
+	apiPath := "data/v1/" + path
+	// post to API
+	err := api.HTTPPost(apiPath, data, nil, &options)
+	if err != nil {
+		hintAboutPermissions(err)
+		return err
+	}
+
+	// log traceresponse
+	tr := ""
+	if trh, ok := options.ResponseHeaders["Traceresponse"]; ok {
+		tr = trh[0] // first value only
+	}
+	log.WithFields(log.Fields{
+		"kind":           path,
+		"path":           apiPath,
+		"trace_response": tr,
+	}).Info("Sent MELT data")
+	return nil
+}
+
+// gzipPayload compresses a payload with gzip
+func gzipPayload(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress MELT data: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress MELT data: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// exportOTLP sends a protobuf payload to the OTLP/HTTP endpoint of the exporter
+func (exp *Exporter) exportOTLP(path string, data []byte) error {
+	url := strings.TrimSuffix(exp.Endpoint, "/") + "/" + otlpPaths[path]
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request to %q: %w", url, err)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	if exp.Compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for name, value := range exp.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send MELT data to %q: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &api.HttpStatusError{
+			StatusCode: resp.StatusCode,
+			WrappedErr: fmt.Errorf("failed to send MELT data to %q: %v %s", url, resp.Status, strings.TrimSpace(string(body))),
+		}
+	}
+	if body, err := io.ReadAll(resp.Body); err == nil && len(body) > 0 {
+		response := exportResponse(path)
+		if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+			err = protojson.Unmarshal(body, response)
+		} else {
+			err = proto.Unmarshal(body, response)
+		}
+		if err != nil {
+			log.Warnf("Failed to parse the response to the %v sent: %v", path, err)
+		} else {
+			exp.reportPartialSuccess(path, response)
 		}
-		log.WithFields(log.Fields{
-			"kind":           path,
-			"path":           apiPath,
-			"trace_response": tr,
-		}).Info("Sent MELT data")
 	}
 
+	log.WithFields(log.Fields{
+		"kind": path,
+		"url":  url,
+	}).Info("Sent MELT data")
 	return nil
 }
 
@@ -543,6 +841,8 @@ func dumpPayload(m proto.Message, format string, writer func(string)) {
 		b, err = json.MarshalIndent(m, "", output.JsonIndent)
 	case DumpFormatYaml:
 		b, err = yaml.Marshal(m)
+	case DumpFormatOtlpJson:
+		b, err = protojson.Marshal(m)
 	case DumpFormatHex:
 		b, err = proto.Marshal(m)
 		if err == nil {
@@ -567,7 +867,8 @@ func dumpPayload(m proto.Message, format string, writer func(string)) {
 func hintAboutPermissions(err error) {
 	// provide a detailed hint if it is a permissions error and using a profile that is not an agent principal
 	var statusError *api.HttpStatusError
-	if errors.As(err, &statusError) && statusError.StatusCode == http.StatusForbidden {
+	forbidden := errors.As(err, &statusError) && statusError.StatusCode == http.StatusForbidden
+	if forbidden || status.Code(err) == codes.PermissionDenied {
 		// provide more info if not using an agent principal auth type in the profile
 		ctx := config.GetCurrentContext()
 		if ctx.AuthMethod != config.AuthMethodAgentPrincipal {
@@ -1,12 +1,19 @@
 package melt
 
 import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
+	collmetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
 	common "go.opentelemetry.io/proto/otlp/common/v1"
 	metrics "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 )
 
 // TestBuildMetricsPayload - Test metrics payload
@@ -164,6 +171,55 @@ func TestBuildEventsPayload(t *testing.T) {
 	}
 }
 
+func TestExportMetricsToEndpoint(t *testing.T) {
+	var paths []string
+	var received collmetrics.ExportMetricsServiceRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		require.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		require.Equal(t, "secret", r.Header.Get("X-Api-Key"))
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, proto.Unmarshal(body, &received))
+	}))
+	defer server.Close()
+
+	e := newTestEntity()
+	e.AddMetric(NewMetric("geometry:area", "m2", "gauge", "double").AddDataPoint(1, 2, 100))
+	var dumped []string
+	exp := &Exporter{
+		Endpoint:   server.URL + "/",
+		Headers:    map[string]string{"X-Api-Key": "secret"},
+		DumpFunc:   func(text string) { dumped = append(dumped, text) },
+		DumpFormat: DumpFormatOtlpJson,
+	}
+	require.NoError(t, exp.ExportMetrics([]*Entity{e}))
+
+	require.Equal(t, []string{"/v1/metrics"}, paths)
+	require.Len(t, received.ResourceMetrics, 1)
+	require.Equal(t, "geometry:area", received.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Name)
+	require.Len(t, dumped, 1)
+	require.True(t, strings.HasSuffix(dumped[0], "}\n"))
+	require.NotContains(t, strings.TrimSuffix(dumped[0], "\n"), "\n")
+	var dumpedRequest collmetrics.ExportMetricsServiceRequest
+	require.NoError(t, protojson.Unmarshal([]byte(dumped[0]), &dumpedRequest))
+	require.True(t, proto.Equal(&received, &dumpedRequest))
+}
+
+func TestExportMetricsToEndpointFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	e := newTestEntity()
+	e.AddMetric(NewMetric("geometry:area", "m2", "gauge", "double").AddDataPoint(1, 2, 100))
+	exp := &Exporter{Endpoint: server.URL}
+	err := exp.ExportMetrics([]*Entity{e})
+
+	require.ErrorContains(t, err, "429 Too Many Requests quota exceeded")
+}
+
 func assertAttributes(t *testing.T, expected map[string]interface{}, actual []*common.KeyValue) {
 	// fmt.Printf("Expected: %+v, Actual: %+v\n", expected, actual)
 	for k, v := range expected {
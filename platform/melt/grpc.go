@@ -0,0 +1,183 @@
+package melt
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	colllogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collmetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collspans "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/cisco-open/fsoc/config"
+	"github.com/cisco-open/fsoc/platform/api"
+)
+
+const (
+	// ProtocolHTTP sends the data to the platform's ingestion API (the default)
+	ProtocolHTTP = "http"
+
+	// ProtocolGrpc sends the data with OTLP/gRPC to the platform's collector endpoint, as agents do
+	ProtocolGrpc = "grpc"
+)
+
+// grpcTimeout is the time limit of each OTLP/gRPC export request
+const grpcTimeout = 30 * time.Second
+
+// exportGrpc sends an OTLP export request to the OTLP/gRPC endpoint of the platform, with the
+// access token of the current context, logging in again once if the token is rejected
+func (exp *Exporter) exportGrpc(path string, m protoreflect.ProtoMessage) error {
+	if err := exp.connectGrpc(); err != nil {
+		return err
+	}
+
+	token, err := accessToken(false)
+	if err != nil {
+		return err
+	}
+	err = exp.invokeGrpc(path, m, token)
+	if status.Code(err) == codes.Unauthenticated {
+		log.Info("The access token was rejected, logging in again")
+		if token, err = accessToken(true); err != nil {
+			return err
+		}
+		err = exp.invokeGrpc(path, m, token)
+	}
+	if err != nil {
+		hintAboutPermissions(err)
+		return fmt.Errorf("failed to send MELT data to %q: %w", exp.grpcConn.Target(), err)
+	}
+	return nil
+}
+
+// connectGrpc creates the connection to the OTLP/gRPC endpoint, unless already connected
+func (exp *Exporter) connectGrpc() error {
+	exp.grpcMutex.Lock()
+	defer exp.grpcMutex.Unlock()
+	if exp.grpcConn != nil {
+		return nil
+	}
+	target, creds, err := exp.grpcTarget()
+	if err != nil {
+		return err
+	}
+	options := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if exp.Compress {
+		options = append(options, grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)))
+	}
+	exp.grpcConn, err = grpc.NewClient(target, options...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %q: %w", target, err)
+	}
+	return nil
+}
+
+// accessToken returns the access token of the current context, logging in if it has none or if
+// forced to
+func accessToken(forceLogin bool) (string, error) {
+	ctx := config.GetCurrentContext()
+	if ctx == nil {
+		return "", fmt.Errorf("no profile selected to send the MELT data with")
+	}
+	if ctx.Token != "" && !forceLogin {
+		return ctx.Token, nil
+	}
+	if err := api.Login(); err != nil {
+		return "", fmt.Errorf("failed to log in: %w", err)
+	}
+	return config.GetCurrentContext().Token, nil
+}
+
+// invokeGrpc calls the export method of the OTLP service for a kind of MELT data, with an access token
+func (exp *Exporter) invokeGrpc(path string, m protoreflect.ProtoMessage, token string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), grpcTimeout)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+
+	var header metadata.MD
+	var resp proto.Message
+	var err error
+	switch path {
+	case pathMetrics:
+		resp, err = collmetrics.NewMetricsServiceClient(exp.grpcConn).Export(ctx, m.(*collmetrics.ExportMetricsServiceRequest), grpc.Header(&header))
+	case pathLogs:
+		resp, err = colllogs.NewLogsServiceClient(exp.grpcConn).Export(ctx, m.(*colllogs.ExportLogsServiceRequest), grpc.Header(&header))
+	case pathSpans:
+		resp, err = collspans.NewTraceServiceClient(exp.grpcConn).Export(ctx, m.(*collspans.ExportTraceServiceRequest), grpc.Header(&header))
+	default:
+		return fmt.Errorf("(bug) unknown kind of MELT data %q", path)
+	}
+	if err != nil {
+		return err
+	}
+	exp.reportPartialSuccess(path, resp)
+
+	// log traceresponse
+	tr := ""
+	if values := header.Get("traceresponse"); len(values) > 0 {
+		tr = values[0] // first value only
+	}
+	log.WithFields(log.Fields{
+		"kind":           path,
+		"target":         exp.grpcConn.Target(),
+		"trace_response": tr,
+	}).Info("Sent MELT data")
+	return nil
+}
+
+// grpcTarget returns the address of the OTLP/gRPC endpoint, by default the host of the current
+// context's URL on port 443, with its transport credentials: plaintext for http:// URLs, TLS
+// otherwise
+func (exp *Exporter) grpcTarget() (string, credentials.TransportCredentials, error) {
+	endpoint := exp.GrpcEndpoint
+	if endpoint == "" {
+		if ctx := config.GetCurrentContext(); ctx != nil {
+			endpoint = ctx.URL
+		}
+		if endpoint == "" {
+			return "", nil, fmt.Errorf("no URL in the current profile to send the MELT data to")
+		}
+	}
+	secure := true
+	if strings.Contains(endpoint, "://") {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid OTLP/gRPC endpoint %q: %w", endpoint, err)
+		}
+		secure = u.Scheme != "http"
+		endpoint = u.Host
+		if u.Port() == "" {
+			endpoint = net.JoinHostPort(u.Hostname(), "443")
+		}
+	} else if _, _, err := net.SplitHostPort(endpoint); err != nil {
+		endpoint = net.JoinHostPort(endpoint, "443")
+	}
+	if !secure {
+		return endpoint, insecure.NewCredentials(), nil
+	}
+	return endpoint, credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12}), nil
+}
+
+// Close releases the connection to the OTLP/gRPC endpoint, if any
+func (exp *Exporter) Close() error {
+	if exp.grpcConn == nil {
+		return nil
+	}
+	err := exp.grpcConn.Close()
+	exp.grpcConn = nil
+	return err
+}
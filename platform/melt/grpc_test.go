@@ -0,0 +1,68 @@
+package melt
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	collmetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+type testMetricsService struct {
+	collmetrics.UnimplementedMetricsServiceServer
+	requests      []*collmetrics.ExportMetricsServiceRequest
+	authorization []string
+}
+
+func (s *testMetricsService) Export(ctx context.Context, req *collmetrics.ExportMetricsServiceRequest) (*collmetrics.ExportMetricsServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.authorization = md.Get("authorization")
+	s.requests = append(s.requests, req)
+	return &collmetrics.ExportMetricsServiceResponse{}, nil
+}
+
+func TestInvokeGrpc(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	service := &testMetricsService{}
+	server := grpc.NewServer()
+	collmetrics.RegisterMetricsServiceServer(server, service)
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	exp := &Exporter{}
+	exp.grpcConn, err = grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer exp.Close()
+
+	e := newTestEntity()
+	e.AddMetric(NewMetric("geometry:area", "m2", "gauge", "double").AddDataPoint(100, 200, 1))
+	require.NoError(t, exp.invokeGrpc(pathMetrics, exp.buildMetricsPayload([]*Entity{e}), "token"))
+
+	require.Len(t, service.requests, 1)
+	require.Equal(t, "geometry:area", service.requests[0].ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Name)
+	require.Equal(t, []string{"Bearer token"}, service.authorization)
+}
+
+func TestGrpcTarget(t *testing.T) {
+	for endpoint, expected := range map[string]string{
+		"collector.example.com":              "collector.example.com:443",
+		"collector.example.com:4317":         "collector.example.com:4317",
+		"https://tenant.example.com":         "tenant.example.com:443",
+		"http://localhost:4317":              "localhost:4317",
+		"https://tenant.example.com:8443/ui": "tenant.example.com:8443",
+	} {
+		target, creds, err := (&Exporter{GrpcEndpoint: endpoint}).grpcTarget()
+		require.NoError(t, err)
+		require.Equal(t, expected, target)
+		if endpoint == "http://localhost:4317" {
+			require.Equal(t, "insecure", creds.Info().SecurityProtocol)
+		} else {
+			require.Equal(t, "tls", creds.Info().SecurityProtocol)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package melt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	colllogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collmetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collspans "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// OtlpData - OTLP export requests read from files, e.g., as written by the file exporter of the
+// OpenTelemetry collector, ready to be sent as they are
+type OtlpData struct {
+	Metrics []*collmetrics.ExportMetricsServiceRequest
+	Logs    []*colllogs.ExportLogsServiceRequest
+	Spans   []*collspans.ExportTraceServiceRequest
+}
+
+// otlpIdKeys are the keys of the trace and span ids in OTLP JSON, which are hex-encoded rather
+// than base64-encoded as protojson expects
+var otlpIdKeys = map[string]bool{"traceId": true, "spanId": true, "parentSpanId": true}
+
+// ParseOtlpJson parses OTLP JSON with one export request per line, of any kind of data, as
+// written by the file exporter of the collector with the json format
+func ParseOtlpJson(data []byte) (*OtlpData, error) {
+	otlpData := &OtlpData{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := otlpData.addJson(line); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return otlpData, nil
+}
+
+func (d *OtlpData) addJson(line []byte) error {
+	var request map[string]any
+	if err := json.Unmarshal(line, &request); err != nil {
+		return fmt.Errorf("invalid OTLP JSON: %w", err)
+	}
+	hexIdsToBase64(request)
+	line, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case request["resourceMetrics"] != nil:
+		m := &collmetrics.ExportMetricsServiceRequest{}
+		err = protojson.Unmarshal(line, m)
+		d.Metrics = append(d.Metrics, m)
+	case request["resourceLogs"] != nil:
+		l := &colllogs.ExportLogsServiceRequest{}
+		err = protojson.Unmarshal(line, l)
+		d.Logs = append(d.Logs, l)
+	case request["resourceSpans"] != nil:
+		s := &collspans.ExportTraceServiceRequest{}
+		err = protojson.Unmarshal(line, s)
+		d.Spans = append(d.Spans, s)
+	default:
+		return fmt.Errorf("no resourceMetrics, resourceLogs or resourceSpans in OTLP JSON")
+	}
+	if err != nil {
+		return fmt.Errorf("invalid OTLP JSON: %w", err)
+	}
+	return nil
+}
+
+// otlpJsonToProtojson converts OTLP JSON to the JSON that protojson parses, with base64 ids
+func otlpJsonToProtojson(data []byte) ([]byte, error) {
+	var request any
+	if err := json.Unmarshal(data, &request); err != nil {
+		return nil, fmt.Errorf("invalid OTLP JSON: %w", err)
+	}
+	hexIdsToBase64(request)
+	return json.Marshal(request)
+}
+
+// hexIdsToBase64 converts the hex-encoded trace and span ids of OTLP JSON to base64, in place
+func hexIdsToBase64(value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if s, ok := item.(string); ok && otlpIdKeys[key] && (len(s) == 32 || len(s) == 16) {
+				if id, err := hex.DecodeString(s); err == nil {
+					v[key] = base64.StdEncoding.EncodeToString(id)
+				}
+				continue
+			}
+			hexIdsToBase64(item)
+		}
+	case []any:
+		for _, item := range v {
+			hexIdsToBase64(item)
+		}
+	}
+}
+
+// ParseOtlpProto parses OTLP protobuf export requests, either a sequence of requests each prefixed
+// with its size as a 4-byte big-endian integer, as written by the file exporter of the collector
+// with the proto format, or a single request. The kind of data of each request is the one that
+// parses without unknown fields.
+func ParseOtlpProto(data []byte) (*OtlpData, error) {
+	messages, ok := splitSizePrefixed(data)
+	if !ok {
+		messages = [][]byte{data}
+	}
+	otlpData := &OtlpData{}
+	for i, message := range messages {
+		if err := otlpData.addProto(message); err != nil {
+			return nil, fmt.Errorf("request %d: %w", i+1, err)
+		}
+	}
+	return otlpData, nil
+}
+
+func (d *OtlpData) addProto(message []byte) error {
+	m := &collmetrics.ExportMetricsServiceRequest{}
+	if proto.Unmarshal(message, m) == nil && len(m.ResourceMetrics) > 0 && !hasUnknownFields(m.ProtoReflect()) {
+		d.Metrics = append(d.Metrics, m)
+		return nil
+	}
+	l := &colllogs.ExportLogsServiceRequest{}
+	if proto.Unmarshal(message, l) == nil && len(l.ResourceLogs) > 0 && !hasUnknownFields(l.ProtoReflect()) {
+		d.Logs = append(d.Logs, l)
+		return nil
+	}
+	s := &collspans.ExportTraceServiceRequest{}
+	if proto.Unmarshal(message, s) == nil && len(s.ResourceSpans) > 0 && !hasUnknownFields(s.ProtoReflect()) {
+		d.Spans = append(d.Spans, s)
+		return nil
+	}
+	return fmt.Errorf("not an OTLP metrics, logs or traces export request")
+}
+
+// splitSizePrefixed splits data into messages each prefixed with its size, returning false if the
+// data are not such a sequence
+func splitSizePrefixed(data []byte) ([][]byte, bool) {
+	var messages [][]byte
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, false
+		}
+		size := binary.BigEndian.Uint32(data)
+		if size == 0 || uint64(size) > uint64(len(data)-4) {
+			return nil, false
+		}
+		messages = append(messages, data[4:4+size])
+		data = data[4+size:]
+	}
+	return messages, len(messages) > 0
+}
+
+// hasUnknownFields tells whether a message, or any message in it, has fields unknown to its type
+func hasUnknownFields(m protoreflect.Message) bool {
+	if len(m.GetUnknown()) > 0 {
+		return true
+	}
+	unknown := false
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			list := v.List()
+			for i := 0; i < list.Len() && !unknown; i++ {
+				unknown = hasUnknownFields(list.Get(i).Message())
+			}
+		case fd.IsMap():
+			// no OTLP request has maps of messages
+		case fd.Message() != nil:
+			unknown = hasUnknownFields(v.Message())
+		}
+		return !unknown
+	})
+	return unknown
+}
+
+// ExportOtlp - export OTLP requests as they are, in sections (metrics, logs, spans); section is
+// called before each section with requests
+func (exp *Exporter) ExportOtlp(d *OtlpData, section func(name string)) error {
+	if len(d.Metrics) > 0 {
+		section("Metrics")
+	}
+	for _, m := range d.Metrics {
+		if err := exp.exportHTTP(pathMetrics, m); err != nil {
+			return fmt.Errorf("failed to export metrics: %w", err)
+		}
+	}
+	if len(d.Logs) > 0 {
+		section("Logs")
+	}
+	for _, l := range d.Logs {
+		if err := exp.exportHTTP(pathLogs, l); err != nil {
+			return fmt.Errorf("failed to export logs: %w", err)
+		}
+	}
+	if len(d.Spans) > 0 {
+		section("Spans")
+	}
+	for _, s := range d.Spans {
+		if err := exp.exportHTTP(pathSpans, s); err != nil {
+			return fmt.Errorf("failed to export spans: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package melt
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+// language=json
+const otlpJsonLines = `{"resourceMetrics":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"cart"}}]},"scopeMetrics":[{"metrics":[{"name":"http.requests","gauge":{"dataPoints":[{"timeUnixNano":"1705150800000000000","asInt":"3"}]}}]}]}]}
+{"resourceSpans":[{"resource":{},"scopeSpans":[{"spans":[{"traceId":"5b8efff798038103d269b633813fc60c","spanId":"eee19b7ec3c1b174","name":"GET /cart","kind":2}]}]}]}
+
+{"resourceLogs":[{"resource":{},"scopeLogs":[{"logRecords":[{"timeUnixNano":"1705150800000000000","severityText":"INFO","body":{"stringValue":"hello"}}]}]}]}
+`
+
+func TestParseOtlpJson(t *testing.T) {
+	data, err := ParseOtlpJson([]byte(otlpJsonLines))
+
+	require.NoError(t, err)
+	require.Len(t, data.Metrics, 1)
+	require.Len(t, data.Logs, 1)
+	require.Len(t, data.Spans, 1)
+	require.Equal(t, "http.requests", data.Metrics[0].ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Name)
+	require.Equal(t, "hello", data.Logs[0].ResourceLogs[0].ScopeLogs[0].LogRecords[0].Body.GetStringValue())
+	span := data.Spans[0].ResourceSpans[0].ScopeSpans[0].Spans[0]
+	require.Equal(t, "5b8efff798038103d269b633813fc60c", hex.EncodeToString(span.TraceId))
+	require.Equal(t, "eee19b7ec3c1b174", hex.EncodeToString(span.SpanId))
+}
+
+func TestParseOtlpJsonInvalid(t *testing.T) {
+	_, err := ParseOtlpJson([]byte(`{"resourceMetrics":[]}` + "\n" + `{"items":[]}`))
+
+	require.ErrorContains(t, err, "line 2: no resourceMetrics, resourceLogs or resourceSpans")
+}
+
+func TestParseOtlpProto(t *testing.T) {
+	exp := &Exporter{}
+	e := newTestEntity()
+	e.AddMetric(NewMetric("geometry:area", "m2", "gauge", "double").AddDataPoint(1, 2, 100))
+	l := NewLog()
+	l.Body = "hello"
+	e.AddLog(l)
+	e.AddSpan(NewSpan("0123456789abcdef", "01234567", "draw"))
+	metricsData, err := proto.Marshal(exp.buildMetricsPayload([]*Entity{e}))
+	require.NoError(t, err)
+	logsData, err := proto.Marshal(exp.buildLogsPayload([]*Entity{e}))
+	require.NoError(t, err)
+	spansData, err := proto.Marshal(exp.buildSpansPayload([]*Entity{e}))
+	require.NoError(t, err)
+
+	// size-prefixed requests, as written by the file exporter of the collector
+	var file []byte
+	for _, message := range [][]byte{logsData, metricsData, spansData} {
+		file = binary.BigEndian.AppendUint32(file, uint32(len(message)))
+		file = append(file, message...)
+	}
+	data, err := ParseOtlpProto(file)
+	require.NoError(t, err)
+	require.Len(t, data.Metrics, 1)
+	require.Len(t, data.Logs, 1)
+	require.Len(t, data.Spans, 1)
+	require.Equal(t, "geometry:area", data.Metrics[0].ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Name)
+	require.Equal(t, "hello", data.Logs[0].ResourceLogs[0].ScopeLogs[0].LogRecords[0].Body.GetStringValue())
+	require.Equal(t, "draw", data.Spans[0].ResourceSpans[0].ScopeSpans[0].Spans[0].Name)
+
+	// a single request
+	data, err = ParseOtlpProto(logsData)
+	require.NoError(t, err)
+	require.Len(t, data.Logs, 1)
+	require.Empty(t, data.Metrics)
+}
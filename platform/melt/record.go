@@ -0,0 +1,434 @@
+package melt
+
+import (
+	"compress/gzip"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+
+	colllogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collmetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collspans "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	common "go.opentelemetry.io/proto/otlp/common/v1"
+	logs "go.opentelemetry.io/proto/otlp/logs/v1"
+	metrics "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resource "go.opentelemetry.io/proto/otlp/resource/v1"
+	spans "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Receiver - an OTLP/HTTP receiver that collects the export requests sent to it, e.g., by an
+// OpenTelemetry SDK or collector, until they are taken
+type Receiver struct {
+	// OnReceive, if set, is called after each export request is received, with its kind of data
+	// (metrics, logs or trace) and its number of data points, log records or spans
+	OnReceive func(kind string, items int)
+
+	// MaxRequestSize is the maximum size of an export request's body, both as received and once
+	// decompressed; DefaultMaxRequestSize is used if it is 0
+	MaxRequestSize int64
+
+	mutex sync.Mutex
+	data  OtlpData
+}
+
+// DefaultMaxRequestSize is the default maximum size of an export request's body received
+const DefaultMaxRequestSize = 32 << 20
+
+// receiverPaths are the OTLP/HTTP paths of the kinds of data, as named by the exporter
+var receiverPaths = map[string]string{"/v1/metrics": pathMetrics, "/v1/logs": pathLogs, "/v1/traces": pathSpans}
+
+// ServeHTTP receives an OTLP/HTTP export request, in protobuf or JSON, optionally gzip-compressed
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	kind, ok := receiverPaths[req.URL.Path]
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	if req.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	contentType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if contentType != "application/x-protobuf" && contentType != "application/json" {
+		http.Error(w, fmt.Sprintf("unsupported content type %q", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	maxSize := r.MaxRequestSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxRequestSize
+	}
+	var body io.ReadCloser = http.MaxBytesReader(w, req.Body, maxSize)
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		reader, err := gzip.NewReader(body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid gzip content: %v", err), http.StatusBadRequest)
+			return
+		}
+		defer reader.Close()
+		body = http.MaxBytesReader(w, reader, maxSize) // the decompressed content is limited too
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, fmt.Sprintf("the request is larger than %d bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, fmt.Sprintf("failed to read the request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	received := &OtlpData{}
+	var m, resp proto.Message
+	switch kind {
+	case pathMetrics:
+		request := &collmetrics.ExportMetricsServiceRequest{}
+		received.Metrics, m, resp = append(received.Metrics, request), request, &collmetrics.ExportMetricsServiceResponse{}
+	case pathLogs:
+		request := &colllogs.ExportLogsServiceRequest{}
+		received.Logs, m, resp = append(received.Logs, request), request, &colllogs.ExportLogsServiceResponse{}
+	case pathSpans:
+		request := &collspans.ExportTraceServiceRequest{}
+		received.Spans, m, resp = append(received.Spans, request), request, &collspans.ExportTraceServiceResponse{}
+	}
+	if contentType == "application/json" {
+		data, err = otlpJsonToProtojson(data)
+		if err == nil {
+			err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, m)
+		}
+	} else {
+		err = proto.Unmarshal(data, m)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid OTLP %v request: %v", kind, err), http.StatusBadRequest)
+		return
+	}
+
+	r.mutex.Lock()
+	r.data.Metrics = append(r.data.Metrics, received.Metrics...)
+	r.data.Logs = append(r.data.Logs, received.Logs...)
+	r.data.Spans = append(r.data.Spans, received.Spans...)
+	r.mutex.Unlock()
+	if r.OnReceive != nil {
+		dataPoints, logs, spans := received.ItemCounts()
+		r.OnReceive(kind, dataPoints+logs+spans)
+	}
+
+	// respond with an empty export response, i.e., full success, in the request's format
+	if contentType == "application/json" {
+		data, err = protojson.Marshal(resp)
+	} else {
+		data, err = proto.Marshal(resp)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(data)
+}
+
+// Take returns the export requests received since the last time they were taken
+func (r *Receiver) Take() *OtlpData {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	data := r.data
+	r.data = OtlpData{}
+	return &data
+}
+
+// recordedEntityTypes are the entity types of resources with well-known OpenTelemetry resource
+// attributes, by the first attribute the resource has
+var recordedEntityTypes = []struct{ attribute, typeName string }{
+	{"service.instance.id", "apm:service_instance"},
+	{"service.name", "apm:service"},
+	{"k8s.pod.name", "k8s:pod"},
+	{"k8s.node.name", "k8s:node"},
+	{"host.name", "infra:host"},
+}
+
+// ToFsocData converts OTLP export requests to fsoc telemetry data, e.g., to edit recorded data
+// before replaying it. Each distinct resource becomes an entity, typed by its well-known resource
+// attributes, if any (otherwise the type is left empty, to be filled in), and with the
+// relationships of the appd.fmm.entity.relations attribute. Data points with different attributes
+// become separate metrics of the same type; histograms become distributions with their count and
+// sum, without their buckets. Logs marked as events become events, and spans are nested under
+// their parents in the same entity.
+func (d *OtlpData) ToFsocData() *FsocData {
+	c := &otlpConverter{data: &FsocData{Melt: []*Entity{}}, entities: map[string]*Entity{}, metrics: map[metricKey]*Metric{}}
+	for _, req := range d.Metrics {
+		for _, rm := range req.ResourceMetrics {
+			e := c.entity(rm.Resource)
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					c.addMetric(e, m)
+				}
+			}
+		}
+	}
+	for _, req := range d.Logs {
+		for _, rl := range req.ResourceLogs {
+			e := c.entity(rl.Resource)
+			for _, sl := range rl.ScopeLogs {
+				for _, l := range sl.LogRecords {
+					e.AddLog(convertLog(l))
+				}
+			}
+		}
+	}
+	spansOf := map[*Entity][]*Span{}
+	for _, req := range d.Spans {
+		for _, rs := range req.ResourceSpans {
+			e := c.entity(rs.Resource)
+			for _, ss := range rs.ScopeSpans {
+				for _, s := range ss.Spans {
+					spansOf[e] = append(spansOf[e], convertSpan(s))
+				}
+			}
+		}
+	}
+	for _, e := range c.data.Melt {
+		e.Spans = nestSpans(spansOf[e])
+	}
+	return c.data
+}
+
+// otlpConverter - the entities and metrics of the fsoc data converted from OTLP, by identity
+type otlpConverter struct {
+	data     *FsocData
+	entities map[string]*Entity // by attributes
+	metrics  map[metricKey]*Metric
+}
+
+// metricKey - the identity of a metric: its entity, type and attributes
+type metricKey struct {
+	entity     *Entity
+	typeName   string
+	attributes string
+}
+
+// entity returns the entity of a resource, adding it if it's new; resources with the same attributes
+// other than relationships are the same entity
+func (c *otlpConverter) entity(r *resource.Resource) *Entity {
+	attributes := fromKeyValueList(r.GetAttributes())
+	relationships := []*Relationship{}
+	if rels, ok := attributes[keyAppdFMMEntityRelationships].([]interface{}); ok {
+		for _, rel := range rels {
+			if relAttributes, ok := rel.(map[string]interface{}); ok {
+				relationships = append(relationships, &Relationship{Attributes: relAttributes})
+			}
+		}
+		delete(attributes, keyAppdFMMEntityRelationships)
+	}
+	key := attributesKey(attributes)
+	e, ok := c.entities[key]
+	if !ok {
+		e = NewEntity("")
+		for _, t := range recordedEntityTypes {
+			if _, ok := attributes[t.attribute]; ok {
+				e.TypeName = t.typeName
+				break
+			}
+		}
+		e.Attributes = attributes
+		c.entities[key] = e
+		c.data.Melt = append(c.data.Melt, e)
+	}
+	if len(e.Relationships) == 0 {
+		e.Relationships = relationships
+	}
+	return e
+}
+
+// metric returns the metric of an entity with a type and attributes, adding it if it's new
+func (c *otlpConverter) metric(e *Entity, otm *metrics.Metric, contentType string, attributes []*common.KeyValue) *Metric {
+	a := fromKeyValueList(attributes)
+	key := metricKey{e, otm.Name, attributesKey(a)}
+	if m, ok := c.metrics[key]; ok {
+		return m
+	}
+	m := NewMetric(otm.Name, otm.Unit, contentType, "double")
+	m.Description = otm.Description
+	m.Attributes = a
+	c.metrics[key] = m
+	e.AddMetric(m)
+	return m
+}
+
+func (c *otlpConverter) addMetric(e *Entity, otm *metrics.Metric) {
+	addNumberDataPoints := func(contentType string, dps []*metrics.NumberDataPoint) []*Metric {
+		added := []*Metric{}
+		for _, dp := range dps {
+			m := c.metric(e, otm, contentType, dp.Attributes)
+			value := dp.GetAsDouble()
+			if v, ok := dp.Value.(*metrics.NumberDataPoint_AsInt); ok {
+				m.Type = "long"
+				value = float64(v.AsInt)
+			}
+			m.AddDataPoint(int64(dp.StartTimeUnixNano), int64(dp.TimeUnixNano), value)
+			added = append(added, m)
+		}
+		return added
+	}
+
+	switch data := otm.Data.(type) {
+	case *metrics.Metric_Gauge:
+		addNumberDataPoints("gauge", data.Gauge.DataPoints)
+	case *metrics.Metric_Sum:
+		for _, m := range addNumberDataPoints("sum", data.Sum.DataPoints) {
+			m.IsMonotonic = data.Sum.IsMonotonic
+			switch data.Sum.AggregationTemporality {
+			case metrics.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA:
+				m.AggregationTemporality = AggregationTemporalityDelta
+			case metrics.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE:
+				m.AggregationTemporality = AggregationTemporalityCumulative
+			}
+		}
+	case *metrics.Metric_Summary:
+		for _, dp := range data.Summary.DataPoints {
+			quantiles := []*QuantileValue{}
+			for _, q := range dp.QuantileValues {
+				quantiles = append(quantiles, &QuantileValue{Quantile: q.Quantile, Value: q.Value})
+			}
+			c.metric(e, otm, "distribution", dp.Attributes).AddDistributionDataPoint(int64(dp.StartTimeUnixNano), int64(dp.TimeUnixNano), dp.Sum, int64(dp.Count), quantiles)
+		}
+	case *metrics.Metric_Histogram:
+		for _, dp := range data.Histogram.DataPoints {
+			c.metric(e, otm, "distribution", dp.Attributes).AddDistributionDataPoint(int64(dp.StartTimeUnixNano), int64(dp.TimeUnixNano), dp.GetSum(), int64(dp.Count), nil)
+		}
+	case *metrics.Metric_ExponentialHistogram:
+		for _, dp := range data.ExponentialHistogram.DataPoints {
+			c.metric(e, otm, "distribution", dp.Attributes).AddDistributionDataPoint(int64(dp.StartTimeUnixNano), int64(dp.TimeUnixNano), dp.GetSum(), int64(dp.Count), nil)
+		}
+	}
+}
+
+func convertLog(otl *logs.LogRecord) *Log {
+	attributes := fromKeyValueList(otl.Attributes)
+	l := NewLog()
+	if fmt.Sprint(attributes[keyAppdIsEvent]) == "true" {
+		l = NewEvent(fmt.Sprint(attributes[keyAppdEventType]))
+		delete(attributes, keyAppdIsEvent)
+		delete(attributes, keyAppdEventType)
+	}
+	l.Attributes = attributes
+	if body, ok := otl.Body.GetValue().(*common.AnyValue_StringValue); ok {
+		l.Body = body.StringValue
+	} else if otl.Body != nil {
+		b, _ := json.Marshal(fromAnyValue(otl.Body))
+		l.Body = string(b)
+	}
+	l.Severity = otl.SeverityText
+	if l.Severity == "" {
+		l.Severity = severityText(otl.SeverityNumber)
+	}
+	l.Timestamp = int64(otl.TimeUnixNano)
+	if l.Timestamp == 0 {
+		l.Timestamp = int64(otl.ObservedTimeUnixNano)
+	}
+	return l
+}
+
+// severityText returns the name of the range of an OTLP severity number, empty if unspecified
+func severityText(n logs.SeverityNumber) string {
+	names := []string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR", "FATAL"}
+	if n < logs.SeverityNumber_SEVERITY_NUMBER_TRACE || n > logs.SeverityNumber_SEVERITY_NUMBER_FATAL4 {
+		return ""
+	}
+	return names[(n-logs.SeverityNumber_SEVERITY_NUMBER_TRACE)/4]
+}
+
+func convertSpan(ots *spans.Span) *Span {
+	s := NewSpan(hex.EncodeToString(ots.TraceId), hex.EncodeToString(ots.SpanId), ots.Name)
+	s.ParentSpanID = hex.EncodeToString(ots.ParentSpanId)
+	s.TraceState = ots.TraceState
+	s.Kind = SpanKind(ots.Kind)
+	s.StartTime = int64(ots.StartTimeUnixNano)
+	s.EndTime = int64(ots.EndTimeUnixNano)
+	s.Attributes = fromKeyValueList(ots.Attributes)
+	for _, e := range ots.Events {
+		s.NewEvent(e.Name, int64(e.TimeUnixNano)).Attributes = fromKeyValueList(e.Attributes)
+	}
+	for _, l := range ots.Links {
+		s.NewLink(hex.EncodeToString(l.TraceId), hex.EncodeToString(l.SpanId), l.TraceState).Attributes = fromKeyValueList(l.Attributes)
+	}
+	if ots.Status != nil && (ots.Status.Code != spans.Status_STATUS_CODE_UNSET || ots.Status.Message != "") {
+		s.SetStatus(ots.Status.Message, SpanStatusCode(ots.Status.Code))
+	}
+	return s
+}
+
+// nestSpans nests spans under their parents, if among them, in order, returning the root spans.
+// Nested spans have no trace and parent span ids, since they are set from their parents when
+// exporting.
+func nestSpans(spanList []*Span) []*Span {
+	byID := map[string]*Span{}
+	for _, s := range spanList {
+		byID[s.SpanID] = s
+	}
+	roots := []*Span{}
+	for _, s := range spanList {
+		parent, ok := byID[s.ParentSpanID]
+		if !ok || parent == s || parent.TraceID != s.TraceID {
+			roots = append(roots, s)
+			continue
+		}
+		parent.AddChild(s)
+	}
+	for _, s := range spanList {
+		for _, c := range s.Children {
+			c.TraceID, c.ParentSpanID = "", ""
+		}
+	}
+	return roots
+}
+
+func fromKeyValueList(kvl []*common.KeyValue) map[string]interface{} {
+	attributes := map[string]interface{}{}
+	for _, kv := range kvl {
+		if v := fromAnyValue(kv.Value); v != nil {
+			attributes[kv.Key] = v
+		}
+	}
+	return attributes
+}
+
+// fromAnyValue returns the value of an OTLP attribute: bytes as hex, arrays as slices and key-value
+// lists as maps, nil if not set
+func fromAnyValue(v *common.AnyValue) interface{} {
+	switch value := v.GetValue().(type) {
+	case *common.AnyValue_StringValue:
+		return value.StringValue
+	case *common.AnyValue_BoolValue:
+		return value.BoolValue
+	case *common.AnyValue_IntValue:
+		return value.IntValue
+	case *common.AnyValue_DoubleValue:
+		return value.DoubleValue
+	case *common.AnyValue_BytesValue:
+		return hex.EncodeToString(value.BytesValue)
+	case *common.AnyValue_ArrayValue:
+		values := []interface{}{}
+		for _, item := range value.ArrayValue.Values {
+			values = append(values, fromAnyValue(item))
+		}
+		return values
+	case *common.AnyValue_KvlistValue:
+		return fromKeyValueList(value.KvlistValue.Values)
+	}
+	return nil
+}
+
+// attributesKey returns a key identifying a set of attributes, regardless of their order
+func attributesKey(attributes map[string]interface{}) string {
+	b, _ := json.Marshal(attributes) // with sorted keys
+	return string(b)
+}
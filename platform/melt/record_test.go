@@ -0,0 +1,132 @@
+package melt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestReceiverToFsocData(t *testing.T) {
+	var data FsocData
+	require.NoError(t, yaml.Unmarshal([]byte(spansYaml), &data))
+	e := data.Melt[0]
+	e.AddMetric(NewMetric("geometry:area", "m2", "gauge", "long").AddDataPoint(100, 200, 4))
+	e.AddLog(NewEvent("geometry:resized").SetAttribute("geometry.scale", 2.0))
+	e.AddRelationship(NewRelationship().SetAttribute("geometry.canvas.name", "canvas-1"))
+	exp := &Exporter{}
+
+	var received []string
+	receiver := &Receiver{OnReceive: func(kind string, items int) { received = append(received, kind) }}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	metricsBody, err := proto.Marshal(exp.buildMetricsPayload(data.Melt))
+	require.NoError(t, err)
+	resp, err := http.Post(server.URL+"/v1/metrics", "application/x-protobuf", bytes.NewReader(metricsBody))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	logsBody, err := protojson.Marshal(exp.buildLogsPayload(data.Melt))
+	require.NoError(t, err)
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, _ = writer.Write(logsBody)
+	require.NoError(t, writer.Close())
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/v1/logs", &compressed)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	spansBody, err := proto.Marshal(exp.buildSpansPayload(data.Melt))
+	require.NoError(t, err)
+	resp, err = http.Post(server.URL+"/v1/traces", "application/x-protobuf", bytes.NewReader(spansBody))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Post(server.URL+"/v1/traces", "text/plain", bytes.NewReader(spansBody))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+
+	require.Equal(t, []string{pathMetrics, pathLogs, pathSpans}, received)
+	recorded := receiver.Take().ToFsocData()
+	require.Empty(t, receiver.Take().Metrics)
+
+	require.Len(t, recorded.Melt, 1)
+	r := recorded.Melt[0]
+	require.Equal(t, e.Attributes, r.Attributes)
+	require.Equal(t, e.Relationships, r.Relationships)
+
+	require.Len(t, r.Metrics, 1)
+	require.Equal(t, "geometry:area", r.Metrics[0].TypeName)
+	require.Equal(t, "long", r.Metrics[0].Type)
+	require.Equal(t, []*DataPoint{{StartTime: 100, EndTime: 200, Value: 4}}, r.Metrics[0].DataPoints)
+
+	require.Len(t, r.Logs, 2)
+	require.Equal(t, "drawing failed", r.Logs[0].Body)
+	require.Equal(t, "ERROR", r.Logs[0].Severity)
+	require.True(t, r.Logs[1].IsEvent)
+	require.Equal(t, "geometry:resized", r.Logs[1].TypeName)
+	require.Equal(t, map[string]interface{}{"geometry.scale": 2.0}, r.Logs[1].Attributes)
+
+	require.Len(t, r.Spans, 1)
+	draw := r.Spans[0]
+	require.Equal(t, "draw", draw.Name)
+	require.Equal(t, "5b8efff798038103d269b633813fc60c", draw.TraceID)
+	require.Equal(t, SpanKindServer, draw.Kind)
+	require.Equal(t, SpanStatusCodeError, draw.Status.Code)
+	require.Len(t, draw.Children, 1)
+	measure := draw.Children[0]
+	require.Equal(t, "measure", measure.Name)
+	require.Empty(t, measure.TraceID) // set from the parent when exporting
+	require.Equal(t, int64(1000), measure.StartTime)
+	require.Len(t, measure.Children, 1)
+	require.Equal(t, "query", measure.Children[0].Name)
+}
+
+func TestSeverityText(t *testing.T) {
+	for severity, expected := range map[string]string{"trace": "TRACE", "info": "INFO", "warning": "WARN", "critical": "FATAL", "": ""} {
+		require.Equal(t, expected, severityText(severityNumber(severity)))
+	}
+}
+
+func TestReceiverMaxRequestSize(t *testing.T) {
+	receiver := &Receiver{MaxRequestSize: 1024}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	post := func(body []byte, compress bool) int {
+		var content bytes.Buffer
+		if compress {
+			writer := gzip.NewWriter(&content)
+			_, _ = writer.Write(body)
+			require.NoError(t, writer.Close())
+		} else {
+			content.Write(body)
+		}
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/v1/logs", &content)
+		req.Header.Set("Content-Type", "application/json")
+		if compress {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	large := []byte(`{"resourceLogs":[],"padding":"` + strings.Repeat("x", 4096) + `"}`)
+	require.Equal(t, http.StatusOK, post([]byte(`{"resourceLogs":[]}`), false))
+	require.Equal(t, http.StatusRequestEntityTooLarge, post(large, false))
+	// small once compressed, but too large once decompressed
+	require.Equal(t, http.StatusRequestEntityTooLarge, post(large, true))
+}
@@ -0,0 +1,191 @@
+package melt
+
+import (
+	"sort"
+	"strings"
+
+	colllogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collmetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collspans "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// TimeSlice - the part of data whose data points, logs and spans end in a time window
+type TimeSlice struct {
+	End  int64 // end of the window, in nanoseconds since the epoch
+	Data *FsocData
+}
+
+// TimeRange returns the earliest and the latest timestamps of the data, or zeros if it has none
+func (d *FsocData) TimeRange() (int64, int64) {
+	var first, last int64
+	d.MapTimestamps(func(t int64) int64 {
+		if first == 0 || t < first {
+			first = t
+		}
+		last = max(last, t)
+		return t
+	})
+	return first, last
+}
+
+// MapTimestamps replaces the timestamps of the data (data points, logs, spans with their children
+// and span events) with the result of a function; zero timestamps, i.e., not set, are left as they are
+func (d *FsocData) MapTimestamps(f func(int64) int64) {
+	mapTime := func(t *int64) {
+		if *t != 0 {
+			*t = f(*t)
+		}
+	}
+	for _, e := range d.Melt {
+		for _, m := range e.Metrics {
+			for _, dp := range m.DataPoints {
+				mapTime(&dp.StartTime)
+				mapTime(&dp.EndTime)
+			}
+		}
+		for _, l := range e.Logs {
+			mapTime(&l.Timestamp)
+		}
+		var mapSpans func(spans []*Span)
+		mapSpans = func(spans []*Span) {
+			for _, s := range spans {
+				mapTime(&s.StartTime)
+				mapTime(&s.EndTime)
+				for _, se := range s.Events {
+					mapTime(&se.Timestamp)
+				}
+				mapSpans(s.Children)
+			}
+		}
+		mapSpans(e.Spans)
+	}
+}
+
+// SplitByTime splits the data into consecutive time windows of a given length, from start, by
+// the end time of its data points, logs and spans, returning the non-empty windows in order. The
+// entities and metrics in each window are copies with only the items of the window; data points,
+// logs and spans before start are in the first window.
+func (d *FsocData) SplitByTime(start int64, window int64) []*TimeSlice {
+	windows := map[int64]*TimeSlice{}
+	entities := map[int64]*Entity{} // copies of the current entity, by window index
+	metrics := map[int64]*Metric{}  // copies of the current metric, by window index
+	windowOf := func(t int64) int64 {
+		return max(0, (t-start)/window)
+	}
+	entityIn := func(e *Entity, t int64) *Entity {
+		i := windowOf(t)
+		if entities[i] == nil {
+			if windows[i] == nil {
+				windows[i] = &TimeSlice{End: start + (i+1)*window, Data: &FsocData{Melt: []*Entity{}}}
+			}
+			entities[i] = &Entity{TypeName: e.TypeName, ID: e.ID, Attributes: e.Attributes, Relationships: e.Relationships}
+			windows[i].Data.Melt = append(windows[i].Data.Melt, entities[i])
+		}
+		return entities[i]
+	}
+
+	for _, e := range d.Melt {
+		clear(entities)
+		for _, m := range e.Metrics {
+			clear(metrics)
+			for _, dp := range m.DataPoints {
+				i := windowOf(dp.EndTime)
+				if metrics[i] == nil {
+					mc := *m
+					mc.DataPoints = []*DataPoint{}
+					metrics[i] = &mc
+					entityIn(e, dp.EndTime).AddMetric(metrics[i])
+				}
+				metrics[i].DataPoints = append(metrics[i].DataPoints, dp)
+			}
+		}
+		for _, l := range e.Logs {
+			entityIn(e, l.Timestamp).AddLog(l)
+		}
+		for _, s := range e.Spans {
+			end := s.EndTime
+			if end == 0 {
+				end = s.StartTime
+			}
+			entityIn(e, end).AddSpan(s)
+		}
+	}
+
+	result := make([]*TimeSlice, 0, len(windows))
+	for _, slice := range windows {
+		result = append(result, slice)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].End < result[j].End })
+	return result
+}
+
+// TimeRange returns the earliest and the latest timestamps of the OTLP requests, or zeros if they
+// have none
+func (d *OtlpData) TimeRange() (int64, int64) {
+	var first, last int64
+	d.MapTimestamps(func(t int64) int64 {
+		if first == 0 || t < first {
+			first = t
+		}
+		last = max(last, t)
+		return t
+	})
+	return first, last
+}
+
+// MapTimestamps replaces the timestamps of the OTLP requests, i.e., all their *_time_unix_nano
+// fields, with the result of a function; zero timestamps, i.e., not set, are left as they are
+func (d *OtlpData) MapTimestamps(f func(int64) int64) {
+	for _, m := range d.Metrics {
+		mapOtlpTimestamps(m.ProtoReflect(), f)
+	}
+	for _, l := range d.Logs {
+		mapOtlpTimestamps(l.ProtoReflect(), f)
+	}
+	for _, s := range d.Spans {
+		mapOtlpTimestamps(s.ProtoReflect(), f)
+	}
+}
+
+// SplitRequests splits the OTLP data into one part per request, in the order metrics, logs and
+// spans
+func (d *OtlpData) SplitRequests() []*OtlpData {
+	parts := []*OtlpData{}
+	for _, m := range d.Metrics {
+		parts = append(parts, &OtlpData{Metrics: []*collmetrics.ExportMetricsServiceRequest{m}})
+	}
+	for _, l := range d.Logs {
+		parts = append(parts, &OtlpData{Logs: []*colllogs.ExportLogsServiceRequest{l}})
+	}
+	for _, s := range d.Spans {
+		parts = append(parts, &OtlpData{Spans: []*collspans.ExportTraceServiceRequest{s}})
+	}
+	return parts
+}
+
+// mapOtlpTimestamps replaces the timestamp fields of a message and the messages in it
+func mapOtlpTimestamps(m protoreflect.Message, f func(int64) int64) {
+	var timestamps []protoreflect.FieldDescriptor // set after ranging, as the message can't change during it
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				mapOtlpTimestamps(list.Get(i).Message(), f)
+			}
+		case fd.IsMap():
+			// no OTLP request has maps of messages
+		case fd.Message() != nil:
+			mapOtlpTimestamps(v.Message(), f)
+		case fd.Kind() == protoreflect.Fixed64Kind && strings.HasSuffix(string(fd.Name()), "time_unix_nano"):
+			timestamps = append(timestamps, fd)
+		}
+		return true
+	})
+	for _, fd := range timestamps {
+		if t := m.Get(fd).Uint(); t != 0 {
+			m.Set(fd, protoreflect.ValueOfUint64(uint64(f(int64(t)))))
+		}
+	}
+}
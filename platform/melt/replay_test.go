@@ -0,0 +1,73 @@
+package melt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestReplayData() *FsocData {
+	e := newTestEntity()
+	e.AddMetric(NewMetric("geometry:area", "m2", "gauge", "double").AddDataPoint(100, 200, 1).AddDataPoint(200, 300, 2).AddDataPoint(300, 400, 3))
+	l := NewLog()
+	l.Timestamp = 250
+	e.AddLog(l)
+	s := NewSpan("0123456789abcdef", "01234567", "draw")
+	s.StartTime, s.EndTime = 350, 380
+	s.Events = []*SpanEvent{{Name: "drawn", Timestamp: 370}}
+	e.AddSpan(s)
+	return &FsocData{Melt: []*Entity{e}}
+}
+
+func TestFsocDataMapTimestamps(t *testing.T) {
+	data := newTestReplayData()
+	first, last := data.TimeRange()
+	require.Equal(t, int64(100), first)
+	require.Equal(t, int64(400), last)
+
+	data.MapTimestamps(func(t int64) int64 { return t * 10 })
+
+	e := data.Melt[0]
+	require.Equal(t, int64(1000), e.Metrics[0].DataPoints[0].StartTime)
+	require.Equal(t, int64(4000), e.Metrics[0].DataPoints[2].EndTime)
+	require.Equal(t, int64(2500), e.Logs[0].Timestamp)
+	require.Equal(t, int64(3500), e.Spans[0].StartTime)
+	require.Equal(t, int64(3700), e.Spans[0].Events[0].Timestamp)
+}
+
+func TestFsocDataSplitByTime(t *testing.T) {
+	data := newTestReplayData()
+
+	slices := data.SplitByTime(100, 150)
+
+	require.Len(t, slices, 3)
+	require.Equal(t, int64(250), slices[0].End)
+	require.Len(t, slices[0].Data.Melt, 1)
+	require.Len(t, slices[0].Data.Melt[0].Metrics[0].DataPoints, 1)
+	require.Empty(t, slices[0].Data.Melt[0].Logs)
+	require.Equal(t, int64(400), slices[1].End)
+	require.Len(t, slices[1].Data.Melt[0].Metrics[0].DataPoints, 1)
+	require.Len(t, slices[1].Data.Melt[0].Logs, 1)
+	require.Len(t, slices[1].Data.Melt[0].Spans, 1)
+	require.Equal(t, int64(550), slices[2].End)
+	require.Equal(t, float64(3), slices[2].Data.Melt[0].Metrics[0].DataPoints[0].Value)
+	require.Equal(t, data.Melt[0].Attributes, slices[2].Data.Melt[0].Attributes)
+
+	// the original data is unchanged
+	require.Len(t, data.Melt[0].Metrics[0].DataPoints, 3)
+}
+
+func TestOtlpDataMapTimestamps(t *testing.T) {
+	data, err := ParseOtlpJson([]byte(otlpJsonLines))
+	require.NoError(t, err)
+	first, last := data.TimeRange()
+	require.Equal(t, int64(1705150800000000000), first)
+	require.Equal(t, int64(1705150800000000000), last)
+
+	data.MapTimestamps(func(t int64) int64 { return t + 1000 })
+
+	require.Equal(t, uint64(1705150800000001000), data.Metrics[0].ResourceMetrics[0].ScopeMetrics[0].Metrics[0].GetGauge().DataPoints[0].TimeUnixNano)
+	require.Equal(t, uint64(1705150800000001000), data.Logs[0].ResourceLogs[0].ScopeLogs[0].LogRecords[0].TimeUnixNano)
+	require.Zero(t, data.Spans[0].ResourceSpans[0].ScopeSpans[0].Spans[0].StartTimeUnixNano)
+	require.Len(t, data.SplitRequests(), 3)
+}
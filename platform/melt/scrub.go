@@ -0,0 +1,298 @@
+package melt
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"path"
+
+	common "go.opentelemetry.io/proto/otlp/common/v1"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// ScrubLogBodiesKeep keeps log bodies as they are (the default)
+	ScrubLogBodiesKeep = "keep"
+	// ScrubLogBodiesHash replaces log bodies with hashes
+	ScrubLogBodiesHash = "hash"
+	// ScrubLogBodiesDrop removes log bodies
+	ScrubLogBodiesDrop = "drop"
+)
+
+// ScrubConfig - how to scrub telemetry, e.g., captured in a customer's environment, before sending
+// it to a shared tenant. Attributes are given by name or by pattern, e.g., "k8s.*"; the first list
+// an attribute is in, in the order deny, hash and allow, applies.
+type ScrubConfig struct {
+	// Allow lists the attributes kept as they are; when set, all other attributes are hashed
+	Allow []string `yaml:"allow,omitempty"`
+	// Deny lists the attributes removed
+	Deny []string `yaml:"deny,omitempty"`
+	// Hash lists the attributes whose values are replaced by hashes
+	Hash []string `yaml:"hash,omitempty"`
+	// Salt is mixed into the hashes, so that they can't be reversed by hashing guessed values
+	Salt string `yaml:"salt,omitempty"`
+	// LogBodies is what to do with log bodies: keep, hash or drop
+	LogBodies string `yaml:"logBodies,omitempty"`
+}
+
+// Scrubber removes and hashes attributes of telemetry as configured. Values are hashed consistently,
+// so that the same value, e.g., an entity's name, has the same hash everywhere, keeping entities
+// and their relationships linked; strings are hashed into strings and integers into integers, while
+// other values are kept.
+type Scrubber struct {
+	config ScrubConfig
+}
+
+type scrubAction int
+
+const (
+	scrubKeep scrubAction = iota
+	scrubHash
+	scrubDrop
+)
+
+// keyValueName is the name of the message type of OTLP attributes
+var keyValueName = (&common.KeyValue{}).ProtoReflect().Descriptor().FullName()
+
+// scrubExemptKeys are the attributes that mark logs as events, which are never scrubbed
+var scrubExemptKeys = map[string]bool{keyAppdIsEvent: true, keyAppdEventType: true}
+
+// NewScrubber returns a scrubber with a configuration, checking its patterns
+func NewScrubber(config ScrubConfig) (*Scrubber, error) {
+	for _, patterns := range [][]string{config.Allow, config.Deny, config.Hash} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid attribute pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	switch config.LogBodies {
+	case "":
+		config.LogBodies = ScrubLogBodiesKeep
+	case ScrubLogBodiesKeep, ScrubLogBodiesHash, ScrubLogBodiesDrop:
+	default:
+		return nil, fmt.Errorf("invalid logBodies %q, must be one of (keep, hash, drop)", config.LogBodies)
+	}
+	return &Scrubber{config: config}, nil
+}
+
+// ScrubFsocData scrubs the attributes of the entities, relationships, metrics, logs and spans of
+// fsoc telemetry data, and the log bodies, in place
+func (s *Scrubber) ScrubFsocData(d *FsocData) {
+	for _, e := range d.Melt {
+		s.scrubAttributes(e.Attributes)
+		for _, r := range e.Relationships {
+			s.scrubAttributes(r.Attributes)
+		}
+		for _, m := range e.Metrics {
+			s.scrubAttributes(m.Attributes)
+			s.scrubAttributes(m.Resource.Attributes)
+		}
+		for _, l := range e.Logs {
+			s.scrubAttributes(l.Attributes)
+			s.scrubAttributes(l.Resource.Attributes)
+			switch s.config.LogBodies {
+			case ScrubLogBodiesHash:
+				l.Body = s.hashString(l.Body)
+			case ScrubLogBodiesDrop:
+				l.Body = ""
+			}
+		}
+		s.scrubSpans(e.Spans)
+	}
+}
+
+func (s *Scrubber) scrubSpans(spanList []*Span) {
+	for _, span := range spanList {
+		s.scrubAttributes(span.Attributes)
+		for _, e := range span.Events {
+			s.scrubAttributes(e.Attributes)
+		}
+		for _, l := range span.Links {
+			s.scrubAttributes(l.Attributes)
+		}
+		s.scrubSpans(span.Children)
+	}
+}
+
+// ScrubOtlpData scrubs the attributes of all kinds (resource, scope, data point, log record, span,
+// span event and link attributes) of OTLP export requests, and the log bodies, in place
+func (s *Scrubber) ScrubOtlpData(d *OtlpData) {
+	for _, m := range d.Metrics {
+		s.scrubOtlpMessage(m.ProtoReflect())
+	}
+	for _, req := range d.Logs {
+		s.scrubOtlpMessage(req.ProtoReflect())
+		for _, rl := range req.ResourceLogs {
+			for _, sl := range rl.ScopeLogs {
+				for _, l := range sl.LogRecords {
+					switch body, isString := l.Body.GetValue().(*common.AnyValue_StringValue); {
+					case s.config.LogBodies == ScrubLogBodiesHash && isString:
+						body.StringValue = s.hashString(body.StringValue)
+					case s.config.LogBodies == ScrubLogBodiesDrop:
+						l.Body = nil
+					}
+				}
+			}
+		}
+	}
+	for _, t := range d.Spans {
+		s.scrubOtlpMessage(t.ProtoReflect())
+	}
+}
+
+// scrubOtlpMessage scrubs the lists of attributes of a message and of the messages in it
+func (s *Scrubber) scrubOtlpMessage(m protoreflect.Message) {
+	var attributes []protoreflect.List // scrubbed after ranging, as the message can't change during it
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList() && fd.Message() != nil && fd.Message().FullName() == keyValueName:
+			attributes = append(attributes, v.List())
+		case fd.IsList() && fd.Message() != nil:
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				s.scrubOtlpMessage(list.Get(i).Message())
+			}
+		case fd.IsMap():
+			// no OTLP request has maps of messages
+		case fd.Message() != nil:
+			s.scrubOtlpMessage(v.Message())
+		}
+		return true
+	})
+	for _, list := range attributes {
+		kvl := make([]*common.KeyValue, list.Len())
+		for i := range kvl {
+			kvl[i] = list.Get(i).Message().Interface().(*common.KeyValue)
+		}
+		kvl = s.scrubKeyValues(kvl)
+		list.Truncate(0)
+		for _, kv := range kvl {
+			list.Append(protoreflect.ValueOfMessage(kv.ProtoReflect()))
+		}
+	}
+}
+
+// scrubKeyValues returns OTLP attributes without the removed ones and with the hashed values
+func (s *Scrubber) scrubKeyValues(kvl []*common.KeyValue) []*common.KeyValue {
+	scrubbed := make([]*common.KeyValue, 0, len(kvl))
+	for _, kv := range kvl {
+		switch s.action(kv.Key) {
+		case scrubDrop:
+			continue
+		case scrubHash:
+			s.hashAnyValue(kv.Value)
+		case scrubKeep:
+			s.scrubAnyValue(kv.Value)
+		}
+		scrubbed = append(scrubbed, kv)
+	}
+	return scrubbed
+}
+
+// scrubAnyValue scrubs the attributes nested in a value kept as it is, e.g., the attributes of
+// related entities
+func (s *Scrubber) scrubAnyValue(v *common.AnyValue) {
+	switch value := v.GetValue().(type) {
+	case *common.AnyValue_ArrayValue:
+		for _, item := range value.ArrayValue.Values {
+			s.scrubAnyValue(item)
+		}
+	case *common.AnyValue_KvlistValue:
+		value.KvlistValue.Values = s.scrubKeyValues(value.KvlistValue.Values)
+	}
+}
+
+func (s *Scrubber) hashAnyValue(v *common.AnyValue) {
+	switch value := v.GetValue().(type) {
+	case *common.AnyValue_StringValue:
+		value.StringValue = s.hashString(value.StringValue)
+	case *common.AnyValue_IntValue:
+		value.IntValue = s.hashInt(value.IntValue)
+	case *common.AnyValue_BytesValue:
+		value.BytesValue = s.hashBytes(value.BytesValue)
+	case *common.AnyValue_ArrayValue:
+		for _, item := range value.ArrayValue.Values {
+			s.hashAnyValue(item)
+		}
+	case *common.AnyValue_KvlistValue:
+		value.KvlistValue.Values = s.scrubKeyValues(value.KvlistValue.Values)
+	}
+}
+
+// scrubAttributes removes and hashes the attributes of fsoc telemetry data, in place
+func (s *Scrubber) scrubAttributes(attributes map[string]interface{}) {
+	for key, value := range attributes {
+		switch s.action(key) {
+		case scrubDrop:
+			delete(attributes, key)
+		case scrubHash:
+			attributes[key] = s.hashValue(value)
+		case scrubKeep:
+			if nested, ok := value.(map[string]interface{}); ok {
+				s.scrubAttributes(nested)
+			}
+		}
+	}
+}
+
+func (s *Scrubber) hashValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return s.hashString(v)
+	case int:
+		return int(s.hashInt(int64(v)))
+	case int64:
+		return s.hashInt(v)
+	case []interface{}:
+		hashed := make([]interface{}, len(v))
+		for i, item := range v {
+			hashed[i] = s.hashValue(item)
+		}
+		return hashed
+	case map[string]interface{}:
+		s.scrubAttributes(v)
+	}
+	return value
+}
+
+// action returns what to do with an attribute
+func (s *Scrubber) action(key string) scrubAction {
+	switch {
+	case scrubExemptKeys[key]:
+		return scrubKeep
+	case matchesAny(s.config.Deny, key):
+		return scrubDrop
+	case matchesAny(s.config.Hash, key):
+		return scrubHash
+	case len(s.config.Allow) == 0 || matchesAny(s.config.Allow, key):
+		return scrubKeep
+	default:
+		return scrubHash
+	}
+}
+
+func matchesAny(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scrubber) hashBytes(b []byte) []byte {
+	hash := sha256.Sum256(append([]byte(s.config.Salt), b...))
+	return hash[:8]
+}
+
+// hashString returns a hash of a string, as 16 hex digits
+func (s *Scrubber) hashString(value string) string {
+	return hex.EncodeToString(s.hashBytes([]byte(value)))
+}
+
+// hashInt returns a hash of an integer, as a positive integer
+func (s *Scrubber) hashInt(value int64) int64 {
+	return int64(binary.BigEndian.Uint64(s.hashBytes(binary.BigEndian.AppendUint64(nil, uint64(value)))) >> 1)
+}
@@ -0,0 +1,83 @@
+package melt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestScrubber(t *testing.T) *Scrubber {
+	s, err := NewScrubber(ScrubConfig{
+		Deny:      []string{"geometry.shape.float_attribute"},
+		Hash:      []string{"geometry.shape.name", "geometry.shape.int_attribute"},
+		Salt:      "secret",
+		LogBodies: ScrubLogBodiesHash,
+	})
+	require.NoError(t, err)
+	return s
+}
+
+func TestScrubFsocData(t *testing.T) {
+	e := newTestEntity()
+	e.AddRelationship(NewRelationship().SetAttribute("geometry.shape.name", "My Square"))
+	e.AddLog(NewLog().SetAttribute("geometry.shape.name", "My Square"))
+	e.Logs[0].Body = "drawing My Square"
+	s := newTestScrubber(t)
+
+	s.ScrubFsocData(&FsocData{Melt: []*Entity{e}})
+
+	hashedName := e.Attributes["geometry.shape.name"]
+	require.Len(t, hashedName, 16)
+	require.NotContains(t, e.Attributes, "geometry.shape.float_attribute")
+	require.IsType(t, int64(0), e.Attributes["geometry.shape.int_attribute"])
+	require.NotEqual(t, int64(1), e.Attributes["geometry.shape.int_attribute"])
+	require.Equal(t, "square", e.Attributes["geometry.shape.type"])
+	require.Equal(t, hashedName, e.Relationships[0].Attributes["geometry.shape.name"]) // still related
+	require.Equal(t, hashedName, e.Logs[0].Attributes["geometry.shape.name"])
+	require.Equal(t, s.hashString("drawing My Square"), e.Logs[0].Body)
+}
+
+func TestScrubOtlpData(t *testing.T) {
+	e := newTestEntity()
+	e.AddRelationship(NewRelationship().SetAttribute("geometry.shape.name", "My Square"))
+	e.AddMetric(NewMetric("geometry:area", "m2", "gauge", "double").SetAttribute("geometry.shape.name", "My Square").AddDataPoint(1, 2, 100))
+	e.AddLog(NewLog())
+	e.Logs[0].Body = "drawing My Square"
+	exp := &Exporter{}
+	data := &OtlpData{}
+	data.Metrics = append(data.Metrics, exp.buildMetricsPayload([]*Entity{e}))
+	data.Logs = append(data.Logs, exp.buildLogsPayload([]*Entity{e}))
+	s := newTestScrubber(t)
+
+	s.ScrubOtlpData(data)
+
+	fsocData := data.ToFsocData()
+	require.Len(t, fsocData.Melt, 1)
+	scrubbed := fsocData.Melt[0]
+	hashedName := s.hashString("My Square")
+	require.Equal(t, hashedName, scrubbed.Attributes["geometry.shape.name"])
+	require.Equal(t, s.hashInt(1), scrubbed.Attributes["geometry.shape.int_attribute"])
+	require.NotContains(t, scrubbed.Attributes, "geometry.shape.float_attribute")
+	require.Equal(t, "square", scrubbed.Attributes["geometry.shape.type"])
+	require.Equal(t, hashedName, scrubbed.Relationships[0].Attributes["geometry.shape.name"])
+	require.Equal(t, hashedName, scrubbed.Metrics[0].Attributes["geometry.shape.name"])
+	require.Equal(t, s.hashString("drawing My Square"), scrubbed.Logs[0].Body)
+}
+
+func TestScrubAllowList(t *testing.T) {
+	s, err := NewScrubber(ScrubConfig{Allow: []string{"geometry.shape.type", "geometry.square.*"}})
+	require.NoError(t, err)
+	e := newTestEntity()
+
+	s.ScrubFsocData(&FsocData{Melt: []*Entity{e}})
+
+	require.Equal(t, "square", e.Attributes["geometry.shape.type"])
+	require.Equal(t, "10", e.Attributes["geometry.square.side"])
+	require.NotEqual(t, "My Square", e.Attributes["geometry.shape.name"])
+	require.Equal(t, 1.1, e.Attributes["geometry.shape.float_attribute"]) // only strings and integers are hashed
+
+	_, err = NewScrubber(ScrubConfig{Deny: []string{"["}})
+	require.Error(t, err)
+	_, err = NewScrubber(ScrubConfig{LogBodies: "redact"})
+	require.Error(t, err)
+}
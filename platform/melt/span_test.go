@@ -0,0 +1,99 @@
+package melt
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	logs "go.opentelemetry.io/proto/otlp/logs/v1"
+	spans "go.opentelemetry.io/proto/otlp/trace/v1"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// language=yaml
+const spansYaml = `
+melt:
+- typename: geometry:square
+  attributes:
+    geometry.square.name: square-1
+  logs:
+  - body: drawing failed
+    severity: ERROR
+  spans:
+  - name: draw
+    traceid: 5b8efff798038103d269b633813fc60c
+    spanid: eee19b7ec3c1b174
+    kind: server
+    starttime: 1000
+    endtime: 5000
+    status:
+      code: error
+    children:
+    - name: measure
+      spanid: measure
+      kind: internal
+      children:
+      - name: query
+        kind: client
+        starttime: 2000
+        endtime: 3000
+`
+
+func TestBuildSpansPayloadChildren(t *testing.T) {
+	var data FsocData
+	require.NoError(t, yaml.Unmarshal([]byte(spansYaml), &data))
+
+	payload := (&Exporter{}).buildSpansPayload(data.Melt)
+
+	otlpSpans := payload.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, otlpSpans, 3)
+	draw, measure, query := otlpSpans[0], otlpSpans[1], otlpSpans[2]
+	require.Equal(t, "5b8efff798038103d269b633813fc60c", hex.EncodeToString(draw.TraceId))
+	require.Equal(t, "eee19b7ec3c1b174", hex.EncodeToString(draw.SpanId))
+	require.Empty(t, draw.ParentSpanId)
+	require.Equal(t, spans.Span_SPAN_KIND_SERVER, draw.Kind)
+	require.Equal(t, spans.Status_STATUS_CODE_ERROR, draw.Status.Code)
+
+	require.Equal(t, draw.TraceId, measure.TraceId)
+	require.Equal(t, draw.SpanId, measure.ParentSpanId)
+	require.Len(t, measure.SpanId, spanIDSize)
+	require.Equal(t, otlpID("measure", spanIDSize), measure.SpanId)
+	require.Equal(t, uint64(1000), measure.StartTimeUnixNano)
+	require.Equal(t, uint64(5000), measure.EndTimeUnixNano)
+
+	require.Equal(t, draw.TraceId, query.TraceId)
+	require.Equal(t, measure.SpanId, query.ParentSpanId)
+	require.Len(t, query.SpanId, spanIDSize)
+	require.Equal(t, spans.Span_SPAN_KIND_CLIENT, query.Kind)
+	require.Equal(t, uint64(2000), query.StartTimeUnixNano)
+
+	logsPayload := (&Exporter{}).buildLogsPayload(data.Melt)
+	record := logsPayload.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	require.Equal(t, "ERROR", record.SeverityText)
+	require.Equal(t, logs.SeverityNumber_SEVERITY_NUMBER_ERROR, record.SeverityNumber)
+}
+
+func TestOtlpID(t *testing.T) {
+	require.Nil(t, otlpID("", traceIDSize))
+	require.Equal(t, "5b8efff798038103d269b633813fc60c", hex.EncodeToString(otlpID("5b8efff7-9803-8103-d269-b633813fc60c", traceIDSize)))
+	require.Equal(t, "5b8efff798038103", hex.EncodeToString(otlpID("5b8efff798038103d269b633813fc60c", spanIDSize)))
+	require.Len(t, otlpID("checkout", traceIDSize), traceIDSize)
+	require.Equal(t, otlpID("checkout", spanIDSize), otlpID("checkout", spanIDSize))
+}
+
+func TestSpanKindParsing(t *testing.T) {
+	var s Span
+	require.NoError(t, yaml.Unmarshal([]byte("kind: CONSUMER"), &s))
+	require.Equal(t, SpanKindConsumer, s.Kind)
+	require.NoError(t, yaml.Unmarshal([]byte("kind: SPAN_KIND_PRODUCER"), &s))
+	require.Equal(t, SpanKindProducer, s.Kind)
+	require.NoError(t, yaml.Unmarshal([]byte("kind: 2"), &s))
+	require.Equal(t, SpanKindServer, s.Kind)
+	require.Error(t, yaml.Unmarshal([]byte("kind: 6"), &s))
+	require.Error(t, yaml.Unmarshal([]byte("kind: serving"), &s))
+
+	var status SpanStatus
+	require.NoError(t, yaml.Unmarshal([]byte("code: ok"), &status))
+	require.Equal(t, SpanStatusCodeOK, status.Code)
+	require.Error(t, yaml.Unmarshal([]byte("code: failed"), &status))
+}
@@ -118,6 +118,10 @@ type Span struct {
 	Events       []*SpanEvent
 	Links        []*SpanLink
 	Status       *SpanStatus
+
+	// Children are the spans whose parent is the span, in the same trace; their trace id, parent
+	// span id and missing times are set from the span when exporting
+	Children []*Span `yaml:"children,omitempty"`
 }
 
 // SpanEvent - event for span
@@ -310,6 +314,12 @@ func (s *SpanLink) SetAttribute(key string, value interface{}) *SpanLink {
 	return s
 }
 
+// AddChild - add a child span
+func (s *Span) AddChild(c *Span) *Span {
+	s.Children = append(s.Children, c)
+	return s
+}
+
 // SetStatus - set the span status
 func (s *Span) SetStatus(message string, code SpanStatusCode) *Span {
 	s.Status = &SpanStatus{
@@ -383,3 +393,69 @@ func (a *AggregationTemporality) UnmarshalYAML(unmarshal func(interface{}) error
 	}
 	return nil
 }
+
+// UnmarshalYAML for SpanKind support both integer and string (human) enumerated values
+func (k *SpanKind) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var originalValue string
+	if err := unmarshal(&originalValue); err != nil {
+		return err
+	}
+
+	// First try to parse as an integer
+	if valueInt, err := strconv.ParseInt(originalValue, 10, 8); err == nil {
+		if valueInt < int64(SpanKindUnspecified) || valueInt > int64(SpanKindConsumer) {
+			return fmt.Errorf("invalid span kind value %v, must be 0-5 or one of (unspecified, internal, server, client, producer, consumer)", valueInt)
+		}
+		*k = SpanKind(valueInt)
+		return nil
+	}
+
+	// If that fails, try to match the string
+	switch strings.TrimPrefix(strings.ToLower(originalValue), "span_kind_") {
+	case "unspecified":
+		*k = SpanKindUnspecified
+	case "internal":
+		*k = SpanKindInternal
+	case "server":
+		*k = SpanKindServer
+	case "client":
+		*k = SpanKindClient
+	case "producer":
+		*k = SpanKindProducer
+	case "consumer":
+		*k = SpanKindConsumer
+	default:
+		return fmt.Errorf("invalid span kind value %q, must be 0-5 or one of (unspecified, internal, server, client, producer, consumer)", originalValue)
+	}
+	return nil
+}
+
+// UnmarshalYAML for SpanStatusCode support both integer and string (human) enumerated values
+func (c *SpanStatusCode) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var originalValue string
+	if err := unmarshal(&originalValue); err != nil {
+		return err
+	}
+
+	// First try to parse as an integer
+	if valueInt, err := strconv.ParseInt(originalValue, 10, 8); err == nil {
+		if valueInt < int64(SpanStatusCodeUnset) || valueInt > int64(SpanStatusCodeError) {
+			return fmt.Errorf("invalid span status code value %v, must be 0-2 or one of (unset, ok, error)", valueInt)
+		}
+		*c = SpanStatusCode(valueInt)
+		return nil
+	}
+
+	// If that fails, try to match the string
+	switch strings.TrimPrefix(strings.ToLower(originalValue), "status_code_") {
+	case "unset":
+		*c = SpanStatusCodeUnset
+	case "ok":
+		*c = SpanStatusCodeOK
+	case "error":
+		*c = SpanStatusCodeError
+	default:
+		return fmt.Errorf("invalid span status code value %q, must be 0-2 or one of (unset, ok, error)", originalValue)
+	}
+	return nil
+}
@@ -0,0 +1,323 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package uqlquery builds UQL queries programmatically. Values, e.g., in filters, are escaped, so
+// that they cannot change the structure of a query, while type names and times are validated.
+//
+// For example:
+//
+//	query, err := uqlquery.Fetch(uqlquery.Name("id"), uqlquery.Attributes("k8s.workload.name")).
+//		From(uqlquery.Entities("k8s:deployment").Where(uqlquery.Attribute("k8s.cluster.name").Eq(cluster))).
+//		Since("-1w").
+//		Build()
+package uqlquery
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// typeNameRegexp matches fully qualified type names, e.g., k8s:deployment
+var typeNameRegexp = regexp.MustCompile(`^[\w.-]+:[\w.-]+$`)
+
+// fieldNameRegexp matches the names of plain fields, e.g., id, and of limits, e.g., events.count
+var fieldNameRegexp = regexp.MustCompile(`^[A-Za-z_][\w.]*$`)
+
+// relativeTimeRegexp matches relative times, e.g., -15m, -3d or now()-2h
+var relativeTimeRegexp = regexp.MustCompile(`^(-(\d+[smhdw])+|now\(\)([+-](\d+[smhdw])+)?)$`)
+
+// Query is a UQL query being built
+type Query struct {
+	fetch  []Field
+	from   *Source
+	since  string
+	until  string
+	limits []string
+	errs   []error
+}
+
+// Field is a field to fetch, e.g., id or attributes("k8s.workload.name")
+type Field struct {
+	text string
+	err  error
+}
+
+// Source is the data source of a query, e.g., entities(k8s:deployment), with optional filters
+type Source struct {
+	text       string
+	conditions []Condition
+	err        error
+}
+
+// Condition is a filter condition, e.g., attributes("k8s.cluster.name") = "prod"
+type Condition struct {
+	text string
+	err  error
+}
+
+// AttributeRef is an attribute to compare in a condition
+type AttributeRef struct {
+	name string
+}
+
+// Fetch starts a query fetching the given fields
+func Fetch(fields ...Field) *Query {
+	return &Query{fetch: fields}
+}
+
+// From sets the data source of the query
+func (q *Query) From(source *Source) *Query {
+	q.from = source
+	return q
+}
+
+// Since sets the start of the query's time range: a relative time, e.g., -15m, -3d or now()-2h,
+// or a timestamp, e.g., 2024-01-13T13:57:20Z
+func (q *Query) Since(t string) *Query {
+	q.since = q.checkTime("since", t)
+	return q
+}
+
+// SinceTime sets the start of the query's time range to a point in time
+func (q *Query) SinceTime(t time.Time) *Query {
+	q.since = t.UTC().Format(time.RFC3339)
+	return q
+}
+
+// Until sets the end of the query's time range, in the same forms as Since
+func (q *Query) Until(t string) *Query {
+	q.until = q.checkTime("until", t)
+	return q
+}
+
+// UntilTime sets the end of the query's time range to a point in time
+func (q *Query) UntilTime(t time.Time) *Query {
+	q.until = t.UTC().Format(time.RFC3339)
+	return q
+}
+
+// Limit adds a limit on the number of items of a field, e.g., Limit("events.count", 1)
+// for LIMITS events.count(1)
+func (q *Query) Limit(name string, count int) *Query {
+	if !fieldNameRegexp.MatchString(name) {
+		q.errs = append(q.errs, fmt.Errorf("invalid limit name %q", name))
+		return q
+	}
+	q.limits = append(q.limits, fmt.Sprintf("%s(%d)", name, count))
+	return q
+}
+
+// Build returns the text of the query, or an error if any of its parts is invalid
+func (q *Query) Build() (string, error) {
+	errs := append([]error{}, q.errs...)
+	if len(q.fetch) == 0 {
+		errs = append(errs, fmt.Errorf("the query has no fields to fetch"))
+	}
+
+	var clauses []string
+	if q.since != "" {
+		clauses = append(clauses, "SINCE "+q.since)
+	}
+	if q.until != "" {
+		clauses = append(clauses, "UNTIL "+q.until)
+	}
+	fields := make([]string, len(q.fetch))
+	for i, field := range q.fetch {
+		fields[i] = field.text
+		errs = append(errs, field.err)
+	}
+	clauses = append(clauses, "FETCH "+strings.Join(fields, ", "))
+	if q.from != nil {
+		text, err := q.from.build()
+		clauses = append(clauses, "FROM "+text)
+		errs = append(errs, err)
+	}
+	if len(q.limits) > 0 {
+		clauses = append(clauses, "LIMITS "+strings.Join(q.limits, ", "))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return "", fmt.Errorf("invalid UQL query: %w", err)
+	}
+	return strings.Join(clauses, "\n"), nil
+}
+
+func (q *Query) checkTime(clause string, t string) string {
+	if relativeTimeRegexp.MatchString(t) {
+		return t
+	}
+	if _, err := time.Parse(time.RFC3339Nano, t); err != nil {
+		q.errs = append(q.errs, fmt.Errorf("invalid %v time %q: it must be a relative time, e.g., -15m, or an RFC 3339 timestamp", clause, t))
+	}
+	return t
+}
+
+// Name is a plain field, e.g., id or type
+func Name(name string) Field {
+	if !fieldNameRegexp.MatchString(name) {
+		return Field{text: name, err: fmt.Errorf("invalid field name %q", name)}
+	}
+	return Field{text: name}
+}
+
+// Attributes is the attributes field: all attributes if no names are given, otherwise the
+// attributes with the given names
+func Attributes(names ...string) Field {
+	if len(names) == 0 {
+		return Field{text: "attributes"}
+	}
+	return Field{text: "attributes(" + quoteAll(names) + ")"}
+}
+
+// Events is the events of a type, with the given fields (all fields if none are given)
+func Events(eventType string, fields ...Field) Field {
+	return typedField("events", eventType, fields)
+}
+
+// Metrics is the metrics of a type, with the given fields (all fields if none are given)
+func Metrics(metricType string, fields ...Field) Field {
+	return typedField("metrics", metricType, fields)
+}
+
+func typedField(kind string, typeName string, fields []Field) Field {
+	f := Field{text: kind + "(" + typeName + ")", err: checkTypeName(typeName)}
+	if len(fields) == 0 {
+		return f
+	}
+	texts := make([]string, len(fields))
+	errs := []error{f.err}
+	for i, field := range fields {
+		texts[i] = field.text
+		errs = append(errs, field.err)
+	}
+	f.text += "{" + strings.Join(texts, ", ") + "}"
+	f.err = errors.Join(errs...)
+	return f
+}
+
+// Entities is the entities of the given types
+func Entities(types ...string) *Source {
+	var errs []error
+	if len(types) == 0 {
+		errs = append(errs, fmt.Errorf("no entity type"))
+	}
+	for _, t := range types {
+		errs = append(errs, checkTypeName(t))
+	}
+	return &Source{text: "entities(" + strings.Join(types, ", ") + ")", err: errors.Join(errs...)}
+}
+
+// Where adds conditions that the entities must all meet
+func (s *Source) Where(conditions ...Condition) *Source {
+	s.conditions = append(s.conditions, conditions...)
+	return s
+}
+
+func (s *Source) build() (string, error) {
+	if len(s.conditions) == 0 {
+		return s.text, s.err
+	}
+	texts := make([]string, len(s.conditions))
+	errs := []error{s.err}
+	for i, c := range s.conditions {
+		texts[i] = c.text
+		errs = append(errs, c.err)
+	}
+	return s.text + "[" + strings.Join(texts, " && ") + "]", errors.Join(errs...)
+}
+
+// Attribute refers to an attribute in a condition
+func Attribute(name string) AttributeRef {
+	return AttributeRef{name: name}
+}
+
+// Eq is the condition that the attribute is equal to the value
+func (a AttributeRef) Eq(value string) Condition {
+	return a.compare("=", value)
+}
+
+// Ne is the condition that the attribute is not equal to the value
+func (a AttributeRef) Ne(value string) Condition {
+	return a.compare("!=", value)
+}
+
+// Matches is the condition that the attribute matches a pattern, where "*" matches any characters,
+// e.g., "frontend*"
+func (a AttributeRef) Matches(pattern string) Condition {
+	return a.compare("~", pattern)
+}
+
+func (a AttributeRef) compare(operator string, value string) Condition {
+	return Condition{text: fmt.Sprintf("attributes(%s) %s %s", Quote(a.name), operator, Quote(value))}
+}
+
+// ID is the condition that the entity has the given ID, e.g., k8s:deployment:aBc
+func ID(id string) Condition {
+	return Condition{text: "id = " + Quote(id)}
+}
+
+// And is the condition that all the conditions are met
+func And(conditions ...Condition) Condition {
+	return join(" && ", conditions)
+}
+
+// Or is the condition that any of the conditions is met
+func Or(conditions ...Condition) Condition {
+	return join(" || ", conditions)
+}
+
+// Not is the condition that the condition is not met
+func Not(condition Condition) Condition {
+	return Condition{text: "!(" + condition.text + ")", err: condition.err}
+}
+
+func join(operator string, conditions []Condition) Condition {
+	if len(conditions) == 1 {
+		return conditions[0]
+	}
+	texts := make([]string, len(conditions))
+	errs := make([]error, len(conditions))
+	for i, c := range conditions {
+		texts[i] = c.text
+		errs[i] = c.err
+	}
+	if len(conditions) == 0 {
+		errs = append(errs, fmt.Errorf("no conditions"))
+	}
+	return Condition{text: "(" + strings.Join(texts, operator) + ")", err: errors.Join(errs...)}
+}
+
+// Quote returns a value as a UQL string literal, escaping it as needed
+func Quote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+}
+
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = Quote(v)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func checkTypeName(name string) error {
+	if !typeNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid type name %q: it must be qualified by a namespace, e.g., k8s:deployment", name)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2024 Cisco Systems, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uqlquery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild(t *testing.T) {
+	query, err := Fetch(Name("id"), Events("k8sprofiler:report", Attributes("resource_metadata.cluster_name"))).
+		From(Entities("k8s:deployment").Where(
+			Attribute("k8s.cluster.name").Matches("prod*"),
+			Or(Attribute("k8s.namespace.name").Eq("default"), Not(ID("k8s:deployment:abc"))),
+		)).
+		Since("-3d").
+		Limit("events.count", 1).
+		Build()
+
+	assert.NoError(t, err)
+	assert.Equal(t, `SINCE -3d
+FETCH id, events(k8sprofiler:report){attributes("resource_metadata.cluster_name")}
+FROM entities(k8s:deployment)[attributes("k8s.cluster.name") ~ "prod*" && (attributes("k8s.namespace.name") = "default" || !(id = "k8s:deployment:abc"))]
+LIMITS events.count(1)`, query)
+}
+
+func TestBuild_Escaping(t *testing.T) {
+	query, err := Fetch(Attributes()).
+		From(Entities("k8s:workload").Where(Attribute("k8s.workload.name").Ne(`a" || true || "\`))).
+		SinceTime(time.Date(2024, 1, 13, 14, 0, 0, 0, time.UTC)).
+		Until("now()-1h").
+		Build()
+
+	assert.NoError(t, err)
+	assert.Equal(t, `SINCE 2024-01-13T14:00:00Z
+UNTIL now()-1h
+FETCH attributes
+FROM entities(k8s:workload)[attributes("k8s.workload.name") != "a\" || true || \"\\"]`, query)
+}
+
+func TestBuild_Invalid(t *testing.T) {
+	tests := map[string]*Query{
+		"no fields":       Fetch(),
+		"field name":      Fetch(Name("id, type")),
+		"entity type":     Fetch(Name("id")).From(Entities("deployment")),
+		"event type":      Fetch(Events("k8s:event)")),
+		"no entity types": Fetch(Name("id")).From(Entities()),
+		"since":           Fetch(Name("id")).Since("yesterday"),
+		"until":           Fetch(Name("id")).Until("-1h; FETCH"),
+		"limit":           Fetch(Name("id")).Limit("events.count(1)", 2),
+		"no conditions":   Fetch(Name("id")).From(Entities("k8s:deployment").Where(Or())),
+	}
+	for name, q := range tests {
+		_, err := q.Build()
+		assert.Error(t, err, name)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	assert.Equal(t, `"plain"`, Quote("plain"))
+	assert.Equal(t, `"a\"b\\c"`, Quote(`a"b\c`))
+}